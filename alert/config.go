@@ -0,0 +1,79 @@
+package alert
+
+import "time"
+
+const (
+	NotifierSlack     = "slack"
+	NotifierWebhook   = "webhook"
+	NotifierPagerDuty = "pagerduty"
+
+	// CounterLogError is the counter fed by LogHook.
+	CounterLogError = "log.error"
+)
+
+// Config is the configuration of the alert plugin.
+type Config struct {
+	// Source is the service name shown in alerts.
+	Source string `yaml:"source" mapstructure:"source"`
+	// Rules are the thresholds to watch.
+	Rules []Rule `yaml:"rules" mapstructure:"rules"`
+	// Notifiers are the webhook targets, every alert is sent to all of them.
+	Notifiers []NotifierConfig `yaml:"notifiers" mapstructure:"notifiers"`
+	// MaxPerMinute limits the alerts sent per minute across all rules, default as 10.
+	MaxPerMinute int `yaml:"max_per_minute" mapstructure:"max_per_minute"`
+	// QueueSize is the size of the pending alert queue, default as 100.
+	QueueSize int `yaml:"queue_size" mapstructure:"queue_size"`
+}
+
+// Rule fires an alert when Counter increases by at least Threshold within Window.
+type Rule struct {
+	Name      string        `yaml:"name" mapstructure:"name"`
+	Counter   string        `yaml:"counter" mapstructure:"counter"`
+	Threshold int           `yaml:"threshold" mapstructure:"threshold"`
+	Window    time.Duration `yaml:"window" mapstructure:"window"`
+	// Cooldown is the minimal interval between two alerts of the rule, default as Window.
+	Cooldown time.Duration `yaml:"cooldown" mapstructure:"cooldown"`
+	// Severity is one of critical, error, warning, info, default as error.
+	Severity string `yaml:"severity" mapstructure:"severity"`
+}
+
+// NotifierConfig is the config of a webhook target.
+type NotifierConfig struct {
+	// Type is slack, webhook or pagerduty.
+	Type string `yaml:"type" mapstructure:"type"`
+	// URL is the webhook url, pagerduty defaults to the events v2 api.
+	URL string `yaml:"url" mapstructure:"url"`
+	// RoutingKey is the integration key of pagerduty.
+	RoutingKey string `yaml:"routing_key" mapstructure:"routing_key"`
+	// Headers are extra http headers of the generic webhook.
+	Headers map[string]string `yaml:"headers" mapstructure:"headers"`
+	// Timeout is the timeout of each request, default as 5s.
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+func (c *Config) setDefaults() {
+	if c.MaxPerMinute <= 0 {
+		c.MaxPerMinute = 10
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 100
+	}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Window <= 0 {
+			r.Window = time.Minute
+		}
+		if r.Cooldown <= 0 {
+			r.Cooldown = r.Window
+		}
+		if r.Threshold <= 0 {
+			r.Threshold = 1
+		}
+		if r.Severity == "" {
+			r.Severity = "error"
+		}
+		if r.Name == "" {
+			r.Name = r.Counter
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// pagerDutyEventsURL is the default url of the pagerduty events api v2.
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Notifier sends the alert to an external system.
+type Notifier interface {
+	Notify(ctx context.Context, a *Alert) error
+}
+
+// NotifierFunc is an adapter to allow the use of ordinary functions as Notifier.
+type NotifierFunc func(ctx context.Context, a *Alert) error
+
+// Notify calls fn(ctx, a).
+func (fn NotifierFunc) Notify(ctx context.Context, a *Alert) error {
+	return fn(ctx, a)
+}
+
+// NewNotifier creates a Notifier by the config.
+func NewNotifier(cfg NotifierConfig) (Notifier, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+	switch cfg.Type {
+	case NotifierSlack:
+		if cfg.URL == "" {
+			return nil, errors.New("alert: slack url empty")
+		}
+		return &SlackNotifier{URL: cfg.URL, Client: client}, nil
+	case NotifierWebhook:
+		if cfg.URL == "" {
+			return nil, errors.New("alert: webhook url empty")
+		}
+		return &WebhookNotifier{URL: cfg.URL, Headers: cfg.Headers, Client: client}, nil
+	case NotifierPagerDuty:
+		if cfg.RoutingKey == "" {
+			return nil, errors.New("alert: pagerduty routing_key empty")
+		}
+		url := cfg.URL
+		if url == "" {
+			url = pagerDutyEventsURL
+		}
+		return &PagerDutyNotifier{URL: url, RoutingKey: cfg.RoutingKey, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("alert: notifier type %s not supported", cfg.Type)
+	}
+}
+
+// SlackNotifier posts the alert to a slack incoming webhook.
+type SlackNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (n *SlackNotifier) Notify(ctx context.Context, a *Alert) error {
+	return postJSON(ctx, n.Client, n.URL, nil, map[string]string{
+		"text": fmt.Sprintf(":rotating_light: *%s* %s", a.Severity, a.Summary()),
+	})
+}
+
+// WebhookNotifier posts the alert as json to a generic http endpoint.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// Notify implements Notifier.
+func (n *WebhookNotifier) Notify(ctx context.Context, a *Alert) error {
+	return postJSON(ctx, n.Client, n.URL, n.Headers, a)
+}
+
+// PagerDutyNotifier triggers a pagerduty event, deduplicated by source and rule.
+type PagerDutyNotifier struct {
+	URL        string
+	RoutingKey string
+	Client     *http.Client
+}
+
+// Notify implements Notifier.
+func (n *PagerDutyNotifier) Notify(ctx context.Context, a *Alert) error {
+	source := a.Source
+	if source == "" {
+		source = "go-kits"
+	}
+	return postJSON(ctx, n.Client, n.URL, nil, map[string]any{
+		"routing_key":  n.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    source + "/" + a.Rule,
+		"payload": map[string]any{
+			"summary":        a.Summary(),
+			"source":         source,
+			"severity":       a.Severity,
+			"timestamp":      a.Time.Format(time.RFC3339),
+			"custom_details": a,
+		},
+	})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	rsp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 512))
+		return fmt.Errorf("alert: post %s status %d: %s", url, rsp.StatusCode, msg)
+	}
+	return nil
+}
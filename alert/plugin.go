@@ -0,0 +1,96 @@
+package alert
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "alert"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+var (
+	mu             sync.RWMutex
+	defaultWatcher *Watcher
+)
+
+// Default returns the watcher created by the alert plugin, nil if not set up.
+func Default() *Watcher {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultWatcher
+}
+
+// SetDefault sets the default watcher.
+func SetDefault(w *Watcher) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultWatcher = w
+}
+
+// Add increases the counter of the default watcher, no-op if not set up.
+func Add(counter string, n int) {
+	if w := Default(); w != nil {
+		w.Add(counter, n)
+	}
+}
+
+// LogHook returns a zap hook counting error and above entries into CounterLogError.
+// It can be attached with zap.Hooks.
+func LogHook(w *Watcher) func(zapcore.Entry) error {
+	return func(e zapcore.Entry) error {
+		if e.Level >= zapcore.ErrorLevel {
+			w.Inc(CounterLogError)
+		}
+		return nil
+	}
+}
+
+// DefaultFactory is the alert plugin factory registered as alert-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of alert. The watcher set up is the default one.
+type Factory struct {
+	watcher *Watcher
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates the watcher by the plugin config.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	w, err := NewWatcher(cfg)
+	if err != nil {
+		return err
+	}
+	f.watcher = w
+	SetDefault(w)
+	return nil
+}
+
+// Close sends the pending alerts and stops the watcher.
+func (f *Factory) Close() error {
+	if f.watcher == nil {
+		return nil
+	}
+	w := f.watcher
+	f.watcher = nil
+	if Default() == w {
+		SetDefault(nil)
+	}
+	return w.Close()
+}
@@ -0,0 +1,210 @@
+/*
+alert 错误率告警，统计计数器在时间窗口内的增量，超过阈值时通过 webhook 发送告警
+*/
+
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// windowBuckets is the number of buckets of a sliding window.
+const windowBuckets = 10
+
+// Alert is a fired alert.
+type Alert struct {
+	Rule      string        `json:"rule"`
+	Counter   string        `json:"counter"`
+	Severity  string        `json:"severity"`
+	Source    string        `json:"source"`
+	Count     int           `json:"count"`
+	Threshold int           `json:"threshold"`
+	Window    time.Duration `json:"window"`
+	Time      time.Time     `json:"time"`
+}
+
+// Summary returns the human readable description of the alert.
+func (a *Alert) Summary() string {
+	return fmt.Sprintf("[%s] %s: %s reached %d in %s (threshold %d)",
+		a.Source, a.Rule, a.Counter, a.Count, a.Window, a.Threshold)
+}
+
+// Watcher counts events and fires alerts when rules are violated.
+type Watcher struct {
+	cfg       Config
+	notifiers []Notifier
+	now       func() time.Time
+
+	mu     sync.Mutex
+	rules  map[string][]*ruleState // counter => rules
+	sent   []time.Time             // alerts sent in the last minute
+	closed bool
+
+	queue chan *Alert
+	done  chan struct{}
+}
+
+// NewWatcher creates a Watcher with the notifiers from cfg and the extra ones.
+func NewWatcher(cfg Config, extra ...Notifier) (*Watcher, error) {
+	cfg.setDefaults()
+	notifiers := make([]Notifier, 0, len(cfg.Notifiers)+len(extra))
+	for _, nc := range cfg.Notifiers {
+		n, err := NewNotifier(nc)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	notifiers = append(notifiers, extra...)
+
+	w := &Watcher{
+		cfg:       cfg,
+		notifiers: notifiers,
+		now:       time.Now,
+		rules:     make(map[string][]*ruleState),
+		queue:     make(chan *Alert, cfg.QueueSize),
+		done:      make(chan struct{}),
+	}
+	for _, r := range cfg.Rules {
+		if r.Counter == "" {
+			return nil, fmt.Errorf("alert: rule %s counter empty", r.Name)
+		}
+		w.rules[r.Counter] = append(w.rules[r.Counter], newRuleState(r))
+	}
+	go w.loop()
+	return w, nil
+}
+
+// Inc increases the counter by one.
+func (w *Watcher) Inc(counter string) {
+	w.Add(counter, 1)
+}
+
+// Add increases the counter by n and checks the rules watching it.
+func (w *Watcher) Add(counter string, n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	now := w.now()
+	for _, rs := range w.rules[counter] {
+		rs.add(now, n)
+		count := rs.sum(now)
+		if count < rs.rule.Threshold {
+			continue
+		}
+		// 同一规则在冷却期内只告警一次
+		if !rs.lastFired.IsZero() && now.Sub(rs.lastFired) < rs.rule.Cooldown {
+			continue
+		}
+		if !w.allow(now) {
+			continue
+		}
+		rs.lastFired = now
+		a := &Alert{
+			Rule:      rs.rule.Name,
+			Counter:   counter,
+			Severity:  rs.rule.Severity,
+			Source:    w.cfg.Source,
+			Count:     count,
+			Threshold: rs.rule.Threshold,
+			Window:    rs.rule.Window,
+			Time:      now,
+		}
+		select {
+		case w.queue <- a:
+		default:
+			log.Warnf("alert: queue full, drop alert %s", a.Rule)
+		}
+	}
+}
+
+// allow applies the global MaxPerMinute rate limit.
+func (w *Watcher) allow(now time.Time) bool {
+	i := 0
+	for i < len(w.sent) && now.Sub(w.sent[i]) >= time.Minute {
+		i++
+	}
+	w.sent = w.sent[i:]
+	if len(w.sent) >= w.cfg.MaxPerMinute {
+		return false
+	}
+	w.sent = append(w.sent, now)
+	return true
+}
+
+func (w *Watcher) loop() {
+	defer close(w.done)
+	for a := range w.queue {
+		w.notify(a)
+	}
+}
+
+func (w *Watcher) notify(a *Alert) {
+	for _, n := range w.notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := n.Notify(ctx, a); err != nil {
+			log.Errorf("alert: notify %s error: %v", a.Rule, err)
+		}
+		cancel()
+	}
+}
+
+// Close stops accepting events and waits for the pending alerts to be sent.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return errors.New("alert: watcher already closed")
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+	<-w.done
+	return nil
+}
+
+// ruleState is the sliding window of a rule.
+type ruleState struct {
+	rule      Rule
+	bucketDur time.Duration
+	counts    [windowBuckets]int
+	stamps    [windowBuckets]int64
+	lastFired time.Time
+}
+
+func newRuleState(r Rule) *ruleState {
+	d := r.Window / windowBuckets
+	if d <= 0 {
+		d = time.Millisecond
+	}
+	return &ruleState{rule: r, bucketDur: d}
+}
+
+func (rs *ruleState) add(now time.Time, n int) {
+	idx := now.UnixNano() / int64(rs.bucketDur)
+	slot := idx % windowBuckets
+	if rs.stamps[slot] != idx {
+		rs.stamps[slot] = idx
+		rs.counts[slot] = 0
+	}
+	rs.counts[slot] += n
+}
+
+func (rs *ruleState) sum(now time.Time) int {
+	idx := now.UnixNano() / int64(rs.bucketDur)
+	total := 0
+	for i := range rs.counts {
+		if idx-rs.stamps[i] < windowBuckets {
+			total += rs.counts[i]
+		}
+	}
+	return total
+}
@@ -0,0 +1,175 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// mockNotifier records the received alerts.
+type mockNotifier struct {
+	mu     sync.Mutex
+	alerts []*Alert
+}
+
+func (m *mockNotifier) Notify(_ context.Context, a *Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alerts = append(m.alerts, a)
+	return nil
+}
+
+// fakeNow returns a controllable clock.
+func fakeNow(start time.Time) (func() time.Time, func(time.Duration)) {
+	now := start
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+// TestWatcherThreshold tests that an alert fires only when the threshold is reached.
+func TestWatcherThreshold(t *testing.T) {
+	n := &mockNotifier{}
+	w, err := NewWatcher(Config{
+		Source: "svc",
+		Rules:  []Rule{{Name: "errors", Counter: "c", Threshold: 3, Window: time.Minute}},
+	}, n)
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	now, advance := fakeNow(time.Unix(1700000000, 0))
+	w.now = now
+
+	w.Inc("c")
+	w.Inc("c")
+	w.Inc("other")
+	advance(time.Second)
+	w.Inc("c")
+	_ = w.Close()
+
+	if len(n.alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(n.alerts))
+	}
+	a := n.alerts[0]
+	if a.Rule != "errors" || a.Count != 3 || a.Source != "svc" {
+		t.Errorf("Unexpected alert: %+v", a)
+	}
+}
+
+// TestWatcherWindowExpire tests that events outside the window are not counted.
+func TestWatcherWindowExpire(t *testing.T) {
+	n := &mockNotifier{}
+	w, _ := NewWatcher(Config{
+		Rules: []Rule{{Counter: "c", Threshold: 2, Window: 10 * time.Second}},
+	}, n)
+	now, advance := fakeNow(time.Unix(1700000000, 0))
+	w.now = now
+
+	w.Inc("c")
+	advance(11 * time.Second)
+	w.Inc("c")
+	_ = w.Close()
+
+	if len(n.alerts) != 0 {
+		t.Fatalf("Expected no alert, got %d", len(n.alerts))
+	}
+}
+
+// TestWatcherCooldown tests de-duplication of alerts of the same rule.
+func TestWatcherCooldown(t *testing.T) {
+	n := &mockNotifier{}
+	w, _ := NewWatcher(Config{
+		Rules: []Rule{{Counter: "c", Threshold: 1, Window: time.Second, Cooldown: time.Minute}},
+	}, n)
+	now, advance := fakeNow(time.Unix(1700000000, 0))
+	w.now = now
+
+	w.Inc("c")
+	advance(30 * time.Second)
+	w.Inc("c")
+	advance(31 * time.Second)
+	w.Inc("c")
+	_ = w.Close()
+
+	if len(n.alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %d", len(n.alerts))
+	}
+}
+
+// TestWatcherRateLimit tests the global MaxPerMinute limit.
+func TestWatcherRateLimit(t *testing.T) {
+	n := &mockNotifier{}
+	w, _ := NewWatcher(Config{
+		MaxPerMinute: 2,
+		Rules: []Rule{
+			{Name: "a", Counter: "c", Threshold: 1},
+			{Name: "b", Counter: "c", Threshold: 1},
+			{Name: "c", Counter: "c", Threshold: 1},
+		},
+	}, n)
+	w.Inc("c")
+	_ = w.Close()
+
+	if len(n.alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %d", len(n.alerts))
+	}
+}
+
+// TestLogHook tests that only error and above entries are counted.
+func TestLogHook(t *testing.T) {
+	n := &mockNotifier{}
+	w, _ := NewWatcher(Config{
+		Rules: []Rule{{Counter: CounterLogError, Threshold: 2}},
+	}, n)
+	hook := LogHook(w)
+	_ = hook(zapcore.Entry{Level: zapcore.InfoLevel})
+	_ = hook(zapcore.Entry{Level: zapcore.WarnLevel})
+	_ = hook(zapcore.Entry{Level: zapcore.ErrorLevel})
+	_ = hook(zapcore.Entry{Level: zapcore.ErrorLevel})
+	_ = w.Close()
+
+	if len(n.alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(n.alerts))
+	}
+}
+
+// TestNotifiers tests the request bodies of the built-in notifiers.
+func TestNotifiers(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	a := &Alert{Rule: "errors", Severity: "error", Source: "svc", Time: time.Now()}
+
+	tests := []struct {
+		cfg NotifierConfig
+		key string
+	}{
+		{NotifierConfig{Type: NotifierSlack, URL: srv.URL}, "text"},
+		{NotifierConfig{Type: NotifierWebhook, URL: srv.URL}, "rule"},
+		{NotifierConfig{Type: NotifierPagerDuty, URL: srv.URL, RoutingKey: "key"}, "routing_key"},
+	}
+	for _, tt := range tests {
+		n, err := NewNotifier(tt.cfg)
+		if err != nil {
+			t.Fatalf("NewNotifier(%s) failed: %v", tt.cfg.Type, err)
+		}
+		if err := n.Notify(context.Background(), a); err != nil {
+			t.Fatalf("Notify(%s) failed: %v", tt.cfg.Type, err)
+		}
+		if _, ok := body[tt.key]; !ok {
+			t.Errorf("%s body missing key %s: %v", tt.cfg.Type, tt.key, body)
+		}
+	}
+
+	if _, err := NewNotifier(NotifierConfig{Type: "unknown"}); err == nil {
+		t.Error("Expected error for unknown notifier type")
+	}
+}
@@ -0,0 +1,63 @@
+/*
+cache 泛型缓存，Cache[K,V] 接口和进程内 LRU + TTL 分片实现，内置 singleflight 加载防止缓存击穿
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Get when the key is missing or expired.
+var ErrNotFound = errors.New("cache: not found")
+
+// NoExpiration makes the entry never expire when passed as the ttl of Set.
+const NoExpiration time.Duration = -1
+
+// LoadFunc loads the value of a missing key, e.g. from the database.
+type LoadFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// Cache is the interface of the caches.
+type Cache[K comparable, V any] interface {
+	// Get returns the value of key, ErrNotFound if missing or expired.
+	Get(ctx context.Context, key K) (V, error)
+	// Set sets the value of key. ttl 0 means the default ttl of the cache, and
+	// NoExpiration means never expire.
+	Set(ctx context.Context, key K, value V, ttl time.Duration) error
+	// Delete deletes key, no error if missing.
+	Delete(ctx context.Context, key K) error
+	// GetOrLoad returns the value of key, or loads and sets it with the default ttl
+	// if missing. The concurrent loads of the same key are merged into one.
+	GetOrLoad(ctx context.Context, key K, load LoadFunc[K, V]) (V, error)
+}
+
+// Observer is notified of the cache activity, e.g. to export the hit ratio metrics.
+type Observer interface {
+	// ObserveGet is called by every Get and GetOrLoad.
+	ObserveGet(hit bool)
+	// ObserveLoad is called after a load of GetOrLoad finishes.
+	ObserveLoad(duration time.Duration, err error)
+	// ObserveEvict is called when an entry is evicted, expired is false if evicted by capacity.
+	ObserveEvict(expired bool)
+}
+
+// Stats is the statistics of a cache.
+type Stats struct {
+	Hits       int64
+	Misses     int64
+	Loads      int64
+	LoadErrors int64
+	Evictions  int64
+	// Len is the number of entries including the expired ones not removed yet.
+	Len int
+}
+
+// HitRatio returns the ratio of hits to gets, 0 if no get.
+func (s Stats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baisiyi/go-kits/clock"
+)
+
+type countObserver struct {
+	hits, misses, loads, evicts, expires atomic.Int32
+}
+
+func (o *countObserver) ObserveGet(hit bool) {
+	if hit {
+		o.hits.Add(1)
+	} else {
+		o.misses.Add(1)
+	}
+}
+
+func (o *countObserver) ObserveLoad(time.Duration, error) { o.loads.Add(1) }
+
+func (o *countObserver) ObserveEvict(expired bool) {
+	if expired {
+		o.expires.Add(1)
+	} else {
+		o.evicts.Add(1)
+	}
+}
+
+// TestMemoryLRU tests that the least recently used entry is evicted.
+func TestMemoryLRU(t *testing.T) {
+	ctx := context.Background()
+	obs := &countObserver{}
+	c := NewMemory[string, int](WithCapacity(2), WithShards(1), WithObserver(obs))
+	_ = c.Set(ctx, "a", 1, 0)
+	_ = c.Set(ctx, "b", 2, 0)
+	if v, err := c.Get(ctx, "a"); err != nil || v != 1 {
+		t.Fatalf("Get(a) = %v, %v", v, err)
+	}
+	_ = c.Set(ctx, "c", 3, 0) // evicts b
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(b) error = %v, want ErrNotFound", err)
+	}
+	_ = c.Set(ctx, "a", 10, 0) // update does not evict
+	if v, _ := c.Get(ctx, "a"); v != 10 || c.Len() != 2 {
+		t.Errorf("a = %d, len = %d", v, c.Len())
+	}
+	_ = c.Delete(ctx, "a")
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Error("a not deleted")
+	}
+
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 2 || s.Evictions != 1 || s.HitRatio() != 0.5 {
+		t.Errorf("stats = %+v", s)
+	}
+	if obs.hits.Load() != 2 || obs.misses.Load() != 2 || obs.evicts.Load() != 1 {
+		t.Errorf("observer hits = %d, misses = %d, evicts = %d", obs.hits.Load(), obs.misses.Load(), obs.evicts.Load())
+	}
+}
+
+// TestMemoryTTL tests the default and the per entry ttl.
+func TestMemoryTTL(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewMemory[string, int](WithTTL(time.Minute), WithClock(clk))
+	_ = c.Set(ctx, "default", 1, 0)
+	_ = c.Set(ctx, "short", 2, time.Second)
+	_ = c.Set(ctx, "forever", 3, NoExpiration)
+
+	clk.Advance(time.Second)
+	if _, err := c.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+		t.Error("short not expired")
+	}
+	if _, err := c.Get(ctx, "default"); err != nil {
+		t.Error("default expired early")
+	}
+	clk.Advance(time.Hour)
+	c.DeleteExpired()
+	if c.Len() != 1 {
+		t.Errorf("len = %d, want 1", c.Len())
+	}
+	if v, err := c.Get(ctx, "forever"); err != nil || v != 3 {
+		t.Errorf("forever = %d, %v", v, err)
+	}
+}
+
+// TestGetOrLoad tests that the concurrent loads of a key are merged.
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemory[int, string]()
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context, key int) (string, error) {
+		loads.Add(1)
+		<-release
+		return fmt.Sprint("v", key), nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.GetOrLoad(ctx, 1, load)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if loads.Load() != 1 {
+		t.Errorf("loads = %d, want 1", loads.Load())
+	}
+	for _, r := range results {
+		if r != "v1" {
+			t.Fatalf("results = %v", results)
+		}
+	}
+	if v, err := c.Get(ctx, 1); err != nil || v != "v1" {
+		t.Errorf("loaded value not cached: %v, %v", v, err)
+	}
+
+	// the failed loads are not cached
+	loadErr := errors.New("db down")
+	if _, err := c.GetOrLoad(ctx, 2, func(context.Context, int) (string, error) { return "", loadErr }); !errors.Is(err, loadErr) {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := c.Get(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Error("failed load cached")
+	}
+	if s := c.Stats(); s.Loads != 2 || s.LoadErrors != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+// TestGroupCancel tests that a canceled caller does not fail the others and a
+// panic is returned as an error.
+func TestGroupCancel(t *testing.T) {
+	var g Group[string, int]
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (int, error) {
+		<-release
+		return 1, ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.Do(ctx, "k", fn)
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled caller err = %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	if v, err := g.Do(context.Background(), "k", fn); err != nil || v != 1 {
+		t.Errorf("Do = %d, %v", v, err)
+	}
+
+	if _, err := g.Do(context.Background(), "p", func(context.Context) (int, error) { panic("boom") }); err == nil {
+		t.Error("Expected error for panic")
+	}
+}
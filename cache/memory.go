@@ -0,0 +1,299 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/baisiyi/go-kits/clock"
+)
+
+// Option is the option of Memory.
+type Option func(*options)
+
+type options struct {
+	capacity int
+	ttl      time.Duration
+	shards   int
+	observer Observer
+	clock    clock.Clock
+}
+
+// WithCapacity sets the max number of entries, the least recently used ones are
+// evicted when exceeded, default as 10000. Each shard holds capacity/shards entries.
+func WithCapacity(n int) Option {
+	return func(o *options) {
+		o.capacity = n
+	}
+}
+
+// WithTTL sets the default ttl of the entries, default as no expiration.
+func WithTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.ttl = d
+	}
+}
+
+// WithShards sets the number of shards, each locked separately, default as 16.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// WithObserver sets the observer of the cache.
+func WithObserver(ob Observer) Option {
+	return func(o *options) {
+		o.observer = ob
+	}
+}
+
+// WithClock sets the clock of the expiration, default as clock.Real.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// Memory is an in-memory Cache with LRU eviction and per entry ttl. The keys are
+// spread over shards to reduce the lock contention.
+type Memory[K comparable, V any] struct {
+	opts   options
+	clock  clock.Clock
+	seed   maphash.Seed
+	shards []*shard[K, V]
+	group  Group[K, V]
+
+	hits       atomic.Int64
+	misses     atomic.Int64
+	loads      atomic.Int64
+	loadErrors atomic.Int64
+	evictions  atomic.Int64
+}
+
+type shard[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	lru      *list.List // front is the most recently used
+	items    map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key      K
+	value    V
+	expireAt time.Time // zero means never
+}
+
+var _ Cache[string, int] = (*Memory[string, int])(nil)
+
+// NewMemory creates an in-memory cache.
+func NewMemory[K comparable, V any](opts ...Option) *Memory[K, V] {
+	o := options{capacity: 10000, shards: 16}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.shards <= 0 {
+		o.shards = 16
+	}
+	if o.capacity <= 0 {
+		o.capacity = 10000
+	}
+	o.shards = min(o.shards, o.capacity)
+	m := &Memory[K, V]{
+		opts:   o,
+		clock:  clock.OrReal(o.clock),
+		seed:   maphash.MakeSeed(),
+		shards: make([]*shard[K, V], o.shards),
+	}
+	perShard := (o.capacity + o.shards - 1) / o.shards
+	for i := range m.shards {
+		m.shards[i] = &shard[K, V]{capacity: perShard, lru: list.New(), items: make(map[K]*list.Element)}
+	}
+	return m
+}
+
+// Get returns the value of key, ErrNotFound if missing or expired.
+func (m *Memory[K, V]) Get(_ context.Context, key K) (V, error) {
+	v, ok := m.get(key)
+	m.observeGet(ok)
+	if !ok {
+		return v, ErrNotFound
+	}
+	return v, nil
+}
+
+// Set sets the value of key, evicting the least recently used entry of the shard if full.
+func (m *Memory[K, V]) Set(_ context.Context, key K, value V, ttl time.Duration) error {
+	m.set(key, value, ttl)
+	return nil
+}
+
+// Delete deletes key.
+func (m *Memory[K, V]) Delete(_ context.Context, key K) error {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.items[key]; ok {
+		s.lru.Remove(e)
+		delete(s.items, key)
+	}
+	return nil
+}
+
+// GetOrLoad returns the value of key, or loads and sets it if missing. The concurrent
+// loads of the same key are merged into one, the failed loads are not cached.
+func (m *Memory[K, V]) GetOrLoad(ctx context.Context, key K, load LoadFunc[K, V]) (V, error) {
+	if v, ok := m.get(key); ok {
+		m.observeGet(true)
+		return v, nil
+	}
+	m.observeGet(false)
+	return m.group.Do(ctx, key, func(ctx context.Context) (V, error) {
+		// another load may have finished between get and Do
+		if v, ok := m.get(key); ok {
+			return v, nil
+		}
+		start := m.clock.Now()
+		v, err := load(ctx, key)
+		m.loads.Add(1)
+		if err != nil {
+			m.loadErrors.Add(1)
+		}
+		if m.opts.observer != nil {
+			m.opts.observer.ObserveLoad(m.clock.Since(start), err)
+		}
+		if err == nil {
+			m.set(key, v, 0)
+		}
+		return v, err
+	})
+}
+
+// Len returns the number of entries including the expired ones not removed yet.
+func (m *Memory[K, V]) Len() int {
+	n := 0
+	for _, s := range m.shards {
+		s.mu.Lock()
+		n += s.lru.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Purge deletes all the entries.
+func (m *Memory[K, V]) Purge() {
+	for _, s := range m.shards {
+		s.mu.Lock()
+		s.lru.Init()
+		clear(s.items)
+		s.mu.Unlock()
+	}
+}
+
+// DeleteExpired deletes the expired entries, which are otherwise removed when got or
+// evicted, e.g. to release the memory periodically.
+func (m *Memory[K, V]) DeleteExpired() {
+	now := m.clock.Now()
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for e := s.lru.Front(); e != nil; {
+			next := e.Next()
+			if it := e.Value.(*entry[K, V]); it.expired(now) {
+				m.remove(s, e, true)
+			}
+			e = next
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Stats returns the statistics of the cache.
+func (m *Memory[K, V]) Stats() Stats {
+	return Stats{
+		Hits:       m.hits.Load(),
+		Misses:     m.misses.Load(),
+		Loads:      m.loads.Load(),
+		LoadErrors: m.loadErrors.Load(),
+		Evictions:  m.evictions.Load(),
+		Len:        m.Len(),
+	}
+}
+
+func (m *Memory[K, V]) shard(key K) *shard[K, V] {
+	return m.shards[maphash.Comparable(m.seed, key)%uint64(len(m.shards))]
+}
+
+func (m *Memory[K, V]) get(key K) (V, bool) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var zero V
+	e, ok := s.items[key]
+	if !ok {
+		return zero, false
+	}
+	it := e.Value.(*entry[K, V])
+	if it.expired(m.clock.Now()) {
+		m.remove(s, e, true)
+		return zero, false
+	}
+	s.lru.MoveToFront(e)
+	return it.value, true
+}
+
+func (m *Memory[K, V]) set(key K, value V, ttl time.Duration) {
+	if ttl == 0 {
+		ttl = m.opts.ttl
+	}
+	now := m.clock.Now()
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = now.Add(ttl)
+	}
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.items[key]; ok {
+		it := e.Value.(*entry[K, V])
+		it.value, it.expireAt = value, expireAt
+		s.lru.MoveToFront(e)
+		return
+	}
+	// 新 key 加入前先清理尾部过期的 key，再按容量淘汰最久未使用的
+	for e := s.lru.Back(); e != nil; e = s.lru.Back() {
+		expired := e.Value.(*entry[K, V]).expired(now)
+		if !expired && s.lru.Len() < s.capacity {
+			break
+		}
+		m.remove(s, e, expired)
+	}
+	s.items[key] = s.lru.PushFront(&entry[K, V]{key: key, value: value, expireAt: expireAt})
+}
+
+// remove removes the entry of the shard locked.
+func (m *Memory[K, V]) remove(s *shard[K, V], e *list.Element, expired bool) {
+	s.lru.Remove(e)
+	delete(s.items, e.Value.(*entry[K, V]).key)
+	m.evictions.Add(1)
+	if m.opts.observer != nil {
+		m.opts.observer.ObserveEvict(expired)
+	}
+}
+
+func (m *Memory[K, V]) observeGet(hit bool) {
+	if hit {
+		m.hits.Add(1)
+	} else {
+		m.misses.Add(1)
+	}
+	if m.opts.observer != nil {
+		m.opts.observer.ObserveGet(hit)
+	}
+}
+
+func (e *entry[K, V]) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
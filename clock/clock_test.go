@@ -0,0 +1,87 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFakeTimer tests that a timer fires only when advanced past its deadline.
+func TestFakeTimer(t *testing.T) {
+	f := NewFake(time.Time{})
+	start := f.Now()
+	timer := f.NewTimer(time.Second)
+
+	f.Advance(500 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+	f.Advance(500 * time.Millisecond)
+	select {
+	case ts := <-timer.C():
+		if ts != start.Add(time.Second) {
+			t.Errorf("fired at %v", ts)
+		}
+	default:
+		t.Fatal("timer not fired")
+	}
+	if f.Since(start) != time.Second {
+		t.Errorf("Since = %v", f.Since(start))
+	}
+
+	timer.Reset(time.Second)
+	if !timer.Stop() || timer.Stop() {
+		t.Error("Expected Stop true once")
+	}
+	if f.Waiters() != 0 {
+		t.Errorf("Waiters = %d", f.Waiters())
+	}
+}
+
+// TestFakeTicker tests the ticks of a ticker.
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(time.Time{})
+	ticker := f.NewTicker(time.Minute)
+	var ticks int
+	for i := 0; i < 3; i++ {
+		f.Advance(time.Minute)
+		select {
+		case <-ticker.C():
+			ticks++
+		default:
+		}
+	}
+	ticker.Stop()
+	f.Advance(time.Minute)
+	if ticks != 3 || len(ticker.C()) != 0 {
+		t.Errorf("ticks = %d", ticks)
+	}
+}
+
+// TestFakeSleep tests that Sleep returns after another goroutine advances the clock.
+func TestFakeSleep(t *testing.T) {
+	f := NewFake(time.Time{})
+	done := make(chan struct{})
+	go func() {
+		f.Sleep(time.Hour)
+		close(done)
+	}()
+	f.BlockUntil(1)
+	f.Advance(time.Hour)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Sleep not returned")
+	}
+}
+
+// TestReal tests the real clock.
+func TestReal(t *testing.T) {
+	c := OrReal(nil)
+	timer := c.NewTimer(time.Millisecond)
+	<-timer.C()
+	if c.Since(c.Now()) > time.Second {
+		t.Error("unexpected real clock")
+	}
+}
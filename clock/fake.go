@@ -0,0 +1,203 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a clock only moving by Advance and Set. The timers, tickers and
+// sleeps fire when the clock passes their deadline.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	period   time.Duration // > 0 for tickers
+	c        chan time.Time
+	active   bool
+}
+
+// NewFake creates a fake clock at now, the zero time means 2024-01-01 UTC.
+func NewFake(now time.Time) *Fake {
+	if now.IsZero() {
+		now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &Fake{now: now}
+}
+
+// Now implements Clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since implements Clock.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After implements Clock.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Sleep implements Clock, blocking until another goroutine advances the clock by d.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// NewTimer implements Clock.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return &fakeTimer{f: f, w: f.add(d, 0)}
+}
+
+// NewTicker implements Clock.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return &fakeTicker{f: f, w: f.add(d, d)}
+}
+
+// Advance moves the clock forward by d and fires the due timers in order.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the clock to t, firing the due timers in deadline order.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for {
+		w := f.nextDue(t)
+		if w == nil {
+			break
+		}
+		f.now = w.deadline
+		select {
+		case w.c <- w.deadline:
+		default: // 与 time.Ticker 一致，接收方跟不上时丢弃
+		}
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			f.remove(w)
+		}
+	}
+	if t.After(f.now) {
+		f.now = t
+	}
+}
+
+// Waiters returns the number of active timers, tickers and sleeps, useful to
+// wait in tests until a goroutine is blocked on the clock before advancing it.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil blocks until there are at least n active waiters.
+func (f *Fake) BlockUntil(n int) {
+	for f.Waiters() < n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (f *Fake) add(d, period time.Duration) *fakeWaiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{deadline: f.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	if d <= 0 && period == 0 {
+		w.c <- f.now
+		return w
+	}
+	w.active = true
+	f.waiters = append(f.waiters, w)
+	return w
+}
+
+func (f *Fake) nextDue(t time.Time) *fakeWaiter {
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+	})
+	if len(f.waiters) > 0 && !f.waiters[0].deadline.After(t) {
+		return f.waiters[0]
+	}
+	return nil
+}
+
+// remove removes w, reporting whether it was active. f.mu must be held.
+func (f *Fake) remove(w *fakeWaiter) bool {
+	if !w.active {
+		return false
+	}
+	w.active = false
+	for i, x := range f.waiters {
+		if x == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (f *Fake) reset(w *fakeWaiter, d, period time.Duration) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	active := f.remove(w)
+	w.deadline = f.now.Add(d)
+	w.period = period
+	if d <= 0 && period == 0 {
+		select {
+		case w.c <- f.now:
+		default:
+		}
+		return active
+	}
+	w.active = true
+	f.waiters = append(f.waiters, w)
+	return active
+}
+
+type fakeTimer struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.w.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	return t.f.remove(t.w)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	return t.f.reset(t.w, d, 0)
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.c }
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.f.reset(t.w, d, d)
+}
@@ -0,0 +1,180 @@
+/*
+concurrent 并发任务组，errgroup 风格，支持并发上限、panic 转错误、首个错误取消或收集全部错误、单任务超时
+*/
+
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// PanicError is the error converted from a panic of a task.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error implements error.
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("concurrent: task panic: %v\n%s", p.Value, p.Stack)
+}
+
+// Option is the option of Group.
+type Option func(*Group)
+
+// Limit sets the max number of tasks running at the same time, Go blocks when
+// reached. n <= 0 means no limit.
+func Limit(n int) Option {
+	return func(g *Group) {
+		if n > 0 {
+			g.sem = make(chan struct{}, n)
+		}
+	}
+}
+
+// CollectAll keeps running the other tasks after an error and makes Wait
+// return all the errors joined. By default the first error cancels the group
+// context and is the only one returned.
+func CollectAll() Option {
+	return func(g *Group) {
+		g.collectAll = true
+	}
+}
+
+// Timeout sets the default deadline of each task context.
+func Timeout(d time.Duration) Option {
+	return func(g *Group) {
+		g.timeout = d
+	}
+}
+
+// TaskOption is the option of a task.
+type TaskOption func(*task)
+
+// TaskTimeout overrides the deadline of the task context.
+func TaskTimeout(d time.Duration) TaskOption {
+	return func(t *task) {
+		t.timeout = d
+	}
+}
+
+type task struct {
+	timeout time.Duration
+}
+
+// Group runs tasks concurrently and waits for them.
+type Group struct {
+	ctx        context.Context
+	cancel     context.CancelCauseFunc
+	sem        chan struct{}
+	collectAll bool
+	timeout    time.Duration
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// New creates a Group with ctx as the parent of the task contexts.
+func New(ctx context.Context, opts ...Option) *Group {
+	g := &Group{}
+	for _, o := range opts {
+		o(g)
+	}
+	g.ctx, g.cancel = context.WithCancelCause(ctx)
+	return g
+}
+
+// WithContext creates a Group and returns the context canceled on the first
+// error (unless CollectAll) or when Wait returns.
+func WithContext(ctx context.Context, opts ...Option) (*Group, context.Context) {
+	g := New(ctx, opts...)
+	return g, g.ctx
+}
+
+// Go runs fn in a new goroutine, blocking while the limit is reached.
+func (g *Group) Go(fn func(ctx context.Context) error, opts ...TaskOption) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(fn, opts)
+}
+
+// TryGo runs fn only if the limit is not reached, reporting whether it started.
+func (g *Group) TryGo(fn func(ctx context.Context) error, opts ...TaskOption) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(fn, opts)
+	return true
+}
+
+func (g *Group) start(fn func(ctx context.Context) error, opts []TaskOption) {
+	t := task{timeout: g.timeout}
+	for _, o := range opts {
+		o(&t)
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+		if err := g.run(fn, t); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+func (g *Group) run(fn func(ctx context.Context) error, t task) (err error) {
+	ctx := g.ctx
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
+
+func (g *Group) fail(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.collectAll {
+		g.errs = append(g.errs, err)
+		return
+	}
+	if len(g.errs) == 0 {
+		g.errs = append(g.errs, err)
+		g.cancel(err)
+	}
+}
+
+// Wait waits for all the tasks and returns the first error, or all errors
+// joined with CollectAll.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.collectAll {
+		return errors.Join(g.errs...)
+	}
+	if len(g.errs) > 0 {
+		return g.errs[0]
+	}
+	return nil
+}
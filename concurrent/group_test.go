@@ -0,0 +1,97 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestLimit tests that no more than the limit tasks run at the same time.
+func TestLimit(t *testing.T) {
+	g := New(context.Background(), Limit(2))
+	var running, peak atomic.Int32
+	for i := 0; i < 10; i++ {
+		g.Go(func(ctx context.Context) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if peak.Load() > 2 {
+		t.Errorf("peak = %d, want <= 2", peak.Load())
+	}
+}
+
+// TestFirstError tests that the first error cancels the other tasks.
+func TestFirstError(t *testing.T) {
+	errFirst := errors.New("first")
+	g, ctx := WithContext(context.Background())
+	g.Go(func(ctx context.Context) error { return errFirst })
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err := g.Wait(); err != errFirst {
+		t.Errorf("Wait = %v, want first error", err)
+	}
+	if !errors.Is(context.Cause(ctx), errFirst) {
+		t.Errorf("Expected cause first error, got %v", context.Cause(ctx))
+	}
+}
+
+// TestCollectAll tests that all errors are returned, including panics.
+func TestCollectAll(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	g := New(context.Background(), CollectAll())
+	g.Go(func(ctx context.Context) error { return errA })
+	g.Go(func(ctx context.Context) error { return errB })
+	g.Go(func(ctx context.Context) error { panic("boom") })
+	g.Go(func(ctx context.Context) error { return nil })
+
+	err := g.Wait()
+	var pe *PanicError
+	if !errors.Is(err, errA) || !errors.Is(err, errB) || !errors.As(err, &pe) {
+		t.Errorf("Wait = %v, want a, b and panic", err)
+	}
+	if pe != nil && pe.Value != "boom" {
+		t.Errorf("panic value = %v", pe.Value)
+	}
+}
+
+// TestTaskTimeout tests the per task deadline.
+func TestTaskTimeout(t *testing.T) {
+	g := New(context.Background(), Timeout(time.Hour))
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, TaskTimeout(10*time.Millisecond))
+	if err := g.Wait(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait = %v, want deadline exceeded", err)
+	}
+}
+
+// TestTryGo tests that TryGo does not start a task over the limit.
+func TestTryGo(t *testing.T) {
+	g := New(context.Background(), Limit(1))
+	release := make(chan struct{})
+	if !g.TryGo(func(ctx context.Context) error { <-release; return nil }) {
+		t.Fatal("Expected first task started")
+	}
+	if g.TryGo(func(ctx context.Context) error { return nil }) {
+		t.Error("Expected second task rejected")
+	}
+	close(release)
+	_ = g.Wait()
+}
@@ -0,0 +1,204 @@
+/*
+config 配置加载，支持 YAML/JSON/TOML 文件、环境变量和命令行参数合并，输出结构体和插件配置
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/plugin"
+	"github.com/baisiyi/go-kits/validate"
+)
+
+// PluginsKey is the key of the plugin configs, whose value is type => name => config.
+const PluginsKey = "plugins"
+
+// Config is the merged config tree, the keys are case sensitive and the paths are
+// separated by dots like "database.dsn.host".
+type Config struct {
+	data map[string]any
+}
+
+// New creates a Config of the data.
+func New(data map[string]any) *Config {
+	if data == nil {
+		data = make(map[string]any)
+	}
+	return &Config{data: data}
+}
+
+// Map returns the underlying config tree.
+func (c *Config) Map() map[string]any {
+	return c.data
+}
+
+// Get returns the value of the path, nil and false if not found.
+func (c *Config) Get(path string) (any, bool) {
+	var v any = c.data
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// String returns the value of the path formatted as string, "" if not found.
+func (c *Config) String(path string) string {
+	v, ok := c.Get(path)
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// Set sets the value of the path, creating the intermediate maps.
+func (c *Config) Set(path string, value any) {
+	keys := strings.Split(path, ".")
+	m := c.data
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+// Sub returns the config of the path, an empty config if not found or not a map.
+func (c *Config) Sub(path string) *Config {
+	v, _ := c.Get(path)
+	m, _ := v.(map[string]any)
+	return New(m)
+}
+
+// Decode decodes the whole config into v by the yaml tags of v.
+func (c *Config) Decode(v any) error {
+	return c.DecodeKey("", v)
+}
+
+// DecodeKey decodes the config of the path into v by the yaml tags of v, v is left
+// unchanged if the path is not found. The string values are converted to the types of
+// the fields like the plain scalars of yaml, e.g. "8080" to an int field, and kept as
+// is for the string fields. A struct v is validated by validate.Default afterwards.
+func (c *Config) DecodeKey(path string, v any) error {
+	value, ok := c.Get(path)
+	if !ok {
+		return nil
+	}
+	node, err := toNode(value)
+	if err != nil {
+		return fmt.Errorf("config: encode %s error: %w", path, err)
+	}
+	if err := node.Decode(v); err != nil {
+		return fmt.Errorf("config: decode %s error: %w", path, err)
+	}
+	if !isStruct(v) {
+		return nil
+	}
+	if err := validate.Struct(v); err != nil {
+		return fmt.Errorf("config: validate %s error: %w", path, err)
+	}
+	return nil
+}
+
+// toNode converts the config tree into a yaml node, the strings as untagged plain
+// scalars so they are resolved by the types of the fields.
+func toNode(v any) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case string:
+		n := &yaml.Node{Kind: yaml.ScalarNode, Value: v}
+		if n.ShortTag() == "!!null" {
+			// e.g. "~" and "null" are kept as strings
+			n.Tag = "!!str"
+		}
+		return n, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			value, err := toNode(v[k])
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, value)
+		}
+		return n, nil
+	case []any:
+		return seqNode(v)
+	case []map[string]any:
+		return seqNode(v)
+	default:
+		n := &yaml.Node{}
+		if err := n.Encode(v); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+}
+
+func seqNode[T any](vs []T) (*yaml.Node, error) {
+	n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, e := range vs {
+		value, err := toNode(e)
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, value)
+	}
+	return n, nil
+}
+
+// isStruct returns whether v points to a struct.
+func isStruct(v any) bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t != nil && t.Kind() == reflect.Struct
+}
+
+// Plugins returns the plugin configs under the plugins key.
+func (c *Config) Plugins() (plugin.Config, error) {
+	cfg := make(plugin.Config)
+	if err := c.DecodeKey(PluginsKey, &cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// merge merges src into dst recursively, the values of src take precedence.
+func merge(dst, src map[string]any) {
+	for k, v := range src {
+		sm, ok := v.(map[string]any)
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dm, ok := dst[k].(map[string]any)
+		if !ok {
+			dm = make(map[string]any)
+			dst[k] = dm
+		}
+		merge(dm, sm)
+	}
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baisiyi/go-kits/validate"
+)
+
+type serverConfig struct {
+	Host    string        `yaml:"host"`
+	Port    int           `yaml:"port"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type appConfig struct {
+	Name     string       `yaml:"name"`
+	Server   serverConfig `yaml:"server"`
+	Password string       `yaml:"password"`
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoadFormats tests loading the same config from the supported formats.
+func TestLoadFormats(t *testing.T) {
+	files := map[string]string{
+		"app.yaml": "name: demo\nserver:\n  host: 0.0.0.0\n  port: 8080\n  timeout: 3s\n",
+		"app.json": `{"name": "demo", "server": {"host": "0.0.0.0", "port": 8080, "timeout": "3s"}}`,
+		"app.toml": "name = \"demo\"\n[server]\nhost = \"0.0.0.0\"\nport = 8080\ntimeout = \"3s\"\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			c, err := LoadFile(writeFile(t, name, content))
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+			var cfg appConfig
+			if err := c.Decode(&cfg); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			want := appConfig{Name: "demo", Server: serverConfig{Host: "0.0.0.0", Port: 8080, Timeout: 3 * time.Second}}
+			if cfg != want {
+				t.Errorf("Decode() = %+v, want %+v", cfg, want)
+			}
+		})
+	}
+
+	if _, err := LoadFile(writeFile(t, "app.ini", "name=demo")); err == nil {
+		t.Error("LoadFile() expected error for unknown format")
+	}
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadFile() expected error for missing file")
+	}
+	if _, err := Load(WithOptionalFile(filepath.Join(t.TempDir(), "missing.yaml"))); err != nil {
+		t.Errorf("Load() optional file error = %v", err)
+	}
+}
+
+// TestLoadOverrides tests the precedence of the files, the environment variables and the flags.
+func TestLoadOverrides(t *testing.T) {
+	t.Setenv("CONFIG_TEST_PASSWORD", "secret")
+	t.Setenv("APP_SERVER__PORT", "9090")
+	t.Setenv("APP_SERVER__HOST", "10.0.0.1")
+
+	base := writeFile(t, "base.yaml", `
+name: demo
+password: ${CONFIG_TEST_PASSWORD}
+server:
+  host: 0.0.0.0
+  port: 8080
+  timeout: ${CONFIG_TEST_TIMEOUT:-5s}
+`)
+	local := writeFile(t, "local.json", `{"name": "local"}`)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("server.host", "", "")
+	fs.Int("server.port", 0, "")
+	if err := fs.Parse([]string{"-server.host=127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(WithFile(base), WithFile(local), WithEnvPrefix("APP"), WithFlagSet(fs))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var cfg appConfig
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := appConfig{
+		Name:     "local",
+		Password: "secret",
+		Server:   serverConfig{Host: "127.0.0.1", Port: 9090, Timeout: 5 * time.Second},
+	}
+	if cfg != want {
+		t.Errorf("Decode() = %+v, want %+v", cfg, want)
+	}
+	if port, _ := c.Get("server.port"); port != "9090" {
+		t.Errorf("Get(server.port) = %#v, want the string 9090", port)
+	}
+
+	c, err = Load(WithFile(base), WithExpandEnv(false))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := c.String("password"); got != "${CONFIG_TEST_PASSWORD}" {
+		t.Errorf("String(password) = %q, want unexpanded", got)
+	}
+}
+
+// TestOverrideStrings tests that the expanded values and the overrides are kept as
+// strings for the string fields, and can not change the structure of the file.
+func TestOverrideStrings(t *testing.T) {
+	t.Setenv("CONFIG_TEST_PASSWORD", "x\nname: evil")
+	t.Setenv("APP_SERVER__HOST", "0x10")
+	t.Setenv("APP_SERVER__PORT", "8080")
+	base := writeFile(t, "base.yaml", "name: demo\npassword: ${CONFIG_TEST_PASSWORD}\nserver:\n  host: ${CONFIG_TEST_HOST:-null}\n")
+
+	c, err := Load(WithFile(base), WithEnvPrefix("APP"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var cfg appConfig
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := appConfig{Name: "demo", Password: "x\nname: evil", Server: serverConfig{Host: "0x10", Port: 8080}}
+	if cfg != want {
+		t.Errorf("Decode() = %+v, want %+v", cfg, want)
+	}
+
+	c.Set("server.host", "null")
+	if err := c.DecodeKey("server", &cfg.Server); err != nil || cfg.Server.Host != "null" {
+		t.Errorf("DecodeKey() = %+v, %v", cfg.Server, err)
+	}
+}
+
+// TestDecodeValidate tests that the decoded structs are validated.
+func TestDecodeValidate(t *testing.T) {
+	c := New(nil)
+	c.Set("server.port", 0)
+	var sc struct {
+		Port int `yaml:"port" validate:"min=1"`
+	}
+	if err := c.DecodeKey("server", &sc); err == nil {
+		t.Error("DecodeKey() expected validation error")
+	}
+	c.Set("server.port", "80")
+	if err := c.DecodeKey("server", &sc); err != nil || sc.Port != 80 {
+		t.Errorf("DecodeKey() = %+v, %v", sc, err)
+	}
+
+	// the rules of the validator set by validate.SetDefault apply
+	prev := validate.Default()
+	defer validate.SetDefault(prev)
+	v := validate.New()
+	if err := v.RegisterRule("even", func(fl validate.FieldLevel) bool { return fl.Field().Int()%2 == 0 }, nil); err != nil {
+		t.Fatal(err)
+	}
+	validate.SetDefault(v)
+	var even struct {
+		Port int `yaml:"port" validate:"even"`
+	}
+	c.Set("server.port", "81")
+	if err := c.DecodeKey("server", &even); err == nil {
+		t.Error("DecodeKey() expected the error of the default validator")
+	}
+}
+
+// TestExpandEnv tests the environment variable expansion.
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("CONFIG_TEST_USER", "root")
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"${CONFIG_TEST_USER}", "root"},
+		{"${CONFIG_TEST_UNSET}", ""},
+		{"${CONFIG_TEST_UNSET:-guest}", "guest"},
+		{"${CONFIG_TEST_USER:-guest}", "root"},
+		{"pa$$word $HOME", "pa$$word $HOME"},
+	}
+	for _, tt := range tests {
+		if got := ExpandEnv(tt.in); got != tt.want {
+			t.Errorf("ExpandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestConfigAccess tests Get, Set, Sub and DecodeKey.
+func TestConfigAccess(t *testing.T) {
+	c := New(nil)
+	c.Set("server.port", 8080)
+	c.Set("server.host", "localhost")
+
+	if v, ok := c.Get("server.port"); !ok || v != 8080 {
+		t.Errorf("Get(server.port) = %v, %v", v, ok)
+	}
+	if _, ok := c.Get("server.port.x"); ok {
+		t.Error("Get(server.port.x) expected not found")
+	}
+	if got := c.Sub("server").String("host"); got != "localhost" {
+		t.Errorf("Sub(server).String(host) = %q", got)
+	}
+	var sc serverConfig
+	if err := c.DecodeKey("server", &sc); err != nil || sc.Port != 8080 {
+		t.Errorf("DecodeKey() = %+v, %v", sc, err)
+	}
+	sc = serverConfig{Port: 1}
+	if err := c.DecodeKey("missing", &sc); err != nil || sc.Port != 1 {
+		t.Errorf("DecodeKey(missing) = %+v, %v", sc, err)
+	}
+}
+
+// TestPlugins tests converting the plugins key to plugin.Config.
+func TestPlugins(t *testing.T) {
+	c, err := LoadFile(writeFile(t, "app.toml", `
+[plugins.log.default]
+level = "info"
+
+[plugins.database.default.main]
+driver = "sqlite"
+`))
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	cfg, err := c.Plugins()
+	if err != nil {
+		t.Fatalf("Plugins() error = %v", err)
+	}
+	node, ok := cfg["log"]["default"]
+	if !ok {
+		t.Fatalf("Plugins() = %v, missing log-default", cfg)
+	}
+	var lc struct {
+		Level string `yaml:"level"`
+	}
+	if err := node.Decode(&lc); err != nil || lc.Level != "info" {
+		t.Errorf("Decode() = %+v, %v", lc, err)
+	}
+	if _, ok := cfg["database"]["default"]; !ok {
+		t.Errorf("Plugins() = %v, missing database-default", cfg)
+	}
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the format of a config file.
+type Format string
+
+// The supported formats, detected by the file extension.
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+type source struct {
+	path     string
+	optional bool
+}
+
+// Loader loads and merges the config sources in order: the files, the environment
+// variables and the command-line flags, the later ones take precedence. The values of
+// the environment variables and the flags are kept as strings, and converted to the
+// types of the fields by Decode.
+type Loader struct {
+	files     []source
+	envPrefix string
+	flagSet   *flag.FlagSet
+	expandEnv bool
+}
+
+// Option is the option of Loader.
+type Option func(*Loader)
+
+// WithFile adds a config file, the files are merged in the order added.
+func WithFile(path string) Option {
+	return func(l *Loader) {
+		l.files = append(l.files, source{path: path})
+	}
+}
+
+// WithOptionalFile adds a config file which is skipped if not exists, e.g. a local override.
+func WithOptionalFile(path string) Option {
+	return func(l *Loader) {
+		l.files = append(l.files, source{path: path, optional: true})
+	}
+}
+
+// WithEnvPrefix enables the environment variable overrides. PREFIX_A__B overrides the
+// path a.b, the double underscore separates the levels and the keys are lower cased,
+// e.g. APP_DATABASE__MAX_OPEN_CONNS overrides database.max_open_conns.
+func WithEnvPrefix(prefix string) Option {
+	return func(l *Loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithFlagSet enables the command-line flag overrides. Each flag set on the command
+// line whose name is a path like "server.port" overrides the path, so flags must be
+// parsed before Load.
+func WithFlagSet(fs *flag.FlagSet) Option {
+	return func(l *Loader) {
+		l.flagSet = fs
+	}
+}
+
+// WithExpandEnv sets whether ${VAR} and ${VAR:-default} in the string values of the
+// files are expanded by the environment variables, default as true. The values are
+// expanded after parsing, so a variable can not change the structure of the file.
+func WithExpandEnv(expand bool) Option {
+	return func(l *Loader) {
+		l.expandEnv = expand
+	}
+}
+
+// Load loads the config by the options.
+func Load(opts ...Option) (*Config, error) {
+	l := &Loader{expandEnv: true}
+	for _, o := range opts {
+		o(l)
+	}
+	return l.Load()
+}
+
+// LoadFile loads the config file with the environment variables expanded.
+func LoadFile(path string) (*Config, error) {
+	return Load(WithFile(path))
+}
+
+// Load loads and merges all the sources.
+func (l *Loader) Load() (*Config, error) {
+	c := New(nil)
+	for _, f := range l.files {
+		data, err := l.readFile(f)
+		if err != nil {
+			return nil, err
+		}
+		merge(c.data, data)
+	}
+	if l.envPrefix != "" {
+		prefix := l.envPrefix + "_"
+		for _, kv := range os.Environ() {
+			k, v, _ := strings.Cut(kv, "=")
+			if !strings.HasPrefix(k, prefix) || len(k) == len(prefix) {
+				continue
+			}
+			path := strings.ReplaceAll(strings.ToLower(k[len(prefix):]), "__", ".")
+			c.Set(path, v)
+		}
+	}
+	if l.flagSet != nil {
+		l.flagSet.Visit(func(f *flag.Flag) {
+			c.Set(f.Name, f.Value.String())
+		})
+	}
+	return c, nil
+}
+
+func (l *Loader) readFile(f source) (map[string]any, error) {
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		if f.optional && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("config: read %s error: %w", f.path, err)
+	}
+	format, err := formatOf(f.path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := Parse(content, format)
+	if err != nil {
+		return nil, fmt.Errorf("config: parse %s error: %w", f.path, err)
+	}
+	if l.expandEnv {
+		expandValues(data)
+	}
+	return data, nil
+}
+
+func formatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".toml":
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("config: unknown format of %s", path)
+	}
+}
+
+// Parse parses the content in the format into a config tree.
+func Parse(content []byte, format Format) (map[string]any, error) {
+	data := make(map[string]any)
+	var err error
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(content, &data)
+	case FormatJSON:
+		err = json.Unmarshal(content, &data)
+	case FormatTOML:
+		err = toml.Unmarshal(content, &data)
+	default:
+		return nil, fmt.Errorf("config: format %s not supported", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces ${VAR} by the environment variable and ${VAR:-default} by
+// default if VAR is unset or empty. The other $ are kept as is.
+func ExpandEnv(s string) string {
+	return envPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := envPattern.FindStringSubmatch(m)
+		if v := os.Getenv(sub[1]); v != "" {
+			return v
+		}
+		return sub[3]
+	})
+}
+
+// expandValues expands the environment variables in the string values of v in place.
+func expandValues(v any) any {
+	switch v := v.(type) {
+	case string:
+		return ExpandEnv(v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = expandValues(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = expandValues(e)
+		}
+	case []map[string]any:
+		// the arrays of tables of TOML
+		for _, e := range v {
+			expandValues(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,114 @@
+/*
+contextkit 定义各组件共用的请求上下文字段（request id、trace id、租户、操作者）
+*/
+
+package contextkit
+
+import (
+	"context"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// Canonical log field keys of the context values.
+const (
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+	FieldTenant    = "tenant"
+	FieldActor     = "actor"
+)
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	traceIDKey
+	tenantKey
+	actorKey
+)
+
+func init() {
+	log.RegisterContextFieldExtractor("contextkit", LogFields)
+}
+
+// Generator generates request/trace ids, default as NewULID.
+var Generator = NewULID
+
+// WithRequestID returns a copy of ctx carrying the request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request id of ctx, "" if not set.
+func RequestID(ctx context.Context) string {
+	return stringValue(ctx, requestIDKey)
+}
+
+// EnsureRequestID returns ctx with a request id, generating one if absent.
+func EnsureRequestID(ctx context.Context) (context.Context, string) {
+	if id := RequestID(ctx); id != "" {
+		return ctx, id
+	}
+	id := Generator()
+	return WithRequestID(ctx, id), id
+}
+
+// WithTraceID returns a copy of ctx carrying the trace id.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey, id)
+}
+
+// TraceID returns the trace id of ctx, "" if not set.
+func TraceID(ctx context.Context) string {
+	return stringValue(ctx, traceIDKey)
+}
+
+// WithTenant returns a copy of ctx carrying the tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// Tenant returns the tenant of ctx, "" if not set.
+func Tenant(ctx context.Context) string {
+	return stringValue(ctx, tenantKey)
+}
+
+// WithActor returns a copy of ctx carrying the actor (user or service performing the request).
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns the actor of ctx, "" if not set.
+func Actor(ctx context.Context) string {
+	return stringValue(ctx, actorKey)
+}
+
+// LogFields returns the non-empty context values as log fields.
+func LogFields(ctx context.Context) []log.Field {
+	if ctx == nil {
+		return nil
+	}
+	var fields []log.Field
+	for _, kv := range []struct {
+		key string
+		val string
+	}{
+		{FieldRequestID, RequestID(ctx)},
+		{FieldTraceID, TraceID(ctx)},
+		{FieldTenant, Tenant(ctx)},
+		{FieldActor, Actor(ctx)},
+	} {
+		if kv.val != "" {
+			fields = append(fields, log.String(kv.key, kv.val))
+		}
+	}
+	return fields
+}
+
+func stringValue(ctx context.Context, key ctxKey) string {
+	if ctx == nil {
+		return ""
+	}
+	v, _ := ctx.Value(key).(string)
+	return v
+}
@@ -0,0 +1,151 @@
+package contextkit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestContextValues tests setting and getting the context values.
+func TestContextValues(t *testing.T) {
+	ctx := context.Background()
+	if RequestID(ctx) != "" || TraceID(ctx) != "" || Tenant(ctx) != "" || Actor(ctx) != "" {
+		t.Fatal("Expected empty values for background context")
+	}
+
+	ctx = WithRequestID(ctx, "req")
+	ctx = WithTraceID(ctx, "trace")
+	ctx = WithTenant(ctx, "tenant")
+	ctx = WithActor(ctx, "actor")
+
+	if RequestID(ctx) != "req" {
+		t.Errorf("RequestID = %q, want req", RequestID(ctx))
+	}
+	if TraceID(ctx) != "trace" {
+		t.Errorf("TraceID = %q, want trace", TraceID(ctx))
+	}
+	if Tenant(ctx) != "tenant" {
+		t.Errorf("Tenant = %q, want tenant", Tenant(ctx))
+	}
+	if Actor(ctx) != "actor" {
+		t.Errorf("Actor = %q, want actor", Actor(ctx))
+	}
+	if fields := LogFields(ctx); len(fields) != 4 {
+		t.Errorf("Expected 4 log fields, got %d", len(fields))
+	}
+}
+
+// TestEnsureRequestID tests that a request id is generated only when absent.
+func TestEnsureRequestID(t *testing.T) {
+	ctx, id := EnsureRequestID(context.Background())
+	if id == "" || RequestID(ctx) != id {
+		t.Fatalf("Expected generated request id, got %q", id)
+	}
+	_, id2 := EnsureRequestID(ctx)
+	if id2 != id {
+		t.Errorf("Expected existing request id %q, got %q", id, id2)
+	}
+}
+
+// TestNewULID tests the format and monotonicity of ULIDs.
+func TestNewULID(t *testing.T) {
+	now := time.Now()
+	prev := newULID(now)
+	for i := 0; i < 1000; i++ {
+		id := newULID(now)
+		if len(id) != 26 {
+			t.Fatalf("ULID length = %d, want 26", len(id))
+		}
+		if id <= prev {
+			t.Fatalf("ULID not monotonic: %s <= %s", id, prev)
+		}
+		prev = id
+	}
+}
+
+// TestEncodeULID tests encoding against a known value.
+func TestEncodeULID(t *testing.T) {
+	var id [16]byte
+	if got := encodeULID(id); got != "00000000000000000000000000" {
+		t.Errorf("encodeULID(zero) = %s", got)
+	}
+	for i := range id {
+		id[i] = 0xff
+	}
+	if got := encodeULID(id); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("encodeULID(max) = %s", got)
+	}
+}
+
+// TestHTTPPropagation tests injecting and extracting http headers.
+func TestHTTPPropagation(t *testing.T) {
+	ctx := WithTenant(WithRequestID(context.Background(), "req"), "t1")
+	h := http.Header{}
+	InjectHTTPHeader(ctx, h)
+	if h.Get(HeaderRequestID) != "req" || h.Get(HeaderTenant) != "t1" {
+		t.Fatalf("Unexpected header: %v", h)
+	}
+	if h.Get(HeaderTraceID) != "" {
+		t.Errorf("Empty values should not be injected")
+	}
+
+	got := ExtractHTTPHeader(context.Background(), h)
+	if RequestID(got) != "req" || Tenant(got) != "t1" {
+		t.Errorf("Unexpected extracted values: %q %q", RequestID(got), Tenant(got))
+	}
+}
+
+// TestMetadataPropagation tests injecting and extracting grpc metadata.
+func TestMetadataPropagation(t *testing.T) {
+	md := map[string][]string{}
+	InjectMetadata(WithTraceID(context.Background(), "trace"), md)
+	if v := md["x-trace-id"]; len(v) != 1 || v[0] != "trace" {
+		t.Fatalf("Unexpected metadata: %v", md)
+	}
+	if TraceID(ExtractMetadata(context.Background(), md)) != "trace" {
+		t.Errorf("Expected trace id to be extracted")
+	}
+}
+
+// TestHTTPMiddleware tests that the middleware generates and echoes the request id.
+func TestHTTPMiddleware(t *testing.T) {
+	var got string
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestID(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got == "" || w.Header().Get(HeaderRequestID) != got {
+		t.Errorf("Expected generated request id echoed, got %q / %q", got, w.Header().Get(HeaderRequestID))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(HeaderRequestID, "incoming")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got != "incoming" {
+		t.Errorf("RequestID = %q, want incoming", got)
+	}
+}
+
+// TestTransport tests that the transport injects headers into outgoing requests.
+func TestTransport(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(HeaderRequestID)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+	req, _ := http.NewRequestWithContext(WithRequestID(context.Background(), "out"), http.MethodGet, srv.URL, nil)
+	rsp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	rsp.Body.Close()
+	if got != "out" {
+		t.Errorf("X-Request-Id = %q, want out", got)
+	}
+}
@@ -0,0 +1,95 @@
+package contextkit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// HTTP header names used for propagation.
+const (
+	HeaderRequestID = "X-Request-Id"
+	HeaderTraceID   = "X-Trace-Id"
+	HeaderTenant    = "X-Tenant-Id"
+	HeaderActor     = "X-Actor"
+)
+
+type propagated struct {
+	header string
+	get    func(context.Context) string
+	set    func(context.Context, string) context.Context
+}
+
+var propagatedValues = []propagated{
+	{HeaderRequestID, RequestID, WithRequestID},
+	{HeaderTraceID, TraceID, WithTraceID},
+	{HeaderTenant, Tenant, WithTenant},
+	{HeaderActor, Actor, WithActor},
+}
+
+// InjectHTTPHeader writes the context values into the http header.
+func InjectHTTPHeader(ctx context.Context, h http.Header) {
+	for _, p := range propagatedValues {
+		if v := p.get(ctx); v != "" {
+			h.Set(p.header, v)
+		}
+	}
+}
+
+// ExtractHTTPHeader returns a copy of ctx carrying the values found in the http header.
+func ExtractHTTPHeader(ctx context.Context, h http.Header) context.Context {
+	for _, p := range propagatedValues {
+		if v := h.Get(p.header); v != "" {
+			ctx = p.set(ctx, v)
+		}
+	}
+	return ctx
+}
+
+// InjectMetadata writes the context values into grpc metadata.
+// metadata.MD is a map[string][]string, so it can be passed directly.
+func InjectMetadata(ctx context.Context, md map[string][]string) {
+	for _, p := range propagatedValues {
+		if v := p.get(ctx); v != "" {
+			md[strings.ToLower(p.header)] = []string{v}
+		}
+	}
+}
+
+// ExtractMetadata returns a copy of ctx carrying the values found in grpc metadata.
+func ExtractMetadata(ctx context.Context, md map[string][]string) context.Context {
+	for _, p := range propagatedValues {
+		if vs := md[strings.ToLower(p.header)]; len(vs) > 0 && vs[0] != "" {
+			ctx = p.set(ctx, vs[0])
+		}
+	}
+	return ctx
+}
+
+// HTTPMiddleware extracts the context values from the incoming request,
+// generates a request id if absent and echoes it in the response header.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ExtractHTTPHeader(r.Context(), r.Header)
+		ctx, id := EnsureRequestID(ctx)
+		w.Header().Set(HeaderRequestID, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Transport is a http.RoundTripper injecting the context values into outgoing requests.
+type Transport struct {
+	// Base is the underlying RoundTripper, default as http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	r = r.Clone(r.Context())
+	InjectHTTPHeader(r.Context(), r.Header)
+	return base.RoundTrip(r)
+}
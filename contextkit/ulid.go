@@ -0,0 +1,82 @@
+package contextkit
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// crockford is the Crockford's Base32 alphabet used by ULID.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	ulidMu      sync.Mutex
+	lastULIDMs  uint64
+	lastEntropy [10]byte
+)
+
+// NewULID generates a 26 chars ULID (https://github.com/ulid/spec).
+// IDs generated in the same millisecond are monotonically increasing.
+func NewULID() string {
+	return newULID(time.Now())
+}
+
+func newULID(now time.Time) string {
+	ms := uint64(now.UnixMilli())
+
+	ulidMu.Lock()
+	if ms <= lastULIDMs {
+		// 同一毫秒内（或时钟回拨）沿用上次的时间戳并对随机部分加一，保证单调递增
+		ms = lastULIDMs
+		incEntropy(&lastEntropy)
+	} else {
+		lastULIDMs = ms
+		_, _ = rand.Read(lastEntropy[:])
+	}
+	entropy := lastEntropy
+	ulidMu.Unlock()
+
+	var id [16]byte
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	copy(id[6:], entropy[:])
+	return encodeULID(id)
+}
+
+func incEntropy(b *[10]byte) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return
+		}
+	}
+}
+
+// encodeULID encodes 128 bits into 26 base32 chars, 5 bits per char.
+func encodeULID(id [16]byte) string {
+	dst := make([]byte, 26)
+	var (
+		acc  uint32
+		bits uint
+		pos  = 25
+	)
+	// 从低位开始每 5 bit 编码一个字符，最高位字符只使用 3 bit
+	for i := len(id) - 1; i >= 0; i-- {
+		acc |= uint32(id[i]) << bits
+		bits += 8
+		for bits >= 5 && pos >= 0 {
+			dst[pos] = crockford[acc&0x1f]
+			acc >>= 5
+			bits -= 5
+			pos--
+		}
+	}
+	if pos >= 0 {
+		dst[pos] = crockford[acc&0x1f]
+	}
+	return string(dst)
+}
@@ -0,0 +1,108 @@
+/*
+cryptox 常用加密原语：AES-GCM 加解密、HMAC 签名、常量时间比较和密码哈希（bcrypt/argon2id）
+*/
+
+package cryptox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrDecrypt is returned when the ciphertext is malformed, tampered or the key is wrong.
+var ErrDecrypt = errors.New("cryptox: decrypt failed")
+
+// GenerateKey returns n random bytes, 32 for AES-256.
+func GenerateKey(n int) ([]byte, error) {
+	key := make([]byte, n)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("cryptox: read random error: %w", err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cryptox: invalid key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt encrypts plaintext with AES-GCM, the key is 16, 24 or 32 bytes.
+// A random nonce is prepended to the result. aad is the optional additional
+// data authenticated but not encrypted, e.g. the row id of an encrypted column.
+func Encrypt(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("cryptox: read nonce error: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Decrypt decrypts the result of Encrypt with the same key and aad.
+func Decrypt(key, ciphertext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, ErrDecrypt
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// EncryptString encrypts s and encodes the result with base64 url encoding.
+func EncryptString(key []byte, s string) (string, error) {
+	ct, err := Encrypt(key, []byte(s), nil)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(ct), nil
+}
+
+// DecryptString decrypts the result of EncryptString.
+func DecryptString(key []byte, s string) (string, error) {
+	ct, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	pt, err := Decrypt(key, ct, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(pt), nil
+}
+
+// Sign returns the HMAC-SHA256 of data.
+func Sign(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// Verify reports whether sig is the HMAC-SHA256 of data, in constant time.
+func Verify(key, data, sig []byte) bool {
+	return hmac.Equal(Sign(key, data), sig)
+}
+
+// Equal compares a and b in constant time, e.g. for tokens and api keys.
+func Equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
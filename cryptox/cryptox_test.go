@@ -0,0 +1,100 @@
+package cryptox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestEncrypt tests the AES-GCM round trip and the tamper detection.
+func TestEncrypt(t *testing.T) {
+	key, _ := GenerateKey(32)
+	ct, err := Encrypt(key, []byte("secret"), []byte("row-1"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	pt, err := Decrypt(key, ct, []byte("row-1"))
+	if err != nil || string(pt) != "secret" {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+	if _, err := Decrypt(key, ct, []byte("row-2")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for wrong aad, got %v", err)
+	}
+	ct[len(ct)-1] ^= 1
+	if _, err := Decrypt(key, ct, []byte("row-1")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for tampered data, got %v", err)
+	}
+	if _, err := Encrypt([]byte("short"), nil, nil); err == nil {
+		t.Error("Expected error for invalid key")
+	}
+
+	s, _ := EncryptString(key, "hello")
+	if got, err := DecryptString(key, s); err != nil || got != "hello" {
+		t.Errorf("DecryptString = %q, %v", got, err)
+	}
+}
+
+// TestSign tests the HMAC signing.
+func TestSign(t *testing.T) {
+	sig := Sign([]byte("k"), []byte("data"))
+	if !Verify([]byte("k"), []byte("data"), sig) {
+		t.Error("Expected signature verified")
+	}
+	if Verify([]byte("k2"), []byte("data"), sig) || bytes.Equal(sig, Sign([]byte("k"), []byte("data2"))) {
+		t.Error("Expected signature mismatch")
+	}
+	if !Equal("token", "token") || Equal("token", "tokem") {
+		t.Error("Equal mismatch")
+	}
+}
+
+// TestPassword tests both algorithms and the rehash check.
+func TestPassword(t *testing.T) {
+	argon, err := NewHasher(PasswordConfig{Argon2: Argon2Config{Memory: 1024, Time: 1}})
+	if err != nil {
+		t.Fatalf("NewHasher failed: %v", err)
+	}
+	bc, _ := NewHasher(PasswordConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+
+	for _, h := range []*Hasher{argon, bc} {
+		hash, err := h.Hash("p@ss")
+		if err != nil {
+			t.Fatalf("Hash failed: %v", err)
+		}
+		if err := h.Verify("p@ss", hash); err != nil {
+			t.Errorf("%s Verify failed: %v", h.cfg.Algorithm, err)
+		}
+		if err := h.Verify("wrong", hash); !errors.Is(err, ErrMismatch) {
+			t.Errorf("%s Expected ErrMismatch, got %v", h.cfg.Algorithm, err)
+		}
+		if h.NeedsRehash(hash) {
+			t.Errorf("%s Expected no rehash", h.cfg.Algorithm)
+		}
+	}
+
+	// 迁移场景：bcrypt 的哈希仍可校验，但需要按当前配置重新哈希
+	old, _ := bc.Hash("p@ss")
+	if err := argon.Verify("p@ss", old); err != nil || !argon.NeedsRehash(old) {
+		t.Errorf("Expected bcrypt hash verified and rehash needed, got %v", err)
+	}
+	// the malformed hashes return ErrInvalidHash instead of panicking in argon2
+	for _, hash := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$",
+		"$argon2id$v=19$m=1024,t=1,p=0$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$$a2V5a2V5",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=1024$c2FsdHNhbHQ$a2V5a2V5",
+		"$2a$04$short",
+	} {
+		if err := argon.Verify("p@ss", hash); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("Verify(%s) = %v, want ErrInvalidHash", hash, err)
+		}
+	}
+	if _, err := NewHasher(PasswordConfig{Algorithm: "md5"}); err == nil {
+		t.Error("Expected error for unknown algorithm")
+	}
+}
@@ -0,0 +1,177 @@
+package cryptox
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var (
+	// ErrMismatch is returned when the password does not match the hash.
+	ErrMismatch = errors.New("cryptox: password mismatch")
+	// ErrInvalidHash is returned when the hash is malformed, e.g. truncated or with zero parameters.
+	ErrInvalidHash = errors.New("cryptox: invalid hash")
+)
+
+// PasswordConfig is the config of the password hashing.
+type PasswordConfig struct {
+	// Algorithm is bcrypt or argon2id, default as argon2id.
+	Algorithm string `yaml:"algorithm" mapstructure:"algorithm"`
+	// BcryptCost is the bcrypt cost, default as bcrypt.DefaultCost.
+	BcryptCost int          `yaml:"bcrypt_cost" mapstructure:"bcrypt_cost"`
+	Argon2     Argon2Config `yaml:"argon2" mapstructure:"argon2"`
+}
+
+// Argon2Config is the argon2id parameters, defaults follow the OWASP recommendation.
+type Argon2Config struct {
+	// Time is the number of passes, default as 3.
+	Time uint32 `yaml:"time" mapstructure:"time"`
+	// Memory is the memory in KiB, default as 64MB.
+	Memory uint32 `yaml:"memory" mapstructure:"memory"`
+	// Threads is the parallelism, default as 2.
+	Threads uint8 `yaml:"threads" mapstructure:"threads"`
+	// KeyLen is the length of the hash, default as 32.
+	KeyLen uint32 `yaml:"key_len" mapstructure:"key_len"`
+	// SaltLen is the length of the salt, default as 16.
+	SaltLen uint32 `yaml:"salt_len" mapstructure:"salt_len"`
+}
+
+func (c *PasswordConfig) setDefaults() {
+	if c.Algorithm == "" {
+		c.Algorithm = AlgorithmArgon2id
+	}
+	if c.BcryptCost == 0 {
+		c.BcryptCost = bcrypt.DefaultCost
+	}
+	a := &c.Argon2
+	if a.Time == 0 {
+		a.Time = 3
+	}
+	if a.Memory == 0 {
+		a.Memory = 64 * 1024
+	}
+	if a.Threads == 0 {
+		a.Threads = 2
+	}
+	if a.KeyLen == 0 {
+		a.KeyLen = 32
+	}
+	if a.SaltLen == 0 {
+		a.SaltLen = 16
+	}
+}
+
+// Hasher hashes and verifies passwords.
+type Hasher struct {
+	cfg PasswordConfig
+}
+
+// NewHasher creates a Hasher by cfg.
+func NewHasher(cfg PasswordConfig) (*Hasher, error) {
+	cfg.setDefaults()
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("cryptox: bcrypt cost %d out of range", cfg.BcryptCost)
+		}
+	case AlgorithmArgon2id:
+	default:
+		return nil, fmt.Errorf("cryptox: unknown password algorithm %s", cfg.Algorithm)
+	}
+	return &Hasher{cfg: cfg}, nil
+}
+
+// Hash hashes the password. The result is self describing, bcrypt's modular
+// crypt format or the PHC string of argon2id, so the parameters can change later.
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.cfg.Algorithm == AlgorithmBcrypt {
+		b, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("cryptox: bcrypt error: %w", err)
+		}
+		return string(b), nil
+	}
+	a := h.cfg.Argon2
+	salt := make([]byte, a.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("cryptox: read salt error: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.Memory, a.Time, a.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify checks the password against a hash of either algorithm, returning
+// ErrMismatch if it does not match.
+func (h *Hasher) Verify(password, hash string) error {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		p, salt, key, err := parseArgon2(hash)
+		if err != nil {
+			return err
+		}
+		got := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return ErrMismatch
+		}
+		return nil
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidHash, err)
+	}
+	return nil
+}
+
+// NeedsRehash reports whether the hash was made with another algorithm or
+// parameters than the current config, so it should be rehashed after a successful login.
+func (h *Hasher) NeedsRehash(hash string) bool {
+	if h.cfg.Algorithm == AlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.cfg.BcryptCost
+	}
+	p, _, key, err := parseArgon2(hash)
+	if err != nil {
+		return true
+	}
+	a := h.cfg.Argon2
+	return p.Time != a.Time || p.Memory != a.Memory || p.Threads != a.Threads || uint32(len(key)) != a.KeyLen
+}
+
+// parseArgon2 parses the PHC string of argon2id, the errors wrap ErrInvalidHash.
+func parseArgon2(hash string) (p Argon2Config, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return p, nil, nil, fmt.Errorf("%w: not an argon2id hash", ErrInvalidHash)
+	}
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("%w: unsupported argon2 version", ErrInvalidHash)
+	}
+	// argon2.IDKey panics with the zero parameters
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil ||
+		p.Memory == 0 || p.Time == 0 || p.Threads == 0 {
+		return p, nil, nil, fmt.Errorf("%w: invalid argon2id params", ErrInvalidHash)
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(salt) == 0 {
+		return p, nil, nil, fmt.Errorf("%w: invalid argon2id salt", ErrInvalidHash)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("%w: invalid argon2id key", ErrInvalidHash)
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,331 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// ErrCircuitOpen 熔断期间 SQL 不再执行，直接返回该错误
+var ErrCircuitOpen = errors.New("database: circuit open")
+
+const (
+	breakerPluginName  = "go-kits:breaker"
+	breakerInstanceKey = "go-kits:breaker_probe"
+)
+
+// BreakerConfig 熔断配置，ConsecutiveFailures 或 FailureRate 大于 0 时启用
+type BreakerConfig struct {
+	// ConsecutiveFailures 连续失败次数达到该值时熔断，0 表示不按连续失败熔断
+	ConsecutiveFailures int `mapstructure:"consecutive_failures" yaml:"consecutive_failures"`
+	// FailureRate 统计窗口内的失败率（0~1）达到该值且请求数不少于 MinRequests 时熔断，0 表示不按失败率熔断
+	FailureRate float64 `mapstructure:"failure_rate" yaml:"failure_rate"`
+	// MinRequests 按失败率熔断时窗口内的最少请求数，默认 20
+	MinRequests int `mapstructure:"min_requests" yaml:"min_requests"`
+	// Window 失败率的统计窗口，默认 10s
+	Window time.Duration `mapstructure:"window" yaml:"window"`
+	// OpenDuration 熔断持续时间，之后进入半开状态放行探测请求，默认 30s
+	OpenDuration time.Duration `mapstructure:"open_duration" yaml:"open_duration"`
+	// HalfOpenProbes 半开状态放行的探测请求数，全部成功后恢复，任一失败重新熔断，默认 1
+	HalfOpenProbes int `mapstructure:"half_open_probes" yaml:"half_open_probes"`
+}
+
+func (c *BreakerConfig) enabled() bool {
+	return c.ConsecutiveFailures > 0 || c.FailureRate > 0
+}
+
+func (c *BreakerConfig) setDefaults() {
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = 30 * time.Second
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = 1
+	}
+}
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	// BreakerClosed 正常放行
+	BreakerClosed BreakerState = iota
+	// BreakerOpen 熔断中，SQL 直接返回 ErrCircuitOpen
+	BreakerOpen
+	// BreakerHalfOpen 半开，放行 HalfOpenProbes 个探测请求
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerStats 熔断器统计，未配置熔断时为零值
+type BreakerStats struct {
+	State BreakerState
+	// ConsecutiveFailures 当前的连续失败次数
+	ConsecutiveFailures int
+	// Opens 累计熔断次数
+	Opens int64
+	// Rejected 累计被熔断拒绝的 SQL 数
+	Rejected int64
+}
+
+// BreakerPlugin GORM 熔断插件，数据库不可用（连接失败、超时、连接数打满等）时按连续失败次数或失败率熔断，
+// 熔断期间 SQL 直接返回 ErrCircuitOpen，避免大量协程阻塞在连接等待上；OpenDuration 后放行探测请求，
+// 探测成功后恢复。状态变化通过日志记录
+type BreakerPlugin struct {
+	cfg    BreakerConfig
+	logger log.Logger
+
+	mu          sync.Mutex
+	state       BreakerState
+	consecutive int
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+	opens       int64
+	rejected    int64
+}
+
+// NewBreakerPlugin 创建熔断插件，通过 db.Use 注册
+func NewBreakerPlugin(cfg BreakerConfig, logger log.Logger) *BreakerPlugin {
+	cfg.setDefaults()
+	if logger == nil {
+		logger = log.GetDefaultLogger()
+	}
+	return &BreakerPlugin{cfg: cfg, logger: logger}
+}
+
+// Name 实现 gorm.Plugin 接口
+func (p *BreakerPlugin) Name() string {
+	return breakerPluginName
+}
+
+// Initialize 实现 gorm.Plugin 接口，在各类操作前后注册回调
+func (p *BreakerPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		op     string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("breaker:before_"+h.op, p.before); err != nil {
+			return err
+		}
+		if err := h.after("breaker:after_"+h.op, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *BreakerPlugin) before(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	probe, err := p.enter()
+	if err != nil {
+		// 设置错误后 GORM 不再执行 SQL
+		_ = db.AddError(err)
+		return
+	}
+	db.InstanceSet(breakerInstanceKey, probe)
+}
+
+func (p *BreakerPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(breakerInstanceKey)
+	if !ok {
+		return
+	}
+	probe, _ := v.(bool)
+	p.leave(probe, db.Error)
+}
+
+// Stats 返回熔断器统计，p 为 nil 时返回零值
+func (p *BreakerPlugin) Stats() BreakerStats {
+	if p == nil {
+		return BreakerStats{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return BreakerStats{State: p.state, ConsecutiveFailures: p.consecutive, Opens: p.opens, Rejected: p.rejected}
+}
+
+// enter 判断是否放行一条 SQL，probe 表示为半开状态的探测请求，执行结束后需调用 leave
+func (p *BreakerPlugin) enter() (probe bool, err error) {
+	if p == nil {
+		return false, nil
+	}
+	now := time.Now()
+	p.mu.Lock()
+	from := p.state
+	if p.state == BreakerOpen && now.Sub(p.openedAt) >= p.cfg.OpenDuration {
+		p.state = BreakerHalfOpen
+		p.probes, p.successes = 0, 0
+	}
+	switch {
+	case p.state == BreakerClosed:
+	case p.state == BreakerHalfOpen && p.probes < p.cfg.HalfOpenProbes:
+		p.probes++
+		probe = true
+	default:
+		p.rejected++
+		err = ErrCircuitOpen
+	}
+	to := p.state
+	p.mu.Unlock()
+	p.logTransition(from, to, "open duration elapsed")
+	return probe, err
+}
+
+// leave 记录 enter 放行的 SQL 的执行结果
+func (p *BreakerPlugin) leave(probe bool, err error) {
+	if p == nil {
+		return
+	}
+	failure, ignore := breakerOutcome(err)
+	now := time.Now()
+	p.mu.Lock()
+	from := p.state
+	var reason string
+	switch {
+	case probe && p.state == BreakerHalfOpen:
+		p.probes--
+		switch {
+		case ignore:
+		case failure:
+			reason = "probe failed: " + err.Error()
+			p.open(now)
+		default:
+			p.successes++
+			if p.successes >= p.cfg.HalfOpenProbes {
+				reason = "probes succeeded"
+				p.state = BreakerClosed
+				p.consecutive = 0
+				p.windowStart, p.requests, p.failures = now, 0, 0
+			}
+		}
+	case !probe && p.state == BreakerClosed && !ignore:
+		reason = p.record(now, failure, err)
+	}
+	to := p.state
+	p.mu.Unlock()
+	p.logTransition(from, to, reason)
+}
+
+// record 在关闭状态下记录一次执行结果，达到阈值时熔断并返回原因
+func (p *BreakerPlugin) record(now time.Time, failure bool, err error) string {
+	if now.Sub(p.windowStart) >= p.cfg.Window {
+		p.windowStart, p.requests, p.failures = now, 0, 0
+	}
+	p.requests++
+	if !failure {
+		p.consecutive = 0
+		return ""
+	}
+	p.failures++
+	p.consecutive++
+	switch {
+	case p.cfg.ConsecutiveFailures > 0 && p.consecutive >= p.cfg.ConsecutiveFailures:
+		p.open(now)
+		return fmt.Sprintf("%d consecutive failures, last: %v", p.consecutive, err)
+	case p.cfg.FailureRate > 0 && p.requests >= p.cfg.MinRequests &&
+		float64(p.failures)/float64(p.requests) >= p.cfg.FailureRate:
+		p.open(now)
+		return fmt.Sprintf("failure rate %d/%d, last: %v", p.failures, p.requests, err)
+	}
+	return ""
+}
+
+func (p *BreakerPlugin) open(now time.Time) {
+	p.state = BreakerOpen
+	p.openedAt = now
+	p.opens++
+}
+
+func (p *BreakerPlugin) logTransition(from, to BreakerState, reason string) {
+	if from == to {
+		return
+	}
+	logf := p.logger.Infof
+	if to == BreakerOpen {
+		logf = p.logger.Warnf
+	}
+	logf("[DB_BREAKER] State: %s -> %s | Reason: %s", from, to, reason)
+}
+
+// 数据库不可用时返回的 MySQL 错误码：1040 连接数过多，1053 服务端关闭中，1203 用户连接数超限
+var breakerMySQLErrors = map[uint16]bool{1040: true, 1053: true, 1203: true}
+
+// 数据库不可用时返回的 PostgreSQL 错误码前缀：08 连接异常，53 资源不足，57P 管理员操作（关闭、重启）
+var breakerPgCodes = []string{"08", "53", "57P"}
+
+// breakerOutcome 判断 SQL 执行结果是否为数据库不可用的失败：记录不存在、唯一键冲突、语法错误等
+// 数据库已正常响应的错误不计为失败；调用方取消的 SQL 不计入统计（ignore）。
+// MySQL、PostgreSQL 之外的驱动返回的错误均计为失败
+func breakerOutcome(err error) (failure, ignore bool) {
+	switch {
+	case err == nil:
+		return false, false
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrCircuitOpen):
+		return false, true
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, gorm.ErrMissingWhereClause),
+		errors.Is(err, gorm.ErrPrimaryKeyRequired), errors.Is(err, gorm.ErrModelValueRequired),
+		errors.Is(err, gorm.ErrInvalidData), errors.Is(err, gorm.ErrInvalidField),
+		errors.Is(err, gorm.ErrInvalidValue), errors.Is(err, gorm.ErrDuplicatedKey),
+		errors.Is(err, gorm.ErrForeignKeyViolated), errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return false, false
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return breakerMySQLErrors[myErr.Number], false
+	}
+	var pgErr sqlStateError
+	if errors.As(err, &pgErr) {
+		for _, prefix := range breakerPgCodes {
+			if strings.HasPrefix(pgErr.SQLState(), prefix) {
+				return true, false
+			}
+		}
+		return false, false
+	}
+	return true, false
+}
+
+// breaker 返回已注册的熔断插件，未配置熔断时为 nil
+func (c *Client) breaker() *BreakerPlugin {
+	p, _ := c.db.Plugins[breakerPluginName].(*BreakerPlugin)
+	return p
+}
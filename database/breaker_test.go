@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// TestBreakerPlugin tests that the breaker opens on the consecutive failures, rejects the
+// statements while open and closes after a successful probe.
+func TestBreakerPlugin(t *testing.T) {
+	logger := &mockLogger{}
+	c, err := newClient(&DBConfig{
+		Driver:  DriverSQLite,
+		DSN:     Connect{Name: filepath.Join(t.TempDir(), "breaker.db")},
+		Breaker: BreakerConfig{ConsecutiveFailures: 3, OpenDuration: 50 * time.Millisecond},
+	}, logger)
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+	if err := c.GetDB(ctx).AutoMigrate(&repoUser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// not found is not a failure of the database
+	for i := 0; i < 5; i++ {
+		var u repoUser
+		if err := c.GetDB(ctx).First(&u).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("First = %v", err)
+		}
+	}
+	missing := func() error {
+		var n int64
+		return c.GetDB(ctx).Raw("SELECT count(*) FROM missing").Scan(&n).Error
+	}
+	for i := 0; i < 3; i++ {
+		if err := missing(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected the query failed, got %v", err)
+		}
+	}
+	if err := c.GetDB(ctx).Create(&repoUser{Name: "a"}).Error; !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if _, err := Exec(ctx, c, "DELETE FROM repo_user", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen for Exec, got %v", err)
+	}
+	s := c.Stats().Breaker
+	if s.State != BreakerOpen || s.Opens != 1 || s.Rejected < 2 {
+		t.Errorf("Unexpected breaker stats %+v", s)
+	}
+	if !strings.Contains(fmt.Sprint(logger.warns), "[DB_BREAKER]") {
+		t.Errorf("Expected the transition logged, got %v", logger.warns)
+	}
+
+	// the failed probe opens the breaker again
+	time.Sleep(60 * time.Millisecond)
+	if err := missing(); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the probe executed and failed, got %v", err)
+	}
+	if s := c.Stats().Breaker; s.State != BreakerOpen || s.Opens != 2 {
+		t.Errorf("Expected the breaker opened again, got %+v", s)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := c.GetDB(ctx).Create(&repoUser{Name: "a"}).Error; err != nil {
+		t.Fatalf("Expected the probe succeeded, got %v", err)
+	}
+	if s := c.Stats().Breaker; s.State != BreakerClosed || s.ConsecutiveFailures != 0 {
+		t.Errorf("Expected the breaker closed, got %+v", s)
+	}
+	var n int64
+	if err := c.GetDB(ctx).Model(&repoUser{}).Count(&n).Error; err != nil || n != 1 {
+		t.Errorf("Count = %d, %v", n, err)
+	}
+}
+
+// TestBreakerFailureRate tests opening on the failure rate in the window and the half-open probes.
+func TestBreakerFailureRate(t *testing.T) {
+	p := NewBreakerPlugin(BreakerConfig{FailureRate: 0.5, MinRequests: 4, OpenDuration: time.Millisecond, HalfOpenProbes: 2}, &mockLogger{})
+	fail := errors.New("connection refused")
+	run := func(err error) error {
+		probe, rejected := p.enter()
+		if rejected != nil {
+			return rejected
+		}
+		p.leave(probe, err)
+		return nil
+	}
+
+	for _, err := range []error{nil, fail, nil} {
+		_ = run(err)
+	}
+	if s := p.Stats(); s.State != BreakerClosed {
+		t.Fatalf("Expected closed below MinRequests, got %+v", s)
+	}
+	_ = run(fail)
+	if err := run(nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected open at 2/4 failures, got %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	p1, err1 := p.enter()
+	p2, err2 := p.enter()
+	if _, err := p.enter(); !p1 || !p2 || err1 != nil || err2 != nil || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected 2 probes allowed, got %v %v %v", err1, err2, err)
+	}
+	p.leave(p1, nil)
+	if s := p.Stats(); s.State != BreakerHalfOpen {
+		t.Errorf("Expected half open until all probes succeed, got %+v", s)
+	}
+	p.leave(p2, nil)
+	if s := p.Stats(); s.State != BreakerClosed || s.Opens != 1 {
+		t.Errorf("Expected closed, got %+v", s)
+	}
+}
+
+// TestBreakerOutcome tests which errors are counted as the failures of the database.
+func TestBreakerOutcome(t *testing.T) {
+	tests := []struct {
+		err     error
+		failure bool
+		ignore  bool
+	}{
+		{nil, false, false},
+		{gorm.ErrRecordNotFound, false, false},
+		{context.Canceled, false, true},
+		{context.DeadlineExceeded, true, false},
+		{&mysql.MySQLError{Number: 1062}, false, false},
+		{&mysql.MySQLError{Number: 1040}, true, false},
+		{&pgconn.PgError{Code: "23505"}, false, false},
+		{&pgconn.PgError{Code: "57P01"}, true, false},
+		{fmt.Errorf("query: %w", errors.New("dial tcp: connection refused")), true, false},
+	}
+	for _, tt := range tests {
+		failure, ignore := breakerOutcome(tt.err)
+		if failure != tt.failure || ignore != tt.ignore {
+			t.Errorf("breakerOutcome(%v) = %v, %v, want %v, %v", tt.err, failure, ignore, tt.failure, tt.ignore)
+		}
+	}
+}
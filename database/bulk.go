@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBulkBatchSize 批量写入默认每批的行数
+const DefaultBulkBatchSize = 1000
+
+// BulkOption 批量写入配置选项
+type BulkOption func(*bulkOptions)
+
+type bulkOptions struct {
+	batchSize       int
+	conflictColumns []string
+	updateColumns   []string
+	stopOnError     bool
+}
+
+// WithBatchSize 设置每批的行数，默认 DefaultBulkBatchSize；每批为一条 INSERT 语句，
+// 过大时可能超过 MySQL max_allowed_packet 或 PostgreSQL 的 65535 个参数限制
+func WithBatchSize(n int) BulkOption {
+	return func(o *bulkOptions) {
+		o.batchSize = n
+	}
+}
+
+// WithConflictColumns 设置 BulkUpsert 判断冲突的唯一键列（ON CONFLICT (...)），默认为主键；
+// MySQL 的 ON DUPLICATE KEY UPDATE 对所有唯一键生效，忽略该设置
+func WithConflictColumns(columns ...string) BulkOption {
+	return func(o *bulkOptions) {
+		o.conflictColumns = columns
+	}
+}
+
+// WithUpdateColumns 设置 BulkUpsert 冲突时更新的列，默认为主键和创建时间之外的所有列
+func WithUpdateColumns(columns ...string) BulkOption {
+	return func(o *bulkOptions) {
+		o.updateColumns = columns
+	}
+}
+
+// WithStopOnError 某一批失败时不再写入后续批次，默认继续写入并汇总各批的错误
+func WithStopOnError() BulkOption {
+	return func(o *bulkOptions) {
+		o.stopOnError = true
+	}
+}
+
+// BulkResult 批量写入的结果
+type BulkResult struct {
+	// Chunks 已执行的批次数，包括失败的批次
+	Chunks int
+	// RowsAffected 成功批次的影响行数之和；MySQL 的 ON DUPLICATE KEY UPDATE 中更新的行计为 2
+	RowsAffected int64
+	// Failed 失败的批次，失败批次中的行均未写入
+	Failed []*ChunkError
+}
+
+// ChunkError 某一批写入失败的错误，Offset 为该批第一行在 items 中的下标
+type ChunkError struct {
+	Index  int
+	Offset int
+	Size   int
+	Err    error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("database: bulk chunk %d (rows %d-%d) error: %v", e.Index, e.Offset, e.Offset+e.Size-1, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// BulkInsert 将 items 按批插入，每批在独立的事务中执行（在 Transact 中调用时为 SAVEPOINT），
+// 失败的批次整体回滚，不影响其他批次；自增主键等回填到 items 中。
+// 返回的错误为各失败批次的 *ChunkError 合并后的错误，失败详情见 BulkResult.Failed
+func BulkInsert[T any](ctx context.Context, c *Client, items []T, opts ...BulkOption) (*BulkResult, error) {
+	o := newBulkOptions(opts)
+	return bulkWrite(ctx, c, items, o, nil)
+}
+
+// BulkUpsert 与 BulkInsert 相同地按批写入，主键或唯一键冲突时更新已有记录：
+// MySQL 使用 ON DUPLICATE KEY UPDATE，PostgreSQL 和 SQLite 使用 ON CONFLICT ... DO UPDATE
+func BulkUpsert[T any](ctx context.Context, c *Client, items []T, opts ...BulkOption) (*BulkResult, error) {
+	o := newBulkOptions(opts)
+	onConflict, err := upsertClause[T](c, o)
+	if err != nil {
+		return nil, err
+	}
+	return bulkWrite(ctx, c, items, o, onConflict)
+}
+
+// BulkInsert 按批插入模型 T 的记录，见 BulkInsert
+func (r *Repository[T]) BulkInsert(ctx context.Context, items []T, opts ...BulkOption) (*BulkResult, error) {
+	return BulkInsert(ctx, r.client, items, opts...)
+}
+
+// BulkUpsert 按批插入或更新模型 T 的记录，见 BulkUpsert
+func (r *Repository[T]) BulkUpsert(ctx context.Context, items []T, opts ...BulkOption) (*BulkResult, error) {
+	return BulkUpsert(ctx, r.client, items, opts...)
+}
+
+func newBulkOptions(opts []BulkOption) *bulkOptions {
+	o := &bulkOptions{batchSize: DefaultBulkBatchSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.batchSize <= 0 {
+		o.batchSize = DefaultBulkBatchSize
+	}
+	return o
+}
+
+// upsertClause 按配置生成 ON CONFLICT 子句，列名校验为模型的字段，避免外部传入的列名拼入 SQL
+func upsertClause[T any](c *Client, o *bulkOptions) (*clause.OnConflict, error) {
+	stmt := &gorm.Statement{DB: c.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("database: parse model error: %w", err)
+	}
+	columns := func(names []string) ([]string, error) {
+		dbNames := make([]string, 0, len(names))
+		for _, name := range names {
+			f := stmt.Schema.LookUpField(name)
+			if f == nil || f.DBName == "" {
+				return nil, fmt.Errorf("database: unknown column %s of %s", name, stmt.Schema.Name)
+			}
+			dbNames = append(dbNames, f.DBName)
+		}
+		return dbNames, nil
+	}
+
+	onConflict := &clause.OnConflict{}
+	conflict := o.conflictColumns
+	if len(conflict) == 0 {
+		conflict = stmt.Schema.PrimaryFieldDBNames
+	}
+	names, err := columns(conflict)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: name})
+	}
+	if len(o.updateColumns) == 0 {
+		// 主键、创建时间等之外的列由 GORM 按写入的列生成
+		onConflict.UpdateAll = true
+		return onConflict, nil
+	}
+	names, err = columns(o.updateColumns)
+	if err != nil {
+		return nil, err
+	}
+	onConflict.DoUpdates = clause.AssignmentColumns(names)
+	return onConflict, nil
+}
+
+func bulkWrite[T any](ctx context.Context, c *Client, items []T, o *bulkOptions, onConflict *clause.OnConflict) (*BulkResult, error) {
+	var (
+		res  = &BulkResult{}
+		errs []error
+	)
+	for offset := 0; offset < len(items); offset += o.batchSize {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		chunk := items[offset:min(offset+o.batchSize, len(items))]
+		var rows int64
+		err := c.Transact(ctx, func(tx *gorm.DB) error {
+			if onConflict != nil {
+				tx = tx.Clauses(*onConflict)
+			}
+			r := tx.Create(&chunk)
+			rows = r.RowsAffected
+			return r.Error
+		})
+		res.Chunks++
+		if err != nil {
+			ce := &ChunkError{Index: res.Chunks - 1, Offset: offset, Size: len(chunk), Err: err}
+			res.Failed = append(res.Failed, ce)
+			errs = append(errs, ce)
+			if o.stopOnError {
+				break
+			}
+			continue
+		}
+		res.RowsAffected += rows
+	}
+	return res, errors.Join(errs...)
+}
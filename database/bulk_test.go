@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type bulkItem struct {
+	ID    int64
+	Code  string `gorm:"uniqueIndex"`
+	Name  string
+	Stock int
+}
+
+// TestBulkInsert tests that the items are inserted in chunks and the ids are filled back.
+func TestBulkInsert(t *testing.T) {
+	c := newRepoClient(t)
+	ctx := context.Background()
+	if err := c.DB(ctx).AutoMigrate(&bulkItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	items := make([]bulkItem, 25)
+	for i := range items {
+		items[i] = bulkItem{Code: fmt.Sprintf("c%d", i), Name: "n"}
+	}
+	res, err := BulkInsert(ctx, c, items, WithBatchSize(10))
+	if err != nil {
+		t.Fatalf("BulkInsert failed: %v", err)
+	}
+	if res.Chunks != 3 || res.RowsAffected != 25 || len(res.Failed) != 0 {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	for i, item := range items {
+		if item.ID == 0 {
+			t.Fatalf("Expected id filled back for item %d", i)
+		}
+	}
+	var n int64
+	c.DB(ctx).Model(&bulkItem{}).Count(&n)
+	if n != 25 {
+		t.Errorf("Expected 25 rows, got %d", n)
+	}
+}
+
+// TestBulkInsertChunkError tests that a failed chunk is rolled back and reported, and the
+// other chunks are written unless WithStopOnError.
+func TestBulkInsertChunkError(t *testing.T) {
+	c := newRepoClient(t)
+	ctx := context.Background()
+	if err := c.DB(ctx).AutoMigrate(&bulkItem{}); err != nil {
+		t.Fatal(err)
+	}
+	newItems := func() []bulkItem {
+		items := make([]bulkItem, 6)
+		for i := range items {
+			items[i] = bulkItem{Code: fmt.Sprintf("c%d", i)}
+		}
+		// duplicate code in the second chunk
+		items[3].Code = "c2"
+		return items
+	}
+
+	res, err := BulkInsert(ctx, c, newItems(), WithBatchSize(2), WithStopOnError())
+	if err == nil || res.Chunks != 2 || len(res.Failed) != 1 {
+		t.Fatalf("Expected the second chunk failed and stopped, got %+v, %v", res, err)
+	}
+	var ce *ChunkError
+	if !errors.As(err, &ce) || ce.Index != 1 || ce.Offset != 2 || ce.Size != 2 {
+		t.Errorf("Unexpected chunk error %+v", ce)
+	}
+	var n int64
+	c.DB(ctx).Model(&bulkItem{}).Count(&n)
+	if n != 2 {
+		t.Errorf("Expected only the first chunk written, got %d rows", n)
+	}
+
+	c.DB(ctx).Where("1 = 1").Delete(&bulkItem{})
+	res, err = BulkInsert(ctx, c, newItems(), WithBatchSize(2))
+	if err == nil || res.Chunks != 3 || len(res.Failed) != 1 || res.RowsAffected != 4 {
+		t.Fatalf("Expected the other chunks written, got %+v, %v", res, err)
+	}
+	c.DB(ctx).Model(&bulkItem{}).Count(&n)
+	if n != 4 {
+		t.Errorf("Expected 4 rows, got %d", n)
+	}
+}
+
+// TestBulkUpsert tests updating the existing rows on the primary key and the unique key conflicts.
+func TestBulkUpsert(t *testing.T) {
+	c := newRepoClient(t)
+	ctx := context.Background()
+	if err := c.DB(ctx).AutoMigrate(&bulkItem{}); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository[bulkItem](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.BulkInsert(ctx, []bulkItem{{ID: 1, Code: "a", Name: "a", Stock: 1}, {ID: 2, Code: "b", Name: "b", Stock: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// conflict on the primary key, all columns updated
+	res, err := repo.BulkUpsert(ctx, []bulkItem{{ID: 1, Code: "a", Name: "a2", Stock: 2}, {ID: 3, Code: "c", Name: "c", Stock: 1}}, WithBatchSize(1))
+	if err != nil || res.Chunks != 2 {
+		t.Fatalf("BulkUpsert failed: %+v, %v", res, err)
+	}
+	var got bulkItem
+	c.DB(ctx).Take(&got, 1)
+	if got.Name != "a2" || got.Stock != 2 {
+		t.Errorf("Expected item 1 updated, got %+v", got)
+	}
+
+	// conflict on the unique code, only stock updated
+	_, err = BulkUpsert(ctx, c, []bulkItem{{ID: 10, Code: "b", Name: "ignored", Stock: 5}},
+		WithConflictColumns("Code"), WithUpdateColumns("stock"))
+	if err != nil {
+		t.Fatalf("BulkUpsert failed: %v", err)
+	}
+	got = bulkItem{}
+	c.DB(ctx).Take(&got, 2)
+	if got.Name != "b" || got.Stock != 5 {
+		t.Errorf("Expected only stock of item 2 updated, got %+v", got)
+	}
+	var n int64
+	c.DB(ctx).Model(&bulkItem{}).Count(&n)
+	if n != 3 {
+		t.Errorf("Expected 3 rows, got %d", n)
+	}
+
+	if _, err := BulkUpsert(ctx, c, []bulkItem{{ID: 1}}, WithUpdateColumns("missing")); err == nil {
+		t.Error("Expected error for the unknown column")
+	}
+}
@@ -2,17 +2,22 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/baisiyi/go-kits/cache"
 	"github.com/baisiyi/go-kits/log"
-	"gorm.io/driver/mysql"
+	"github.com/baisiyi/go-kits/retry"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 )
 
 type DBConfig struct {
+	// Driver 数据库驱动：mysql（默认）、postgres、sqlite
+	Driver          string        `mapstructure:"driver" json:"driver" yaml:"driver"`
 	DSN             Connect       `mapstructure:"dsn" json:"dsn" yaml:"dsn"`
 	MaxOpenConns    int           `mapstructure:"max_open_conns" yaml:"max_open_conns"`
 	MaxIdleConns    int           `mapstructure:"max_idle_conns" yaml:"max_idle_conns"`
@@ -20,20 +25,65 @@ type DBConfig struct {
 	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time" yaml:"conn_max_idle_time"`
 	LogLevel        int           `mapstructure:"log_level" yaml:"log_level"` // 1:Silent, 2:Error, 3:Warn, 4:Info
 	SlowThreshold   time.Duration `mapstructure:"slow_threshold" yaml:"slow_threshold"`
+	// ExplainSlowQueries 超过 SlowThreshold 的 SELECT 在独立的连接上后台执行 EXPLAIN，执行计划以 [DB_EXPLAIN] 记录，每秒最多一次
+	ExplainSlowQueries bool `mapstructure:"explain_slow_queries" yaml:"explain_slow_queries"`
+	// LogFormat SQL 日志格式：text（默认）、structured（SQL、行数、耗时作为日志字段输出）
+	LogFormat string `mapstructure:"log_format" yaml:"log_format"`
+	// Tracing 为每条 SQL 创建 OpenTelemetry Span，使用全局 TracerProvider（可由 tracing 插件设置）
+	Tracing bool `mapstructure:"tracing" yaml:"tracing"`
+	// EnableMetrics 按操作类型记录每条 SQL 的次数和耗时到 metrics.DefaultRegistry，标签包含实例名称、表名和状态
+	EnableMetrics bool `mapstructure:"enable_metrics" yaml:"enable_metrics"`
+	// Retry 瞬时错误自动重试
+	Retry RetryConfig `mapstructure:"retry" yaml:"retry"`
+	// ConnectRetry 初始化时连接失败的重试
+	ConnectRetry ConnectRetryConfig `mapstructure:"connect_retry" yaml:"connect_retry"`
+	// Breaker 熔断，数据库不可用时快速失败，避免大量协程阻塞在连接等待上
+	Breaker BreakerConfig `mapstructure:"breaker" yaml:"breaker"`
+	// QueryTimeout 单条 SQL 的执行超时，超时后取消执行并返回 context.DeadlineExceeded，0 表示不限制
+	QueryTimeout time.Duration `mapstructure:"query_timeout" yaml:"query_timeout"`
+	// MySQLMaxExecutionTime 同时将 QueryTimeout 设置为 MySQL 会话变量 max_execution_time，
+	// 由服务端中止超时的 SELECT，避免客户端取消后查询仍在服务端执行
+	MySQLMaxExecutionTime bool `mapstructure:"mysql_max_execution_time" yaml:"mysql_max_execution_time"`
+
+	// QueryCache 按主键查询的读穿透缓存，只缓存实现了 Cacheable 的模型
+	QueryCache QueryCacheConfig `mapstructure:"query_cache" yaml:"query_cache"`
+
+	// Replicas 只读副本，读请求在副本间负载均衡，写请求和事务使用主库
+	Replicas []ReplicaConfig `mapstructure:"replicas" yaml:"replicas"`
+	// ReplicaPolicy 副本选择策略：random（默认）、round_robin
+	ReplicaPolicy string `mapstructure:"replica_policy" yaml:"replica_policy"`
+	// Sharding 按后缀分表，声明的表按分表键路由到对应的分表
+	Sharding []ShardingConfig `mapstructure:"sharding" yaml:"sharding"`
+	// Tenancy 多租户，通过 GetTenantDB 按 ctx 中的租户使用独立的库或表名前缀
+	Tenancy TenancyConfig `mapstructure:"tenancy" yaml:"tenancy"`
+	// PoolStats 定期上报连接池统计，在连接数打满导致超时之前发现饱和
+	PoolStats PoolStatsConfig `mapstructure:"pool_stats" yaml:"pool_stats"`
+
+	// PrepareStmt 缓存预编译语句，重复执行的 SQL 不再重复解析
+	PrepareStmt bool `mapstructure:"prepare_stmt" yaml:"prepare_stmt"`
+	// SkipDefaultTransaction 单条写操作不包裹默认事务，可提升 30%+ 性能（业务已在 repo 层手动控制事务时开启）
+	SkipDefaultTransaction bool `mapstructure:"skip_default_transaction" yaml:"skip_default_transaction"`
+	// DisableNestedTransaction GORM 的嵌套 Transaction 不使用 SAVEPOINT
+	DisableNestedTransaction bool `mapstructure:"disable_nested_transaction" yaml:"disable_nested_transaction"`
+	// QueryFields 查询时列出模型的全部字段而不是 SELECT *
+	QueryFields bool `mapstructure:"query_fields" yaml:"query_fields"`
+	// DryRun 只生成 SQL 不执行，用于调试和测试
+	DryRun bool `mapstructure:"dry_run" yaml:"dry_run"`
 }
 
 type Connect struct {
-	Host         string        `mapstructure:"host"`
-	Port         int           `mapstructure:"port"`
-	Username     string        `mapstructure:"username"`
-	Password     string        `mapstructure:"password"`
-	Name         string        `mapstructure:"name"`
-	TablePrefix  string        `mapstructure:"table_prefix"`
+	Host         string        `mapstructure:"host" yaml:"host"`
+	Port         int           `mapstructure:"port" yaml:"port"`
+	Username     string        `mapstructure:"username" yaml:"username"`
+	Password     string        `mapstructure:"password" yaml:"password"`
+	Name         string        `mapstructure:"name" yaml:"name"` // 数据库名称，sqlite 为文件路径
+	TablePrefix  string        `mapstructure:"table_prefix" yaml:"table_prefix"`
 	Timeout      time.Duration `mapstructure:"timeout" yaml:"timeout"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout" yaml:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout" yaml:"write_timeout"`
-	Location     string        `mapstructure:"location"`
-	TLS          bool          `mapstructure:"tls"`
+	Location     string        `mapstructure:"location" yaml:"location"` // 时区，mysql 默认 Local
+	TLS          bool          `mapstructure:"tls" yaml:"tls"`
+	SSLMode      string        `mapstructure:"ssl_mode" yaml:"ssl_mode"` // postgres sslmode，默认 disable，TLS 为 true 时默认 require
 }
 
 // ToDSN 将 Connect 转换为 MySQL DSN 字符串
@@ -64,66 +114,197 @@ func (c *Connect) ToDSN() string {
 
 // Client 封装了 GORM 实例，不对外直接暴露 *gorm.DB，而是通过 GetDB() 获取
 type Client struct {
-	db *gorm.DB
+	db       *gorm.DB
+	replicas []*sql.DB
+	logger   log.Logger
+	sharding *ShardingPlugin
+	tenancy  *tenancy
+	explain  *explainPlugin // 慢查询 EXPLAIN，持有独立的连接
+
+	opened    time.Time // 连接创建时间，Stats 的统计周期起点
+	stopStats func()    // 停止按配置启动的统计上报
 }
 
 var (
+	initMu         sync.Mutex
 	clientInstance *Client
-	once           sync.Once
-	initErr        error
 )
 
 // Init 初始化数据库连接 (单例模式)
-// 即使多次调用，也只会初始化一次
+// 初始化成功后多次调用返回同一实例，失败时关闭已创建的连接，之后可以再次调用 Init 重试；
+// 创建的实例同时注册为 DefaultManager 的 default 实例
 func Init(cfg *DBConfig, svcLogger log.Logger) (*Client, error) {
-	// 使用 sync.Once 确保线程安全的单例创建
-	once.Do(func() {
-		clientInstance, initErr = newClient(cfg, svcLogger)
-	})
-
-	if initErr != nil {
-		return nil, initErr
+	initMu.Lock()
+	defer initMu.Unlock()
+	if clientInstance != nil {
+		return clientInstance, nil
 	}
-	return clientInstance, nil
+	c, err := newClient(cfg, svcLogger)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.registerMetrics(DefaultName, cfg); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	if err := defaultManager.Add(DefaultName, c); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	c.startStatsReporter(DefaultName, cfg.PoolStats)
+	clientInstance = c
+	return c, nil
 }
 
 // GetInstance 获取已经初始化的单例
 func GetInstance() *Client {
-	if clientInstance == nil {
+	initMu.Lock()
+	c := clientInstance
+	initMu.Unlock()
+	if c == nil {
 		log.Errorf("Database client has not been initialized. Call Init() first.")
 	}
-	return clientInstance
+	return c
 }
 
 // 内部构造函数
 func newClient(cfg *DBConfig, svcLogger log.Logger) (*Client, error) {
 	// A. 配置 Logger
-	newLogger := NewGormLogger(
+	newLogger, err := NewGormLogger(
 		svcLogger,
 		cfg.SlowThreshold,
 		cfg.LogLevel,
-	)
+	).WithFormat(cfg.LogFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	// B. 建立连接并 Ping (Fail Fast)，失败时按 ConnectRetry 重试
+	var db *gorm.DB
+	err = cfg.ConnectRetry.do(svcLogger, func() error {
+		db, err = connect(cfg, newLogger)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, _ := db.DB()
+
+	// C. 注册链路追踪
+	if cfg.Tracing {
+		if err := db.Use(NewTracingPlugin(nil)); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("failed to register tracing: %w", err)
+		}
+	}
+
+	// D. 注册语句超时
+	if cfg.QueryTimeout > 0 {
+		if err := db.Use(NewTimeoutPlugin(cfg.QueryTimeout)); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("failed to register query timeout: %w", err)
+		}
+	}
+
+	// E. 注册瞬时错误重试
+	if cfg.Retry.Attempts > 1 {
+		if err := db.Use(NewRetryPlugin(cfg.Retry, svcLogger)); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("failed to register retry: %w", err)
+		}
+	}
 
-	// B. GORM 配置
+	// F. 注册熔断，在重试之后注册，重试结束后才记录执行结果
+	if cfg.Breaker.enabled() {
+		if err := db.Use(NewBreakerPlugin(cfg.Breaker, svcLogger)); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("failed to register breaker: %w", err)
+		}
+	}
+
+	// G. 注册分表
+	var sharding *ShardingPlugin
+	if len(cfg.Sharding) > 0 {
+		if sharding, err = NewShardingPlugin(cfg.Sharding...); err == nil {
+			err = db.Use(sharding)
+		}
+		if err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("failed to register sharding: %w", err)
+		}
+	}
+
+	// H. 多租户，在分表之后注册，表名前缀加在分表名上
+	var tenants *tenancy
+	if cfg.Tenancy.Strategy != "" {
+		if tenants, err = newTenancy(db, cfg); err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+	}
+
+	// I. 注册查询缓存
+	if cfg.QueryCache.Capacity > 0 {
+		backend := cache.NewMemory[string, []byte](cache.WithCapacity(cfg.QueryCache.Capacity))
+		if err := db.Use(NewQueryCachePlugin(backend, cfg.QueryCache, svcLogger)); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("failed to register query cache: %w", err)
+		}
+	}
+
+	// J. 注册只读副本
+	var replicas []*sql.DB
+	if len(cfg.Replicas) > 0 {
+		if replicas, err = registerReplicas(db, cfg); err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+	}
+
+	// K. 慢查询 EXPLAIN，使用单独的连接，连接失败不影响使用，只是没有执行计划
+	var explain *explainPlugin
+	if cfg.ExplainSlowQueries && cfg.SlowThreshold > 0 {
+		if explain, err = registerExplain(db, cfg, newLogger); err != nil {
+			svcLogger.Warnf("[DB_EXPLAIN] Failed to register EXPLAIN of the slow queries: %v", err)
+		}
+	}
+
+	return &Client{db: db, replicas: replicas, logger: svcLogger, sharding: sharding, tenancy: tenants, explain: explain, opened: time.Now()}, nil
+}
+
+// connect 建立连接、配置连接池并 Ping，失败时关闭已建立的连接，配置错误不重试
+func connect(cfg *DBConfig, logger *GormLoggerAdapter) (*gorm.DB, error) {
+	// GORM 配置，gorm.Open 会修改配置，每次连接使用新的配置
 	gormConfig := &gorm.Config{
-		Logger: newLogger,
+		Logger: logger,
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: true, // 表名不加 s
 		},
-		// 禁用自动事务可以提升 30%+ 性能（如果你的业务逻辑已经在 repo 层手动控制事务）
-		// SkipDefaultTransaction: true,
+		PrepareStmt:              cfg.PrepareStmt,
+		SkipDefaultTransaction:   cfg.SkipDefaultTransaction,
+		DisableNestedTransaction: cfg.DisableNestedTransaction,
+		QueryFields:              cfg.QueryFields,
+		DryRun:                   cfg.DryRun,
 	}
 
-	// C. 建立连接
-	db, err := gorm.Open(mysql.Open(cfg.DSN.ToDSN()), gormConfig)
+	dialector, err := cfg.Dialector()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open mysql connection: %w", err)
+		return nil, retry.Unrecoverable(err)
+	}
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		if db != nil {
+			if sqlDB, _ := db.DB(); sqlDB != nil {
+				_ = sqlDB.Close()
+			}
+		}
+		return nil, fmt.Errorf("failed to open %s connection: %w", dialector.Name(), err)
 	}
 
-	// D. 配置连接池
+	// 配置连接池
 	sqlDB, err := db.DB()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get sql.DB: %w", err)
+		return nil, retry.Unrecoverable(fmt.Errorf("failed to get sql.DB: %w", err))
 	}
 
 	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
@@ -131,18 +312,22 @@ func newClient(cfg *DBConfig, svcLogger log.Logger) (*Client, error) {
 	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	// E. 立即执行一次 Ping (Fail Fast)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := sqlDB.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping mysql: %w", err)
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("failed to ping %s: %w", dialector.Name(), err)
 	}
+	return db, nil
+}
 
-	return &Client{db: db}, nil
+// Sharding 返回分表插件，未配置分表时为 nil
+func (c *Client) Sharding() *ShardingPlugin {
+	return c.sharding
 }
 
 // GetDB 获取 GORM 实例
-// 建议必须传入 Context，以便支持 Trace 和 Timeout
+// 建议必须传入 Context，以便支持 Trace 和 Timeout，配置 QueryTimeout 时每条 SQL 另有执行超时
 func (c *Client) GetDB(ctx context.Context) *gorm.DB {
 	return c.db.WithContext(ctx)
 }
@@ -153,7 +338,10 @@ func (c *Client) Health(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	return sqlDB.PingContext(ctx)
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return err
+	}
+	return c.pingReplicas(ctx)
 }
 
 // Close 优雅关闭
@@ -162,6 +350,13 @@ func (c *Client) Close() error {
 	if err != nil {
 		return err
 	}
+	if c.stopStats != nil {
+		c.stopStats()
+	}
 	log.Infof("Closing database connection pool...")
-	return sqlDB.Close()
+	closeErrs := []error{sqlDB.Close(), c.closeReplicas(), c.tenancy.close()}
+	if c.explain != nil {
+		closeErrs = append(closeErrs, c.explain.close())
+	}
+	return errors.Join(closeErrs...)
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/baisiyi/go-kits/log"
+	"go.uber.org/zap"
 )
 
 // mockLogger is a mock implementation of log.Logger for testing.
@@ -17,6 +18,13 @@ type mockLogger struct {
 	debugs     []string
 	lastFormat string
 	lastArgs   []interface{}
+	entries    []mockEntry // structured logs
+}
+
+type mockEntry struct {
+	level  string
+	msg    string
+	fields []log.Field
 }
 
 func (m *mockLogger) Infof(format string, args ...interface{}) {
@@ -45,20 +53,42 @@ func (m *mockLogger) Debugf(format string, args ...interface{}) {
 
 func (m *mockLogger) Debug(msg string, fields ...log.Field) {}
 
-func (m *mockLogger) Info(msg string, fields ...log.Field) {}
+func (m *mockLogger) Info(msg string, fields ...log.Field) {
+	m.entries = append(m.entries, mockEntry{"info", msg, fields})
+}
 
-func (m *mockLogger) Warn(msg string, fields ...log.Field) {}
+func (m *mockLogger) Warn(msg string, fields ...log.Field) {
+	m.entries = append(m.entries, mockEntry{"warn", msg, fields})
+}
 
-func (m *mockLogger) Error(msg string, fields ...log.Field) {}
+func (m *mockLogger) Error(msg string, fields ...log.Field) {
+	m.entries = append(m.entries, mockEntry{"error", msg, fields})
+}
 
 func (m *mockLogger) Fatal(msg string, fields ...log.Field) {}
 
+func (m *mockLogger) Fatalf(format string, args ...interface{}) {}
+
+func (m *mockLogger) Panicf(format string, args ...interface{}) {}
+
 func (m *mockLogger) Panic(msg string, fields ...log.Field) {}
 
+func (m *mockLogger) Debugw(msg string, keysAndValues ...interface{}) {}
+
+func (m *mockLogger) Infow(msg string, keysAndValues ...interface{}) {}
+
+func (m *mockLogger) Warnw(msg string, keysAndValues ...interface{}) {}
+
+func (m *mockLogger) Errorw(msg string, keysAndValues ...interface{}) {}
+
 func (m *mockLogger) With(fields ...log.Field) log.Logger { return m }
 
 func (m *mockLogger) Named(name string) log.Logger { return m }
 
+func (m *mockLogger) WithCallerSkip(delta int) log.Logger { return m }
+
+func (m *mockLogger) WithOptions(opts ...zap.Option) log.Logger { return m }
+
 func (m *mockLogger) Sync() error { return nil }
 
 // TestConnect_ToDSN tests the DSN generation from Connect config.
@@ -76,6 +106,7 @@ func TestConnect_ToDSN(t *testing.T) {
 				Username: "root",
 				Password: "password",
 				Name:     "testdb",
+				Location: "Local",
 			},
 			expected: "root:password@tcp(localhost:3306)/testdb?charset=utf8mb4&parseTime=True&loc=Local",
 		},
@@ -87,6 +118,7 @@ func TestConnect_ToDSN(t *testing.T) {
 				Username: "admin",
 				Password: "secret",
 				Name:     "production",
+				Location: "Local",
 			},
 			expected: "admin:secret@tcp(db.example.com:3307)/production?charset=utf8mb4&parseTime=True&loc=Local",
 		},
@@ -98,6 +130,7 @@ func TestConnect_ToDSN(t *testing.T) {
 				Username: "root",
 				Password: "",
 				Name:     "testdb",
+				Location: "Local",
 			},
 			expected: "root:@tcp(localhost:3306)/testdb?charset=utf8mb4&parseTime=True&loc=Local",
 		},
@@ -121,6 +154,7 @@ func TestConnect_ToDSN_Format(t *testing.T) {
 		Username: "user",
 		Password: "pass",
 		Name:     "mydb",
+		Location: "Local",
 	}
 
 	dsn := connect.ToDSN()
@@ -258,6 +292,53 @@ func TestGormLoggerAdapter_Trace(t *testing.T) {
 	}
 }
 
+// TestGormLoggerAdapter_Structured tests that the structured mode logs the SQL as fields.
+func TestGormLoggerAdapter_Structured(t *testing.T) {
+	mock := &mockLogger{}
+	adapter, err := NewGormLogger(mock, 50*time.Millisecond, 4).WithFormat(LogFormatStructured)
+	if err != nil {
+		t.Fatalf("WithFormat failed: %v", err)
+	}
+	ctx := context.Background()
+	adapter.Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	adapter.Trace(ctx, time.Now().Add(-300*time.Millisecond), func() (string, int64) { return "SELECT 2", 2 }, nil)
+	adapter.Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 3", 0 }, &testError{"bad conn"})
+	adapter.TraceTx(ctx, time.Now(), false, nil)
+
+	if len(mock.infos)+len(mock.warns)+len(mock.errors) != 0 {
+		t.Errorf("Expected no printf logs, got %v %v %v", mock.infos, mock.warns, mock.errors)
+	}
+	want := []struct{ level, msg, sql string }{
+		{"info", "[DB_SQL]", "SELECT 1"},
+		{"warn", "[DB_SLOW]", "SELECT 2"},
+		{"error", "[DB_ERR]", "SELECT 3"},
+		{"info", "[DB_TX]", ""},
+	}
+	if len(mock.entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), mock.entries)
+	}
+	for i, w := range want {
+		e := mock.entries[i]
+		fields := make(map[string]log.Field, len(e.fields))
+		for _, f := range e.fields {
+			fields[f.Key] = f
+		}
+		if e.level != w.level || e.msg != w.msg {
+			t.Errorf("entry %d = %s %s, want %s %s", i, e.level, e.msg, w.level, w.msg)
+		}
+		if w.sql != "" && (fields["sql"].String != w.sql || fields["rows"].Key == "" || fields["elapsed_ms"].Key == "") {
+			t.Errorf("entry %d fields = %+v", i, e.fields)
+		}
+	}
+	if f := mock.entries[2].fields[len(mock.entries[2].fields)-1]; f.Key != "error" || f.String != "bad conn" {
+		t.Errorf("error field = %+v", f)
+	}
+
+	if _, err := adapter.WithFormat("xml"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
 // TestGormLoggerAdapter_Trace_SlowQuery tests Trace with slow query.
 func TestGormLoggerAdapter_Trace_SlowQuery(t *testing.T) {
 	mock := &mockLogger{}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// 支持的数据库驱动，mysql 内置，postgres 和 sqlite 需导入对应的子包注册：
+//
+//	import _ "github.com/baisiyi/go-kits/database/postgres"
+//	import _ "github.com/baisiyi/go-kits/database/sqlite" // 依赖 cgo
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Driver 数据库驱动，由驱动子包在 init 中通过 RegisterDriver 注册，未导入的驱动不会链接进程序
+type Driver struct {
+	// SQLDriver database/sql 的驱动名称，用于副本、租户等独立的连接池
+	SQLDriver string
+	// DSN 返回 conn 的 DSN
+	DSN func(cfg *DBConfig, conn *Connect) string
+	// Open 以 DSN 创建 Dialector
+	Open func(dsn string) gorm.Dialector
+	// New 以已建立的连接池创建 Dialector
+	New func(pool *sql.DB) gorm.Dialector
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// RegisterDriver 注册名称为 name 的驱动，重复注册时替换
+func RegisterDriver(name string, d Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[name] = d
+}
+
+func init() {
+	mysqlDriver := Driver{
+		SQLDriver: "mysql",
+		DSN:       (*DBConfig).mysqlDSN,
+		Open:      mysql.Open,
+		New: func(pool *sql.DB) gorm.Dialector {
+			return mysql.New(mysql.Config{Conn: pool})
+		},
+	}
+	RegisterDriver("", mysqlDriver)
+	RegisterDriver(DriverMySQL, mysqlDriver)
+}
+
+// sqlStateError 带 SQLSTATE 错误码的错误，如 postgres 驱动的 *pgconn.PgError
+type sqlStateError interface {
+	error
+	SQLState() string
+}
+
+// lookupDriver 返回已注册的驱动
+func lookupDriver(name string) (Driver, error) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	d, ok := drivers[name]
+	if ok {
+		return d, nil
+	}
+	var pkg string
+	switch name {
+	case DriverPostgres, "postgresql":
+		pkg = DriverPostgres
+	case DriverSQLite, "sqlite3":
+		pkg = DriverSQLite
+	default:
+		return Driver{}, fmt.Errorf("database: driver %s not supported", name)
+	}
+	return Driver{}, fmt.Errorf("database: driver %s not registered, import github.com/baisiyi/go-kits/database/%s", name, pkg)
+}
+
+// Dialector 按 Driver 创建 GORM Dialector，Driver 为空时使用 mysql
+func (c *DBConfig) Dialector() (gorm.Dialector, error) {
+	d, err := lookupDriver(c.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return d.Open(d.DSN(c, &c.DSN)), nil
+}
+
+// ToPostgresDSN 将 Connect 转换为 PostgreSQL DSN 字符串（key=value 格式）
+func (c *Connect) ToPostgresDSN() string {
+	port := c.Port
+	if port == 0 {
+		port = 5432
+	}
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+		if c.TLS {
+			sslMode = "require"
+		}
+	}
+	kvs := []string{
+		"host=" + quoteDSNValue(c.Host),
+		fmt.Sprintf("port=%d", port),
+		"user=" + quoteDSNValue(c.Username),
+		"password=" + quoteDSNValue(c.Password),
+		"dbname=" + quoteDSNValue(c.Name),
+		"sslmode=" + sslMode,
+	}
+	if c.Location != "" {
+		kvs = append(kvs, "TimeZone="+quoteDSNValue(c.Location))
+	}
+	if c.Timeout > 0 {
+		// connect_timeout 单位为秒，不足 1 秒按 1 秒计算
+		kvs = append(kvs, fmt.Sprintf("connect_timeout=%d", max(int(c.Timeout.Seconds()), 1)))
+	}
+	return strings.Join(kvs, " ")
+}
+
+// ToSQLiteDSN 将 Connect 转换为 SQLite DSN 字符串，Name 为数据库文件路径，为空时使用内存数据库
+func (c *Connect) ToSQLiteDSN() string {
+	if c.Name == "" {
+		return "file::memory:"
+	}
+	return c.Name
+}
+
+// quoteDSNValue 按 libpq 规则对包含空格、引号或反斜杠的值加单引号
+func quoteDSNValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// the driver sub-packages import this package, the tests register the drivers the same way
+func init() {
+	pg := Driver{
+		SQLDriver: "pgx",
+		DSN:       func(_ *DBConfig, c *Connect) string { return c.ToPostgresDSN() },
+		Open:      postgres.Open,
+		New: func(pool *sql.DB) gorm.Dialector {
+			return postgres.New(postgres.Config{Conn: pool})
+		},
+	}
+	RegisterDriver(DriverPostgres, pg)
+	RegisterDriver("postgresql", pg)
+	lite := Driver{
+		SQLDriver: sqlite.DriverName,
+		DSN:       func(_ *DBConfig, c *Connect) string { return c.ToSQLiteDSN() },
+		Open:      sqlite.Open,
+		New: func(pool *sql.DB) gorm.Dialector {
+			return sqlite.New(sqlite.Config{Conn: pool})
+		},
+	}
+	RegisterDriver(DriverSQLite, lite)
+	RegisterDriver("sqlite3", lite)
+}
+
+// TestConnect_ToPostgresDSN tests the postgres DSN generation and its defaults.
+func TestConnect_ToPostgresDSN(t *testing.T) {
+	c := &Connect{Host: "localhost", Username: "app", Password: "p w'd", Name: "orders"}
+	want := `host=localhost port=5432 user=app password='p w\'d' dbname=orders sslmode=disable`
+	if got := c.ToPostgresDSN(); got != want {
+		t.Errorf("ToPostgresDSN() = %v, want %v", got, want)
+	}
+
+	c = &Connect{Host: "db", Port: 6432, Username: "app", Password: "x", Name: "orders",
+		TLS: true, Location: "Asia/Shanghai", Timeout: 500 * time.Millisecond}
+	want = "host=db port=6432 user=app password=x dbname=orders sslmode=require TimeZone=Asia/Shanghai connect_timeout=1"
+	if got := c.ToPostgresDSN(); got != want {
+		t.Errorf("ToPostgresDSN() = %v, want %v", got, want)
+	}
+}
+
+// TestDBConfig_Dialector tests the dialector of each driver.
+func TestDBConfig_Dialector(t *testing.T) {
+	for driver, name := range map[string]string{"": "mysql", "mysql": "mysql", "postgres": "postgres", "sqlite": "sqlite"} {
+		d, err := (&DBConfig{Driver: driver}).Dialector()
+		if err != nil || d.Name() != name {
+			t.Errorf("Dialector(%q) = %v, %v", driver, d, err)
+		}
+	}
+	if _, err := (&DBConfig{Driver: "oracle"}).Dialector(); err == nil {
+		t.Error("Expected error for unsupported driver")
+	}
+}
+
+// TestLookupDriver tests the error of a known driver whose sub-package is not imported.
+func TestLookupDriver(t *testing.T) {
+	driversMu.Lock()
+	lite := drivers[DriverSQLite]
+	delete(drivers, DriverSQLite)
+	driversMu.Unlock()
+	defer RegisterDriver(DriverSQLite, lite)
+
+	_, err := (&DBConfig{Driver: DriverSQLite}).Dialector()
+	want := "database: driver sqlite not registered, import github.com/baisiyi/go-kits/database/sqlite"
+	if err == nil || err.Error() != want {
+		t.Errorf("Dialector() error = %v, want %s", err, want)
+	}
+}
+
+// TestNewClient_SQLite tests creating a client with the sqlite driver.
+func TestNewClient_SQLite(t *testing.T) {
+	m := NewManager()
+	c, err := m.Register("local", &DBConfig{Driver: DriverSQLite, MaxOpenConns: 1}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	defer m.CloseAll()
+	if err := c.Health(context.Background()); err != nil {
+		t.Errorf("Health failed: %v", err)
+	}
+	var n int
+	if err := c.GetDB(context.Background()).Raw("SELECT 1 + 1").Scan(&n).Error; err != nil || n != 2 {
+		t.Errorf("query = %d, %v", n, err)
+	}
+}
+
+// TestNewClient_SessionOptions tests that the session options are applied to gorm.
+func TestNewClient_SessionOptions(t *testing.T) {
+	c, err := newClient(&DBConfig{
+		Driver:                 DriverSQLite,
+		PrepareStmt:            true,
+		SkipDefaultTransaction: true,
+		QueryFields:            true,
+		DryRun:                 true,
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	db := c.GetDB(context.Background())
+	if !db.Config.PrepareStmt || !db.Config.SkipDefaultTransaction || !db.Config.QueryFields || !db.Config.DryRun {
+		t.Errorf("config not applied: %+v", db.Config)
+	}
+
+	type user struct {
+		ID   int
+		Name string
+	}
+	stmt := db.Find(&[]user{}).Statement
+	if sql := stmt.SQL.String(); sql != "SELECT `user`.`id`,`user`.`name` FROM `user`" {
+		t.Errorf("dry run SQL = %q", sql)
+	}
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// explainInterval 两次 EXPLAIN 的最小间隔，慢查询集中出现时不会给数据库增加明显负担
+	explainInterval = time.Second
+	// explainTimeout 单次 EXPLAIN 的超时
+	explainTimeout = time.Second
+
+	explainPluginName  = "go-kits:explain"
+	explainInstanceKey = "go-kits:explain_start"
+)
+
+// explainer 在独立的连接上执行慢查询的 EXPLAIN，连接池打满时也不会与业务 SQL 争抢连接
+type explainer struct {
+	pool   *sql.DB
+	prefix string // EXPLAIN 语句前缀，sqlite 为 EXPLAIN QUERY PLAN
+
+	mu   sync.Mutex
+	next time.Time // 下次允许执行的时间
+}
+
+// openExplainPool 以主库的 DSN 建立只有一个连接的连接池
+func openExplainPool(cfg *DBConfig) (*sql.DB, error) {
+	return openPool(cfg, &ReplicaConfig{DSN: cfg.DSN, MaxOpenConns: 1, MaxIdleConns: 1})
+}
+
+func newExplainer(pool *sql.DB, driver string) (*explainer, error) {
+	e := &explainer{pool: pool, prefix: "EXPLAIN "}
+	switch driver {
+	case "", DriverMySQL, DriverPostgres, "postgresql":
+	case DriverSQLite, "sqlite3":
+		e.prefix = "EXPLAIN QUERY PLAN "
+	default:
+		return nil, fmt.Errorf("database: driver %s not supported", driver)
+	}
+	return e, nil
+}
+
+// allow 按 explainInterval 限制执行频率
+func (e *explainer) allow(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if now.Before(e.next) {
+		return false
+	}
+	e.next = now.Add(explainInterval)
+	return true
+}
+
+// explain 返回 query 的执行计划，每行一条记录，多列时以 col=value 输出。
+// query 和 vars 为实际执行的带占位符的 SQL 和参数，EXPLAIN 不带 ANALYZE，不会真正执行语句
+func (e *explainer) explain(ctx context.Context, query string, vars []any) (string, error) {
+	// 慢查询返回时调用方的 ctx 可能已经结束
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), explainTimeout)
+	defer cancel()
+	rows, err := e.pool.QueryContext(ctx, e.prefix+query, vars...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	values := make([]sql.NullString, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var lines []string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		// PostgreSQL 的执行计划只有一列 QUERY PLAN，每行为计划的一行
+		if len(cols) == 1 {
+			lines = append(lines, nullString(values[0]))
+			continue
+		}
+		fields := make([]string, len(cols))
+		for i, col := range cols {
+			fields[i] = col + "=" + nullString(values[i])
+		}
+		lines = append(lines, strings.Join(fields, " "))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func nullString(s sql.NullString) string {
+	if !s.Valid {
+		return "NULL"
+	}
+	return s.String
+}
+
+// explainPlugin 在超过慢查询阈值的 SELECT 执行后，以语句实际的 SQL 和绑定参数在后台执行 EXPLAIN，
+// 执行计划以 [DB_EXPLAIN] 单独记录，不会延长业务请求的耗时
+type explainPlugin struct {
+	explainer *explainer
+	logger    *GormLoggerAdapter
+	wg        sync.WaitGroup
+}
+
+func newExplainPlugin(pool *sql.DB, driver string, logger *GormLoggerAdapter) (*explainPlugin, error) {
+	e, err := newExplainer(pool, driver)
+	if err != nil {
+		return nil, err
+	}
+	return &explainPlugin{explainer: e, logger: logger}, nil
+}
+
+// Name 实现 gorm.Plugin 接口
+func (p *explainPlugin) Name() string {
+	return explainPluginName
+}
+
+// Initialize 实现 gorm.Plugin 接口，在查询前后注册回调
+func (p *explainPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("explain:before_query", p.before); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("explain:after_query", p.after); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("explain:before_row", p.before); err != nil {
+		return err
+	}
+	return cb.Row().After("gorm:row").Register("explain:after_row", p.after)
+}
+
+func (p *explainPlugin) before(db *gorm.DB) {
+	db.InstanceSet(explainInstanceKey, p.logger.clock.Now())
+}
+
+func (p *explainPlugin) after(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.DryRun || p.logger.slowThreshold <= 0 {
+		return
+	}
+	v, ok := db.InstanceGet(explainInstanceKey)
+	if !ok {
+		return
+	}
+	start, ok := v.(time.Time)
+	if !ok || p.logger.clock.Since(start) <= p.logger.slowThreshold {
+		return
+	}
+	query := strings.TrimSpace(stmt.SQL.String())
+	if len(query) < 6 || !strings.EqualFold(query[:6], "SELECT") || !p.explainer.allow(p.logger.clock.Now()) {
+		return
+	}
+	// 语句结束后 gorm 会重置 Vars
+	vars := append([]any(nil), stmt.Vars...)
+	ctx, explained := stmt.Context, db.Dialector.Explain(query, vars...)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		plan, err := p.explainer.explain(ctx, query, vars)
+		if err != nil {
+			plan = "explain failed: " + err.Error()
+		}
+		p.logger.tracePlan(ctx, explained, plan)
+	}()
+}
+
+// registerExplain 建立 EXPLAIN 的独立连接并注册插件
+func registerExplain(db *gorm.DB, cfg *DBConfig, logger *GormLoggerAdapter) (*explainPlugin, error) {
+	pool, err := openExplainPool(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newExplainPlugin(pool, cfg.Driver, logger)
+	if err == nil {
+		err = db.Use(p)
+	}
+	if err != nil {
+		_ = pool.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// close 等待执行中的 EXPLAIN 结束后关闭连接
+func (p *explainPlugin) close() error {
+	p.wg.Wait()
+	return p.explainer.pool.Close()
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExplainSlowQueries tests that the plans of the slow SELECTs are logged in the
+// background with the bind parameters, at most once per interval.
+func TestExplainSlowQueries(t *testing.T) {
+	cfg := &DBConfig{
+		Driver:   DriverSQLite,
+		DSN:      Connect{Name: filepath.Join(t.TempDir(), "explain.db")},
+		LogLevel: 1,
+	}
+	c, err := newClient(cfg, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	db := c.GetDB(context.Background())
+	if err := db.AutoMigrate(&repoUser{}); err != nil {
+		t.Fatal(err)
+	}
+	// the plans are logged by the goroutines of the plugin only
+	logger := &mockLogger{}
+	p, err := registerExplain(db, cfg, NewGormLogger(logger, time.Nanosecond, 3))
+	if err != nil {
+		t.Fatalf("registerExplain failed: %v", err)
+	}
+	defer p.close()
+	plans := func() []string {
+		p.wg.Wait()
+		return logger.warns
+	}
+
+	// the value breaking out of the quotes of the logged SQL is bound as a parameter
+	var users []repoUser
+	if err := db.Where("name = ?", `\" OR 1=1 --`).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := plans(); len(got) != 1 || got[0] != "[DB_EXPLAIN] SQL: %s | Plan: %s" {
+		t.Fatalf("Expected a plan logged, got %v", got)
+	}
+	if plan := logger.lastArgs[1].(string); !strings.Contains(plan, "SCAN repo_user") {
+		t.Errorf("Unexpected plan %s", plan)
+	}
+	if err := db.Where("id = ?", 1).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := plans(); len(got) != 1 {
+		t.Errorf("Expected EXPLAIN rate limited, got %v", got)
+	}
+
+	p.explainer.next = time.Time{}
+	if err := db.Model(&repoUser{}).Where("id = ?", 1).Update("name", "b").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := plans(); len(got) != 1 {
+		t.Errorf("Expected no EXPLAIN for UPDATE, got %v", got)
+	}
+
+	// binary parameters are bound as is
+	if err := db.Where("name = ?", []byte{0xff, 0x00}).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := plans(); len(got) != 2 || strings.Contains(logger.lastArgs[1].(string), "explain failed") {
+		t.Errorf("Expected the plan of the binary parameter, got %v %v", got, logger.lastArgs)
+	}
+
+	if _, err := newExplainer(nil, "oracle"); err == nil {
+		t.Error("Expected error for unsupported driver")
+	}
+}
+
+// TestExplainPluginRegistered tests that the client registers the plugin by the config.
+func TestExplainPluginRegistered(t *testing.T) {
+	c, err := newClient(&DBConfig{
+		Driver:             DriverSQLite,
+		DSN:                Connect{Name: filepath.Join(t.TempDir(), "explain.db")},
+		SlowThreshold:      time.Hour,
+		ExplainSlowQueries: true,
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	if c.explain == nil {
+		t.Error("Expected the EXPLAIN plugin registered")
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
@@ -2,16 +2,29 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/baisiyi/go-kits/clock"
+	_ "github.com/baisiyi/go-kits/contextkit" // registers the context log fields
 	"github.com/baisiyi/go-kits/log"
 	"gorm.io/gorm/logger"
 )
 
+// 日志格式
+const (
+	// LogFormatText 输出 [DB_SQL] Elapsed: ... | SQL: ... 格式的文本日志
+	LogFormatText = "text"
+	// LogFormatStructured 以 [DB_SQL] 等标签为消息，SQL、行数、耗时等作为日志字段输出，便于 JSON 日志的采集和检索
+	LogFormatStructured = "structured"
+)
+
 type GormLoggerAdapter struct {
 	logger        log.Logger
 	logLevel      logger.LogLevel
 	slowThreshold time.Duration
+	clock         clock.Clock
+	structured    bool
 }
 
 // NewGormLogger 创建适配器
@@ -20,9 +33,31 @@ func NewGormLogger(l log.Logger, slowThreshold time.Duration, level int) *GormLo
 		logger:        l,
 		slowThreshold: slowThreshold,
 		logLevel:      logger.LogLevel(level),
+		clock:         clock.Real,
 	}
 }
 
+// WithClock 返回使用指定时钟计算 SQL 耗时的适配器，便于测试慢查询判定
+func (l *GormLoggerAdapter) WithClock(c clock.Clock) *GormLoggerAdapter {
+	newLogger := *l
+	newLogger.clock = clock.OrReal(c)
+	return &newLogger
+}
+
+// WithFormat 返回使用指定日志格式的适配器，format 为 LogFormatText（默认）或 LogFormatStructured
+func (l *GormLoggerAdapter) WithFormat(format string) (*GormLoggerAdapter, error) {
+	newLogger := *l
+	switch format {
+	case "", LogFormatText:
+		newLogger.structured = false
+	case LogFormatStructured:
+		newLogger.structured = true
+	default:
+		return nil, fmt.Errorf("database: log format %s not supported", format)
+	}
+	return &newLogger, nil
+}
+
 // LogMode 实现 gorm 接口: 设置日志级别
 func (l *GormLoggerAdapter) LogMode(level logger.LogLevel) logger.Interface {
 	newLogger := *l
@@ -33,21 +68,21 @@ func (l *GormLoggerAdapter) LogMode(level logger.LogLevel) logger.Interface {
 // Info 实现 gorm 接口
 func (l *GormLoggerAdapter) Info(ctx context.Context, msg string, data ...interface{}) {
 	if l.logLevel >= logger.Info {
-		l.logger.Infof(msg, data...)
+		l.loggerFor(ctx).Infof(msg, data...)
 	}
 }
 
 // Warn 实现 gorm 接口
 func (l *GormLoggerAdapter) Warn(ctx context.Context, msg string, data ...interface{}) {
 	if l.logLevel >= logger.Warn {
-		l.logger.Warnf(msg, data...)
+		l.loggerFor(ctx).Warnf(msg, data...)
 	}
 }
 
 // Error 实现 gorm 接口
 func (l *GormLoggerAdapter) Error(ctx context.Context, msg string, data ...interface{}) {
 	if l.logLevel >= logger.Error {
-		l.logger.Errorf(msg, data...)
+		l.loggerFor(ctx).Errorf(msg, data...)
 	}
 }
 
@@ -57,23 +92,115 @@ func (l *GormLoggerAdapter) Trace(ctx context.Context, begin time.Time, fc func(
 		return
 	}
 
-	elapsed := time.Since(begin)
+	elapsed := l.clock.Since(begin)
 	sql, rows := fc() // 获取 SQL 语句和受影响行数
 
 	// 1. 记录错误 (Error)
 	if err != nil && l.logLevel >= logger.Error {
-		l.logger.Errorf("[DB_ERR] %s | Elapsed: %v | Rows: %d | SQL: %s", err, elapsed, rows, sql)
+		if l.structured {
+			l.loggerFor(ctx).Error("[DB_ERR]", sqlFields(sql, rows, elapsed, log.String("error", err.Error()))...)
+			return
+		}
+		l.loggerFor(ctx).Errorf("[DB_ERR] %s | Elapsed: %v | Rows: %d | SQL: %s", err, elapsed, rows, sql)
 		return
 	}
 
 	// 2. 记录慢查询 (Warn)
 	if l.slowThreshold != 0 && elapsed > l.slowThreshold && l.logLevel >= logger.Warn {
-		l.logger.Warnf("[DB_SLOW] Elapsed: %v > %v | Rows: %d | SQL: %s", elapsed, l.slowThreshold, rows, sql)
+		if l.structured {
+			l.loggerFor(ctx).Warn("[DB_SLOW]", sqlFields(sql, rows, elapsed, millis("slow_threshold_ms", l.slowThreshold))...)
+			return
+		}
+		l.loggerFor(ctx).Warnf("[DB_SLOW] Elapsed: %v > %v | Rows: %d | SQL: %s", elapsed, l.slowThreshold, rows, sql)
 		return
 	}
 
 	// 3. 记录普通 SQL (Info)
 	if l.logLevel >= logger.Info {
-		l.logger.Infof("[DB_SQL] Elapsed: %v | Rows: %d | SQL: %s", elapsed, rows, sql)
+		if l.structured {
+			l.loggerFor(ctx).Info("[DB_SQL]", sqlFields(sql, rows, elapsed)...)
+			return
+		}
+		l.loggerFor(ctx).Infof("[DB_SQL] Elapsed: %v | Rows: %d | SQL: %s", elapsed, rows, sql)
+	}
+}
+
+// tracePlan 记录慢查询的执行计划，与 [DB_SLOW] 同为 Warn 级别
+func (l *GormLoggerAdapter) tracePlan(ctx context.Context, sql, plan string) {
+	if l.logLevel < logger.Warn {
+		return
+	}
+	if l.structured {
+		l.loggerFor(ctx).Warn("[DB_EXPLAIN]", log.String("sql", sql), log.String("plan", plan))
+		return
+	}
+	l.loggerFor(ctx).Warnf("[DB_EXPLAIN] SQL: %s | Plan: %s", sql, plan)
+}
+
+// sqlFields 结构化日志的字段：sql、rows、elapsed_ms 及额外字段
+func sqlFields(sql string, rows int64, elapsed time.Duration, extra ...log.Field) []log.Field {
+	return append([]log.Field{
+		log.String("sql", sql),
+		log.Int64("rows", rows),
+		millis("elapsed_ms", elapsed),
+	}, extra...)
+}
+
+// millis 以毫秒为单位的耗时字段，保留小数以区分亚毫秒的 SQL
+func millis(key string, d time.Duration) log.Field {
+	return log.Float64(key, float64(d)/float64(time.Millisecond))
+}
+
+// loggerFor 附带 ctx 中的 request_id、trace_id 等字段，以及链路追踪 Span 的 trace_id/span_id
+func (l *GormLoggerAdapter) loggerFor(ctx context.Context) log.Logger {
+	logger := log.WithContextFields(l.logger, ctx)
+	if fields := spanFields(ctx); len(fields) > 0 {
+		logger = logger.With(fields...)
+	}
+	return logger
+}
+
+// TraceTx 记录 Client.Transact 的事务耗时和结果：失败或 panic 回滚记录 Error，慢事务记录 Warn，提交记录 Info
+func (l *GormLoggerAdapter) TraceTx(ctx context.Context, begin time.Time, savepoint bool, err error) {
+	if l.logLevel <= logger.Silent {
+		return
+	}
+
+	elapsed := l.clock.Since(begin)
+	if l.structured {
+		l.traceTxStructured(ctx, elapsed, savepoint, err)
+		return
+	}
+	if err != nil && l.logLevel >= logger.Error {
+		l.loggerFor(ctx).Errorf("[DB_TX] Rollback | Elapsed: %v | Savepoint: %t | Error: %s", elapsed, savepoint, err)
+		return
+	}
+	if err != nil {
+		return
+	}
+	if l.slowThreshold != 0 && elapsed > l.slowThreshold && l.logLevel >= logger.Warn {
+		l.loggerFor(ctx).Warnf("[DB_TX_SLOW] Commit | Elapsed: %v > %v | Savepoint: %t", elapsed, l.slowThreshold, savepoint)
+		return
+	}
+	if l.logLevel >= logger.Info {
+		l.loggerFor(ctx).Infof("[DB_TX] Commit | Elapsed: %v | Savepoint: %t", elapsed, savepoint)
+	}
+}
+
+func (l *GormLoggerAdapter) traceTxStructured(ctx context.Context, elapsed time.Duration, savepoint bool, err error) {
+	fields := []log.Field{millis("elapsed_ms", elapsed), log.Bool("savepoint", savepoint)}
+	if err != nil {
+		if l.logLevel >= logger.Error {
+			l.loggerFor(ctx).Error("[DB_TX]", append(fields, log.String("action", "rollback"), log.String("error", err.Error()))...)
+		}
+		return
+	}
+	fields = append(fields, log.String("action", "commit"))
+	if l.slowThreshold != 0 && elapsed > l.slowThreshold && l.logLevel >= logger.Warn {
+		l.loggerFor(ctx).Warn("[DB_TX_SLOW]", append(fields, millis("slow_threshold_ms", l.slowThreshold))...)
+		return
+	}
+	if l.logLevel >= logger.Info {
+		l.loggerFor(ctx).Info("[DB_TX]", fields...)
 	}
 }
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PoolHealth 单个连接池的健康状态
+type PoolHealth struct {
+	// Pool 连接池名称：primary、replica-0 ...
+	Pool string
+	// Latency Ping 耗时
+	Latency time.Duration
+	// OpenConns 已建立的连接数，InUse 使用中，Idle 空闲
+	OpenConns    int
+	InUse        int
+	Idle         int
+	MaxOpenConns int
+	// Lag 副本复制延迟，仅副本查询（MySQL 的 Seconds_Behind_Source，PostgreSQL 的最后回放时间），
+	// 未知（主库、SQLite、无权限等）时为 -1
+	Lag time.Duration
+	// Err Ping 失败的错误
+	Err error
+}
+
+// MarshalJSON 将时长输出为 1.5ms 格式，用于健康检查接口
+func (h PoolHealth) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Pool         string `json:"pool"`
+		Latency      string `json:"latency"`
+		OpenConns    int    `json:"open_conns"`
+		InUse        int    `json:"in_use"`
+		Idle         int    `json:"idle"`
+		MaxOpenConns int    `json:"max_open_conns"`
+		Lag          string `json:"lag,omitempty"`
+		Error        string `json:"error,omitempty"`
+	}{
+		Pool:         h.Pool,
+		Latency:      h.Latency.String(),
+		OpenConns:    h.OpenConns,
+		InUse:        h.InUse,
+		Idle:         h.Idle,
+		MaxOpenConns: h.MaxOpenConns,
+	}
+	if h.Lag >= 0 {
+		v.Lag = h.Lag.String()
+	}
+	if h.Err != nil {
+		v.Error = h.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// HealthReport 健康检查报告，包括主库和各副本连接池
+type HealthReport struct {
+	Primary  PoolHealth   `json:"primary"`
+	Replicas []PoolHealth `json:"replicas,omitempty"`
+}
+
+// Err 返回各连接池 Ping 失败的错误，全部成功时为 nil
+func (r HealthReport) Err() error {
+	errs := []error{r.Primary.Err}
+	for _, h := range r.Replicas {
+		if h.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.Pool, h.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HealthReport 检查主库和各副本，返回 Ping 耗时、连接数和副本延迟
+func (c *Client) HealthReport(ctx context.Context) HealthReport {
+	var report HealthReport
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		report.Primary = PoolHealth{Pool: PoolPrimary, Lag: -1, Err: err}
+	} else {
+		report.Primary = poolHealth(ctx, PoolPrimary, sqlDB)
+	}
+	for i, r := range c.replicas {
+		h := poolHealth(ctx, fmt.Sprintf("replica-%d", i), r)
+		if h.Err == nil {
+			h.Lag = replicaLag(ctx, c.db.Dialector.Name(), r)
+		}
+		report.Replicas = append(report.Replicas, h)
+	}
+	return report
+}
+
+// Check 实现 health.Checker，details 为 HealthReport，主库或任一副本 Ping 失败时返回错误
+func (c *Client) Check(ctx context.Context) (any, error) {
+	report := c.HealthReport(ctx)
+	return report, report.Err()
+}
+
+func poolHealth(ctx context.Context, pool string, db *sql.DB) PoolHealth {
+	start := time.Now()
+	err := db.PingContext(ctx)
+	s := db.Stats()
+	return PoolHealth{
+		Pool:         pool,
+		Latency:      time.Since(start),
+		OpenConns:    s.OpenConnections,
+		InUse:        s.InUse,
+		Idle:         s.Idle,
+		MaxOpenConns: s.MaxOpenConnections,
+		Lag:          -1,
+		Err:          err,
+	}
+}
+
+// replicaLag 按 Dialector 名称查询副本的复制延迟，未知时返回 -1
+func replicaLag(ctx context.Context, dialect string, db *sql.DB) time.Duration {
+	switch dialect {
+	case DriverMySQL:
+		// MySQL 8.0.22 起为 SHOW REPLICA STATUS，之前为 SHOW SLAVE STATUS
+		for _, query := range []string{"SHOW REPLICA STATUS", "SHOW SLAVE STATUS"} {
+			if lag, ok := mysqlLag(ctx, db, query); ok {
+				return lag
+			}
+		}
+	case DriverPostgres:
+		var seconds sql.NullFloat64
+		err := db.QueryRowContext(ctx, "SELECT EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())").Scan(&seconds)
+		if err == nil && seconds.Valid {
+			return time.Duration(max(seconds.Float64, 0) * float64(time.Second))
+		}
+	}
+	return -1
+}
+
+// mysqlLag 从复制状态中读取 Seconds_Behind_Source（旧版本为 Seconds_Behind_Master）
+func mysqlLag(ctx context.Context, db *sql.DB, query string) (time.Duration, bool) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, false
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil || !rows.Next() {
+		return 0, false
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, false
+	}
+	for i, col := range cols {
+		if col != "Seconds_Behind_Source" && col != "Seconds_Behind_Master" {
+			continue
+		}
+		var seconds int64
+		if _, err := fmt.Sscan(string(values[i]), &seconds); err != nil {
+			return 0, false // NULL 表示复制线程未运行
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	return 0, false
+}
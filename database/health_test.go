@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/baisiyi/go-kits/health"
+)
+
+// TestHealthReport tests the report of the primary and the replicas, and the checker
+// registered to the health registry.
+func TestHealthReport(t *testing.T) {
+	dir := t.TempDir()
+	c, err := newClient(&DBConfig{
+		Driver:       DriverSQLite,
+		DSN:          Connect{Name: filepath.Join(dir, "primary.db")},
+		MaxOpenConns: 4,
+		Replicas:     []ReplicaConfig{{DSN: Connect{Name: filepath.Join(dir, "replica.db")}}},
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	report := c.HealthReport(ctx)
+	if err := report.Err(); err != nil {
+		t.Fatalf("Expected healthy, got %v", err)
+	}
+	if report.Primary.Pool != PoolPrimary || report.Primary.MaxOpenConns != 4 || report.Primary.Latency <= 0 {
+		t.Errorf("primary = %+v", report.Primary)
+	}
+	if len(report.Replicas) != 1 || report.Replicas[0].Pool != "replica-0" || report.Replicas[0].Lag != -1 {
+		t.Errorf("replicas = %+v", report.Replicas)
+	}
+	data, err := json.Marshal(report)
+	if err != nil || !strings.Contains(string(data), `"pool":"replica-0"`) || strings.Contains(string(data), `"lag"`) {
+		t.Errorf("json = %s, %v", data, err)
+	}
+
+	reg := health.NewRegistry()
+	reg.Register("db", c)
+	if r := reg.Readiness(ctx); !r.Up() {
+		t.Errorf("Expected readiness up, got %+v", r)
+	}
+	_ = c.replicas[0].Close()
+	if r := reg.Readiness(ctx); r.Up() || !strings.Contains(r.Checks["db"].Error, "replica-0") {
+		t.Errorf("Expected readiness down by the replica, got %+v", r)
+	}
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/baisiyi/go-kits/log"
+	"gorm.io/gorm"
+)
+
+// DefaultName 是 Init 注册的默认实例名称
+const DefaultName = "default"
+
+// ErrDuplicateInstance 重复注册同名实例时返回
+var ErrDuplicateInstance = errors.New("database: duplicate instance")
+
+// Manager 管理多个命名的数据库实例，每个实例拥有独立的连接池和 GORM 日志
+type Manager struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+// NewManager 创建 Manager
+func NewManager() *Manager {
+	return &Manager{clients: make(map[string]*Client)}
+}
+
+// NewClientFromDB 使用已创建的 GORM 实例构造 Client，便于接入自定义驱动或测试
+func NewClientFromDB(db *gorm.DB) *Client {
+	return &Client{db: db, opened: time.Now()}
+}
+
+// Register 按配置创建连接并注册为 name 实例，实例的日志附带 database 字段以区分来源
+func (m *Manager) Register(name string, cfg *DBConfig, svcLogger log.Logger) (*Client, error) {
+	m.mu.RLock()
+	_, ok := m.clients[name]
+	m.mu.RUnlock()
+	if ok {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateInstance, name)
+	}
+
+	if svcLogger == nil {
+		svcLogger = log.GetDefaultLogger()
+	}
+	c, err := newClient(cfg, svcLogger.With(log.String("database", name)))
+	if err != nil {
+		return nil, fmt.Errorf("database %s: %w", name, err)
+	}
+	if err := c.registerMetrics(name, cfg); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("database %s: failed to register metrics: %w", name, err)
+	}
+	if err := m.Add(name, c); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	c.startStatsReporter(name, cfg.PoolStats)
+	return c, nil
+}
+
+// Add 注册已创建的 Client
+func (m *Manager) Add(name string, c *Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateInstance, name)
+	}
+	m.clients[name] = c
+	return nil
+}
+
+// Get 获取 name 实例，未注册时返回 nil
+func (m *Manager) Get(name string) *Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.clients[name]
+}
+
+// Names 返回已注册的实例名称（按字典序）
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.clients))
+	for name := range m.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close 关闭并移除 name 实例
+func (m *Manager) Close(name string) error {
+	m.mu.Lock()
+	c, ok := m.clients[name]
+	delete(m.clients, name)
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return c.Close()
+}
+
+// CloseAll 关闭并移除全部实例，用于优雅关闭
+func (m *Manager) CloseAll() error {
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = make(map[string]*Client)
+	m.mu.Unlock()
+
+	var errs []error
+	for name, c := range clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("database %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var defaultManager = NewManager()
+
+// DefaultManager 返回全局 Manager，Init 创建的单例注册在其中的 default 实例
+func DefaultManager() *Manager {
+	return defaultManager
+}
+
+// Register 在全局 Manager 中注册 name 实例
+func Register(name string, cfg *DBConfig, svcLogger log.Logger) (*Client, error) {
+	return defaultManager.Register(name, cfg, svcLogger)
+}
+
+// Get 获取全局 Manager 中的 name 实例
+func Get(name string) *Client {
+	return defaultManager.Get(name)
+}
+
+// CloseAll 关闭全局 Manager 中的全部实例
+func CloseAll() error {
+	return defaultManager.CloseAll()
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openSQLite(t *testing.T) *Client {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	return NewClientFromDB(db)
+}
+
+// TestManager tests registering, getting and closing the named instances.
+func TestManager(t *testing.T) {
+	m := NewManager()
+	orders, users := openSQLite(t), openSQLite(t)
+	if err := m.Add("orders", orders); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := m.Add("users", users); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := m.Add("orders", users); !errors.Is(err, ErrDuplicateInstance) {
+		t.Errorf("Expected ErrDuplicateInstance, got %v", err)
+	}
+	if _, err := m.Register("orders", &DBConfig{}, nil); !errors.Is(err, ErrDuplicateInstance) {
+		t.Errorf("Expected ErrDuplicateInstance from Register, got %v", err)
+	}
+	if m.Get("orders") != orders || m.Get("missing") != nil {
+		t.Error("Unexpected Get result")
+	}
+	if names := m.Names(); len(names) != 2 || names[0] != "orders" || names[1] != "users" {
+		t.Errorf("Names = %v", names)
+	}
+
+	if err := m.Close("users"); err != nil || m.Get("users") != nil {
+		t.Errorf("Close = %v", err)
+	}
+	if err := m.CloseAll(); err != nil {
+		t.Errorf("CloseAll failed: %v", err)
+	}
+	if len(m.Names()) != 0 {
+		t.Error("Expected no instance after CloseAll")
+	}
+	if orders.Health(t.Context()) == nil {
+		t.Error("Expected closed instance unhealthy")
+	}
+}
+
+// TestInitRetry tests that Init can be retried after it fails to register the default instance.
+func TestInitRetry(t *testing.T) {
+	if err := defaultManager.Add(DefaultName, openSQLite(t)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	cfg := &DBConfig{Driver: DriverSQLite, MaxOpenConns: 1}
+	if _, err := Init(cfg, &mockLogger{}); !errors.Is(err, ErrDuplicateInstance) {
+		t.Fatalf("Expected ErrDuplicateInstance, got %v", err)
+	}
+	if err := defaultManager.Close(DefaultName); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	defer func() {
+		initMu.Lock()
+		clientInstance = nil
+		initMu.Unlock()
+		_ = defaultManager.Close(DefaultName)
+	}()
+
+	c, err := Init(cfg, &mockLogger{})
+	if err != nil {
+		t.Fatalf("Init retry failed: %v", err)
+	}
+	if again, err := Init(cfg, &mockLogger{}); err != nil || again != c || GetInstance() != c {
+		t.Errorf("Expected the same instance, got %v %v", again, err)
+	}
+	if defaultManager.Get(DefaultName) != c {
+		t.Error("Expected the instance registered as default")
+	}
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/metrics"
+)
+
+const (
+	metricsPluginName  = "go-kits:metrics"
+	metricsInstanceKey = "go-kits:metrics_start"
+)
+
+// MetricsPlugin GORM 指标插件，按操作类型记录每条 SQL 的次数 db_queries_total 和耗时 db_query_duration_seconds，
+// 标签为 database（实例名称）、operation（create/query/update/delete/row/raw）、table 和 status（ok/error），
+// gorm.ErrRecordNotFound 计为 ok
+type MetricsPlugin struct {
+	database string
+	queries  metrics.Counter
+	duration metrics.Timer
+}
+
+// NewMetricsPlugin 创建指标插件，指标写入 reg，通过 db.Use 注册
+func NewMetricsPlugin(reg *metrics.Registry, database string) *MetricsPlugin {
+	return &MetricsPlugin{
+		database: database,
+		queries:  reg.Counter("db_queries_total", "Total number of SQL statements executed.", "database", "operation", "table", "status"),
+		duration: reg.Timer("db_query_duration_seconds", "Duration of SQL statements in seconds.", "database", "operation", "table", "status"),
+	}
+}
+
+// Name 实现 gorm.Plugin 接口
+func (p *MetricsPlugin) Name() string {
+	return metricsPluginName
+}
+
+// Initialize 实现 gorm.Plugin 接口，在各类操作前后注册回调
+func (p *MetricsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		op     string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("metrics:before_"+h.op, p.before); err != nil {
+			return err
+		}
+		if err := h.after("metrics:after_"+h.op, p.after(h.op)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *MetricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(metricsInstanceKey, time.Now())
+}
+
+func (p *MetricsPlugin) after(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(metricsInstanceKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		p.observe(op, db.Statement.Table, start, db.Error)
+	}
+}
+
+// observe 记录一条 SQL 的次数和耗时，也用于 Query、Exec 等绕过 GORM 回调的 SQL
+func (p *MetricsPlugin) observe(op, table string, start time.Time, err error) {
+	status := "ok"
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		status = "error"
+	}
+	p.queries.With(p.database, op, table, status).Inc()
+	p.duration.With(p.database, op, table, status).ObserveSince(start)
+}
+
+// registerMetrics 开启 EnableMetrics 时注册指标插件，指标写入 metrics.DefaultRegistry
+func (c *Client) registerMetrics(name string, cfg *DBConfig) error {
+	if !cfg.EnableMetrics {
+		return nil
+	}
+	return c.db.Use(NewMetricsPlugin(metrics.DefaultRegistry, name))
+}
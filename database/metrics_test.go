@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/baisiyi/go-kits/metrics"
+)
+
+// TestMetricsPlugin tests the counters and latencies of the statements by operation,
+// table and status.
+func TestMetricsPlugin(t *testing.T) {
+	c, err := newClient(&DBConfig{Driver: DriverSQLite, DSN: Connect{Name: filepath.Join(t.TempDir(), "metrics.db")}}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	reg := metrics.NewRegistry()
+	if err := c.db.Use(NewMetricsPlugin(reg, "local")); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+
+	db := c.GetDB(context.Background())
+	if err := db.AutoMigrate(&resolverItem{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := db.Create(&resolverItem{Name: name}).Error; err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	var item resolverItem
+	db.Where("name = ?", "missing").First(&item)
+	db.Exec("SELECT * FROM not_exists")
+
+	got := make(map[string]float64)
+	var observed uint64
+	for _, f := range reg.Gather() {
+		for _, s := range f.Series {
+			labels := make([]string, len(s.Labels))
+			for i, l := range s.Labels {
+				labels[i] = l.Value
+			}
+			switch f.Name {
+			case "db_queries_total":
+				got[strings.Join(labels, ",")] = s.Value
+			case "db_query_duration_seconds":
+				observed += s.Count
+			}
+		}
+	}
+	for key, want := range map[string]float64{
+		"local,create,resolver_item,ok": 2,
+		"local,query,resolver_item,ok":  1,
+		"local,raw,,error":              1,
+	} {
+		if got[key] != want {
+			t.Errorf("db_queries_total{%s} = %v, want %v", key, got[key], want)
+		}
+	}
+	var total float64
+	for _, v := range got {
+		total += v
+	}
+	if observed != uint64(total) {
+		t.Errorf("Expected %v latencies observed, got %d", total, observed)
+	}
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"os"
+
+	"github.com/baisiyi/go-kits/database/migrations"
+	"github.com/baisiyi/go-kits/log"
+)
+
+// Migrate 执行 dir 目录下未执行的 SQL 迁移（<version>_<name>.up.sql / .down.sql）以及注册的 Go 迁移，
+// 已执行版本记录在 schema_migrations 表中，多个副本同时启动时通过咨询锁保证只执行一次
+func (c *Client) Migrate(ctx context.Context, dir string) error {
+	ms, err := migrations.Load(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	_, err = c.migrator().Up(ctx, ms)
+	return err
+}
+
+// Rollback 回滚最近执行的 n 个迁移，SQL 迁移使用执行时记录的 down SQL，Go 迁移使用注册的 Down
+func (c *Client) Rollback(ctx context.Context, n int) error {
+	_, err := c.migrator().Down(ctx, n)
+	return err
+}
+
+func (c *Client) migrator() *migrations.Migrator {
+	logger := c.logger
+	if logger == nil {
+		logger = log.GetDefaultLogger()
+	}
+	return migrations.New(c.db, migrations.WithLogger(logger))
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestClientMigrate tests migrating and rolling back from a directory.
+func TestClientMigrate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"0001_users.up.sql":   "CREATE TABLE users (id INT);",
+		"0001_users.down.sql": "DROP TABLE users;",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	c := NewClientFromDB(db)
+
+	ctx := context.Background()
+	if err := c.Migrate(ctx, dir); err != nil || !db.Migrator().HasTable("users") {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if err := c.Rollback(ctx, 1); err != nil || db.Migrator().HasTable("users") {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+}
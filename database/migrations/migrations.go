@@ -0,0 +1,177 @@
+/*
+migrations 数据库迁移：按版本顺序执行 SQL 或 Go 迁移，记录已执行版本，多实例通过数据库咨询锁互斥
+*/
+
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Func is a Go migration step, it runs in the transaction of the migration.
+type Func func(ctx context.Context, tx *gorm.DB) error
+
+// Migration is a versioned schema change, either a Go migration with Up/Down or a
+// SQL migration with UpSQL/DownSQL.
+type Migration struct {
+	Version int64
+	Name    string
+
+	Up   Func
+	Down Func
+
+	UpSQL   string
+	DownSQL string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[int64]Migration)
+)
+
+// Register registers a Go migration, usually in init. It panics if the version is
+// registered twice or Up is nil.
+func Register(m Migration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if m.Up == nil {
+		panic(fmt.Sprintf("migrations: migration %d has no Up", m.Version))
+	}
+	if _, ok := registry[m.Version]; ok {
+		panic(fmt.Sprintf("migrations: migration %d registered twice", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+func registered(version int64) (Migration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	m, ok := registry[version]
+	return m, ok
+}
+
+// Load reads the SQL migrations of fsys and merges the registered Go migrations,
+// sorted by version. The SQL files are named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, e.g. 0001_create_users.up.sql, the other files are ignored.
+// fsys may be nil to load the Go migrations only.
+func Load(fsys fs.FS) ([]Migration, error) {
+	byVersion := make(map[int64]*Migration)
+	if fsys != nil {
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			return nil, fmt.Errorf("migrations: read dir error: %w", err)
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			version, name, up, ok := parseFileName(e.Name())
+			if !ok {
+				continue
+			}
+			data, err := fs.ReadFile(fsys, e.Name())
+			if err != nil {
+				return nil, fmt.Errorf("migrations: read %s error: %w", e.Name(), err)
+			}
+			m := byVersion[version]
+			if m == nil {
+				m = &Migration{Version: version, Name: name}
+				byVersion[version] = m
+			} else if m.Name != name {
+				return nil, fmt.Errorf("migrations: version %d used by %s and %s", version, m.Name, name)
+			}
+			if up {
+				m.UpSQL = string(data)
+			} else {
+				m.DownSQL = string(data)
+			}
+		}
+	}
+
+	registryMu.RLock()
+	for v, rm := range registry {
+		if _, ok := byVersion[v]; ok {
+			registryMu.RUnlock()
+			return nil, fmt.Errorf("migrations: version %d used by both SQL and Go migrations", v)
+		}
+		rm := rm
+		byVersion[v] = &rm
+	}
+	registryMu.RUnlock()
+
+	ms := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == nil && m.UpSQL == "" {
+			return nil, fmt.Errorf("migrations: migration %d_%s has no up", m.Version, m.Name)
+		}
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms, nil
+}
+
+// parseFileName parses <version>_<name>.up.sql or <version>_<name>.down.sql.
+func parseFileName(file string) (version int64, name string, up bool, ok bool) {
+	base := path.Base(file)
+	switch {
+	case strings.HasSuffix(base, ".up.sql"):
+		base, up = strings.TrimSuffix(base, ".up.sql"), true
+	case strings.HasSuffix(base, ".down.sql"):
+		base = strings.TrimSuffix(base, ".down.sql")
+	default:
+		return 0, "", false, false
+	}
+	v, name, _ := strings.Cut(base, "_")
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", false, false
+	}
+	return version, name, up, true
+}
+
+// splitStatements splits the SQL script into statements by the semicolons ending
+// the lines, the comment lines starting with -- are dropped.
+func splitStatements(script string) []string {
+	var (
+		stmts []string
+		cur   strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		}
+	}
+	if s := strings.TrimSpace(cur.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+func execScript(tx *gorm.DB, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrNoDown is returned when rolling back a migration without the down step.
+var ErrNoDown = errors.New("migrations: no down migration")
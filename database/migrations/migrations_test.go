@@ -0,0 +1,192 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	// a file database, the tables of an in-memory one are lost across connections
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	return db
+}
+
+func tableExists(db *gorm.DB, table string) bool {
+	return db.Migrator().HasTable(table)
+}
+
+// TestLoad tests loading the SQL migrations merged with the Go ones.
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_orders.up.sql":   {Data: []byte("CREATE TABLE orders (id INT);")},
+		"0001_add_users.up.sql":    {Data: []byte("CREATE TABLE users (id INT);")},
+		"0001_add_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+		"README.md":                {Data: []byte("ignored")},
+		"latest_add_users.up.sql":  {Data: []byte("ignored")},
+		"0003_add_orders.down.sql": {Data: []byte("DROP TABLE orders;")},
+	}
+	if _, err := Load(fsys); err == nil {
+		t.Error("Expected error for the migration without up")
+	}
+	delete(fsys, "0003_add_orders.down.sql")
+	ms, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(ms) != 2 || ms[0].Version != 1 || ms[0].Name != "add_users" || ms[0].DownSQL == "" || ms[1].Version != 2 {
+		t.Errorf("Unexpected migrations %+v", ms)
+	}
+
+	fsys["0002_other.down.sql"] = &fstest.MapFile{Data: []byte("")}
+	if _, err := Load(fsys); err == nil {
+		t.Error("Expected error for the duplicate version")
+	}
+}
+
+// TestSplitStatements tests splitting a script into statements.
+func TestSplitStatements(t *testing.T) {
+	stmts := splitStatements(`-- create users
+CREATE TABLE users (
+	id INT
+);
+
+INSERT INTO users VALUES (1); 
+INSERT INTO users VALUES (2)`)
+	if len(stmts) != 3 || stmts[0] != "CREATE TABLE users (\n\tid INT\n);" || stmts[2] != "INSERT INTO users VALUES (2)" {
+		t.Errorf("Unexpected statements %q", stmts)
+	}
+}
+
+// TestUpDown tests applying and rolling back the migrations.
+func TestUpDown(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	ms := []Migration{
+		{Version: 1, Name: "users", UpSQL: "CREATE TABLE users (id INT);\nINSERT INTO users VALUES (1);", DownSQL: "DROP TABLE users;"},
+		{Version: 2, Name: "orders", UpSQL: "CREATE TABLE orders (id INT);"},
+	}
+	m := New(db)
+	n, err := m.Up(ctx, ms)
+	if err != nil || n != 2 {
+		t.Fatalf("Up = %d, %v", n, err)
+	}
+	if !tableExists(db, "users") || !tableExists(db, "orders") {
+		t.Fatal("Expected the tables created")
+	}
+	if n, err := m.Up(ctx, ms); err != nil || n != 0 {
+		t.Errorf("Second Up = %d, %v", n, err)
+	}
+	applied, err := m.Applied(ctx)
+	if err != nil || len(applied) != 2 || applied[0].Name != "users" || applied[0].DownSQL == "" || applied[1].Version != 2 {
+		t.Errorf("Applied = %+v, %v", applied, err)
+	}
+
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrNoDown) {
+		t.Errorf("Expected ErrNoDown, got %v", err)
+	}
+	ms[1].DownSQL = "DROP TABLE orders;"
+	if err := db.Exec("UPDATE schema_migrations SET down_sql = ? WHERE version = 2", ms[1].DownSQL).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n, err := m.Down(ctx, 5); err != nil || n != 2 {
+		t.Fatalf("Down = %d, %v", n, err)
+	}
+	if tableExists(db, "users") || tableExists(db, "orders") {
+		t.Error("Expected the tables dropped")
+	}
+	if applied, _ := m.Applied(ctx); len(applied) != 0 {
+		t.Errorf("Expected no applied migration, got %+v", applied)
+	}
+}
+
+// TestUpWithReplica tests that the migrations table is read on the locked connection
+// of the primary, not routed to the replica by dbresolver.
+func TestUpWithReplica(t *testing.T) {
+	db := openDB(t)
+	// the replica has no migrations table
+	replica := sqlite.Open(filepath.Join(t.TempDir(), "replica.db"))
+	if err := db.Use(dbresolver.Register(dbresolver.Config{Replicas: []gorm.Dialector{replica}})); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	ms := []Migration{{Version: 1, Name: "users", UpSQL: "CREATE TABLE users (id INT);", DownSQL: "DROP TABLE users;"}}
+	m := New(db)
+	if n, err := m.Up(ctx, ms); err != nil || n != 1 {
+		t.Fatalf("Up = %d, %v", n, err)
+	}
+	if applied, err := m.Applied(ctx); err != nil || len(applied) != 1 {
+		t.Errorf("Applied = %+v, %v", applied, err)
+	}
+	if n, err := m.Down(ctx, 1); err != nil || n != 1 {
+		t.Errorf("Down = %d, %v", n, err)
+	}
+}
+
+// TestUpFailure tests that a failed migration is rolled back and stops the later ones.
+func TestUpFailure(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	m := New(db, WithTable("custom_migrations"))
+	ms := []Migration{
+		{Version: 1, Name: "ok", UpSQL: "CREATE TABLE a (id INT);"},
+		{Version: 2, Name: "bad", UpSQL: "INSERT INTO a VALUES (1);\nINSERT INTO missing VALUES (1);"},
+		{Version: 3, Name: "later", UpSQL: "CREATE TABLE c (id INT);"},
+	}
+	n, err := m.Up(ctx, ms)
+	if err == nil || n != 1 {
+		t.Fatalf("Up = %d, %v", n, err)
+	}
+	var count int64
+	db.Table("a").Count(&count)
+	if count != 0 || tableExists(db, "c") {
+		t.Errorf("Expected the failed migration rolled back and the later skipped, count %d", count)
+	}
+	if applied, _ := m.Applied(ctx); len(applied) != 1 || applied[0].Version != 1 {
+		t.Errorf("Applied = %+v", applied)
+	}
+}
+
+// TestGoMigration tests the registered Go migrations.
+func TestGoMigration(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	Register(Migration{
+		Version: 100,
+		Name:    "go_table",
+		Up: func(ctx context.Context, tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE go_table (id INT)").Error
+		},
+		Down: func(ctx context.Context, tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE go_table").Error
+		},
+	})
+	defer func() {
+		registryMu.Lock()
+		delete(registry, 100)
+		registryMu.Unlock()
+	}()
+
+	ms, err := Load(nil)
+	if err != nil || len(ms) != 1 {
+		t.Fatalf("Load = %+v, %v", ms, err)
+	}
+	m := New(db)
+	if _, err := m.Up(ctx, ms); err != nil || !tableExists(db, "go_table") {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if _, err := m.Down(ctx, 1); err != nil || tableExists(db, "go_table") {
+		t.Fatalf("Down failed: %v", err)
+	}
+}
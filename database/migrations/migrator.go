@@ -0,0 +1,247 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// DefaultTable is the default table recording the applied versions.
+const DefaultTable = "schema_migrations"
+
+// Record is a row of the migrations table.
+type Record struct {
+	Version   int64
+	Name      string
+	DownSQL   string
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back the migrations on a database. Migrations run on
+// a dedicated connection holding an advisory lock, so replicas started together
+// apply each migration once: GET_LOCK on MySQL, pg_advisory_lock on PostgreSQL,
+// SQLite needs no lock since it allows one writer only. Every statement runs in a
+// transaction on that connection, also with the read/write splitting of dbresolver.
+type Migrator struct {
+	db     *gorm.DB
+	table  string
+	logger log.Logger
+}
+
+// Option is the option of Migrator.
+type Option func(*Migrator)
+
+// WithTable sets the table recording the applied versions, default as schema_migrations.
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLogger sets the logger of the progress, default as the default logger.
+func WithLogger(logger log.Logger) Option {
+	return func(m *Migrator) {
+		m.logger = logger
+	}
+}
+
+// New creates a Migrator of db.
+func New(db *gorm.DB, opts ...Option) *Migrator {
+	m := &Migrator{db: db, table: DefaultTable}
+	for _, o := range opts {
+		o(m)
+	}
+	if m.logger == nil {
+		m.logger = log.GetDefaultLogger()
+	}
+	return m
+}
+
+// Up applies the migrations of ms not applied yet in version order, each in its own
+// transaction, and returns the number applied. It stops at the first failure.
+func (m *Migrator) Up(ctx context.Context, ms []Migration) (int, error) {
+	n := 0
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		applied, err := m.records(db)
+		if err != nil {
+			return err
+		}
+		done := make(map[int64]bool, len(applied))
+		for _, r := range applied {
+			done[r.Version] = true
+		}
+		for _, mig := range ms {
+			if done[mig.Version] {
+				continue
+			}
+			start := time.Now()
+			m.logger.Infof("[MIGRATE] Applying %d_%s", mig.Version, mig.Name)
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if mig.Up != nil {
+					if err := mig.Up(ctx, tx); err != nil {
+						return err
+					}
+				} else if err := execScript(tx, mig.UpSQL); err != nil {
+					return err
+				}
+				return tx.Exec(fmt.Sprintf("INSERT INTO %s (version, name, down_sql, applied_at) VALUES (?, ?, ?, ?)", m.table),
+					mig.Version, mig.Name, mig.DownSQL, time.Now()).Error
+			}); err != nil {
+				m.logger.Errorf("[MIGRATE] Failed %d_%s | Error: %v", mig.Version, mig.Name, err)
+				return fmt.Errorf("migrations: apply %d_%s error: %w", mig.Version, mig.Name, err)
+			}
+			m.logger.Infof("[MIGRATE] Applied %d_%s | Duration: %v", mig.Version, mig.Name, time.Since(start))
+			n++
+		}
+		if n == 0 {
+			m.logger.Infof("[MIGRATE] No pending migrations")
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Down rolls back the latest n applied migrations and returns the number rolled back.
+// A migration is rolled back by the Down of the registered Go migration, or the down
+// SQL saved when it was applied, ErrNoDown is returned if neither exists.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	rolled := 0
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		applied, err := m.records(db)
+		if err != nil {
+			return err
+		}
+		for i := len(applied) - 1; i >= 0 && rolled < n; i-- {
+			r := applied[i]
+			goMig, isGo := registered(r.Version)
+			if (!isGo || goMig.Down == nil) && r.DownSQL == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDown, r.Version, r.Name)
+			}
+			start := time.Now()
+			m.logger.Infof("[MIGRATE] Rolling back %d_%s", r.Version, r.Name)
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if isGo && goMig.Down != nil {
+					if err := goMig.Down(ctx, tx); err != nil {
+						return err
+					}
+				} else if err := execScript(tx, r.DownSQL); err != nil {
+					return err
+				}
+				return tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE version = ?", m.table), r.Version).Error
+			}); err != nil {
+				m.logger.Errorf("[MIGRATE] Failed rolling back %d_%s | Error: %v", r.Version, r.Name, err)
+				return fmt.Errorf("migrations: roll back %d_%s error: %w", r.Version, r.Name, err)
+			}
+			m.logger.Infof("[MIGRATE] Rolled back %d_%s | Duration: %v", r.Version, r.Name, time.Since(start))
+			rolled++
+		}
+		return nil
+	})
+	return rolled, err
+}
+
+// Applied returns the applied migrations in version order.
+func (m *Migrator) Applied(ctx context.Context) ([]Record, error) {
+	db := m.db.WithContext(ctx)
+	if err := m.createTable(db); err != nil {
+		return nil, err
+	}
+	return m.records(db)
+}
+
+// createTable and records run in a transaction, a read/write splitting plugin such as
+// dbresolver routes the statements of the session to the pools except in a transaction,
+// while they must run on the locked connection and read the latest versions.
+func (m *Migrator) createTable(db *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	down_sql TEXT,
+	applied_at TIMESTAMP NOT NULL
+)`, m.table)).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migrations: create table %s error: %w", m.table, err)
+	}
+	return nil
+}
+
+func (m *Migrator) records(db *gorm.DB) ([]Record, error) {
+	var rs []Record
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Raw(fmt.Sprintf("SELECT version, name, down_sql, applied_at FROM %s ORDER BY version", m.table)).
+			Scan(&rs).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migrations: read table %s error: %w", m.table, err)
+	}
+	return rs, nil
+}
+
+// withLock runs fn with a session on a dedicated connection holding the advisory lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(db *gorm.DB) error) (err error) {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrations: get connection error: %w", err)
+	}
+	defer conn.Close()
+
+	db := m.db.Session(&gorm.Session{NewDB: true, Context: ctx})
+	db.Statement.ConnPool = conn
+
+	unlock, err := m.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// ctx may be canceled, release the lock anyway or the connection keeps it
+		if uerr := unlock(context.Background()); uerr != nil && err == nil {
+			err = uerr
+		}
+	}()
+
+	if err := m.createTable(db); err != nil {
+		return err
+	}
+	return fn(db)
+}
+
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) (func(context.Context) error, error) {
+	var acquire, release string
+	var key any
+	switch m.db.Dialector.Name() {
+	case "mysql":
+		key = "migrations:" + m.table
+		acquire, release = "SELECT GET_LOCK(?, -1)", "SELECT RELEASE_LOCK(?)"
+	case "postgres":
+		h := fnv.New64a()
+		_, _ = h.Write([]byte("migrations:" + m.table))
+		key = int64(h.Sum64())
+		acquire, release = "SELECT pg_advisory_lock($1)", "SELECT pg_advisory_unlock($1)"
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	start := time.Now()
+	if _, err := conn.ExecContext(ctx, acquire, key); err != nil {
+		return nil, fmt.Errorf("migrations: acquire lock error: %w", err)
+	}
+	m.logger.Debugf("[MIGRATE] Lock acquired | Wait: %v", time.Since(start))
+	return func(ctx context.Context) error {
+		if _, err := conn.ExecContext(ctx, release, key); err != nil && !errors.Is(err, sql.ErrConnDone) {
+			return fmt.Errorf("migrations: release lock error: %w", err)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,230 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SQLDB 返回主库的 *sql.DB，用于热点路径绕过 GORM；连接池由 Client 管理，不要关闭
+func (c *Client) SQLDB() (*sql.DB, error) {
+	return c.db.DB()
+}
+
+// Exec 执行带 :name 命名参数的 SQL，arg 为 map[string]any 或结构体（指针），结构体按列名或字段名取值，
+// 没有参数时为 nil；PostgreSQL 的 :: 类型转换和引号内的内容不作为参数。
+// 不经过 GORM 的回调，直接使用 Client 的连接池（在 Transact 中调用时使用该事务），
+// 通过 Client 的日志记录 SQL、慢查询和错误，开启 EnableMetrics 时记录 operation 为 raw 的指标，
+// 配置熔断时同样受熔断控制
+func Exec(ctx context.Context, c *Client, query string, arg any) (sql.Result, error) {
+	q, args, err := c.bindNamed(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	b := c.breaker()
+	probe, err := b.enter()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	res, err := c.DB(ctx).Statement.ConnPool.ExecContext(ctx, q, args...)
+	b.leave(probe, err)
+	var rows int64
+	if err == nil {
+		rows, _ = res.RowsAffected()
+	}
+	c.traceSQL(ctx, "raw", start, q, args, rows, err)
+	return res, err
+}
+
+// Query 执行带 :name 命名参数的查询并将每行扫描为 T：T 为结构体时按列名匹配字段（规则同 GORM），
+// 没有对应字段的列被忽略；T 为其他类型时查询只能返回一列。参数、连接和日志同 Exec，
+// 指标的 operation 为 row
+func Query[T any](ctx context.Context, c *Client, query string, arg any) ([]T, error) {
+	q, args, err := c.bindNamed(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	b := c.breaker()
+	probe, err := b.enter()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	items, err := queryRows[T](ctx, c, q, args)
+	b.leave(probe, err)
+	c.traceSQL(ctx, "row", start, q, args, int64(len(items)), err)
+	return items, err
+}
+
+func queryRows[T any](ctx context.Context, c *Client, query string, args []any) ([]T, error) {
+	rows, err := c.DB(ctx).Statement.ConnPool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	dest, err := rowDest[T](ctx, c, columns)
+	if err != nil {
+		return nil, err
+	}
+	items := []T{}
+	for rows.Next() {
+		var item T
+		if err := rows.Scan(dest(&item)...); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// rowDest 返回将一行扫描到 item 的目标地址
+func rowDest[T any](ctx context.Context, c *Client, columns []string) (func(item *T) []any, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) || reflect.PointerTo(t).Implements(scannerType) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("database: scan %d columns into %s", len(columns), t)
+		}
+		return func(item *T) []any { return []any{item} }, nil
+	}
+	s, err := parseSchema(c, new(T))
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]*schema.Field, len(columns))
+	for i, column := range columns {
+		if f := s.LookUpField(column); f != nil && f.DBName != "" {
+			fields[i] = f
+		}
+	}
+	return func(item *T) []any {
+		rv := reflect.ValueOf(item).Elem()
+		dest := make([]any, len(fields))
+		for i, f := range fields {
+			if f == nil {
+				dest[i] = new(any)
+				continue
+			}
+			dest[i] = f.ReflectValueOf(ctx, rv).Addr().Interface()
+		}
+		return dest
+	}, nil
+}
+
+func parseSchema(c *Client, v any) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: c.db}
+	if err := stmt.Parse(v); err != nil {
+		return nil, fmt.Errorf("database: parse model error: %w", err)
+	}
+	return stmt.Schema, nil
+}
+
+// traceSQL 通过 GORM 日志和指标插件记录绕过 GORM 回调执行的 SQL
+func (c *Client) traceSQL(ctx context.Context, op string, start time.Time, query string, args []any, rows int64, err error) {
+	c.db.Logger.Trace(ctx, start, func() (string, int64) {
+		return c.db.Dialector.Explain(query, args...), rows
+	}, err)
+	if p, ok := c.db.Plugins[metricsPluginName].(*MetricsPlugin); ok {
+		p.observe(op, "", start, err)
+	}
+}
+
+// bindNamed 按 arg 绑定 query 中的命名参数，占位符按驱动替换为 ? 或 $n
+func (c *Client) bindNamed(ctx context.Context, query string, arg any) (string, []any, error) {
+	lookup, err := c.namedLookup(ctx, arg)
+	if err != nil {
+		return "", nil, err
+	}
+	return bindNamed(query, c.db.Dialector.Name() == DriverPostgres, lookup)
+}
+
+// namedLookup 返回按名称获取参数值的函数
+func (c *Client) namedLookup(ctx context.Context, arg any) (func(name string) (any, bool), error) {
+	switch a := arg.(type) {
+	case nil:
+		return func(string) (any, bool) { return nil, false }, nil
+	case map[string]any:
+		return func(name string) (any, bool) {
+			v, ok := a[name]
+			return v, ok
+		}, nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(arg))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("database: named arg must be a map[string]any or a struct, got %T", arg)
+	}
+	s, err := parseSchema(c, arg)
+	if err != nil {
+		return nil, err
+	}
+	return func(name string) (any, bool) {
+		f := s.LookUpField(name)
+		if f == nil || f.DBName == "" {
+			return nil, false
+		}
+		v, _ := f.ValueOf(ctx, rv)
+		return v, true
+	}, nil
+}
+
+// bindNamed 将 query 中的 :name 替换为占位符，dollar 为 true 时使用 PostgreSQL 的 $n，否则使用 ?；
+// 引号内的内容、:: 和 := 原样保留
+func bindNamed(query string, dollar bool, lookup func(name string) (any, bool)) (string, []any, error) {
+	var (
+		b    strings.Builder
+		args []any
+	)
+	b.Grow(len(query))
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := strings.IndexByte(query[i+1:], ch)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String(), args, nil
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case ch == ':' && i+1 < len(query) && query[i+1] == ':':
+			b.WriteString("::")
+			i++
+		case ch == ':' && i+1 < len(query) && isNameStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && (isNameStart(query[j]) || query[j] >= '0' && query[j] <= '9') {
+				j++
+			}
+			name := query[i+1 : j]
+			v, ok := lookup(name)
+			if !ok {
+				return "", nil, fmt.Errorf("database: named parameter %s not found", name)
+			}
+			args = append(args, v)
+			if dollar {
+				fmt.Fprintf(&b, "$%d", len(args))
+			} else {
+				b.WriteByte('?')
+			}
+			i = j - 1
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String(), args, nil
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
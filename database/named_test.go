@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/metrics"
+)
+
+// TestBindNamed tests replacing the named parameters with the placeholders of the drivers.
+func TestBindNamed(t *testing.T) {
+	lookup := func(name string) (any, bool) {
+		v, ok := map[string]any{"id": 1, "name": "a", "user_id": 2}[name]
+		return v, ok
+	}
+	tests := []struct {
+		query  string
+		dollar bool
+		want   string
+		args   []any
+	}{
+		{"SELECT * FROM t WHERE id = :id AND name = :name", false, "SELECT * FROM t WHERE id = ? AND name = ?", []any{1, "a"}},
+		{"SELECT * FROM t WHERE id = :id OR parent = :id", true, "SELECT * FROM t WHERE id = $1 OR parent = $2", []any{1, 1}},
+		{"SELECT :user_id::text, ':id', \"a:b\", `:c` FROM t", true, "SELECT $1::text, ':id', \"a:b\", `:c` FROM t", []any{2}},
+		{"SET @x := 1, @y = ':name", false, "SET @x := 1, @y = ':name", nil},
+	}
+	for _, tt := range tests {
+		got, args, err := bindNamed(tt.query, tt.dollar, lookup)
+		if err != nil || got != tt.want || len(args) != len(tt.args) {
+			t.Errorf("bindNamed(%q) = %q, %v, %v, want %q, %v", tt.query, got, args, err, tt.want, tt.args)
+			continue
+		}
+		for i := range args {
+			if args[i] != tt.args[i] {
+				t.Errorf("bindNamed(%q) args = %v, want %v", tt.query, args, tt.args)
+			}
+		}
+	}
+	if _, _, err := bindNamed("SELECT :missing", false, lookup); err == nil {
+		t.Error("Expected error for the missing parameter")
+	}
+}
+
+// TestQueryExec tests the named query and exec with the struct scanning, the logs, the
+// metrics and the transaction in ctx.
+func TestQueryExec(t *testing.T) {
+	logger := &mockLogger{}
+	c, err := newClient(&DBConfig{Driver: DriverSQLite, DSN: Connect{Name: filepath.Join(t.TempDir(), "named.db")}, LogLevel: 4}, logger)
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+	if err := c.GetDB(ctx).AutoMigrate(&repoUser{}); err != nil {
+		t.Fatal(err)
+	}
+	reg := metrics.NewRegistry()
+	if err := c.db.Use(NewMetricsPlugin(reg, "local")); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := c.SQLDB()
+	if err != nil || sqlDB == nil {
+		t.Fatalf("SQLDB = %v, %v", sqlDB, err)
+	}
+
+	insert := "INSERT INTO repo_user (id, name, status) VALUES (:id, :name, :status)"
+	if _, err := Exec(ctx, c, insert, &repoUser{ID: 1, Name: "a", Status: 1}); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	res, err := Exec(ctx, c, insert, map[string]any{"id": 2, "name": "b", "status": 2})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Errorf("RowsAffected = %d, want 1", n)
+	}
+
+	users, err := Query[repoUser](ctx, c, "SELECT id, name, status, 'x' AS extra FROM repo_user WHERE status >= :status ORDER BY id", map[string]any{"status": 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(users) != 2 || users[0].Name != "a" || users[1].ID != 2 || users[1].Status != 2 {
+		t.Errorf("Unexpected users %+v", users)
+	}
+	if sql := fmt.Sprint(logger.lastArgs...); !strings.Contains(sql, "WHERE status >= 1") {
+		t.Errorf("Expected the query logged with the args, got %s", sql)
+	}
+	names, err := Query[string](ctx, c, "SELECT name FROM repo_user WHERE id = :ID", repoUser{ID: 2})
+	if err != nil || len(names) != 1 || names[0] != "b" {
+		t.Errorf("Query names = %v, %v", names, err)
+	}
+	if _, err := Query[string](ctx, c, "SELECT id, name FROM repo_user", nil); err == nil {
+		t.Error("Expected error for scanning 2 columns into string")
+	}
+	if _, err := Exec(ctx, c, insert, 1); err == nil {
+		t.Error("Expected error for the invalid arg")
+	}
+
+	// the statements in Transact are rolled back with the transaction
+	rollback := errors.New("rollback")
+	err = c.Transact(ctx, func(tx *gorm.DB) error {
+		if _, err := Exec(tx.Statement.Context, c, "DELETE FROM repo_user WHERE id = :id", map[string]any{"id": 1}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Transact = %v", err)
+	}
+	ids, _ := Query[int64](ctx, c, "SELECT id FROM repo_user", nil)
+	if len(ids) != 2 {
+		t.Errorf("Expected the delete rolled back, got ids %v", ids)
+	}
+
+	got := make(map[string]float64)
+	for _, f := range reg.Gather() {
+		if f.Name != "db_queries_total" {
+			continue
+		}
+		for _, s := range f.Series {
+			got[s.Labels[1].Value+","+s.Labels[3].Value] += s.Value
+		}
+	}
+	if got["raw,ok"] != 3 || got["raw,error"] != 0 || got["row,ok"] != 3 || got["row,error"] != 1 {
+		t.Errorf("Unexpected metrics %v", got)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+outbox 事务性发件箱：业务事务内写入消息，由 Relay 异步投递到消息队列，保证消息至少投递一次
+*/
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/contextkit"
+)
+
+// HeaderIdempotencyKey is the header carrying Message.Key, consumers dedup by it.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// Status is the delivery status of a message.
+type Status int8
+
+const (
+	StatusPending Status = iota
+	StatusSent
+	// StatusDead means the message exceeded RelayConfig.MaxAttempts.
+	StatusDead
+)
+
+// Message is a row of the outbox table.
+type Message struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"`
+	// Key is the idempotency key, a ULID generated on Enqueue if empty.
+	Key string `gorm:"size:64;not null;uniqueIndex"`
+	// Topic is the kafka topic, the rabbit routing key or the nats subject.
+	Topic string `gorm:"size:255;not null"`
+	// PartitionKey is the kafka message key, optional.
+	PartitionKey string              `gorm:"size:255"`
+	Payload      []byte              `gorm:"not null"`
+	Headers      map[string][]string `gorm:"serializer:json"`
+	Status       Status              `gorm:"not null;default:0;index:idx_outbox_pending,priority:1"`
+	Attempts     int                 `gorm:"not null;default:0"`
+	LastError    string              `gorm:"size:1024"`
+	// NextAttemptAt is when the relay picks the message up, pushed back after a failure.
+	NextAttemptAt time.Time `gorm:"not null;index:idx_outbox_pending,priority:2"`
+	CreatedAt     time.Time `gorm:"not null"`
+	SentAt        *time.Time
+}
+
+// TableName returns the name of the outbox table.
+func (Message) TableName() string {
+	return "outbox_message"
+}
+
+// AutoMigrate creates or updates the outbox table.
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Message{})
+}
+
+// Enqueue inserts the message with tx, which should be the transaction of the
+// business change so both commit or roll back together. The request context
+// of ctx is saved in the headers and restored when the relay publishes.
+func Enqueue(ctx context.Context, tx *gorm.DB, msgs ...*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	md := make(map[string][]string)
+	contextkit.InjectMetadata(ctx, md)
+	now := time.Now()
+	for _, m := range msgs {
+		if m.Topic == "" {
+			return errors.New("outbox: topic empty")
+		}
+		if m.Key == "" {
+			m.Key = contextkit.NewULID()
+		}
+		if len(md) > 0 {
+			if m.Headers == nil {
+				m.Headers = make(map[string][]string, len(md))
+			}
+			for k, v := range md {
+				if _, ok := m.Headers[k]; !ok {
+					m.Headers[k] = v
+				}
+			}
+		}
+		m.Status = StatusPending
+		m.NextAttemptAt = now
+		m.CreatedAt = now
+	}
+	return tx.WithContext(ctx).Create(msgs).Error
+}
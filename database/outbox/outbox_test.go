@@ -0,0 +1,180 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/baisiyi/go-kits/contextkit"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := AutoMigrate(db); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	return db
+}
+
+// TestEnqueueAndRelay tests that the enqueued messages are published and marked sent.
+func TestEnqueueAndRelay(t *testing.T) {
+	db := newTestDB(t)
+	ctx := contextkit.WithRequestID(context.Background(), "req-1")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return Enqueue(ctx, tx, &Message{Topic: "orders", Payload: []byte("a")},
+			&Message{Topic: "orders", Payload: []byte("b")})
+	})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	var (
+		keys []string
+		reqs []string
+	)
+	r := NewRelay(db, PublisherFunc(func(ctx context.Context, m *Message) error {
+		keys = append(keys, m.Key)
+		reqs = append(reqs, contextkit.RequestID(ctx))
+		return nil
+	}), RelayConfig{})
+
+	n, err := r.RelayOnce(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] == keys[1] {
+		t.Errorf("Expected distinct idempotency keys, got %v", keys)
+	}
+	if reqs[0] != "req-1" {
+		t.Errorf("Expected request id restored, got %q", reqs[0])
+	}
+
+	var sent int64
+	db.Model(&Message{}).Where("status = ?", StatusSent).Count(&sent)
+	if sent != 2 {
+		t.Errorf("sent = %d, want 2", sent)
+	}
+	if n, _ := r.RelayOnce(context.Background()); n != 0 {
+		t.Errorf("Expected nothing to relay, got %d", n)
+	}
+	if st := r.Stats(); st.Published != 2 || st.Pending != 0 {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+// TestRelayRetry tests the backoff and the dead status after max attempts.
+func TestRelayRetry(t *testing.T) {
+	db := newTestDB(t)
+	if err := Enqueue(context.Background(), db, &Message{Topic: "orders", Payload: []byte("a")}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	now := time.Now()
+	var lag time.Duration
+	r := NewRelay(db, PublisherFunc(func(ctx context.Context, m *Message) error {
+		return errors.New("broker down")
+	}), RelayConfig{MaxAttempts: 2, RetryBackoff: time.Minute}, WithLagObserver(func(l time.Duration, pending int64) {
+		lag = l
+	}))
+	r.now = func() time.Time { return now }
+
+	if n, err := r.RelayOnce(context.Background()); n != 1 || err != nil {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if lag < 0 || r.Stats().Pending != 1 {
+		t.Errorf("Expected pending message with lag, got %+v", r.Stats())
+	}
+	// 退避期间不会再次投递
+	if n, _ := r.RelayOnce(context.Background()); n != 0 {
+		t.Errorf("Expected message backed off, got %d", n)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if n, _ := r.RelayOnce(context.Background()); n != 1 {
+		t.Errorf("Expected message retried, got %d", n)
+	}
+	var m Message
+	db.First(&m)
+	if m.Status != StatusDead || m.Attempts != 2 || m.LastError != "broker down" {
+		t.Errorf("Expected dead message, got %+v", m)
+	}
+	if st := r.Stats(); st.Dead != 1 || st.Failed != 2 {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+// TestRelayMarkFailure tests that a failed mark does not roll back the marks of the
+// messages published before, and the stats count the saved marks only.
+func TestRelayMarkFailure(t *testing.T) {
+	db := newTestDB(t)
+	if err := Enqueue(context.Background(), db, &Message{Topic: "orders", Payload: []byte("a")},
+		&Message{Topic: "orders", Payload: []byte("b")}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	updates := 0
+	err := db.Callback().Update().Before("gorm:update").Register("test:fail_second", func(db *gorm.DB) {
+		if updates++; updates == 2 {
+			db.AddError(errors.New("db down"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRelay(db, PublisherFunc(func(ctx context.Context, m *Message) error {
+		return nil
+	}), RelayConfig{})
+	if n, err := r.RelayOnce(context.Background()); n != 2 || err == nil {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	var sent int64
+	db.Model(&Message{}).Where("status = ?", StatusSent).Count(&sent)
+	if sent != 1 {
+		t.Errorf("sent = %d, want 1", sent)
+	}
+	if st := r.Stats(); st.Published != 1 {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+// TestRelayLease tests that with SkipLocked the batch is leased while it is published.
+func TestRelayLease(t *testing.T) {
+	db := newTestDB(t)
+	if err := Enqueue(context.Background(), db, &Message{Topic: "orders", Payload: []byte("a")}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	now := time.Now()
+	var leased bool
+	r := NewRelay(db, PublisherFunc(func(ctx context.Context, m *Message) error {
+		var cur Message
+		db.First(&cur, m.ID)
+		leased = cur.NextAttemptAt.After(now)
+		return nil
+	}), RelayConfig{SkipLocked: true})
+	r.now = func() time.Time { return now }
+	if n, err := r.RelayOnce(context.Background()); n != 1 || err != nil {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if !leased {
+		t.Error("Expected the message leased while published")
+	}
+}
+
+// TestEnqueueValidate tests that a message without topic is rejected.
+func TestEnqueueValidate(t *testing.T) {
+	db := newTestDB(t)
+	if err := Enqueue(context.Background(), db, &Message{}); err == nil {
+		t.Error("Expected error for empty topic")
+	}
+}
@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"context"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/baisiyi/go-kits/mq/kafka"
+	"github.com/baisiyi/go-kits/mq/nats"
+	"github.com/baisiyi/go-kits/mq/rabbit"
+)
+
+// KafkaPublisher publishes to the topic with PartitionKey as the message key.
+func KafkaPublisher(p *kafka.Producer) Publisher {
+	return PublisherFunc(func(ctx context.Context, m *Message) error {
+		msg := kafka.Message{Topic: m.Topic, Value: m.Payload}
+		if m.PartitionKey != "" {
+			msg.Key = []byte(m.PartitionKey)
+		}
+		for k, vs := range m.Headers {
+			for _, v := range vs {
+				msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+			}
+		}
+		msg.Headers = append(msg.Headers, kafka.Header{Key: HeaderIdempotencyKey, Value: []byte(m.Key)})
+		return p.Send(ctx, msg)
+	})
+}
+
+// RabbitPublisher publishes to the exchange with Topic as the routing key and
+// Key as the message id.
+func RabbitPublisher(p *rabbit.Publisher, exchange string) Publisher {
+	return PublisherFunc(func(ctx context.Context, m *Message) error {
+		headers := amqp.Table{HeaderIdempotencyKey: m.Key}
+		for k, vs := range m.Headers {
+			if len(vs) > 0 {
+				headers[k] = vs[0]
+			}
+		}
+		return p.Publish(ctx, exchange, m.Topic, rabbit.Publishing{
+			MessageId:    m.Key,
+			DeliveryMode: amqp.Persistent,
+			Headers:      headers,
+			Body:         m.Payload,
+		})
+	})
+}
+
+// NATSPublisher publishes to jetstream with Topic as the subject and Key as
+// the message id, which the stream dedups within its duplicate window.
+func NATSPublisher(c *nats.Client) Publisher {
+	return PublisherFunc(func(ctx context.Context, m *Message) error {
+		msg := natsgo.NewMsg(m.Topic)
+		msg.Data = m.Payload
+		for k, vs := range m.Headers {
+			for _, v := range vs {
+				msg.Header.Add(k, v)
+			}
+		}
+		msg.Header.Set(HeaderIdempotencyKey, m.Key)
+		_, err := c.JetStream().PublishMsg(ctx, msg, jetstream.WithMsgID(m.Key))
+		return err
+	})
+}
@@ -0,0 +1,288 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/baisiyi/go-kits/contextkit"
+	"github.com/baisiyi/go-kits/log"
+)
+
+// Publisher publishes an outbox message to the message queue.
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// PublisherFunc is the function adapter of Publisher.
+type PublisherFunc func(ctx context.Context, msg *Message) error
+
+// Publish implements Publisher.
+func (f PublisherFunc) Publish(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// RelayConfig is the configuration of Relay.
+type RelayConfig struct {
+	// Interval is the poll interval when the last batch was not full, default as 1s.
+	Interval time.Duration `yaml:"interval" mapstructure:"interval"`
+	// BatchSize is the max number of messages per poll, default as 100.
+	BatchSize int `yaml:"batch_size" mapstructure:"batch_size"`
+	// MaxAttempts is the number of attempts before a message is dead, default as 10.
+	MaxAttempts int `yaml:"max_attempts" mapstructure:"max_attempts"`
+	// RetryBackoff is the delay after the first failure, doubled every attempt, default as 1s.
+	RetryBackoff time.Duration `yaml:"retry_backoff" mapstructure:"retry_backoff"`
+	// MaxBackoff caps the retry delay, default as 5m.
+	MaxBackoff time.Duration `yaml:"max_backoff" mapstructure:"max_backoff"`
+	// SkipLocked selects the batch with FOR UPDATE SKIP LOCKED so several relay
+	// replicas share the table, requires MySQL 8 or PostgreSQL.
+	SkipLocked bool `yaml:"skip_locked" mapstructure:"skip_locked"`
+	// PublishTimeout is the timeout of each publish, default as 10s.
+	PublishTimeout time.Duration `yaml:"publish_timeout" mapstructure:"publish_timeout"`
+}
+
+func (c *RelayConfig) setDefaults() {
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 10
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 5 * time.Minute
+	}
+	if c.PublishTimeout <= 0 {
+		c.PublishTimeout = 10 * time.Second
+	}
+}
+
+// RelayStats is the statistics of a relay.
+type RelayStats struct {
+	Published int64
+	Failed    int64
+	Dead      int64
+	// Pending is the number of pending messages at the last poll.
+	Pending int64
+	// Lag is the age of the oldest pending message at the last poll.
+	Lag time.Duration
+}
+
+// LagObserver is notified of the outbox lag after each poll, e.g. to export it as a gauge.
+type LagObserver func(lag time.Duration, pending int64)
+
+// RelayOption is the option of Relay.
+type RelayOption func(*Relay)
+
+// WithLagObserver sets the observer of the outbox lag.
+func WithLagObserver(o LagObserver) RelayOption {
+	return func(r *Relay) {
+		r.observer = o
+	}
+}
+
+// WithRelayLogger sets the logger, default as the default logger.
+func WithRelayLogger(l log.Logger) RelayOption {
+	return func(r *Relay) {
+		r.logger = l
+	}
+}
+
+// Relay polls the pending outbox messages, publishes them and marks them sent.
+type Relay struct {
+	db       *gorm.DB
+	pub      Publisher
+	cfg      RelayConfig
+	observer LagObserver
+	logger   log.Logger
+	now      func() time.Time
+
+	mu    sync.Mutex
+	stats RelayStats
+}
+
+// NewRelay creates a relay publishing the outbox messages of db with pub.
+func NewRelay(db *gorm.DB, pub Publisher, cfg RelayConfig, opts ...RelayOption) *Relay {
+	cfg.setDefaults()
+	r := &Relay{db: db, pub: pub, cfg: cfg, now: time.Now}
+	for _, o := range opts {
+		o(r)
+	}
+	if r.logger == nil {
+		r.logger = log.GetDefaultLogger()
+	}
+	return r
+}
+
+// Run polls until ctx is done. A full batch is followed by the next poll immediately.
+func (r *Relay) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Errorf("outbox: relay error: %v", err)
+		}
+		if n >= r.cfg.BatchSize && err == nil {
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.cfg.Interval)
+		}
+	}
+}
+
+// RelayOnce publishes one batch of the due messages, returning the batch size. Each
+// message is marked in its own statement right after it is published, so a failure
+// later in the batch does not roll back the marks and publish the sent messages again.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	msgs, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range msgs {
+		if err := r.deliver(ctx, m); err != nil {
+			return len(msgs), err
+		}
+	}
+	return len(msgs), r.updateLag(ctx)
+}
+
+// claim selects the due messages. With SkipLocked, the selected messages are leased by
+// pushing next_attempt_at past the publishing of the batch before the row locks are
+// released, so the other replicas skip them while they are published.
+func (r *Relay) claim(ctx context.Context) ([]*Message, error) {
+	var msgs []*Message
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := r.now()
+		q := tx.Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+			Order("id").Limit(r.cfg.BatchSize)
+		if r.cfg.SkipLocked {
+			q = q.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked})
+		}
+		if err := q.Find(&msgs).Error; err != nil {
+			return fmt.Errorf("outbox: select pending error: %w", err)
+		}
+		if !r.cfg.SkipLocked || len(msgs) == 0 {
+			return nil
+		}
+		ids := make([]uint64, len(msgs))
+		for i, m := range msgs {
+			ids[i] = m.ID
+		}
+		lease := now.Add(time.Duration(len(msgs)) * r.cfg.PublishTimeout)
+		if err := tx.Model(&Message{}).Where("id IN ?", ids).Update("next_attempt_at", lease).Error; err != nil {
+			return fmt.Errorf("outbox: lease messages error: %w", err)
+		}
+		return nil
+	})
+	return msgs, err
+}
+
+// deliver publishes m and marks the result, the stats are counted after the mark is saved.
+func (r *Relay) deliver(ctx context.Context, m *Message) error {
+	pctx := contextkit.ExtractMetadata(ctx, m.Headers)
+	pctx, cancel := context.WithTimeout(pctx, r.cfg.PublishTimeout)
+	err := r.pub.Publish(pctx, m)
+	cancel()
+
+	now := r.now()
+	updates := map[string]any{"attempts": m.Attempts + 1}
+	dead := err != nil && m.Attempts+1 >= r.cfg.MaxAttempts
+	var count func(*RelayStats)
+	if err == nil {
+		updates["status"] = StatusSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+		count = func(s *RelayStats) { s.Published++ }
+	} else {
+		msg := err.Error()
+		if len(msg) > 1024 {
+			msg = msg[:1024]
+		}
+		updates["last_error"] = msg
+		if dead {
+			updates["status"] = StatusDead
+			count = func(s *RelayStats) { s.Failed++; s.Dead++ }
+		} else {
+			updates["next_attempt_at"] = now.Add(r.backoff(m.Attempts))
+			count = func(s *RelayStats) { s.Failed++ }
+		}
+	}
+	// the message is published, mark it even if ctx is done
+	mctx := context.WithoutCancel(ctx)
+	if uerr := r.db.WithContext(mctx).Model(&Message{}).Where("id = ?", m.ID).Updates(updates).Error; uerr != nil {
+		return fmt.Errorf("outbox: update message %s error: %w", m.Key, uerr)
+	}
+	r.count(count)
+	switch {
+	case err == nil:
+	case dead:
+		r.logger.Errorf("outbox: message %s dead after %d attempts: %v", m.Key, m.Attempts+1, err)
+	default:
+		r.logger.Warnf("outbox: publish message %s error: %v", m.Key, err)
+	}
+	return nil
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.cfg.RetryBackoff
+	for i := 0; i < attempts && d < r.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.cfg.MaxBackoff)
+}
+
+func (r *Relay) updateLag(ctx context.Context) error {
+	db := r.db.WithContext(ctx)
+	var pending int64
+	if err := db.Model(&Message{}).Where("status = ?", StatusPending).Count(&pending).Error; err != nil {
+		return fmt.Errorf("outbox: count pending error: %w", err)
+	}
+	var lag time.Duration
+	if pending > 0 {
+		var oldest Message
+		err := db.Select("created_at").Where("status = ?", StatusPending).Order("id").Take(&oldest).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("outbox: select oldest error: %w", err)
+		}
+		if err == nil {
+			lag = r.now().Sub(oldest.CreatedAt)
+		}
+	}
+	r.count(func(s *RelayStats) {
+		s.Pending = pending
+		s.Lag = lag
+	})
+	if r.observer != nil {
+		r.observer(lag, pending)
+	}
+	return nil
+}
+
+func (r *Relay) count(fn func(*RelayStats)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.stats)
+}
+
+// Stats returns the statistics of the relay.
+func (r *Relay) Stats() RelayStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
@@ -0,0 +1,114 @@
+package pagination
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor not encoded by EncodeCursor or not
+// matching the ordering of the keyset.
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+// timeValue keeps the type of the time values in the cursor, so that they are
+// compared as times instead of strings.
+type timeValue struct {
+	T time.Time `json:"t"`
+}
+
+// EncodeCursor encodes the values of the ordering columns into an opaque url safe
+// cursor. The values are strings, bools, numbers, times or driver.Valuer of them.
+func EncodeCursor(values ...any) (string, error) {
+	vs := make([]any, len(values))
+	for i, v := range values {
+		cv, err := cursorValue(v)
+		if err != nil {
+			return "", err
+		}
+		vs[i] = cv
+	}
+	data, err := json.Marshal(vs)
+	if err != nil {
+		return "", fmt.Errorf("pagination: encode cursor error: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func cursorValue(v any) (any, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return nil, fmt.Errorf("pagination: cursor value error: %w", err)
+		}
+		v = dv
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Kind() == reflect.Pointer {
+		return nil, errors.New("pagination: cursor value is null")
+	}
+	switch x := rv.Interface().(type) {
+	case time.Time:
+		return timeValue{T: x}, nil
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return x, nil
+	default:
+		return nil, fmt.Errorf("pagination: cursor value of %T not supported", x)
+	}
+}
+
+// DecodeCursor decodes the values encoded by EncodeCursor, the integers are decoded
+// as int64, the other numbers as float64.
+func DecodeCursor(cursor string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raws []json.RawMessage
+	if err := dec.Decode(&raws); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]any, len(raws))
+	for i, raw := range raws {
+		if values[i], err = decodeValue(raw); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return values, nil
+}
+
+func decodeValue(raw json.RawMessage) (any, error) {
+	if len(raw) > 0 && raw[0] == '{' {
+		var tv timeValue
+		if err := json.Unmarshal(raw, &tv); err != nil {
+			return nil, err
+		}
+		return tv.T, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch x := v.(type) {
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n, nil
+		}
+		return x.Float64()
+	case string, bool:
+		return x, nil
+	default:
+		return nil, ErrInvalidCursor
+	}
+}
@@ -0,0 +1,158 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidOrder is returned for an invalid keyset ordering.
+var ErrInvalidOrder = errors.New("pagination: invalid order")
+
+var columnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Order is an ordering column of the keyset pagination.
+type Order struct {
+	// Column is the column name, optionally qualified by the table like "orders.id".
+	Column string
+	Desc   bool
+}
+
+// Keyset paginates by the values of the ordering columns of the last item instead of
+// offset, so the cost of a page does not grow with the page number and the items
+// inserted meanwhile do not shift the pages.
+type Keyset struct {
+	orders []Order
+	size   int
+	// CountTotal counts PageResult.Total on every page, which costs a full count query.
+	CountTotal bool
+}
+
+// NewKeyset creates a keyset pagination of size by the orders. The orders must
+// identify an item uniquely, so the last one is usually the primary key, like
+// created_at desc, id desc. The size is normalized like Find.
+func NewKeyset(size int, orders ...Order) (*Keyset, error) {
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("%w: no ordering columns", ErrInvalidOrder)
+	}
+	seen := make(map[string]bool, len(orders))
+	for _, o := range orders {
+		if !columnPattern.MatchString(o.Column) {
+			return nil, fmt.Errorf("%w: column %q", ErrInvalidOrder, o.Column)
+		}
+		if seen[o.Column] {
+			return nil, fmt.Errorf("%w: column %s ordered twice", ErrInvalidOrder, o.Column)
+		}
+		seen[o.Column] = true
+	}
+	_, size = normalize(1, size)
+	return &Keyset{orders: orders, size: size}, nil
+}
+
+// Scope returns the scope of the page after cursor, the first page if cursor is empty.
+// It adds the conditions, the ordering and a limit of one more item than the size to
+// tell if there are more items, so the query must not have its own ordering.
+func (k *Keyset) Scope(cursor string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if _, ok := db.Statement.Clauses["ORDER BY"]; ok {
+			_ = db.AddError(fmt.Errorf("%w: the query has its own ordering", ErrInvalidOrder))
+			return db
+		}
+		if cursor != "" {
+			values, err := DecodeCursor(cursor)
+			if err != nil {
+				_ = db.AddError(err)
+				return db
+			}
+			if len(values) != len(k.orders) {
+				_ = db.AddError(ErrInvalidCursor)
+				return db
+			}
+			db = db.Where(k.after(values))
+		}
+		for _, o := range k.orders {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: o.Column}, Desc: o.Desc})
+		}
+		return db.Limit(k.size + 1)
+	}
+}
+
+// after returns the condition of the items after values in the ordering:
+// c1 > v1 OR (c1 = v1 AND c2 > v2) OR ..., < for the descending columns.
+func (k *Keyset) after(values []any) clause.Expression {
+	ors := make([]clause.Expression, 0, len(k.orders))
+	for i, o := range k.orders {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: clause.Column{Name: k.orders[j].Column}, Value: values[j]})
+		}
+		col := clause.Column{Name: o.Column}
+		if o.Desc {
+			ands = append(ands, clause.Lt{Column: col, Value: values[i]})
+		} else {
+			ands = append(ands, clause.Gt{Column: col, Value: values[i]})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return clause.Or(ors...)
+}
+
+// FindKeyset queries the page of T after cursor with the conditions of db, the first
+// page if cursor is empty. PageResult.NextCursor is the cursor of the next page and
+// Total is -1 unless Keyset.CountTotal is set. The ordering columns must be the
+// fields of T.
+func FindKeyset[T any](db *gorm.DB, k *Keyset, cursor string) (*PageResult[T], error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	fields := make([]func(reflect.Value) any, len(k.orders))
+	for i, o := range k.orders {
+		name := o.Column
+		if j := strings.LastIndexByte(name, '.'); j >= 0 {
+			name = name[j+1:]
+		}
+		f := stmt.Schema.LookUpField(name)
+		if f == nil {
+			return nil, fmt.Errorf("%w: column %s not in %s", ErrInvalidOrder, o.Column, stmt.Schema.Name)
+		}
+		fields[i] = func(v reflect.Value) any {
+			value, _ := f.ValueOf(db.Statement.Context, v)
+			return value
+		}
+	}
+
+	db = db.Session(&gorm.Session{})
+	r := &PageResult[T]{Total: -1, Size: k.size}
+	if k.CountTotal {
+		if err := db.Model(new(T)).Count(&r.Total).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := db.Scopes(k.Scope(cursor)).Find(&r.Items).Error; err != nil {
+		return nil, err
+	}
+	if r.Items == nil {
+		r.Items = []T{}
+	}
+	if len(r.Items) > k.size {
+		r.Items = r.Items[:k.size]
+		r.HasMore = true
+		last := reflect.ValueOf(&r.Items[k.size-1]).Elem()
+		values := make([]any, len(fields))
+		for i, value := range fields {
+			values[i] = value(last)
+		}
+		next, err := EncodeCursor(values...)
+		if err != nil {
+			return nil, err
+		}
+		r.NextCursor = next
+	}
+	return r, nil
+}
@@ -0,0 +1,77 @@
+/*
+pagination 分页查询，提供页码分页和基于游标的 keyset 分页，以 GORM Scope 的方式使用
+*/
+
+package pagination
+
+import (
+	"gorm.io/gorm"
+)
+
+var (
+	// DefaultSize is the page size used when the requested size is not positive.
+	DefaultSize = 20
+	// MaxSize is the max page size, the larger sizes are truncated to it.
+	MaxSize = 100
+)
+
+// PageResult is a page of the items.
+type PageResult[T any] struct {
+	Items []T `json:"items"`
+	// Total is the number of all the items matched, -1 if not counted.
+	Total int64 `json:"total"`
+	// Page is the page number of the offset pagination, starting from 1.
+	Page int `json:"page,omitempty"`
+	Size int `json:"size"`
+	// NextCursor is the cursor of the next page of the keyset pagination, empty if
+	// there are no more items.
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// normalize returns the page starting from 1 and the size in (0, MaxSize].
+func normalize(page, size int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if size <= 0 {
+		size = DefaultSize
+	}
+	if size > MaxSize {
+		size = MaxSize
+	}
+	return page, size
+}
+
+// Paginate returns the scope of the page starting from 1 by offset and limit, the
+// page and size are normalized like Find.
+//
+//	db.Scopes(pagination.Paginate(page, size)).Order("id").Find(&users)
+func Paginate(page, size int) func(*gorm.DB) *gorm.DB {
+	page, size = normalize(page, size)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Offset((page - 1) * size).Limit(size)
+	}
+}
+
+// Find queries the page of T with the conditions of db and counts the total. The page
+// less than 1 is treated as 1, the size not positive as DefaultSize and larger than
+// MaxSize as MaxSize. db should have a stable order, like Order("id").
+func Find[T any](db *gorm.DB, page, size int) (*PageResult[T], error) {
+	page, size = normalize(page, size)
+	db = db.Session(&gorm.Session{})
+	r := &PageResult[T]{Page: page, Size: size}
+	if err := db.Model(new(T)).Count(&r.Total).Error; err != nil {
+		return nil, err
+	}
+	if int64((page-1)*size) < r.Total {
+		if err := db.Scopes(Paginate(page, size)).Find(&r.Items).Error; err != nil {
+			return nil, err
+		}
+	}
+	if r.Items == nil {
+		r.Items = []T{}
+	}
+	r.HasMore = int64(page*size) < r.Total
+	return r, nil
+}
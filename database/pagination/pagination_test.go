@@ -0,0 +1,165 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type article struct {
+	ID        int64
+	Author    string
+	Score     float64
+	CreatedAt time.Time
+}
+
+func newTestDB(t *testing.T, n int) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&article{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		// two articles per timestamp, so that the ordering needs the id to be unique.
+		a := article{ID: int64(i), Author: "a", Score: float64(i) / 2, CreatedAt: base.Add(time.Duration(i/2) * time.Hour)}
+		if err := db.Create(&a).Error; err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return db
+}
+
+// TestFind tests the offset pagination and the normalization of the page and size.
+func TestFind(t *testing.T) {
+	db := newTestDB(t, 25)
+	r, err := Find[article](db.Order("id"), 2, 10)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if r.Total != 25 || r.Page != 2 || len(r.Items) != 10 || r.Items[0].ID != 11 || !r.HasMore {
+		t.Errorf("page 2 = %+v", r)
+	}
+	r, err = Find[article](db.Order("id"), 3, 10)
+	if err != nil || len(r.Items) != 5 || r.HasMore {
+		t.Errorf("page 3 = %+v, %v", r, err)
+	}
+	r, err = Find[article](db.Where("id > ?", 20), 5, 10)
+	if err != nil || r.Total != 5 || len(r.Items) != 0 || r.Items == nil {
+		t.Errorf("page out of range = %+v, %v", r, err)
+	}
+	if r, _ := Find[article](db, 0, 1000); r.Page != 1 || r.Size != MaxSize {
+		t.Errorf("normalized page = %d, size = %d", r.Page, r.Size)
+	}
+}
+
+// TestFindKeyset tests walking all the pages by the cursors in a mixed ordering.
+func TestFindKeyset(t *testing.T) {
+	db := newTestDB(t, 25)
+	ks, err := NewKeyset(10, Order{Column: "created_at", Desc: true}, Order{Column: "id"})
+	if err != nil {
+		t.Fatalf("NewKeyset failed: %v", err)
+	}
+	ks.CountTotal = true
+
+	var (
+		ids    []int64
+		cursor string
+		pages  int
+	)
+	for {
+		r, err := FindKeyset[article](db.Where("author = ?", "a"), ks, cursor)
+		if err != nil {
+			t.Fatalf("FindKeyset failed: %v", err)
+		}
+		if r.Total != 25 {
+			t.Errorf("Total = %d", r.Total)
+		}
+		for _, a := range r.Items {
+			ids = append(ids, a.ID)
+		}
+		pages++
+		if !r.HasMore {
+			if r.NextCursor != "" {
+				t.Errorf("Expected no cursor on the last page, got %q", r.NextCursor)
+			}
+			break
+		}
+		cursor = r.NextCursor
+	}
+	if pages != 3 || len(ids) != 25 {
+		t.Fatalf("pages = %d, ids = %v", pages, ids)
+	}
+	// created_at desc, id asc: 24 and 25 share the latest timestamp.
+	if ids[0] != 24 || ids[1] != 25 || ids[2] != 22 || ids[24] != 1 {
+		t.Errorf("ids = %v", ids)
+	}
+	seen := make(map[int64]bool)
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate id %d in %v", id, ids)
+		}
+		seen[id] = true
+	}
+}
+
+// TestKeysetInvalid tests the invalid orderings and cursors.
+func TestKeysetInvalid(t *testing.T) {
+	for _, orders := range [][]Order{nil, {{Column: "id; DROP TABLE x"}}, {{Column: "id"}, {Column: "id", Desc: true}}} {
+		if _, err := NewKeyset(10, orders...); !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("NewKeyset(%v) = %v, want ErrInvalidOrder", orders, err)
+		}
+	}
+
+	db := newTestDB(t, 3)
+	ks, _ := NewKeyset(2, Order{Column: "id"})
+	if _, err := FindKeyset[article](db, ks, "not a cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+	cursor, _ := EncodeCursor(1, 2)
+	if _, err := FindKeyset[article](db, ks, cursor); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor for the cursor of another ordering, got %v", err)
+	}
+	if _, err := FindKeyset[article](db.Order("score"), ks, ""); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("Expected ErrInvalidOrder for the query ordered, got %v", err)
+	}
+	missing, _ := NewKeyset(2, Order{Column: "title"})
+	if _, err := FindKeyset[article](db, missing, ""); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("Expected ErrInvalidOrder for the column not in the model, got %v", err)
+	}
+}
+
+// TestCursor tests that the cursor values keep their types.
+func TestCursor(t *testing.T) {
+	now := time.Date(2026, 3, 4, 5, 6, 7, 8, time.UTC)
+	s := "x"
+	cursor, err := EncodeCursor(int32(42), 1.5, "a", true, now, &s)
+	if err != nil {
+		t.Fatalf("EncodeCursor failed: %v", err)
+	}
+	values, err := DecodeCursor(cursor)
+	if err != nil {
+		t.Fatalf("DecodeCursor failed: %v", err)
+	}
+	if len(values) != 6 || values[0] != int64(42) || values[1] != 1.5 || values[2] != "a" || values[3] != true || values[5] != "x" {
+		t.Errorf("values = %#v", values)
+	}
+	if tm, ok := values[4].(time.Time); !ok || !tm.Equal(now) {
+		t.Errorf("time value = %#v", values[4])
+	}
+	if _, err := EncodeCursor(nil); err == nil {
+		t.Error("Expected error for null value")
+	}
+	if _, err := EncodeCursor([]int{1}); err == nil {
+		t.Error("Expected error for unsupported value")
+	}
+}
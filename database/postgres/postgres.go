@@ -0,0 +1,31 @@
+/*
+postgres 注册 database 包的 PostgreSQL 驱动，驱动名称为 postgres 和 postgresql：
+
+	import _ "github.com/baisiyi/go-kits/database/postgres"
+*/
+
+package postgres
+
+import (
+	"database/sql"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/database"
+)
+
+func init() {
+	d := database.Driver{
+		SQLDriver: "pgx",
+		DSN: func(_ *database.DBConfig, c *database.Connect) string {
+			return c.ToPostgresDSN()
+		},
+		Open: postgres.Open,
+		New: func(pool *sql.DB) gorm.Dialector {
+			return postgres.New(postgres.Config{Conn: pool})
+		},
+	}
+	database.RegisterDriver(database.DriverPostgres, d)
+	database.RegisterDriver("postgresql", d)
+}
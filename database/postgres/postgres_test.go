@@ -0,0 +1,17 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/baisiyi/go-kits/database"
+)
+
+// TestRegistered tests that the driver is registered under both names.
+func TestRegistered(t *testing.T) {
+	for _, driver := range []string{database.DriverPostgres, "postgresql"} {
+		d, err := (&database.DBConfig{Driver: driver}).Dialector()
+		if err != nil || d.Name() != "postgres" {
+			t.Errorf("Dialector(%q) = %v, %v", driver, d, err)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/baisiyi/go-kits/cache"
+	"github.com/baisiyi/go-kits/log"
+)
+
+const queryCachePluginName = "go-kits:query_cache"
+
+// Cacheable 由需要缓存按主键查询结果的模型实现，未实现的模型不经过缓存
+type Cacheable interface {
+	// CacheTTL 返回该模型缓存的过期时间，0 表示使用 QueryCacheConfig.TTL
+	CacheTTL() time.Duration
+}
+
+// QueryCacheConfig 按主键查询的读穿透缓存配置，Capacity 大于 0 时使用进程内缓存启用
+type QueryCacheConfig struct {
+	// Capacity 进程内缓存的最大条目数
+	Capacity int `mapstructure:"capacity" yaml:"capacity"`
+	// TTL 模型的 CacheTTL 返回 0 时使用的过期时间，默认 1m
+	TTL time.Duration `mapstructure:"ttl" yaml:"ttl"`
+	// KeyPrefix 缓存 key 的前缀，多个服务共享缓存后端时用于区分，默认 "db:"
+	KeyPrefix string `mapstructure:"key_prefix" yaml:"key_prefix"`
+}
+
+func (c *QueryCacheConfig) setDefaults() {
+	if c.TTL <= 0 {
+		c.TTL = time.Minute
+	}
+	if c.KeyPrefix == "" {
+		c.KeyPrefix = "db:"
+	}
+}
+
+// QueryCachePlugin GORM 查询缓存插件，缓存实现了 Cacheable 的模型按主键查询单条记录的结果，
+// 如 First(&user, id)、Where("id", id)、Where(&User{ID: id})，Where("id = ?", id) 形式的条件不识别为主键。
+// 通过模型执行的 Create（仅 ON CONFLICT）、Update、Delete 成功后使缓存失效：条件中包含主键时删除对应的 key，
+// 否则递增表的版本号使该表的全部缓存失效。
+// 事务内的查询、Unscoped、Select、Joins、Preload、FOR UPDATE 等查询不经过缓存；不缓存未找到的结果；
+// Exec、Raw 执行的写操作和不指定模型的写操作（如 Table("users").Update）不会使缓存失效；
+// 并发写入和未提交的事务可能使缓存在 TTL 内读到旧数据
+type QueryCachePlugin struct {
+	backend cache.Cache[string, []byte]
+	cfg     QueryCacheConfig
+	logger  log.Logger
+}
+
+// NewQueryCachePlugin 创建查询缓存插件，backend 可以是进程内缓存或 Redis 等共享缓存，通过 db.Use 注册
+func NewQueryCachePlugin(backend cache.Cache[string, []byte], cfg QueryCacheConfig, logger log.Logger) *QueryCachePlugin {
+	cfg.setDefaults()
+	if logger == nil {
+		logger = log.GetDefaultLogger()
+	}
+	return &QueryCachePlugin{backend: backend, cfg: cfg, logger: logger}
+}
+
+// Name 实现 gorm.Plugin 接口
+func (p *QueryCachePlugin) Name() string {
+	return queryCachePluginName
+}
+
+// Initialize 实现 gorm.Plugin 接口，包装查询的执行回调，在写操作后注册失效回调
+func (p *QueryCachePlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if fn := cb.Query().Get("gorm:query"); fn != nil {
+		if err := cb.Query().Replace("gorm:query", p.wrap(fn)); err != nil {
+			return err
+		}
+	}
+	hooks := []struct {
+		op       string
+		register func(string, func(*gorm.DB)) error
+		fn       func(*gorm.DB)
+	}{
+		{"create", cb.Create().After("gorm:create").Register, p.invalidateUpsert},
+		{"update", cb.Update().After("gorm:update").Register, p.invalidate},
+		{"delete", cb.Delete().After("gorm:delete").Register, p.invalidate},
+	}
+	for _, h := range hooks {
+		if err := h.register("query_cache:invalidate_"+h.op, h.fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wrap 返回命中缓存时不执行 fn 的查询回调，未命中时执行 fn 并缓存查到的记录
+func (p *QueryCachePlugin) wrap(fn func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		id, ttl, ok := p.lookup(db)
+		if !ok {
+			fn(db)
+			return
+		}
+		ctx := statementContext(db)
+		key, ok := p.key(ctx, db.Statement, id)
+		if !ok {
+			fn(db)
+			return
+		}
+		data, err := p.backend.Get(ctx, key)
+		if err == nil && p.decode(db, data) {
+			return
+		}
+		if err != nil && !errors.Is(err, cache.ErrNotFound) {
+			p.warn(ctx, "get", key, err)
+		}
+
+		fn(db)
+		if db.Error != nil || db.RowsAffected != 1 {
+			return
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(db.Statement.ReflectValue.Interface()); err != nil {
+			// 无法编码的模型（如包含 interface 或 func 字段）不缓存
+			return
+		}
+		if err := p.backend.Set(ctx, key, buf.Bytes(), ttl); err != nil {
+			p.warn(ctx, "set", key, err)
+		}
+	}
+}
+
+// lookup 判断查询能否经过缓存，返回查询的主键值和缓存的过期时间
+func (p *QueryCachePlugin) lookup(db *gorm.DB) (any, time.Duration, bool) {
+	stmt := db.Statement
+	if db.Error != nil || db.DryRun || stmt.Schema == nil || stmt.SQL.Len() > 0 || stmt.Unscoped || inTransaction(db) {
+		return nil, 0, false
+	}
+	if len(stmt.Selects) > 0 || len(stmt.Omits) > 0 || len(stmt.Joins) > 0 || len(stmt.Preloads) > 0 ||
+		stmt.Distinct || stmt.TableExpr != nil {
+		return nil, 0, false
+	}
+	ttl, ok := p.ttl(stmt.Schema)
+	if !ok || len(stmt.Schema.PrimaryFields) != 1 {
+		return nil, 0, false
+	}
+	rv := stmt.ReflectValue
+	if rv.Kind() != reflect.Struct || !rv.CanSet() || rv.Type() != stmt.Schema.ModelType {
+		return nil, 0, false
+	}
+	for name, c := range stmt.Clauses {
+		switch name {
+		case "WHERE", "ORDER BY":
+		case "LIMIT":
+			// First、Take 的 LIMIT 1 不影响按主键查询的结果，OFFSET 会
+			if limit, ok := c.Expression.(clause.Limit); !ok || limit.Offset != 0 || (limit.Limit != nil && *limit.Limit <= 0) {
+				return nil, 0, false
+			}
+		default:
+			return nil, 0, false
+		}
+	}
+
+	pk := stmt.Schema.PrioritizedPrimaryField
+	destID, destZero := pk.ValueOf(stmt.Context, rv)
+	var exprs []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			exprs = where.Exprs
+		}
+	}
+	switch {
+	case len(exprs) == 0 && !destZero:
+		// First(&user) 按 user 的主键查询
+		return destID, ttl, true
+	case len(exprs) == 1 && destZero:
+		ids, ok := primaryKeyValues(exprs[0], pk)
+		if ok && len(ids) == 1 {
+			return ids[0], ttl, true
+		}
+	}
+	return nil, 0, false
+}
+
+// ttl 返回模型的缓存过期时间，模型未实现 Cacheable 时返回 false
+func (p *QueryCachePlugin) ttl(s *schema.Schema) (time.Duration, bool) {
+	c, ok := reflect.New(s.ModelType).Interface().(Cacheable)
+	if !ok {
+		return 0, false
+	}
+	if ttl := c.CacheTTL(); ttl > 0 {
+		return ttl, true
+	}
+	return p.cfg.TTL, true
+}
+
+// decode 将缓存的记录写入查询的目标，失败时返回 false 由数据库重新查询
+func (p *QueryCachePlugin) decode(db *gorm.DB, data []byte) bool {
+	v := reflect.New(db.Statement.Schema.ModelType)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(v.Interface()); err != nil {
+		return false
+	}
+	db.Statement.ReflectValue.Set(v.Elem())
+	db.RowsAffected = 1
+	return true
+}
+
+// invalidate 在通过模型执行的写操作成功后使受影响记录的缓存失效
+func (p *QueryCachePlugin) invalidate(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || db.DryRun || stmt.Schema == nil || db.RowsAffected == 0 {
+		return
+	}
+	if _, ok := p.ttl(stmt.Schema); !ok {
+		return
+	}
+	ctx := statementContext(db)
+	ids, ok := p.affectedIDs(stmt)
+	if !ok {
+		p.bumpVersion(ctx, stmt)
+		return
+	}
+	version, err := p.backend.Get(ctx, p.versionKey(stmt))
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotFound) {
+			p.bumpVersion(ctx, stmt)
+		}
+		// 没有版本号时不存在可读到的缓存
+		return
+	}
+	for _, id := range ids {
+		key := p.entryKey(stmt, string(version), id)
+		if err := p.backend.Delete(ctx, key); err != nil {
+			p.warn(ctx, "delete", key, err)
+			p.bumpVersion(ctx, stmt)
+			return
+		}
+	}
+}
+
+// invalidateUpsert 在 Create 后使缓存失效，只有 ON CONFLICT 更新的记录可能已被缓存
+func (p *QueryCachePlugin) invalidateUpsert(db *gorm.DB) {
+	if _, ok := db.Statement.Clauses["ON CONFLICT"]; ok {
+		p.invalidate(db)
+	}
+}
+
+// affectedIDs 返回写操作影响的记录的主键值：条件中包含主键时取条件中的值，
+// 否则取模型中非零的主键（如 Create 后的记录）。无法确定时返回 false
+func (p *QueryCachePlugin) affectedIDs(stmt *gorm.Statement) ([]any, bool) {
+	if len(stmt.Schema.PrimaryFields) != 1 {
+		return nil, false
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			for _, expr := range where.Exprs {
+				if _, ok := expr.(clause.OrConditions); ok {
+					return nil, false
+				}
+			}
+			// 顶层条件之间为 AND，任一主键条件即限定了受影响的记录
+			for _, expr := range where.Exprs {
+				if ids, ok := primaryKeyValues(expr, pk); ok {
+					return ids, true
+				}
+			}
+			return nil, false
+		}
+	}
+
+	var ids []any
+	rv := reflect.Indirect(stmt.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Struct:
+		if id, zero := pk.ValueOf(stmt.Context, rv); !zero {
+			ids = append(ids, id)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			elem := reflect.Indirect(rv.Index(i))
+			if elem.Kind() != reflect.Struct {
+				return nil, false
+			}
+			id, zero := pk.ValueOf(stmt.Context, elem)
+			if zero {
+				return nil, false
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, len(ids) > 0
+}
+
+// primaryKeyValues 返回条件 expr 为主键的 = 或 IN 条件时的值
+func primaryKeyValues(expr clause.Expression, pk *schema.Field) ([]any, bool) {
+	switch e := expr.(type) {
+	case clause.Eq:
+		if !isPrimaryKeyColumn(e.Column, pk) {
+			return nil, false
+		}
+		// Where("id", ids) 的值为切片时生成 IN
+		if rv := reflect.ValueOf(e.Value); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+			ids := make([]any, rv.Len())
+			for i := range ids {
+				ids[i] = rv.Index(i).Interface()
+			}
+			return ids, len(ids) > 0
+		}
+		if e.Value == nil {
+			return nil, false
+		}
+		return []any{e.Value}, true
+	case clause.IN:
+		if !isPrimaryKeyColumn(e.Column, pk) || len(e.Values) == 0 {
+			return nil, false
+		}
+		return e.Values, true
+	}
+	return nil, false
+}
+
+func isPrimaryKeyColumn(column any, pk *schema.Field) bool {
+	var name string
+	switch c := column.(type) {
+	case clause.Column:
+		name = c.Name
+	case string:
+		name = c
+	default:
+		return false
+	}
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Trim(name, "`\"")
+	return name == clause.PrimaryKey || name == pk.DBName || name == pk.Name
+}
+
+// key 返回主键 id 的缓存 key，包含表的版本号，表还没有版本号时创建
+func (p *QueryCachePlugin) key(ctx context.Context, stmt *gorm.Statement, id any) (string, bool) {
+	versionKey := p.versionKey(stmt)
+	version, err := p.backend.Get(ctx, versionKey)
+	if errors.Is(err, cache.ErrNotFound) {
+		// 版本号被淘汰时不能回退到旧的版本号，否则会读到已失效的缓存
+		version, err = p.bumpVersion(ctx, stmt)
+	}
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotFound) {
+			p.warn(ctx, "get", versionKey, err)
+		}
+		return "", false
+	}
+	return p.entryKey(stmt, string(version), id), true
+}
+
+// bumpVersion 为表设置新的版本号，使该表已有的缓存全部失效
+func (p *QueryCachePlugin) bumpVersion(ctx context.Context, stmt *gorm.Statement) ([]byte, error) {
+	key := p.versionKey(stmt)
+	version := []byte(strconv.FormatInt(time.Now().UnixNano(), 36))
+	if err := p.backend.Set(ctx, key, version, cache.NoExpiration); err != nil {
+		p.warn(ctx, "set", key, err)
+		return nil, err
+	}
+	return version, nil
+}
+
+// table 返回缓存 key 中的表名，按库区分租户的实例加上租户名
+func (p *QueryCachePlugin) table(stmt *gorm.Statement) string {
+	if v, ok := stmt.Settings.Load(tenantDBKey); ok {
+		return fmt.Sprintf("%v/%s", v, stmt.Table)
+	}
+	return stmt.Table
+}
+
+func (p *QueryCachePlugin) versionKey(stmt *gorm.Statement) string {
+	return p.cfg.KeyPrefix + p.table(stmt) + ":version"
+}
+
+func (p *QueryCachePlugin) entryKey(stmt *gorm.Statement, version string, id any) string {
+	return fmt.Sprintf("%s%s:%s:%v", p.cfg.KeyPrefix, p.table(stmt), version, id)
+}
+
+func (p *QueryCachePlugin) warn(ctx context.Context, op, key string, err error) {
+	log.WithContextFields(p.logger, ctx).Warnf("[DB_CACHE] Op: %s | Key: %s | Error: %v", op, key, err)
+}
+
+func statementContext(db *gorm.DB) context.Context {
+	if ctx := db.Statement.Context; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/baisiyi/go-kits/cache"
+)
+
+type cachedUser struct {
+	ID        uint
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+func (cachedUser) CacheTTL() time.Duration { return time.Minute }
+
+func newCacheClient(t *testing.T) (*Client, *cache.Memory[string, []byte]) {
+	t.Helper()
+	c, err := newClient(&DBConfig{Driver: DriverSQLite, DSN: Connect{Name: filepath.Join(t.TempDir(), "cache.db")}}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	backend := cache.NewMemory[string, []byte](cache.WithCapacity(100))
+	if err := c.GetDB(context.Background()).Use(NewQueryCachePlugin(backend, QueryCacheConfig{}, &mockLogger{})); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+	if err := c.GetDB(context.Background()).AutoMigrate(&cachedUser{}, &repoUser{}); err != nil {
+		t.Fatal(err)
+	}
+	return c, backend
+}
+
+// TestQueryCache tests the primary key lookups served from the cache and the invalidation
+// on the writes through the model.
+func TestQueryCache(t *testing.T) {
+	c, _ := newCacheClient(t)
+	ctx := context.Background()
+	db := c.GetDB(ctx)
+	u := cachedUser{Name: "a"}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Exec 绕过失效回调，能读到旧值说明命中了缓存
+	rename := func(name string) {
+		t.Helper()
+		if err := db.Exec("UPDATE cached_user SET name = ?", name).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(query func(*gorm.DB, *cachedUser) *gorm.DB) string {
+		t.Helper()
+		var got cachedUser
+		if err := query(c.GetDB(ctx), &got).Error; err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		return got.Name
+	}
+	byID := func(db *gorm.DB, got *cachedUser) *gorm.DB { return db.First(got, u.ID) }
+	if name := get(byID); name != "a" {
+		t.Fatalf("First = %s", name)
+	}
+	rename("b")
+	queries := map[string]func(*gorm.DB, *cachedUser) *gorm.DB{
+		"First":       byID,
+		"Take":        func(db *gorm.DB, got *cachedUser) *gorm.DB { return db.Take(got, u.ID) },
+		"Where":       func(db *gorm.DB, got *cachedUser) *gorm.DB { return db.Where("id", u.ID).First(got) },
+		"WhereStruct": func(db *gorm.DB, got *cachedUser) *gorm.DB { return db.Where(&cachedUser{ID: u.ID}).Find(got) },
+		"Dest":        func(db *gorm.DB, got *cachedUser) *gorm.DB { got.ID = u.ID; return db.First(got) },
+	}
+	for name, query := range queries {
+		if got := get(query); got != "a" {
+			t.Errorf("%s = %s, want the cached a", name, got)
+		}
+	}
+	uncached := map[string]func(*gorm.DB, *cachedUser) *gorm.DB{
+		"Expr":     func(db *gorm.DB, got *cachedUser) *gorm.DB { return db.First(got, "id = ?", u.ID) },
+		"Select":   func(db *gorm.DB, got *cachedUser) *gorm.DB { return db.Select("id", "name").First(got, u.ID) },
+		"Unscoped": func(db *gorm.DB, got *cachedUser) *gorm.DB { return db.Unscoped().First(got, u.ID) },
+	}
+	for name, query := range uncached {
+		if got := get(query); got != "b" {
+			t.Errorf("%s = %s, want b from the database", name, got)
+		}
+	}
+	if err := c.Transact(ctx, func(tx *gorm.DB) error {
+		var got cachedUser
+		if err := tx.First(&got, u.ID).Error; err != nil || got.Name != "b" {
+			t.Errorf("First in transaction = %s, %v", got.Name, err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 按主键更新删除对应的 key
+	if err := db.Model(&u).Update("name", "c").Error; err != nil {
+		t.Fatal(err)
+	}
+	if name := get(byID); name != "c" {
+		t.Fatalf("First after Update = %s", name)
+	}
+	// 不含主键的条件使整张表的缓存失效
+	rename("d")
+	if err := db.Model(&cachedUser{}).Where("name = ?", "d").Update("name", "e").Error; err != nil {
+		t.Fatal(err)
+	}
+	if name := get(byID); name != "e" {
+		t.Fatalf("First after the bulk update = %s", name)
+	}
+	// upsert
+	rename("f")
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&cachedUser{ID: u.ID, Name: "g"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if name := get(byID); name != "g" {
+		t.Fatalf("First after the upsert = %s", name)
+	}
+
+	if err := db.Delete(&cachedUser{}, u.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var got cachedUser
+	if err := db.First(&got, u.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected ErrRecordNotFound after Delete, got %v", err)
+	}
+}
+
+// TestQueryCacheNotCacheable tests that the models not implementing Cacheable are not cached,
+// and the plugin registered by DBConfig.QueryCache.
+func TestQueryCacheNotCacheable(t *testing.T) {
+	c, backend := newCacheClient(t)
+	ctx := context.Background()
+	u := repoUser{Name: "a"}
+	if err := c.GetDB(ctx).Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	var got repoUser
+	if err := c.GetDB(ctx).First(&got, u.ID).Error; err != nil || got.Name != "a" {
+		t.Fatalf("First = %+v, %v", got, err)
+	}
+	if n := backend.Len(); n != 0 {
+		t.Errorf("Expected nothing cached, got %d entries", n)
+	}
+
+	c2, err := newClient(&DBConfig{
+		Driver:     DriverSQLite,
+		DSN:        Connect{Name: filepath.Join(t.TempDir(), "config.db")},
+		QueryCache: QueryCacheConfig{Capacity: 10},
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c2.Close()
+	if _, ok := c2.db.Plugins[queryCachePluginName]; !ok {
+		t.Error("Expected the query cache registered")
+	}
+}
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrSoftDeleteUnsupported 模型没有 gorm.DeletedAt 等软删除字段时 DeleteSoft 返回
+var ErrSoftDeleteUnsupported = errors.New("database: model does not support soft delete")
+
+// RepoHooks 持久化前后的钩子，Before 返回错误时中止操作；After 返回错误时数据已写入，
+// 在 Transact 中调用时由事务回滚
+type RepoHooks[T any] struct {
+	BeforeCreate func(ctx context.Context, entity *T) error
+	AfterCreate  func(ctx context.Context, entity *T) error
+	BeforeUpdate func(ctx context.Context, id any, fields map[string]any) error
+	AfterUpdate  func(ctx context.Context, id any, fields map[string]any) error
+	BeforeDelete func(ctx context.Context, id any) error
+	AfterDelete  func(ctx context.Context, id any) error
+}
+
+// RepoOption Repository 配置选项
+type RepoOption[T any] func(*Repository[T])
+
+// WithRepoHooks 设置持久化前后的钩子
+func WithRepoHooks[T any](hooks RepoHooks[T]) RepoOption[T] {
+	return func(r *Repository[T]) {
+		r.hooks = hooks
+	}
+}
+
+// Repository 模型 T 的通用 CRUD，通过 Client.DB 获取连接，在 Transact 中调用时自动加入事务。
+// 模型有 gorm.DeletedAt 字段时查询自动排除已软删除的记录
+type Repository[T any] struct {
+	client *Client
+	schema *schema.Schema
+	pk     string
+	hooks  RepoHooks[T]
+}
+
+// NewRepository 创建模型 T 的 Repository，T 必须是只有一个主键的 GORM 模型
+func NewRepository[T any](c *Client, opts ...RepoOption[T]) (*Repository[T], error) {
+	stmt := &gorm.Statement{DB: c.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("database: parse model error: %w", err)
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil || len(stmt.Schema.PrimaryFields) != 1 {
+		return nil, fmt.Errorf("database: model %s requires a single primary key", stmt.Schema.Name)
+	}
+	r := &Repository[T]{client: c, schema: stmt.Schema, pk: pk.DBName}
+	for _, o := range opts {
+		o(r)
+	}
+	return r, nil
+}
+
+// DB 返回模型 T 的查询，用于 CRUD 之外的自定义查询
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return r.client.DB(ctx).Model(new(T))
+}
+
+// Create 创建记录，自增主键等回填到 entity
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	if h := r.hooks.BeforeCreate; h != nil {
+		if err := h(ctx, entity); err != nil {
+			return err
+		}
+	}
+	if err := r.client.DB(ctx).Create(entity).Error; err != nil {
+		return err
+	}
+	if h := r.hooks.AfterCreate; h != nil {
+		return h(ctx, entity)
+	}
+	return nil
+}
+
+// GetByID 按主键查询，记录不存在或已软删除时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) GetByID(ctx context.Context, id any) (*T, error) {
+	var entity T
+	if err := r.client.DB(ctx).Where(r.pkEq(id)).Take(&entity).Error; err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// UpdateFields 按主键更新 fields 中的列，key 为列名或字段名；记录不存在时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) UpdateFields(ctx context.Context, id any, fields map[string]any) error {
+	if err := r.checkColumns(fields); err != nil {
+		return err
+	}
+	if h := r.hooks.BeforeUpdate; h != nil {
+		if err := h(ctx, id, fields); err != nil {
+			return err
+		}
+	}
+	res := r.DB(ctx).Where(r.pkEq(id)).Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		// MySQL 默认返回实际变更的行数，值未变化时需确认记录是否存在
+		var n int64
+		if err := r.DB(ctx).Where(r.pkEq(id)).Count(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	if h := r.hooks.AfterUpdate; h != nil {
+		return h(ctx, id, fields)
+	}
+	return nil
+}
+
+// DeleteSoft 按主键软删除，模型没有软删除字段时返回 ErrSoftDeleteUnsupported，
+// 记录不存在或已删除时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) DeleteSoft(ctx context.Context, id any) error {
+	if len(r.schema.DeleteClauses) == 0 {
+		return ErrSoftDeleteUnsupported
+	}
+	if h := r.hooks.BeforeDelete; h != nil {
+		if err := h(ctx, id); err != nil {
+			return err
+		}
+	}
+	res := r.client.DB(ctx).Where(r.pkEq(id)).Delete(new(T))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if h := r.hooks.AfterDelete; h != nil {
+		return h(ctx, id)
+	}
+	return nil
+}
+
+// List 查询 filters 中各列等值（AND）的记录，key 为列名或字段名，值为切片时为 IN；
+// scopes 用于排序、分页等，如 pagination.Paginate
+func (r *Repository[T]) List(ctx context.Context, filters map[string]any, scopes ...func(*gorm.DB) *gorm.DB) ([]T, error) {
+	db, err := r.filter(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	items := []T{}
+	if err := db.Scopes(scopes...).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Count 统计 filters 中各列等值的记录数
+func (r *Repository[T]) Count(ctx context.Context, filters map[string]any) (int64, error) {
+	db, err := r.filter(ctx, filters)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	if err := db.Count(&n).Error; err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (r *Repository[T]) filter(ctx context.Context, filters map[string]any) (*gorm.DB, error) {
+	if err := r.checkColumns(filters); err != nil {
+		return nil, err
+	}
+	db := r.DB(ctx)
+	for name, value := range filters {
+		column := clause.Column{Name: r.schema.LookUpField(name).DBName}
+		if values, ok := sliceValues(value); ok {
+			db = db.Where(clause.IN{Column: column, Values: values})
+			continue
+		}
+		db = db.Where(clause.Eq{Column: column, Value: value})
+	}
+	return db, nil
+}
+
+// checkColumns 校验 key 均为模型的字段，避免外部传入的 key 拼入 SQL
+func (r *Repository[T]) checkColumns(fields map[string]any) error {
+	for name := range fields {
+		if f := r.schema.LookUpField(name); f == nil || f.DBName == "" {
+			return fmt.Errorf("database: unknown column %s of %s", name, r.schema.Name)
+		}
+	}
+	return nil
+}
+
+// sliceValues 将切片（[]byte 除外）展开为 IN 的值
+func sliceValues(value any) ([]any, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	values := make([]any, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values, true
+}
+
+func (r *Repository[T]) pkEq(id any) clause.Eq {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: r.pk}, Value: id}
+}
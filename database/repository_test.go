@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type repoUser struct {
+	ID        int64
+	Name      string
+	Status    int
+	DeletedAt gorm.DeletedAt
+}
+
+type repoTag struct {
+	ID   int64
+	Name string
+}
+
+func newRepoClient(t *testing.T) *Client {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&repoUser{}, &repoTag{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewClientFromDB(db)
+}
+
+// TestRepository tests the CRUD, the soft delete and the filters.
+func TestRepository(t *testing.T) {
+	c := newRepoClient(t)
+	ctx := context.Background()
+	var events []string
+	repo, err := NewRepository(c, WithRepoHooks(RepoHooks[repoUser]{
+		BeforeCreate: func(ctx context.Context, u *repoUser) error {
+			if u.Name == "" {
+				return errors.New("name required")
+			}
+			return nil
+		},
+		AfterCreate: func(ctx context.Context, u *repoUser) error {
+			events = append(events, "created")
+			return nil
+		},
+		AfterUpdate: func(ctx context.Context, id any, fields map[string]any) error {
+			events = append(events, "updated")
+			return nil
+		},
+		AfterDelete: func(ctx context.Context, id any) error {
+			events = append(events, "deleted")
+			return nil
+		},
+	}))
+	if err != nil {
+		t.Fatalf("NewRepository failed: %v", err)
+	}
+
+	if err := repo.Create(ctx, &repoUser{}); err == nil {
+		t.Error("Expected the BeforeCreate error")
+	}
+	for i, name := range []string{"a", "b", "c"} {
+		u := &repoUser{Name: name, Status: i % 2}
+		if err := repo.Create(ctx, u); err != nil || u.ID == 0 {
+			t.Fatalf("Create failed: %v, id %d", err, u.ID)
+		}
+	}
+
+	u, err := repo.GetByID(ctx, 2)
+	if err != nil || u.Name != "b" {
+		t.Fatalf("GetByID = %+v, %v", u, err)
+	}
+	if err := repo.UpdateFields(ctx, 2, map[string]any{"Name": "bb", "status": 0}); err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+	if u, _ := repo.GetByID(ctx, 2); u.Name != "bb" || u.Status != 0 {
+		t.Errorf("Updated user = %+v", u)
+	}
+	if err := repo.UpdateFields(ctx, 9, map[string]any{"name": "x"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected ErrRecordNotFound, got %v", err)
+	}
+	if err := repo.UpdateFields(ctx, 2, map[string]any{"name; --": "x"}); err == nil {
+		t.Error("Expected error for unknown column")
+	}
+
+	if err := repo.DeleteSoft(ctx, 1); err != nil {
+		t.Fatalf("DeleteSoft failed: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, 1); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected the soft deleted user not found, got %v", err)
+	}
+	if err := repo.DeleteSoft(ctx, 1); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected ErrRecordNotFound deleting twice, got %v", err)
+	}
+	var n int64
+	c.db.Unscoped().Model(&repoUser{}).Count(&n)
+	if n != 3 {
+		t.Errorf("Expected the row kept, got %d rows", n)
+	}
+
+	users, err := repo.List(ctx, map[string]any{"status": 0}, func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+	if err != nil || len(users) != 2 || users[0].ID != 2 || users[1].ID != 3 {
+		t.Errorf("List = %+v, %v", users, err)
+	}
+	if n, err := repo.Count(ctx, map[string]any{"id": []int64{1, 2}}); err != nil || n != 1 {
+		t.Errorf("Count = %d, %v", n, err)
+	}
+	if _, err := repo.List(ctx, map[string]any{"password": "x"}); err == nil {
+		t.Error("Expected error for unknown column")
+	}
+
+	if len(events) != 5 || events[3] != "updated" || events[4] != "deleted" {
+		t.Errorf("events = %v", events)
+	}
+}
+
+// TestRepositoryTransact tests the repository joining the transaction in ctx.
+func TestRepositoryTransact(t *testing.T) {
+	c := newRepoClient(t)
+	ctx := context.Background()
+	repo, _ := NewRepository[repoUser](c)
+	err := c.Transact(ctx, func(tx *gorm.DB) error {
+		if err := repo.Create(tx.Statement.Context, &repoUser{Name: "a"}); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("Expected the transaction error")
+	}
+	if n, _ := repo.Count(ctx, nil); n != 0 {
+		t.Errorf("Expected the create rolled back, got %d users", n)
+	}
+
+	tags, _ := NewRepository[repoTag](c)
+	if err := tags.DeleteSoft(ctx, 1); !errors.Is(err, ErrSoftDeleteUnsupported) {
+		t.Errorf("Expected ErrSoftDeleteUnsupported, got %v", err)
+	}
+}
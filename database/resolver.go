@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 副本选择策略
+const (
+	PolicyRandom     = "random"
+	PolicyRoundRobin = "round_robin"
+)
+
+// ReplicaConfig 只读副本配置，连接池参数为 0 时使用主库的配置
+type ReplicaConfig struct {
+	DSN             Connect       `mapstructure:"dsn" json:"dsn" yaml:"dsn"`
+	MaxOpenConns    int           `mapstructure:"max_open_conns" yaml:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time" yaml:"conn_max_idle_time"`
+}
+
+// registerReplicas 为每个副本创建独立的连接池，并通过 dbresolver 将读请求分发到副本
+func registerReplicas(db *gorm.DB, cfg *DBConfig) ([]*sql.DB, error) {
+	var policy dbresolver.Policy
+	switch cfg.ReplicaPolicy {
+	case "", PolicyRandom:
+		policy = dbresolver.RandomPolicy{}
+	case PolicyRoundRobin:
+		policy = dbresolver.StrictRoundRobinPolicy()
+	default:
+		return nil, fmt.Errorf("database: replica policy %s not supported", cfg.ReplicaPolicy)
+	}
+
+	var (
+		pools      []*sql.DB
+		dialectors []gorm.Dialector
+	)
+	closeAll := func() {
+		for _, p := range pools {
+			_ = p.Close()
+		}
+	}
+	for i := range cfg.Replicas {
+		rc := &cfg.Replicas[i]
+		pool, err := openPool(cfg, rc)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to open replica %d: %w", i, err)
+		}
+		pools = append(pools, pool)
+		dialector, err := replicaDialector(cfg.Driver, pool)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		dialectors = append(dialectors, dialector)
+	}
+
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   policy,
+	})); err != nil {
+		closeAll()
+		return nil, fmt.Errorf("failed to register replicas: %w", err)
+	}
+	return pools, nil
+}
+
+// openPool 按 rc 的 DSN 和连接池参数建立连接池并 Ping，参数为 0 时使用主库的配置，用于副本和租户
+func openPool(cfg *DBConfig, rc *ReplicaConfig) (*sql.DB, error) {
+	d, err := lookupDriver(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := sql.Open(d.SQLDriver, d.DSN(cfg, &rc.DSN))
+	if err != nil {
+		return nil, err
+	}
+	pool.SetMaxOpenConns(orDefault(rc.MaxOpenConns, cfg.MaxOpenConns))
+	pool.SetMaxIdleConns(orDefault(rc.MaxIdleConns, cfg.MaxIdleConns))
+	pool.SetConnMaxLifetime(orDefault(rc.ConnMaxLifetime, cfg.ConnMaxLifetime))
+	pool.SetConnMaxIdleTime(orDefault(rc.ConnMaxIdleTime, cfg.ConnMaxIdleTime))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := pool.PingContext(ctx); err != nil {
+		_ = pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+func replicaDialector(driver string, pool *sql.DB) (gorm.Dialector, error) {
+	d, err := lookupDriver(driver)
+	if err != nil {
+		return nil, err
+	}
+	return d.New(pool), nil
+}
+
+func orDefault[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+	return v
+}
+
+// Primary 获取强制走主库的 GORM 实例，用于写后立即读等场景
+func (c *Client) Primary(ctx context.Context) *gorm.DB {
+	return c.GetDB(ctx).Clauses(dbresolver.Write)
+}
+
+// Replica 获取强制走只读副本的 GORM 实例，未配置副本时使用主库
+func (c *Client) Replica(ctx context.Context) *gorm.DB {
+	return c.GetDB(ctx).Clauses(dbresolver.Read)
+}
+
+func (c *Client) pingReplicas(ctx context.Context) error {
+	var errs []error
+	for i, r := range c.replicas {
+		if err := r.PingContext(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Client) closeReplicas() error {
+	var errs []error
+	for i, r := range c.replicas {
+		if err := r.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+type resolverItem struct {
+	ID   uint
+	Name string
+}
+
+// TestReplicas tests that the reads go to the replicas and the writes to the primary.
+func TestReplicas(t *testing.T) {
+	dir := t.TempDir()
+	primaryPath, replicaPath := filepath.Join(dir, "primary.db"), filepath.Join(dir, "replica.db")
+
+	// seed the replica with its own row to tell the pools apart
+	seed, err := newClient(&DBConfig{Driver: DriverSQLite, DSN: Connect{Name: replicaPath}}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("open replica: %v", err)
+	}
+	ctx := context.Background()
+	if err := seed.GetDB(ctx).AutoMigrate(&resolverItem{}); err != nil {
+		t.Fatal(err)
+	}
+	seed.GetDB(ctx).Create(&resolverItem{Name: "replica"})
+	_ = seed.Close()
+
+	c, err := newClient(&DBConfig{
+		Driver:        DriverSQLite,
+		DSN:           Connect{Name: primaryPath},
+		MaxOpenConns:  1,
+		Replicas:      []ReplicaConfig{{DSN: Connect{Name: replicaPath}, MaxOpenConns: 2}},
+		ReplicaPolicy: PolicyRoundRobin,
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	if err := c.Primary(ctx).AutoMigrate(&resolverItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GetDB(ctx).Create(&resolverItem{Name: "primary"}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var item resolverItem
+	c.GetDB(ctx).First(&item)
+	if item.Name != "replica" {
+		t.Errorf("Expected read from replica, got %q", item.Name)
+	}
+	c.Primary(ctx).First(&item)
+	if item.Name != "primary" {
+		t.Errorf("Expected read from primary, got %q", item.Name)
+	}
+	if len(c.replicas) != 1 || c.replicas[0].Stats().MaxOpenConnections != 2 {
+		t.Error("Expected replica pool with its own settings")
+	}
+	if err := c.Health(ctx); err != nil {
+		t.Errorf("Health failed: %v", err)
+	}
+
+	if _, err := newClient(&DBConfig{Driver: DriverSQLite, Replicas: []ReplicaConfig{{}}, ReplicaPolicy: "weighted"}, &mockLogger{}); err == nil {
+		t.Error("Expected error for unsupported policy")
+	}
+}
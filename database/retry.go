@@ -0,0 +1,314 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/errs"
+	"github.com/baisiyi/go-kits/log"
+	"github.com/baisiyi/go-kits/retry"
+)
+
+// RetryConfig 瞬时错误自动重试配置，Attempts 大于 1 时启用
+type RetryConfig struct {
+	// Attempts 最大执行次数（包含首次），0 或 1 表示不重试
+	Attempts int `mapstructure:"attempts" yaml:"attempts"`
+	// BaseDelay 首次重试前的等待时间，之后每次翻倍，默认 50ms
+	BaseDelay time.Duration `mapstructure:"base_delay" yaml:"base_delay"`
+	// MaxDelay 重试等待时间上限，默认 1s
+	MaxDelay time.Duration `mapstructure:"max_delay" yaml:"max_delay"`
+	// RetryableErrors 额外的可重试错误，错误信息包含其中任一字符串时重试
+	RetryableErrors []string `mapstructure:"retryable_errors" yaml:"retryable_errors"`
+}
+
+func (c *RetryConfig) setDefaults() {
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = 50 * time.Millisecond
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = time.Second
+	}
+}
+
+// ConnectRetryConfig 初始化时建立连接失败的重试配置，Attempts 大于 1 时启用，如数据库晚于服务启动
+type ConnectRetryConfig struct {
+	// Attempts 最大连接次数（包含首次），0 或 1 表示不重试
+	Attempts int `mapstructure:"attempts" yaml:"attempts"`
+	// BaseDelay 首次重试前的等待时间，之后每次翻倍，默认 1s
+	BaseDelay time.Duration `mapstructure:"base_delay" yaml:"base_delay"`
+	// MaxDelay 重试等待时间上限，默认 10s
+	MaxDelay time.Duration `mapstructure:"max_delay" yaml:"max_delay"`
+}
+
+// do 执行 connect 并在失败时按配置重试，每次重试通过日志记录，connect 通过 retry.Unrecoverable 返回不可重试的错误
+func (c ConnectRetryConfig) do(logger log.Logger, connect func() error) error {
+	if c.Attempts <= 1 {
+		return connect()
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = time.Second
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = 10 * time.Second
+	}
+	return retry.Do(context.Background(), func(context.Context) error {
+		return connect()
+	},
+		retry.Attempts(c.Attempts),
+		retry.ExponentialBackoff(c.BaseDelay, c.MaxDelay),
+		retry.Jitter(),
+		retry.OnRetry(func(attempt int, err error, delay time.Duration) {
+			logger.Warnf("[DB_CONNECT_RETRY] Attempt: %d/%d | Delay: %v | Error: %v", attempt, c.Attempts, delay, err)
+		}),
+	)
+}
+
+// 连接中断的错误信息：SQL 可能已在服务端执行，只重试读操作和标记为幂等的写操作
+var connLostMessages = []string{
+	"server has gone away",
+	"lost connection",
+	"connection reset by peer",
+	"broken pipe",
+	"invalid connection",
+	"bad connection",
+}
+
+// 其他默认可重试的错误信息：SQL 未执行或已回滚
+var retryableMessages = []string{
+	"connection refused",
+	"deadlock",
+	"database is locked",
+}
+
+// idempotentKey 标记写操作可以安全地重复执行
+const idempotentKey = "go-kits:idempotent"
+
+// startedTransactionKey 是 gorm:begin_transaction 开启默认事务时设置的标记
+const startedTransactionKey = "gorm:started_transaction"
+
+// retryingKey 标记默认事务正在重新执行，避免重新执行时再次重试
+const retryingKey = "go-kits:retrying"
+
+// Idempotent 将写操作标记为幂等，连接中断时 RetryPlugin 也会重试，如按主键覆盖写入的 Save：
+//
+//	db.Scopes(database.Idempotent).Save(&user)
+func Idempotent(db *gorm.DB) *gorm.DB {
+	return db.Set(idempotentKey, true)
+}
+
+// RetryPlugin GORM 重试插件，对事务外执行失败且为瞬时错误（死锁、锁等待超时、连接中断等）的 SQL
+// 按指数退避加抖动重试，每次重试通过日志记录。GORM 为写操作开启的默认事务回滚后整体重新执行；
+// Transaction、Begin 开启的事务内的 SQL 不重试，由调用方重试整个事务。
+// 连接中断时写操作可能已经执行，INSERT、UPDATE、DELETE 和 Exec 只在通过 Idempotent 标记后重试
+type RetryPlugin struct {
+	cfg    RetryConfig
+	logger log.Logger
+}
+
+// NewRetryPlugin 创建重试插件，通过 db.Use 注册
+func NewRetryPlugin(cfg RetryConfig, logger log.Logger) *RetryPlugin {
+	cfg.setDefaults()
+	return &RetryPlugin{cfg: cfg, logger: logger}
+}
+
+// Name 实现 gorm.Plugin 接口
+func (p *RetryPlugin) Name() string {
+	return "go-kits:retry"
+}
+
+// Initialize 实现 gorm.Plugin 接口，包装各类操作的执行回调
+func (p *RetryPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	processors := []struct {
+		name  string
+		write bool
+		get   func(string) func(*gorm.DB)
+		set   func(string, func(*gorm.DB)) error
+	}{
+		{"gorm:create", true, cb.Create().Get, cb.Create().Replace},
+		{"gorm:query", false, cb.Query().Get, cb.Query().Replace},
+		{"gorm:update", true, cb.Update().Get, cb.Update().Replace},
+		{"gorm:delete", true, cb.Delete().Get, cb.Delete().Replace},
+		{"gorm:row", false, cb.Row().Get, cb.Row().Replace},
+		{"gorm:raw", true, cb.Raw().Get, cb.Raw().Replace},
+	}
+	for _, pr := range processors {
+		fn := pr.get(pr.name)
+		if fn == nil {
+			continue
+		}
+		if err := pr.set(pr.name, p.wrap(fn, pr.write)); err != nil {
+			return err
+		}
+	}
+	// 默认事务内的 SQL 失败时事务已不可用（如死锁后已回滚），在事务结束后重新执行整个操作
+	transactional := []struct {
+		get     func(string) func(*gorm.DB)
+		set     func(string, func(*gorm.DB)) error
+		execute func(*gorm.DB) *gorm.DB
+	}{
+		{cb.Create().Get, cb.Create().Replace, cb.Create().Execute},
+		{cb.Update().Get, cb.Update().Replace, cb.Update().Execute},
+		{cb.Delete().Get, cb.Delete().Replace, cb.Delete().Execute},
+	}
+	for _, tr := range transactional {
+		fn := tr.get("gorm:commit_or_rollback_transaction")
+		if fn == nil {
+			continue
+		}
+		if err := tr.set("gorm:commit_or_rollback_transaction", p.wrapCommit(fn, tr.execute)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wrap 返回失败时按配置重试 fn 的回调，write 表示 fn 执行写操作
+func (p *RetryPlugin) wrap(fn func(*gorm.DB), write bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || inTransaction(db) {
+			fn(db)
+			return
+		}
+		// gorm:row 执行时会删除 rows 设置，重试前需要恢复
+		isRows, hasRows := db.Statement.Settings.Load("rows")
+		err := p.do(db, write, func() {
+			if hasRows {
+				db.Statement.Settings.Store("rows", isRows)
+			}
+			fn(db)
+		}, db.Statement.SQL.String)
+		if err != nil {
+			_ = db.AddError(err)
+		}
+	}
+}
+
+// wrapCommit 返回默认事务回滚后按配置重新执行整个操作的回调，commit 为 gorm:commit_or_rollback_transaction，
+// execute 执行操作的所有回调，包括开启和结束默认事务
+func (p *RetryPlugin) wrapCommit(commit func(*gorm.DB), execute func(*gorm.DB) *gorm.DB) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		_, started := db.InstanceGet(startedTransactionKey)
+		commit(db)
+		if !started || db.Error == nil {
+			return
+		}
+		if _, retrying := db.Statement.Settings.Load(retryingKey); retrying {
+			return
+		}
+		db.Statement.Settings.Store(retryingKey, true)
+		defer db.Statement.Settings.Delete(retryingKey)
+		// 重新执行时会重置 SQL，日志中记录首次执行的 SQL
+		sql := db.Statement.SQL.String()
+		executed := false
+		err := p.do(db, true, func() {
+			if !executed {
+				// 首次执行的结果
+				executed = true
+				return
+			}
+			execute(db)
+		}, func() string { return sql })
+		if err != nil {
+			_ = db.AddError(err)
+		}
+	}
+}
+
+// do 执行 fn 并在失败时按配置重试，fn 的错误通过 db.Error 返回，最终的错误在重试结束后由调用方写回。
+// write 表示写操作，未通过 Idempotent 标记时连接中断不重试
+func (p *RetryPlugin) do(db *gorm.DB, write bool, fn func(), sql func() string) error {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, idempotent := db.Statement.Settings.Load(idempotentKey)
+	retryable := p.retryable
+	if write && !idempotent {
+		retryable = func(err error) bool {
+			return p.retryable(err) && !connectionLost(err)
+		}
+	}
+	return retry.Do(ctx, func(context.Context) error {
+		fn()
+		err := db.Error
+		if err != nil {
+			// 清除错误以便下次执行
+			db.Error = nil
+			db.Statement.RowsAffected = 0
+		}
+		return err
+	},
+		retry.Attempts(p.cfg.Attempts),
+		retry.ExponentialBackoff(p.cfg.BaseDelay, p.cfg.MaxDelay),
+		retry.Jitter(),
+		retry.RetryIf(retryable),
+		retry.OnRetry(func(attempt int, err error, delay time.Duration) {
+			log.WithContextFields(p.logger, ctx).Warnf("[DB_RETRY] Attempt: %d/%d | Delay: %v | Error: %v | SQL: %s",
+				attempt, p.cfg.Attempts, delay, err, sql())
+		}),
+	)
+}
+
+// retryable 判断错误是否为可重试的瞬时错误
+func (p *RetryPlugin) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// 自定义 Dialector、连接池等返回的带错误码的错误
+	var coded *errs.Error
+	if errors.As(err, &coded) && (coded.Code() == errs.CodeUnavailable || coded.Code() == errs.CodeAborted) {
+		return true
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		// 1213: 死锁，1205: 锁等待超时
+		return myErr.Number == 1213 || myErr.Number == 1205
+	}
+	var pgErr sqlStateError
+	if errors.As(err, &pgErr) {
+		// 40P01: 死锁，40001: 序列化失败
+		return pgErr.SQLState() == "40P01" || pgErr.SQLState() == "40001"
+	}
+	if connectionLost(err) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range retryableMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	for _, s := range p.cfg.RetryableErrors {
+		if s != "" && strings.Contains(msg, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// connectionLost 判断错误是否为连接中断，此时无法确定 SQL 是否已在服务端执行
+func connectionLost(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range connLostMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// inTransaction 判断当前 SQL 是否在事务中执行，包括 GORM 的默认事务
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
@@ -0,0 +1,230 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/errs"
+)
+
+// retryLogger calls onWarn for each retry log.
+type retryLogger struct {
+	mockLogger
+	onWarn func()
+}
+
+func (l *retryLogger) Warnf(format string, args ...interface{}) {
+	l.mockLogger.Warnf(format, args...)
+	if l.onWarn != nil {
+		l.onWarn()
+	}
+}
+
+// TestRetryPlugin tests retrying the transient errors outside the transactions.
+func TestRetryPlugin(t *testing.T) {
+	logger := &retryLogger{}
+	c, err := newClient(&DBConfig{
+		Driver: DriverSQLite,
+		DSN:    Connect{Name: filepath.Join(t.TempDir(), "retry.db")},
+		Retry:  RetryConfig{Attempts: 3, BaseDelay: time.Millisecond, RetryableErrors: []string{"No such table"}},
+	}, logger)
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	// the table is missing in all the attempts.
+	var n int
+	err = c.GetDB(ctx).Raw("SELECT count(*) FROM retry_item").Scan(&n).Error
+	if err == nil {
+		t.Fatal("Expected error after the attempts exhausted")
+	}
+	if len(logger.warns) != 2 {
+		t.Errorf("Expected 2 retry logs, got %d", len(logger.warns))
+	}
+
+	// the table is created before the retry.
+	logger.warns = nil
+	logger.onWarn = func() {
+		logger.onWarn = nil
+		if err := c.db.Exec("CREATE TABLE retry_item (id INTEGER)").Error; err != nil {
+			t.Errorf("create table: %v", err)
+		}
+	}
+	if err := c.GetDB(ctx).Raw("SELECT count(*) FROM retry_item").Scan(&n).Error; err != nil {
+		t.Errorf("Expected success after retry, got %v", err)
+	}
+	if len(logger.warns) != 1 {
+		t.Errorf("Expected 1 retry log, got %d", len(logger.warns))
+	}
+
+	// the statements in transactions are not retried.
+	logger.warns = nil
+	err = c.GetDB(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Raw("SELECT count(*) FROM retry_missing").Scan(&n).Error
+	})
+	if err == nil || len(logger.warns) != 0 {
+		t.Errorf("Expected error without retry in transaction, got %v and %d retries", err, len(logger.warns))
+	}
+}
+
+// TestRetryPluginWrites tests that the writes are retried on the connection loss only if
+// marked idempotent.
+func TestRetryPluginWrites(t *testing.T) {
+	logger := &retryLogger{}
+	c, err := newClient(&DBConfig{
+		Driver: DriverSQLite,
+		DSN:    Connect{Name: filepath.Join(t.TempDir(), "retry.db")},
+		Retry:  RetryConfig{Attempts: 3, BaseDelay: time.Millisecond},
+	}, logger)
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	db := c.GetDB(context.Background())
+	if err := db.AutoMigrate(&repoUser{}); err != nil {
+		t.Fatal(err)
+	}
+	// the inserts fail like a connection lost after the statement was sent
+	if err := db.Exec(`CREATE TRIGGER lost BEFORE INSERT ON repo_user BEGIN SELECT RAISE(ABORT, 'lost connection'); END`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Create(&repoUser{Name: "a"}).Error; err == nil || len(logger.warns) != 0 {
+		t.Errorf("Expected error without retry, got %v and %d retries", err, len(logger.warns))
+	}
+	if err := db.Scopes(Idempotent).Create(&repoUser{Name: "a"}).Error; err == nil || len(logger.warns) != 2 {
+		t.Errorf("Expected error after 2 retries, got %v and %d retries", err, len(logger.warns))
+	}
+}
+
+// TestRetryPluginDefaultTransaction tests that the writes in the default transactions are
+// retried as a whole after the transactions are rolled back.
+func TestRetryPluginDefaultTransaction(t *testing.T) {
+	logger := &retryLogger{}
+	c, err := newClient(&DBConfig{
+		Driver: DriverSQLite,
+		DSN:    Connect{Name: filepath.Join(t.TempDir(), "retry.db")},
+		Retry:  RetryConfig{Attempts: 3, BaseDelay: time.Millisecond},
+	}, logger)
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	db := c.GetDB(context.Background())
+	if err := db.AutoMigrate(&repoUser{}); err != nil {
+		t.Fatal(err)
+	}
+	// the insert deadlocks until the trigger is dropped before the retry
+	if err := db.Exec(`CREATE TRIGGER deadlock BEFORE INSERT ON repo_user BEGIN SELECT RAISE(ABORT, 'deadlock'); END`).Error; err != nil {
+		t.Fatal(err)
+	}
+	logger.onWarn = func() {
+		logger.onWarn = nil
+		if err := c.db.Exec("DROP TRIGGER deadlock").Error; err != nil {
+			t.Errorf("drop trigger: %v", err)
+		}
+	}
+
+	user := &repoUser{Name: "a"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Expected success after retry, got %v", err)
+	}
+	if len(logger.warns) != 1 {
+		t.Errorf("Expected 1 retry log, got %d", len(logger.warns))
+	}
+	var n int64
+	if err := db.Model(&repoUser{}).Count(&n).Error; err != nil || n != 1 || user.ID == 0 {
+		t.Errorf("Expected 1 user created, got %d %v id %d", n, err, user.ID)
+	}
+
+	// the statements in the transactions of the caller are not retried
+	logger.warns = nil
+	if err := db.Exec(`CREATE TRIGGER deadlock BEFORE INSERT ON repo_user BEGIN SELECT RAISE(ABORT, 'deadlock'); END`).Error; err != nil {
+		t.Fatal(err)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&repoUser{Name: "b"}).Error
+	})
+	if err == nil || len(logger.warns) != 0 {
+		t.Errorf("Expected error without retry in transaction, got %v and %d retries", err, len(logger.warns))
+	}
+}
+
+// TestConnectRetry tests that newClient retries connecting until the database is available.
+func TestConnectRetry(t *testing.T) {
+	// the directory of the database is created before the retry
+	dir := filepath.Join(t.TempDir(), "data")
+	logger := &retryLogger{}
+	logger.onWarn = func() {
+		logger.onWarn = nil
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Errorf("mkdir: %v", err)
+		}
+	}
+	cfg := &DBConfig{
+		Driver:       DriverSQLite,
+		DSN:          Connect{Name: filepath.Join(dir, "connect.db")},
+		ConnectRetry: ConnectRetryConfig{Attempts: 3, BaseDelay: time.Millisecond},
+	}
+	c, err := newClient(cfg, logger)
+	if err != nil {
+		t.Fatalf("Expected success after retry, got %v", err)
+	}
+	defer c.Close()
+	if len(logger.warns) != 1 {
+		t.Errorf("Expected 1 retry log, got %d", len(logger.warns))
+	}
+
+	// the attempts are exhausted
+	logger.warns = nil
+	cfg.DSN.Name = filepath.Join(t.TempDir(), "missing", "connect.db")
+	if _, err := newClient(cfg, logger); err == nil || len(logger.warns) != 2 {
+		t.Errorf("Expected error after 2 retries, got %v and %d retries", err, len(logger.warns))
+	}
+
+	// the config errors are not retried
+	logger.warns = nil
+	if _, err := newClient(&DBConfig{Driver: "oracle", ConnectRetry: cfg.ConnectRetry}, logger); err == nil || len(logger.warns) != 0 {
+		t.Errorf("Expected error without retry, got %v and %d retries", err, len(logger.warns))
+	}
+}
+
+// TestRetryPluginRetryable tests the retryable errors.
+func TestRetryPluginRetryable(t *testing.T) {
+	p := NewRetryPlugin(RetryConfig{Attempts: 3, RetryableErrors: []string{"too many connections"}}, &mockLogger{})
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, true},
+		{&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, true},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, false},
+		{&pgconn.PgError{Code: "40P01"}, true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{errors.New("Error 2006: MySQL server has gone away"), true},
+		{errors.New("read tcp: connection reset by peer"), true},
+		{errors.New("Error 1040: Too many connections"), true},
+		{errors.New("syntax error"), false},
+		{context.Canceled, false},
+		{errs.Wrap(errors.New("pool exhausted"), errs.CodeUnavailable, "acquire conn"), true},
+		{errs.New(errs.CodeInvalidArgument, "bad query"), false},
+	}
+	for _, tt := range tests {
+		if got := p.retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
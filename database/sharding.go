@@ -0,0 +1,418 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMissingShardKey SQL 的条件和模型中都没有分表键的值
+var ErrMissingShardKey = errors.New("database: sharding key not found")
+
+// ShardingConfig 按后缀分表配置，声明的逻辑表按分表键的值路由到 表名_00 至 表名_{Shards-1}，
+// 后缀至少两位，如 64 张分表为 orders_00..orders_63
+type ShardingConfig struct {
+	// Tables 分表的逻辑表名，如 orders
+	Tables []string `mapstructure:"tables" yaml:"tables"`
+	// ShardKey 分表键列名，如 user_id
+	ShardKey string `mapstructure:"shard_key" yaml:"shard_key"`
+	// Shards 分表数量
+	Shards int `mapstructure:"shards" yaml:"shards"`
+	// ShardFunc 自定义分表算法，返回分表序号；为空时整数按值取模、字符串按 CRC32 取模
+	ShardFunc func(value any) (int, error) `mapstructure:"-" yaml:"-"`
+}
+
+// ShardingPlugin GORM 分表插件，在各类操作执行前从 WHERE 条件或模型中取出分表键的值，
+// 将逻辑表替换为对应的分表，Repo 代码无需修改。Raw/Exec 的 SQL 不会改写，可通过 ShardTable 获取表名
+type ShardingPlugin struct {
+	tables map[string]*shardRule
+}
+
+// shardRule 分表配置及匹配分表键等值、IN 条件的正则
+type shardRule struct {
+	ShardingConfig
+	keyExpr *regexp.Regexp
+}
+
+// orExpr 匹配 SQL 条件中的 OR
+var orExpr = regexp.MustCompile(`(?i)\bOR\b`)
+
+// NewShardingPlugin 创建分表插件，通过 db.Use 注册
+func NewShardingPlugin(cfgs ...ShardingConfig) (*ShardingPlugin, error) {
+	p := &ShardingPlugin{tables: make(map[string]*shardRule)}
+	for _, cfg := range cfgs {
+		if cfg.ShardKey == "" || cfg.Shards <= 0 {
+			return nil, fmt.Errorf("database: sharding of %v requires shard_key and shards", cfg.Tables)
+		}
+		rule := &shardRule{
+			ShardingConfig: cfg,
+			keyExpr: regexp.MustCompile("(?i)(?:^|[^\\w])([`\"]?" + regexp.QuoteMeta(cfg.ShardKey) +
+				"[`\"]?)\\s*(?:=|(IN))\\s*\\(?\\s*\\?"),
+		}
+		for _, table := range cfg.Tables {
+			if _, ok := p.tables[table]; ok {
+				return nil, fmt.Errorf("database: table %s sharded twice", table)
+			}
+			p.tables[table] = rule
+		}
+	}
+	return p, nil
+}
+
+// Name 实现 gorm.Plugin 接口
+func (p *ShardingPlugin) Name() string {
+	return "go-kits:sharding"
+}
+
+// Initialize 实现 gorm.Plugin 接口，在各类操作前注册路由回调
+func (p *ShardingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		op       string
+		register func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register},
+	}
+	for _, h := range hooks {
+		if err := h.register("sharding:"+h.op, p.route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShardTable 返回逻辑表中分表键的值所在的分表名，未分表的表原样返回
+func (p *ShardingPlugin) ShardTable(table string, value any) (string, error) {
+	cfg, ok := p.tables[table]
+	if !ok {
+		return table, nil
+	}
+	shard, err := cfg.shard(value)
+	if err != nil {
+		return "", err
+	}
+	return cfg.tableName(table, shard), nil
+}
+
+// ShardTables 返回逻辑表的全部分表名，例如用于建表迁移，未分表的表返回 nil
+func (p *ShardingPlugin) ShardTables(table string) []string {
+	cfg, ok := p.tables[table]
+	if !ok {
+		return nil
+	}
+	tables := make([]string, cfg.Shards)
+	for i := range tables {
+		tables[i] = cfg.tableName(table, i)
+	}
+	return tables
+}
+
+// route 将逻辑表替换为分表键的值所在的分表
+func (p *ShardingPlugin) route(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil {
+		return
+	}
+	table := stmt.Table
+	cfg, ok := p.tables[table]
+	if !ok {
+		return
+	}
+	values, ok := cfg.whereValues(stmt)
+	if !ok && !hasOr(stmt) {
+		// 有 OR 时模型字段不能代表所有分支，例如查询的目标切片中残留的数据
+		values = modelValues(stmt, cfg.ShardKey)
+	}
+	if len(values) == 0 {
+		_ = db.AddError(fmt.Errorf("%w: %s.%s", ErrMissingShardKey, table, cfg.ShardKey))
+		return
+	}
+	shard := -1
+	for _, v := range values {
+		s, err := cfg.shard(v)
+		if err != nil {
+			_ = db.AddError(err)
+			return
+		}
+		if shard >= 0 && s != shard {
+			_ = db.AddError(fmt.Errorf("database: rows of %s span multiple shards", table))
+			return
+		}
+		shard = s
+	}
+	stmt.Table = cfg.tableName(table, shard)
+	if stmt.TableExpr != nil {
+		stmt.TableExpr = &clause.Expr{SQL: stmt.Quote(stmt.Table)}
+	}
+}
+
+func (c *ShardingConfig) tableName(table string, shard int) string {
+	width := max(2, len(strconv.Itoa(c.Shards-1)))
+	return fmt.Sprintf("%s_%0*d", table, width, shard)
+}
+
+// shard 计算分表键的值所在的分表序号
+func (c *ShardingConfig) shard(value any) (int, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return 0, err
+		}
+		value = v
+	}
+	if c.ShardFunc != nil {
+		shard, err := c.ShardFunc(value)
+		if err != nil {
+			return 0, err
+		}
+		if shard < 0 || shard >= c.Shards {
+			return 0, fmt.Errorf("database: shard %d out of range [0, %d)", shard, c.Shards)
+		}
+		return shard, nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int() % int64(c.Shards)
+		if n < 0 {
+			n = -n
+		}
+		return int(n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint() % uint64(c.Shards)), nil
+	case reflect.String:
+		return int(crc32.ChecksumIEEE([]byte(v.String())) % uint32(c.Shards)), nil
+	default:
+		return 0, fmt.Errorf("database: sharding key of type %T not supported", value)
+	}
+}
+
+// whereValues 从 WHERE 条件中取出分表键的值，OR 连接的每个分支都需要有分表键的等值或 IN 条件，
+// 否则返回 false，避免只查询其中一张分表而静默丢失其他分表的数据
+func (r *shardRule) whereValues(stmt *gorm.Statement) ([]any, bool) {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return nil, false
+	}
+	where, ok := c.Expression.(clause.Where)
+	if !ok {
+		return nil, false
+	}
+	return r.exprsValues(where.Exprs)
+}
+
+// hasOr 返回 WHERE 条件中是否有 OR
+func hasOr(stmt *gorm.Statement) bool {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return false
+	}
+	where, ok := c.Expression.(clause.Where)
+	return ok && exprsHaveOr(where.Exprs)
+}
+
+func exprsHaveOr(exprs []clause.Expression) bool {
+	for _, expr := range exprs {
+		switch e := expr.(type) {
+		case clause.OrConditions:
+			return true
+		case clause.AndConditions:
+			if exprsHaveOr(e.Exprs) {
+				return true
+			}
+		case clause.NotConditions:
+			if exprsHaveOr(e.Exprs) {
+				return true
+			}
+		case clause.Expr:
+			if orExpr.MatchString(e.SQL) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exprsValues 按 gorm 拼接条件的规则，只有一个条件的 OrConditions 与前面的条件以 OR 连接，
+// 其余条件以 AND 连接，返回拆分出的各分支中分表键的值
+func (r *shardRule) exprsValues(exprs []clause.Expression) ([]any, bool) {
+	var branches [][]clause.Expression
+	for i, expr := range exprs {
+		if or, ok := expr.(clause.OrConditions); ok && len(or.Exprs) == 1 && i > 0 {
+			branches = append(branches, []clause.Expression{or.Exprs[0]})
+			continue
+		}
+		if len(branches) == 0 {
+			branches = append(branches, nil)
+		}
+		branches[len(branches)-1] = append(branches[len(branches)-1], expr)
+	}
+	if len(branches) == 0 {
+		return nil, false
+	}
+	var values []any
+	for _, branch := range branches {
+		found := false
+		for _, expr := range branch {
+			if vs, ok := r.exprValues(expr); ok {
+				values = append(values, vs...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+func (r *shardRule) exprValues(expr clause.Expression) ([]any, bool) {
+	switch e := expr.(type) {
+	case clause.Eq:
+		if columnName(e.Column) == r.ShardKey {
+			return []any{e.Value}, true
+		}
+	case clause.IN:
+		if columnName(e.Column) == r.ShardKey && len(e.Values) > 0 {
+			return e.Values, true
+		}
+	case clause.Expr:
+		return r.sqlValues(e)
+	case clause.AndConditions:
+		return r.exprsValues(e.Exprs)
+	case clause.OrConditions:
+		// 以 OR 连接，每个条件都需要约束分表键
+		var values []any
+		for _, sub := range e.Exprs {
+			vs, ok := r.exprValues(sub)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, vs...)
+		}
+		return values, len(values) > 0
+	}
+	return nil, false
+}
+
+// sqlValues 从 "user_id = ?"、"user_id IN ?" 形式的条件中取出参数。括号外的 OR 将 SQL 拆分为
+// 多个分支，每个分支都需要有括号外的分表键条件，括号内的条件可能属于 OR 的分支，不作为依据
+func (r *shardRule) sqlValues(e clause.Expr) ([]any, bool) {
+	var ors []int
+	for _, loc := range orExpr.FindAllStringIndex(e.SQL, -1) {
+		if parenDepth(e.SQL[:loc[0]]) == 0 {
+			ors = append(ors, loc[0])
+		}
+	}
+	covered := make([]bool, len(ors)+1)
+	var values []any
+	for _, m := range r.keyExpr.FindAllStringSubmatchIndex(e.SQL, -1) {
+		if parenDepth(e.SQL[:m[2]]) != 0 {
+			continue
+		}
+		i := strings.Count(e.SQL[:m[1]], "?") - 1
+		if i >= len(e.Vars) {
+			return nil, false
+		}
+		if m[4] >= 0 {
+			vs := inValues(e.Vars[i])
+			if len(vs) == 0 {
+				return nil, false
+			}
+			values = append(values, vs...)
+		} else {
+			values = append(values, e.Vars[i])
+		}
+		branch := 0
+		for branch < len(ors) && ors[branch] < m[2] {
+			branch++
+		}
+		covered[branch] = true
+	}
+	for _, ok := range covered {
+		if !ok {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+// parenDepth 返回 SQL 片段末尾所在的括号层数
+func parenDepth(sql string) int {
+	return strings.Count(sql, "(") - strings.Count(sql, ")")
+}
+
+// inValues 展开 IN 条件的切片参数
+func inValues(v any) []any {
+	rv := reflect.ValueOf(v)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []any{v}
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values
+}
+
+func columnName(column any) string {
+	var name string
+	switch c := column.(type) {
+	case string:
+		name = c
+	case clause.Column:
+		name = c.Name
+	default:
+		return ""
+	}
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.Trim(name, "`\"")
+}
+
+// modelValues 从模型中取出分表键的值，批量创建时为每一行的值，任一行为零值时返回 nil
+func modelValues(stmt *gorm.Statement, key string) []any {
+	if stmt.Schema == nil {
+		return nil
+	}
+	field := stmt.Schema.LookUpField(key)
+	if field == nil {
+		return nil
+	}
+	rv := reflect.Indirect(stmt.ReflectValue)
+	var rows []reflect.Value
+	switch rv.Kind() {
+	case reflect.Struct:
+		rows = append(rows, rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			rows = append(rows, reflect.Indirect(rv.Index(i)))
+		}
+	}
+	values := make([]any, 0, len(rows))
+	for _, row := range rows {
+		if row.Kind() != reflect.Struct {
+			return nil
+		}
+		v, zero := field.ValueOf(stmt.Context, row)
+		if zero {
+			return nil
+		}
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type shardOrder struct {
+	ID     int64 `gorm:"primaryKey"`
+	UserID int64
+	Amount int
+}
+
+func (shardOrder) TableName() string { return "orders" }
+
+func newShardingClient(t *testing.T) *Client {
+	t.Helper()
+	c, err := newClient(&DBConfig{
+		Driver:   DriverSQLite,
+		DSN:      Connect{Name: filepath.Join(t.TempDir(), "test.db")},
+		Sharding: []ShardingConfig{{Tables: []string{"orders"}, ShardKey: "user_id", Shards: 4}},
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	for _, table := range c.Sharding().ShardTables("orders") {
+		if err := c.GetDB(context.Background()).Table(table).AutoMigrate(&shardOrder{}); err != nil {
+			t.Fatalf("migrate %s: %v", table, err)
+		}
+	}
+	return c
+}
+
+// TestSharding tests that the reads and writes are routed to the shard of the key.
+func TestSharding(t *testing.T) {
+	c := newShardingClient(t)
+	ctx := context.Background()
+	db := c.GetDB(ctx)
+
+	if got := c.Sharding().ShardTables("orders"); len(got) != 4 || got[0] != "orders_00" || got[3] != "orders_03" {
+		t.Errorf("ShardTables = %v", got)
+	}
+	if err := db.Create(&shardOrder{ID: 1, UserID: 5, Amount: 10}).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := db.Create([]shardOrder{{ID: 2, UserID: 2, Amount: 20}, {ID: 3, UserID: 6, Amount: 30}}).Error; err != nil {
+		t.Fatalf("batch create: %v", err)
+	}
+	var n int64
+	db.Table("orders_01").Count(&n)
+	if n != 1 {
+		t.Errorf("orders_01 rows = %d, want 1", n)
+	}
+
+	var orders []shardOrder
+	if err := db.Where("user_id = ? AND amount > ?", 2, 0).Find(&orders).Error; err != nil || len(orders) != 1 {
+		t.Errorf("find = %v, %v", orders, err)
+	}
+	// all the OR branches in the same shard
+	var found []shardOrder
+	if err := db.Where("user_id = ?", 2).Or("user_id = ?", 6).Find(&found).Error; err != nil || len(found) != 2 {
+		t.Errorf("find or = %v, %v", found, err)
+	}
+	found = nil
+	if err := db.Where("amount > ?", 0).Where(db.Where("user_id = ?", 2).Or("user_id IN ?", []int64{6, 10})).
+		Find(&found).Error; err != nil || len(found) != 2 {
+		t.Errorf("find grouped or = %v, %v", found, err)
+	}
+	var order shardOrder
+	if err := db.Where(&shardOrder{UserID: 5}).First(&order).Error; err != nil || order.ID != 1 {
+		t.Errorf("first = %+v, %v", order, err)
+	}
+	if err := db.Model(&order).Update("amount", 11).Error; err != nil {
+		t.Errorf("update: %v", err)
+	}
+	if err := db.Model(&shardOrder{}).Where(map[string]any{"user_id": 5}).Count(&n).Error; err != nil || n != 1 {
+		t.Errorf("count = %d, %v", n, err)
+	}
+	if err := db.Where("amount = ? AND user_id = ?", 20, 2).Delete(&shardOrder{}).Error; err != nil {
+		t.Errorf("delete: %v", err)
+	}
+	db.Table("orders_02").Count(&n)
+	if n != 1 {
+		t.Errorf("orders_02 rows = %d, want 1", n)
+	}
+}
+
+// TestShardingErrors tests the statements without the key or spanning multiple shards.
+func TestShardingErrors(t *testing.T) {
+	c := newShardingClient(t)
+	db := c.GetDB(context.Background())
+
+	var orders []shardOrder
+	if err := db.Where("amount > ?", 0).Find(&orders).Error; !errors.Is(err, ErrMissingShardKey) {
+		t.Errorf("find without key error = %v", err)
+	}
+	err := db.Session(&gorm.Session{SkipDefaultTransaction: true}).
+		Create([]shardOrder{{ID: 1, UserID: 1}, {ID: 2, UserID: 2}}).Error
+	if err == nil {
+		t.Error("Expected error for rows spanning multiple shards")
+	}
+	// the OR branches must all be restricted by the key to the same shard
+	orders = []shardOrder{{UserID: 1}} // not used to route the queries with OR
+	for name, q := range map[string]*gorm.DB{
+		"or":          db.Where("user_id = ?", 1).Or("user_id = ?", 2),
+		"sql or":      db.Where("user_id = ? OR user_id = ?", 1, 2),
+		"in":          db.Where("user_id IN ?", []int64{1, 2}),
+		"or no key":   db.Where("user_id = ?", 1).Or("amount > ?", 0),
+		"sql no key":  db.Where("user_id = ? OR amount > ?", 1, 0),
+		"nested or":   db.Where("(user_id = ? OR amount > ?) AND amount < ?", 1, 0, 10),
+		"clause.IN":   db.Where(map[string]any{"user_id": []int64{1, 2}}),
+		"or in group": db.Where("amount > ?", 0).Where(db.Where("user_id = ?", 1).Or("amount < ?", 0)),
+	} {
+		err := q.Find(&orders).Error
+		if !errors.Is(err, ErrMissingShardKey) && (err == nil || !strings.Contains(err.Error(), "span multiple shards")) {
+			t.Errorf("%s: Expected error, got %v", name, err)
+		}
+	}
+	if _, err := NewShardingPlugin(ShardingConfig{Tables: []string{"orders"}}); err == nil {
+		t.Error("Expected error for missing shard key")
+	}
+}
+
+// TestShardTable tests the shard of the integer and the string keys.
+func TestShardTable(t *testing.T) {
+	p, err := NewShardingPlugin(ShardingConfig{Tables: []string{"orders"}, ShardKey: "user_id", Shards: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for value, want := range map[any]string{int64(65): "orders_01", -3: "orders_03", uint(63): "orders_63"} {
+		if got, err := p.ShardTable("orders", value); err != nil || got != want {
+			t.Errorf("ShardTable(%v) = %s, %v, want %s", value, got, err, want)
+		}
+	}
+	a, _ := p.ShardTable("orders", "u-1")
+	b, _ := p.ShardTable("orders", "u-1")
+	if a != b || a == "" {
+		t.Errorf("string shard not stable: %s, %s", a, b)
+	}
+	if got, _ := p.ShardTable("users", 1); got != "users" {
+		t.Errorf("unsharded table = %s", got)
+	}
+}
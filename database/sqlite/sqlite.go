@@ -0,0 +1,31 @@
+/*
+sqlite 注册 database 包的 SQLite 驱动，驱动名称为 sqlite 和 sqlite3，依赖 cgo：
+
+	import _ "github.com/baisiyi/go-kits/database/sqlite"
+*/
+
+package sqlite
+
+import (
+	"database/sql"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/database"
+)
+
+func init() {
+	d := database.Driver{
+		SQLDriver: sqlite.DriverName,
+		DSN: func(_ *database.DBConfig, c *database.Connect) string {
+			return c.ToSQLiteDSN()
+		},
+		Open: sqlite.Open,
+		New: func(pool *sql.DB) gorm.Dialector {
+			return sqlite.New(sqlite.Config{Conn: pool})
+		},
+	}
+	database.RegisterDriver(database.DriverSQLite, d)
+	database.RegisterDriver("sqlite3", d)
+}
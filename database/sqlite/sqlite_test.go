@@ -0,0 +1,24 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/baisiyi/go-kits/database"
+	"github.com/baisiyi/go-kits/log"
+)
+
+// TestRegistered tests that a client is created with the registered driver.
+func TestRegistered(t *testing.T) {
+	for _, driver := range []string{database.DriverSQLite, "sqlite3"} {
+		m := database.NewManager()
+		c, err := m.Register("local", &database.DBConfig{Driver: driver, MaxOpenConns: 1}, log.GetDefaultLogger())
+		if err != nil {
+			t.Fatalf("Register(%q) failed: %v", driver, err)
+		}
+		if err := c.Health(context.Background()); err != nil {
+			t.Errorf("Health(%q) failed: %v", driver, err)
+		}
+		m.CloseAll()
+	}
+}
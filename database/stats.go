@@ -0,0 +1,206 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/baisiyi/go-kits/log"
+	"github.com/baisiyi/go-kits/metrics"
+)
+
+// PoolPrimary 主库连接池名称，副本为 replica-0、replica-1 ...
+const PoolPrimary = "primary"
+
+// PoolStats 连接池统计，在 sql.DBStats 的累计值基础上增加统计周期内的派生指标。
+// Client.Stats 的统计周期为连接创建至今，ReportStats 为两次上报的间隔
+type PoolStats struct {
+	sql.DBStats
+	// InUsePercent 使用中的连接占 MaxOpenConnections 的百分比，未限制最大连接数时为 0
+	InUsePercent float64
+	// Waits 统计周期内等待空闲连接的次数
+	Waits int64
+	// WaitTime 统计周期内等待空闲连接的总时长
+	WaitTime time.Duration
+	// WaitRatio 等待总时长与周期时长之比，即平均同时等待连接的协程数，持续大于 0 说明连接池已饱和
+	WaitRatio float64
+	// Breaker 熔断器统计，仅主库有效，未配置熔断时为零值
+	Breaker BreakerStats
+}
+
+// AvgWait 统计周期内每次等待连接的平均时长
+func (s PoolStats) AvgWait() time.Duration {
+	if s.Waits == 0 {
+		return 0
+	}
+	return s.WaitTime / time.Duration(s.Waits)
+}
+
+// newPoolStats 计算 prev 到 cur 之间 elapsed 时长内的派生指标
+func newPoolStats(cur, prev sql.DBStats, elapsed time.Duration) PoolStats {
+	s := PoolStats{
+		DBStats:  cur,
+		Waits:    cur.WaitCount - prev.WaitCount,
+		WaitTime: cur.WaitDuration - prev.WaitDuration,
+	}
+	if cur.MaxOpenConnections > 0 {
+		s.InUsePercent = float64(cur.InUse) * 100 / float64(cur.MaxOpenConnections)
+	}
+	if elapsed > 0 {
+		s.WaitRatio = float64(s.WaitTime) / float64(elapsed)
+	}
+	return s
+}
+
+// PoolStatsConfig 连接池统计上报配置，Interval 大于 0 时启用
+type PoolStatsConfig struct {
+	// Interval 上报间隔
+	Interval time.Duration `mapstructure:"interval" yaml:"interval"`
+	// Log 每个周期打印 [DB_POOL] 日志，使用率达到 WarnPercent 或存在等待时为 Warn 级别
+	Log bool `mapstructure:"log" yaml:"log"`
+	// Metrics 写入 metrics.DefaultRegistry
+	Metrics bool `mapstructure:"metrics" yaml:"metrics"`
+	// WarnPercent 连接使用率告警阈值（百分比），默认 80
+	WarnPercent float64 `mapstructure:"warn_percent" yaml:"warn_percent"`
+}
+
+func (c *PoolStatsConfig) setDefaults() {
+	if c.WarnPercent <= 0 {
+		c.WarnPercent = 80
+	}
+}
+
+// StatsReporter 接收 ReportStats 每个周期各连接池的统计
+type StatsReporter func(pool string, s PoolStats)
+
+// Stats 返回主库连接池的统计
+func (c *Client) Stats() PoolStats {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return PoolStats{}
+	}
+	s := newPoolStats(sqlDB.Stats(), sql.DBStats{}, time.Since(c.opened))
+	s.Breaker = c.breaker().Stats()
+	return s
+}
+
+// ReplicaStats 返回各只读副本连接池的统计，顺序与配置一致
+func (c *Client) ReplicaStats() []PoolStats {
+	stats := make([]PoolStats, len(c.replicas))
+	for i, r := range c.replicas {
+		stats[i] = newPoolStats(r.Stats(), sql.DBStats{}, time.Since(c.opened))
+	}
+	return stats
+}
+
+// ReportStats 启动后台协程，每隔 interval 将主库和各副本连接池的统计交给 reporters，
+// 返回的函数用于停止上报。interval 不大于 0 时不上报，返回的函数为空操作
+func (c *Client) ReportStats(interval time.Duration, reporters ...StatsReporter) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	pools := map[string]*sql.DB{}
+	if sqlDB, err := c.db.DB(); err == nil {
+		pools[PoolPrimary] = sqlDB
+	}
+	for i, r := range c.replicas {
+		pools[fmt.Sprintf("replica-%d", i)] = r
+	}
+
+	prev := make(map[string]sql.DBStats, len(pools))
+	for name, p := range pools {
+		prev[name] = p.Stats()
+	}
+	last := time.Now()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				for name, p := range pools {
+					cur := p.Stats()
+					s := newPoolStats(cur, prev[name], now.Sub(last))
+					prev[name] = cur
+					if name == PoolPrimary {
+						s.Breaker = c.breaker().Stats()
+					}
+					for _, report := range reporters {
+						report(name, s)
+					}
+				}
+				last = now
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// LogStatsReporter 打印 [DB_POOL] 日志，使用率达到 warnPercent 或周期内存在等待时为 Warn 级别，否则为 Info 级别
+func LogStatsReporter(logger log.Logger, warnPercent float64) StatsReporter {
+	if logger == nil {
+		logger = log.GetDefaultLogger()
+	}
+	return func(pool string, s PoolStats) {
+		logf := logger.Infof
+		if s.Waits > 0 || (s.MaxOpenConnections > 0 && s.InUsePercent >= warnPercent) {
+			logf = logger.Warnf
+		}
+		logf("[DB_POOL] Pool: %s | Open: %d/%d | InUse: %d (%.1f%%) | Idle: %d | Waits: %d | AvgWait: %v | WaitRatio: %.3f",
+			pool, s.OpenConnections, s.MaxOpenConnections, s.InUse, s.InUsePercent, s.Idle,
+			s.Waits, s.AvgWait(), s.WaitRatio)
+	}
+}
+
+// MetricsStatsReporter 将统计写入 reg 的 db_pool_* 指标，标签 database 为实例名称，pool 为连接池名称
+func MetricsStatsReporter(reg *metrics.Registry, database string) StatsReporter {
+	var (
+		open     = reg.Gauge("db_pool_open_connections", "Number of established connections.", "database", "pool")
+		inUse    = reg.Gauge("db_pool_in_use_connections", "Number of connections in use.", "database", "pool")
+		idle     = reg.Gauge("db_pool_idle_connections", "Number of idle connections.", "database", "pool")
+		maxOpen  = reg.Gauge("db_pool_max_open_connections", "Maximum number of open connections, 0 means unlimited.", "database", "pool")
+		percent  = reg.Gauge("db_pool_in_use_percent", "Percentage of the maximum open connections in use.", "database", "pool")
+		ratio    = reg.Gauge("db_pool_wait_ratio", "Average number of goroutines waiting for a connection in the last interval.", "database", "pool")
+		waits    = reg.Counter("db_pool_waits_total", "Total number of waits for a connection.", "database", "pool")
+		waitTime = reg.Counter("db_pool_wait_seconds_total", "Total time waited for a connection.", "database", "pool")
+	)
+	return func(pool string, s PoolStats) {
+		open.With(database, pool).Set(float64(s.OpenConnections))
+		inUse.With(database, pool).Set(float64(s.InUse))
+		idle.With(database, pool).Set(float64(s.Idle))
+		maxOpen.With(database, pool).Set(float64(s.MaxOpenConnections))
+		percent.With(database, pool).Set(s.InUsePercent)
+		ratio.With(database, pool).Set(s.WaitRatio)
+		waits.With(database, pool).Add(float64(s.Waits))
+		waitTime.With(database, pool).Add(s.WaitTime.Seconds())
+	}
+}
+
+// startStatsReporter 按配置启动连接池统计上报，由 Close 停止
+func (c *Client) startStatsReporter(name string, cfg PoolStatsConfig) {
+	if cfg.Interval <= 0 || (!cfg.Log && !cfg.Metrics) {
+		return
+	}
+	cfg.setDefaults()
+	var reporters []StatsReporter
+	if cfg.Log {
+		reporters = append(reporters, LogStatsReporter(c.logger, cfg.WarnPercent))
+	}
+	if cfg.Metrics {
+		reporters = append(reporters, MetricsStatsReporter(metrics.DefaultRegistry, name))
+	}
+	c.stopStats = c.ReportStats(cfg.Interval, reporters...)
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/baisiyi/go-kits/metrics"
+)
+
+// TestNewPoolStats tests the derived numbers of the pool stats.
+func TestNewPoolStats(t *testing.T) {
+	prev := sql.DBStats{WaitCount: 2, WaitDuration: time.Second}
+	cur := sql.DBStats{MaxOpenConnections: 10, InUse: 8, WaitCount: 6, WaitDuration: 3 * time.Second}
+	s := newPoolStats(cur, prev, 4*time.Second)
+	if s.InUsePercent != 80 || s.Waits != 4 || s.WaitTime != 2*time.Second {
+		t.Errorf("stats = %+v", s)
+	}
+	if s.WaitRatio != 0.5 || s.AvgWait() != 500*time.Millisecond {
+		t.Errorf("WaitRatio = %v, AvgWait = %v", s.WaitRatio, s.AvgWait())
+	}
+	if s := newPoolStats(sql.DBStats{InUse: 3}, sql.DBStats{}, 0); s.InUsePercent != 0 || s.WaitRatio != 0 || s.AvgWait() != 0 {
+		t.Errorf("unlimited pool stats = %+v", s)
+	}
+}
+
+// TestClient_ReportStats tests that the waits for a saturated pool are reported to the metrics.
+func TestClient_ReportStats(t *testing.T) {
+	c, err := newClient(&DBConfig{Driver: DriverSQLite, MaxOpenConns: 1}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+
+	// a non-positive interval reports nothing
+	c.ReportStats(0, func(string, PoolStats) { t.Error("Expected no report for interval 0") })()
+
+	reg := metrics.NewRegistry()
+	reports := make(chan PoolStats, 10)
+	stop := c.ReportStats(20*time.Millisecond, MetricsStatsReporter(reg, "local"), func(pool string, s PoolStats) {
+		if pool == PoolPrimary {
+			reports <- s
+		}
+	})
+	defer stop()
+
+	ctx := context.Background()
+	sqlDB, _ := c.db.DB()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = conn.Close()
+	}()
+	if err := sqlDB.PingContext(ctx); err != nil { // waits for the conn
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	if s := c.Stats(); s.Waits != 1 || s.MaxOpenConnections != 1 {
+		t.Errorf("Stats = %+v", s)
+	}
+	var waits int64
+	deadline := time.After(2 * time.Second)
+	for waits == 0 {
+		select {
+		case s := <-reports:
+			waits += s.Waits
+		case <-deadline:
+			t.Fatal("Timeout waiting for the reported wait")
+		}
+	}
+	stop()
+
+	for _, f := range reg.Gather() {
+		if f.Name == "db_pool_waits_total" {
+			if len(f.Series) != 1 || f.Series[0].Value != 1 {
+				t.Errorf("db_pool_waits_total = %+v", f.Series)
+			}
+			return
+		}
+	}
+	t.Error("db_pool_waits_total not found")
+}
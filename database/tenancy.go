@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/baisiyi/go-kits/contextkit"
+)
+
+// 多租户隔离方式
+const (
+	// TenancySchema 每个租户使用独立的库（PostgreSQL 为 dbname，SQLite 为文件），按租户建立连接池
+	TenancySchema = "schema"
+	// TenancyPrefix 租户共用一个库，按表名前缀隔离
+	TenancyPrefix = "prefix"
+)
+
+const tenantPrefixKey = "go-kits:tenant_prefix"
+
+// tenantDBKey 记录按库区分租户的实例所属的租户，查询缓存据此区分各租户的同名表
+const tenantDBKey = "go-kits:tenant_db"
+
+var (
+	// ErrNoTenant ctx 中没有租户 ID
+	ErrNoTenant = errors.New("database: tenant not found in context")
+	// ErrTenancyDisabled 未配置多租户时调用 GetTenantDB
+	ErrTenancyDisabled = errors.New("database: tenancy not configured")
+
+	tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+)
+
+// TenancyConfig 多租户配置，Strategy 为空时不启用
+type TenancyConfig struct {
+	// Strategy 隔离方式：schema、prefix
+	Strategy string `mapstructure:"strategy" yaml:"strategy"`
+	// Pattern 租户的库名（schema）或表名前缀（prefix），{tenant} 替换为租户 ID，
+	// 默认分别为 {主库库名}_{tenant} 和 {tenant}_
+	Pattern string `mapstructure:"pattern" yaml:"pattern"`
+	// MaxOpenConns 等为 schema 方式下每个租户连接池的参数，为 0 时使用主库的配置
+	MaxOpenConns    int           `mapstructure:"max_open_conns" yaml:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time" yaml:"conn_max_idle_time"`
+	// MaxTenants schema 方式下最多建立连接池的租户数，超出时返回错误，0 表示不限制
+	MaxTenants int `mapstructure:"max_tenants" yaml:"max_tenants"`
+	// TenantFunc 从 ctx 取出租户 ID，为空时使用 contextkit.Tenant
+	TenantFunc func(ctx context.Context) string `mapstructure:"-" yaml:"-"`
+}
+
+// tenancy 按租户隔离的连接，schema 方式下连接池在首次使用时建立，Close 时关闭
+type tenancy struct {
+	cfg  TenancyConfig
+	base *DBConfig
+
+	mu    sync.Mutex
+	pools map[string]*tenantPool
+}
+
+type tenantPool struct {
+	once sync.Once
+	db   *sql.DB
+	err  error
+}
+
+// newTenancy 按配置创建多租户，prefix 方式注册改写表名的回调
+func newTenancy(db *gorm.DB, cfg *DBConfig) (*tenancy, error) {
+	t := &tenancy{cfg: cfg.Tenancy, base: cfg, pools: make(map[string]*tenantPool)}
+	if t.cfg.TenantFunc == nil {
+		t.cfg.TenantFunc = contextkit.Tenant
+	}
+	switch t.cfg.Strategy {
+	case TenancySchema:
+		if len(cfg.Replicas) > 0 {
+			// dbresolver 会将读请求切换到主库的副本
+			return nil, errors.New("database: schema tenancy does not support replicas")
+		}
+		if t.cfg.Pattern == "" {
+			t.cfg.Pattern = cfg.DSN.Name + "_{tenant}"
+		}
+	case TenancyPrefix:
+		if t.cfg.Pattern == "" {
+			t.cfg.Pattern = "{tenant}_"
+		}
+		if err := db.Use(&tenantPrefixPlugin{}); err != nil {
+			return nil, fmt.Errorf("failed to register tenancy: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("database: tenancy strategy %s not supported", t.cfg.Strategy)
+	}
+	if !strings.Contains(t.cfg.Pattern, "{tenant}") {
+		return nil, fmt.Errorf("database: tenancy pattern %s requires {tenant}", t.cfg.Pattern)
+	}
+	return t, nil
+}
+
+// GetTenantDB 返回 ctx 中租户的 GORM 实例：schema 方式使用该租户库的连接池，prefix 方式为表名加上租户前缀。
+// ctx 中没有租户、租户 ID 不合法或连接失败时，错误在执行 SQL 时返回。
+// schema 方式下不加入 Transact 开启的事务，需使用 GetTenantDB(ctx).Transaction；Raw/Exec 的 SQL 不会改写表名
+func (c *Client) GetTenantDB(ctx context.Context) *gorm.DB {
+	if c.tenancy == nil {
+		db := c.GetDB(ctx)
+		_ = db.AddError(ErrTenancyDisabled)
+		return db
+	}
+	return c.tenancy.db(ctx, c)
+}
+
+// TenantTable 返回 ctx 中租户的表名，prefix 方式加上租户前缀，schema 方式原样返回，
+// 用于 Raw/Exec 的 SQL 和 Table(...).AutoMigrate 等不经过回调的操作
+func (c *Client) TenantTable(ctx context.Context, table string) (string, error) {
+	if c.tenancy == nil {
+		return "", ErrTenancyDisabled
+	}
+	_, name, err := c.tenancy.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+	if c.tenancy.cfg.Strategy != TenancyPrefix {
+		return table, nil
+	}
+	return name + table, nil
+}
+
+// resolve 返回 ctx 中的租户 ID 及其库名或表名前缀
+func (t *tenancy) resolve(ctx context.Context) (tenant, name string, err error) {
+	tenant = t.cfg.TenantFunc(ctx)
+	if tenant == "" {
+		return "", "", ErrNoTenant
+	}
+	if !tenantPattern.MatchString(tenant) {
+		return "", "", fmt.Errorf("database: invalid tenant %q", tenant)
+	}
+	return tenant, strings.ReplaceAll(t.cfg.Pattern, "{tenant}", tenant), nil
+}
+
+func (t *tenancy) db(ctx context.Context, c *Client) *gorm.DB {
+	tenant, name, err := t.resolve(ctx)
+	if err != nil {
+		db := c.GetDB(ctx)
+		_ = db.AddError(err)
+		return db
+	}
+	if t.cfg.Strategy == TenancyPrefix {
+		return c.DB(ctx).Set(tenantPrefixKey, name)
+	}
+
+	db := c.db.Session(&gorm.Session{NewDB: true, Context: ctx}).Set(tenantDBKey, tenant)
+	pool, err := t.pool(tenant, name)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	db.Statement.ConnPool = pool
+	return db
+}
+
+// pool 返回租户的连接池，首次使用时建立，失败后下次使用时重试
+func (t *tenancy) pool(tenant, name string) (*sql.DB, error) {
+	t.mu.Lock()
+	p, ok := t.pools[tenant]
+	if !ok {
+		if t.cfg.MaxTenants > 0 && len(t.pools) >= t.cfg.MaxTenants {
+			t.mu.Unlock()
+			return nil, fmt.Errorf("database: tenant pools exceed max_tenants %d", t.cfg.MaxTenants)
+		}
+		p = &tenantPool{}
+		t.pools[tenant] = p
+	}
+	t.mu.Unlock()
+
+	p.once.Do(func() {
+		dsn := t.base.DSN
+		dsn.Name = name
+		p.db, p.err = openPool(t.base, &ReplicaConfig{
+			DSN:             dsn,
+			MaxOpenConns:    t.cfg.MaxOpenConns,
+			MaxIdleConns:    t.cfg.MaxIdleConns,
+			ConnMaxLifetime: t.cfg.ConnMaxLifetime,
+			ConnMaxIdleTime: t.cfg.ConnMaxIdleTime,
+		})
+	})
+	if p.err != nil {
+		t.mu.Lock()
+		if t.pools[tenant] == p {
+			delete(t.pools, tenant)
+		}
+		t.mu.Unlock()
+		return nil, fmt.Errorf("database: open tenant %s error: %w", tenant, p.err)
+	}
+	return p.db, nil
+}
+
+func (t *tenancy) close() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var errs []error
+	for tenant, p := range t.pools {
+		if p.db != nil {
+			if err := p.db.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("tenant %s: %w", tenant, err))
+			}
+		}
+	}
+	t.pools = make(map[string]*tenantPool)
+	return errors.Join(errs...)
+}
+
+// tenantPrefixPlugin 为 GetTenantDB 返回的实例的表名加上租户前缀，在分表路由之后执行
+type tenantPrefixPlugin struct{}
+
+// Name 实现 gorm.Plugin 接口
+func (p *tenantPrefixPlugin) Name() string {
+	return "go-kits:tenancy"
+}
+
+// Initialize 实现 gorm.Plugin 接口，在各类操作前注册改写表名的回调
+func (p *tenantPrefixPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		op       string
+		register func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register},
+	}
+	for _, h := range hooks {
+		if err := h.register("tenancy:"+h.op, p.prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *tenantPrefixPlugin) prefix(db *gorm.DB) {
+	stmt := db.Statement
+	v, ok := stmt.Settings.Load(tenantPrefixKey)
+	if !ok || db.Error != nil || stmt.Table == "" {
+		return
+	}
+	prefix, _ := v.(string)
+	if strings.HasPrefix(stmt.Table, prefix) {
+		// 复用的 Statement 已改写过，或通过 Table(TenantTable(...)) 指定了租户表
+		return
+	}
+	stmt.Table = prefix + stmt.Table
+	if stmt.TableExpr != nil {
+		stmt.TableExpr = &clause.Expr{SQL: stmt.Quote(stmt.Table)}
+	}
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/contextkit"
+)
+
+// TestTenancySchema tests that the tenants use their own databases with separate pools.
+func TestTenancySchema(t *testing.T) {
+	dir := t.TempDir()
+	c, err := newClient(&DBConfig{
+		Driver:  DriverSQLite,
+		DSN:     Connect{Name: filepath.Join(dir, "app.db")},
+		Tenancy: TenancyConfig{Strategy: TenancySchema, MaxOpenConns: 2, MaxTenants: 2},
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+
+	t1 := contextkit.WithTenant(context.Background(), "t1")
+	t2 := contextkit.WithTenant(context.Background(), "t2")
+	for _, ctx := range []context.Context{t1, t2} {
+		if err := c.GetTenantDB(ctx).AutoMigrate(&resolverItem{}); err != nil {
+			t.Fatalf("AutoMigrate failed: %v", err)
+		}
+	}
+	if err := c.GetTenantDB(t1).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&resolverItem{Name: "a"}).Error
+	}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var n1, n2 int64
+	c.GetTenantDB(t1).Model(&resolverItem{}).Count(&n1)
+	c.GetTenantDB(t2).Model(&resolverItem{}).Count(&n2)
+	if n1 != 1 || n2 != 0 {
+		t.Errorf("counts = %d, %d, want 1 and 0", n1, n2)
+	}
+	if c.GetDB(context.Background()).Migrator().HasTable(&resolverItem{}) {
+		t.Error("Expected no table in the primary database")
+	}
+	if s := c.tenancy.pools["t1"].db.Stats(); s.MaxOpenConnections != 2 {
+		t.Errorf("MaxOpenConnections = %d, want 2", s.MaxOpenConnections)
+	}
+
+	t3 := contextkit.WithTenant(context.Background(), "t3")
+	if err := c.GetTenantDB(t3).Exec("SELECT 1").Error; err == nil {
+		t.Error("Expected error beyond max_tenants")
+	}
+	if err := c.GetTenantDB(context.Background()).Exec("SELECT 1").Error; !errors.Is(err, ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant, got %v", err)
+	}
+	bad := contextkit.WithTenant(context.Background(), "t1; DROP")
+	if err := c.GetTenantDB(bad).Exec("SELECT 1").Error; err == nil {
+		t.Error("Expected error for the invalid tenant")
+	}
+}
+
+// TestTenancyPrefix tests that the tables of the tenants are prefixed after sharding.
+func TestTenancyPrefix(t *testing.T) {
+	c, err := newClient(&DBConfig{
+		Driver:   DriverSQLite,
+		DSN:      Connect{Name: filepath.Join(t.TempDir(), "app.db")},
+		Sharding: []ShardingConfig{{Tables: []string{"resolver_item"}, ShardKey: "id", Shards: 2}},
+		Tenancy:  TenancyConfig{Strategy: TenancyPrefix, Pattern: "tenant_{tenant}_"},
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+
+	ctx := contextkit.WithTenant(context.Background(), "t1")
+	table, err := c.TenantTable(ctx, "resolver_item_01")
+	if err != nil || table != "tenant_t1_resolver_item_01" {
+		t.Fatalf("TenantTable = %s, %v", table, err)
+	}
+	if err := c.GetTenantDB(ctx).Table(table).AutoMigrate(&resolverItem{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	if err := c.GetTenantDB(ctx).Create(&resolverItem{ID: 3, Name: "a"}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	var item resolverItem
+	if err := c.GetTenantDB(ctx).Where("id = ?", 3).Take(&item).Error; err != nil || item.Name != "a" {
+		t.Errorf("Take = %+v, %v", item, err)
+	}
+	if err := c.GetDB(context.Background()).Where("id = ?", 3).Take(&item).Error; err == nil {
+		t.Error("Expected the shard without prefix not to exist")
+	}
+}
+
+// TestTenancyConfig tests the invalid tenancy configs.
+func TestTenancyConfig(t *testing.T) {
+	for _, cfg := range []TenancyConfig{
+		{Strategy: "table"},
+		{Strategy: TenancyPrefix, Pattern: "tenant_"},
+	} {
+		if _, err := newClient(&DBConfig{Driver: DriverSQLite, Tenancy: cfg}, &mockLogger{}); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+	c, err := newClient(&DBConfig{Driver: DriverSQLite}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	if err := c.GetTenantDB(context.Background()).Exec("SELECT 1").Error; !errors.Is(err, ErrTenancyDisabled) {
+		t.Errorf("Expected ErrTenancyDisabled, got %v", err)
+	}
+}
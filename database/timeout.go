@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const timeoutInstanceKey = "go-kits:query_timeout_cancel"
+
+// TimeoutPlugin GORM 语句超时插件，每条 SQL 执行前将 Statement.Context 包装为带超时的 Context，
+// 超时后驱动取消执行（MySQL 发送 KILL QUERY 需配合 max_execution_time，见 DBConfig.MySQLMaxExecutionTime）。
+// ctx 已有更早的截止时间时以 ctx 为准。Rows/Row/Raw().Scan 在回调结束后才读取结果，不设置超时
+type TimeoutPlugin struct {
+	timeout time.Duration
+}
+
+// NewTimeoutPlugin 创建语句超时插件，通过 db.Use 注册
+func NewTimeoutPlugin(timeout time.Duration) *TimeoutPlugin {
+	return &TimeoutPlugin{timeout: timeout}
+}
+
+// Name 实现 gorm.Plugin 接口
+func (p *TimeoutPlugin) Name() string {
+	return "go-kits:timeout"
+}
+
+// Initialize 实现 gorm.Plugin 接口，在各类操作前后注册回调
+func (p *TimeoutPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		op     string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("timeout:before_"+h.op, p.before); err != nil {
+			return err
+		}
+		if err := h.after("timeout:after_"+h.op, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *TimeoutPlugin) before(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	db.Statement.Context = ctx
+	db.InstanceSet(timeoutInstanceKey, cancel)
+}
+
+func (p *TimeoutPlugin) after(db *gorm.DB) {
+	if v, ok := db.InstanceGet(timeoutInstanceKey); ok {
+		if cancel, ok := v.(context.CancelFunc); ok {
+			cancel()
+		}
+	}
+}
+
+// mysqlDSN 返回 MySQL DSN，开启 MySQLMaxExecutionTime 时附加会话变量 max_execution_time（毫秒）
+func (c *DBConfig) mysqlDSN(conn *Connect) string {
+	// 连接时使用 mysql 的默认端口和时区，ToDSN 的输出保持不变
+	withDefaults := *conn
+	if withDefaults.Port == 0 {
+		withDefaults.Port = 3306
+	}
+	if withDefaults.Location == "" {
+		withDefaults.Location = "Local"
+	}
+	dsn := withDefaults.ToDSN()
+	if c.MySQLMaxExecutionTime && c.QueryTimeout > 0 {
+		dsn += fmt.Sprintf("&max_execution_time=%d", c.QueryTimeout.Milliseconds())
+	}
+	return dsn
+}
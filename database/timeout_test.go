@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowQuery counts to 1e9 by a recursive CTE, far slower than the timeouts of the tests.
+const slowQuery = `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000000000)
+SELECT count(*) FROM n`
+
+// TestTimeoutPlugin tests that the slow statements are cancelled at QueryTimeout.
+func TestTimeoutPlugin(t *testing.T) {
+	c, err := newClient(&DBConfig{Driver: DriverSQLite, QueryTimeout: 50 * time.Millisecond}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+
+	var n int64
+	begin := time.Now()
+	err = c.GetDB(context.Background()).Raw(slowQuery).Find(&n).Error
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the query to be cancelled, got %v", err)
+	}
+	if d := time.Since(begin); d > 5*time.Second {
+		t.Errorf("Expected the query to be cancelled at the timeout, took %s", d)
+	}
+
+	// 超时只作用于单条语句，后续语句不受影响
+	if err := c.GetDB(context.Background()).Raw("SELECT 1 + 1").Find(&n).Error; err != nil || n != 2 {
+		t.Errorf("query = %d, %v", n, err)
+	}
+}
+
+// TestTimeoutPlugin_EarlierDeadline tests that the earlier deadline of ctx is kept.
+func TestTimeoutPlugin_EarlierDeadline(t *testing.T) {
+	c, err := newClient(&DBConfig{Driver: DriverSQLite, QueryTimeout: time.Hour}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var n int64
+	begin := time.Now()
+	if err := c.GetDB(ctx).Raw(slowQuery).Find(&n).Error; err == nil {
+		t.Fatal("Expected the query to be cancelled")
+	}
+	if d := time.Since(begin); d > 5*time.Second {
+		t.Errorf("Expected the query to be cancelled at the ctx deadline, took %s", d)
+	}
+}
+
+// TestDBConfig_MySQLDSN tests the max_execution_time session variable in the DSN.
+func TestDBConfig_MySQLDSN(t *testing.T) {
+	conn := Connect{Username: "root", Host: "localhost", Name: "app"}
+	cfg := &DBConfig{QueryTimeout: 3 * time.Second}
+	dsn := cfg.mysqlDSN(&conn)
+	if strings.Contains(dsn, "max_execution_time") {
+		t.Errorf("Unexpected max_execution_time without MySQLMaxExecutionTime: %s", dsn)
+	}
+	// the defaults of the connection are not applied to ToDSN
+	if want := "root:@tcp(localhost:3306)/app?charset=utf8mb4&parseTime=True&loc=Local"; dsn != want {
+		t.Errorf("mysqlDSN() = %s, want %s", dsn, want)
+	}
+	if dsn := conn.ToDSN(); dsn != "root:@tcp(localhost:0)/app?charset=utf8mb4" {
+		t.Errorf("Unexpected ToDSN() %s", dsn)
+	}
+	cfg.MySQLMaxExecutionTime = true
+	if dsn := cfg.mysqlDSN(&conn); !strings.HasSuffix(dsn, "&max_execution_time=3000") {
+		t.Errorf("Expected max_execution_time=3000, got %s", dsn)
+	}
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/baisiyi/go-kits/contextkit"
+	"github.com/baisiyi/go-kits/log"
+)
+
+const (
+	tracerName      = "github.com/baisiyi/go-kits/database"
+	spanInstanceKey = "go-kits:tracing_span"
+)
+
+// TracingPlugin GORM 链路追踪插件，每条 SQL 创建一个 OpenTelemetry Span，
+// 记录 SQL 语句、影响行数和错误；Span 写入 Statement.Context，日志中会附带 trace_id/span_id
+type TracingPlugin struct {
+	// TracerProvider 为空时使用 otel.GetTracerProvider()
+	TracerProvider trace.TracerProvider
+	// DBSystem 数据库类型，写入 db.system 属性，为空时使用 Dialector 名称
+	DBSystem string
+
+	tracer trace.Tracer
+}
+
+// NewTracingPlugin 创建链路追踪插件，通过 db.Use 注册
+func NewTracingPlugin(tp trace.TracerProvider) *TracingPlugin {
+	return &TracingPlugin{TracerProvider: tp}
+}
+
+// Name 实现 gorm.Plugin 接口
+func (p *TracingPlugin) Name() string {
+	return "go-kits:tracing"
+}
+
+// Initialize 实现 gorm.Plugin 接口，在各类操作前后注册回调
+func (p *TracingPlugin) Initialize(db *gorm.DB) error {
+	tp := p.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	p.tracer = tp.Tracer(tracerName)
+	if p.DBSystem == "" {
+		p.DBSystem = db.Dialector.Name()
+	}
+
+	cb := db.Callback()
+	hooks := []struct {
+		op       string
+		before   func(string, func(*gorm.DB)) error
+		after    func(string, func(*gorm.DB)) error
+		callback string
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register, "gorm:create"},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register, "gorm:query"},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register, "gorm:update"},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register, "gorm:delete"},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register, "gorm:row"},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register, "gorm:raw"},
+	}
+	for _, h := range hooks {
+		if err := h.before("tracing:before_"+h.op, p.before(h.op)); err != nil {
+			return err
+		}
+		if err := h.after("tracing:after_"+h.op, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *TracingPlugin) before(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx, span := p.tracer.Start(ctx, "gorm."+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", p.DBSystem),
+				attribute.String("db.operation", op),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(spanInstanceKey, span)
+	}
+}
+
+func (p *TracingPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanInstanceKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	attrs := []attribute.KeyValue{
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	}
+	if db.Statement.Table != "" {
+		attrs = append(attrs, attribute.String("db.sql.table", db.Statement.Table))
+	}
+	span.SetAttributes(attrs...)
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// spanFields 返回 ctx 中 Span 的 trace_id/span_id 字段，ctx 已通过 contextkit 设置 trace_id 时只返回 span_id
+func spanFields(ctx context.Context) []log.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	fields := make([]log.Field, 0, 2)
+	if contextkit.TraceID(ctx) == "" {
+		fields = append(fields, log.String(contextkit.FieldTraceID, sc.TraceID().String()))
+	}
+	return append(fields, log.String("span_id", sc.SpanID().String()))
+}
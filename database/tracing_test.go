@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// fieldLogger records the fields added by With.
+type fieldLogger struct {
+	mockLogger
+	fields map[string]string
+}
+
+func (l *fieldLogger) With(fields ...log.Field) log.Logger {
+	for _, f := range fields {
+		l.fields[f.Key] = f.String
+	}
+	return l
+}
+
+// TestTracingPlugin tests the spans of the queries and the trace fields of the SQL logs.
+func TestTracingPlugin(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer tp.Shutdown(context.Background())
+
+	logger := &fieldLogger{fields: make(map[string]string)}
+	c, err := newClient(&DBConfig{Driver: DriverSQLite, DSN: Connect{Name: filepath.Join(t.TempDir(), "trace.db")}, LogLevel: 4}, logger)
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	defer c.Close()
+	if err := c.db.Use(NewTracingPlugin(tp)); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	db := c.GetDB(ctx)
+	if err := db.AutoMigrate(&resolverItem{}); err != nil {
+		t.Fatal(err)
+	}
+	recorder.Reset()
+	if err := db.Create(&resolverItem{Name: "a"}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	var item resolverItem
+	db.Where("name = ?", "missing").First(&item)
+	db.Exec("SELECT * FROM not_exists")
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("Expected 3 query spans and the parent, got %d", len(spans))
+	}
+	create := spans[0]
+	if create.Name() != "gorm.create" || create.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Unexpected create span %s with parent %s", create.Name(), create.Parent().SpanID())
+	}
+	attrs := make(map[string]string)
+	for _, kv := range create.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["db.system"] != "sqlite" || attrs["db.rows_affected"] != "1" || attrs["db.sql.table"] != "resolver_item" {
+		t.Errorf("Unexpected attributes %v", attrs)
+	}
+	if attrs["db.statement"] == "" {
+		t.Error("Expected db.statement attribute")
+	}
+	if spans[1].Name() != "gorm.query" || spans[1].Status().Code == codes.Error {
+		t.Errorf("Expected record not found not to be an error, got %v", spans[1].Status())
+	}
+	if spans[2].Name() != "gorm.raw" || spans[2].Status().Code != codes.Error {
+		t.Errorf("Expected raw span with error status, got %s %v", spans[2].Name(), spans[2].Status())
+	}
+
+	if logger.fields["trace_id"] != parent.SpanContext().TraceID().String() || logger.fields["span_id"] == "" {
+		t.Errorf("Expected trace fields in SQL logs, got %v", logger.fields)
+	}
+}
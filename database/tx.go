@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// txKey 是 ctx 中事务的 key，按 Client 区分，避免不同实例误用同一个事务
+type txKey struct {
+	c *Client
+}
+
+// txTracer 记录事务耗时和结果，由 GormLoggerAdapter 实现
+type txTracer interface {
+	TraceTx(ctx context.Context, begin time.Time, savepoint bool, err error)
+}
+
+// Transact 在事务中执行 fn：fn 返回错误或 panic 时回滚（panic 会继续抛出），否则提交。
+// fn 收到的 tx 的 Context 中携带了该事务，在 fn 内以 tx.Statement.Context 再次调用 Transact 时
+// 使用 SAVEPOINT 嵌套，内层失败只回滚到保存点；Repo 通过 DB(ctx) 获取当前事务即可自动加入。
+// 事务耗时和结果通过 GormLoggerAdapter 记录
+func (c *Client) Transact(ctx context.Context, fn func(tx *gorm.DB) error) (err error) {
+	db, savepoint := c.txFromContext(ctx)
+	if !savepoint {
+		db = c.db
+	}
+	begin := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			c.traceTx(ctx, begin, savepoint, fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+		c.traceTx(ctx, begin, savepoint, err)
+	}()
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(tx.WithContext(context.WithValue(ctx, txKey{c}, tx)))
+	})
+}
+
+// DB 返回 ctx 中 Transact 开启的事务，不在事务中时与 GetDB 相同
+func (c *Client) DB(ctx context.Context) *gorm.DB {
+	if tx, ok := c.txFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return c.GetDB(ctx)
+}
+
+func (c *Client) txFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{c}).(*gorm.DB)
+	return tx, ok
+}
+
+func (c *Client) traceTx(ctx context.Context, begin time.Time, savepoint bool, err error) {
+	if t, ok := c.db.Logger.(txTracer); ok {
+		t.TraceTx(ctx, begin, savepoint, err)
+	}
+}
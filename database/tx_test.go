@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type txUser struct {
+	ID   int
+	Name string
+}
+
+func countPrefix(formats []string, prefix string) int {
+	n := 0
+	for _, f := range formats {
+		if strings.HasPrefix(f, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// TestClientTransact tests commit, rollback, panic and nested savepoints.
+func TestClientTransact(t *testing.T) {
+	mock := &mockLogger{}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: NewGormLogger(mock, 0, 4),
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&txUser{}); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClientFromDB(db)
+	ctx := context.Background()
+	count := func() int64 {
+		var n int64
+		db.Model(&txUser{}).Count(&n)
+		return n
+	}
+
+	// outer commits, the failed inner rolls back to the savepoint only.
+	err = c.Transact(ctx, func(tx *gorm.DB) error {
+		if err := c.DB(tx.Statement.Context).Create(&txUser{ID: 1, Name: "a"}).Error; err != nil {
+			return err
+		}
+		innerErr := c.Transact(tx.Statement.Context, func(tx *gorm.DB) error {
+			if err := tx.Create(&txUser{ID: 2, Name: "b"}).Error; err != nil {
+				return err
+			}
+			return errors.New("inner failed")
+		})
+		if innerErr == nil {
+			t.Error("Expected inner error")
+		}
+		return c.Transact(tx.Statement.Context, func(tx *gorm.DB) error {
+			return tx.Create(&txUser{ID: 3, Name: "c"}).Error
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transact failed: %v", err)
+	}
+	var ids []int
+	db.Model(&txUser{}).Order("id").Pluck("id", &ids)
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("Expected users 1 and 3, got %v", ids)
+	}
+	if countPrefix(mock.infos, "[DB_TX] Commit") != 2 || countPrefix(mock.errors, "[DB_TX] Rollback") != 1 {
+		t.Errorf("Unexpected tx logs, infos %v, errors %v", mock.infos, mock.errors)
+	}
+
+	// error rolls back.
+	wantErr := errors.New("failed")
+	err = c.Transact(ctx, func(tx *gorm.DB) error {
+		tx.Create(&txUser{ID: 4})
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) || count() != 2 {
+		t.Errorf("Expected rollback, err %v, count %d", err, count())
+	}
+
+	// panic rolls back and is rethrown.
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Expected panic boom, got %v", r)
+			}
+		}()
+		_ = c.Transact(ctx, func(tx *gorm.DB) error {
+			tx.Create(&txUser{ID: 5})
+			panic("boom")
+		})
+	}()
+	if count() != 2 {
+		t.Errorf("Expected rollback on panic, count %d", count())
+	}
+	if countPrefix(mock.errors, "[DB_TX] Rollback") != 3 {
+		t.Errorf("Expected 3 rollback logs, got %v", mock.errors)
+	}
+}
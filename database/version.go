@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrStaleObject 乐观锁更新时版本号不匹配（已被其他请求更新）或记录不存在
+var ErrStaleObject = errors.New("database: stale object")
+
+// versionField 返回模型的版本号字段：带 `gorm:"version"` 标签的字段，没有时为名为 Version 的字段，
+// 版本号必须是整数类型
+func versionField(s *schema.Schema) (*schema.Field, error) {
+	var field *schema.Field
+	for _, f := range s.Fields {
+		if _, ok := f.TagSettings["VERSION"]; ok {
+			field = f
+			break
+		}
+	}
+	if field == nil {
+		field = s.LookUpField("Version")
+	}
+	if field == nil || field.DBName == "" {
+		return nil, fmt.Errorf("database: model %s has no version field", s.Name)
+	}
+	switch field.FieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field, nil
+	default:
+		return nil, fmt.Errorf("database: version field %s of %s must be an integer", field.Name, s.Name)
+	}
+}
+
+// UpdateWithVersion 乐观锁更新：按 entity 的主键和当前版本号更新 fields 中的列（key 为列名或字段名），
+// 同时将版本号加 1，成功后回写到 entity；影响行数为 0 时返回 ErrStaleObject，调用方应重新读取后重试。
+// db 通常为 Client.DB(ctx)，在 Transact 中调用时自动加入事务
+func UpdateWithVersion(db *gorm.DB, entity any, fields map[string]any) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(entity); err != nil {
+		return fmt.Errorf("database: parse model error: %w", err)
+	}
+	vf, err := versionField(stmt.Schema)
+	if err != nil {
+		return err
+	}
+	rv := reflect.Indirect(reflect.ValueOf(entity))
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	where := make([]clause.Expression, 0, len(stmt.Schema.PrimaryFields)+1)
+	for _, pf := range stmt.Schema.PrimaryFields {
+		v, zero := pf.ValueOf(ctx, rv)
+		if zero {
+			return fmt.Errorf("database: primary key %s of %s is zero", pf.Name, stmt.Schema.Name)
+		}
+		where = append(where, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: pf.DBName}, Value: v})
+	}
+	version, _ := vf.ValueOf(ctx, rv)
+	next, err := nextVersion(version)
+	if err != nil {
+		return err
+	}
+
+	updates, err := versionUpdates(stmt.Schema, vf, fields, next)
+	if err != nil {
+		return err
+	}
+	where = append(where, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: vf.DBName}, Value: version})
+	res := db.Model(entity).Clauses(clause.Where{Exprs: where}).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrStaleObject
+	}
+	return vf.Set(ctx, rv, next)
+}
+
+// UpdateWithVersion 按主键 id 和版本号 version 乐观锁更新 fields 中的列，版本号加 1；
+// 版本号不匹配或记录不存在时返回 ErrStaleObject
+func (r *Repository[T]) UpdateWithVersion(ctx context.Context, id any, version int64, fields map[string]any) error {
+	vf, err := versionField(r.schema)
+	if err != nil {
+		return err
+	}
+	if err := r.checkColumns(fields); err != nil {
+		return err
+	}
+	updates, err := versionUpdates(r.schema, vf, fields, version+1)
+	if err != nil {
+		return err
+	}
+	if h := r.hooks.BeforeUpdate; h != nil {
+		if err := h(ctx, id, fields); err != nil {
+			return err
+		}
+	}
+	versionEq := clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: vf.DBName}, Value: version}
+	res := r.DB(ctx).Where(r.pkEq(id)).Where(versionEq).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrStaleObject
+	}
+	if h := r.hooks.AfterUpdate; h != nil {
+		return h(ctx, id, fields)
+	}
+	return nil
+}
+
+// versionUpdates 复制 fields 并加入新版本号，fields 不能包含版本号列
+func versionUpdates(s *schema.Schema, vf *schema.Field, fields map[string]any, next any) (map[string]any, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("database: no fields to update")
+	}
+	updates := make(map[string]any, len(fields)+1)
+	for name, value := range fields {
+		if f := s.LookUpField(name); f == vf {
+			return nil, fmt.Errorf("database: version field %s is updated automatically", vf.Name)
+		}
+		updates[name] = value
+	}
+	updates[vf.DBName] = next
+	return updates, nil
+}
+
+// nextVersion 返回与 version 同类型的 version+1
+func nextVersion(version any) (any, error) {
+	v := reflect.ValueOf(version)
+	next := reflect.New(v.Type()).Elem()
+	switch {
+	case v.CanInt():
+		next.SetInt(v.Int() + 1)
+	case v.CanUint():
+		next.SetUint(v.Uint() + 1)
+	default:
+		return nil, fmt.Errorf("database: version %v is not an integer", version)
+	}
+	return next.Interface(), nil
+}
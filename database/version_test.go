@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type versionedOrder struct {
+	ID       int64
+	Status   string
+	Revision int32 `gorm:"version"`
+}
+
+// TestUpdateWithVersion tests the optimistic update of the entity and the stale entity.
+func TestUpdateWithVersion(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&versionedOrder{}, &repoUser{}); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClientFromDB(db)
+	ctx := context.Background()
+
+	order := &versionedOrder{Status: "created"}
+	if err := c.DB(ctx).Create(order).Error; err != nil {
+		t.Fatal(err)
+	}
+	stale := *order
+	if err := UpdateWithVersion(c.DB(ctx), order, map[string]any{"status": "paid"}); err != nil {
+		t.Fatalf("UpdateWithVersion failed: %v", err)
+	}
+	if order.Revision != 1 {
+		t.Errorf("Expected revision 1, got %d", order.Revision)
+	}
+	if err := UpdateWithVersion(c.DB(ctx), &stale, map[string]any{"Status": "canceled"}); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("Expected ErrStaleObject, got %v", err)
+	}
+	var got versionedOrder
+	c.DB(ctx).Take(&got, order.ID)
+	if got.Status != "paid" || got.Revision != 1 {
+		t.Errorf("got %+v", got)
+	}
+
+	if err := UpdateWithVersion(c.DB(ctx), order, map[string]any{"revision": 5}); err == nil {
+		t.Error("Expected error for updating the version field")
+	}
+	if err := UpdateWithVersion(c.DB(ctx), &repoUser{ID: 1}, map[string]any{"name": "a"}); err == nil {
+		t.Error("Expected error for the model without version field")
+	}
+}
+
+// TestRepositoryUpdateWithVersion tests the optimistic update by id and version.
+func TestRepositoryUpdateWithVersion(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&versionedOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository[versionedOrder](NewClientFromDB(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	order := &versionedOrder{Status: "created"}
+	if err := repo.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.UpdateWithVersion(ctx, order.ID, 0, map[string]any{"status": "paid"}); err != nil {
+		t.Fatalf("UpdateWithVersion failed: %v", err)
+	}
+	if err := repo.UpdateWithVersion(ctx, order.ID, 0, map[string]any{"status": "canceled"}); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("Expected ErrStaleObject for the stale version, got %v", err)
+	}
+	if err := repo.UpdateWithVersion(ctx, order.ID+1, 1, map[string]any{"status": "canceled"}); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("Expected ErrStaleObject for the missing record, got %v", err)
+	}
+	got, err := repo.GetByID(ctx, order.ID)
+	if err != nil || got.Status != "paid" || got.Revision != 1 {
+		t.Errorf("got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,245 @@
+/*
+errs 带错误码的错误，支持包装、调用栈和 HTTP / gRPC 状态码映射，供各模块共用同一套错误模型
+*/
+
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/status"
+)
+
+// The codes of the common errors, the same as the gRPC canonical codes so they map to
+// the gRPC status codes directly. The business codes should not overlap with them, like
+// 10001 and above, and be mapped to the status codes by Register.
+const (
+	CodeOK                 = 0
+	CodeCanceled           = 1
+	CodeUnknown            = 2
+	CodeInvalidArgument    = 3
+	CodeDeadlineExceeded   = 4
+	CodeNotFound           = 5
+	CodeAlreadyExists      = 6
+	CodePermissionDenied   = 7
+	CodeResourceExhausted  = 8
+	CodeFailedPrecondition = 9
+	CodeAborted            = 10
+	CodeOutOfRange         = 11
+	CodeUnimplemented      = 12
+	CodeInternal           = 13
+	CodeUnavailable        = 14
+	CodeDataLoss           = 15
+	CodeUnauthenticated    = 16
+)
+
+var stackEnabled atomic.Bool
+
+// EnableStack enables capturing the stacks of the errors created by New and Wrap, disabled
+// by default as it costs a runtime.Callers. The stacks are printed by the %+v verb.
+func EnableStack(enabled bool) {
+	stackEnabled.Store(enabled)
+}
+
+// Error is an error with a code and a message, optionally wrapping a cause.
+type Error struct {
+	code  int
+	msg   string
+	cause error
+	stack []uintptr
+}
+
+// New returns an error with the code and the message.
+func New(code int, msg string) error {
+	return newError(code, msg, nil)
+}
+
+// Newf returns an error with the code and the formatted message.
+func Newf(code int, format string, args ...any) error {
+	return newError(code, fmt.Sprintf(format, args...), nil)
+}
+
+// Wrap returns an error with the code and the message wrapping err, nil if err is nil.
+// The code of the returned error takes precedence over the codes of err.
+func Wrap(err error, code int, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return newError(code, msg, err)
+}
+
+// Wrapf returns an error with the code and the formatted message wrapping err, nil if err is nil.
+func Wrapf(err error, code int, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	return newError(code, fmt.Sprintf(format, args...), err)
+}
+
+func newError(code int, msg string, cause error) *Error {
+	e := &Error{code: code, msg: msg, cause: cause}
+	// the stack of the innermost error is enough to locate the origin
+	var inner *Error
+	if stackEnabled.Load() && !errors.As(cause, &inner) {
+		var pcs [32]uintptr
+		n := runtime.Callers(3, pcs[:])
+		e.stack = pcs[:n]
+	}
+	return e
+}
+
+// Code returns the code of the error.
+func (e *Error) Code() int {
+	return e.code
+}
+
+// Msg returns the message of the error, without the cause.
+func (e *Error) Msg() string {
+	return e.msg
+}
+
+// Error implements error, the message followed by the cause.
+func (e *Error) Error() string {
+	if e.cause == nil {
+		return e.msg
+	}
+	if e.msg == "" {
+		return e.cause.Error()
+	}
+	return e.msg + ": " + e.cause.Error()
+}
+
+// Unwrap returns the cause.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Stack returns the stack captured when the error was created, empty if not captured.
+func (e *Error) Stack() string {
+	if len(e.stack) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// Format implements fmt.Formatter. The %+v verb prints the error chain, an error per line
+// with its code, and the stack if captured, e.g.
+//
+//	[code=5] user not found
+//	    caused by: sql: no rows in result set
+//	main.getUser
+//		/src/main.go:20
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		_, _ = io.WriteString(s, Chain(e))
+	case verb == 'q':
+		_, _ = io.WriteString(s, strconv.Quote(e.Error()))
+	default:
+		_, _ = io.WriteString(s, e.Error())
+	}
+}
+
+// GRPCStatus returns the gRPC status of the error, used by status.FromError and status.Code
+// so the errors returned by the gRPC handlers carry the mapped status codes.
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(GRPCCode(e), e.Error())
+}
+
+// Code returns the code of the first Error in the chain of err: CodeOK for nil, CodeCanceled
+// and CodeDeadlineExceeded for the context errors, the code of a gRPC status error, and
+// CodeUnknown for the others.
+func Code(err error) int {
+	if err == nil {
+		return CodeOK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.code
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	}
+	if s, ok := status.FromError(err); ok {
+		return int(s.Code())
+	}
+	return CodeUnknown
+}
+
+// Msg returns the message of the first Error in the chain of err, err.Error() if none,
+// empty for nil. Unlike Error() it does not include the causes, which is suitable for
+// the responses to the clients.
+func Msg(err error) string {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.msg
+	}
+	return err.Error()
+}
+
+// Is reports whether the code of err is code.
+func Is(err error, code int) bool {
+	return Code(err) == code
+}
+
+// Chain formats the error chain of err, an error per line with the codes of the Errors,
+// followed by the innermost stack captured.
+func Chain(err error) string {
+	if err == nil {
+		return ""
+	}
+	var (
+		b     strings.Builder
+		stack string
+	)
+	for i := 0; err != nil; i++ {
+		if i > 0 {
+			b.WriteString("\n    caused by: ")
+		}
+		e, ok := err.(*Error)
+		if !ok {
+			b.WriteString(err.Error())
+			// the causes of the other errors are included in their messages
+			if next := errors.Unwrap(err); next != nil {
+				var inner *Error
+				if errors.As(next, &inner) {
+					err = inner
+					continue
+				}
+			}
+			break
+		}
+		fmt.Fprintf(&b, "[code=%d] %s", e.code, e.msg)
+		if s := e.Stack(); s != "" {
+			stack = s
+		}
+		err = e.cause
+	}
+	if stack != "" {
+		b.WriteByte('\n')
+		b.WriteString(strings.TrimRight(stack, "\n"))
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errNoRows = errors.New("sql: no rows in result set")
+
+// TestError tests the codes, the messages and the chain of the wrapped errors.
+func TestError(t *testing.T) {
+	err := Wrap(errNoRows, CodeNotFound, "user not found")
+	err = fmt.Errorf("get user: %w", err)
+	if Code(err) != CodeNotFound || Msg(err) != "user not found" || !Is(err, CodeNotFound) {
+		t.Errorf("Code = %d, Msg = %q", Code(err), Msg(err))
+	}
+	if err.Error() != "get user: user not found: sql: no rows in result set" {
+		t.Errorf("Error = %q", err.Error())
+	}
+	if !errors.Is(err, errNoRows) {
+		t.Error("Expected the cause unwrapped")
+	}
+	if Wrap(nil, CodeInternal, "x") != nil || Wrapf(nil, CodeInternal, "%d", 1) != nil {
+		t.Error("Expected Wrap of nil to be nil")
+	}
+
+	// the outer code takes precedence
+	outer := Wrapf(Newf(CodeNotFound, "order %d not found", 1), CodeInternal, "load")
+	if Code(outer) != CodeInternal || Msg(outer) != "load" {
+		t.Errorf("Code = %d, Msg = %q", Code(outer), Msg(outer))
+	}
+	want := "get user: user not found: sql: no rows in result set\n    caused by: [code=5] user not found\n    caused by: sql: no rows in result set"
+	if got := Chain(err); got != want {
+		t.Errorf("Chain =\n%s\nwant\n%s", got, want)
+	}
+	if got := fmt.Sprintf("%+v", outer); got != "[code=13] load\n    caused by: [code=5] order 1 not found" {
+		t.Errorf("%%+v = %q", got)
+	}
+	if got := fmt.Sprintf("%v|%s|%q", outer, outer, New(CodeInternal, "x")); got != `load: order 1 not found|load: order 1 not found|"x"` {
+		t.Errorf("Format = %s", got)
+	}
+
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, CodeOK},
+		{context.Canceled, CodeCanceled},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), CodeDeadlineExceeded},
+		{status.Error(codes.PermissionDenied, "denied"), CodePermissionDenied},
+		{errNoRows, CodeUnknown},
+	}
+	for _, tt := range tests {
+		if got := Code(tt.err); got != tt.code {
+			t.Errorf("Code(%v) = %d, want %d", tt.err, got, tt.code)
+		}
+	}
+	if Msg(nil) != "" || Msg(errNoRows) != errNoRows.Error() {
+		t.Error("Unexpected Msg of the plain errors")
+	}
+}
+
+// TestStack tests capturing the stack of the innermost error when enabled.
+func TestStack(t *testing.T) {
+	if e := New(CodeInternal, "x").(*Error); e.Stack() != "" {
+		t.Errorf("Expected no stack by default, got %s", e.Stack())
+	}
+	EnableStack(true)
+	defer EnableStack(false)
+
+	inner := New(CodeNotFound, "not found").(*Error)
+	if s := inner.Stack(); !strings.Contains(s, "errs.TestStack") || !strings.Contains(s, "errs_test.go:") {
+		t.Errorf("Stack = %s", s)
+	}
+	outer := Wrap(inner, CodeInternal, "load").(*Error)
+	if outer.Stack() != "" {
+		t.Error("Expected the stack captured by the innermost error only")
+	}
+	if s := fmt.Sprintf("%+v", outer); !strings.Contains(s, "caused by: [code=5] not found\ngithub.com/baisiyi/go-kits/errs.TestStack") {
+		t.Errorf("%%+v = %s", s)
+	}
+	if e := Wrap(errNoRows, CodeNotFound, "x").(*Error); e.Stack() == "" {
+		t.Error("Expected the stack captured by wrapping a plain error")
+	}
+}
+
+// TestStatus tests the mapping to the HTTP and gRPC status codes.
+func TestStatus(t *testing.T) {
+	const codeBalanceNotEnough = 10001
+	Register(codeBalanceNotEnough, http.StatusBadRequest, codes.FailedPrecondition)
+
+	tests := []struct {
+		err  error
+		http int
+		grpc codes.Code
+	}{
+		{nil, http.StatusOK, codes.OK},
+		{New(CodeNotFound, "x"), http.StatusNotFound, codes.NotFound},
+		{New(CodeUnauthenticated, "x"), http.StatusUnauthorized, codes.Unauthenticated},
+		{New(codeBalanceNotEnough, "x"), http.StatusBadRequest, codes.FailedPrecondition},
+		{New(10002, "x"), http.StatusInternalServerError, codes.Unknown},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, codes.DeadlineExceeded},
+		{errNoRows, http.StatusInternalServerError, codes.Unknown},
+	}
+	for _, tt := range tests {
+		if got := HTTPStatus(tt.err); got != tt.http {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.http)
+		}
+		if got := GRPCCode(tt.err); got != tt.grpc {
+			t.Errorf("GRPCCode(%v) = %v, want %v", tt.err, got, tt.grpc)
+		}
+	}
+
+	// the gRPC status of the errors returned by the handlers
+	s := status.Convert(Wrap(errNoRows, CodeNotFound, "user not found"))
+	if s.Code() != codes.NotFound || s.Message() != "user not found: sql: no rows in result set" {
+		t.Errorf("status = %v", s)
+	}
+}
@@ -0,0 +1,72 @@
+package errs
+
+import (
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+)
+
+// statusMapping is the HTTP and gRPC status codes of an error code.
+type statusMapping struct {
+	http int
+	grpc codes.Code
+}
+
+var (
+	mu       sync.RWMutex
+	mappings = map[int]statusMapping{
+		CodeOK:                 {http.StatusOK, codes.OK},
+		CodeCanceled:           {499, codes.Canceled}, // client closed request
+		CodeUnknown:            {http.StatusInternalServerError, codes.Unknown},
+		CodeInvalidArgument:    {http.StatusBadRequest, codes.InvalidArgument},
+		CodeDeadlineExceeded:   {http.StatusGatewayTimeout, codes.DeadlineExceeded},
+		CodeNotFound:           {http.StatusNotFound, codes.NotFound},
+		CodeAlreadyExists:      {http.StatusConflict, codes.AlreadyExists},
+		CodePermissionDenied:   {http.StatusForbidden, codes.PermissionDenied},
+		CodeResourceExhausted:  {http.StatusTooManyRequests, codes.ResourceExhausted},
+		CodeFailedPrecondition: {http.StatusBadRequest, codes.FailedPrecondition},
+		CodeAborted:            {http.StatusConflict, codes.Aborted},
+		CodeOutOfRange:         {http.StatusBadRequest, codes.OutOfRange},
+		CodeUnimplemented:      {http.StatusNotImplemented, codes.Unimplemented},
+		CodeInternal:           {http.StatusInternalServerError, codes.Internal},
+		CodeUnavailable:        {http.StatusServiceUnavailable, codes.Unavailable},
+		CodeDataLoss:           {http.StatusInternalServerError, codes.DataLoss},
+		CodeUnauthenticated:    {http.StatusUnauthorized, codes.Unauthenticated},
+	}
+)
+
+// Register maps the code to the HTTP and gRPC status codes, usually called in init for
+// the business codes, e.g.
+//
+//	const CodeBalanceNotEnough = 10001
+//
+//	func init() {
+//		errs.Register(CodeBalanceNotEnough, http.StatusBadRequest, codes.FailedPrecondition)
+//	}
+//
+// The codes not registered map to 500 and codes.Unknown.
+func Register(code, httpStatus int, grpcCode codes.Code) {
+	mu.Lock()
+	defer mu.Unlock()
+	mappings[code] = statusMapping{http: httpStatus, grpc: grpcCode}
+}
+
+// HTTPStatus returns the HTTP status code of err by its code, 200 for nil.
+func HTTPStatus(err error) int {
+	return mapping(Code(err)).http
+}
+
+// GRPCCode returns the gRPC status code of err by its code, codes.OK for nil.
+func GRPCCode(err error) codes.Code {
+	return mapping(Code(err)).grpc
+}
+
+func mapping(code int) statusMapping {
+	mu.RLock()
+	defer mu.RUnlock()
+	if m, ok := mappings[code]; ok {
+		return m
+	}
+	return statusMapping{http: http.StatusInternalServerError, grpc: codes.Unknown}
+}
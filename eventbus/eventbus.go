@@ -0,0 +1,253 @@
+/*
+eventbus 进程内泛型事件总线，事件类型即主题，支持同步/异步分发、handler panic 隔离和关闭时排空
+*/
+
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sync"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// ErrClosed is returned when publishing to a closed bus.
+var ErrClosed = errors.New("eventbus: bus closed")
+
+// Handler handles an event of type T.
+type Handler[T any] func(ctx context.Context, event T) error
+
+// SubscribeOption is the option of a subscription.
+type SubscribeOption func(*subscriber)
+
+// Async dispatches the events to the handler in its own goroutine through a
+// queue of the given size. Publish blocks when the queue is full, until ctx is done
+// or the bus is closed.
+func Async(queueSize int) SubscribeOption {
+	return func(s *subscriber) {
+		s.async = true
+		s.queueSize = queueSize
+	}
+}
+
+// Option is the option of Bus.
+type Option func(*Bus)
+
+// WithLogger sets the logger of the async handler errors, default as the default logger.
+func WithLogger(l log.Logger) Option {
+	return func(b *Bus) {
+		b.logger = l
+	}
+}
+
+// Bus dispatches the published events to the subscribers of the event type.
+type Bus struct {
+	logger log.Logger
+
+	mu     sync.RWMutex
+	subs   map[reflect.Type][]*subscriber
+	closed bool
+	done   chan struct{} // closed by Close
+	wg     sync.WaitGroup
+}
+
+type subscriber struct {
+	handle    func(ctx context.Context, event any) error
+	async     bool
+	queueSize int
+	queue     chan delivery
+	done      chan struct{} // closed on unsubscribe or Close, the loop exits after draining the queue
+}
+
+type delivery struct {
+	ctx   context.Context
+	event any
+}
+
+// New creates a Bus.
+func New(opts ...Option) *Bus {
+	b := &Bus{subs: make(map[reflect.Type][]*subscriber), done: make(chan struct{})}
+	for _, o := range opts {
+		o(b)
+	}
+	if b.logger == nil {
+		b.logger = log.GetDefaultLogger()
+	}
+	return b
+}
+
+var defaultBus = New()
+
+// Default returns the process wide bus.
+func Default() *Bus {
+	return defaultBus
+}
+
+// Subscribe subscribes the events of type T, returning the function to unsubscribe.
+// A nil bus means the default bus.
+func Subscribe[T any](b *Bus, handler Handler[T], opts ...SubscribeOption) (unsubscribe func()) {
+	if b == nil {
+		b = defaultBus
+	}
+	s := &subscriber{
+		handle: func(ctx context.Context, event any) error {
+			return handler(ctx, event.(T))
+		},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return b.subscribe(reflect.TypeFor[T](), s)
+}
+
+// Publish publishes the event to the subscribers of type T. The sync handlers
+// run in order in the caller goroutine and their errors are joined; the async
+// handlers are queued and their errors logged. A nil bus means the default bus.
+func Publish[T any](ctx context.Context, b *Bus, event T) error {
+	if b == nil {
+		b = defaultBus
+	}
+	return b.publish(ctx, reflect.TypeFor[T](), event)
+}
+
+func (b *Bus) subscribe(t reflect.Type, s *subscriber) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return func() {}
+	}
+	if s.async {
+		s.queue = make(chan delivery, s.queueSize)
+		s.done = make(chan struct{})
+		b.wg.Add(1)
+		go b.loop(t, s)
+	}
+	b.subs[t] = append(b.subs[t], s)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.unsubscribe(t, s) })
+	}
+}
+
+func (b *Bus) unsubscribe(t reflect.Type, s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.subs[t]
+	for i, sub := range subs {
+		if sub == s {
+			b.subs[t] = append(subs[:i:i], subs[i+1:]...)
+			if s.async && !b.closed {
+				close(s.done)
+			}
+			return
+		}
+	}
+}
+
+func (b *Bus) publish(ctx context.Context, t reflect.Type, event any) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	// 复制订阅者后释放读锁，队列满时阻塞入队不会阻塞 Close/unsubscribe
+	subs := b.subs[t]
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subs {
+		if !s.async {
+			continue
+		}
+		select {
+		case s.queue <- delivery{ctx: context.WithoutCancel(ctx), event: event}:
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+		case <-b.done:
+			errs = append(errs, ErrClosed)
+		case <-s.done:
+			// 已取消订阅
+		}
+	}
+
+	for _, s := range subs {
+		if s.async {
+			continue
+		}
+		if err := safeHandle(ctx, s, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *Bus) loop(t reflect.Type, s *subscriber) {
+	defer b.wg.Done()
+	for {
+		select {
+		case d := <-s.queue:
+			b.handle(t, s, d)
+		case <-s.done:
+			// 队列不会关闭，排空已入队的事件后退出
+			for {
+				select {
+				case d := <-s.queue:
+					b.handle(t, s, d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (b *Bus) handle(t reflect.Type, s *subscriber, d delivery) {
+	if err := safeHandle(d.ctx, s, d.event); err != nil {
+		b.logger.Errorf("eventbus: async handler of %s error: %v", t, err)
+	}
+}
+
+// Close rejects new events and waits for the queued async events to be handled until ctx is done.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	for _, subs := range b.subs {
+		for _, s := range subs {
+			if s.async {
+				close(s.done)
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func safeHandle(ctx context.Context, s *subscriber, event any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("eventbus: handler panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return s.handle(ctx, event)
+}
@@ -0,0 +1,134 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type userCreated struct {
+	ID int
+}
+
+type userDeleted struct {
+	ID int
+}
+
+// TestSyncDispatch tests that the sync handlers receive the events of their type only.
+func TestSyncDispatch(t *testing.T) {
+	b := New()
+	var created, deleted []int
+	Subscribe(b, func(ctx context.Context, e userCreated) error {
+		created = append(created, e.ID)
+		return nil
+	})
+	Subscribe(b, func(ctx context.Context, e userDeleted) error {
+		deleted = append(deleted, e.ID)
+		return errors.New("fail")
+	})
+
+	if err := Publish(context.Background(), b, userCreated{ID: 1}); err != nil {
+		t.Errorf("Publish failed: %v", err)
+	}
+	if err := Publish(context.Background(), b, userDeleted{ID: 2}); err == nil {
+		t.Error("Expected handler error returned")
+	}
+	if len(created) != 1 || created[0] != 1 || len(deleted) != 1 {
+		t.Errorf("created = %v, deleted = %v", created, deleted)
+	}
+}
+
+// TestPanicIsolation tests that a panicking handler does not affect the others.
+func TestPanicIsolation(t *testing.T) {
+	b := New()
+	var called bool
+	Subscribe(b, func(ctx context.Context, e userCreated) error { panic("boom") })
+	Subscribe(b, func(ctx context.Context, e userCreated) error {
+		called = true
+		return nil
+	})
+	if err := Publish(context.Background(), b, userCreated{}); err == nil {
+		t.Error("Expected panic returned as error")
+	}
+	if !called {
+		t.Error("Expected second handler called")
+	}
+}
+
+// TestAsyncDrain tests that Close drains the queued async events.
+func TestAsyncDrain(t *testing.T) {
+	b := New()
+	var n atomic.Int32
+	Subscribe(b, func(ctx context.Context, e userCreated) error {
+		n.Add(1)
+		return nil
+	}, Async(100))
+	for i := 0; i < 50; i++ {
+		if err := Publish(context.Background(), b, userCreated{ID: i}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n.Load() != 50 {
+		t.Errorf("handled = %d, want 50", n.Load())
+	}
+	if err := Publish(context.Background(), b, userCreated{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+// TestCloseWhilePublishBlocked tests that Close is not blocked by a publisher waiting
+// on a full queue, and the publisher returns ErrClosed.
+func TestCloseWhilePublishBlocked(t *testing.T) {
+	b := New()
+	release := make(chan struct{})
+	Subscribe(b, func(ctx context.Context, e userCreated) error {
+		<-release
+		return nil
+	}, Async(1))
+	// one event in the handler, one in the queue
+	for i := 0; i < 2; i++ {
+		if err := Publish(context.Background(), b, userCreated{ID: i}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	published := make(chan error, 1)
+	go func() {
+		published <- Publish(context.Background(), b, userCreated{ID: 2})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Close timed out by the blocked handler, got %v", err)
+	}
+	if err := <-published; !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	close(release)
+	if err := b.Close(context.Background()); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+// TestUnsubscribe tests that an unsubscribed handler no longer receives events.
+func TestUnsubscribe(t *testing.T) {
+	b := New()
+	var n int
+	unsubscribe := Subscribe(b, func(ctx context.Context, e userCreated) error {
+		n++
+		return nil
+	})
+	_ = Publish(context.Background(), b, userCreated{})
+	unsubscribe()
+	unsubscribe()
+	_ = Publish(context.Background(), b, userCreated{})
+	if n != 1 {
+		t.Errorf("handled = %d, want 1", n)
+	}
+}
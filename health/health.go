@@ -0,0 +1,231 @@
+/*
+health 健康检查，汇总数据库、Redis 等组件的检查结果，提供 Kubernetes 存活和就绪探针的 HTTP 接口
+*/
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The status of the reports and the checks.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker checks the health of a component, e.g. database.Client and redis.Client.
+// The details, such as the latency and the pool stats, are reported as is.
+type Checker interface {
+	Check(ctx context.Context) (details any, err error)
+}
+
+// CheckerFunc adapts a func to Checker.
+type CheckerFunc func(ctx context.Context) (any, error)
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context) (any, error) {
+	return f(ctx)
+}
+
+// PingChecker adapts a ping func without details to Checker.
+func PingChecker(ping func(ctx context.Context) error) Checker {
+	return CheckerFunc(func(ctx context.Context) (any, error) {
+		return nil, ping(ctx)
+	})
+}
+
+// Result is the result of a check.
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Latency is the duration of the check like 1.5ms.
+	Latency string `json:"latency"`
+	// Optional is set for the checks whose failure does not fail the probe.
+	Optional bool `json:"optional,omitempty"`
+	Details  any  `json:"details,omitempty"`
+}
+
+// Report is the result of a probe, down if any required check is down.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Up reports whether the status is up.
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+// check is a registered checker.
+type check struct {
+	checker  Checker
+	liveness bool
+	optional bool
+	timeout  time.Duration
+}
+
+// CheckOption is the option of a registered checker.
+type CheckOption func(*check)
+
+// WithLiveness includes the check in the liveness probe too. A failing liveness probe
+// restarts the pod, so only the checks of the process itself should be included, not
+// the dependencies like the database.
+func WithLiveness() CheckOption {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// WithOptional reports the failure of the check without failing the probe, e.g. a
+// cache the service can run without.
+func WithOptional() CheckOption {
+	return func(c *check) {
+		c.optional = true
+	}
+}
+
+// WithCheckTimeout sets the timeout of the check, default as the timeout of the registry.
+func WithCheckTimeout(d time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// Option is the option of Registry.
+type Option func(*Registry)
+
+// WithTimeout sets the default timeout of the checks, default as 5s.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Registry) {
+		r.timeout = d
+	}
+}
+
+// Registry runs the registered checks for the probes. The readiness probe runs all
+// the checks, the liveness probe only the ones registered WithLiveness.
+type Registry struct {
+	timeout  time.Duration
+	notReady atomic.Bool
+
+	mu     sync.RWMutex
+	checks map[string]*check
+}
+
+// DefaultRegistry is the registry of the package level functions.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates a registry.
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{timeout: 5 * time.Second, checks: make(map[string]*check)}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Register registers a checker to DefaultRegistry.
+func Register(name string, c Checker, opts ...CheckOption) {
+	DefaultRegistry.Register(name, c, opts...)
+}
+
+// Register registers a checker, replacing the one of the same name.
+func (r *Registry) Register(name string, c Checker, opts ...CheckOption) {
+	ch := &check{checker: c}
+	for _, o := range opts {
+		o(ch)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = ch
+}
+
+// Deregister removes the checker of name.
+func (r *Registry) Deregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, name)
+}
+
+// SetReady sets whether the service is ready, the readiness probe is down without
+// running the checks while not ready, e.g. during the warm up or the graceful shutdown.
+// It is ready by default.
+func (r *Registry) SetReady(ready bool) {
+	r.notReady.Store(!ready)
+}
+
+// Liveness runs the liveness checks, up if none is registered.
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, true)
+}
+
+// Readiness runs all the checks.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	if r.notReady.Load() {
+		return Report{Status: StatusDown}
+	}
+	return r.run(ctx, false)
+}
+
+// run runs the checks concurrently, each with its timeout.
+func (r *Registry) run(ctx context.Context, liveness bool) Report {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checks))
+	checks := make([]*check, 0, len(r.checks))
+	for name, c := range r.checks {
+		if liveness && !c.liveness {
+			continue
+		}
+		names = append(names, name)
+		checks = append(checks, c)
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status == StatusDown && !checks[i].optional {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// runCheck runs a check with its timeout, the panic is reported as the error.
+func (r *Registry) runCheck(ctx context.Context, c *check) (res Result) {
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = r.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			res = Result{Status: StatusDown, Error: fmt.Sprintf("panic: %v", p)}
+		}
+		res.Latency = time.Since(start).String()
+		res.Optional = c.optional
+	}()
+	details, err := c.checker.Check(ctx)
+	if err != nil {
+		return Result{Status: StatusDown, Error: err.Error(), Details: details}
+	}
+	return Result{Status: StatusUp, Details: details}
+}
@@ -0,0 +1,71 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRegistry tests the liveness and readiness checks, the optional checks and the timeout.
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(WithTimeout(20 * time.Millisecond))
+	r.Register("self", PingChecker(func(context.Context) error { return nil }), WithLiveness())
+	r.Register("db", CheckerFunc(func(context.Context) (any, error) { return map[string]int{"open": 1}, nil }))
+	r.Register("cache", PingChecker(func(context.Context) error { return errors.New("refused") }), WithOptional())
+	ctx := context.Background()
+
+	if report := r.Liveness(ctx); !report.Up() || len(report.Checks) != 1 {
+		t.Errorf("liveness = %+v", report)
+	}
+	report := r.Readiness(ctx)
+	if !report.Up() || report.Checks["cache"].Status != StatusDown || !report.Checks["cache"].Optional {
+		t.Errorf("Expected up with the optional check down, got %+v", report)
+	}
+
+	r.Register("slow", PingChecker(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	r.Register("panic", PingChecker(func(context.Context) error { panic("boom") }))
+	report = r.Readiness(ctx)
+	if report.Up() || report.Checks["slow"].Error != context.DeadlineExceeded.Error() || report.Checks["panic"].Error != "panic: boom" {
+		t.Errorf("Expected down by the timeout and the panic, got %+v", report)
+	}
+
+	r.Deregister("slow")
+	r.Deregister("panic")
+	r.SetReady(false)
+	if report := r.Readiness(ctx); report.Up() {
+		t.Error("Expected down while not ready")
+	}
+	r.SetReady(true)
+	if report := r.Readiness(ctx); !report.Up() {
+		t.Errorf("Expected up when ready again, got %+v", report)
+	}
+}
+
+// TestHandler tests the status codes and the JSON of the probes.
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Register("db", PingChecker(func(context.Context) error { return errors.New("down") }))
+	h := r.Handler()
+
+	for path, code := range map[string]int{"/livez": http.StatusOK, "/readyz": http.StatusServiceUnavailable} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != code {
+			t.Errorf("%s: code = %d, want %d", path, rec.Code, code)
+		}
+		var report Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("%s: invalid json %q", path, rec.Body.String())
+		}
+		if path == "/readyz" && report.Checks["db"].Error != "down" {
+			t.Errorf("%s: report = %+v", path, report)
+		}
+	}
+}
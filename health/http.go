@@ -0,0 +1,40 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// LivenessHandler serves the liveness probe like /livez.
+func (r *Registry) LivenessHandler() http.Handler {
+	return reportHandler(r.Liveness)
+}
+
+// ReadinessHandler serves the readiness probe like /readyz.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return reportHandler(r.Readiness)
+}
+
+// Handler serves /livez and /readyz, e.g. mux.Handle("/health/", http.StripPrefix("/health", r.Handler())).
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/livez", r.LivenessHandler())
+	mux.Handle("/readyz", r.ReadinessHandler())
+	return mux
+}
+
+// reportHandler writes the report as JSON, with 200 if up and 503 if down.
+func reportHandler(probe func(ctx context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := probe(req.Context())
+		code := http.StatusOK
+		if !report.Up() {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
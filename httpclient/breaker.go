@@ -0,0 +1,96 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when the circuit of the host is open.
+var ErrCircuitOpen = errors.New("httpclient: circuit open")
+
+type breakerState int8
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// breakers holds the circuit breaker of each host.
+type breakers struct {
+	cfg BreakerConfig
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+func newBreakers(cfg BreakerConfig) *breakers {
+	return &breakers{cfg: cfg, hosts: make(map[string]*breaker)}
+}
+
+func (bs *breakers) get(host string) *breaker {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	b, ok := bs.hosts[host]
+	if !ok {
+		b = &breaker{cfg: bs.cfg}
+		bs.hosts[host] = b
+	}
+	return b
+}
+
+// breaker is the circuit breaker of a host.
+type breaker struct {
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool // a probe request is in flight in half-open state
+}
+
+// allow reports whether a request can be sent, letting a probe through once the
+// open timeout elapses.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if now.Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return false
+		}
+		b.state = stateHalfOpen
+	case stateHalfOpen:
+		if b.probing {
+			return false
+		}
+	default:
+		return true
+	}
+	b.probing = true
+	return true
+}
+
+// record records the result of an allowed request.
+func (b *breaker) record(now time.Time, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if success {
+		b.state, b.failures = stateClosed, 0
+		return
+	}
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.state, b.openedAt = stateOpen, now
+	}
+}
+
+// release releases an allowed request whose result does not count.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
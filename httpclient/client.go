@@ -0,0 +1,161 @@
+/*
+httpclient 出站 HTTP 客户端，支持超时、连接池、重试、按 host 熔断、请求日志和指标回调，可通过插件配置
+*/
+
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/baisiyi/go-kits/log"
+	"github.com/baisiyi/go-kits/tracing"
+)
+
+// Observer is notified of every request, e.g. to export the latency and error metrics.
+// status is 0 if the request failed without response.
+type Observer interface {
+	ObserveRequest(method, host string, status int, duration time.Duration, err error)
+}
+
+// ObserverFunc is an adapter to allow the use of ordinary functions as Observer.
+type ObserverFunc func(method, host string, status int, duration time.Duration, err error)
+
+// ObserveRequest calls fn.
+func (fn ObserverFunc) ObserveRequest(method, host string, status int, duration time.Duration, err error) {
+	fn(method, host, status, duration, err)
+}
+
+// Option is the option of Client.
+type Option func(*Client)
+
+// WithLogger sets the logger of the requests, default as the default logger.
+func WithLogger(l log.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// WithObserver sets the observer of the requests.
+func WithObserver(o Observer) Option {
+	return func(c *Client) {
+		c.observer = o
+	}
+}
+
+// WithTransport sets the underlying transport, e.g. for tests or a custom tls config.
+// The connection pool options of Config are ignored then.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.base = rt
+	}
+}
+
+// Client wraps http.Client, whose transport retries, breaks the circuits, logs and
+// observes the requests, all the methods of http.Client are available on it.
+type Client struct {
+	*http.Client
+	cfg      Config
+	baseURL  *url.URL
+	logger   log.Logger
+	observer Observer
+	base     http.RoundTripper
+	breakers *breakers
+}
+
+// New creates a Client.
+func New(cfg Config, opts ...Option) (*Client, error) {
+	cfg.setDefaults()
+	c := &Client{cfg: cfg}
+	if cfg.BaseURL != "" {
+		u, err := url.Parse(cfg.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("httpclient: parse base_url error: %w", err)
+		}
+		c.baseURL = u
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.logger == nil {
+		c.logger = log.GetDefaultLogger()
+	}
+	if c.base == nil {
+		c.base = cfg.transport()
+	}
+	if cfg.Tracing {
+		c.base = tracing.Transport(c.base)
+	}
+	if cfg.Breaker.FailureThreshold > 0 {
+		c.breakers = newBreakers(cfg.Breaker)
+	}
+	c.Client = &http.Client{
+		Transport: &transport{c: c},
+		Timeout:   cfg.Timeout,
+	}
+	return c, nil
+}
+
+// Config returns the config of the client with the defaults applied.
+func (c *Client) Config() Config {
+	return c.cfg
+}
+
+// NewRequest creates a request with ctx, the relative url is resolved by BaseURL.
+func (c *Client) NewRequest(ctx context.Context, method, rawURL string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, c.resolve(rawURL), body)
+}
+
+// GetContext sends a GET request with ctx.
+func (c *Client) GetContext(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := c.NewRequest(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// PostContext sends a POST request with ctx.
+func (c *Client) PostContext(ctx context.Context, rawURL, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := c.NewRequest(ctx, http.MethodPost, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// Close closes the idle connections of the pool.
+func (c *Client) Close() error {
+	c.CloseIdleConnections()
+	return nil
+}
+
+func (c *Client) resolve(rawURL string) string {
+	if c.baseURL == nil || strings.Contains(rawURL, "://") {
+		return rawURL
+	}
+	return strings.TrimRight(c.baseURL.String(), "/") + "/" + strings.TrimLeft(rawURL, "/")
+}
+
+func (c *Config) transport() *http.Transport {
+	dialer := &net.Dialer{Timeout: c.DialTimeout, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   c.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: c.ResponseHeaderTimeout,
+		MaxIdleConns:          c.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       c.MaxConnsPerHost,
+		IdleConnTimeout:       c.IdleConnTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
@@ -0,0 +1,246 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/plugin"
+	"github.com/baisiyi/go-kits/tracing"
+)
+
+func TestBaseURLHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Path+"|"+r.Header.Get("X-Caller")+"|"+r.Header.Get("X-Trace"))
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{BaseURL: srv.URL + "/api/", Headers: map[string]string{"X-Caller": "test", "X-Trace": "default"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := c.NewRequest(context.Background(), http.MethodGet, "/users/1", nil)
+	req.Header.Set("X-Trace", "abc")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if got := string(body); got != "/api/users/1|test|abc" {
+		t.Errorf("body = %q", got)
+	}
+	if req.Header.Get("X-Caller") != "" {
+		t.Error("request of caller modified")
+	}
+}
+
+// TestTracing tests the traceparent injected into each attempt.
+func TestTracing(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+	tracing.SetGlobal(sdktrace.NewTracerProvider())
+
+	var traceparents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+	}))
+	defer srv.Close()
+
+	c, _ := New(Config{BaseURL: srv.URL, Tracing: true})
+	ctx, span := tracing.Start(context.Background(), "caller")
+	defer span.End()
+	resp, err := c.GetContext(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(traceparents) != 1 || !strings.Contains(traceparents[0], tracing.TraceID(ctx)) {
+		t.Errorf("Expected the traceparent of trace %s, got %v", tracing.TraceID(ctx), traceparents)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	c, _ := New(Config{BaseURL: srv.URL, Retry: RetryConfig{Attempts: 3, BaseDelay: time.Millisecond}})
+	req, _ := c.NewRequest(context.Background(), http.MethodPut, "/", strings.NewReader("payload"))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "payload" || calls.Load() != 3 {
+		t.Errorf("status = %d, body = %q, calls = %d", resp.StatusCode, body, calls.Load())
+	}
+
+	// the response of the last attempt is returned
+	calls.Store(-10)
+	resp, err = c.GetContext(context.Background(), "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != -7 {
+		t.Errorf("status = %d, calls = %d", resp.StatusCode, calls.Load())
+	}
+
+	// POST is not retried without idempotency key
+	calls.Store(0)
+	resp, err = c.PostContext(context.Background(), "/", "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls.Load() != 1 {
+		t.Errorf("post calls = %d", calls.Load())
+	}
+	calls.Store(0)
+	req, _ = c.NewRequest(context.Background(), http.MethodPost, "/", strings.NewReader("x"))
+	req.Header.Set(HeaderIdempotencyKey, "k1")
+	resp, err = c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls.Load() != 3 {
+		t.Errorf("post with key calls = %d", calls.Load())
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var fail atomic.Bool
+	fail.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c, _ := New(Config{BaseURL: srv.URL, Breaker: BreakerConfig{FailureThreshold: 2, OpenTimeout: 50 * time.Millisecond}})
+	get := func() error {
+		resp, err := c.GetContext(context.Background(), "/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d", calls.Load())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	fail.Store(false)
+	if err := get(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if err := get(); err != nil {
+		t.Fatalf("closed: %v", err)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := &breaker{cfg: BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second}}
+	now := time.Now()
+	b.record(now, false)
+	if b.allow(now) {
+		t.Fatal("open breaker allowed")
+	}
+	now = now.Add(time.Second)
+	if !b.allow(now) || b.allow(now) {
+		t.Fatal("half-open should allow exactly one probe")
+	}
+	b.record(now, false)
+	if b.allow(now.Add(time.Millisecond)) {
+		t.Fatal("failed probe should reopen")
+	}
+}
+
+func TestObserver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	var (
+		mu   sync.Mutex
+		seen []int
+	)
+	obs := ObserverFunc(func(method, host string, status int, d time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, status)
+	})
+	c, _ := New(Config{BaseURL: srv.URL}, WithObserver(obs))
+	resp, err := c.GetContext(context.Background(), "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetContext(ctx, "/"); err == nil {
+		t.Fatal("want canceled error")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 2 || seen[0] != http.StatusNotFound || seen[1] != 0 {
+		t.Errorf("seen = %v", seen)
+	}
+}
+
+func TestFactory(t *testing.T) {
+	var node yaml.Node
+	content := "user-svc:\n  base_url: http://user-svc:8080\n  timeout: 3s\n  retry:\n    attempts: 2\n"
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		t.Fatal(err)
+	}
+	f := &Factory{}
+	if err := f.Setup(pluginName, &plugin.YamlNodeDecoder{Node: &node}); err != nil {
+		t.Fatal(err)
+	}
+	c := f.clients["user-svc"]
+	if c == nil {
+		t.Fatal("client not created")
+	}
+	cfg := c.Config()
+	if cfg.Timeout != 3*time.Second || cfg.Retry.Attempts != 2 || cfg.Retry.BaseDelay != 100*time.Millisecond {
+		t.Errorf("config = %+v", cfg)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"net/http"
+	"time"
+)
+
+// Config is the configuration of an http client.
+type Config struct {
+	// BaseURL is prepended to the relative request urls, like http://user-svc:8080/api.
+	BaseURL string `yaml:"base_url" mapstructure:"base_url"`
+	// Headers are set on every request unless already set.
+	Headers map[string]string `yaml:"headers" mapstructure:"headers"`
+
+	// Timeout is the timeout of a request including the retries, default as 10s.
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// DialTimeout is the timeout of establishing connections, default as 5s.
+	DialTimeout time.Duration `yaml:"dial_timeout" mapstructure:"dial_timeout"`
+	// TLSHandshakeTimeout is the timeout of the tls handshake, default as 5s.
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout" mapstructure:"tls_handshake_timeout"`
+	// ResponseHeaderTimeout is the wait for the response headers of an attempt, 0 means no limit.
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout" mapstructure:"response_header_timeout"`
+
+	// MaxIdleConns is the max idle connections of all hosts, default as 100.
+	MaxIdleConns int `yaml:"max_idle_conns" mapstructure:"max_idle_conns"`
+	// MaxIdleConnsPerHost is the max idle connections per host, default as 10.
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host" mapstructure:"max_idle_conns_per_host"`
+	// MaxConnsPerHost limits the connections per host, 0 means no limit.
+	MaxConnsPerHost int `yaml:"max_conns_per_host" mapstructure:"max_conns_per_host"`
+	// IdleConnTimeout closes the connections idle longer than it, default as 90s.
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout" mapstructure:"idle_conn_timeout"`
+
+	// Retry retries the failed requests, disabled if Attempts <= 1.
+	Retry RetryConfig `yaml:"retry" mapstructure:"retry"`
+	// Breaker is the per host circuit breaker, disabled if FailureThreshold <= 0.
+	Breaker BreakerConfig `yaml:"breaker" mapstructure:"breaker"`
+
+	// SlowThreshold logs the requests slower than it as warnings, 0 disables.
+	SlowThreshold time.Duration `yaml:"slow_threshold" mapstructure:"slow_threshold"`
+	// Tracing starts a client span for each attempt and injects the traceparent header,
+	// by the global TracerProvider, see the tracing kit.
+	Tracing bool `yaml:"tracing" mapstructure:"tracing"`
+}
+
+// RetryConfig is the retry policy. Only the idempotent requests (GET, HEAD, OPTIONS,
+// PUT, DELETE) or the requests with Idempotency-Key header are retried, on the
+// connection errors and RetryStatus.
+type RetryConfig struct {
+	// Attempts is the max number of attempts including the first one.
+	Attempts int `yaml:"attempts" mapstructure:"attempts"`
+	// BaseDelay is the delay before the first retry, doubled every retry, default as 100ms.
+	BaseDelay time.Duration `yaml:"base_delay" mapstructure:"base_delay"`
+	// MaxDelay caps the delay, default as 2s.
+	MaxDelay time.Duration `yaml:"max_delay" mapstructure:"max_delay"`
+	// RetryStatus are the retried status codes, default as 429, 502, 503 and 504.
+	RetryStatus []int `yaml:"retry_status" mapstructure:"retry_status"`
+}
+
+// BreakerConfig is the circuit breaker config. The circuit of a host opens after
+// FailureThreshold consecutive failures (connection errors or 5xx), the requests
+// fail fast with ErrCircuitOpen for OpenTimeout, then a probe request is let through,
+// closing the circuit on success and opening it again on failure.
+type BreakerConfig struct {
+	FailureThreshold int `yaml:"failure_threshold" mapstructure:"failure_threshold"`
+	// OpenTimeout is how long the circuit stays open, default as 30s.
+	OpenTimeout time.Duration `yaml:"open_timeout" mapstructure:"open_timeout"`
+}
+
+func (c *Config) setDefaults() {
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = 5 * time.Second
+	}
+	if c.TLSHandshakeTimeout <= 0 {
+		c.TLSHandshakeTimeout = 5 * time.Second
+	}
+	if c.MaxIdleConns <= 0 {
+		c.MaxIdleConns = 100
+	}
+	if c.MaxIdleConnsPerHost <= 0 {
+		c.MaxIdleConnsPerHost = 10
+	}
+	if c.IdleConnTimeout <= 0 {
+		c.IdleConnTimeout = 90 * time.Second
+	}
+	if c.Retry.BaseDelay <= 0 {
+		c.Retry.BaseDelay = 100 * time.Millisecond
+	}
+	if c.Retry.MaxDelay <= 0 {
+		c.Retry.MaxDelay = 2 * time.Second
+	}
+	if c.Retry.RetryStatus == nil {
+		c.Retry.RetryStatus = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	if c.Breaker.OpenTimeout <= 0 {
+		c.Breaker.OpenTimeout = 30 * time.Second
+	}
+}
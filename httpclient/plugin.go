@@ -0,0 +1,88 @@
+package httpclient
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "httpclient"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the httpclient plugin factory registered as httpclient-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of httpclient. The config is a map of client name => Config:
+//
+//	httpclient:
+//	  default:
+//	    user-svc:
+//	      base_url: http://user-svc:8080
+//	      timeout: 3s
+//	      retry:
+//	        attempts: 3
+//	      breaker:
+//	        failure_threshold: 5
+type Factory struct {
+	// Options are applied to all the clients on Setup.
+	Options []Option
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates the clients of the plugin config.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfgs map[string]Config
+	if err := dec.Decode(&cfgs); err != nil {
+		return err
+	}
+	clients := make(map[string]*Client, len(cfgs))
+	for client, cfg := range cfgs {
+		c, err := New(cfg, f.Options...)
+		if err != nil {
+			return fmt.Errorf("httpclient %s: %w", client, err)
+		}
+		clients[client] = c
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clients = clients
+	return nil
+}
+
+// FlexDependsOn sets up the tracing plugin first if configured, used by the clients with tracing.
+func (f *Factory) FlexDependsOn() []string {
+	return []string{"tracing-default"}
+}
+
+// Close closes the idle connections of all the clients.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.clients {
+		_ = c.Close()
+	}
+	f.clients = nil
+	return nil
+}
+
+// GetClient returns the configured client, nil if not found.
+func GetClient(name string) *Client {
+	DefaultFactory.mu.RLock()
+	defer DefaultFactory.mu.RUnlock()
+	return DefaultFactory.clients[name]
+}
@@ -0,0 +1,162 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/baisiyi/go-kits/log"
+	"github.com/baisiyi/go-kits/retry"
+)
+
+// HeaderIdempotencyKey marks a non-idempotent request safe to retry.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// maxDrainBytes is the max body read before closing a retried response, so the
+// connection can be reused.
+const maxDrainBytes = 4 << 10
+
+// StatusError is the error of an attempt answered with a retried status code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("httpclient: status %d", e.StatusCode)
+}
+
+// transport logs and observes the requests, retrying each by the retry policy.
+type transport struct {
+	c *Client
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.c
+	if len(c.cfg.Headers) > 0 {
+		req = req.Clone(req.Context())
+		for k, v := range c.cfg.Headers {
+			if req.Header.Get(k) == "" {
+				req.Header.Set(k, v)
+			}
+		}
+	}
+	start := time.Now()
+	resp, err := t.roundTripRetry(req)
+	elapsed := time.Since(start)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.log(req, status, elapsed, err)
+	if c.observer != nil {
+		c.observer.ObserveRequest(req.Method, req.URL.Host, status, elapsed, err)
+	}
+	return resp, err
+}
+
+func (t *transport) log(req *http.Request, status int, elapsed time.Duration, err error) {
+	logger := log.WithContextFields(t.c.logger, req.Context())
+	u := *req.URL
+	u.RawQuery, u.User = "", nil // the query and the user may carry credentials
+	switch {
+	case err != nil:
+		logger.Errorf("[HTTP_ERROR] %s %s | Elapsed: %v | Error: %v", req.Method, u.String(), elapsed, err)
+	case status >= http.StatusInternalServerError:
+		logger.Errorf("[HTTP_ERROR] %s %s | Status: %d | Elapsed: %v", req.Method, u.String(), status, elapsed)
+	case t.c.cfg.SlowThreshold > 0 && elapsed > t.c.cfg.SlowThreshold:
+		logger.Warnf("[HTTP_SLOW] %s %s | Status: %d | Elapsed: %v > %v", req.Method, u.String(), status, elapsed, t.c.cfg.SlowThreshold)
+	default:
+		logger.Debugf("[HTTP] %s %s | Status: %d | Elapsed: %v", req.Method, u.String(), status, elapsed)
+	}
+}
+
+func (t *transport) roundTripRetry(req *http.Request) (*http.Response, error) {
+	rc := t.c.cfg.Retry
+	if rc.Attempts <= 1 || !retryableRequest(req) {
+		return t.attempt(req)
+	}
+	var (
+		resp    *http.Response
+		attempt int
+	)
+	err := retry.Do(req.Context(), func(ctx context.Context) error {
+		attempt++
+		r := req
+		if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return retry.Unrecoverable(err)
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+		var err error
+		resp, err = t.attempt(r)
+		if err != nil {
+			if errors.Is(err, ErrCircuitOpen) {
+				return retry.Unrecoverable(err)
+			}
+			return err
+		}
+		if attempt < rc.Attempts && slices.Contains(rc.RetryStatus, resp.StatusCode) {
+			_, _ = io.CopyN(io.Discard, resp.Body, maxDrainBytes)
+			_ = resp.Body.Close()
+			err := &StatusError{StatusCode: resp.StatusCode}
+			resp = nil
+			return err
+		}
+		return nil
+	},
+		retry.Attempts(rc.Attempts),
+		retry.ExponentialBackoff(rc.BaseDelay, rc.MaxDelay),
+		retry.Jitter(),
+		retry.OnRetry(func(attempt int, err error, delay time.Duration) {
+			log.WithContextFields(t.c.logger, req.Context()).Warnf("[HTTP_RETRY] Attempt: %d/%d | Delay: %v | Error: %v | %s %s",
+				attempt, rc.Attempts, delay, err, req.Method, req.URL.Host+req.URL.Path)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// attempt sends the request once through the circuit breaker of the host.
+func (t *transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.c.breakers == nil {
+		return t.c.base.RoundTrip(req)
+	}
+	b := t.c.breakers.get(req.URL.Host)
+	if !b.allow(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, req.URL.Host)
+	}
+	resp, err := t.c.base.RoundTrip(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// canceled by the caller, not a failure of the host
+		b.release()
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		b.record(time.Now(), false)
+	default:
+		b.record(time.Now(), true)
+	}
+	return resp, err
+}
+
+// retryableRequest reports whether the request is idempotent and its body can be resent.
+func retryableRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get(HeaderIdempotencyKey) == "" {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
@@ -0,0 +1,219 @@
+/*
+idgen ID 生成器，提供 Snowflake、ULID 和短 ID 三种实现
+*/
+
+package idgen
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/baisiyi/go-kits/contextkit"
+	"github.com/baisiyi/go-kits/redis"
+)
+
+const (
+	TypeSnowflake = "snowflake"
+	TypeULID      = "ulid"
+	TypeShortID   = "shortid"
+
+	// NodeStatic uses Config.Node.
+	NodeStatic = "static"
+	// NodeEnv reads the node id from the env Config.NodeEnv.
+	NodeEnv = "env"
+	// NodeIP derives the node id from the low bits of the first private IPv4 address.
+	NodeIP = "ip"
+	// NodeRedis allocates a free node id in redis, see AllocateNode.
+	NodeRedis = "redis"
+)
+
+// Generator generates unique ids.
+type Generator interface {
+	Generate() (string, error)
+}
+
+// Config is the configuration of the idgen plugin.
+type Config struct {
+	// Type is snowflake, ulid or shortid, default as snowflake.
+	Type string `yaml:"type" mapstructure:"type"`
+	// NodeStrategy is how the snowflake node id is assigned: static, env, ip or redis, default as static.
+	NodeStrategy string `yaml:"node_strategy" mapstructure:"node_strategy"`
+	// Node is the node id of the static strategy.
+	Node int64 `yaml:"node" mapstructure:"node"`
+	// NodeEnv is the env name of the env strategy, default as NODE_ID.
+	NodeEnv string `yaml:"node_env" mapstructure:"node_env"`
+	// RedisClient is the client name of the redis plugin used by the redis strategy, default as default.
+	RedisClient string `yaml:"redis_client" mapstructure:"redis_client"`
+	// NodeKeyPrefix is the key prefix of the nodes allocated by the redis strategy, default as idgen:node:.
+	NodeKeyPrefix string `yaml:"node_key_prefix" mapstructure:"node_key_prefix"`
+	// NodeTTL is the ttl of the nodes allocated by the redis strategy, renewed every NodeTTL/3, default as 30s.
+	NodeTTL time.Duration `yaml:"node_ttl" mapstructure:"node_ttl"`
+	// Epoch is the snowflake epoch, default as DefaultEpoch.
+	Epoch time.Time `yaml:"epoch" mapstructure:"epoch"`
+	// ClockTolerance is the max clock backwards waited out by snowflake, default as 10ms.
+	ClockTolerance time.Duration `yaml:"clock_tolerance" mapstructure:"clock_tolerance"`
+	// ShortIDLength is the length of the short ids, default as 10.
+	ShortIDLength int `yaml:"shortid_length" mapstructure:"shortid_length"`
+}
+
+func (c *Config) setDefaults() {
+	if c.Type == "" {
+		c.Type = TypeSnowflake
+	}
+	if c.NodeStrategy == "" {
+		c.NodeStrategy = NodeStatic
+	}
+	if c.NodeEnv == "" {
+		c.NodeEnv = "NODE_ID"
+	}
+	if c.RedisClient == "" {
+		c.RedisClient = "default"
+	}
+	if c.NodeKeyPrefix == "" {
+		c.NodeKeyPrefix = "idgen:node:"
+	}
+	if c.NodeTTL <= 0 {
+		c.NodeTTL = 30 * time.Second
+	}
+	if c.ClockTolerance <= 0 {
+		c.ClockTolerance = 10 * time.Millisecond
+	}
+	if c.ShortIDLength <= 0 {
+		c.ShortIDLength = 10
+	}
+}
+
+// New creates the generator by cfg.
+func New(cfg Config) (Generator, error) {
+	cfg.setDefaults()
+	switch cfg.Type {
+	case TypeSnowflake:
+		if cfg.NodeStrategy == NodeRedis {
+			return newRedisSnowflake(cfg)
+		}
+		node, err := ResolveNode(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSnowflake(node, cfg.Epoch, cfg.ClockTolerance)
+	case TypeULID:
+		return ULID{}, nil
+	case TypeShortID:
+		return NewShortID(cfg.ShortIDLength), nil
+	default:
+		return nil, fmt.Errorf("idgen: unknown type %s", cfg.Type)
+	}
+}
+
+// ResolveNode returns the snowflake node id by the node strategy of cfg.
+func ResolveNode(cfg Config) (int64, error) {
+	cfg.setDefaults()
+	switch cfg.NodeStrategy {
+	case NodeStatic:
+		return cfg.Node, nil
+	case NodeEnv:
+		v := os.Getenv(cfg.NodeEnv)
+		if v == "" {
+			return 0, fmt.Errorf("idgen: env %s empty", cfg.NodeEnv)
+		}
+		node, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("idgen: env %s invalid: %w", cfg.NodeEnv, err)
+		}
+		return node, nil
+	case NodeIP:
+		return nodeFromIP()
+	case NodeRedis:
+		return 0, errors.New("idgen: node strategy redis allocates a lease, use New or AllocateNode")
+	default:
+		return 0, fmt.Errorf("idgen: unknown node strategy %s", cfg.NodeStrategy)
+	}
+}
+
+// newRedisSnowflake allocates the node with the client of the redis plugin.
+func newRedisSnowflake(cfg Config) (*Snowflake, error) {
+	client := redis.GetClient(cfg.RedisClient)
+	if client == nil {
+		return nil, fmt.Errorf("idgen: redis client %s not found", cfg.RedisClient)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), client.Config().DialTimeout)
+	defer cancel()
+	lease, err := AllocateNode(ctx, client, cfg.NodeKeyPrefix, cfg.NodeTTL)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewLeasedSnowflake(lease, cfg.Epoch, cfg.ClockTolerance)
+	if err != nil {
+		_ = lease.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func nodeFromIP() (int64, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return 0, fmt.Errorf("idgen: list interface addrs error: %w", err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip := ipNet.IP.To4(); ip != nil && ip.IsPrivate() {
+			return ipNode(ip), nil
+		}
+	}
+	return 0, errors.New("idgen: no private ipv4 address found")
+}
+
+// ipNode takes the low 10 bits of the ip, unique within a /22 network.
+func ipNode(ip net.IP) int64 {
+	return (int64(ip[2])<<8 | int64(ip[3])) & MaxNode
+}
+
+// ULID generates the monotonic ULIDs of contextkit.NewULID.
+type ULID struct{}
+
+// Generate implements Generator.
+func (ULID) Generate() (string, error) {
+	return contextkit.NewULID(), nil
+}
+
+// shortIDAlphabet is the base62 alphabet of the short ids.
+const shortIDAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ShortID generates random base62 ids of a fixed length, e.g. for share links.
+// They are not ordered; 10 chars give about 59 bits of randomness.
+type ShortID struct {
+	length int
+}
+
+// NewShortID creates a short id generator.
+func NewShortID(length int) *ShortID {
+	return &ShortID{length: length}
+}
+
+// Generate implements Generator.
+func (s *ShortID) Generate() (string, error) {
+	buf := make([]byte, s.length)
+	out := make([]byte, 0, s.length)
+	for len(out) < s.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("idgen: read random error: %w", err)
+		}
+		for _, b := range buf {
+			// 拒绝采样，避免取模带来的分布偏差
+			if b < 248 && len(out) < s.length {
+				out = append(out, shortIDAlphabet[b%62])
+			}
+		}
+	}
+	return string(out), nil
+}
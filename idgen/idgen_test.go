@@ -0,0 +1,155 @@
+package idgen
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// TestSnowflake tests the monotonicity and the layout of the snowflake ids.
+func TestSnowflake(t *testing.T) {
+	s, err := NewSnowflake(7, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("NewSnowflake failed: %v", err)
+	}
+	var last int64
+	for i := 0; i < 10000; i++ {
+		id, err := s.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if id <= last {
+			t.Fatalf("id %d not greater than %d", id, last)
+		}
+		last = id
+	}
+	ts, node, _ := s.Parse(last)
+	if node != 7 || time.Since(ts) > time.Minute {
+		t.Errorf("Parse = %v, %d", ts, node)
+	}
+	if _, err := NewSnowflake(MaxNode+1, time.Time{}, 0); err == nil {
+		t.Error("Expected error for node out of range")
+	}
+}
+
+// TestSnowflakeClockBackwards tests the clock backwards protection.
+func TestSnowflakeClockBackwards(t *testing.T) {
+	s, _ := NewSnowflake(1, time.Time{}, 0)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	if _, err := s.Next(); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	now = now.Add(-time.Second)
+	if _, err := s.Next(); !errors.Is(err, ErrClockBackwards) {
+		t.Errorf("Expected ErrClockBackwards, got %v", err)
+	}
+}
+
+// TestResolveNode tests the node strategies.
+func TestResolveNode(t *testing.T) {
+	t.Setenv("TEST_NODE_ID", "42")
+	if node, err := ResolveNode(Config{NodeStrategy: NodeEnv, NodeEnv: "TEST_NODE_ID"}); err != nil || node != 42 {
+		t.Errorf("env node = %d, %v", node, err)
+	}
+	if _, err := ResolveNode(Config{NodeStrategy: NodeEnv, NodeEnv: "TEST_NODE_MISSING"}); err == nil {
+		t.Error("Expected error for missing env")
+	}
+	if node := ipNode(net.IPv4(10, 0, 3, 7).To4()); node != 3<<8|7 {
+		t.Errorf("ip node = %d", node)
+	}
+}
+
+// TestNew tests the generators created by config.
+func TestNew(t *testing.T) {
+	for _, typ := range []string{TypeSnowflake, TypeULID, TypeShortID} {
+		g, err := New(Config{Type: typ})
+		if err != nil {
+			t.Fatalf("New(%s) failed: %v", typ, err)
+		}
+		a, _ := g.Generate()
+		b, _ := g.Generate()
+		if a == "" || a == b {
+			t.Errorf("%s generated %q and %q", typ, a, b)
+		}
+	}
+	g, _ := New(Config{Type: TypeShortID, ShortIDLength: 8})
+	if id, _ := g.Generate(); len(id) != 8 {
+		t.Errorf("short id %q length %d, want 8", id, len(id))
+	}
+	if _, err := New(Config{Type: "uuid"}); err == nil {
+		t.Error("Expected error for unknown type")
+	}
+}
+
+// TestAllocateNode tests allocating, renewing, losing and releasing the redis nodes.
+func TestAllocateNode(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	a, err := AllocateNode(ctx, client, "test:node:", time.Minute)
+	if err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+	b, err := AllocateNode(ctx, client, "test:node:", time.Minute)
+	if err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+	if a.Node() == b.Node() {
+		t.Fatalf("Both leases allocated node %d", a.Node())
+	}
+
+	s, err := NewLeasedSnowflake(a, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("NewLeasedSnowflake failed: %v", err)
+	}
+	id, err := s.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if _, node, _ := s.Parse(id); node != a.Node() {
+		t.Errorf("id node = %d, want %d", node, a.Node())
+	}
+
+	// the expired key is taken again on renew
+	mr.Del(a.key)
+	a.renew()
+	if err := a.Err(); err != nil || !mr.Exists(a.key) {
+		t.Errorf("Expected the lease taken again, got %v", err)
+	}
+
+	// not renewed within the ttl
+	a.mu.Lock()
+	a.renewedAt = time.Now().Add(-time.Minute)
+	a.mu.Unlock()
+	if _, err := s.Next(); !errors.Is(err, ErrNodeLost) {
+		t.Errorf("Expected ErrNodeLost for the lease expired, got %v", err)
+	}
+
+	// taken by another process
+	_ = mr.Set(b.key, "other")
+	b.renew()
+	if err := b.Err(); !errors.Is(err, ErrNodeLost) {
+		t.Errorf("Expected ErrNodeLost for the lease taken, got %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if v, _ := mr.Get(b.key); v != "other" {
+		t.Errorf("Close released the node of another process")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if mr.Exists(a.key) {
+		t.Error("Expected the node released on Close")
+	}
+}
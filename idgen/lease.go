@@ -0,0 +1,171 @@
+package idgen
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ErrNodeLost is returned by the snowflake of a NodeLease not renewed within its TTL,
+// the node id may have been allocated to another process.
+var ErrNodeLost = errors.New("idgen: node lease lost")
+
+// renewScript extends the lease if it is still owned by the holder, or takes it
+// again if it has expired and not been allocated to another process.
+var renewScript = goredis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if v == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+if v == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0`)
+
+// releaseScript deletes the lease if it is still owned by the holder.
+var releaseScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+
+// NodeLease is a snowflake node id allocated in redis. The key prefix+node is held
+// with a TTL and renewed in background every TTL/3 until Close.
+type NodeLease struct {
+	client goredis.Cmdable
+	key    string
+	value  string
+	node   int64
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	renewedAt time.Time
+	lost      bool
+
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// AllocateNode allocates a free node id in [0, MaxNode] by SET NX on prefix+node,
+// starting from a random node so that the processes started together rarely collide.
+func AllocateNode(ctx context.Context, client goredis.Cmdable, prefix string, ttl time.Duration) (*NodeLease, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("idgen: node ttl %s must be positive", ttl)
+	}
+	value, err := leaseValue()
+	if err != nil {
+		return nil, err
+	}
+	start, err := rand.Int(rand.Reader, big.NewInt(MaxNode+1))
+	if err != nil {
+		return nil, fmt.Errorf("idgen: read random error: %w", err)
+	}
+	for i := int64(0); i <= MaxNode; i++ {
+		node := (start.Int64() + i) % (MaxNode + 1)
+		key := fmt.Sprintf("%s%d", prefix, node)
+		ok, err := client.SetNX(ctx, key, value, ttl).Result()
+		if err != nil {
+			return nil, fmt.Errorf("idgen: allocate node error: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		l := &NodeLease{
+			client:    client,
+			key:       key,
+			value:     value,
+			node:      node,
+			ttl:       ttl,
+			renewedAt: time.Now(),
+			done:      make(chan struct{}),
+			stopped:   make(chan struct{}),
+		}
+		go l.run()
+		return l, nil
+	}
+	return nil, fmt.Errorf("idgen: all %d nodes allocated", MaxNode+1)
+}
+
+// leaseValue identifies the holder, the random suffix tells apart the restarts of a pid.
+func leaseValue() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("idgen: read random error: %w", err)
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b)), nil
+}
+
+// Node returns the allocated node id.
+func (l *NodeLease) Node() int64 {
+	return l.node
+}
+
+// Err returns ErrNodeLost if the lease is taken by another process or has not been
+// renewed within the TTL, e.g. redis unreachable.
+func (l *NodeLease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lost || time.Since(l.renewedAt) >= l.ttl {
+		return ErrNodeLost
+	}
+	return nil
+}
+
+func (l *NodeLease) run() {
+	defer close(l.stopped)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			l.renew()
+		}
+	}
+}
+
+func (l *NodeLease) renew() {
+	ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+	defer cancel()
+	ok, err := renewScript.Run(ctx, l.client, []string{l.key}, l.value, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		// 续期失败时保留原状态，超过 TTL 未续期由 Err 判定为丢失
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ok == 1 && !l.lost {
+		l.renewedAt = time.Now()
+		return
+	}
+	// 已被其他进程占用，即使之后释放也不再使用，避免与其生成的 id 重复
+	l.lost = true
+}
+
+// Close stops renewing and releases the node.
+func (l *NodeLease) Close() error {
+	var err error
+	l.once.Do(func() {
+		close(l.done)
+		<-l.stopped
+		ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+		defer cancel()
+		if rerr := releaseScript.Run(ctx, l.client, []string{l.key}, l.value).Err(); rerr != nil {
+			err = fmt.Errorf("idgen: release node %d error: %w", l.node, rerr)
+		}
+	})
+	return err
+}
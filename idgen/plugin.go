@@ -0,0 +1,90 @@
+package idgen
+
+import (
+	"sync"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "idgen"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+var (
+	mu               sync.RWMutex
+	defaultGenerator Generator = ULID{}
+)
+
+// Default returns the generator set up by the idgen plugin, ULID if not set up.
+func Default() Generator {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultGenerator
+}
+
+// SetDefault sets the default generator.
+func SetDefault(g Generator) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultGenerator = g
+}
+
+// NewID generates an id with the default generator.
+func NewID() (string, error) {
+	return Default().Generate()
+}
+
+// DefaultFactory is the idgen plugin factory registered as idgen-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of idgen, the node id is assigned by its config.
+type Factory struct {
+	mu        sync.Mutex
+	snowflake *Snowflake
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates the default generator by the plugin config.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	g, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	SetDefault(g)
+	if s, ok := g.(*Snowflake); ok {
+		f.mu.Lock()
+		f.snowflake = s
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// FlexDependsOn sets up the redis plugin first if configured, used by the redis node strategy.
+func (f *Factory) FlexDependsOn() []string {
+	return []string{"redis-default"}
+}
+
+// Close releases the node allocated by the redis node strategy.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.snowflake == nil {
+		return nil
+	}
+	err := f.snowflake.Close()
+	f.snowflake = nil
+	return err
+}
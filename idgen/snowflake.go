@@ -0,0 +1,136 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+	// MaxNode is the max node id of the snowflake generator.
+	MaxNode     = 1<<nodeBits - 1
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// DefaultEpoch is the default snowflake epoch, 2024-01-01 UTC.
+var DefaultEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrClockBackwards is returned when the clock moves backwards more than the tolerance.
+var ErrClockBackwards = errors.New("idgen: clock moved backwards")
+
+// Snowflake generates 64 bits ids: 41 bits milliseconds since epoch, 10 bits
+// node id and 12 bits sequence, monotonically increasing per node.
+type Snowflake struct {
+	node      int64
+	epoch     int64 // ms
+	tolerance time.Duration
+	now       func() time.Time
+	lease     *NodeLease
+
+	mu       sync.Mutex
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflake creates a snowflake generator of the node. epoch zero means
+// DefaultEpoch. A clock moving backwards within tolerance is waited out,
+// beyond it Next returns ErrClockBackwards.
+func NewSnowflake(node int64, epoch time.Time, tolerance time.Duration) (*Snowflake, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("idgen: node %d out of range [0, %d]", node, MaxNode)
+	}
+	if epoch.IsZero() {
+		epoch = DefaultEpoch
+	}
+	return &Snowflake{
+		node:      node,
+		epoch:     epoch.UnixMilli(),
+		tolerance: tolerance,
+		now:       time.Now,
+	}, nil
+}
+
+// Node returns the node id.
+func (s *Snowflake) Node() int64 {
+	return s.node
+}
+
+// NewLeasedSnowflake creates a snowflake generator of the node allocated by lease,
+// Next returns ErrNodeLost once the lease is lost.
+func NewLeasedSnowflake(lease *NodeLease, epoch time.Time, tolerance time.Duration) (*Snowflake, error) {
+	s, err := NewSnowflake(lease.Node(), epoch, tolerance)
+	if err != nil {
+		return nil, err
+	}
+	s.lease = lease
+	return s, nil
+}
+
+// Close releases the node lease if any.
+func (s *Snowflake) Close() error {
+	if s.lease == nil {
+		return nil
+	}
+	return s.lease.Close()
+}
+
+// Next returns the next id.
+func (s *Snowflake) Next() (int64, error) {
+	if s.lease != nil {
+		if err := s.lease.Err(); err != nil {
+			return 0, err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := s.now().UnixMilli() - s.epoch
+	if ms < s.lastMs {
+		// 时钟回拨：在容忍范围内等待追上，否则报错，避免生成重复 id
+		back := time.Duration(s.lastMs-ms) * time.Millisecond
+		if back > s.tolerance {
+			return 0, fmt.Errorf("%w by %s", ErrClockBackwards, back)
+		}
+		time.Sleep(back)
+		ms = s.waitAfter(s.lastMs - 1)
+	}
+	if ms == s.lastMs {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 {
+			// 当前毫秒序列号用尽，等待下一毫秒
+			ms = s.waitAfter(s.lastMs)
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.lastMs = ms
+	return ms<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.sequence, nil
+}
+
+func (s *Snowflake) waitAfter(last int64) int64 {
+	ms := s.now().UnixMilli() - s.epoch
+	for ms <= last {
+		time.Sleep(100 * time.Microsecond)
+		ms = s.now().UnixMilli() - s.epoch
+	}
+	return ms
+}
+
+// Generate implements Generator, returning the decimal id.
+func (s *Snowflake) Generate() (string, error) {
+	id, err := s.Next()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// Parse splits a snowflake id into its time, node and sequence.
+func (s *Snowflake) Parse(id int64) (t time.Time, node, sequence int64) {
+	ms := id >> (nodeBits + sequenceBits)
+	return time.UnixMilli(ms + s.epoch), id >> sequenceBits & MaxNode, id & maxSequence
+}
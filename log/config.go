@@ -1,13 +1,40 @@
 package log
 
+import (
+	"os"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+)
+
 const (
 	OutputConsole = "console"
 	OutputFile    = "file"
+	OutputSyslog  = "syslog"
+	OutputKafka   = "kafka"
+	OutputOTel    = "otel"
 
 	FormatterConsole = "console"
 	FormatterJson    = "json"
+	// FormatterDev is the aligned and colorized format for reading the logs in the terminal
+	// during the local development, not for parsing.
+	FormatterDev = "dev"
 
 	DefaultLogFileName = "ap.log"
+
+	// WriteModeSync writes the logs to the file synchronously.
+	WriteModeSync = "sync"
+	// WriteModeAsync queues the logs and writes them to the file in background.
+	WriteModeAsync = "async"
+
+	// RotatorNative rotates the log files by rollwriter.RollWriter.
+	RotatorNative = "native"
+	// RotatorRotateLogs is the alias of RotatorNative, which replaced file-rotatelogs
+	// with the same time suffix naming.
+	RotatorRotateLogs = "rotatelogs"
+	// RotatorLumberjack rotates the log files by lumberjack, with its size based
+	// semantics and backup names like app-2006-01-02T15-04-05.000.log.
+	RotatorLumberjack = "lumberjack"
 )
 
 var defaultConfig = []OutputConfig{{
@@ -19,6 +46,11 @@ var defaultConfig = []OutputConfig{{
 type Config []OutputConfig
 
 type OutputConfig struct {
+	// Name is the name of the output used by SetLevel and GetLevels, unique among the
+	// outputs. Default as the writer name, suffixed by .1, .2 and so on in order if
+	// several outputs use the writer, like file.1 and file.2.
+	Name string `yaml:"name" mapstructure:"name"`
+
 	// Writer is the output of log, such as console or file.
 	Writer      string      `yaml:"writer" mapstructure:"writer"`
 	WriteConfig WriteConfig `yaml:"writer_config" mapstructure:"writer_config"`
@@ -30,15 +62,142 @@ type OutputConfig struct {
 	// Level controls the log level, like debug, info or error.
 	Level string `yaml:"level" mapstructure:"level"`
 
+	// MinLevel and MaxLevel limit the output to the entries in the level range besides
+	// Level, e.g. max_level warn for app.log and min_level error for app.error.log.
+	// Unlike Level they are not changed by SetLevel, empty means no limit.
+	MinLevel string `yaml:"min_level" mapstructure:"min_level"`
+	MaxLevel string `yaml:"max_level" mapstructure:"max_level"`
+
+	// StacktraceLevel adds the stacktraces to the entries of the level and above, like
+	// error or panic. Empty or off disables the stacktraces of the output.
+	StacktraceLevel string `yaml:"stacktrace_level" mapstructure:"stacktrace_level"`
+
+	// Levels override Level for the loggers created by Named, keyed by the logger name
+	// like {"dao": "warn", "http.access": "info"}. A name also applies to its children
+	// split by dots, "http" applies to "http.access" unless "http.access" is set too.
+	// Unlike Level they are not changed by SetLevel.
+	Levels map[string]string `yaml:"levels" mapstructure:"levels"`
+
 	// CallerSkip controls the nesting depth of log function.
 	CallerSkip int `yaml:"caller_skip" mapstructure:"caller_skip"`
 
 	// EnableColor determines if the output is colored. The default value is false.
 	EnableColor bool `yaml:"enable_color" mapstructure:"enable_color"`
+
+	// SyslogConfig is the config of the syslog writer.
+	SyslogConfig SyslogConfig `yaml:"syslog_config" mapstructure:"syslog_config"`
+
+	// KafkaConfig is the config of the kafka writer.
+	KafkaConfig KafkaConfig `yaml:"kafka_config" mapstructure:"kafka_config"`
+
+	// OTelConfig is the config of the opentelemetry writer.
+	OTelConfig OTelConfig `yaml:"otel_config" mapstructure:"otel_config"`
+
+	// Sampling drops the repeated entries of the output, nil disables sampling.
+	Sampling *SamplingConfig `yaml:"sampling" mapstructure:"sampling"`
+
+	// RateLimit limits the entries per key of the output, nil disables it. Unlike
+	// Sampling it limits the entries of a key whatever the level, so that the loops
+	// logging an error can not flood the disk or the collector.
+	RateLimit *RateLimitConfig `yaml:"rate_limit" mapstructure:"rate_limit"`
+
+	// Mask redacts the sensitive values of the output before encoding, nil disables it.
+	Mask *MaskConfig `yaml:"mask" mapstructure:"mask"`
+
+	// Dedup collapses the consecutive repeated entries of the output, nil disables it.
+	Dedup *DedupConfig `yaml:"dedup" mapstructure:"dedup"`
+
+	// Fallback writes the entries to another target when the writes of the output keep
+	// failing, nil disables it. The write errors are isolated whether or not it is set:
+	// they are reported once per failure streak instead of once per entry.
+	Fallback *FallbackConfig `yaml:"fallback" mapstructure:"fallback"`
+
+	// Hooks are the names of the hooks registered by RegisterHook, called with every
+	// entry written by the output. The entries dropped by sampling or rate limit are not hooked.
+	Hooks []string `yaml:"hooks" mapstructure:"hooks"`
+	// HookFuncs are the hooks of the output set in code, called after Hooks.
+	HookFuncs []Hook `yaml:"-" mapstructure:"-"`
+
+	// Fields are the static fields added to every entry of the output, such as the
+	// service name, env and version. The values are expanded by the environment
+	// variables like ${POD_NAME}, the fields empty after expansion are dropped.
+	Fields map[string]string `yaml:"fields" mapstructure:"fields"`
+}
+
+// SamplingConfig is the sampling config of an output, see zapcore.NewSamplerWithOptions.
+// In every Tick, the first Initial entries with the same level and message are logged,
+// then every Thereafter-th entry is logged and the others are dropped.
+type SamplingConfig struct {
+	// Initial is the number of entries logged per tick, default as 100.
+	Initial int `yaml:"initial" mapstructure:"initial"`
+	// Thereafter logs every Thereafter-th entry after Initial, 0 drops all of them.
+	Thereafter int `yaml:"thereafter" mapstructure:"thereafter"`
+	// Tick is the sampling interval, default as 1s.
+	Tick time.Duration `yaml:"tick" mapstructure:"tick"`
+}
+
+// RateLimitConfig is the rate limit config of an output. The entries of a key beyond
+// Rate in any Interval are dropped, the panic and fatal entries are never dropped.
+type RateLimitConfig struct {
+	// Rate is the max number of entries per key in an Interval.
+	Rate int `yaml:"rate" mapstructure:"rate"`
+	// Interval is the sliding window of Rate, default as 1s.
+	Interval time.Duration `yaml:"interval" mapstructure:"interval"`
+	// KeyField is the field keying the entries, e.g. error_code. The entries without
+	// the field and all the entries if empty are keyed by the message.
+	KeyField string `yaml:"key_field" mapstructure:"key_field"`
+	// MaxKeys is the max number of keys tracked, the least recently used ones are
+	// evicted when exceeded, default as 10000.
+	MaxKeys int `yaml:"max_keys" mapstructure:"max_keys"`
+}
+
+// DedupConfig is the deduplication config of an output. The consecutive entries
+// with the same level, logger name and message within Window after the first one
+// are dropped, and summarized by a "last message repeated N times" entry with the
+// fields repeated_message and repeated. The fields are not compared, and the panic
+// and fatal entries are never dropped.
+type DedupConfig struct {
+	// Window is how long the repeated entries are collapsed after the first one,
+	// the summary is written when it ends, default as 10s.
+	Window time.Duration `yaml:"window" mapstructure:"window"`
+}
+
+// FallbackConfig is the fallback config of an output. After the writes of the output
+// keep failing for After, the entries are written to Target instead, and the output is
+// retried every RetryInterval until a write succeeds. The entries failing within After
+// are lost.
+type FallbackConfig struct {
+	// Target is the console target written to, stdout, stderr or a target registered
+	// by RegisterWriterTarget, default as stderr.
+	Target string `yaml:"target" mapstructure:"target"`
+	// After is how long the writes keep failing before switching, default as 10s.
+	After time.Duration `yaml:"after" mapstructure:"after"`
+	// RetryInterval is the interval of retrying the output after switching, default as 5s.
+	RetryInterval time.Duration `yaml:"retry_interval" mapstructure:"retry_interval"`
+}
+
+// MaskConfig is the masking config of an output. The masked values are replaced
+// before the entries are encoded, so they never reach the writer.
+type MaskConfig struct {
+	// Fields are the keys of the fields replaced as a whole whatever the type, like
+	// password or token, matched case-insensitively.
+	Fields []string `yaml:"fields" mapstructure:"fields"`
+	// Patterns are the regexps replaced in the message and the string, []byte and
+	// error fields, like card numbers. The fields of other types, e.g. by Any, are
+	// masked by Fields only.
+	Patterns []string `yaml:"patterns" mapstructure:"patterns"`
+	// Builtin are the names of the built-in patterns applied like Patterns: phone,
+	// email, id_card and bank_card.
+	Builtin []string `yaml:"builtin" mapstructure:"builtin"`
+	// Replacement replaces the masked values, default as ***.
+	Replacement string `yaml:"replacement" mapstructure:"replacement"`
 }
 
 // WriteConfig is the local file config.
 type WriteConfig struct {
+	// Target is the target of the console writer: stdout, stderr or a name registered
+	// by RegisterWriterTarget, default as stdout.
+	Target string `yaml:"target"`
 	// LogPath is the log path like /usr/local/trpc/log/.
 	LogPath string `yaml:"log_path"`
 	// Filename is the file name like trpc.log.
@@ -49,19 +208,136 @@ type WriteConfig struct {
 	MaxBackups uint `yaml:"max_backups"`
 	// MaxSize is the max size of log file(MB).
 	MaxSize int64 `yaml:"max_size"`
+	// MaxDiskUsage is the max total size of the log directory(MB), counting the files and
+	// the backups of all the file outputs in the directory such as level_files, the oldest
+	// backups are removed when exceeded, 0 means no limit.
+	MaxDiskUsage int64 `yaml:"max_disk_usage"`
+	// FileMode is the permission of the log files like 0640 regardless of umask, the
+	// existing files are changed too. 0 means 0644 masked by umask.
+	FileMode os.FileMode `yaml:"file_mode"`
+	// DirMode is the permission of the log directories created like 0750 regardless of
+	// umask, the existing ones are kept. 0 means 0755 masked by umask.
+	DirMode os.FileMode `yaml:"dir_mode"`
+	// Rotator is the rotation backend of the file writer: native (rotatelogs) or
+	// lumberjack, default as native. Lumberjack ignores TimeFormat and does not
+	// support MaxDiskUsage.
+	Rotator string `yaml:"rotator"`
 	// RotationTime is the rotation time interval (minute).
 	RotationTime int `yaml:"rotation_time"`
+	// RotationAlign rotates at the calendar boundaries instead of RotationTime: hour at
+	// the top of every hour, day at every midnight, whatever the start time.
+	RotationAlign string `yaml:"rotation_align"`
+	// RotationTimezone is the IANA time zone of RotationAlign and the backup names, like
+	// UTC or Asia/Shanghai, default as the local time zone.
+	RotationTimezone string `yaml:"rotation_timezone"`
 	// TimeFormat is the time format for log file name.
 	// Default is ".%Y%m%d%H%M" (精确到分钟).
 	// Examples:
 	//   - ".%Y%m%d" -> app.log.20260211
 	//   - ".%Y%m%d%H" -> app.log.2026021122
 	TimeFormat string `yaml:"time_format"`
+	// WriteMode is sync or async, default as sync.
+	WriteMode string `yaml:"write_mode"`
+	// QueueSize is the number of logs queued in async mode, default as 10000.
+	QueueSize int `yaml:"queue_size"`
+	// DropOnFull drops the logs when the async queue is full instead of blocking.
+	DropOnFull bool `yaml:"drop_on_full"`
+	// BufferSize is the buffer size(byte) of the file writes, the logs are written when
+	// the buffer is full or every FlushInterval. 0 means no buffer in sync mode and 4KB
+	// in async mode.
+	BufferSize int `yaml:"buffer_size"`
+	// FlushInterval is the interval of writing the buffered logs, default as 1s in sync
+	// mode with BufferSize set and 100ms in async mode.
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// LevelFiles splits the output into a file per level instead of Filename, like
+	// {info: app.info.log, error: app.error.log}. A file gets the entries from its
+	// level to the next configured level, the entries below the lowest one are dropped.
+	// The bare file names are in the directory of Filename, all the files share the
+	// rotation and write mode options above.
+	LevelFiles map[string]string `yaml:"level_files"`
+}
+
+// SyslogConfig is the syslog writer config.
+type SyslogConfig struct {
+	// Network is tcp, udp or unix, empty means the local syslog daemon.
+	Network string `yaml:"network"`
+	// Address is the address of the syslog daemon like 127.0.0.1:514.
+	Address string `yaml:"address"`
+	// Facility is the syslog facility like user, daemon or local0-local7, default as user.
+	Facility string `yaml:"facility"`
+	// Tag is the syslog tag, default as the program name.
+	Tag string `yaml:"tag"`
+}
+
+// KafkaConfig is the kafka writer config, each log entry is sent as a message.
+type KafkaConfig struct {
+	// Brokers is the addresses of the kafka brokers.
+	Brokers []string `yaml:"brokers"`
+	// Topic is the topic of the log messages.
+	Topic string `yaml:"topic"`
+	// Key is the key of the log messages like the service name, empty means no key.
+	Key string `yaml:"key"`
+	// BatchSize is the max number of messages per batch, default as 100.
+	BatchSize int `yaml:"batch_size"`
+	// BatchBytes is the max bytes per batch, default as 1MB.
+	BatchBytes int64 `yaml:"batch_bytes"`
+	// BatchTimeout is the max time to wait before a batch is sent, default as 100ms.
+	BatchTimeout time.Duration `yaml:"batch_timeout"`
+	// Compression is one of gzip, snappy, lz4, zstd, empty means none.
+	Compression string `yaml:"compression"`
+	// RequiredAcks is one of none, one, all, default as one.
+	RequiredAcks string `yaml:"required_acks"`
+	// WriteTimeout is the timeout of write requests, default as 10s.
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// QueueSize is the number of log entries buffered before sent, default as 10000.
+	QueueSize int `yaml:"queue_size"`
+	// DropOnFull drops the logs when the queue is full instead of blocking.
+	DropOnFull bool `yaml:"drop_on_full"`
+}
+
+// OTelConfig is the config of the opentelemetry writer, which exports the logs to the
+// collector by OTLP over HTTP. The empty fields fall back to the OTEL_EXPORTER_OTLP_*
+// and OTEL_BLRP_* environment variables, then the defaults of the opentelemetry sdk.
+type OTelConfig struct {
+	// Endpoint is the host:port of the collector, default as localhost:4318.
+	Endpoint string `yaml:"endpoint"`
+	// URLPath is the path of the logs, default as /v1/logs.
+	URLPath string `yaml:"url_path"`
+	// Insecure sends the logs by http instead of https.
+	Insecure bool `yaml:"insecure"`
+	// Headers are sent with every export request, e.g. the authorization.
+	Headers map[string]string `yaml:"headers"`
+	// Compression is gzip or none, default as none.
+	Compression string `yaml:"compression"`
+	// Timeout is the timeout of an export, default as 10s.
+	Timeout time.Duration `yaml:"timeout"`
+	// BatchSize is the max number of records per export, default as 512.
+	BatchSize int `yaml:"batch_size"`
+	// QueueSize is the max number of records buffered, the records are dropped when
+	// the queue is full, default as 2048.
+	QueueSize int `yaml:"queue_size"`
+	// ExportInterval is the max wait before the buffered records are exported, default as 1s.
+	ExportInterval time.Duration `yaml:"export_interval"`
+	// ServiceName is the service.name of the resource, default as OTEL_SERVICE_NAME.
+	ServiceName string `yaml:"service_name"`
+
+	// LoggerProvider emits the records instead of the OTLP exporter above, e.g. the
+	// provider shared with the traces of the service.
+	LoggerProvider otellog.LoggerProvider `yaml:"-"`
+}
+
+func (c *OTelConfig) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 10 * time.Second
 }
 
 type FormatConfig struct {
 	// TimeFmt is the time format of log output, default as "2006-01-02 15:04:05.000" on empty.
 	TimeFmt string `yaml:"time_fmt"`
+	// TimeZone is the time zone of the timestamps, e.g. "UTC" or "Asia/Shanghai", default as Local.
+	TimeZone string `yaml:"time_zone"`
 
 	// TimeKey is the time key of log output, default as "T".
 	TimeKey string `yaml:"time_key"`
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextFieldExtractor extracts the request scoped fields (trace_id, user_id ...) from ctx.
+type ContextFieldExtractor func(ctx context.Context) []Field
+
+type namedExtractor struct {
+	name string
+	fn   ContextFieldExtractor
+}
+
+var (
+	extractorMu sync.RWMutex
+	extractors  []namedExtractor
+)
+
+// RegisterContextFieldExtractor registers the extractor called for every context log call.
+// Registering the same name again replaces the previous one, the extractors are
+// called in the order they are first registered.
+func RegisterContextFieldExtractor(name string, fn ContextFieldExtractor) {
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+	for i := range extractors {
+		if extractors[i].name == name {
+			extractors[i].fn = fn
+			return
+		}
+	}
+	extractors = append(extractors, namedExtractor{name: name, fn: fn})
+}
+
+// DeregisterContextFieldExtractor removes the extractor registered by name.
+func DeregisterContextFieldExtractor(name string) {
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+	for i := range extractors {
+		if extractors[i].name == name {
+			extractors = append(extractors[:i], extractors[i+1:]...)
+			return
+		}
+	}
+}
+
+// ContextFields returns the fields extracted from ctx by all the registered extractors.
+func ContextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	extractorMu.RLock()
+	defer extractorMu.RUnlock()
+	var fields []Field
+	for _, e := range extractors {
+		fields = append(fields, e.fn(ctx)...)
+	}
+	return fields
+}
+
+// WithContextFields returns l with the fields extracted from ctx, or l itself if there is none.
+func WithContextFields(l Logger, ctx context.Context) Logger {
+	if fields := ContextFields(ctx); len(fields) > 0 {
+		return l.With(fields...)
+	}
+	return l
+}
+
+// WithContext 创建带有 ctx 中请求字段的logger
+func WithContext(ctx context.Context) Logger {
+	return WithContextFields(GetDefaultLogger(), ctx)
+}
+
+// DebugContext 结构化 debug 日志，附带 ctx 中的请求字段
+func DebugContext(ctx context.Context, msg string, fields ...Field) {
+	GetDefaultLogger().Debug(msg, appendContextFields(ctx, fields)...)
+}
+
+// InfoContext 结构化 info 日志，附带 ctx 中的请求字段
+func InfoContext(ctx context.Context, msg string, fields ...Field) {
+	GetDefaultLogger().Info(msg, appendContextFields(ctx, fields)...)
+}
+
+// WarnContext 结构化 warn 日志，附带 ctx 中的请求字段
+func WarnContext(ctx context.Context, msg string, fields ...Field) {
+	GetDefaultLogger().Warn(msg, appendContextFields(ctx, fields)...)
+}
+
+// ErrorContext 结构化 error 日志，附带 ctx 中的请求字段
+func ErrorContext(ctx context.Context, msg string, fields ...Field) {
+	GetDefaultLogger().Error(msg, appendContextFields(ctx, fields)...)
+}
+
+func appendContextFields(ctx context.Context, fields []Field) []Field {
+	ctxFields := ContextFields(ctx)
+	if len(ctxFields) == 0 {
+		return fields
+	}
+	return append(ctxFields, fields...)
+}
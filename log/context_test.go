@@ -0,0 +1,55 @@
+package log
+
+import (
+	"context"
+	"testing"
+)
+
+type ctxKey struct{}
+
+// TestInfoContext tests that the registered extractors add fields to the context log calls.
+func TestInfoContext(t *testing.T) {
+	mock := &mockLogger{}
+	oldLogger := defaultLogger
+	SetDefault(mock)
+	defer SetDefault(oldLogger)
+
+	RegisterContextFieldExtractor("test", func(ctx context.Context) []Field {
+		if v, ok := ctx.Value(ctxKey{}).(string); ok {
+			return []Field{String("user_id", v)}
+		}
+		return nil
+	})
+	defer DeregisterContextFieldExtractor("test")
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "u1")
+	InfoContext(ctx, "hello", String("key", "value"))
+	if !mock.infoCalled || len(mock.lastFields) != 2 || mock.lastFields[0].Key != "user_id" {
+		t.Errorf("Expected user_id field first, got %v", mock.lastFields)
+	}
+
+	InfoContext(context.Background(), "hello", String("key", "value"))
+	if len(mock.lastFields) != 1 {
+		t.Errorf("Expected no extracted field, got %v", mock.lastFields)
+	}
+	if fields := ContextFields(nil); fields != nil {
+		t.Errorf("Expected no field of nil ctx, got %v", fields)
+	}
+}
+
+// TestRegisterContextFieldExtractor tests the replacement and removal of extractors.
+func TestRegisterContextFieldExtractor(t *testing.T) {
+	RegisterContextFieldExtractor("a", func(context.Context) []Field { return []Field{String("a", "1")} })
+	RegisterContextFieldExtractor("b", func(context.Context) []Field { return []Field{String("b", "1")} })
+	RegisterContextFieldExtractor("a", func(context.Context) []Field { return []Field{String("a", "2")} })
+	defer DeregisterContextFieldExtractor("b")
+
+	fields := ContextFields(context.Background())
+	if len(fields) != 2 || fields[0].Key != "a" || fields[0].String != "2" || fields[1].Key != "b" {
+		t.Errorf("Unexpected fields %v", fields)
+	}
+	DeregisterContextFieldExtractor("a")
+	if fields := ContextFields(context.Background()); len(fields) != 1 {
+		t.Errorf("Expected 1 field after deregister, got %v", fields)
+	}
+}
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// dedupCore collapses the consecutive entries with the same level, logger name and
+// message within the window: the first one is written, the repeated ones are counted
+// and summarized by a "last message repeated N times" entry when a different entry
+// comes, the window ends or the core is synced. Like rateLimitCore, the repeated
+// entries are dropped in Write so that the hooks and the sampling do not see them.
+type dedupCore struct {
+	zapcore.Core
+	state *dedupState // shared by the cores of With, the entries are consecutive per output
+}
+
+// dedupState is the last entry written and its repeated count.
+type dedupState struct {
+	window time.Duration
+
+	mu       sync.Mutex
+	last     zapcore.Entry
+	core     zapcore.Core // the core the last entry is written by, writing the summary
+	repeated int
+	lastTime time.Time // time of the last repeated entry
+	timer    *time.Timer
+	gen      int // incremented on every new entry, stopping the timers of the previous ones
+}
+
+// newDedupCore wraps core with the deduplication of the output config.
+func newDedupCore(core zapcore.Core, c *DedupConfig) zapcore.Core {
+	window := c.Window
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	return &dedupCore{Core: core, state: &dedupState{window: window}}
+}
+
+// With implements zapcore.Core.
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{Core: c.Core.With(fields), state: c.state}
+}
+
+// Check implements zapcore.Core.
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+	return ce.AddCore(ent, &dedupEntry{dedupCore: c, inner: inner})
+}
+
+// Sync implements zapcore.Core, writing the pending summary first.
+func (c *dedupCore) Sync() error {
+	c.state.flush(-1)
+	return c.Core.Sync()
+}
+
+// dedupEntry writes the checked entry of the wrapped core unless it is repeated.
+type dedupEntry struct {
+	*dedupCore
+	inner *zapcore.CheckedEntry
+}
+
+// Write implements zapcore.Core.
+func (e *dedupEntry) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	s := e.state
+	s.mu.Lock()
+	if ent.Level <= zapcore.ErrorLevel && s.repeats(ent) {
+		s.repeated++
+		s.lastTime = ent.Time
+		if s.timer == nil {
+			gen := s.gen
+			s.timer = time.AfterFunc(s.window-ent.Time.Sub(s.last.Time), func() { s.flush(gen) })
+		}
+		s.mu.Unlock()
+		return nil
+	}
+	summary := s.take()
+	s.last, s.core = ent, e.Core
+	s.mu.Unlock()
+
+	summary()
+	return writeChecked(e.inner, ent, fields)
+}
+
+// repeats reports whether ent repeats the last entry within the window.
+func (s *dedupState) repeats(ent zapcore.Entry) bool {
+	return s.core != nil && ent.Level == s.last.Level && ent.LoggerName == s.last.LoggerName &&
+		ent.Message == s.last.Message && ent.Time.Sub(s.last.Time) < s.window
+}
+
+// take resets the state and returns the func writing the summary of the repeated
+// entries, called after unlocking. It must be called with the lock held.
+func (s *dedupState) take() func() {
+	s.gen++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	last, core, repeated, lastTime := s.last, s.core, s.repeated, s.lastTime
+	s.last, s.core, s.repeated = zapcore.Entry{}, nil, 0
+	if repeated == 0 {
+		return func() {}
+	}
+	return func() {
+		ent := last
+		ent.Time = lastTime
+		ent.Message = fmt.Sprintf("last message repeated %d times", repeated)
+		ent.Stack = ""
+		if ce := core.Check(ent, nil); ce != nil {
+			ce.Write(zapcore.Field{Key: "repeated_message", Type: zapcore.StringType, String: last.Message},
+				zapcore.Field{Key: "repeated", Type: zapcore.Int64Type, Integer: int64(repeated)})
+		}
+	}
+}
+
+// flush writes the summary of the repeated entries of generation gen, -1 as any.
+func (s *dedupState) flush(gen int) {
+	s.mu.Lock()
+	if gen >= 0 && gen != s.gen {
+		s.mu.Unlock()
+		return
+	}
+	summary := s.take()
+	s.mu.Unlock()
+	summary()
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// syncBuffer is a bytes.Buffer safe for the summary written by the timer.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+// TestDedup tests that the consecutive repeated entries are collapsed and summarized
+// when a different entry comes, the window ends or the logger is synced.
+func TestDedup(t *testing.T) {
+	buf := &syncBuffer{}
+	RegisterWriterTarget("test-dedup", buf)
+	logger := NewZapLog(Config{{
+		Writer:      OutputConsole,
+		Level:       "info",
+		Formatter:   "json",
+		WriteConfig: WriteConfig{Target: "test-dedup"},
+		Dedup:       &DedupConfig{Window: 100 * time.Millisecond},
+	}})
+
+	for i := 0; i < 5; i++ {
+		logger.Error("connect failed")
+	}
+	logger.With(String("k", "v")).Error("connect failed")
+	logger.Info("recovered")
+	lines := buf.lines()
+	if len(lines) != 3 || !strings.Contains(lines[1], `"last message repeated 5 times"`) ||
+		!strings.Contains(lines[1], `"repeated_message":"connect failed"`) || !strings.Contains(lines[2], "recovered") {
+		t.Fatalf("lines = %q", lines)
+	}
+
+	logger.Info("recovered")
+	time.Sleep(200 * time.Millisecond)
+	if lines := buf.lines(); len(lines) != 4 || !strings.Contains(lines[3], "repeated 1 times") {
+		t.Errorf("Expected the summary written when the window ends, lines = %q", lines)
+	}
+	logger.Info("recovered")
+	if lines := buf.lines(); len(lines) != 5 {
+		t.Errorf("Expected a new window after the summary, lines = %q", lines)
+	}
+
+	logger.Info("recovered")
+	_ = logger.Sync()
+	if lines := buf.lines(); len(lines) != 6 || !strings.Contains(lines[5], "repeated 1 times") {
+		t.Errorf("Expected the summary written on Sync, lines = %q", lines)
+	}
+}
+
+// TestDedupErrorOutput tests that the write errors are reported to the ErrorOutput of the logger.
+func TestDedupErrorOutput(t *testing.T) {
+	testErrorOutput(t, func(core zapcore.Core) zapcore.Core {
+		return newDedupCore(core, &DedupConfig{})
+	})
+}
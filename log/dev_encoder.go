@@ -0,0 +1,357 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// The widths the columns of the dev format are padded to, the longer values are not cut.
+const (
+	devCallerWidth  = 24
+	devMessageWidth = 40
+)
+
+// The ANSI colors of the dev format.
+const (
+	devColorReset   = "\x1b[0m"
+	devColorRed     = "\x1b[31m"
+	devColorYellow  = "\x1b[33m"
+	devColorBlue    = "\x1b[34m"
+	devColorMagenta = "\x1b[35m"
+	devColorCyan    = "\x1b[36m"
+	devColorGray    = "\x1b[90m"
+)
+
+var devBufferPool = buffer.NewPool()
+
+// devEncoder is the encoder of FormatterDev for reading the logs in the terminal:
+//
+//	2026-01-02 15:04:05.000 INFO  [dao] user/repo.go:42          user loaded                              id=1 name=tom
+//	    profile:
+//	      age: 20
+//
+// The columns are aligned and colorized unless the NO_COLOR environment variable is set,
+// the scalar fields follow the message as key=value and the nested objects, the arrays
+// of objects and the multi-line strings are printed below the line, indented.
+// The fields are accumulated by a JSON encoder and decoded in order when encoding the entry.
+type devEncoder struct {
+	zapcore.Encoder // the JSON encoder of the fields only
+	cfg             zapcore.EncoderConfig
+	color           bool
+}
+
+// newDevEncoder creates the encoder of FormatterDev.
+func newDevEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return &devEncoder{
+		Encoder: zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+			EncodeTime:     cfg.EncodeTime,
+			EncodeDuration: cfg.EncodeDuration,
+		}),
+		cfg:   cfg,
+		color: !noColor,
+	}
+}
+
+// Clone implements zapcore.Encoder.
+func (e *devEncoder) Clone() zapcore.Encoder {
+	return &devEncoder{Encoder: e.Encoder.Clone(), cfg: e.cfg, color: e.color}
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *devEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	obj, err := e.fields(fields)
+	if err != nil {
+		return nil, err
+	}
+	buf := devBufferPool.Get()
+	if e.cfg.TimeKey != "" && e.cfg.EncodeTime != nil {
+		e.writeColored(buf, devColorGray, e.formatTime(ent))
+		buf.AppendByte(' ')
+	}
+	level := ent.Level.CapitalString()
+	e.writeColored(buf, devLevelColor(ent.Level), level)
+	writePadding(buf, level, 5)
+	buf.AppendByte(' ')
+	if e.cfg.NameKey != "" && ent.LoggerName != "" {
+		buf.AppendString("[" + ent.LoggerName + "]")
+		buf.AppendByte(' ')
+	}
+	if e.cfg.CallerKey != "" && ent.Caller.Defined {
+		caller := ent.Caller.TrimmedPath()
+		e.writeColored(buf, devColorGray, caller)
+		writePadding(buf, caller, devCallerWidth)
+		buf.AppendByte(' ')
+	}
+	buf.AppendString(ent.Message)
+
+	var blocks devObject
+	first := true
+	for _, f := range obj {
+		if !devInline(f.value) {
+			blocks = append(blocks, f)
+			continue
+		}
+		if first {
+			writePadding(buf, ent.Message, devMessageWidth)
+			first = false
+		}
+		buf.AppendByte(' ')
+		e.writeColored(buf, devColorCyan, f.key+"=")
+		buf.AppendString(devScalar(f.value))
+	}
+	buf.AppendByte('\n')
+	for _, f := range blocks {
+		e.writeBlock(buf, 4, "", f.key, f.value)
+	}
+	if ent.Stack != "" && e.cfg.StacktraceKey != "" {
+		for _, line := range strings.Split(strings.TrimRight(ent.Stack, "\n"), "\n") {
+			buf.AppendString("    ")
+			e.writeColored(buf, devColorGray, line)
+			buf.AppendByte('\n')
+		}
+	}
+	return buf, nil
+}
+
+// fields returns the context and the entry fields in order, decoded from the JSON encoding.
+func (e *devEncoder) fields(fields []zapcore.Field) (devObject, error) {
+	enc, err := e.Encoder.EncodeEntry(zapcore.Entry{}, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer enc.Free()
+	dec := json.NewDecoder(bytes.NewReader(enc.Bytes()))
+	dec.UseNumber()
+	v, err := decodeDevValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf("log: dev encoder: %w", err)
+	}
+	obj, _ := v.(devObject)
+	return obj, nil
+}
+
+// formatTime formats the entry time by the configured time encoder.
+func (e *devEncoder) formatTime(ent zapcore.Entry) string {
+	enc := zapcore.NewMapObjectEncoder()
+	_ = enc.AddArray("t", zapcore.ArrayMarshalerFunc(func(ae zapcore.ArrayEncoder) error {
+		e.cfg.EncodeTime(ent.Time, ae)
+		return nil
+	}))
+	if t, ok := enc.Fields["t"].([]interface{}); ok && len(t) == 1 {
+		return fmt.Sprint(t[0])
+	}
+	return ent.Time.Format("2006-01-02 15:04:05.000")
+}
+
+// writeBlock writes the field in multiple lines like YAML, lead replaces the indent of
+// the first line for the elements of the arrays.
+func (e *devEncoder) writeBlock(buf *buffer.Buffer, indent int, lead, key string, v any) {
+	if lead == "" {
+		lead = strings.Repeat(" ", indent)
+	}
+	buf.AppendString(lead)
+	e.writeColored(buf, devColorCyan, key+":")
+	switch v := v.(type) {
+	case devObject:
+		if len(v) == 0 {
+			buf.AppendString(" {}\n")
+			return
+		}
+		buf.AppendByte('\n')
+		for _, f := range v {
+			e.writeBlock(buf, indent+2, "", f.key, f.value)
+		}
+	case []any:
+		if devInline(v) {
+			buf.AppendByte(' ')
+			buf.AppendString(devScalar(v))
+			buf.AppendByte('\n')
+			return
+		}
+		buf.AppendByte('\n')
+		for _, elem := range v {
+			e.writeElement(buf, indent+2, elem)
+		}
+	case string:
+		if !strings.Contains(v, "\n") {
+			buf.AppendByte(' ')
+			buf.AppendString(devScalar(v))
+			buf.AppendByte('\n')
+			return
+		}
+		buf.AppendString(" |\n")
+		for _, line := range strings.Split(strings.TrimRight(v, "\n"), "\n") {
+			buf.AppendString(strings.Repeat(" ", indent+2))
+			buf.AppendString(line)
+			buf.AppendByte('\n')
+		}
+	default:
+		buf.AppendByte(' ')
+		buf.AppendString(devScalar(v))
+		buf.AppendByte('\n')
+	}
+}
+
+// writeElement writes an element of an array in the block, prefixed with "- ".
+func (e *devEncoder) writeElement(buf *buffer.Buffer, indent int, v any) {
+	lead := strings.Repeat(" ", indent) + "- "
+	switch v := v.(type) {
+	case devObject:
+		if len(v) == 0 {
+			buf.AppendString(lead + "{}\n")
+			return
+		}
+		for i, f := range v {
+			if i == 0 {
+				e.writeBlock(buf, indent+2, lead, f.key, f.value)
+			} else {
+				e.writeBlock(buf, indent+2, "", f.key, f.value)
+			}
+		}
+	case []any:
+		if devInline(v) {
+			buf.AppendString(lead + devScalar(v) + "\n")
+			return
+		}
+		buf.AppendString(lead + "\n")
+		for _, elem := range v {
+			e.writeElement(buf, indent+2, elem)
+		}
+	default:
+		buf.AppendString(lead + devScalar(v) + "\n")
+	}
+}
+
+func (e *devEncoder) writeColored(buf *buffer.Buffer, color, s string) {
+	if !e.color {
+		buf.AppendString(s)
+		return
+	}
+	buf.AppendString(color)
+	buf.AppendString(s)
+	buf.AppendString(devColorReset)
+}
+
+func writePadding(buf *buffer.Buffer, s string, width int) {
+	for n := utf8.RuneCountInString(s); n < width; n++ {
+		buf.AppendByte(' ')
+	}
+}
+
+func devLevelColor(l zapcore.Level) string {
+	switch l {
+	case zapcore.DebugLevel:
+		return devColorMagenta
+	case zapcore.InfoLevel:
+		return devColorBlue
+	case zapcore.WarnLevel:
+		return devColorYellow
+	default:
+		return devColorRed
+	}
+}
+
+// devObject is a JSON object with the keys in order.
+type devObject []devField
+
+type devField struct {
+	key   string
+	value any // string, json.Number, bool, nil, devObject or []any
+}
+
+// decodeDevValue decodes the next JSON value of dec, keeping the order of the object keys.
+func decodeDevValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := devObject{}
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeDevValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			key, _ := k.(string)
+			obj = append(obj, devField{key: key, value: v})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case '[':
+		arr := []any{}
+		for dec.More() {
+			v, err := decodeDevValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return nil, fmt.Errorf("unexpected %v", delim)
+}
+
+// devInline reports whether v fits in the line: the scalars except the multi-line strings,
+// the empty objects and the arrays of the scalars.
+func devInline(v any) bool {
+	switch v := v.(type) {
+	case devObject:
+		return len(v) == 0
+	case []any:
+		for _, elem := range v {
+			switch elem.(type) {
+			case devObject, []any:
+				return false
+			}
+			if !devInline(elem) {
+				return false
+			}
+		}
+		return true
+	case string:
+		return !strings.Contains(v, "\n")
+	}
+	return true
+}
+
+// devScalar formats an inline value, quoting the strings which are empty or contain spaces,
+// quotes or control characters.
+func devScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		if v == "" || strings.ContainsAny(v, " =") || strconv.Quote(v) != `"`+v+`"` {
+			return strconv.Quote(v)
+		}
+		return v
+	case devObject:
+		return "{}"
+	case []any:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			elems[i] = devScalar(elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
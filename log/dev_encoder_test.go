@@ -0,0 +1,101 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// TestDevEncoder tests the aligned columns, the inline scalar fields and the nested
+// fields printed below the line.
+func TestDevEncoder(t *testing.T) {
+	enc := newDevEncoder(zapcore.EncoderConfig{
+		TimeKey:        "T",
+		NameKey:        "N",
+		CallerKey:      "C",
+		StacktraceKey:  "S",
+		EncodeTime:     NewTimeEncoderInLocation("2006-01-02 15:04:05", time.UTC),
+		EncodeDuration: zapcore.StringDurationEncoder,
+	}).(*devEncoder)
+	enc.color = false
+	enc.AddString("request_id", "r1")
+
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		LoggerName: "dao",
+		Message:    "user loaded",
+		Caller:     zapcore.NewEntryCaller(0, "/src/app/user/repo.go", 42, true),
+		Stack:      "main.main\n\t/src/main.go:10",
+	}
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+		Int("id", 1),
+		String("name", "tom cat"),
+		Duration("elapsed", 1500*time.Millisecond),
+		Any("tags", []string{"a", "b"}),
+		Any("profile", map[string]any{"age": 20, "address": map[string]string{"city": "sh"}}),
+		Any("orders", []map[string]int{{"id": 1}, {"id": 2}}),
+		zapcore.Field{Key: "detail", Type: zapcore.StringType, String: "line1\nline2"},
+	})
+	if err != nil {
+		t.Fatalf("EncodeEntry failed: %v", err)
+	}
+	defer buf.Free()
+
+	want := "2026-01-02 03:04:05 WARN  [dao] user/repo.go:42          user loaded                              " +
+		`request_id=r1 id=1 name="tom cat" elapsed=1.5s tags=[a, b]` + "\n" +
+		"    profile:\n" +
+		"      address:\n" +
+		"        city: sh\n" +
+		"      age: 20\n" +
+		"    orders:\n" +
+		"      - id: 1\n" +
+		"      - id: 2\n" +
+		"    detail: |\n" +
+		"      line1\n" +
+		"      line2\n" +
+		"    main.main\n" +
+		"    \t/src/main.go:10\n"
+	if got := buf.String(); got != want {
+		t.Errorf("EncodeEntry =\n%s\nwant\n%s", got, want)
+	}
+
+	// the context fields are kept by Clone and not shared
+	clone := enc.Clone()
+	clone.AddString("user", "u1")
+	buf2, _ := enc.EncodeEntry(zapcore.Entry{Message: "m"}, nil)
+	defer buf2.Free()
+	if s := buf2.String(); !strings.Contains(s, "request_id=r1") || strings.Contains(s, "user=") {
+		t.Errorf("Unexpected fields %q", s)
+	}
+}
+
+// TestDevFormatter tests the dev formatter of the output, colorized unless NO_COLOR is set.
+func TestDevFormatter(t *testing.T) {
+	for _, noColor := range []bool{false, true} {
+		buf := &syncBuffer{}
+		RegisterWriterTarget("test-dev", buf)
+		if noColor {
+			t.Setenv("NO_COLOR", "1")
+		} else if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			continue
+		}
+		logger := NewZapLog(Config{{
+			Writer:      OutputConsole,
+			Level:       "info",
+			Formatter:   FormatterDev,
+			WriteConfig: WriteConfig{Target: "test-dev"},
+		}})
+		logger.Error("failed", String("error", "boom"))
+
+		lines := buf.lines()
+		colored := strings.Contains(lines[0], devColorRed+"ERROR"+devColorReset)
+		if len(lines) != 1 || colored == noColor || !strings.Contains(lines[0], "failed") ||
+			!strings.Contains(lines[0], "error=") {
+			t.Errorf("NO_COLOR=%v, lines = %q", noColor, lines)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// WriteErrorHook is called for every failed write of an output, with the total number
+// of the failures of the output.
+type WriteErrorHook func(output string, err error, failures int64)
+
+var writeErrorHook atomic.Pointer[WriteErrorHook]
+
+// SetWriteErrorHook sets the hook of the failed writes, e.g. to export the failures as
+// a metric. It applies to the loggers already created, nil removes the hook.
+func SetWriteErrorHook(hook WriteErrorHook) {
+	if hook == nil {
+		writeErrorHook.Store(nil)
+		return
+	}
+	writeErrorHook.Store(&hook)
+}
+
+// errorOutput is where the failures and the recoveries of the outputs are reported.
+var errorOutput zapcore.WriteSyncer = zapcore.Lock(os.Stderr)
+
+// isolatedCore isolates the write errors of an output: the errors are counted and
+// reported once per failure streak instead of once per entry, and with a fallback,
+// the entries are written to the fallback target after the writes keep failing for
+// After, retrying the output every RetryInterval. It wraps the core of the writer
+// directly, so the entries dropped by the other wrappers are not written to the fallback.
+type isolatedCore struct {
+	zapcore.Core
+	fallback zapcore.Core // nil if no fallback
+	state    *isolationState
+}
+
+// isolationState is the failure streak of an output, shared by the cores of With.
+type isolationState struct {
+	output        string
+	after         time.Duration
+	retryInterval time.Duration
+
+	mu        sync.Mutex
+	failures  int64     // the total failures
+	streak    int64     // the failures of the current streak
+	since     time.Time // the first failure of the current streak, zero if writing well
+	fallback  bool      // writing to the fallback
+	nextRetry time.Time // the next time to retry the output when writing to the fallback
+}
+
+// newIsolatedCore wraps the core of the writer with the write error isolation and the
+// fallback of the output config.
+func newIsolatedCore(core zapcore.Core, c *OutputConfig) (zapcore.Core, error) {
+	ic := &isolatedCore{Core: core, state: &isolationState{output: c.Writer}}
+	if f := c.Fallback; f != nil {
+		target := f.Target
+		if target == "" {
+			target = TargetStderr
+		}
+		ws, err := writerTarget(target)
+		if err != nil {
+			return nil, err
+		}
+		// the entries are checked by the output, the fallback writes them whatever the level
+		ic.fallback = zapcore.NewCore(newEncoder(c), ws, zapcore.DebugLevel)
+		ic.state.after, ic.state.retryInterval = f.After, f.RetryInterval
+		if ic.state.after <= 0 {
+			ic.state.after = 10 * time.Second
+		}
+		if ic.state.retryInterval <= 0 {
+			ic.state.retryInterval = 5 * time.Second
+		}
+	}
+	return ic, nil
+}
+
+// With implements zapcore.Core.
+func (c *isolatedCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	if c.fallback != nil {
+		clone.fallback = c.fallback.With(fields)
+	}
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *isolatedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+	return ce.AddCore(ent, &isolatedEntry{isolatedCore: c, inner: inner})
+}
+
+// Sync implements zapcore.Core.
+func (c *isolatedCore) Sync() error {
+	if c.fallback != nil {
+		_ = c.fallback.Sync()
+	}
+	return c.Core.Sync()
+}
+
+// isolatedEntry writes the checked entry of the wrapped core, or the fallback.
+type isolatedEntry struct {
+	*isolatedCore
+	inner *zapcore.CheckedEntry
+}
+
+// Write implements zapcore.Core, the errors are never returned to zap.
+func (e *isolatedEntry) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if e.fallback != nil && !e.state.retry(ent.Time) {
+		return e.fallback.Write(ent, fields)
+	}
+	// the outer wrappers may change the entry after Check, e.g. masking the message
+	e.inner.Entry = ent
+	captured := &capturedError{}
+	e.inner.ErrorOutput = captured
+	e.inner.Write(fields...)
+	if captured.err == nil {
+		e.state.succeed()
+		return nil
+	}
+	if e.state.fail(ent.Time, captured.err, e.fallback != nil) {
+		return e.fallback.Write(ent, fields)
+	}
+	return nil
+}
+
+// retry reports whether to write the output, false if writing to the fallback until
+// the next retry.
+func (s *isolationState) retry(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.fallback || !now.Before(s.nextRetry)
+}
+
+// succeed ends the failure streak.
+func (s *isolationState) succeed() {
+	s.mu.Lock()
+	if s.since.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	streak, duration, fallback := s.streak, time.Since(s.since), s.fallback
+	s.streak, s.since, s.fallback = 0, time.Time{}, false
+	s.mu.Unlock()
+	if fallback {
+		reportf("log: output %s recovered after %d failures in %s, switched back from the fallback",
+			s.output, streak, duration.Round(time.Millisecond))
+		return
+	}
+	reportf("log: output %s recovered after %d failures in %s", s.output, streak, duration.Round(time.Millisecond))
+}
+
+// fail counts the failure and reports whether to write the entry to the fallback.
+func (s *isolationState) fail(now time.Time, err error, hasFallback bool) bool {
+	s.mu.Lock()
+	s.failures++
+	s.streak++
+	failures, first, switched := s.failures, s.since.IsZero(), false
+	if first {
+		s.since = now
+	}
+	if hasFallback && !now.Before(s.since.Add(s.after)) {
+		switched = !s.fallback
+		s.fallback = true
+		s.nextRetry = now.Add(s.retryInterval)
+	}
+	fallback := s.fallback
+	s.mu.Unlock()
+
+	if hook := writeErrorHook.Load(); hook != nil {
+		(*hook)(s.output, err, failures)
+	}
+	if first {
+		reportf("log: output %s write failed: %v", s.output, err)
+	}
+	if switched {
+		reportf("log: output %s failing for %s, switched to the fallback", s.output, s.after)
+	}
+	return fallback
+}
+
+// reportf writes a line to errorOutput, like the write errors reported by zap.
+func reportf(format string, args ...any) {
+	fmt.Fprintf(errorOutput, "%v "+format+"\n", append([]any{time.Now()}, args...)...)
+	_ = errorOutput.Sync()
+}
+
+// capturedError captures the write error zap reports to the ErrorOutput of the entry.
+type capturedError struct {
+	err error
+}
+
+func (c *capturedError) Write(p []byte) (int, error) {
+	c.err = errors.New(trimErrorOutput(p))
+	return len(p), nil
+}
+
+func (c *capturedError) Sync() error {
+	return nil
+}
+
+// trimErrorOutput trims the "<time> write error: " prefix and the newline zap adds.
+func trimErrorOutput(p []byte) string {
+	s := string(p)
+	if _, after, ok := strings.Cut(s, " write error: "); ok {
+		s = after
+	}
+	return strings.TrimRight(s, "\n")
+}
@@ -0,0 +1,104 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// failingWriter fails the writes while failing is set.
+type failingWriter struct {
+	syncBuffer
+	failing atomic.Bool
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.failing.Load() {
+		return 0, errors.New("disk full")
+	}
+	return w.syncBuffer.Write(p)
+}
+
+// TestFallback tests that the write errors are reported once per failure streak, and
+// the entries are written to the fallback after the output keeps failing.
+func TestFallback(t *testing.T) {
+	primary, fallback, reports := &failingWriter{}, &syncBuffer{}, &syncBuffer{}
+	RegisterWriterTarget("test-fallback-primary", primary)
+	RegisterWriterTarget("test-fallback", fallback)
+	defer func(ws zapcore.WriteSyncer) { errorOutput = ws }(errorOutput)
+	errorOutput = zapcore.AddSync(reports)
+	var failures atomic.Int64
+	SetWriteErrorHook(func(output string, err error, n int64) {
+		if output != OutputConsole || err.Error() != "disk full" {
+			t.Errorf("hook output = %s, err = %v", output, err)
+		}
+		failures.Store(n)
+	})
+	defer SetWriteErrorHook(nil)
+
+	logger := NewZapLog(Config{{
+		Writer:      OutputConsole,
+		Level:       "info",
+		Formatter:   "json",
+		WriteConfig: WriteConfig{Target: "test-fallback-primary"},
+		Fallback:    &FallbackConfig{Target: "test-fallback", After: 50 * time.Millisecond, RetryInterval: 50 * time.Millisecond},
+	}}).With(String("k", "v"))
+
+	primary.failing.Store(true)
+	logger.Info("lost 1")
+	logger.Info("lost 2")
+	if lines := reports.lines(); len(lines) != 1 || !strings.HasSuffix(lines[0], "log: output console write failed: disk full") {
+		t.Fatalf("Expected the failure reported once, reports = %q", lines)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	logger.Info("fallback 1")
+	logger.Info("fallback 2") // not retried within the retry interval
+	if n := failures.Load(); n != 3 {
+		t.Errorf("failures = %d, want 3", n)
+	}
+	if lines := fallback.lines(); len(lines) != 2 || !strings.Contains(lines[0], `"M":"fallback 1","k":"v"`) {
+		t.Fatalf("fallback = %q", lines)
+	}
+
+	primary.failing.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	logger.Info("recovered")
+	if lines := primary.lines(); len(lines) != 1 || !strings.Contains(lines[0], `"M":"recovered"`) {
+		t.Errorf("primary = %q", lines)
+	}
+	lines := reports.lines()
+	if len(lines) != 3 || !strings.Contains(lines[1], "switched to the fallback") ||
+		!strings.Contains(lines[2], "log: output console recovered after 3 failures") {
+		t.Errorf("reports = %q", lines)
+	}
+}
+
+// TestFallbackIsolation tests that a failing output does not affect the others and
+// the entries are not written to the fallback within After.
+func TestFallbackIsolation(t *testing.T) {
+	primary, other, reports := &failingWriter{}, &syncBuffer{}, &syncBuffer{}
+	RegisterWriterTarget("test-isolation-primary", primary)
+	RegisterWriterTarget("test-isolation-other", other)
+	defer func(ws zapcore.WriteSyncer) { errorOutput = ws }(errorOutput)
+	errorOutput = zapcore.AddSync(reports)
+
+	primary.failing.Store(true)
+	logger := NewZapLog(Config{
+		{Writer: OutputConsole, Level: "info", Formatter: "json", WriteConfig: WriteConfig{Target: "test-isolation-primary"}},
+		{Writer: OutputConsole, Level: "info", Formatter: "json", WriteConfig: WriteConfig{Target: "test-isolation-other"}},
+	})
+	for i := 0; i < 3; i++ {
+		logger.Info("hello")
+	}
+	if lines := other.lines(); len(lines) != 3 {
+		t.Errorf("other = %q", lines)
+	}
+	if lines := reports.lines(); len(lines) != 1 {
+		t.Errorf("Expected the failure reported once, reports = %q", lines)
+	}
+}
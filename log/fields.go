@@ -0,0 +1,67 @@
+package log
+
+import (
+	"os"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// HostFields returns the fields of the host: hostname, and pod and namespace if the
+// POD_NAME and POD_NAMESPACE environment variables are set, e.g. by the kubernetes
+// downward API.
+func HostFields() map[string]string {
+	fields := make(map[string]string)
+	if v := hostname(); v != "" {
+		fields["hostname"] = v
+	}
+	if v := os.Getenv("POD_NAME"); v != "" {
+		fields["pod"] = v
+	}
+	if v := os.Getenv("POD_NAMESPACE"); v != "" {
+		fields["namespace"] = v
+	}
+	return fields
+}
+
+// staticFields converts the fields of the output into zap fields sorted by key. The
+// values are expanded by the environment variables like ${SERVICE_ENV}, and the
+// fields empty after expansion are dropped.
+func staticFields(fields map[string]string) []zap.Field {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	zfs := make([]zap.Field, 0, len(keys))
+	for _, k := range keys {
+		if v := os.Expand(fields[k], expandEnv); v != "" {
+			zfs = append(zfs, zap.String(k, v))
+		}
+	}
+	return zfs
+}
+
+// expandEnv returns the environment variable, HOSTNAME falls back to os.Hostname
+// since it is a shell variable not always exported.
+func expandEnv(key string) string {
+	v := os.Getenv(key)
+	if v == "" && key == "HOSTNAME" {
+		v = hostname()
+	}
+	return v
+}
+
+func hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
+
+// withStaticFields adds the fields of the output to the core.
+func withStaticFields(core zapcore.Core, fields map[string]string) zapcore.Core {
+	if zfs := staticFields(fields); len(zfs) > 0 {
+		return core.With(zfs)
+	}
+	return core
+}
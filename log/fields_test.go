@@ -0,0 +1,57 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestStaticFields tests that the fields of the output are added to every entry and
+// expanded by the environment variables.
+func TestStaticFields(t *testing.T) {
+	t.Setenv("LOG_TEST_ENV", "prod")
+	filename := filepath.Join(t.TempDir(), "fields.log")
+	cfg := Config{{
+		Writer:      OutputFile,
+		Formatter:   FormatterJson,
+		Level:       "info",
+		WriteConfig: WriteConfig{Filename: filename},
+		Fields:      map[string]string{"service": "order", "env": "${LOG_TEST_ENV}", "pod": "${LOG_TEST_UNSET}"},
+	}}
+	WithGlobalFields(map[string]string{"version": "v1.2.0", "service": "order-api"}).apply((*[]OutputConfig)(&cfg))
+	logger := NewZapLog(cfg)
+	logger.With(String("uid", "1")).Info("hello")
+	_ = logger.Sync()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(content, &entry); err != nil {
+		t.Fatalf("unmarshal %q: %v", content, err)
+	}
+	want := map[string]string{"service": "order-api", "env": "prod", "version": "v1.2.0", "uid": "1"}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %s", k, entry[k], v)
+		}
+	}
+	if _, ok := entry["pod"]; ok {
+		t.Error("empty field pod should be dropped")
+	}
+}
+
+// TestHostFields tests the host fields from the environment.
+func TestHostFields(t *testing.T) {
+	t.Setenv("POD_NAME", "order-7d9f")
+	t.Setenv("POD_NAMESPACE", "")
+	fields := HostFields()
+	if fields["pod"] != "order-7d9f" || fields["hostname"] == "" {
+		t.Errorf("fields = %v", fields)
+	}
+	if _, ok := fields["namespace"]; ok {
+		t.Error("namespace should be absent")
+	}
+}
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"errors"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Hook is called with every entry written, e.g. to count the error logs, forward the
+// fatal events to alerting or mirror the entries to Sentry. The returned error is
+// reported to stderr by zap, the entry is written anyway.
+type Hook func(zapcore.Entry) error
+
+var (
+	hookMu      sync.RWMutex
+	hooks       = make(map[string]Hook)
+	globalHooks []Hook
+)
+
+// RegisterHook registers a named hook, which is enabled per output by OutputConfig.Hooks.
+func RegisterHook(name string, h Hook) {
+	hookMu.Lock()
+	defer hookMu.Unlock()
+	hooks[name] = h
+}
+
+// GetHook gets a registered hook.
+func GetHook(name string) Hook {
+	hookMu.RLock()
+	h := hooks[name]
+	hookMu.RUnlock()
+	return h
+}
+
+// AddGlobalHook adds a hook called once per entry of every logger created by NewZapLog,
+// including the ones created before, however many outputs the entry is written to.
+func AddGlobalHook(h Hook) {
+	hookMu.Lock()
+	defer hookMu.Unlock()
+	globalHooks = append(globalHooks, h)
+}
+
+// runGlobalHooks runs the global hooks, registered to the loggers by zap.Hooks.
+func runGlobalHooks(ent zapcore.Entry) error {
+	hookMu.RLock()
+	hs := globalHooks
+	hookMu.RUnlock()
+	var errs []error
+	for _, h := range hs {
+		if err := h(ent); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// outputHooks returns the hooks of the output, the named ones first.
+func outputHooks(c *OutputConfig) ([]Hook, error) {
+	hs := make([]Hook, 0, len(c.Hooks)+len(c.HookFuncs))
+	for _, name := range c.Hooks {
+		h := GetHook(name)
+		if h == nil {
+			return nil, errors.New("hook " + name + " no registered")
+		}
+		hs = append(hs, h)
+	}
+	return append(hs, c.HookFuncs...), nil
+}
+
+// hookCore runs the hooks of an output after writing the entries the wrapped core
+// accepts. Unlike zapcore.RegisterHooks, the hooks are not run for the entries of the
+// other outputs of the logger.
+type hookCore struct {
+	zapcore.Core
+	hooks []Hook
+}
+
+// With implements zapcore.Core.
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{Core: c.Core.With(fields), hooks: c.hooks}
+}
+
+// Check implements zapcore.Core.
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+	return ce.AddCore(ent, &hookedEntry{hookCore: c, inner: inner})
+}
+
+// hookedEntry writes the checked entry of the wrapped core and runs the hooks.
+type hookedEntry struct {
+	*hookCore
+	inner *zapcore.CheckedEntry
+}
+
+// Write implements zapcore.Core.
+func (e *hookedEntry) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	errs := []error{writeChecked(e.inner, ent, fields)}
+	for _, h := range e.hooks {
+		errs = append(errs, h(ent))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package log
+
+import (
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// TestOutputHooks tests the named and the code hooks of an output.
+func TestOutputHooks(t *testing.T) {
+	var named, errorLogs atomic.Int32
+	RegisterHook("test_count", func(zapcore.Entry) error {
+		named.Add(1)
+		return nil
+	})
+	dir := t.TempDir()
+	logger := NewZapLog(Config{
+		{
+			Writer: OutputFile,
+			Level:  "info",
+			Hooks:  []string{"test_count"},
+			HookFuncs: []Hook{func(e zapcore.Entry) error {
+				if e.Level >= zapcore.ErrorLevel {
+					errorLogs.Add(1)
+				}
+				return nil
+			}},
+			WriteConfig: WriteConfig{Filename: filepath.Join(dir, "a.log")},
+		},
+		{
+			Writer:      OutputFile,
+			Level:       "info",
+			WriteConfig: WriteConfig{Filename: filepath.Join(dir, "b.log")},
+		},
+	})
+	logger.Debug("filtered")
+	logger.Info("info")
+	logger.Error("error")
+	if named.Load() != 2 || errorLogs.Load() != 1 {
+		t.Errorf("named = %d, error logs = %d, want 2 and 1", named.Load(), errorLogs.Load())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for the unregistered hook")
+		}
+	}()
+	NewZapLog(Config{{Writer: OutputConsole, Hooks: []string{"missing"}}})
+}
+
+// TestOutputHooksLevel tests that the hooks of an output are not run for the entries
+// written only by the other outputs.
+func TestOutputHooksLevel(t *testing.T) {
+	var debugHooks, errorHooks atomic.Int32
+	dir := t.TempDir()
+	logger := NewZapLog(Config{
+		{
+			Writer:      OutputFile,
+			Level:       "debug",
+			HookFuncs:   []Hook{func(zapcore.Entry) error { debugHooks.Add(1); return nil }},
+			WriteConfig: WriteConfig{Filename: filepath.Join(dir, "debug.log")},
+		},
+		{
+			Writer:      OutputFile,
+			Level:       "error",
+			HookFuncs:   []Hook{func(zapcore.Entry) error { errorHooks.Add(1); return nil }},
+			WriteConfig: WriteConfig{Filename: filepath.Join(dir, "error.log")},
+		},
+	})
+	logger.Info("info")
+	logger.Error("error")
+	if debugHooks.Load() != 2 || errorHooks.Load() != 1 {
+		t.Errorf("debug hooks = %d, error hooks = %d, want 2 and 1", debugHooks.Load(), errorHooks.Load())
+	}
+}
+
+// TestGlobalHooks tests that the global hooks run once per entry, for the loggers
+// created before they are added too.
+func TestGlobalHooks(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewZapLog(Config{
+		{Writer: OutputFile, Level: "info", WriteConfig: WriteConfig{Filename: filepath.Join(dir, "a.log")}},
+		{Writer: OutputFile, Level: "info", WriteConfig: WriteConfig{Filename: filepath.Join(dir, "b.log")}},
+	})
+	var calls atomic.Int32
+	AddGlobalHook(func(zapcore.Entry) error {
+		calls.Add(1)
+		return errors.New("reported to stderr")
+	})
+	defer func() {
+		hookMu.Lock()
+		globalHooks = nil
+		hookMu.Unlock()
+	}()
+
+	logger.Info("hooked")
+	logger.Debug("filtered")
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
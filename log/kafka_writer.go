@@ -0,0 +1,194 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/baisiyi/go-kits/log/rollwriter"
+)
+
+func init() {
+	RegisterWriter(OutputKafka, WriterFactoryFunc(defaultKafkaWriterFactory))
+}
+
+// defaultKafkaWriterFactory creates a kafka writer.
+func defaultKafkaWriterFactory(name string, dec *Decoder) error {
+	c := dec.OutputConfig
+	w, err := newKafkaWriter(c.KafkaConfig)
+	if err != nil {
+		return err
+	}
+	lvl := zap.NewAtomicLevelAt(Levels[c.Level])
+	dec.Core = zapcore.NewCore(newEncoder(c), w, lvl)
+	dec.ZapLevel = lvl
+	dec.Closer = w
+	return nil
+}
+
+// messageWriter is the interface of kafkago.Writer used by the kafka writer.
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafkago.Message) error
+	Close() error
+}
+
+func newKafkaWriter(cfg KafkaConfig) (fileWriteSyncer, error) {
+	if len(cfg.Brokers) == 0 || cfg.Topic == "" {
+		return nil, errors.New("log: kafka brokers or topic empty")
+	}
+	cfg.setDefaults()
+	compression, err := parseKafkaCompression(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+	acks, err := parseKafkaRequiredAcks(cfg.RequiredAcks)
+	if err != nil {
+		return nil, err
+	}
+	return startKafkaWriter(cfg, &kafkago.Writer{
+		Addr:         kafkago.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafkago.Hash{},
+		BatchSize:    cfg.BatchSize,
+		BatchBytes:   cfg.BatchBytes,
+		BatchTimeout: time.Millisecond, // batched by the async writer
+		WriteTimeout: cfg.WriteTimeout,
+		RequiredAcks: acks,
+		Compression:  compression,
+	}), nil
+}
+
+// startKafkaWriter queues the log entries in an async writer, which sends them to kafka
+// in batches in background. Close sends the queued entries and then closes w.
+func startKafkaWriter(cfg KafkaConfig, w messageWriter) fileWriteSyncer {
+	async := rollwriter.NewAsyncRollWriter(&kafkaSender{cfg: cfg, w: w},
+		rollwriter.WithQueueSize(cfg.QueueSize),
+		rollwriter.WithDropOnFull(cfg.DropOnFull),
+		rollwriter.WithWriteSize(int(min(cfg.BatchBytes, math.MaxInt))),
+		rollwriter.WithWriteInterval(cfg.BatchTimeout),
+	)
+	return &syncCloser{WriteSyncer: async, stop: async.Close, writer: w}
+}
+
+func (c *KafkaConfig) setDefaults() {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.BatchBytes <= 0 {
+		c.BatchBytes = 1 << 20
+	}
+	if c.BatchTimeout <= 0 {
+		c.BatchTimeout = 100 * time.Millisecond
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 10 * time.Second
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 10000
+	}
+}
+
+// kafkaSender sends the entries merged by the async writer to kafka, one message per
+// entry and at most BatchSize messages per request.
+type kafkaSender struct {
+	cfg KafkaConfig
+	w   messageWriter
+
+	mu  sync.Mutex
+	err error // the first send error since the last Sync
+}
+
+// WriteBatch implements rollwriter.BatchWriter.
+func (s *kafkaSender) WriteBatch(entries [][]byte) error {
+	var errs []error
+	for len(entries) > 0 {
+		n := min(len(entries), s.cfg.BatchSize)
+		if err := s.send(entries[:n]); err != nil {
+			errs = append(errs, err)
+		}
+		entries = entries[n:]
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		s.mu.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *kafkaSender) send(entries [][]byte) error {
+	msgs := make([]kafkago.Message, len(entries))
+	for i, b := range entries {
+		msgs[i].Value = b
+		if s.cfg.Key != "" {
+			msgs[i].Key = []byte(s.cfg.Key)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.WriteTimeout)
+	defer cancel()
+	err := s.w.WriteMessages(ctx, msgs...)
+	if err != nil {
+		// the logger can not log its own errors, report them to stderr like zap.
+		fmt.Fprintf(os.Stderr, "%v log: send %d entries to kafka error: %v\n", time.Now(), len(msgs), err)
+	}
+	return err
+}
+
+// Write sends p as one message, the async writer calls WriteBatch instead.
+func (s *kafkaSender) Write(p []byte) (int, error) {
+	if err := s.WriteBatch([][]byte{p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync returns the first send error since the last Sync, the async writer calls it after
+// sending the queued entries.
+func (s *kafkaSender) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.err
+	s.err = nil
+	return err
+}
+
+func parseKafkaCompression(s string) (kafkago.Compression, error) {
+	switch s {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafkago.Gzip, nil
+	case "snappy":
+		return kafkago.Snappy, nil
+	case "lz4":
+		return kafkago.Lz4, nil
+	case "zstd":
+		return kafkago.Zstd, nil
+	default:
+		return 0, fmt.Errorf("log: kafka compression %s not supported", s)
+	}
+}
+
+func parseKafkaRequiredAcks(s string) (kafkago.RequiredAcks, error) {
+	switch s {
+	case "", "one":
+		return kafkago.RequireOne, nil
+	case "all":
+		return kafkago.RequireAll, nil
+	case "none":
+		return kafkago.RequireNone, nil
+	default:
+		return 0, fmt.Errorf("log: kafka required_acks %s not supported", s)
+	}
+}
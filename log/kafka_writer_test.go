@@ -0,0 +1,146 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/baisiyi/go-kits/log/rollwriter"
+)
+
+// mockMessageWriter records the batches sent.
+type mockMessageWriter struct {
+	mu      sync.Mutex
+	batches [][]kafkago.Message
+	closed  bool
+}
+
+func (m *mockMessageWriter) WriteMessages(_ context.Context, msgs ...kafkago.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// the values are reused after WriteMessages returns
+	batch := make([]kafkago.Message, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = msg
+		batch[i].Value = append([]byte(nil), msg.Value...)
+	}
+	m.batches = append(m.batches, batch)
+	return nil
+}
+
+func (m *mockMessageWriter) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func (m *mockMessageWriter) messages() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var msgs []string
+	for _, b := range m.batches {
+		for _, msg := range b {
+			msgs = append(msgs, strings.TrimSpace(string(msg.Value)))
+		}
+	}
+	return msgs
+}
+
+// TestKafkaWriter tests sending the entries in batches with the key.
+func TestKafkaWriter(t *testing.T) {
+	cfg := KafkaConfig{Key: "order-svc", BatchSize: 2, BatchTimeout: time.Hour}
+	cfg.setDefaults()
+	mw := &mockMessageWriter{}
+	w := startKafkaWriter(cfg, mw)
+
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "M"}), w, zap.InfoLevel)
+	logger := zap.New(core)
+	for _, msg := range []string{"a", "b", "c"} {
+		logger.Info(msg)
+	}
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	want := []string{`{"M":"a"}`, `{"M":"b"}`, `{"M":"c"}`}
+	if got := mw.messages(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("messages = %v, want %v", got, want)
+	}
+	if len(mw.batches) != 2 || len(mw.batches[0]) != 2 {
+		t.Errorf("Expected a full batch and a synced batch, got %d batches", len(mw.batches))
+	}
+	if string(mw.batches[0][0].Key) != "order-svc" {
+		t.Errorf("key = %q", mw.batches[0][0].Key)
+	}
+
+	logger.Info("d")
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := mw.messages(); len(got) != 4 || !mw.closed {
+		t.Errorf("Expected the queued entry sent before close, got %v", got)
+	}
+	if _, err := w.Write([]byte("late")); !errors.Is(err, rollwriter.ErrClosed) {
+		t.Errorf("Write after Close error = %v", err)
+	}
+}
+
+// TestKafkaWriterSendError tests that Sync returns the send error of the queued entries.
+func TestKafkaWriterSendError(t *testing.T) {
+	cfg := KafkaConfig{BatchTimeout: time.Hour}
+	cfg.setDefaults()
+	mw := &failingMessageWriter{err: errors.New("broker down")}
+	w := startKafkaWriter(cfg, mw)
+	if _, err := w.Write([]byte("a\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := w.Sync(); !errors.Is(err, mw.err) {
+		t.Errorf("Sync error = %v, want %v", err, mw.err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Errorf("Expected the error returned once, got %v", err)
+	}
+	if err := w.Close(); err != nil || !mw.closed {
+		t.Errorf("Close = %v, closed %v", err, mw.closed)
+	}
+}
+
+// failingMessageWriter fails all the writes.
+type failingMessageWriter struct {
+	err    error
+	closed bool
+}
+
+func (m *failingMessageWriter) WriteMessages(context.Context, ...kafkago.Message) error {
+	return m.err
+}
+
+func (m *failingMessageWriter) Close() error {
+	m.closed = true
+	return nil
+}
+
+// TestKafkaWriterConfig tests the invalid kafka writer configs.
+func TestKafkaWriterConfig(t *testing.T) {
+	for _, cfg := range []KafkaConfig{
+		{Topic: "logs"},
+		{Brokers: []string{"127.0.0.1:9092"}},
+		{Brokers: []string{"127.0.0.1:9092"}, Topic: "logs", Compression: "brotli"},
+		{Brokers: []string{"127.0.0.1:9092"}, Topic: "logs", RequiredAcks: "two"},
+	} {
+		if _, err := newKafkaWriter(cfg); err == nil {
+			t.Errorf("newKafkaWriter(%+v) expected error", cfg)
+		}
+	}
+	if GetWriter(OutputKafka) == nil {
+		t.Error("kafka writer not registered")
+	}
+}
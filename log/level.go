@@ -0,0 +1,173 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelController is implemented by the loggers whose level can be changed at runtime.
+type LevelController interface {
+	// SetLevel sets the level of the output named output, see OutputConfig.Name. If no
+	// output has the name, it sets all the outputs of the writer named output. An empty
+	// output sets all the outputs.
+	SetLevel(output, level string) error
+	// GetLevels returns the level of each output keyed by the output name.
+	GetLevels() map[string]string
+}
+
+type outputLevel struct {
+	output string // the output name
+	writer string
+	level  zap.AtomicLevel
+	// floor is the level of the writer core of an output with name levels, kept at the
+	// lower of level and the lowest name level nameMin, see namedLevelCore
+	floor   zap.AtomicLevel
+	nameMin zapcore.Level
+}
+
+func (o outputLevel) setLevel(lvl zapcore.Level) {
+	o.level.SetLevel(lvl)
+	if o.floor != (zap.AtomicLevel{}) {
+		o.floor.SetLevel(min(lvl, o.nameMin))
+	}
+}
+
+// outputNames returns the names of the outputs, see OutputConfig.Name.
+func outputNames(cfg Config) ([]string, error) {
+	var (
+		shared = make(map[string]int, len(cfg)) // the outputs of each writer without name
+		index  = make(map[string]int, len(cfg))
+		seen   = make(map[string]bool, len(cfg))
+		names  = make([]string, len(cfg))
+	)
+	for _, c := range cfg {
+		if c.Name == "" {
+			shared[c.Writer]++
+		}
+	}
+	for i, c := range cfg {
+		name := c.Name
+		if name == "" {
+			name = c.Writer
+			if shared[c.Writer] > 1 {
+				index[c.Writer]++
+				name = fmt.Sprintf("%s.%d", c.Writer, index[c.Writer])
+			}
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("log: output name %s duplicated", name)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names, nil
+}
+
+// ParseLevel parses the level name, the names are the keys of Levels except the empty one.
+func ParseLevel(level string) (zapcore.Level, error) {
+	lvl, ok := Levels[strings.ToLower(level)]
+	if !ok || level == "" {
+		return 0, fmt.Errorf("log: unknown level %q", level)
+	}
+	return lvl, nil
+}
+
+// SetLevel implements LevelController.
+func (z *ZapLogger) SetLevel(output, level string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	byName := false
+	for _, l := range z.levels {
+		if l.output == output {
+			byName = true
+			break
+		}
+	}
+	var found bool
+	for _, l := range z.levels {
+		if output == "" || (byName && l.output == output) || (!byName && l.writer == output) {
+			l.setLevel(lvl)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("log: output %s not found", output)
+	}
+	return nil
+}
+
+// GetLevels implements LevelController.
+func (z *ZapLogger) GetLevels() map[string]string {
+	levels := make(map[string]string, len(z.levels))
+	for _, l := range z.levels {
+		levels[l.output] = l.level.String()
+	}
+	return levels
+}
+
+// SetLevel 运行时调整默认logger指定输出的日志级别，output 为输出名称（见 OutputConfig.Name），
+// 没有该名称的输出时调整该 writer 的全部输出，为空时调整全部输出
+func SetLevel(output, level string) error {
+	lc, ok := GetDefaultLogger().(LevelController)
+	if !ok {
+		return fmt.Errorf("log: default logger %T does not support SetLevel", GetDefaultLogger())
+	}
+	return lc.SetLevel(output, level)
+}
+
+// GetLevels 获取默认logger各输出的日志级别，key 为输出名称
+func GetLevels() map[string]string {
+	if lc, ok := GetDefaultLogger().(LevelController); ok {
+		return lc.GetLevels()
+	}
+	return nil
+}
+
+type levelRequest struct {
+	Output string `json:"output"`
+	Level  string `json:"level"`
+}
+
+// LevelHandler returns the http handler of the default logger's levels.
+// GET returns the levels keyed by the output names as {"console":"info"}. PUT or
+// POST sets a level by the json body {"output":"console","level":"debug"} or the
+// same query params, the output is matched like SetLevel and an empty one sets all
+// the outputs.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			req := levelRequest{Output: r.URL.Query().Get("output"), Level: r.URL.Query().Get("level")}
+			if req.Level == "" {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					writeLevelError(w, http.StatusBadRequest, fmt.Errorf("log: decode level request: %w", err))
+					return
+				}
+			}
+			if err := SetLevel(req.Output, req.Level); err != nil {
+				writeLevelError(w, http.StatusBadRequest, err)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			writeLevelError(w, http.StatusMethodNotAllowed, fmt.Errorf("log: method %s not allowed", r.Method))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(GetLevels())
+	})
+}
+
+func writeLevelError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
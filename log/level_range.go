@@ -0,0 +1,60 @@
+package log
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// levelRangeCore only passes the entries in [min, max] to the wrapped core.
+type levelRangeCore struct {
+	zapcore.Core
+	min, max zapcore.Level
+}
+
+// newLevelRangeCore wraps core with the level range, empty means no limit.
+func newLevelRangeCore(core zapcore.Core, minLevel, maxLevel string) (zapcore.Core, error) {
+	c := &levelRangeCore{Core: core, min: zapcore.DebugLevel, max: zapcore.FatalLevel}
+	var err error
+	if minLevel != "" {
+		if c.min, err = ParseLevel(minLevel); err != nil {
+			return nil, err
+		}
+	}
+	if maxLevel != "" {
+		if c.max, err = ParseLevel(maxLevel); err != nil {
+			return nil, err
+		}
+	}
+	if c.min > c.max {
+		return nil, fmt.Errorf("log: min_level %s greater than max_level %s", c.min, c.max)
+	}
+	return c, nil
+}
+
+// Level returns the minimum enabled level of the core.
+func (c *levelRangeCore) Level() zapcore.Level {
+	return max(zapcore.LevelOf(c.Core), c.min)
+}
+
+// Enabled implements zapcore.Core.
+func (c *levelRangeCore) Enabled(l zapcore.Level) bool {
+	return c.inRange(l) && c.Core.Enabled(l)
+}
+
+// With implements zapcore.Core.
+func (c *levelRangeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelRangeCore{Core: c.Core.With(fields), min: c.min, max: c.max}
+}
+
+// Check implements zapcore.Core.
+func (c *levelRangeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.inRange(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *levelRangeCore) inRange(l zapcore.Level) bool {
+	return l >= c.min && l <= c.max
+}
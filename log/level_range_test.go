@@ -0,0 +1,145 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLevelRange tests splitting the entries into the files by the level range.
+func TestLevelRange(t *testing.T) {
+	dir := t.TempDir()
+	appLog, errLog := filepath.Join(dir, "app.log"), filepath.Join(dir, "app.error.log")
+	logger := NewZapLog(Config{
+		{Writer: OutputFile, Level: "debug", MaxLevel: "warn", WriteConfig: WriteConfig{Filename: appLog}},
+		{Writer: OutputFile, Level: "debug", MinLevel: "error", WriteConfig: WriteConfig{Filename: errLog}},
+	})
+	logger.Debug("debug msg")
+	logger.With(String("k", "v")).Warn("warn msg")
+	logger.Error("error msg")
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		return string(data)
+	}
+	app, errs := read(appLog), read(errLog)
+	for _, want := range []string{"debug msg", "warn msg"} {
+		if !strings.Contains(app, want) {
+			t.Errorf("app.log missing %q: %s", want, app)
+		}
+	}
+	if strings.Contains(app, "error msg") {
+		t.Errorf("app.log should not contain error entries: %s", app)
+	}
+	if !strings.Contains(errs, "error msg") || strings.Contains(errs, "warn msg") {
+		t.Errorf("app.error.log = %s", errs)
+	}
+
+	// the range is kept when the level is changed at runtime.
+	if err := logger.(LevelController).SetLevel(OutputFile, "info"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	logger.Debug("hidden msg")
+	logger.Sync()
+	if strings.Contains(read(appLog), "hidden msg") {
+		t.Error("Expected debug entries dropped after SetLevel")
+	}
+}
+
+// TestLevelRangeInvalid tests the invalid level ranges.
+func TestLevelRangeInvalid(t *testing.T) {
+	for _, r := range [][2]string{{"unknown", ""}, {"", "unknown"}, {"error", "info"}} {
+		if _, err := newLevelRangeCore(nil, r[0], r[1]); err == nil {
+			t.Errorf("newLevelRangeCore(%q, %q) expected error", r[0], r[1])
+		}
+	}
+}
+
+// TestLevelFiles tests splitting the file output into a file per level.
+func TestLevelFiles(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewZapLog(Config{{
+		Writer: OutputFile,
+		Level:  "debug",
+		WriteConfig: WriteConfig{
+			Filename:   filepath.Join(dir, "app.log"),
+			LevelFiles: map[string]string{"info": "app.info.log", "error": filepath.Join(dir, "err", "app.error.log")},
+		},
+	}})
+	logger.Debug("debug msg")
+	logger.Info("info msg")
+	logger.Warn("warn msg")
+	logger.Error("error msg")
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		return string(data)
+	}
+	info, errs := read(filepath.Join(dir, "app.info.log")), read(filepath.Join(dir, "err", "app.error.log"))
+	if !strings.Contains(info, "info msg") || !strings.Contains(info, "warn msg") ||
+		strings.Contains(info, "error msg") || strings.Contains(info, "debug msg") {
+		t.Errorf("app.info.log = %s", info)
+	}
+	if !strings.Contains(errs, "error msg") || strings.Contains(errs, "warn msg") {
+		t.Errorf("app.error.log = %s", errs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.log")); !os.IsNotExist(err) {
+		t.Errorf("Expected app.log not created, got %v", err)
+	}
+
+	// the level is shared by the files.
+	if err := logger.(LevelController).SetLevel(OutputFile, "error"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	logger.Info("hidden msg")
+	logger.Sync()
+	if strings.Contains(read(filepath.Join(dir, "app.info.log")), "hidden msg") {
+		t.Error("Expected info entries dropped after SetLevel")
+	}
+}
+
+// TestLevelFilesInvalid tests the invalid level files.
+func TestLevelFilesInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for _, files := range []map[string]string{{"verbose": "a.log"}, {"debug": "a.log", "trace": "b.log"}} {
+		c := &OutputConfig{Writer: OutputFile, WriteConfig: WriteConfig{Filename: filepath.Join(dir, "app.log"), LevelFiles: files}}
+		if _, _, _, err := newFileCore(c); err == nil {
+			t.Errorf("newFileCore(%v) expected error", files)
+		}
+	}
+}
+
+// TestRotator tests selecting the rotation backend of the file outputs.
+func TestRotator(t *testing.T) {
+	dir := t.TempDir()
+	for _, rotator := range []string{"", RotatorNative, RotatorRotateLogs, RotatorLumberjack} {
+		c := &OutputConfig{Writer: OutputFile, Level: "info", WriteConfig: WriteConfig{
+			Filename: filepath.Join(dir, "app-"+rotator+".log"),
+			Rotator:  rotator,
+		}}
+		if _, _, _, err := newFileCore(c); err != nil {
+			t.Errorf("newFileCore(%q) failed: %v", rotator, err)
+		}
+	}
+	for _, wc := range []WriteConfig{
+		{Filename: filepath.Join(dir, "a.log"), Rotator: "logrotate"},
+		{Filename: filepath.Join(dir, "b.log"), Rotator: RotatorLumberjack, MaxDiskUsage: 100},
+	} {
+		if _, _, _, err := newFileCore(&OutputConfig{Writer: OutputFile, WriteConfig: wc}); err == nil {
+			t.Errorf("newFileCore(%+v) expected error", wc)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSetLevel tests changing the level of the outputs at runtime.
+func TestSetLevel(t *testing.T) {
+	logger := NewZapLog(Config{{Writer: OutputConsole, Level: "info"}}).(*ZapLogger)
+	child := logger.Named("child")
+	if logger.logger.Core().Enabled(Levels["debug"]) {
+		t.Fatal("Expected debug disabled")
+	}
+	if err := logger.SetLevel(OutputConsole, "DEBUG"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	if !child.(*ZapLogger).logger.Core().Enabled(Levels["debug"]) {
+		t.Error("Expected debug enabled on the child logger")
+	}
+	if got := logger.GetLevels()[OutputConsole]; got != "debug" {
+		t.Errorf("GetLevels = %s, want debug", got)
+	}
+	if err := logger.SetLevel(OutputFile, "info"); err == nil {
+		t.Error("Expected error for missing output")
+	}
+	if err := logger.SetLevel("", "verbose"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
+
+// TestSetLevelOutputNames tests the levels keyed by the output names when several outputs
+// use the same writer.
+func TestSetLevelOutputNames(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := newZapLog(Config{
+		{Writer: OutputFile, Level: "info", WriteConfig: WriteConfig{Filename: filepath.Join(dir, "app.log")}},
+		{Writer: OutputFile, Level: "info", WriteConfig: WriteConfig{Filename: filepath.Join(dir, "app.error.log")}},
+		{Name: "audit", Writer: OutputFile, Level: "info", WriteConfig: WriteConfig{Filename: filepath.Join(dir, "audit.log")}},
+	}, 0, loggerOptions{})
+	if err != nil {
+		t.Fatalf("newZapLog failed: %v", err)
+	}
+	defer logger.Close()
+	want := map[string]string{"file.1": "info", "file.2": "info", "audit": "info"}
+	if got := logger.GetLevels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLevels = %v, want %v", got, want)
+	}
+
+	if err := logger.SetLevel("file.2", "error"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	want["file.2"] = "error"
+	if got := logger.GetLevels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLevels = %v, want %v", got, want)
+	}
+
+	// the writer name sets all the outputs of the writer
+	if err := logger.SetLevel(OutputFile, "debug"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	want = map[string]string{"file.1": "debug", "file.2": "debug", "audit": "debug"}
+	if got := logger.GetLevels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLevels = %v, want %v", got, want)
+	}
+
+	if _, err := newZapLog(Config{
+		{Name: "app", Writer: OutputConsole},
+		{Name: "app", Writer: OutputConsole},
+	}, 0, loggerOptions{}); err == nil {
+		t.Error("Expected error for duplicated output names")
+	}
+}
+
+// TestLevelHandler tests the http handler of the default logger's levels.
+func TestLevelHandler(t *testing.T) {
+	oldLogger := defaultLogger
+	SetDefault(NewZapLog(Config{{Writer: OutputConsole, Level: "info"}}))
+	defer SetDefault(oldLogger)
+	h := LevelHandler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"warn"}`)))
+	if w.Code != http.StatusOK || GetLevels()[OutputConsole] != "warn" {
+		t.Errorf("PUT = %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/?output=console&level=error", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"console":"error"`) {
+		t.Errorf("POST = %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/?level=bad", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected bad request, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected method not allowed, got %d", w.Code)
+	}
+}
@@ -5,8 +5,9 @@ log包的全局入口
 package log
 
 import (
-	"fmt"
 	"sync"
+
+	"go.uber.org/zap"
 )
 
 var (
@@ -37,11 +38,7 @@ func ensureInit() {
 
 // Init 初始化日志系统，使用默认配置（控制台输出info级别）
 func Init(opts ...Option) {
-	cfg := defaultConfig
-	for _, opt := range opts {
-		opt.apply(&cfg)
-	}
-	SetDefault(NewZapLog(cfg))
+	SetDefault(NewZapLog(defaultConfig, opts...))
 }
 
 // SetDefault 设置默认logger
@@ -110,6 +107,26 @@ func Panic(msg string, fields ...Field) {
 	GetDefaultLogger().Panic(msg, fields...)
 }
 
+// Debugw 键值对 debug 日志
+func Debugw(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Debugw(msg, keysAndValues...)
+}
+
+// Infow 键值对 info 日志
+func Infow(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Infow(msg, keysAndValues...)
+}
+
+// Warnw 键值对 warn 日志
+func Warnw(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Warnw(msg, keysAndValues...)
+}
+
+// Errorw 键值对 error 日志
+func Errorw(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Errorw(msg, keysAndValues...)
+}
+
 // With 创建带有上下文的logger
 func With(fields ...Field) Logger {
 	return GetDefaultLogger().With(fields...)
@@ -120,6 +137,16 @@ func Named(name string) Logger {
 	return GetDefaultLogger().Named(name)
 }
 
+// WithCallerSkip 创建多跳过 delta 层调用栈的logger，用于中间件、封装库修正日志的调用位置
+func WithCallerSkip(delta int) Logger {
+	return GetDefaultLogger().WithCallerSkip(delta)
+}
+
+// WithOptions 创建应用了 zap.Option 的logger，默认 Logger 不是 zap 实现时 opts 可能被忽略
+func WithOptions(opts ...zap.Option) Logger {
+	return GetDefaultLogger().WithOptions(opts...)
+}
+
 // Sync 同步日志缓冲
 func Sync() error {
 	return GetDefaultLogger().Sync()
@@ -127,10 +154,10 @@ func Sync() error {
 
 // Fatalf 格式化 fatal 日志
 func Fatalf(format string, args ...interface{}) {
-	GetDefaultLogger().Fatal(fmt.Sprintf(format, args...))
+	GetDefaultLogger().Fatalf(format, args...)
 }
 
 // Panicf 格式化 panic 日志
 func Panicf(format string, args ...interface{}) {
-	GetDefaultLogger().Panic(fmt.Sprintf(format, args...))
+	GetDefaultLogger().Panicf(format, args...)
 }
@@ -1,8 +1,14 @@
 package log
 
 import (
+	"errors"
 	"sync"
 	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/baisiyi/go-kits/errs"
 )
 
 // mockLogger is a mock implementation of Logger for testing.
@@ -93,10 +99,28 @@ func (m *mockLogger) Fatal(msg string, fields ...Field) {}
 
 func (m *mockLogger) Panic(msg string, fields ...Field) {}
 
+func (m *mockLogger) Debugw(msg string, keysAndValues ...interface{}) {}
+
+func (m *mockLogger) Infow(msg string, keysAndValues ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.infoCalled = true
+	m.lastMsg = msg
+	m.lastArgs = keysAndValues
+}
+
+func (m *mockLogger) Warnw(msg string, keysAndValues ...interface{}) {}
+
+func (m *mockLogger) Errorw(msg string, keysAndValues ...interface{}) {}
+
 func (m *mockLogger) With(fields ...Field) Logger { return m }
 
 func (m *mockLogger) Named(name string) Logger { return m }
 
+func (m *mockLogger) WithCallerSkip(delta int) Logger { return m }
+
+func (m *mockLogger) WithOptions(opts ...zap.Option) Logger { return m }
+
 func (m *mockLogger) Sync() error { return nil }
 
 // TestInfof tests the Infof convenience function.
@@ -171,3 +195,33 @@ func TestGetDefaultLogger(t *testing.T) {
 		t.Error("Default logger should not be nil")
 	}
 }
+
+// TestInfow tests the Infow convenience function.
+func TestInfow(t *testing.T) {
+	mock := &mockLogger{}
+	oldLogger := defaultLogger
+	SetDefault(mock)
+	defer SetDefault(oldLogger)
+
+	Infow("test message", "key", "value")
+	if !mock.infoCalled || len(mock.lastArgs) != 2 {
+		t.Error("Infow was not called on mock logger")
+	}
+}
+
+// TestErr tests the error field with the code of errs.Error.
+func TestErr(t *testing.T) {
+	enc := zapcore.NewMapObjectEncoder()
+	Err(errs.Wrap(errors.New("no rows"), errs.CodeNotFound, "user not found")).AddTo(enc)
+	if enc.Fields["error"] != "user not found: no rows" || enc.Fields["error_code"] != errs.CodeNotFound ||
+		enc.Fields["errorVerbose"] == nil {
+		t.Errorf("fields = %v", enc.Fields)
+	}
+
+	enc = zapcore.NewMapObjectEncoder()
+	Err(errors.New("boom")).AddTo(enc)
+	Err(nil).AddTo(enc)
+	if len(enc.Fields) != 1 || enc.Fields["error"] != "boom" {
+		t.Errorf("fields = %v", enc.Fields)
+	}
+}
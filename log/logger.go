@@ -1,7 +1,12 @@
 package log
 
 import (
+	"errors"
+
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/baisiyi/go-kits/errs"
 )
 
 // Logger 日志接口
@@ -19,10 +24,24 @@ type Logger interface {
 	Infof(format string, args ...interface{})
 	Warnf(format string, args ...interface{})
 	Errorf(format string, args ...interface{})
+	// Fatalf 写入日志后退出进程，Panicf 写入日志后 panic
+	Fatalf(format string, args ...interface{})
+	Panicf(format string, args ...interface{})
+
+	// 键值对日志，keysAndValues 为交替的 key、value，也可以直接传入 Field
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
 
 	// 上下文
 	With(fields ...Field) Logger
 	Named(name string) Logger
+	// WithCallerSkip 返回多跳过 delta 层调用栈的 logger，用于封装 Logger 的库修正调用位置
+	WithCallerSkip(delta int) Logger
+	// WithOptions 返回应用了 zap.Option 的 logger，用于附加钩子、开发模式、自定义时钟等 zap 功能；
+	// 非 zap 实现可以忽略 opts
+	WithOptions(opts ...zap.Option) Logger
 
 	// 同步
 	Sync() error
@@ -45,3 +64,27 @@ var (
 	ByteString = zap.ByteString
 	Any        = zap.Any
 )
+
+// Err 返回错误字段：error 为错误信息（实现 fmt.Formatter 时同时输出 errorVerbose），
+// err 为 errs.Error 时附带 error_code，err 为 nil 时不输出
+func Err(err error) Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Inline(codedError{err})
+}
+
+// codedError 将错误和错误码作为同级字段输出
+type codedError struct {
+	err error
+}
+
+// MarshalLogObject 实现 zapcore.ObjectMarshaler
+func (e codedError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	zap.Error(e.err).AddTo(enc)
+	var coded *errs.Error
+	if errors.As(e.err, &coded) {
+		enc.AddInt("error_code", coded.Code())
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The built-in patterns of MaskConfig.Builtin.
+var builtinMaskPatterns = map[string]string{
+	// phone is the mainland China mobile number.
+	"phone": `\b1[3-9]\d{9}\b`,
+	"email": `[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`,
+	// id_card is the 18 digits resident identity card number of mainland China.
+	"id_card":   `\b\d{17}[\dXx]\b`,
+	"bank_card": `\b\d{16,19}\b`,
+}
+
+const defaultMaskReplacement = "***"
+
+// maskCore redacts the sensitive values before the wrapped core encodes them: the
+// values of the masked keys are replaced as a whole and the matches of the patterns
+// in the message and the string, []byte and error fields are replaced.
+type maskCore struct {
+	zapcore.Core
+	keys        map[string]struct{}
+	pattern     *regexp.Regexp // the patterns joined, nil if none
+	replacement string
+}
+
+// newMaskCore wraps core with the masking of the output config.
+func newMaskCore(core zapcore.Core, c *MaskConfig) (zapcore.Core, error) {
+	m := &maskCore{Core: core, keys: make(map[string]struct{}, len(c.Fields)), replacement: c.Replacement}
+	if m.replacement == "" {
+		m.replacement = defaultMaskReplacement
+	}
+	for _, k := range c.Fields {
+		m.keys[strings.ToLower(k)] = struct{}{}
+	}
+	patterns := make([]string, 0, len(c.Builtin)+len(c.Patterns))
+	for _, name := range c.Builtin {
+		p, ok := builtinMaskPatterns[name]
+		if !ok {
+			return nil, fmt.Errorf("log: mask builtin pattern %s not found", name)
+		}
+		patterns = append(patterns, "(?:"+p+")")
+	}
+	for _, p := range c.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("log: mask pattern %s invalid: %w", p, err)
+		}
+		patterns = append(patterns, "(?:"+p+")")
+	}
+	if len(patterns) > 0 {
+		m.pattern = regexp.MustCompile(strings.Join(patterns, "|"))
+	}
+	return m, nil
+}
+
+// With implements zapcore.Core.
+func (c *maskCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(c.maskFields(fields))
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *maskCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+	inner.Entry.Message = c.mask(inner.Entry.Message)
+	return ce.AddCore(ent, &maskedEntry{maskCore: c, inner: inner})
+}
+
+// mask replaces the matches of the patterns in s.
+func (c *maskCore) mask(s string) string {
+	if c.pattern == nil {
+		return s
+	}
+	return c.pattern.ReplaceAllLiteralString(s, c.replacement)
+}
+
+// maskFields returns the masked fields, fields itself if none is changed.
+func (c *maskCore) maskFields(fields []zapcore.Field) []zapcore.Field {
+	var masked []zapcore.Field
+	for i, f := range fields {
+		mf, ok := c.maskField(f)
+		if !ok {
+			continue
+		}
+		if masked == nil {
+			masked = append(make([]zapcore.Field, 0, len(fields)), fields...)
+		}
+		masked[i] = mf
+	}
+	if masked == nil {
+		return fields
+	}
+	return masked
+}
+
+// maskField returns the masked field and true if f is changed.
+func (c *maskCore) maskField(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type == zapcore.NamespaceType || f.Type == zapcore.SkipType {
+		return f, false
+	}
+	if _, ok := c.keys[strings.ToLower(f.Key)]; ok {
+		return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: c.replacement}, true
+	}
+	if c.pattern == nil {
+		return f, false
+	}
+	var s string
+	switch f.Type {
+	case zapcore.StringType:
+		s = f.String
+	case zapcore.ByteStringType:
+		b, _ := f.Interface.([]byte)
+		s = string(b)
+	case zapcore.ErrorType:
+		err, _ := f.Interface.(error)
+		if err == nil {
+			return f, false
+		}
+		s = err.Error()
+	default:
+		return f, false
+	}
+	if !c.pattern.MatchString(s) {
+		return f, false
+	}
+	return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: c.mask(s)}, true
+}
+
+// maskedEntry writes the checked entry of the wrapped core with the fields masked.
+type maskedEntry struct {
+	*maskCore
+	inner *zapcore.CheckedEntry
+}
+
+// Write implements zapcore.Core.
+func (e *maskedEntry) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeChecked(e.inner, ent, e.maskFields(fields))
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// TestMask tests that the masked keys and the matches of the patterns are redacted
+// in the message, the fields and the fields added by With.
+func TestMask(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "mask.log")
+	logger := NewZapLog(Config{{
+		Writer:      OutputFile,
+		Formatter:   FormatterJson,
+		Level:       "info",
+		WriteConfig: WriteConfig{Filename: filename},
+		Mask: &MaskConfig{
+			Fields:   []string{"Password"},
+			Patterns: []string{`token-[a-z0-9]+`},
+			Builtin:  []string{"phone", "email", "id_card"},
+		},
+	}})
+	logger.With(String("contact", "alice@example.com")).Info("login 13812345678",
+		String("password", "secret"),
+		Int("uid", 10086),
+		ByteString("auth", []byte("token-abc123")),
+		Any("error", errors.New("id 11010519491231002X rejected")),
+		String("order", "20240101"))
+	_ = logger.Sync()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(content, &entry); err != nil {
+		t.Fatalf("unmarshal %q: %v", content, err)
+	}
+	want := map[string]any{
+		"M":        "login ***",
+		"contact":  "***",
+		"password": "***",
+		"uid":      float64(10086),
+		"auth":     "***",
+		"error":    "id *** rejected",
+		"order":    "20240101",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+	for _, s := range []string{"13812345678", "alice@example.com", "secret", "token-abc123", "11010519491231002X"} {
+		if strings.Contains(string(content), s) {
+			t.Errorf("%s not masked in %s", s, content)
+		}
+	}
+}
+
+// TestMaskInvalid tests that the invalid patterns fail the logger creation.
+func TestMaskInvalid(t *testing.T) {
+	for _, mask := range []MaskConfig{{Patterns: []string{"("}}, {Builtin: []string{"passport"}}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for %+v", mask)
+				}
+			}()
+			NewZapLog(Config{{Writer: OutputConsole, Mask: &mask}})
+		}()
+	}
+}
+
+// TestMaskErrorOutput tests that the write errors are reported to the ErrorOutput of the logger.
+func TestMaskErrorOutput(t *testing.T) {
+	testErrorOutput(t, func(core zapcore.Core) zapcore.Core {
+		c, err := newMaskCore(core, &MaskConfig{Fields: []string{"password"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	})
+}
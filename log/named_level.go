@@ -0,0 +1,81 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// namedLevels is the parsed name levels of an output.
+type namedLevels struct {
+	levels   map[string]zapcore.Level
+	minLevel zapcore.Level // the minimum of the name levels
+}
+
+// parseNamedLevels parses the name levels, such as {"dao": "warn"}.
+func parseNamedLevels(levels map[string]string) (*namedLevels, error) {
+	n := &namedLevels{levels: make(map[string]zapcore.Level, len(levels)), minLevel: zapcore.FatalLevel}
+	for name, level := range levels {
+		lvl, err := ParseLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("level of logger %s: %w", name, err)
+		}
+		n.levels[name] = lvl
+		n.minLevel = min(n.minLevel, lvl)
+	}
+	return n, nil
+}
+
+// namedLevelCore overrides the level of the entries by the logger name. The name
+// levels are looked up from the full name to its ancestors split by dots, like
+// "http.access", then "http", the entries of the other names use level. The wrapped
+// core must enable the name levels, it checks the entries passed by the level as usual,
+// e.g. by the level ranges of level_files.
+type namedLevelCore struct {
+	zapcore.Core
+	*namedLevels
+	level zapcore.LevelEnabler // the level of the entries of the other names
+}
+
+// Level returns the minimum enabled level of the core.
+func (c *namedLevelCore) Level() zapcore.Level {
+	return max(zapcore.LevelOf(c.Core), min(zapcore.LevelOf(c.level), c.minLevel))
+}
+
+// Enabled implements zapcore.Core. The logger name is unknown here, so it is enabled
+// if any name may log the level, and Check filters by the name.
+func (c *namedLevelCore) Enabled(l zapcore.Level) bool {
+	return (l >= c.minLevel || c.level.Enabled(l)) && c.Core.Enabled(l)
+}
+
+// With implements zapcore.Core.
+func (c *namedLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namedLevelCore{Core: c.Core.With(fields), namedLevels: c.namedLevels, level: c.level}
+}
+
+// Check implements zapcore.Core.
+func (c *namedLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if lvl, ok := c.lookup(ent.LoggerName); ok {
+		if ent.Level < lvl {
+			return ce
+		}
+	} else if !c.level.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (n *namedLevels) lookup(name string) (zapcore.Level, bool) {
+	for name != "" {
+		if lvl, ok := n.levels[name]; ok {
+			return lvl, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return 0, false
+}
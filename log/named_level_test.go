@@ -0,0 +1,117 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestNamedLevels tests overriding the levels of the named loggers and their children.
+func TestNamedLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger := NewZapLog(Config{{
+		Writer:      OutputFile,
+		Level:       "info",
+		Levels:      map[string]string{"dao": "warn", "http": "error", "http.access": "debug"},
+		WriteConfig: WriteConfig{Filename: path},
+	}})
+	logger.Info("root info")
+	logger.Debug("root debug")
+	dao := logger.Named("dao")
+	dao.Info("dao info")
+	dao.Named("user").Warn("dao.user warn")
+	dao.With(String("k", "v")).Warn("dao warn")
+	logger.Named("http").Warn("http warn")
+	logger.Named("http").Named("access").Debug("http.access debug")
+	logger.Named("daox").Info("daox info")
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{"root info", "dao.user warn", "dao warn", "http.access debug", "daox info"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %q: %s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"root debug", "dao info", "http warn"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("unexpected %q: %s", unwanted, content)
+		}
+	}
+}
+
+// TestNamedLevelsLevelFiles tests that the entries of the name levels are checked by
+// the level ranges of level_files and follow SetLevel of the output.
+func TestNamedLevelsLevelFiles(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewZapLog(Config{{
+		Writer: OutputFile,
+		Level:  "info",
+		Levels: map[string]string{"dao": "debug", "http": "error"},
+		WriteConfig: WriteConfig{
+			Filename:   filepath.Join(dir, "app.log"),
+			LevelFiles: map[string]string{"debug": "debug.log", "info": "info.log", "error": "error.log"},
+		},
+	}})
+	dao := logger.Named("dao")
+	dao.Debug("dao debug")
+	dao.Info("dao info")
+	dao.Error("dao error")
+	logger.Named("http").Warn("http warn")
+	logger.Debug("root debug")
+	logger.Info("root info")
+	if err := logger.(LevelController).SetLevel(OutputFile, "error"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	logger.Info("root info after")
+	dao.Debug("dao debug after")
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		return string(data)
+	}
+	want := map[string][]string{
+		"debug.log": {"dao debug", "dao debug after"},
+		"info.log":  {"dao info", "root info"},
+		"error.log": {"dao error"},
+	}
+	for name, msgs := range want {
+		content := read(name)
+		if got := strings.Count(content, "\n"); got != len(msgs) {
+			t.Errorf("%s has %d lines, want %v: %s", name, got, msgs, content)
+		}
+		for _, msg := range msgs {
+			if !strings.Contains(content, "\t"+msg+"\n") {
+				t.Errorf("%s missing %q: %s", name, msg, content)
+			}
+		}
+	}
+}
+
+// TestNamedLevelsInvalid tests the invalid name levels.
+func TestNamedLevelsInvalid(t *testing.T) {
+	if _, err := parseNamedLevels(map[string]string{"dao": "verbose"}); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
+
+// TestWithNamedLevels tests that the option merges the name levels into every output.
+func TestWithNamedLevels(t *testing.T) {
+	cfg := []OutputConfig{{Levels: map[string]string{"dao": "warn", "cache": "error"}}, {}}
+	WithNamedLevels(map[string]string{"dao": "error"}).apply(&cfg)
+	if cfg[0].Levels["dao"] != "error" || cfg[0].Levels["cache"] != "error" || cfg[1].Levels["dao"] != "error" {
+		t.Errorf("levels = %v, %v", cfg[0].Levels, cfg[1].Levels)
+	}
+}
@@ -1,5 +1,10 @@
 package log
 
+import (
+	"os"
+	"time"
+)
+
 // Option 日志配置选项
 type Option interface {
 	apply(cfg *[]OutputConfig)
@@ -11,6 +16,38 @@ func (f optionFunc) apply(cfg *[]OutputConfig) {
 	f(cfg)
 }
 
+// loggerOptions 作用于整个 logger 而不是各个输出的配置
+type loggerOptions struct {
+	exit func(code int) // 替换 Fatal 写入日志后调用的 os.Exit
+}
+
+// loggerOption 由作用于整个 logger 的 Option 实现
+type loggerOption interface {
+	applyLogger(o *loggerOptions)
+}
+
+type loggerOptionFunc func(o *loggerOptions)
+
+func (f loggerOptionFunc) apply(*[]OutputConfig) {}
+
+func (f loggerOptionFunc) applyLogger(o *loggerOptions) {
+	f(o)
+}
+
+// applyOptions 将 opts 应用到 cfg 的副本上，返回输出配置和 logger 配置
+func applyOptions(cfg Config, opts []Option) (Config, loggerOptions) {
+	// 复制配置，避免 Option 修改调用方的配置
+	cfg = append(Config(nil), cfg...)
+	var lo loggerOptions
+	for _, opt := range opts {
+		opt.apply((*[]OutputConfig)(&cfg))
+		if o, ok := opt.(loggerOption); ok {
+			o.applyLogger(&lo)
+		}
+	}
+	return cfg, lo
+}
+
 // WithLevel 设置日志级别
 func WithLevel(level string) Option {
 	return optionFunc(func(cfg *[]OutputConfig) {
@@ -39,6 +76,33 @@ func WithMaxSize(maxSize int64) Option {
 	})
 }
 
+// WithMaxDiskUsage 设置日志目录的总大小上限(MB)，统计目录下所有文件输出的日志文件和备份文件
+func WithMaxDiskUsage(maxDiskUsage int64) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].WriteConfig.MaxDiskUsage = maxDiskUsage
+		}
+	})
+}
+
+// WithFileMode 设置日志文件权限，如 0640，不受 umask 影响
+func WithFileMode(mode os.FileMode) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].WriteConfig.FileMode = mode
+		}
+	})
+}
+
+// WithDirMode 设置新建日志目录的权限，如 0750，不受 umask 影响
+func WithDirMode(mode os.FileMode) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].WriteConfig.DirMode = mode
+		}
+	})
+}
+
 // WithMaxAge 设置日志文件保留天数
 func WithMaxAge(maxAge int) Option {
 	return optionFunc(func(cfg *[]OutputConfig) {
@@ -66,6 +130,25 @@ func WithRotationTime(minutes int) Option {
 	})
 }
 
+// WithRotationAlign 设置按日历边界轮转，align 为 hour（整点）或 day（零点），timezone 为 IANA 时区名，空表示本地时区
+func WithRotationAlign(align, timezone string) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].WriteConfig.RotationAlign = align
+			(*cfg)[i].WriteConfig.RotationTimezone = timezone
+		}
+	})
+}
+
+// WithRotator 设置文件轮转的实现：native（rotatelogs）或 lumberjack
+func WithRotator(rotator string) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].WriteConfig.Rotator = rotator
+		}
+	})
+}
+
 // WithJSONFormatter 设置JSON格式
 func WithJSONFormatter() Option {
 	return optionFunc(func(cfg *[]OutputConfig) {
@@ -84,6 +167,24 @@ func WithConsoleFormatter() Option {
 	})
 }
 
+// WithDevFormatter 设置开发格式：列对齐、彩色输出，嵌套字段多行展示，适合本地开发时在终端阅读
+func WithDevFormatter() Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].Formatter = FormatterDev
+		}
+	})
+}
+
+// WithConsoleTarget 设置控制台输出的目标：stdout、stderr 或 RegisterWriterTarget 注册的名称
+func WithConsoleTarget(target string) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].WriteConfig.Target = target
+		}
+	})
+}
+
 // WithColor 启用彩色输出
 func WithColor() Option {
 	return optionFunc(func(cfg *[]OutputConfig) {
@@ -92,3 +193,100 @@ func WithColor() Option {
 		}
 	})
 }
+
+// WithTimeZone 设置所有输出时间戳的时区，如 UTC、Asia/Shanghai，默认为本地时区
+func WithTimeZone(tz string) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].FormatConfig.TimeZone = tz
+		}
+	})
+}
+
+// WithHooks 为所有输出添加钩子，每条写入的日志都会调用
+func WithHooks(hooks ...Hook) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].HookFuncs = append((*cfg)[i].HookFuncs, hooks...)
+		}
+	})
+}
+
+// WithExitFunc 替换 Fatal/Fatalf 写入日志后调用的 os.Exit，用于测试等场景，fn 返回后 Fatal 也随之返回。
+// 作用于整个 logger，与输出无关
+func WithExitFunc(fn func(code int)) Option {
+	return loggerOptionFunc(func(o *loggerOptions) {
+		o.exit = fn
+	})
+}
+
+// WithGlobalFields 为所有输出的每条日志添加静态字段，如服务名、环境、版本，与输出已配置的 Fields 合并，同名时覆盖
+func WithGlobalFields(fields map[string]string) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			merged := make(map[string]string, len((*cfg)[i].Fields)+len(fields))
+			for k, v := range (*cfg)[i].Fields {
+				merged[k] = v
+			}
+			for k, v := range fields {
+				merged[k] = v
+			}
+			(*cfg)[i].Fields = merged
+		}
+	})
+}
+
+// WithNamedLevels 为所有输出按 logger 名称设置日志级别，如 {"dao": "warn"}，与输出已配置的 Levels 合并，同名时覆盖
+func WithNamedLevels(levels map[string]string) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			merged := make(map[string]string, len((*cfg)[i].Levels)+len(levels))
+			for k, v := range (*cfg)[i].Levels {
+				merged[k] = v
+			}
+			for k, v := range levels {
+				merged[k] = v
+			}
+			(*cfg)[i].Levels = merged
+		}
+	})
+}
+
+// WithStacktraceLevel 为所有输出设置自动附加调用栈的级别，如 error、panic，off 表示不附加
+func WithStacktraceLevel(level string) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].StacktraceLevel = level
+		}
+	})
+}
+
+// WithDedup 为所有输出开启重复日志折叠，window 内连续相同的日志只输出第一条，
+// 结束时输出 "last message repeated N times" 汇总，window 为 0 时使用默认的 10s
+func WithDedup(window time.Duration) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].Dedup = &DedupConfig{Window: window}
+		}
+	})
+}
+
+// WithFallback 为所有输出设置备用输出，输出持续写入失败 after 后改为写入 target，
+// 并定期重试原输出，target 为空时写入 stderr，after 为 0 时使用默认的 10s
+func WithFallback(target string, after time.Duration) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			(*cfg)[i].Fallback = &FallbackConfig{Target: target, After: after}
+		}
+	})
+}
+
+// WithMask 为所有输出设置敏感信息脱敏，脱敏后的值不会写入输出
+func WithMask(mask MaskConfig) Option {
+	return optionFunc(func(cfg *[]OutputConfig) {
+		for i := range *cfg {
+			m := mask
+			(*cfg)[i].Mask = &m
+		}
+	})
+}
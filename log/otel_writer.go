@@ -0,0 +1,279 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	RegisterWriter(OutputOTel, WriterFactoryFunc(defaultOTelWriterFactory))
+}
+
+// otelScope is the instrumentation scope of the log records.
+const otelScope = "github.com/baisiyi/go-kits/log"
+
+// defaultOTelWriterFactory creates an opentelemetry writer.
+func defaultOTelWriterFactory(name string, dec *Decoder) error {
+	c := dec.OutputConfig
+	provider := c.OTelConfig.LoggerProvider
+	if provider == nil {
+		p, err := newOTelLoggerProvider(c.OTelConfig)
+		if err != nil {
+			return err
+		}
+		provider = p
+	}
+	lvl := zap.NewAtomicLevelAt(Levels[c.Level])
+	dec.Core = newOTelCore(provider, lvl, c.OTelConfig.timeout())
+	dec.ZapLevel = lvl
+	return nil
+}
+
+// newOTelLoggerProvider creates a LoggerProvider exporting the records by OTLP over HTTP
+// in batches.
+func newOTelLoggerProvider(cfg OTelConfig) (*sdklog.LoggerProvider, error) {
+	var opts []otlploghttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlploghttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.URLPath != "" {
+		opts = append(opts, otlploghttp.WithURLPath(cfg.URLPath))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlploghttp.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlploghttp.WithHeaders(cfg.Headers))
+	}
+	switch cfg.Compression {
+	case "", "none":
+	case "gzip":
+		opts = append(opts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+	default:
+		return nil, fmt.Errorf("log: otel compression %s not supported", cfg.Compression)
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, otlploghttp.WithTimeout(cfg.Timeout))
+	}
+	exporter, err := otlploghttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("log: create otlp exporter error: %w", err)
+	}
+
+	var batchOpts []sdklog.BatchProcessorOption
+	if cfg.BatchSize > 0 {
+		batchOpts = append(batchOpts, sdklog.WithExportMaxBatchSize(cfg.BatchSize))
+	}
+	if cfg.QueueSize > 0 {
+		batchOpts = append(batchOpts, sdklog.WithMaxQueueSize(cfg.QueueSize))
+	}
+	if cfg.ExportInterval > 0 {
+		batchOpts = append(batchOpts, sdklog.WithExportInterval(cfg.ExportInterval))
+	}
+	if cfg.Timeout > 0 {
+		batchOpts = append(batchOpts, sdklog.WithExportTimeout(cfg.Timeout))
+	}
+	res := resource.Default()
+	if cfg.ServiceName != "" {
+		res, err = resource.Merge(res, resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+		if err != nil {
+			return nil, fmt.Errorf("log: otel resource error: %w", err)
+		}
+	}
+	return sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter, batchOpts...)),
+	), nil
+}
+
+// otelCore is a zapcore.Core emitting the entries as opentelemetry log records, the
+// fields are converted into the attributes of the records.
+type otelCore struct {
+	zapcore.LevelEnabler
+	provider otellog.LoggerProvider
+	logger   otellog.Logger
+	attrs    []otellog.KeyValue
+	timeout  time.Duration
+}
+
+func newOTelCore(provider otellog.LoggerProvider, enab zapcore.LevelEnabler, timeout time.Duration) *otelCore {
+	return &otelCore{
+		LevelEnabler: enab,
+		provider:     provider,
+		logger:       provider.Logger(otelScope),
+		timeout:      timeout,
+	}
+}
+
+// With adds the fields to the core.
+func (c *otelCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.attrs = append(c.attrs[:len(c.attrs):len(c.attrs)], otelAttributes(fields)...)
+	return &clone
+}
+
+// Check adds the core to the checked entry if the level is enabled.
+func (c *otelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write emits the entry as a log record.
+func (c *otelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var r otellog.Record
+	r.SetTimestamp(ent.Time)
+	r.SetObservedTimestamp(time.Now())
+	r.SetSeverity(otelSeverity(ent.Level))
+	r.SetSeverityText(ent.Level.CapitalString())
+	r.SetBody(otellog.StringValue(ent.Message))
+	r.AddAttributes(c.attrs...)
+	r.AddAttributes(otelAttributes(fields)...)
+	if ent.LoggerName != "" {
+		r.AddAttributes(otellog.String("logger", ent.LoggerName))
+	}
+	if ent.Caller.Defined {
+		r.AddAttributes(
+			otellog.String("code.filepath", ent.Caller.File),
+			otellog.Int("code.lineno", ent.Caller.Line),
+		)
+		if ent.Caller.Function != "" {
+			r.AddAttributes(otellog.String("code.function", ent.Caller.Function))
+		}
+	}
+	if ent.Stack != "" {
+		r.AddAttributes(otellog.String("code.stacktrace", ent.Stack))
+	}
+	c.logger.Emit(context.Background(), r)
+	return nil
+}
+
+// Sync flushes the records buffered by the provider.
+func (c *otelCore) Sync() error {
+	f, ok := c.provider.(interface{ ForceFlush(context.Context) error })
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return f.ForceFlush(ctx)
+}
+
+// otelSeverity maps the zap level to the opentelemetry severity.
+func otelSeverity(l zapcore.Level) otellog.Severity {
+	switch l {
+	case zapcore.DebugLevel:
+		return otellog.SeverityDebug
+	case zapcore.InfoLevel:
+		return otellog.SeverityInfo
+	case zapcore.WarnLevel:
+		return otellog.SeverityWarn
+	case zapcore.ErrorLevel:
+		return otellog.SeverityError
+	case zapcore.DPanicLevel:
+		return otellog.SeverityFatal1
+	case zapcore.PanicLevel:
+		return otellog.SeverityFatal2
+	case zapcore.FatalLevel:
+		return otellog.SeverityFatal3
+	default:
+		return otellog.SeverityUndefined
+	}
+}
+
+// otelAttributes converts the fields into attributes by encoding them into a map.
+func otelAttributes(fields []zapcore.Field) []otellog.KeyValue {
+	if len(fields) == 0 {
+		return nil
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return otelKeyValues(enc.Fields)
+}
+
+func otelKeyValues(m map[string]any) []otellog.KeyValue {
+	kvs := make([]otellog.KeyValue, 0, len(m))
+	for k, v := range m {
+		kvs = append(kvs, otellog.KeyValue{Key: k, Value: otelValue(v)})
+	}
+	return kvs
+}
+
+// otelValue converts the value encoded by zapcore.MapObjectEncoder.
+func otelValue(v any) otellog.Value {
+	switch v := v.(type) {
+	case nil:
+		return otellog.Value{}
+	case string:
+		return otellog.StringValue(v)
+	case bool:
+		return otellog.BoolValue(v)
+	case int:
+		return otellog.IntValue(v)
+	case int8:
+		return otellog.Int64Value(int64(v))
+	case int16:
+		return otellog.Int64Value(int64(v))
+	case int32:
+		return otellog.Int64Value(int64(v))
+	case int64:
+		return otellog.Int64Value(v)
+	case uint:
+		return otelUint(uint64(v))
+	case uint8:
+		return otellog.Int64Value(int64(v))
+	case uint16:
+		return otellog.Int64Value(int64(v))
+	case uint32:
+		return otellog.Int64Value(int64(v))
+	case uint64:
+		return otelUint(v)
+	case uintptr:
+		return otelUint(uint64(v))
+	case float32:
+		return otellog.Float64Value(float64(v))
+	case float64:
+		return otellog.Float64Value(v)
+	case []byte:
+		return otellog.BytesValue(v)
+	case time.Time:
+		return otellog.StringValue(v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return otellog.StringValue(v.String())
+	case map[string]any:
+		return otellog.MapValue(otelKeyValues(v)...)
+	case []any:
+		vs := make([]otellog.Value, len(v))
+		for i, e := range v {
+			vs[i] = otelValue(e)
+		}
+		return otellog.SliceValue(vs...)
+	case error:
+		return otellog.StringValue(v.Error())
+	case fmt.Stringer:
+		return otellog.StringValue(v.String())
+	default:
+		return otellog.StringValue(fmt.Sprint(v))
+	}
+}
+
+// otelUint converts the unsigned integer, the ones overflowing int64 as strings.
+func otelUint(v uint64) otellog.Value {
+	if v > math.MaxInt64 {
+		return otellog.StringValue(fmt.Sprint(v))
+	}
+	return otellog.Int64Value(int64(v))
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// memoryExporter keeps the exported records.
+type memoryExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *memoryExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(context.Context) error   { return nil }
+func (e *memoryExporter) ForceFlush(context.Context) error { return nil }
+
+// TestOTelWriter tests the levels, the message and the fields of the records.
+func TestOTelWriter(t *testing.T) {
+	exp := &memoryExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exp)))
+	logger := NewZapLog(Config{{
+		Writer:     OutputOTel,
+		Level:      "info",
+		OTelConfig: OTelConfig{LoggerProvider: provider},
+	}})
+	logger.Debug("filtered")
+	logger.With(String("request_id", "r1")).Named("order").Error("pay failed",
+		Int("amount", 100), Any("err", errors.New("timeout")), Any("tags", []string{"a", "b"}))
+	_ = logger.Sync()
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+	if len(exp.records) != 1 {
+		t.Fatalf("records = %d, want 1", len(exp.records))
+	}
+	r := exp.records[0]
+	if r.Severity() != otellog.SeverityError || r.SeverityText() != "ERROR" || r.Body().AsString() != "pay failed" {
+		t.Errorf("severity = %v %s, body = %v", r.Severity(), r.SeverityText(), r.Body())
+	}
+	attrs := make(map[string]otellog.Value)
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	if attrs["request_id"].AsString() != "r1" || attrs["amount"].AsInt64() != 100 || attrs["err"].AsString() != "timeout" {
+		t.Errorf("attrs = %v", attrs)
+	}
+	if tags := attrs["tags"].AsSlice(); len(tags) != 2 || tags[1].AsString() != "b" {
+		t.Errorf("tags = %v", attrs["tags"])
+	}
+	if attrs["logger"].AsString() != "order" || attrs["code.filepath"].AsString() == "" || attrs["code.lineno"].AsInt64() == 0 {
+		t.Errorf("logger = %v, caller = %v", attrs["logger"], attrs["code.filepath"])
+	}
+}
+
+// TestOTelWriterExport tests that the records are exported to the collector by OTLP on Sync.
+func TestOTelWriterExport(t *testing.T) {
+	var (
+		requests atomic.Int32
+		auth     atomic.Value
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/logs" {
+			requests.Add(1)
+			auth.Store(r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer srv.Close()
+
+	logger := NewZapLog(Config{{
+		Writer: OutputOTel,
+		Level:  "info",
+		OTelConfig: OTelConfig{
+			Endpoint:    strings.TrimPrefix(srv.URL, "http://"),
+			Insecure:    true,
+			Headers:     map[string]string{"Authorization": "Bearer t"},
+			ServiceName: "order",
+		},
+	}})
+	logger.Info("hello")
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 1 || auth.Load() != "Bearer t" {
+		t.Errorf("requests = %d, auth = %v", requests.Load(), auth.Load())
+	}
+}
+
+// TestOTelWriterCompression tests the unsupported compression.
+func TestOTelWriterCompression(t *testing.T) {
+	if _, err := newOTelLoggerProvider(OTelConfig{Compression: "zstd"}); err == nil {
+		t.Error("Expected error for the unsupported compression")
+	}
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"sync"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "log"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the log plugin factory registered as log-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of log, setting the default logger by the outputs of
+// the plugin config:
+//
+//	log:
+//	  default:
+//	    - writer: file
+//	      level: info
+//	      writer_config:
+//	        filename: ./logs/app.log
+//	        buffer_size: 262144
+type Factory struct {
+	mu     sync.Mutex
+	logger *ZapLogger
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates the logger by the plugin config and sets it as the default logger.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	// the same caller skip as Init, for the package-level functions
+	l, err := newZapLog(cfg, 2, loggerOptions{})
+	if err != nil {
+		return err
+	}
+	SetDefault(l)
+
+	f.mu.Lock()
+	prev := f.logger
+	f.logger = l
+	f.mu.Unlock()
+	if prev != nil {
+		return prev.Close()
+	}
+	return nil
+}
+
+// Close writes the buffered and queued logs and closes the writers of the logger set
+// up by the plugin. If it is still the default logger, the default logger falls back
+// to the console output of Init.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	l := f.logger
+	f.logger = nil
+	f.mu.Unlock()
+	if l == nil {
+		return nil
+	}
+	mu.Lock()
+	if defaultLogger == Logger(l) {
+		defaultLogger = nil
+	}
+	mu.Unlock()
+	return l.Close()
+}
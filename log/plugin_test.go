@@ -0,0 +1,56 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+// TestPlugin tests that the plugin sets the default logger and Close flushes the buffered logs.
+func TestPlugin(t *testing.T) {
+	prev := GetDefaultLogger()
+	defer SetDefault(prev)
+
+	filename := filepath.Join(t.TempDir(), "plugin.log")
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(`
+- writer: file
+  level: info
+  writer_config:
+    filename: `+filename+`
+    buffer_size: 65536
+    flush_interval: 1h
+`), &node); err != nil {
+		t.Fatal(err)
+	}
+	f := &Factory{}
+	if err := f.Setup(pluginName, &plugin.YamlNodeDecoder{Node: &node}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	Info("buffered message")
+	if data, _ := os.ReadFile(filename); strings.Contains(string(data), "buffered message") {
+		t.Fatalf("Expected message buffered before Close, got %q", data)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil || !strings.Contains(string(data), "buffered message") {
+		t.Errorf("Expected message written on Close, got %q %v", data, err)
+	}
+	if _, ok := GetDefaultLogger().(*ZapLogger); !ok || GetDefaultLogger() == prev {
+		t.Error("Expected the default logger reset to the console output")
+	}
+
+	if err := yaml.Unmarshal([]byte(`[{writer: nowhere}]`), &node); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Setup(pluginName, &plugin.YamlNodeDecoder{Node: &node}); err == nil {
+		t.Error("Expected error for unregistered writer")
+	}
+}
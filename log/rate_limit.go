@@ -0,0 +1,133 @@
+package log
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/baisiyi/go-kits/ratelimit"
+)
+
+// RateLimitHook is called for every entry dropped by the rate limit of an output.
+type RateLimitHook func(output, key string, entry zapcore.Entry)
+
+var rateLimitHook atomic.Pointer[RateLimitHook]
+
+// SetRateLimitHook sets the hook of the rate limited outputs, e.g. to export the drop
+// rate as a metric. It applies to the loggers already created, nil removes the hook.
+func SetRateLimitHook(hook RateLimitHook) {
+	if hook == nil {
+		rateLimitHook.Store(nil)
+		return
+	}
+	rateLimitHook.Store(&hook)
+}
+
+// rateLimitCore drops the entries of a key exceeding the rate. The key is known only
+// with the fields of Write, so the wrapped core is checked in Check and written in
+// Write of rateLimitedEntry if allowed, which keeps the hooks and the sampling of
+// the wrapped core from seeing the dropped entries.
+type rateLimitCore struct {
+	zapcore.Core
+	output   string
+	keyField string
+	withKey  string // the value of keyField in the fields added by With
+	limiter  *ratelimit.Keyed
+}
+
+// newRateLimitCore wraps core with the rate limit of the output config.
+func newRateLimitCore(core zapcore.Core, output string, c *RateLimitConfig) (zapcore.Core, error) {
+	if c.Rate <= 0 {
+		return nil, fmt.Errorf("log: rate limit rate must be positive")
+	}
+	interval, maxKeys := c.Interval, c.MaxKeys
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if maxKeys <= 0 {
+		maxKeys = 10000
+	}
+	return &rateLimitCore{
+		Core:     core,
+		output:   output,
+		keyField: c.KeyField,
+		limiter: ratelimit.NewKeyedSlidingWindow(c.Rate, interval,
+			ratelimit.WithMaxKeys(maxKeys), ratelimit.WithIdleTTL(max(2*interval, time.Minute))),
+	}, nil
+}
+
+// With implements zapcore.Core.
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	if v, ok := fieldValue(fields, c.keyField); ok {
+		clone.withKey = v
+	}
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level > zapcore.ErrorLevel {
+		// never drop the panic and fatal entries
+		return c.Core.Check(ent, ce)
+	}
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+	return ce.AddCore(ent, &rateLimitedEntry{rateLimitCore: c, inner: inner})
+}
+
+// key returns the rate limit key of the entry.
+func (c *rateLimitCore) key(ent zapcore.Entry, fields []zapcore.Field) string {
+	if c.keyField != "" {
+		if v, ok := fieldValue(fields, c.keyField); ok {
+			return v
+		}
+		if c.withKey != "" {
+			return c.withKey
+		}
+	}
+	return ent.Message
+}
+
+// rateLimitedEntry writes the checked entry of the wrapped core if allowed.
+type rateLimitedEntry struct {
+	*rateLimitCore
+	inner *zapcore.CheckedEntry
+}
+
+// Write implements zapcore.Core.
+func (e *rateLimitedEntry) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key := e.key(ent, fields)
+	if !e.limiter.Allow(key) {
+		if hook := rateLimitHook.Load(); hook != nil {
+			(*hook)(e.output, key, ent)
+		}
+		return nil
+	}
+	return writeChecked(e.inner, ent, fields)
+}
+
+// fieldValue returns the value of the field formatted as string.
+func fieldValue(fields []zapcore.Field, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.Key != key {
+			continue
+		}
+		if f.Type == zapcore.StringType {
+			return f.String, true
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		return fmt.Sprint(enc.Fields[key]), true
+	}
+	return "", false
+}
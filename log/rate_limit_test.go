@@ -0,0 +1,65 @@
+package log
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// TestRateLimit tests that the entries of a key beyond the rate are dropped before
+// the hooks, and keyed by the message or the key field.
+func TestRateLimit(t *testing.T) {
+	var written, dropped atomic.Int32
+	SetRateLimitHook(func(output, key string, entry zapcore.Entry) {
+		dropped.Add(1)
+	})
+	defer SetRateLimitHook(nil)
+
+	logger := NewZapLog(Config{{
+		Writer:    OutputConsole,
+		Level:     "info",
+		RateLimit: &RateLimitConfig{Rate: 2, Interval: time.Minute, KeyField: "error_code"},
+		HookFuncs: []Hook{func(zapcore.Entry) error {
+			written.Add(1)
+			return nil
+		}},
+	}})
+	for i := 0; i < 5; i++ {
+		logger.Error("retry failed")
+	}
+	if written.Load() != 2 || dropped.Load() != 3 {
+		t.Errorf("by message: written = %d, dropped = %d, want 2 and 3", written.Load(), dropped.Load())
+	}
+
+	written.Store(0)
+	dropped.Store(0)
+	for i := 0; i < 3; i++ {
+		logger.Error("call a", String("error_code", "E1"))
+		logger.Error("call b", String("error_code", "E1"))
+		logger.With(String("error_code", "E2")).Error("call c")
+	}
+	// E1: 2 of 6, E2: 2 of 3
+	if written.Load() != 4 || dropped.Load() != 5 {
+		t.Errorf("by field: written = %d, dropped = %d, want 4 and 5", written.Load(), dropped.Load())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for the non-positive rate")
+		}
+	}()
+	NewZapLog(Config{{Writer: OutputConsole, RateLimit: &RateLimitConfig{}}})
+}
+
+// TestRateLimitErrorOutput tests that the write errors are reported to the ErrorOutput of the logger.
+func TestRateLimitErrorOutput(t *testing.T) {
+	testErrorOutput(t, func(core zapcore.Core) zapcore.Core {
+		c, err := newRateLimitCore(core, OutputConsole, &RateLimitConfig{Rate: 1, Interval: time.Minute})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	})
+}
@@ -0,0 +1,261 @@
+package rollwriter
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/baisiyi/go-kits/pool"
+)
+
+// ErrClosed 写入已关闭的写入器时返回
+var ErrClosed = errors.New("rollwriter: writer closed")
+
+// AsyncOptionFunc 是异步写入器配置选项的函数类型
+type AsyncOptionFunc func(*AsyncOptions)
+
+// AsyncOptions 存储异步写入器的配置选项
+type AsyncOptions struct {
+	queueSize     int           // 队列长度（条）
+	writeSize     int           // 合并写入的缓冲大小（Byte）
+	writeInterval time.Duration // 缓冲未满时的刷新间隔
+	dropOnFull    bool          // 队列满时丢弃日志而不是阻塞
+}
+
+// WithQueueSize 设置队列长度，默认 10000 条
+func WithQueueSize(n int) AsyncOptionFunc {
+	return func(o *AsyncOptions) {
+		o.queueSize = n
+	}
+}
+
+// WithWriteSize 设置合并写入的缓冲大小，缓冲满时写入底层文件，默认 4KB
+func WithWriteSize(size int) AsyncOptionFunc {
+	return func(o *AsyncOptions) {
+		o.writeSize = size
+	}
+}
+
+// WithWriteInterval 设置缓冲未满时的刷新间隔，默认 100ms
+func WithWriteInterval(d time.Duration) AsyncOptionFunc {
+	return func(o *AsyncOptions) {
+		o.writeInterval = d
+	}
+}
+
+// WithDropOnFull 设置队列满时丢弃日志，默认阻塞直到队列有空位
+func WithDropOnFull(drop bool) AsyncOptionFunc {
+	return func(o *AsyncOptions) {
+		o.dropOnFull = drop
+	}
+}
+
+// BatchWriter 按条写入日志的底层写入器，如发送到消息队列。AsyncRollWriter 的底层写入器实现
+// BatchWriter 时，合并的日志按条调用 WriteBatch，而不是拼接后调用 Write。
+// entries 在 WriteBatch 返回后会被复用，不能保留
+type BatchWriter interface {
+	WriteBatch(entries [][]byte) error
+}
+
+// AsyncRollWriter 异步写入器，Write 只将日志放入队列，由后台 goroutine 合并写入底层写入器。
+// Sync 返回前保证之前已返回的 Write 全部写入底层写入器。写入底层写入器失败时保存错误，
+// 由之后的 Write 或 Sync 返回，以便上层隔离故障输出或写入兜底输出。
+type AsyncRollWriter struct {
+	w    WriteSyncer
+	opts AsyncOptions
+
+	// mu 保证 Close 关闭 done 时没有正在放入队列的 Write，后台 goroutine 退出前能取出全部日志
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan []byte
+	syncReq chan chan error
+	done    chan struct{}
+	stopped chan struct{}
+	dropped atomic.Int64
+
+	errMu sync.Mutex
+	err   error // 上次写入底层写入器的错误，返回后清除
+}
+
+// NewAsyncRollWriter 创建异步写入器，w 通常为 NewRollWriter 创建的轮转写入器
+func NewAsyncRollWriter(w WriteSyncer, opt ...AsyncOptionFunc) *AsyncRollWriter {
+	opts := AsyncOptions{
+		queueSize:     10000,
+		writeSize:     4 * KB,
+		writeInterval: 100 * time.Millisecond,
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	if opts.queueSize <= 0 {
+		opts.queueSize = 10000
+	}
+	if opts.writeInterval <= 0 {
+		opts.writeInterval = 100 * time.Millisecond
+	}
+	a := &AsyncRollWriter{
+		w:       w,
+		opts:    opts,
+		queue:   make(chan []byte, opts.queueSize),
+		syncReq: make(chan chan error),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Write 将日志复制后放入队列，队列满时按配置阻塞或丢弃。之前写入底层写入器失败时返回该错误，
+// 本条日志不放入队列
+func (a *AsyncRollWriter) Write(p []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return 0, ErrClosed
+	}
+	if err := a.takeErr(); err != nil {
+		return 0, err
+	}
+	// zap 会复用 p 的底层数组，必须复制，写入底层写入器后放回 pool
+	b := pool.GetBytes(len(p))
+	copy(b, p)
+	if a.opts.dropOnFull {
+		select {
+		case a.queue <- b:
+		default:
+			pool.PutBytes(b)
+			a.dropped.Add(1)
+		}
+		return len(p), nil
+	}
+	// 后台 goroutine 在 done 关闭前持续取出日志，阻塞的 Write 最终会放入队列
+	a.queue <- b
+	return len(p), nil
+}
+
+// Sync 写入队列和缓冲中的全部日志并同步底层写入器，返回写入和同步的错误
+func (a *AsyncRollWriter) Sync() error {
+	ch := make(chan error, 1)
+	select {
+	case <-a.done:
+		return ErrClosed
+	case a.syncReq <- ch:
+		return <-ch
+	}
+}
+
+// Close 写入剩余日志并同步底层写入器后停止后台 goroutine
+func (a *AsyncRollWriter) Close() error {
+	err := a.Sync()
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.done)
+	}
+	a.mu.Unlock()
+	<-a.stopped
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// setErr 保存写入底层写入器的错误
+func (a *AsyncRollWriter) setErr(err error) {
+	a.errMu.Lock()
+	a.err = err
+	a.errMu.Unlock()
+}
+
+// takeErr 返回并清除保存的错误
+func (a *AsyncRollWriter) takeErr() error {
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+	err := a.err
+	a.err = nil
+	return err
+}
+
+// Dropped 返回队列满时丢弃的日志条数
+func (a *AsyncRollWriter) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+func (a *AsyncRollWriter) run() {
+	defer close(a.stopped)
+	var (
+		buf     bytes.Buffer
+		entries [][]byte // 底层写入器为 BatchWriter 时按条保存
+		size    int
+	)
+	bw, batched := a.w.(BatchWriter)
+	ticker := time.NewTicker(a.opts.writeInterval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if size == 0 {
+			return
+		}
+		var err error
+		if batched {
+			err = bw.WriteBatch(entries)
+			for i, b := range entries {
+				pool.PutBytes(b)
+				entries[i] = nil
+			}
+			entries = entries[:0]
+		} else {
+			_, err = a.w.Write(buf.Bytes())
+			buf.Reset()
+		}
+		size = 0
+		if err != nil {
+			a.setErr(err)
+		}
+	}
+	add := func(b []byte) {
+		if batched {
+			entries = append(entries, b)
+		} else {
+			buf.Write(b)
+			pool.PutBytes(b)
+		}
+		size += len(b)
+		if size >= a.opts.writeSize {
+			flush()
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case b := <-a.queue:
+				add(b)
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case b := <-a.queue:
+			add(b)
+		case <-ticker.C:
+			flush()
+		case ch := <-a.syncReq:
+			drain()
+			// 底层写入器的 Sync 可能已返回同一个写入错误，如 Kafka 输出
+			err := a.takeErr()
+			if serr := a.w.Sync(); serr != nil {
+				err = serr
+			}
+			ch <- err
+		case <-a.done:
+			drain()
+			return
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package rollwriter
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baisiyi/go-kits/pool"
+)
+
+// memWriter is an in-memory WriteSyncer.
+type memWriter struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+	syncs  int
+	block  chan struct{}
+	err    error // returned by Write if set
+}
+
+func (w *memWriter) Write(p []byte) (int, error) {
+	if w.block != nil {
+		<-w.block
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.buf.Write(p)
+}
+
+func (w *memWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.syncs++
+	return nil
+}
+
+func (w *memWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// TestAsyncRollWriterSync tests that Sync writes all the queued logs.
+func TestAsyncRollWriterSync(t *testing.T) {
+	mw := &memWriter{}
+	w := NewAsyncRollWriter(mw, WithWriteInterval(time.Hour))
+	p := []byte("line1\n")
+	_, _ = w.Write(p)
+	copy(p, "xxxxx\n") // the caller may reuse the buffer
+	_, _ = w.Write([]byte("line2\n"))
+
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if got := mw.String(); got != "line1\nline2\n" {
+		t.Errorf("written = %q", got)
+	}
+	if mw.writes != 1 || mw.syncs != 1 {
+		t.Errorf("Expected 1 merged write and 1 sync, got %d and %d", mw.writes, mw.syncs)
+	}
+
+	_, _ = w.Write([]byte("line3\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := w.Write([]byte("late\n")); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if got := mw.String(); got != "line1\nline2\nline3\n" {
+		t.Errorf("written after close = %q", got)
+	}
+}
+
+// TestAsyncRollWriterWriteError tests that the errors of writing the underlying writer are
+// returned by the next Write or Sync.
+func TestAsyncRollWriterWriteError(t *testing.T) {
+	errDisk := errors.New("disk full")
+	mw := &memWriter{err: errDisk}
+	w := NewAsyncRollWriter(mw, WithWriteInterval(time.Hour))
+	defer w.Close()
+
+	if _, err := w.Write([]byte("line1\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := w.Sync(); !errors.Is(err, errDisk) {
+		t.Errorf("Expected Sync error %v, got %v", errDisk, err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Errorf("Expected the error returned once, got %v", err)
+	}
+
+	_, _ = w.Write(bytes.Repeat([]byte("x"), 4*KB)) // flushed at once by the write size
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := w.Write([]byte("line2\n"))
+		if errors.Is(err, errDisk) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected Write error %v, got %v", errDisk, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestAsyncRollWriterCloseRace tests that the Writes succeeded concurrently with Close are
+// all written.
+func TestAsyncRollWriterCloseRace(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		mw := &memWriter{}
+		w := NewAsyncRollWriter(mw, WithQueueSize(4), WithWriteInterval(time.Hour))
+		var (
+			wg      sync.WaitGroup
+			written atomic.Int64
+		)
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					if _, err := w.Write([]byte("x")); err != nil {
+						return
+					}
+					written.Add(1)
+				}
+			}()
+		}
+		time.Sleep(time.Millisecond)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		wg.Wait()
+		if got := int64(len(mw.String())); got != written.Load() {
+			t.Fatalf("Expected %d entries written, got %d", written.Load(), got)
+		}
+	}
+}
+
+// TestAsyncRollWriterDropOnFull tests that the logs are dropped when the queue is full.
+func TestAsyncRollWriterDropOnFull(t *testing.T) {
+	mw := &memWriter{block: make(chan struct{})}
+	w := NewAsyncRollWriter(mw, WithQueueSize(1), WithWriteSize(1), WithDropOnFull(true))
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte("x")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if w.Dropped() == 0 {
+		t.Error("Expected dropped logs")
+	}
+	close(mw.block)
+	_ = w.Close()
+}
+
+// batchWriter records the entries written by WriteBatch.
+type batchWriter struct {
+	memWriter
+	batches [][]string
+}
+
+func (w *batchWriter) WriteBatch(entries [][]byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	batch := make([]string, len(entries))
+	for i, e := range entries {
+		batch[i] = string(e)
+	}
+	w.batches = append(w.batches, batch)
+	return nil
+}
+
+// TestAsyncRollWriterBatch tests that the entries are kept apart for a BatchWriter.
+func TestAsyncRollWriterBatch(t *testing.T) {
+	bw := &batchWriter{}
+	w := NewAsyncRollWriter(bw, WithWriteSize(12), WithWriteInterval(time.Hour))
+	for _, line := range []string{"line1\n", "line2\n", "line3\n"} {
+		_, _ = w.Write([]byte(line))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(bw.batches) != 2 || len(bw.batches[0]) != 2 || bw.batches[1][0] != "line3\n" {
+		t.Errorf("batches = %q", bw.batches)
+	}
+	if bw.writes != 0 || bw.syncs != 1 {
+		t.Errorf("Expected no Write and 1 sync, got %d and %d", bw.writes, bw.syncs)
+	}
+}
+
+// TestAsyncRollWriterPool tests that the copies of the entries are put back to the pool.
+func TestAsyncRollWriterPool(t *testing.T) {
+	inUse := func() int64 {
+		var n int64
+		for _, s := range pool.DefaultBytePool.Stats() {
+			n += s.InUse()
+		}
+		return n
+	}
+	before := inUse()
+	for _, mw := range []WriteSyncer{&memWriter{}, &batchWriter{}} {
+		w := NewAsyncRollWriter(mw, WithWriteSize(12), WithWriteInterval(time.Hour))
+		for _, line := range []string{"line1\n", "line2\n", "line3\n"} {
+			_, _ = w.Write([]byte(line))
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if _, err := w.Write([]byte("late\n")); err != ErrClosed {
+			t.Errorf("Write after Close error = %v", err)
+		}
+	}
+	if n := inUse() - before; n != 0 {
+		t.Errorf("Expected all the entries put back, %d in use", n)
+	}
+}
@@ -0,0 +1,158 @@
+package rollwriter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/baisiyi/go-kits/clock"
+)
+
+// LumberjackWriter 基于 lumberjack 的日志轮转写入器，使用 lumberjack 的轮转语义和备份文件名：
+// 当前日志始终写入 filePath，超过 RotationSize 时重命名为 name-2006-01-02T15-04-05.000.ext，
+// 过期和超出数量的备份由 lumberjack 清理。配置按时间轮转时由后台 goroutine 在周期边界调用 Rotate。
+// WithTimeFormat 不生效，不支持 WithMaxDiskUsage 和 WithRotationHandler
+type LumberjackWriter struct {
+	logger  *lumberjack.Logger
+	opts    *Options
+	clock   clock.Clock
+	written atomic.Bool // 上次轮转后是否写入过日志
+
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewLumberjackWriter 创建基于 lumberjack 的日志轮转写入器，选项与 NewRollWriter 相同：
+// RotationSize 向上取整为 MB，0 表示不按大小轮转；MaxAge 向上取整为天；RotationCount 为 MaxBackups；
+// WithRotationAlign 的时区为 UTC 时备份文件名使用 UTC 时间，否则使用本地时间
+func NewLumberjackWriter(filePath string, opt ...OptionFunc) (*LumberjackWriter, error) {
+	opts, err := newOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+	if opts.maxDiskUsage > 0 {
+		return nil, errors.New("rollwriter: lumberjack does not support max disk usage")
+	}
+	if opts.rotationHandler != nil {
+		return nil, errors.New("rollwriter: lumberjack does not support rotation handler")
+	}
+	if err := mkdirAll(filepath.Dir(filePath), opts.dirMode); err != nil {
+		return nil, fmt.Errorf("rollwriter: create dir error: %w", err)
+	}
+	// lumberjack 新建文件的权限为 0600，轮转时沿用原文件的权限，因此预先按配置创建文件
+	size, err := createFile(filePath, opts.fileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := math.MaxInt32
+	if opts.rotationSize > 0 {
+		maxSize = int((opts.rotationSize + MB - 1) / MB)
+	}
+	w := &LumberjackWriter{
+		logger: &lumberjack.Logger{
+			Filename:   filePath,
+			MaxSize:    maxSize,
+			MaxAge:     int((opts.maxAge + 24*time.Hour - 1) / (24 * time.Hour)),
+			MaxBackups: int(opts.rotationCount),
+			LocalTime:  opts.alignLocation != time.UTC,
+		},
+		opts:  opts,
+		clock: clock.OrReal(opts.clock),
+		done:  make(chan struct{}),
+	}
+	w.written.Store(size > 0)
+	if opts.rotateByTime() {
+		w.wg.Add(1)
+		go w.rotateOnSchedule()
+	}
+	return w, nil
+}
+
+// createFile 创建不存在的 filePath，mode 不为 0 时修改为 mode，返回已有文件的大小
+func createFile(filePath string, mode os.FileMode) (int64, error) {
+	perm := mode
+	if perm == 0 {
+		perm = 0o644
+	}
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, perm)
+	if err != nil {
+		return 0, fmt.Errorf("rollwriter: open file error: %w", err)
+	}
+	defer f.Close()
+	if mode != 0 {
+		if err := f.Chmod(mode); err != nil {
+			return 0, fmt.Errorf("rollwriter: chmod file error: %w", err)
+		}
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("rollwriter: stat file error: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// Write 实现 io.Writer，由 lumberjack 按大小轮转
+func (w *LumberjackWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	w.written.Store(true)
+	return w.logger.Write(p)
+}
+
+// Sync 实现 WriteSyncer，lumberjack 直接写入文件，没有需要刷新的缓冲
+func (w *LumberjackWriter) Sync() error {
+	return nil
+}
+
+// Rotate 立即轮转当前文件
+func (w *LumberjackWriter) Rotate() error {
+	w.written.Store(false)
+	return w.logger.Rotate()
+}
+
+// Close 停止按时间轮转并关闭当前文件，之后的写入返回 ErrClosed
+func (w *LumberjackWriter) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.wg.Wait()
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.closed = true
+		err = w.logger.Close()
+	})
+	return err
+}
+
+// rotateOnSchedule 在每个轮转周期边界轮转写入过日志的文件
+func (w *LumberjackWriter) rotateOnSchedule() {
+	defer w.wg.Done()
+	for {
+		now := w.clock.Now()
+		timer := w.clock.NewTimer(w.opts.nextPeriod(now).Sub(now))
+		select {
+		case <-timer.C():
+			if w.written.Swap(false) {
+				_ = w.logger.Rotate()
+			}
+		case <-w.done:
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package rollwriter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/baisiyi/go-kits/clock"
+)
+
+// TestLumberjackWriterRotateBySize tests the size rotation with the lumberjack backup names.
+func TestLumberjackWriterRotateBySize(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "size.log")
+	w, err := NewLumberjackWriter(filePath, WithRotationSizeMB(1), WithRotationAge(0))
+	if err != nil {
+		t.Fatalf("NewLumberjackWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	chunk := bytes.Repeat([]byte("a"), 700*KB)
+	for i := 0; i < 2; i++ {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	names := listBackups(t, filePath)
+	re := regexp.MustCompile(`^size-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3}\.log$`)
+	if len(names) != 1 || !re.MatchString(names[0]) {
+		t.Fatalf("backups = %v", names)
+	}
+	if info, err := os.Stat(filePath); err != nil || info.Size() != int64(len(chunk)) {
+		t.Errorf("current file = %v, %v", info, err)
+	}
+}
+
+// TestLumberjackWriterRotateByTime tests that the files written are rotated at the
+// period boundaries and the empty ones are not.
+func TestLumberjackWriterRotateByTime(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "time.log")
+	fc := clock.NewFake(time.Date(2026, 1, 2, 3, 30, 0, 0, time.UTC))
+	w, err := NewLumberjackWriter(filePath, WithClock(fc), WithRotationAge(1), WithRotationSize(0))
+	if err != nil {
+		t.Fatalf("NewLumberjackWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	deadline := time.Now().Add(time.Second)
+	for len(listBackups(t, filePath)) == 0 && time.Now().Before(deadline) {
+		fc.Advance(30 * time.Minute)
+		time.Sleep(5 * time.Millisecond)
+	}
+	names := listBackups(t, filePath)
+	if len(names) != 1 {
+		t.Fatalf("backups = %v", names)
+	}
+	if data, _ := os.ReadFile(filepath.Join(filepath.Dir(filePath), names[0])); string(data) != "first\n" {
+		t.Errorf("backup = %q", data)
+	}
+
+	// nothing written, nothing rotated
+	for i := 0; i < 4; i++ {
+		fc.Advance(30 * time.Minute)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if names := listBackups(t, filePath); len(names) != 1 {
+		t.Errorf("Expected the empty file not rotated, got backups %v", names)
+	}
+}
+
+// TestLumberjackWriterOptions tests the file mode, the unsupported options and the
+// writes after Close.
+func TestLumberjackWriterOptions(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "logs", "mode.log")
+	w, err := NewLumberjackWriter(filePath, WithFileMode(0o640), WithDirMode(0o750))
+	if err != nil {
+		t.Fatalf("NewLumberjackWriter failed: %v", err)
+	}
+	if _, err := w.Write([]byte("x\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if info, err := os.Stat(filePath); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("file mode = %v, %v", info.Mode().Perm(), err)
+	}
+	if info, err := os.Stat(filepath.Dir(filePath)); err != nil || info.Mode().Perm() != 0o750 {
+		t.Errorf("dir mode = %v, %v", info.Mode().Perm(), err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := w.Write([]byte("y\n")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+
+	if _, err := NewLumberjackWriter(filepath.Join(dir, "a.log"), WithMaxDiskUsage(GB)); err == nil {
+		t.Error("Expected error for max disk usage")
+	}
+	if _, err := NewLumberjackWriter(filepath.Join(dir, "b.log"), WithRotationHandler(func(string, string) {})); err == nil {
+		t.Error("Expected error for rotation handler")
+	}
+}
@@ -1,10 +1,17 @@
 package rollwriter
 
 import (
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
-	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/baisiyi/go-kits/clock"
 )
 
 const (
@@ -17,6 +24,12 @@ const (
 	EB                    // 1 << 60
 )
 
+// WithRotationAlign 的日历边界
+const (
+	AlignHour = "hour" // 每个整点轮转
+	AlignDay  = "day"  // 每天零点轮转
+)
+
 // WriteSyncer 定义了日志写入器需要实现的行为
 type WriteSyncer interface {
 	io.Writer
@@ -28,14 +41,22 @@ type OptionFunc func(*Options)
 
 // Options 存储轮转日志的配置选项
 type Options struct {
-	timeFormat    string        // 时间格式
-	maxAge        time.Duration // 日志默认保留时间（Hour）
-	rotationAge   time.Duration // 日志轮转时间（Hour）
-	rotationSize  int64         // 日志轮转容量（Byte）
-	rotationCount uint          // 日志文件数量
+	timeFormat    string         // 备份文件名的时间格式（strftime）
+	maxAge        time.Duration  // 日志默认保留时间（Hour）
+	rotationAge   time.Duration  // 日志轮转时间（Hour），0 表示不按时间轮转
+	rotationAlign string         // 按日历边界轮转：hour、day，设置后 rotationAge 不生效
+	alignLocation *time.Location // rotationAlign 的时区
+	rotationSize  int64          // 日志轮转容量（Byte），0 表示不按大小轮转
+	rotationCount uint           // 最多保留的备份文件数量，0 表示不限制
+	maxDiskUsage  int64          // 目录下日志文件和备份文件的总大小上限（Byte），0 表示不限制
+	clock         clock.Clock    // 轮转使用的时钟
+	fileMode      os.FileMode    // 日志文件权限，0 表示 0644 且受 umask 影响
+	dirMode       os.FileMode    // 新建目录的权限，0 表示 0755 且受 umask 影响
+
+	rotationHandler func(oldPath, newPath string) // 轮转完成后的回调
 }
 
-// WithTimeFormat 设置时间格式
+// WithTimeFormat 设置备份文件名的时间格式，支持 %Y %m %d %H %M %S
 func WithTimeFormat(format string) OptionFunc {
 	return func(o *Options) {
 		o.timeFormat = format
@@ -70,6 +91,15 @@ func WithRotationAgeDuration(d time.Duration) OptionFunc {
 	}
 }
 
+// WithRotationAlign 按日历边界轮转，AlignHour 在每个整点、AlignDay 在每天零点轮转，与启动时间无关；
+// loc 为边界所在的时区，同时用于备份文件名的时间，nil 表示 time.Local。设置后 RotationAge 不再生效
+func WithRotationAlign(align string, loc *time.Location) OptionFunc {
+	return func(o *Options) {
+		o.rotationAlign = align
+		o.alignLocation = loc
+	}
+}
+
 // WithRotationSize 设置单个日志文件的最大字节数
 func WithRotationSize(size int64) OptionFunc {
 	return func(o *Options) {
@@ -84,15 +114,119 @@ func WithRotationSizeMB(size int64) OptionFunc {
 	}
 }
 
-// WithRotationCount 设置最大保留的文件数量
+// WithRotationCount 设置最多保留的备份文件数量
 func WithRotationCount(count uint) OptionFunc {
 	return func(o *Options) {
 		o.rotationCount = count
 	}
 }
 
+// WithMaxDiskUsage 设置日志目录的总大小上限，统计同一目录下本进程打开的所有 RollWriter 的当前文件和备份文件，
+// 超出时从其中最旧的备份开始删除，后台每次轮转后以及每分钟检查一次
+func WithMaxDiskUsage(bytes int64) OptionFunc {
+	return func(o *Options) {
+		o.maxDiskUsage = bytes
+	}
+}
+
+// WithClock 设置轮转使用的时钟，测试中可传入 clock.Fake 控制轮转时间
+func WithClock(c clock.Clock) OptionFunc {
+	return func(o *Options) {
+		o.clock = c
+	}
+}
+
+// WithFileMode 设置日志文件的权限，如 0640，不受 umask 影响，已有的日志文件打开时也会修改为该权限，
+// 轮转后的备份文件保持原权限
+func WithFileMode(mode os.FileMode) OptionFunc {
+	return func(o *Options) {
+		o.fileMode = mode
+	}
+}
+
+// WithDirMode 设置创建日志目录时的权限，如 0750，不受 umask 影响，只作用于不存在而新建的目录
+func WithDirMode(mode os.FileMode) OptionFunc {
+	return func(o *Options) {
+		o.dirMode = mode
+	}
+}
+
+// WithRotationHandler 设置轮转完成后的回调，oldPath 为日志文件路径，newPath 为轮转后已关闭的备份文件路径，
+// 可用于上传、压缩备份文件或上报指标。回调由后台 goroutine 按轮转顺序调用，不阻塞写入，
+// Close 会等待已发生轮转的回调执行完成。回调返回前备份文件不会被清理；回调可为备份文件追加后缀，
+// 如压缩为 newPath + ".gz"，追加后缀的文件同样按 MaxAge、RotationCount 和 MaxDiskUsage 清理
+func WithRotationHandler(fn func(oldPath, newPath string)) OptionFunc {
+	return func(o *Options) {
+		o.rotationHandler = fn
+	}
+}
+
+// RollWriter 按大小和时间轮转的日志文件写入器。当前日志始终写入 filePath，
+// 轮转时重命名为 filePath + 时间后缀（同一时间后缀重复时追加 .1、.2 ...），
+// 过期、超出数量和超出磁盘占用上限的备份由后台 goroutine 清理
+type RollWriter struct {
+	filePath string
+	dir      string // filePath 所在目录的绝对路径，MaxDiskUsage 按目录统计
+	opts     *Options
+	clock    clock.Clock
+	backupRe *regexp.Regexp
+
+	mu     sync.Mutex
+	file   *os.File // 轮转后未能重新打开时为 nil，下次写入时重试打开
+	closed bool
+	size   int64
+	period time.Time // 当前文件所属的轮转周期
+
+	notify    chan struct{}
+	rotated   chan struct{}       // 通知回调 goroutine 有新的轮转
+	pending   []string            // 待回调的备份文件，由 mu 保护
+	handling  map[string]struct{} // 回调未返回的备份文件，清理时跳过，由 mu 保护
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
 // NewRollWriter 创建一个新的日志轮转写入器
-func NewRollWriter(filePath string, opt ...OptionFunc) (WriteSyncer, error) {
+func NewRollWriter(filePath string, opt ...OptionFunc) (*RollWriter, error) {
+	opts, err := newOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &RollWriter{
+		filePath: filePath,
+		opts:     opts,
+		clock:    clock.OrReal(opts.clock),
+		backupRe: backupPattern(filepath.Base(filePath), opts.timeFormat),
+		notify:   make(chan struct{}, 1),
+		rotated:  make(chan struct{}, 1),
+		handling: make(map[string]struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := mkdirAll(filepath.Dir(filePath), opts.dirMode); err != nil {
+		return nil, fmt.Errorf("rollwriter: create dir error: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.dir = filepath.Dir(filePath)
+	if abs, err := filepath.Abs(w.dir); err == nil {
+		w.dir = abs
+	}
+	registerDir(w)
+
+	w.wg.Add(1)
+	go w.scavenge()
+	w.notifyScavenger()
+	if opts.rotationHandler != nil {
+		w.wg.Add(1)
+		go w.handleRotations()
+	}
+	return w, nil
+}
+
+// newOptions 返回应用 opt 后的配置，NewRollWriter 和 NewLumberjackWriter 使用相同的默认值
+func newOptions(opt []OptionFunc) (*Options, error) {
 	opts := &Options{
 		timeFormat:    ".%Y%m%d%H%M",
 		maxAge:        7 * 24 * time.Hour, // 默认保留 7 天
@@ -103,39 +237,480 @@ func NewRollWriter(filePath string, opt ...OptionFunc) (WriteSyncer, error) {
 	for _, o := range opt {
 		o(opts)
 	}
+	switch opts.rotationAlign {
+	case "", AlignHour, AlignDay:
+	default:
+		return nil, fmt.Errorf("rollwriter: rotation align %s not supported", opts.rotationAlign)
+	}
+	if opts.alignLocation == nil {
+		opts.alignLocation = time.Local
+	}
+	return opts, nil
+}
+
+// Write 实现 io.Writer，写入前按需轮转
+func (w *RollWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.file == nil {
+		if err := w.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	now := w.clock.Now()
+	if w.size == 0 {
+		// 空文件无需轮转，直接归入当前周期
+		w.period = w.opts.periodOf(now)
+	}
+	if w.shouldRotate(now, int64(len(p))) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
 
-	// 构建 rotatelogs 选项
-	options := []rotatelogs.Option{
-		rotatelogs.WithLinkName(filePath),
-		rotatelogs.WithRotationTime(opts.rotationAge),
-		rotatelogs.WithRotationSize(opts.rotationSize),
+// Sync 将当前文件刷到磁盘
+func (w *RollWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
 	}
+	return w.file.Sync()
+}
+
+// Close 停止后台清理并关闭当前文件，之后的写入返回 ErrClosed
+func (w *RollWriter) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.wg.Wait()
+		unregisterDir(w)
 
-	// MaxAge 和 RotationCount 不能同时设置，优先使用 MaxAge
-	if opts.maxAge > 0 {
-		options = append(options, rotatelogs.WithMaxAge(opts.maxAge))
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.closed = true
+		if w.file != nil {
+			err = w.file.Close()
+			w.file = nil
+		}
+	})
+	return err
+}
+
+// mkdirAll 创建 dir 及不存在的上级目录，mode 不为 0 时将新建的目录修改为 mode，已有目录不修改
+func mkdirAll(dir string, mode os.FileMode) error {
+	if mode == 0 {
+		return os.MkdirAll(dir, 0o755)
+	}
+	var created []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		created = append(created, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return err
 	}
-	if opts.rotationCount > 0 {
-		options = append(options, rotatelogs.WithRotationCount(opts.rotationCount))
+	// 从上级到下级修改，避免上级目录权限不足时无法访问下级
+	for i := len(created) - 1; i >= 0; i-- {
+		if err := os.Chmod(created[i], mode); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	rl, err := rotatelogs.New(filePath+opts.timeFormat, options...)
+// open 以追加方式打开 filePath，已有文件的轮转周期按其修改时间计算
+func (w *RollWriter) open() error {
+	mode := w.opts.fileMode
+	if mode == 0 {
+		mode = 0o644
+	}
+	f, err := os.OpenFile(w.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, mode)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("rollwriter: open file error: %w", err)
+	}
+	if w.opts.fileMode != 0 {
+		// OpenFile 的权限受 umask 影响，且不修改已有文件
+		if err := f.Chmod(w.opts.fileMode); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("rollwriter: chmod file error: %w", err)
+		}
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("rollwriter: stat file error: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	w.period = w.opts.periodOf(w.clock.Now())
+	if w.size > 0 {
+		w.period = w.opts.periodOf(info.ModTime())
+	}
+	return nil
+}
+
+// reopen 在轮转失败后以追加方式重新打开 filePath，保留当前的大小和周期以便下次写入时重试轮转。
+// 打开失败时 w.file 为 nil，下次写入时再次重试
+func (w *RollWriter) reopen() error {
+	size, period := w.size, w.period
+	w.file = nil
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.size, w.period = max(w.size, size), period
+	return nil
+}
+
+func (w *RollWriter) shouldRotate(now time.Time, n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.opts.rotateByTime() && !w.opts.periodOf(now).Equal(w.period) {
+		return true
+	}
+	return w.opts.rotationSize > 0 && w.size+n > w.opts.rotationSize
+}
+
+// rotate 将当前文件重命名为备份文件并打开新文件。重命名或打开新文件失败时以追加方式重新打开原文件，
+// 之后的写入继续写入原文件并在下次写入时重试轮转
+func (w *RollWriter) rotate(now time.Time) error {
+	if err := w.file.Close(); err != nil {
+		// 关闭失败的文件同样不可用，下次写入时重新打开
+		w.file = nil
+		return fmt.Errorf("rollwriter: close file error: %w", err)
+	}
+	backupTime := now
+	if w.opts.rotateByTime() {
+		backupTime = w.period
+	}
+	backup := w.backupName(backupTime)
+	if err := os.Rename(w.filePath, backup); err != nil {
+		_ = w.reopen()
+		return fmt.Errorf("rollwriter: rename file error: %w", err)
+	}
+	if err := w.open(); err != nil {
+		// 还原备份文件，避免原文件内容被当作备份清理
+		if _, serr := os.Lstat(w.filePath); os.IsNotExist(serr) {
+			_ = os.Rename(backup, w.filePath)
+		}
+		_ = w.reopen()
+		return err
+	}
+	if w.opts.rotationHandler != nil {
+		w.pending = append(w.pending, backup)
+		w.handling[backup] = struct{}{}
+		select {
+		case w.rotated <- struct{}{}:
+		default:
+		}
+	}
+	w.period = w.opts.periodOf(now)
+	w.notifyScavenger()
+	return nil
+}
+
+// handleRotations 按顺序调用轮转回调，Close 时处理完剩余的轮转后退出
+func (w *RollWriter) handleRotations() {
+	defer w.wg.Done()
+	run := func() {
+		w.mu.Lock()
+		pending := w.pending
+		w.pending = nil
+		w.mu.Unlock()
+		for _, backup := range pending {
+			w.opts.rotationHandler(w.filePath, backup)
+			w.mu.Lock()
+			delete(w.handling, backup)
+			w.mu.Unlock()
+		}
+		if len(pending) > 0 {
+			// 清理跳过了回调中的备份文件
+			w.notifyScavenger()
+		}
+	}
+	for {
+		select {
+		case <-w.rotated:
+			run()
+		case <-w.done:
+			run()
+			return
+		}
+	}
+}
+
+// periodOf 返回 t 所属的轮转周期起点
+func (o *Options) periodOf(t time.Time) time.Time {
+	switch {
+	case o.rotationAlign == AlignHour:
+		t = t.In(o.alignLocation)
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case o.rotationAlign == AlignDay:
+		// 按日历计算，夏令时切换的当天也在零点轮转
+		t = t.In(o.alignLocation)
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case o.rotationAge > 0:
+		return t.Truncate(o.rotationAge)
+	default:
+		return time.Time{}
+	}
+}
+
+// nextPeriod 返回 t 所属轮转周期的下一个周期起点，只在按时间轮转时有效
+func (o *Options) nextPeriod(t time.Time) time.Time {
+	p := o.periodOf(t)
+	switch o.rotationAlign {
+	case AlignHour:
+		return time.Date(p.Year(), p.Month(), p.Day(), p.Hour()+1, 0, 0, 0, p.Location())
+	case AlignDay:
+		return time.Date(p.Year(), p.Month(), p.Day()+1, 0, 0, 0, 0, p.Location())
+	default:
+		return p.Add(o.rotationAge)
+	}
+}
+
+// rotateByTime 返回是否按时间轮转
+func (o *Options) rotateByTime() bool {
+	return o.rotationAlign != "" || o.rotationAge > 0
+}
+
+// backupName 返回不与已有文件重名的备份文件名
+func (w *RollWriter) backupName(t time.Time) string {
+	name := w.filePath + strftime(t, w.opts.timeFormat)
+	backup := name
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			return backup
+		}
+		backup = fmt.Sprintf("%s.%d", name, i)
+	}
+}
+
+func (w *RollWriter) notifyScavenger() {
+	if w.opts.maxAge <= 0 && w.opts.rotationCount == 0 && w.opts.maxDiskUsage <= 0 {
+		return
+	}
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// diskUsageCheckInterval 是未轮转时检查磁盘占用的间隔，当前文件持续增长也可能超出上限
+const diskUsageCheckInterval = time.Minute
+
+// scavenge 在每次轮转后清理过期、超出数量和超出磁盘占用上限的备份文件
+func (w *RollWriter) scavenge() {
+	defer w.wg.Done()
+	var tick <-chan time.Time
+	if w.opts.maxDiskUsage > 0 {
+		ticker := w.clock.NewTicker(diskUsageCheckInterval)
+		defer ticker.Stop()
+		tick = ticker.C()
+	}
+	for {
+		select {
+		case <-w.notify:
+			w.removeBackups()
+		case <-tick:
+			w.removeBackups()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// backup 是一个备份文件
+type backup struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// dirWriters 按目录记录本进程打开的 RollWriter，MaxDiskUsage 统计同一目录下所有写入器的文件，
+// dirMu 同时保证同一目录的磁盘占用清理串行执行
+var (
+	dirMu      sync.Mutex
+	dirWriters = make(map[string]map[*RollWriter]struct{})
+)
+
+func registerDir(w *RollWriter) {
+	dirMu.Lock()
+	defer dirMu.Unlock()
+	if dirWriters[w.dir] == nil {
+		dirWriters[w.dir] = make(map[*RollWriter]struct{})
+	}
+	dirWriters[w.dir][w] = struct{}{}
+}
+
+func unregisterDir(w *RollWriter) {
+	dirMu.Lock()
+	defer dirMu.Unlock()
+	delete(dirWriters[w.dir], w)
+	if len(dirWriters[w.dir]) == 0 {
+		delete(dirWriters, w.dir)
+	}
+}
+
+// backups 返回 entries 中属于 w 的备份文件，跳过回调中的备份文件和 current 中其他写入器的当前文件，
+// 按修改时间从新到旧排序
+func (w *RollWriter) backups(entries []os.DirEntry, current map[string]bool) []backup {
+	w.mu.Lock()
+	handling := make([]string, 0, len(w.handling))
+	for b := range w.handling {
+		handling = append(handling, filepath.Base(b))
+	}
+	w.mu.Unlock()
+	var backups []backup
+	for _, e := range entries {
+		if !e.Type().IsRegular() || current[e.Name()] || !w.backupRe.MatchString(e.Name()) || isHandling(e.Name(), handling) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(w.dir, e.Name()), modTime: info.ModTime(), size: info.Size()})
 	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+	return backups
+}
 
-	return &wrapper{rl}, nil
+func (w *RollWriter) removeBackups() {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return
+	}
+	now := w.clock.Now()
+	for i, b := range w.backups(entries, nil) {
+		expired := w.opts.maxAge > 0 && now.Sub(b.modTime) > w.opts.maxAge
+		exceeded := w.opts.rotationCount > 0 && i >= int(w.opts.rotationCount)
+		if expired || exceeded {
+			_ = os.Remove(b.path)
+		}
+	}
+	if w.opts.maxDiskUsage > 0 {
+		w.limitDiskUsage()
+	}
 }
 
-// wrapper 包装 rotatelogs.RotateLogs 以实现 WriteSyncer 接口
-type wrapper struct {
-	*rotatelogs.RotateLogs
+// limitDiskUsage 统计目录下所有写入器的当前文件和备份文件的总大小，超出上限时从最旧的备份开始删除，
+// 当前文件不会被删除，只计入总大小
+func (w *RollWriter) limitDiskUsage() {
+	dirMu.Lock()
+	defer dirMu.Unlock()
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return
+	}
+	writers := dirWriters[w.dir]
+	current := make(map[string]bool, len(writers))
+	var usage int64
+	for other := range writers {
+		current[filepath.Base(other.filePath)] = true
+		if info, err := os.Stat(other.filePath); err == nil {
+			usage += info.Size()
+		}
+	}
+	var backups []backup
+	seen := make(map[string]bool)
+	for other := range writers {
+		for _, b := range other.backups(entries, current) {
+			if !seen[b.path] {
+				seen[b.path] = true
+				usage += b.size
+				backups = append(backups, b)
+			}
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+	for i := len(backups) - 1; i >= 0 && usage > w.opts.maxDiskUsage; i-- {
+		if err := os.Remove(backups[i].path); err == nil {
+			usage -= backups[i].size
+		}
+	}
+}
+
+// isHandling 返回 name 是否为回调中的备份文件或回调正在生成的带后缀的文件
+func isHandling(name string, handling []string) bool {
+	for _, b := range handling {
+		if name == b || strings.HasPrefix(name, b+".") {
+			return true
+		}
+	}
+	return false
 }
 
-func (w *wrapper) Write(p []byte) (n int, err error) {
-	return w.RotateLogs.Write(p)
+// strftimeVerbs 文件名时间格式支持的 strftime 占位符
+var strftimeVerbs = map[byte]struct {
+	layout  string
+	pattern string
+}{
+	'Y': {"2006", `\d{4}`},
+	'm': {"01", `\d{2}`},
+	'd': {"02", `\d{2}`},
+	'H': {"15", `\d{2}`},
+	'M': {"04", `\d{2}`},
+	'S': {"05", `\d{2}`},
 }
 
-func (w *wrapper) Sync() error {
-	return w.RotateLogs.Close()
+// strftime 按 strftime 格式格式化时间，不支持的占位符原样保留
+func strftime(t time.Time, format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] == '%' && i+1 < len(format) {
+			if v, ok := strftimeVerbs[format[i+1]]; ok {
+				b.WriteString(t.Format(v.layout))
+				i++
+				continue
+			}
+			if format[i+1] == '%' {
+				b.WriteByte('%')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(format[i])
+	}
+	return b.String()
+}
+
+// backupPattern 返回匹配备份文件名的正则：文件名 + 时间后缀 + 可选的序号 + 轮转回调追加的后缀，如 .gz
+func backupPattern(base, format string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^" + regexp.QuoteMeta(base))
+	for i := 0; i < len(format); i++ {
+		if format[i] == '%' && i+1 < len(format) {
+			if v, ok := strftimeVerbs[format[i+1]]; ok {
+				b.WriteString(v.pattern)
+				i++
+				continue
+			}
+			if format[i+1] == '%' {
+				b.WriteString("%")
+				i++
+				continue
+			}
+		}
+		b.WriteString(regexp.QuoteMeta(format[i : i+1]))
+	}
+	b.WriteString(`(\.\d+)?(\.[0-9A-Za-z]+)*$`)
+	return regexp.MustCompile(b.String())
 }
@@ -3,8 +3,11 @@ package rollwriter
 import (
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/baisiyi/go-kits/clock"
 )
 
 // TestWithMaxAge tests the WithMaxAge option function.
@@ -148,3 +151,491 @@ func TestNewRollWriterWithOptions(t *testing.T) {
 		t.Error("Expected at least one log file to be created")
 	}
 }
+
+// listBackups returns the sorted backup file names of the log file.
+func listBackups(t *testing.T, filePath string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(filePath))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Name() != filepath.Base(filePath) {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// waitBackups waits until the scavenger leaves n backups.
+func waitBackups(t *testing.T, filePath string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		names := listBackups(t, filePath)
+		if len(names) == n || time.Now().After(deadline) {
+			return names
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestRollWriterRotateBySize tests the size rotation and the backup count limit.
+func TestRollWriterRotateBySize(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "size.log")
+	fc := clock.NewFake(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	w, err := NewRollWriter(filePath,
+		WithClock(fc),
+		WithRotationSize(10),
+		WithRotationAge(0),
+		WithRotationCount(2),
+		WithTimeFormat(".%Y%m%d"),
+	)
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := w.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		fc.Advance(time.Second)
+	}
+	names := waitBackups(t, filePath, 2)
+	want := []string{"size.log.20260102.1", "size.log.20260102.2"}
+	if len(names) != 2 || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("backups = %v, want %v", names, want)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil || string(data) != "0123456789" {
+		t.Errorf("current file = %q, %v", data, err)
+	}
+}
+
+// TestRollWriterRotateByTime tests the time rotation names the backup by the period.
+func TestRollWriterRotateByTime(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "time.log")
+	fc := clock.NewFake(time.Date(2026, 1, 2, 3, 30, 0, 0, time.UTC))
+	w, err := NewRollWriter(filePath, WithClock(fc), WithRotationAge(1), WithRotationSize(0))
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	fc.Advance(20 * time.Minute)
+	w.Write([]byte("second\n"))
+	fc.Advance(20 * time.Minute)
+	w.Write([]byte("third\n"))
+
+	names := listBackups(t, filePath)
+	if len(names) != 1 || names[0] != "time.log.202601020300" {
+		t.Fatalf("backups = %v", names)
+	}
+	data, _ := os.ReadFile(filepath.Join(filepath.Dir(filePath), names[0]))
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("backup = %q", data)
+	}
+}
+
+// TestRollWriterRotationAlign tests that the files are rotated at the midnight of the
+// location whatever the start time, and named by the day in the location.
+func TestRollWriterRotationAlign(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "align.log")
+	shanghai := time.FixedZone("CST", 8*3600)
+	// 2026-01-02 23:30 in Shanghai
+	fc := clock.NewFake(time.Date(2026, 1, 2, 15, 30, 0, 0, time.UTC))
+	w, err := NewRollWriter(filePath, WithClock(fc), WithRotationAlign(AlignDay, shanghai), WithRotationSize(0))
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	fc.Advance(20 * time.Minute)
+	w.Write([]byte("second\n"))
+	fc.Advance(20 * time.Minute) // 00:10 of the next day
+	w.Write([]byte("third\n"))
+	fc.Advance(20 * time.Hour)
+	w.Write([]byte("fourth\n"))
+
+	names := listBackups(t, filePath)
+	if len(names) != 1 || names[0] != "align.log.202601020000" {
+		t.Fatalf("backups = %v", names)
+	}
+	data, _ := os.ReadFile(filepath.Join(filepath.Dir(filePath), names[0]))
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("backup = %q", data)
+	}
+
+	if _, err := NewRollWriter(filePath, WithRotationAlign("week", nil)); err == nil {
+		t.Error("Expected error for unsupported align")
+	}
+}
+
+// TestRollWriterRotationHandler tests that the handler is called with the closed backups in order.
+func TestRollWriterRotationHandler(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "handler.log")
+	fc := clock.NewFake(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	var (
+		mu      sync.Mutex
+		rotated []string
+	)
+	w, err := NewRollWriter(filePath,
+		WithClock(fc),
+		WithRotationSize(10),
+		WithRotationAge(0),
+		WithTimeFormat(".%Y%m%d"),
+		WithRotationHandler(func(oldPath, newPath string) {
+			if oldPath != filePath {
+				t.Errorf("oldPath = %s, want %s", oldPath, filePath)
+			}
+			data, err := os.ReadFile(newPath)
+			if err != nil || string(data) != "0123456789" {
+				t.Errorf("backup %s = %q, %v", newPath, data, err)
+			}
+			mu.Lock()
+			rotated = append(rotated, filepath.Base(newPath))
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Close waits for the handlers.
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"handler.log.20260102", "handler.log.20260102.1"}
+	if len(rotated) != 2 || rotated[0] != want[0] || rotated[1] != want[1] {
+		t.Errorf("rotated = %v, want %v", rotated, want)
+	}
+}
+
+// TestRollWriterRotationHandlerScavenge tests that the backups are not removed while
+// their handlers are pending, and the backups renamed by the handlers are still removed
+// by RotationCount.
+func TestRollWriterRotationHandlerScavenge(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "gz.log")
+	fc := clock.NewFake(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	release := make(chan struct{})
+	var handleErr error
+	w, err := NewRollWriter(filePath,
+		WithClock(fc),
+		WithRotationSize(10),
+		WithRotationAge(0),
+		WithRotationCount(1),
+		WithTimeFormat(".%Y%m%d"),
+		WithRotationHandler(func(oldPath, newPath string) {
+			<-release
+			// compress the backup
+			if err := os.Rename(newPath, newPath+".gz"); err != nil {
+				handleErr = err
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := w.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		fc.Advance(time.Second)
+	}
+	// the scavenger runs after each rotation, the pending backups are kept
+	time.Sleep(20 * time.Millisecond)
+	if names := listBackups(t, filePath); len(names) != 3 {
+		t.Errorf("Expected the pending backups kept, got %v", names)
+	}
+
+	close(release)
+	names := waitBackups(t, filePath, 1)
+	if len(names) != 1 || names[0] != "gz.log.20260102.2.gz" {
+		t.Errorf("backups = %v, want the latest compressed one", names)
+	}
+	if err := w.Close(); err != nil || handleErr != nil {
+		t.Errorf("Close = %v, handler error %v", err, handleErr)
+	}
+}
+
+// TestRollWriterMaxAge tests removing the expired backups and keeping the other files.
+func TestRollWriterMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "age.log")
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	for name, age := range map[string]time.Duration{
+		"age.log.202601010000":   9 * 24 * time.Hour,
+		"age.log.202601090000":   24 * time.Hour,
+		"age.log.202601010000.1": 9 * 24 * time.Hour,
+		"age.log.bak":            9 * 24 * time.Hour,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := NewRollWriter(filePath, WithClock(clock.NewFake(now)), WithMaxAge(7))
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	defer w.Close()
+	names := waitBackups(t, filePath, 2)
+	if len(names) != 2 || names[0] != "age.log.202601090000" || names[1] != "age.log.bak" {
+		t.Errorf("files = %v", names)
+	}
+}
+
+// TestRollWriterMaxDiskUsage tests removing the oldest backups when the total size
+// exceeds the budget, after rotation and periodically.
+func TestRollWriterMaxDiskUsage(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "disk.log")
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"disk.log.202601010000", "disk.log.202601020000", "disk.log.202601030000"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filePath, []byte("01234"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fc := clock.NewFake(now)
+	w, err := NewRollWriter(filePath,
+		WithClock(fc),
+		WithMaxAge(0),
+		WithRotationAge(0),
+		WithRotationSize(0),
+		WithMaxDiskUsage(25),
+	)
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	defer w.Close()
+	names := waitBackups(t, filePath, 2)
+	if len(names) != 2 || names[0] != "disk.log.202601020000" {
+		t.Fatalf("backups = %v", names)
+	}
+
+	// the current file grows without rotation, checked by the ticker.
+	w.Write([]byte("0123456789"))
+	fc.BlockUntil(1)
+	fc.Advance(diskUsageCheckInterval)
+	names = waitBackups(t, filePath, 1)
+	if len(names) != 1 || names[0] != "disk.log.202601030000" {
+		t.Errorf("backups = %v", names)
+	}
+}
+
+// TestRollWriterMaxDiskUsageDir tests that the budget covers the files of all the
+// writers in the directory, removing the oldest backups of any of them.
+func TestRollWriterMaxDiskUsageDir(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"a.log.202601010000", "b.log.202601020000", "a.log.202601030000"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newWriter := func(name string) *RollWriter {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, []byte("01234"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewRollWriter(filePath,
+			WithClock(clock.NewFake(now)),
+			WithMaxAge(0),
+			WithRotationAge(0),
+			WithRotationSize(0),
+			WithMaxDiskUsage(25),
+		)
+		if err != nil {
+			t.Fatalf("NewRollWriter failed: %v", err)
+		}
+		return w
+	}
+	// each writer alone is within the budget, 15 and 25 bytes
+	b := newWriter("b.log")
+	defer b.Close()
+	a := newWriter("a.log")
+	defer a.Close()
+
+	names := waitBackups(t, filepath.Join(dir, "a.log"), 2)
+	if len(names) != 2 || names[0] != "a.log.202601030000" || names[1] != "b.log" {
+		t.Errorf("files = %v", names)
+	}
+}
+
+// TestRollWriterReopen tests appending to the existing file and writing after Close.
+func TestRollWriterReopen(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "reopen.log")
+	for _, msg := range []string{"a", "b"} {
+		w, err := NewRollWriter(filePath)
+		if err != nil {
+			t.Fatalf("NewRollWriter failed: %v", err)
+		}
+		w.Write([]byte(msg))
+		if err := w.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+		if _, err := w.Write([]byte(msg)); err != ErrClosed {
+			t.Errorf("Write after Close error = %v, want ErrClosed", err)
+		}
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != "ab" {
+		t.Errorf("file = %q, want ab", data)
+	}
+}
+
+// TestRollWriterRotateFailure tests that the original file is reopened after a failed
+// rotation, and the rotation is retried by the next write.
+func TestRollWriterRotateFailure(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "fail.log")
+	fc := clock.NewFake(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	// the backup is in a dir not created yet, so the rename fails
+	w, err := NewRollWriter(filePath,
+		WithClock(fc),
+		WithRotationSize(10),
+		WithRotationAge(0),
+		WithTimeFormat(".d/%Y%m%d"),
+	)
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("0123456789")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := w.Write([]byte("a")); err == nil {
+		t.Fatal("Expected the rename error")
+	}
+	if err := os.Mkdir(filePath+".d", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("b")); err != nil {
+		t.Fatalf("Write after the failure failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filePath + ".d/20260102"); string(data) != "0123456789" {
+		t.Errorf("backup = %q", data)
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != "b" {
+		t.Errorf("current file = %q, want b", data)
+	}
+}
+
+// TestRollWriterReopenFailure tests that the file is opened again by the next write after
+// it failed to be reopened by a failed rotation.
+func TestRollWriterReopenFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	filePath := filepath.Join(dir, "fail.log")
+	w, err := NewRollWriter(filePath, WithRotationSize(10), WithRotationAge(0))
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("0123456789")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// both the rename and the reopen fail without the dir
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("a")); err == nil {
+		t.Fatal("Expected the rename error")
+	}
+	if _, err := w.Write([]byte("a")); err == nil || err == ErrClosed {
+		t.Fatalf("Expected the open error, got %v", err)
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("b")); err != nil {
+		t.Fatalf("Write after the dir created failed: %v", err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != "b" {
+		t.Errorf("current file = %q, want b", data)
+	}
+}
+
+// TestRollWriterFileMode tests that the modes of the files and the created dirs are
+// set regardless of umask, and the existing dirs are kept.
+func TestRollWriterFileMode(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Chmod(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(root, "a", "b", "mode.log")
+	w, err := NewRollWriter(filePath, WithFileMode(0o640), WithDirMode(0o750), WithRotationSize(4))
+	if err != nil {
+		t.Fatalf("NewRollWriter failed: %v", err)
+	}
+	defer w.Close()
+	w.Write([]byte("1234"))
+	w.Write([]byte("5678"))
+
+	for path, want := range map[string]os.FileMode{
+		root:                          0o755,
+		filepath.Join(root, "a"):      0o750,
+		filepath.Join(root, "a", "b"): 0o750,
+		filePath:                      0o640,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("mode of %s = %o, want %o", path, info.Mode().Perm(), want)
+		}
+	}
+}
+
+// TestStrftime tests the file name time format.
+func TestStrftime(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got := strftime(ts, ".%Y-%m-%d_%H%M%S%%%j"); got != ".2026-01-02_030405%%j" {
+		t.Errorf("strftime = %q", got)
+	}
+	re := backupPattern("app.log", ".%Y%m%d")
+	for name, want := range map[string]bool{
+		"app.log.20260102":   true,
+		"app.log.20260102.3": true,
+		"app.log":            false,
+		"app.log.error":      false,
+		"xapp.log.20260102":  false,
+	} {
+		if got := re.MatchString(name); got != want {
+			t.Errorf("match %q = %v, want %v", name, got, want)
+		}
+	}
+}
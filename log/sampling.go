@@ -0,0 +1,41 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingHook is called for every entry checked by the sampler of an output,
+// dec has zapcore.LogDropped set for the dropped ones.
+type SamplingHook func(output string, entry zapcore.Entry, dec zapcore.SamplingDecision)
+
+var samplingHook atomic.Pointer[SamplingHook]
+
+// SetSamplingHook sets the hook of the sampled outputs, e.g. to export the drop rate
+// as a metric. It applies to the loggers already created, nil removes the hook.
+func SetSamplingHook(hook SamplingHook) {
+	if hook == nil {
+		samplingHook.Store(nil)
+		return
+	}
+	samplingHook.Store(&hook)
+}
+
+// newSamplerCore wraps core with the sampler of the output config.
+func newSamplerCore(core zapcore.Core, output string, c *SamplingConfig) zapcore.Core {
+	initial, tick := c.Initial, c.Tick
+	if initial <= 0 {
+		initial = 100
+	}
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return zapcore.NewSamplerWithOptions(core, tick, initial, c.Thereafter,
+		zapcore.SamplerHook(func(entry zapcore.Entry, dec zapcore.SamplingDecision) {
+			if hook := samplingHook.Load(); hook != nil {
+				(*hook)(output, entry, dec)
+			}
+		}))
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// TestSampling tests that the repeated entries are dropped and reported to the hook.
+func TestSampling(t *testing.T) {
+	var sampled, dropped atomic.Int32
+	SetSamplingHook(func(output string, entry zapcore.Entry, dec zapcore.SamplingDecision) {
+		if output != OutputConsole {
+			t.Errorf("output = %s", output)
+		}
+		if dec&zapcore.LogDropped != 0 {
+			dropped.Add(1)
+		} else {
+			sampled.Add(1)
+		}
+	})
+	defer SetSamplingHook(nil)
+
+	logger := NewZapLog(Config{{
+		Writer:   OutputConsole,
+		Level:    "info",
+		Sampling: &SamplingConfig{Initial: 2, Thereafter: 3, Tick: time.Minute},
+	}})
+	for i := 0; i < 8; i++ {
+		logger.Info("repeated")
+	}
+	// logged: 1st, 2nd, 5th, 8th
+	if sampled.Load() != 4 || dropped.Load() != 4 {
+		t.Errorf("sampled = %d, dropped = %d, want 4 and 4", sampled.Load(), dropped.Load())
+	}
+}
@@ -0,0 +1,74 @@
+package log
+
+import "go.uber.org/zap/zapcore"
+
+// StacktraceOff disables the stacktraces of an output, see OutputConfig.StacktraceLevel.
+const StacktraceOff = "off"
+
+// parseStacktraceLevel returns the stacktrace level of an output, false if disabled.
+func parseStacktraceLevel(level string) (zapcore.Level, bool, error) {
+	if level == "" || level == StacktraceOff {
+		return 0, false, nil
+	}
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return 0, false, err
+	}
+	return lvl, true, nil
+}
+
+// stacktraceCore drops the stacktraces below its level. The logger captures the
+// stacktraces from the lowest level of all the outputs, so the outputs with a higher
+// level or disabled drop the extra ones.
+type stacktraceCore struct {
+	zapcore.Core
+	enabled bool
+	level   zapcore.Level
+}
+
+// With implements zapcore.Core.
+func (c *stacktraceCore) With(fields []zapcore.Field) zapcore.Core {
+	return &stacktraceCore{Core: c.Core.With(fields), enabled: c.enabled, level: c.level}
+}
+
+// Check implements zapcore.Core.
+func (c *stacktraceCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+	return ce.AddCore(ent, &stacktraceEntry{stacktraceCore: c, inner: inner})
+}
+
+// stacktraceEntry writes the checked entry of the wrapped core without the stacktrace
+// below the level.
+type stacktraceEntry struct {
+	*stacktraceCore
+	inner *zapcore.CheckedEntry
+}
+
+// Write implements zapcore.Core.
+func (e *stacktraceEntry) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !e.enabled || ent.Level < e.level {
+		ent.Stack = ""
+	}
+	return writeChecked(e.inner, ent, fields)
+}
+
+// withCallerStack copies the caller and the stacktrace into the checked entry of a
+// wrapped core, which is checked before the logger adds them.
+func withCallerStack(inner *zapcore.CheckedEntry, ent zapcore.Entry) *zapcore.CheckedEntry {
+	inner.Caller, inner.Stack = ent.Caller, ent.Stack
+	return inner
+}
+
+// writeChecked writes the checked entry of a wrapped core with the caller and the
+// stacktrace of ent. The write error is returned instead of reported by the inner
+// entry, so the outer CheckedEntry reports it to the ErrorOutput of the logger, e.g.
+// set by zap.ErrorOutput.
+func writeChecked(inner *zapcore.CheckedEntry, ent zapcore.Entry, fields []zapcore.Field) error {
+	captured := &capturedError{}
+	inner.ErrorOutput = captured
+	withCallerStack(inner, ent).Write(fields...)
+	return captured.err
+}
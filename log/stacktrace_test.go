@@ -0,0 +1,76 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestStacktraceLevel tests the stacktraces per output, and that the caller and the
+// stacktrace pass through the wrapping cores.
+func TestStacktraceLevel(t *testing.T) {
+	errBuf, offBuf := &syncBuffer{}, &syncBuffer{}
+	RegisterWriterTarget("test-stack-error", errBuf)
+	RegisterWriterTarget("test-stack-off", offBuf)
+	logger := NewZapLog(Config{
+		{
+			Writer:          OutputConsole,
+			Level:           "info",
+			Formatter:       "json",
+			WriteConfig:     WriteConfig{Target: "test-stack-error"},
+			StacktraceLevel: "error",
+			Mask:            &MaskConfig{Fields: []string{"password"}},
+		},
+		{
+			Writer:          OutputConsole,
+			Level:           "info",
+			Formatter:       "json",
+			WriteConfig:     WriteConfig{Target: "test-stack-off"},
+			StacktraceLevel: StacktraceOff,
+		},
+	})
+	logger.Warn("warned")
+	logger.Error("failed")
+
+	lines := errBuf.lines()
+	if len(lines) != 2 || strings.Contains(lines[0], `"S":`) || !strings.Contains(lines[1], `"S":"`) {
+		t.Errorf("Expected the stacktrace of the error only, lines = %q", lines)
+	}
+	if !strings.Contains(lines[1], `"C":"`) {
+		t.Errorf("Expected the caller kept by the mask core, line = %q", lines[1])
+	}
+	for _, line := range offBuf.lines() {
+		if strings.Contains(line, `"S":`) {
+			t.Errorf("Expected no stacktrace of the disabled output, line = %q", line)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for the invalid stacktrace level")
+		}
+	}()
+	NewZapLog(Config{{Writer: OutputConsole, StacktraceLevel: "loud"}})
+}
+
+// TestStacktraceErrorOutput tests that the write errors are reported to the ErrorOutput of the logger.
+func TestStacktraceErrorOutput(t *testing.T) {
+	testErrorOutput(t, func(core zapcore.Core) zapcore.Core {
+		return &stacktraceCore{Core: core, enabled: true, level: zapcore.ErrorLevel}
+	})
+}
+
+// testErrorOutput tests that the write errors of the core wrapped by wrap are reported
+// to the ErrorOutput set by zap.ErrorOutput.
+func testErrorOutput(t *testing.T, wrap func(zapcore.Core) zapcore.Core) {
+	t.Helper()
+	w, errOut := &failingWriter{}, &syncBuffer{}
+	w.failing.Store(true)
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(w), zapcore.DebugLevel)
+	zap.New(wrap(core), zap.ErrorOutput(zapcore.AddSync(errOut))).Error("hello")
+	if got := errOut.lines(); len(got) != 1 || !strings.HasSuffix(got[0], " write error: disk full") {
+		t.Errorf("Expected the write error reported to the error output once, got %q", got)
+	}
+}
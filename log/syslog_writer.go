@@ -0,0 +1,125 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	RegisterWriter(OutputSyslog, WriterFactoryFunc(defaultSyslogWriterFactory))
+}
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+// defaultSyslogWriterFactory creates a syslog writer.
+func defaultSyslogWriterFactory(name string, dec *Decoder) error {
+	core, lvl, err := newSyslogCore(dec.OutputConfig)
+	if err != nil {
+		return err
+	}
+	dec.Core = core
+	dec.ZapLevel = lvl
+	return nil
+}
+
+func newSyslogCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel, error) {
+	sc := c.SyslogConfig
+	facility := syslog.LOG_USER
+	if sc.Facility != "" {
+		f, ok := syslogFacilities[strings.ToLower(sc.Facility)]
+		if !ok {
+			return nil, zap.AtomicLevel{}, fmt.Errorf("log: syslog facility %s not supported", sc.Facility)
+		}
+		facility = f
+	}
+	w, err := syslog.Dial(sc.Network, sc.Address, facility|syslog.LOG_INFO, sc.Tag)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, fmt.Errorf("log: dial syslog error: %w", err)
+	}
+
+	// syslog adds its own timestamp, the time of the entry is only kept if the key is configured.
+	encCfg := *c
+	if encCfg.FormatConfig.TimeKey == "" {
+		encCfg.FormatConfig.TimeKey = zapcore.OmitKey
+	}
+	lvl := zap.NewAtomicLevelAt(Levels[c.Level])
+	return &syslogCore{LevelEnabler: lvl, enc: newEncoder(&encCfg), w: w}, lvl, nil
+}
+
+// syslogCore writes the entries with the syslog severity mapped from the level.
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   *syslog.Writer
+}
+
+// With implements zapcore.Core.
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, w: c.w}
+}
+
+// Check implements zapcore.Core.
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	msg := strings.TrimSuffix(buf.String(), "\n")
+	switch ent.Level {
+	case zapcore.DebugLevel:
+		return c.w.Debug(msg)
+	case zapcore.InfoLevel:
+		return c.w.Info(msg)
+	case zapcore.WarnLevel:
+		return c.w.Warning(msg)
+	case zapcore.ErrorLevel:
+		return c.w.Err(msg)
+	default:
+		return c.w.Crit(msg)
+	}
+}
+
+// Sync implements zapcore.Core.
+func (c *syslogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,53 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSyslogWriter tests writing to a remote syslog daemon over udp.
+func TestSyslogWriter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	defer conn.Close()
+
+	logger := NewZapLog(Config{{
+		Writer: OutputSyslog,
+		Level:  "info",
+		SyslogConfig: SyslogConfig{
+			Network:  "udp",
+			Address:  conn.LocalAddr().String(),
+			Facility: "local0",
+			Tag:      "app",
+		},
+	}})
+	logger.Warn("disk almost full", String("path", "/data"))
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read syslog message: %v", err)
+	}
+	// local0(16) * 8 + warning(4) = 132
+	msg := string(buf[:n])
+	for _, want := range []string{"<132>", "app[", "disk almost full", "/data"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q missing %q", msg, want)
+		}
+	}
+}
+
+// TestSyslogWriterFacility tests the unsupported facility.
+func TestSyslogWriterFacility(t *testing.T) {
+	_, _, err := newSyslogCore(&OutputConfig{SyslogConfig: SyslogConfig{Facility: "local9"}})
+	if err == nil {
+		t.Error("Expected error for unsupported facility")
+	}
+}
@@ -0,0 +1,49 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The built-in targets of the console writer.
+const (
+	TargetStdout = "stdout"
+	TargetStderr = "stderr"
+)
+
+var (
+	targetMu sync.RWMutex
+	targets  = map[string]zapcore.WriteSyncer{
+		TargetStdout: zapcore.Lock(os.Stdout),
+		TargetStderr: zapcore.Lock(os.Stderr),
+	}
+)
+
+// RegisterWriterTarget registers w as a target of the console writer selected by
+// WriteConfig.Target, e.g. a buffer in tests or a pipe of an embedding program. The
+// writes are serialized, w is synced on Sync if it implements zapcore.WriteSyncer.
+// Registering stdout or stderr replaces the built-in target for the loggers created
+// afterwards.
+func RegisterWriterTarget(name string, w io.Writer) {
+	targetMu.Lock()
+	defer targetMu.Unlock()
+	targets[name] = zapcore.Lock(zapcore.AddSync(w))
+}
+
+// writerTarget returns the registered target, stdout if name is empty.
+func writerTarget(name string) (zapcore.WriteSyncer, error) {
+	if name == "" {
+		name = TargetStdout
+	}
+	targetMu.RLock()
+	defer targetMu.RUnlock()
+	ws, ok := targets[name]
+	if !ok {
+		return nil, fmt.Errorf("log: writer target %s not registered", name)
+	}
+	return ws, nil
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestWriterTarget tests that the console output goes to the registered target.
+func TestWriterTarget(t *testing.T) {
+	var buf bytes.Buffer
+	RegisterWriterTarget("test-buffer", &buf)
+	logger := NewZapLog(Config{{
+		Writer:      OutputConsole,
+		Level:       "info",
+		WriteConfig: WriteConfig{Target: "test-buffer"},
+	}})
+	logger.Info("to buffer")
+	_ = logger.Sync()
+	if !strings.Contains(buf.String(), "to buffer") {
+		t.Errorf("buffer = %q", buf.String())
+	}
+
+	if ws, err := writerTarget(TargetStderr); err != nil || ws == nil {
+		t.Errorf("stderr target = %v, %v", ws, err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for the unregistered target")
+		}
+	}()
+	NewZapLog(Config{{Writer: OutputConsole, WriteConfig: WriteConfig{Target: "missing"}}})
+}
@@ -1,8 +1,11 @@
 package log
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -24,6 +27,42 @@ var Levels = map[string]zapcore.Level{
 
 type ZapLogger struct {
 	logger *zap.Logger
+	sugar  *zap.SugaredLogger
+	levels []outputLevel
+	closer *writersCloser // shared by the loggers of With and Named
+}
+
+func newZapLogger(l *zap.Logger, levels []outputLevel, closer *writersCloser) *ZapLogger {
+	return &ZapLogger{logger: l, sugar: l.Sugar(), levels: levels, closer: closer}
+}
+
+// writersCloser closes the writers of the outputs once.
+type writersCloser struct {
+	once    sync.Once
+	closers []io.Closer
+	err     error
+}
+
+// Close closes the writers in the order of the outputs.
+func (c *writersCloser) Close() error {
+	c.once.Do(func() {
+		var errs []error
+		for _, closer := range c.closers {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		c.err = errors.Join(errs...)
+	})
+	return c.err
+}
+
+// closerFunc is an adapter to allow the use of ordinary functions as io.Closer.
+type closerFunc func() error
+
+// Close calls fn().
+func (fn closerFunc) Close() error {
+	return fn()
 }
 
 // WriterFactory creates a zapcore.Core.
@@ -70,6 +109,9 @@ type Decoder struct {
 	OutputConfig *OutputConfig
 	Core         zapcore.Core
 	ZapLevel     zap.AtomicLevel
+	// Closer flushes and closes the writers of Core on ZapLogger.Close, nil if the
+	// writers hold no resources.
+	Closer io.Closer
 }
 
 // Decode 作用：配置plugin，解耦plugin的配置实例和参数实例，参数实例只要实现了Decoder接口，即可在Decode方法中，将参数实例赋值给plugin的配置实例
@@ -84,35 +126,160 @@ func (d Decoder) Decode(cfg interface{}) error {
 	return nil
 }
 
-func NewZapLog(c Config) Logger {
-	return NewZapLogWithCallerSkip(c, 2)
+// NewZapLog creates the Logger of c from zap, opts are applied to a copy of c like Init.
+func NewZapLog(c Config, opts ...Option) Logger {
+	return NewZapLogWithCallerSkip(c, 2, opts...)
 }
 
 // NewZapLogWithCallerSkip creates a trpc default Logger from zap.
-func NewZapLogWithCallerSkip(cfg Config, callerSkip int) Logger {
-	var cores []zapcore.Core
-	for _, c := range cfg {
+func NewZapLogWithCallerSkip(cfg Config, callerSkip int, opts ...Option) Logger {
+	cfg, lo := applyOptions(cfg, opts)
+	l, err := newZapLog(cfg, callerSkip, lo)
+	if err != nil {
+		panic(err.Error())
+	}
+	return l
+}
+
+// newZapLog creates the ZapLogger of cfg, the writers already created are closed on error.
+func newZapLog(cfg Config, callerSkip int, lo loggerOptions) (_ *ZapLogger, err error) {
+	var (
+		cores  []zapcore.Core
+		levels []outputLevel
+		closer = &writersCloser{}
+		opts   = []zap.Option{zap.AddCallerSkip(callerSkip), zap.AddCaller(), zap.Hooks(runGlobalHooks)}
+	)
+	defer func() {
+		if err != nil {
+			_ = closer.Close()
+		}
+	}()
+	stackLevel, stackEnabled, err := minStacktraceLevel(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if stackEnabled {
+		opts = append(opts, zap.AddStacktrace(stackLevel))
+	}
+	names, err := outputNames(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range cfg {
 		writer := GetWriter(c.Writer)
 		if writer == nil {
-			panic("log: writer core: " + c.Writer + " no registered")
+			return nil, errors.New("log: writer core: " + c.Writer + " no registered")
+		}
+		if _, err := loadTimeZone(c.FormatConfig.TimeZone); err != nil {
+			return nil, errors.New("log: writer core: " + c.Writer + " " + err.Error())
+		}
+		// the writer core of an output with name levels enables the name levels lower
+		// than its level, namedLevelCore checks the level of the entries
+		var named *namedLevels
+		setup := c
+		if len(c.Levels) > 0 {
+			n, err := parseNamedLevels(c.Levels)
+			if err != nil {
+				return nil, errors.New("log: writer core: " + c.Writer + " " + err.Error())
+			}
+			named = n
+			setup.Level = min(Levels[c.Level], named.minLevel).String()
 		}
 		var decoder Decoder
-		decoder.OutputConfig = &c
+		decoder.OutputConfig = &setup
 		if err := writer.Setup(c.Writer, &decoder); err != nil {
-			panic("log: writer core: " + c.Writer + " setup fail: " + err.Error())
+			return nil, errors.New("log: writer core: " + c.Writer + " setup fail: " + err.Error())
+		}
+		if decoder.Closer != nil {
+			closer.closers = append(closer.closers, decoder.Closer)
+		}
+		core, err := newIsolatedCore(decoder.Core, &c)
+		if err != nil {
+			return nil, errors.New("log: writer core: " + c.Writer + " fallback: " + err.Error())
+		}
+		core = withStaticFields(core, c.Fields)
+		var level zap.AtomicLevel
+		if named != nil {
+			level = zap.NewAtomicLevelAt(Levels[c.Level])
+			core = &namedLevelCore{Core: core, namedLevels: named, level: level}
+		}
+		if c.MinLevel != "" || c.MaxLevel != "" {
+			if core, err = newLevelRangeCore(core, c.MinLevel, c.MaxLevel); err != nil {
+				return nil, errors.New("log: writer core: " + c.Writer + " level range: " + err.Error())
+			}
 		}
-		cores = append(cores, decoder.Core)
+		if c.Sampling != nil {
+			core = newSamplerCore(core, c.Writer, c.Sampling)
+		}
+		if len(c.Hooks) > 0 || len(c.HookFuncs) > 0 {
+			hs, err := outputHooks(&c)
+			if err != nil {
+				return nil, errors.New("log: writer core: " + c.Writer + " " + err.Error())
+			}
+			core = &hookCore{Core: core, hooks: hs}
+		}
+		if c.RateLimit != nil {
+			if core, err = newRateLimitCore(core, c.Writer, c.RateLimit); err != nil {
+				return nil, errors.New("log: writer core: " + c.Writer + " " + err.Error())
+			}
+		}
+		if c.Mask != nil {
+			if core, err = newMaskCore(core, c.Mask); err != nil {
+				return nil, errors.New("log: writer core: " + c.Writer + " " + err.Error())
+			}
+		}
+		if c.Dedup != nil {
+			core = newDedupCore(core, c.Dedup)
+		}
+		if lvl, ok, _ := parseStacktraceLevel(c.StacktraceLevel); stackEnabled && (!ok || lvl > stackLevel) {
+			core = &stacktraceCore{Core: core, enabled: ok, level: lvl}
+		}
+		cores = append(cores, core)
+		if decoder.ZapLevel != (zap.AtomicLevel{}) {
+			ol := outputLevel{output: names[i], writer: c.Writer, level: decoder.ZapLevel}
+			if named != nil {
+				ol.level, ol.floor, ol.nameMin = level, decoder.ZapLevel, named.minLevel
+			}
+			levels = append(levels, ol)
+		}
+	}
+	if lo.exit != nil {
+		opts = append(opts, zap.WithFatalHook(exitHook(lo.exit)))
 	}
-	return &ZapLogger{
-		logger: zap.New(
-			zapcore.NewTee(cores...),
-			zap.AddCallerSkip(callerSkip),
-			zap.AddCaller(),
-		),
+	return newZapLogger(zap.New(zapcore.NewTee(cores...), opts...), levels, closer), nil
+}
+
+// minStacktraceLevel returns the lowest stacktrace level of the outputs, false if all disabled.
+func minStacktraceLevel(cfg Config) (zapcore.Level, bool, error) {
+	var (
+		level   zapcore.Level
+		enabled bool
+	)
+	for _, c := range cfg {
+		lvl, ok, err := parseStacktraceLevel(c.StacktraceLevel)
+		if err != nil {
+			return 0, false, fmt.Errorf("log: writer core: %s stacktrace level %q invalid", c.Writer, c.StacktraceLevel)
+		}
+		if ok && (!enabled || lvl < level) {
+			level, enabled = lvl, true
+		}
 	}
+	return level, enabled, nil
+}
+
+// exitHook calls the exit func instead of os.Exit after the fatal logs.
+type exitHook func(code int)
+
+// OnWrite implements zapcore.CheckWriteHook.
+func (h exitHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	h(1)
 }
 
 func newEncoder(c *OutputConfig) zapcore.Encoder {
+	loc, err := loadTimeZone(c.FormatConfig.TimeZone)
+	if err != nil {
+		loc = time.Local
+	}
 	encoderCfg := zapcore.EncoderConfig{
 		TimeKey:        GetLogEncoderKey("T", c.FormatConfig.TimeKey),
 		LevelKey:       GetLogEncoderKey("L", c.FormatConfig.LevelKey),
@@ -123,7 +290,7 @@ func newEncoder(c *OutputConfig) zapcore.Encoder {
 		StacktraceKey:  GetLogEncoderKey("S", c.FormatConfig.StacktraceKey),
 		LineEnding:     zapcore.DefaultLineEnding,
 		EncodeLevel:    zapcore.CapitalLevelEncoder,
-		EncodeTime:     NewTimeEncoder(c.FormatConfig.TimeFmt),
+		EncodeTime:     NewTimeEncoderInLocation(c.FormatConfig.TimeFmt, loc),
 		EncodeDuration: zapcore.StringDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
@@ -140,66 +307,221 @@ func newEncoder(c *OutputConfig) zapcore.Encoder {
 var formatEncoders = map[string]NewFormatEncoder{
 	FormatterConsole: zapcore.NewConsoleEncoder,
 	FormatterJson:    zapcore.NewJSONEncoder,
+	FormatterDev:     newDevEncoder,
 }
 
 // NewFormatEncoder is the function type for creating a format encoder out of an encoder config.
 type NewFormatEncoder func(zapcore.EncoderConfig) zapcore.Encoder
 
 // RegisterFormatEncoder registers a NewFormatEncoder with the specified formatName key.
-// The existing formats include "console" and "json", but you can override these format encoders
+// The existing formats include "console", "json" and "dev", but you can override these format encoders
 // or provide a new custom one.
 func RegisterFormatEncoder(formatName string, newFormatEncoder NewFormatEncoder) {
 	formatEncoders[formatName] = newFormatEncoder
 }
 
-func newConsoleCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel) {
+func newConsoleCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel, error) {
+	ws, err := writerTarget(c.WriteConfig.Target)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, err
+	}
 	lvl := zap.NewAtomicLevelAt(Levels[c.Level])
 	return zapcore.NewCore(
 		newEncoder(c),
-		zapcore.Lock(os.Stdout),
-		lvl), lvl
+		ws,
+		lvl), lvl, nil
+}
+
+// newFileCore creates the core of the file output, the closer closes its files.
+func newFileCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel, io.Closer, error) {
+	if c.WriteConfig.Filename == "" {
+		c.WriteConfig.Filename = DefaultLogFileName
+	}
+	switch c.WriteConfig.WriteMode {
+	case "", WriteModeSync, WriteModeAsync:
+	default:
+		return nil, zap.AtomicLevel{}, nil, fmt.Errorf("log: write_mode %s not supported", c.WriteConfig.WriteMode)
+	}
+	// log level.
+	lvl := zap.NewAtomicLevelAt(Levels[c.Level])
+	if len(c.WriteConfig.LevelFiles) > 0 {
+		core, closer, err := newLevelFilesCore(c, lvl)
+		return core, lvl, closer, err
+	}
+	ws, err := newFileWriteSyncer(&c.WriteConfig, c.WriteConfig.Filename)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, nil, err
+	}
+	return zapcore.NewCore(
+		newEncoder(c),
+		ws, lvl,
+	), lvl, ws, nil
+}
+
+// newLevelFilesCore creates a core per level file sharing the level and the rotation
+// options, each file gets the entries from its level to the next configured level.
+func newLevelFilesCore(c *OutputConfig, lvl zap.AtomicLevel) (zapcore.Core, io.Closer, error) {
+	type levelFile struct {
+		level    zapcore.Level
+		filename string
+	}
+	files := make([]levelFile, 0, len(c.WriteConfig.LevelFiles))
+	for level, filename := range c.WriteConfig.LevelFiles {
+		l, err := ParseLevel(level)
+		if err != nil {
+			return nil, nil, err
+		}
+		// the bare file names are in the directory of filename.
+		if filepath.Base(filename) == filename {
+			filename = filepath.Join(filepath.Dir(c.WriteConfig.Filename), filename)
+		}
+		files = append(files, levelFile{level: l, filename: filename})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].level < files[j].level })
+	for i := 1; i < len(files); i++ {
+		if files[i].level == files[i-1].level {
+			return nil, nil, fmt.Errorf("log: level_files level %s configured twice", files[i].level)
+		}
+	}
+
+	cores := make([]zapcore.Core, 0, len(files))
+	closer := &writersCloser{}
+	for i, f := range files {
+		ws, err := newFileWriteSyncer(&c.WriteConfig, f.filename)
+		if err != nil {
+			_ = closer.Close()
+			return nil, nil, err
+		}
+		closer.closers = append(closer.closers, ws)
+		maxLevel := zapcore.FatalLevel
+		if i < len(files)-1 {
+			maxLevel = files[i+1].level - 1
+		}
+		cores = append(cores, &levelRangeCore{
+			Core: zapcore.NewCore(newEncoder(c), ws, lvl),
+			min:  f.level,
+			max:  maxLevel,
+		})
+	}
+	return zapcore.NewTee(cores...), closer, nil
 }
 
-func newFileCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel, error) {
+// fileWriteSyncer is the writer of a file output, Close flushes the buffered logs and
+// closes the file.
+type fileWriteSyncer interface {
+	zapcore.WriteSyncer
+	io.Closer
+}
 
+// syncCloser closes the rolling writer after stopping the writer wrapping it.
+type syncCloser struct {
+	zapcore.WriteSyncer
+	stop   func() error
+	writer io.Closer
+}
+
+// Close implements io.Closer.
+func (s *syncCloser) Close() error {
+	var err error
+	if s.stop != nil {
+		err = s.stop()
+	}
+	return errors.Join(err, s.writer.Close())
+}
+
+// newFileWriteSyncer creates the rolling writer of filename by the write config.
+func newFileWriteSyncer(wc *WriteConfig, filename string) (fileWriteSyncer, error) {
 	opts := []rollwriter.OptionFunc{
-		rollwriter.WithMaxAge(c.WriteConfig.MaxAge),
-		rollwriter.WithRotationAgeDuration(time.Duration(c.WriteConfig.RotationTime) * time.Minute),
-		rollwriter.WithRotationSizeMB(c.WriteConfig.MaxSize),
-		rollwriter.WithRotationCount(c.WriteConfig.MaxBackups),
+		rollwriter.WithMaxAge(wc.MaxAge),
+		rollwriter.WithRotationAgeDuration(time.Duration(wc.RotationTime) * time.Minute),
+		rollwriter.WithRotationSizeMB(wc.MaxSize),
+		rollwriter.WithRotationCount(wc.MaxBackups),
+		rollwriter.WithMaxDiskUsage(wc.MaxDiskUsage * rollwriter.MB),
+		rollwriter.WithFileMode(wc.FileMode),
+		rollwriter.WithDirMode(wc.DirMode),
 	}
 
-	// 使用配置的 TimeFormat，未配置时使用默认值（由 rollwriter 内部处理）
-	if c.WriteConfig.TimeFormat != "" {
-		opts = append(opts, rollwriter.WithTimeFormat(c.WriteConfig.TimeFormat))
+	if wc.RotationAlign != "" {
+		loc := time.Local
+		if wc.RotationTimezone != "" {
+			var err error
+			if loc, err = time.LoadLocation(wc.RotationTimezone); err != nil {
+				return nil, fmt.Errorf("log: rotation timezone error: %w", err)
+			}
+		}
+		opts = append(opts, rollwriter.WithRotationAlign(wc.RotationAlign, loc))
 	}
 
-	if c.WriteConfig.Filename == "" {
-		c.WriteConfig.Filename = DefaultLogFileName
+	// 使用配置的 TimeFormat，未配置时使用默认值（由 rollwriter 内部处理）
+	if wc.TimeFormat != "" {
+		opts = append(opts, rollwriter.WithTimeFormat(wc.TimeFormat))
 	}
 
-	writer, err := rollwriter.NewRollWriter(c.WriteConfig.Filename, opts...)
-	if err != nil {
-		return nil, zap.AtomicLevel{}, err
+	var writer interface {
+		rollwriter.WriteSyncer
+		io.Closer
+	}
+	switch wc.Rotator {
+	case "", RotatorNative, RotatorRotateLogs:
+		w, err := rollwriter.NewRollWriter(filename, opts...)
+		if err != nil {
+			return nil, err
+		}
+		writer = w
+	case RotatorLumberjack:
+		w, err := rollwriter.NewLumberjackWriter(filename, opts...)
+		if err != nil {
+			return nil, err
+		}
+		writer = w
+	default:
+		return nil, fmt.Errorf("log: rotator %s not supported", wc.Rotator)
 	}
 
 	// write mode.
-	var ws zapcore.WriteSyncer
-	ws = zapcore.AddSync(writer)
-	// log level.
-	lvl := zap.NewAtomicLevelAt(Levels[c.Level])
-	return zapcore.NewCore(
-		newEncoder(c),
-		ws, lvl,
-	), lvl, nil
+	if wc.WriteMode == WriteModeAsync {
+		asyncOpts := []rollwriter.AsyncOptionFunc{
+			rollwriter.WithQueueSize(wc.QueueSize),
+			rollwriter.WithDropOnFull(wc.DropOnFull),
+		}
+		if wc.BufferSize > 0 {
+			asyncOpts = append(asyncOpts, rollwriter.WithWriteSize(wc.BufferSize))
+		}
+		if wc.FlushInterval > 0 {
+			asyncOpts = append(asyncOpts, rollwriter.WithWriteInterval(wc.FlushInterval))
+		}
+		async := rollwriter.NewAsyncRollWriter(writer, asyncOpts...)
+		return &syncCloser{WriteSyncer: async, stop: async.Close, writer: writer}, nil
+	}
+	if wc.BufferSize > 0 {
+		// Sync 会写入缓冲中的日志，Fatal 等高于 Error 级别的日志写入后 zap 也会调用 Sync
+		interval := wc.FlushInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		buffered := &zapcore.BufferedWriteSyncer{
+			WS:            zapcore.AddSync(writer),
+			Size:          wc.BufferSize,
+			FlushInterval: interval,
+		}
+		// Stop 写入缓冲中的日志并停止定时写入的 goroutine
+		return &syncCloser{WriteSyncer: buffered, stop: buffered.Stop, writer: writer}, nil
+	}
+	return &syncCloser{WriteSyncer: zapcore.AddSync(writer), writer: writer}, nil
 }
 
-// NewTimeEncoder creates a time format encoder.
+// NewTimeEncoder creates a time format encoder in the Local time zone.
 func NewTimeEncoder(format string) zapcore.TimeEncoder {
+	return NewTimeEncoderInLocation(format, time.Local)
+}
+
+// NewTimeEncoderInLocation creates a time format encoder converting the time to loc,
+// the epoch formats are not affected by loc.
+func NewTimeEncoderInLocation(format string, loc *time.Location) zapcore.TimeEncoder {
 	switch format {
 	case "":
 		return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-			enc.AppendByteString(defaultTimeFormat(t))
+			enc.AppendByteString(defaultTimeFormat(t.In(loc)))
 		}
 	case "seconds":
 		return zapcore.EpochTimeEncoder
@@ -209,15 +531,14 @@ func NewTimeEncoder(format string) zapcore.TimeEncoder {
 		return zapcore.EpochNanosTimeEncoder
 	default:
 		return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-			enc.AppendString(t.Format(format))
+			enc.AppendString(t.In(loc).Format(format))
 		}
 	}
 }
 
-// defaultTimeFormat returns the default time format "2006-01-02 15:04:05.000",
-// which performs better than https://pkg.go.dev/time#Time.AppendFormat.
+// defaultTimeFormat returns the default time format "2006-01-02 15:04:05.000" of t in
+// its location, which performs better than https://pkg.go.dev/time#Time.AppendFormat.
 func defaultTimeFormat(t time.Time) []byte {
-	t = t.Local()
 	year, month, day := t.Date()
 	hour, minute, second := t.Clock()
 	micros := t.Nanosecond() / 1000
@@ -249,6 +570,18 @@ func defaultTimeFormat(t time.Time) []byte {
 	return buf
 }
 
+// loadTimeZone returns the location of the time zone, Local on empty.
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("time zone %s invalid: %w", name, err)
+	}
+	return loc, nil
+}
+
 // GetLogEncoderKey gets user defined log output name, uses defKey if empty.
 func GetLogEncoderKey(defKey, key string) string {
 	if key == "" {
@@ -299,13 +632,52 @@ func (z *ZapLogger) Warnf(format string, args ...interface{}) {
 	z.logger.Warn(fmt.Sprintf(format, args...))
 }
 
+func (z *ZapLogger) Fatalf(format string, args ...interface{}) {
+	z.logger.Fatal(fmt.Sprintf(format, args...))
+}
+
+func (z *ZapLogger) Panicf(format string, args ...interface{}) {
+	z.logger.Panic(fmt.Sprintf(format, args...))
+}
+
+// 键值对日志方法（兼容 zap.SugaredLogger 的调用方式）
+func (z *ZapLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	z.sugar.Debugw(msg, keysAndValues...)
+}
+
+func (z *ZapLogger) Infow(msg string, keysAndValues ...interface{}) {
+	z.sugar.Infow(msg, keysAndValues...)
+}
+
+func (z *ZapLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	z.sugar.Warnw(msg, keysAndValues...)
+}
+
+func (z *ZapLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	z.sugar.Errorw(msg, keysAndValues...)
+}
+
 // 上下文方法
 func (z *ZapLogger) With(fields ...Field) Logger {
-	return &ZapLogger{logger: z.logger.With(fields...)}
+	return newZapLogger(z.logger.With(fields...), z.levels, z.closer)
 }
 
 func (z *ZapLogger) Named(name string) Logger {
-	return &ZapLogger{logger: z.logger.Named(name)}
+	return newZapLogger(z.logger.Named(name), z.levels, z.closer)
+}
+
+func (z *ZapLogger) WithCallerSkip(delta int) Logger {
+	return newZapLogger(z.logger.WithOptions(zap.AddCallerSkip(delta)), z.levels, z.closer)
+}
+
+// WithOptions 返回应用了 opts 的 logger，输出级别仍可通过 SetLevel 调整
+func (z *ZapLogger) WithOptions(opts ...zap.Option) Logger {
+	return newZapLogger(z.logger.WithOptions(opts...), z.levels, z.closer)
+}
+
+// GetZapLogger 返回底层的 *zap.Logger，用于需要 *zap.Logger 的第三方库
+func (z *ZapLogger) GetZapLogger() *zap.Logger {
+	return z.logger
 }
 
 // Sync 实现sync接口
@@ -313,9 +685,18 @@ func (z *ZapLogger) Sync() error {
 	return z.logger.Sync()
 }
 
+// Close 写入队列和缓冲中的日志后关闭各输出的写入器，之后写入这些输出的日志会失败。
+// With、Named 等返回的 logger 共享写入器，关闭任意一个即可，重复调用返回第一次的结果
+func (z *ZapLogger) Close() error {
+	return z.closer.Close()
+}
+
 // defaultConsoleWriterFactory creates a console writer.
 func defaultConsoleWriterFactory(name string, dec *Decoder) error {
-	core, lvl := newConsoleCore(dec.OutputConfig)
+	core, lvl, err := newConsoleCore(dec.OutputConfig)
+	if err != nil {
+		return err
+	}
 	dec.Core = core
 	dec.ZapLevel = lvl
 	return nil
@@ -323,11 +704,12 @@ func defaultConsoleWriterFactory(name string, dec *Decoder) error {
 
 // defaultFileWriterFactory creates a file writer.
 func defaultFileWriterFactory(name string, dec *Decoder) error {
-	core, lvl, err := newFileCore(dec.OutputConfig)
+	core, lvl, closer, err := newFileCore(dec.OutputConfig)
 	if err != nil {
 		return err
 	}
 	dec.Core = core
 	dec.ZapLevel = lvl
+	dec.Closer = closer
 	return nil
 }
@@ -1,10 +1,21 @@
 package log
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
+	_ "time/tzdata"
 
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/baisiyi/go-kits/log/rollwriter"
 )
 
 // TestLevels tests the Levels map for correct string to zapcore.Level mapping.
@@ -111,6 +122,38 @@ func TestDefaultTimeFormat(t *testing.T) {
 	}
 }
 
+// TestTimeZone tests the timestamps converted to the time zone of the output.
+func TestTimeZone(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 18, 30, 45, 123456789, time.UTC)
+	tests := []struct {
+		timeFmt  string
+		timeZone string
+		want     string
+	}{
+		{"", "UTC", `"T":"2024-01-15 18:30:45.123"`},
+		{"", "Asia/Shanghai", `"T":"2024-01-16 02:30:45.123"`},
+		{time.RFC3339, "Asia/Shanghai", `"T":"2024-01-16T02:30:45+08:00"`},
+		{"seconds", "Asia/Shanghai", `"T":1705343445.1234567`},
+	}
+	for _, tt := range tests {
+		enc := newEncoder(&OutputConfig{Formatter: FormatterJson, FormatConfig: FormatConfig{TimeFmt: tt.timeFmt, TimeZone: tt.timeZone}})
+		buf, err := enc.EncodeEntry(zapcore.Entry{Time: ts, Message: "m"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("time_fmt %q time_zone %q: output %s missing %s", tt.timeFmt, tt.timeZone, buf.String(), tt.want)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "time zone Mars/Base invalid") {
+			t.Errorf("recover() = %v, want the invalid time zone panic", r)
+		}
+	}()
+	NewZapLog(Config{{Writer: OutputConsole, FormatConfig: FormatConfig{TimeZone: "Mars/Base"}}})
+}
+
 // TestNewZapLog tests creating a ZapLogger with console output.
 func TestNewZapLog(t *testing.T) {
 	// This should not panic with valid console config
@@ -180,3 +223,174 @@ func TestRegisterFormatEncoder(t *testing.T) {
 	// Clean up
 	delete(formatEncoders, "custom_test")
 }
+
+// TestAsyncWriteMode tests the file output in async write mode.
+func TestAsyncWriteMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "async.log")
+	logger := NewZapLog(Config{{
+		Writer:      OutputFile,
+		Level:       "info",
+		WriteConfig: WriteConfig{Filename: filename, WriteMode: WriteModeAsync},
+	}})
+	logger.Info("async message")
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil || !strings.Contains(string(data), "async message") {
+		t.Errorf("Expected message written after Sync, got %q %v", data, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for unknown write mode")
+		}
+	}()
+	NewZapLog(Config{{Writer: OutputFile, WriteConfig: WriteConfig{Filename: filename, WriteMode: "fast"}}})
+}
+
+// TestAsyncWriteModeClose tests that Close writes the queued logs and stops the async writer.
+func TestAsyncWriteModeClose(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "async.log")
+	logger := NewZapLog(Config{{
+		Writer:      OutputFile,
+		Level:       "info",
+		WriteConfig: WriteConfig{Filename: filename, WriteMode: WriteModeAsync, FlushInterval: time.Hour},
+	}}).(*ZapLogger)
+	logger.With(String("k", "v")).Info("queued message")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil || !strings.Contains(string(data), "queued message") {
+		t.Errorf("Expected message written after Close, got %q %v", data, err)
+	}
+	async := logger.closer.closers[0].(*syncCloser).WriteSyncer.(*rollwriter.AsyncRollWriter)
+	if err := async.Sync(); !errors.Is(err, rollwriter.ErrClosed) {
+		t.Errorf("Expected the async writer closed, got %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Errorf("Close twice failed: %v", err)
+	}
+}
+
+// TestBufferedWrite tests the buffered file writes flushed by Sync.
+func TestBufferedWrite(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "buffered.log")
+	logger := NewZapLog(Config{{
+		Writer:      OutputFile,
+		Level:       "info",
+		WriteConfig: WriteConfig{Filename: filename, BufferSize: 64 * 1024, FlushInterval: time.Hour},
+	}})
+	logger.Info("buffered message")
+	if data, _ := os.ReadFile(filename); strings.Contains(string(data), "buffered message") {
+		t.Errorf("Expected message buffered before Sync, got %q", data)
+	}
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil || !strings.Contains(string(data), "buffered message") {
+		t.Errorf("Expected message written after Sync, got %q %v", data, err)
+	}
+}
+
+// TestZapLoggerKeyValues tests the key/value logging methods.
+func TestZapLoggerKeyValues(t *testing.T) {
+	var buf bytes.Buffer
+	RegisterWriter("kv_buffer", WriterFactoryFunc(func(name string, dec *Decoder) error {
+		dec.ZapLevel = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+		dec.Core = zapcore.NewCore(newEncoder(dec.OutputConfig), zapcore.AddSync(&buf), dec.ZapLevel)
+		return nil
+	}))
+	logger := NewZapLogWithCallerSkip(Config{{Writer: "kv_buffer", Formatter: FormatterJson}}, 1)
+
+	logger.Infow("user login", "user_id", 42, String("ip", "127.0.0.1"))
+	out := buf.String()
+	for _, want := range []string{`"M":"user login"`, `"user_id":42`, `"ip":"127.0.0.1"`, "zaplogger_test.go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %s missing %s", out, want)
+		}
+	}
+}
+
+// logWrapped is a wrapper of Logger, reporting the caller of itself.
+func logWrapped(l Logger, msg string) {
+	l.WithCallerSkip(1).Info(msg)
+}
+
+// TestZapLoggerWithCallerSkip tests the caller corrected for a wrapper.
+func TestZapLoggerWithCallerSkip(t *testing.T) {
+	var buf bytes.Buffer
+	RegisterWriter("skip_buffer", WriterFactoryFunc(func(name string, dec *Decoder) error {
+		dec.ZapLevel = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+		dec.Core = zapcore.NewCore(newEncoder(dec.OutputConfig), zapcore.AddSync(&buf), dec.ZapLevel)
+		return nil
+	}))
+	logger := NewZapLogWithCallerSkip(Config{{Writer: "skip_buffer", Formatter: FormatterJson}}, 1)
+
+	_, _, line, _ := runtime.Caller(0)
+	logWrapped(logger, "wrapped")
+	if want := fmt.Sprintf("zaplogger_test.go:%d", line+1); !strings.Contains(buf.String(), want) {
+		t.Errorf("output %s missing caller %s", buf.String(), want)
+	}
+}
+
+// TestZapLoggerWithOptions tests the zap options passed through and the underlying zap logger.
+func TestZapLoggerWithOptions(t *testing.T) {
+	var buf bytes.Buffer
+	RegisterWriter("options_buffer", WriterFactoryFunc(func(name string, dec *Decoder) error {
+		dec.ZapLevel = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+		dec.Core = zapcore.NewCore(newEncoder(dec.OutputConfig), zapcore.AddSync(&buf), dec.ZapLevel)
+		return nil
+	}))
+	logger := NewZapLogWithCallerSkip(Config{{Writer: "options_buffer", Formatter: FormatterJson}}, 1)
+
+	var hooked []string
+	l := logger.WithOptions(zap.Hooks(func(e zapcore.Entry) error {
+		hooked = append(hooked, e.Message)
+		return nil
+	}), zap.Fields(String("app", "demo")))
+	l.Info("with options")
+	logger.Info("without options")
+	if len(hooked) != 1 || hooked[0] != "with options" {
+		t.Errorf("hooked = %v, want only the message of the logger with options", hooked)
+	}
+	if out := buf.String(); !strings.Contains(out, `"M":"with options","app":"demo"`) {
+		t.Errorf("output %s missing the field added by the options", out)
+	}
+
+	zl := l.(*ZapLogger).GetZapLogger()
+	zl.Info("from zap")
+	if len(hooked) != 2 || !strings.Contains(buf.String(), `"M":"from zap","app":"demo"`) {
+		t.Errorf("GetZapLogger does not return the underlying logger, output %s", buf.String())
+	}
+}
+
+// TestZapLoggerFatalfPanicf tests the formatted fatal and panic logs with the exit replaced.
+func TestZapLoggerFatalfPanicf(t *testing.T) {
+	var buf bytes.Buffer
+	RegisterWriter("fatal_buffer", WriterFactoryFunc(func(name string, dec *Decoder) error {
+		dec.ZapLevel = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+		dec.Core = zapcore.NewCore(newEncoder(dec.OutputConfig), zapcore.AddSync(&buf), dec.ZapLevel)
+		return nil
+	}))
+	cfg := Config{{Writer: "fatal_buffer", Formatter: FormatterJson}}
+	var code int
+	logger := NewZapLogWithCallerSkip(cfg, 1, WithExitFunc(func(c int) { code = c }))
+
+	logger.Fatalf("config %s missing", "db")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if out := buf.String(); !strings.Contains(out, `"M":"config db missing"`) || !strings.Contains(out, `"L":"FATAL"`) {
+		t.Errorf("output %s missing the fatal log", out)
+	}
+
+	defer func() {
+		if r := recover(); r != "state 3 invalid" {
+			t.Errorf("recover() = %v, want the panic message", r)
+		}
+	}()
+	logger.Panicf("state %d invalid", 3)
+}
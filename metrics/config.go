@@ -0,0 +1,75 @@
+package metrics
+
+import "time"
+
+const (
+	// SinkPrometheus serves the metrics in the Prometheus text format over http.
+	SinkPrometheus = "prometheus"
+	// SinkOTLP pushes the metrics to an OpenTelemetry collector by OTLP over http.
+	SinkOTLP = "otlp"
+)
+
+// Config is the metrics config, the namespace and the const labels apply to all the sinks.
+type Config struct {
+	// Namespace is the prefix of the exported metric names, empty means no prefix.
+	Namespace string `yaml:"namespace" mapstructure:"namespace"`
+	// ConstLabels are added to every exported series, like env or region.
+	ConstLabels map[string]string `yaml:"const_labels" mapstructure:"const_labels"`
+	// Sinks are the outputs of the metrics.
+	Sinks []SinkConfig `yaml:"sinks" mapstructure:"sinks"`
+}
+
+// SinkConfig is the config of a sink.
+type SinkConfig struct {
+	// Sink is the registered sink name, such as prometheus.
+	Sink string `yaml:"sink" mapstructure:"sink"`
+
+	// Prometheus is the config of the prometheus sink.
+	Prometheus PrometheusConfig `yaml:"prometheus" mapstructure:"prometheus"`
+	// OTLP is the config of the otlp sink.
+	OTLP OTLPConfig `yaml:"otlp" mapstructure:"otlp"`
+}
+
+// PrometheusConfig is the config of the prometheus sink.
+type PrometheusConfig struct {
+	// Addr is the listen address of the http server, default as :9090.
+	Addr string `yaml:"addr" mapstructure:"addr"`
+	// Path is the path of the metrics, default as /metrics.
+	Path string `yaml:"path" mapstructure:"path"`
+}
+
+func (c *PrometheusConfig) setDefaults() {
+	if c.Addr == "" {
+		c.Addr = ":9090"
+	}
+	if c.Path == "" {
+		c.Path = "/metrics"
+	}
+}
+
+// OTLPConfig is the config of the otlp sink.
+type OTLPConfig struct {
+	// Endpoint is the host:port of the OTLP collector, default as the OTEL_EXPORTER_OTLP_* envs or localhost:4318.
+	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"`
+	// URLPath is the path of the metrics, default as /v1/metrics.
+	URLPath string `yaml:"url_path" mapstructure:"url_path"`
+	// Insecure uses http instead of https.
+	Insecure bool `yaml:"insecure" mapstructure:"insecure"`
+	// Headers are sent with every export, e.g. the auth token.
+	Headers map[string]string `yaml:"headers" mapstructure:"headers"`
+	// Timeout is the timeout of an export, default as 10s.
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// Interval is the interval of the exports, default as 15s.
+	Interval time.Duration `yaml:"interval" mapstructure:"interval"`
+	// ServiceName is the service.name resource attribute.
+	ServiceName string `yaml:"service_name" mapstructure:"service_name"`
+}
+
+func (c *OTLPConfig) setDefaults() {
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.Interval <= 0 {
+		c.Interval = 15 * time.Second
+	}
+}
@@ -0,0 +1,67 @@
+/*
+metrics 指标监控，提供 Counter/Gauge/Histogram/Timer，由可插拔的 Sink 导出（内置 Prometheus /metrics）
+*/
+
+package metrics
+
+import "time"
+
+// Counter is a monotonically increasing value, like the number of requests.
+type Counter interface {
+	// With returns the counter of the label values, bound in the order of the label names.
+	With(labelValues ...string) Counter
+	Inc()
+	// Add adds delta, which must not be negative.
+	Add(delta float64)
+}
+
+// Gauge is a value that goes up and down, like the queue depth.
+type Gauge interface {
+	With(labelValues ...string) Gauge
+	Set(v float64)
+	Add(delta float64)
+	Inc()
+	Dec()
+}
+
+// Histogram samples observations into buckets, like the response size.
+type Histogram interface {
+	With(labelValues ...string) Histogram
+	Observe(v float64)
+}
+
+// Timer is a histogram of durations in seconds, like the request latency.
+type Timer interface {
+	With(labelValues ...string) Timer
+	Observe(d time.Duration)
+	// ObserveSince observes the duration since start, e.g. defer t.ObserveSince(time.Now()).
+	ObserveSince(start time.Time)
+}
+
+var (
+	// DefBuckets are the default histogram buckets, fit for latencies in seconds.
+	DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// DefaultRegistry is the registry of the package level functions and the plugin.
+	DefaultRegistry = NewRegistry()
+)
+
+// NewCounter creates a counter in DefaultRegistry.
+func NewCounter(name, help string, labelNames ...string) Counter {
+	return DefaultRegistry.Counter(name, help, labelNames...)
+}
+
+// NewGauge creates a gauge in DefaultRegistry.
+func NewGauge(name, help string, labelNames ...string) Gauge {
+	return DefaultRegistry.Gauge(name, help, labelNames...)
+}
+
+// NewHistogram creates a histogram in DefaultRegistry, nil buckets as DefBuckets.
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) Histogram {
+	return DefaultRegistry.Histogram(name, help, buckets, labelNames...)
+}
+
+// NewTimer creates a timer in DefaultRegistry.
+func NewTimer(name, help string, labelNames ...string) Timer {
+	return DefaultRegistry.Timer(name, help, labelNames...)
+}
@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// otlpScope is the instrumentation scope of the exported metrics.
+const otlpScope = "github.com/baisiyi/go-kits/metrics"
+
+// OTLPSink pushes the metrics of a registry to an OpenTelemetry collector every interval,
+// as cumulative sums, gauges and histograms. The errors of the exports are reported to
+// the otel error handler, see otel.SetErrorHandler.
+type OTLPSink struct {
+	registry *Registry
+	exporter sdkmetric.Exporter
+	resource *resource.Resource
+	start    time.Time
+	timeout  time.Duration
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewOTLPSink creates the OTLP exporter by cfg and starts pushing the metrics of r.
+func NewOTLPSink(r *Registry, cfg OTLPConfig) (*OTLPSink, error) {
+	cfg.setDefaults()
+	var opts []otlpmetrichttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.URLPath != "" {
+		opts = append(opts, otlpmetrichttp.WithURLPath(cfg.URLPath))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlpmetrichttp.WithHeaders(cfg.Headers))
+	}
+	opts = append(opts, otlpmetrichttp.WithTimeout(cfg.Timeout))
+	exporter, err := otlpmetrichttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: create otlp exporter error: %w", err)
+	}
+	res := resource.Default()
+	if cfg.ServiceName != "" {
+		res, err = resource.Merge(res, resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+		if err != nil {
+			_ = exporter.Shutdown(context.Background())
+			return nil, fmt.Errorf("metrics: otlp resource error: %w", err)
+		}
+	}
+	s := &OTLPSink{
+		registry: r,
+		exporter: exporter,
+		resource: res,
+		start:    time.Now(),
+		timeout:  cfg.Timeout,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.run(cfg.Interval)
+	return s, nil
+}
+
+func newOTLPSink(r *Registry, cfg *SinkConfig) (Sink, error) {
+	return NewOTLPSink(r, cfg.OTLP)
+}
+
+func (s *OTLPSink) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.export(); err != nil {
+				otel.Handle(err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *OTLPSink) export() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	rm := otlpResourceMetrics(s.resource, s.registry.Gather(), s.start, time.Now())
+	return s.exporter.Export(ctx, rm)
+}
+
+// Close stops pushing, exports the metrics for the last time and shuts down the exporter.
+func (s *OTLPSink) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		err := s.export()
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		if shutdownErr := s.exporter.Shutdown(ctx); err == nil {
+			err = shutdownErr
+		}
+		s.closeErr = err
+	})
+	return s.closeErr
+}
+
+// otlpResourceMetrics converts the families into the OTLP metric data, the counters
+// as monotonic cumulative sums since start.
+func otlpResourceMetrics(res *resource.Resource, families []Family, start, now time.Time) *metricdata.ResourceMetrics {
+	ms := make([]metricdata.Metrics, 0, len(families))
+	for _, f := range families {
+		m := metricdata.Metrics{Name: f.Name, Description: f.Help}
+		switch f.Type {
+		case TypeCounter:
+			m.Data = metricdata.Sum[float64]{
+				DataPoints:  otlpDataPoints(f.Series, start, now),
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+			}
+		case TypeGauge:
+			m.Data = metricdata.Gauge[float64]{DataPoints: otlpDataPoints(f.Series, start, now)}
+		case TypeHistogram:
+			m.Data = metricdata.Histogram[float64]{
+				DataPoints:  otlpHistogramDataPoints(f.Series, start, now),
+				Temporality: metricdata.CumulativeTemporality,
+			}
+		default:
+			continue
+		}
+		ms = append(ms, m)
+	}
+	return &metricdata.ResourceMetrics{
+		Resource: res,
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Scope:   instrumentation.Scope{Name: otlpScope},
+			Metrics: ms,
+		}},
+	}
+}
+
+func otlpDataPoints(series []Series, start, now time.Time) []metricdata.DataPoint[float64] {
+	dps := make([]metricdata.DataPoint[float64], 0, len(series))
+	for _, s := range series {
+		dps = append(dps, metricdata.DataPoint[float64]{
+			Attributes: otlpAttributes(s.Labels),
+			StartTime:  start,
+			Time:       now,
+			Value:      s.Value,
+		})
+	}
+	return dps
+}
+
+func otlpHistogramDataPoints(series []Series, start, now time.Time) []metricdata.HistogramDataPoint[float64] {
+	dps := make([]metricdata.HistogramDataPoint[float64], 0, len(series))
+	for _, s := range series {
+		// the buckets of Series are cumulative with the last one +Inf, OTLP counts
+		// each bucket and implies the +Inf bound
+		bounds := make([]float64, 0, len(s.Buckets))
+		counts := make([]uint64, 0, len(s.Buckets))
+		var prev uint64
+		for i, b := range s.Buckets {
+			if i < len(s.Buckets)-1 {
+				bounds = append(bounds, b.UpperBound)
+			}
+			counts = append(counts, b.Count-prev)
+			prev = b.Count
+		}
+		dps = append(dps, metricdata.HistogramDataPoint[float64]{
+			Attributes:   otlpAttributes(s.Labels),
+			StartTime:    start,
+			Time:         now,
+			Count:        s.Count,
+			Bounds:       bounds,
+			BucketCounts: counts,
+			Sum:          s.Sum,
+		})
+	}
+	return dps
+}
+
+func otlpAttributes(labels []Label) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		kvs = append(kvs, attribute.String(l.Name, l.Value))
+	}
+	return attribute.NewSet(kvs...)
+}
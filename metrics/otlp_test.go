@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestOTLPSink tests that the metrics are pushed to the collector when the sink is closed,
+// the counters as monotonic sums and the histograms with the bucket counts not cumulative.
+func TestOTLPSink(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		reqs []*colmetricpb.ExportMetricsServiceRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := &colmetricpb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			t.Errorf("Unmarshal failed: %v", err)
+		}
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer srv.Close()
+
+	r := NewRegistry()
+	r.SetNamespace("app")
+	r.Counter("requests_total", "The requests.", "method").With("GET").Add(3)
+	r.Gauge("inflight", "").Set(2)
+	r.Histogram("size_bytes", "", []float64{10, 100}).Observe(5)
+	r.Histogram("size_bytes", "", []float64{10, 100}).Observe(50)
+
+	s, err := NewOTLPSink(r, OTLPConfig{
+		Endpoint:    strings.TrimPrefix(srv.URL, "http://"),
+		Insecure:    true,
+		Interval:    time.Hour,
+		ServiceName: "demo",
+	})
+	if err != nil {
+		t.Fatalf("NewOTLPSink failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Expected Close idempotent, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reqs) != 1 {
+		t.Fatalf("Expected the metrics exported once on Close, got %d", len(reqs))
+	}
+	rm := reqs[0].ResourceMetrics[0]
+	var service string
+	for _, kv := range rm.Resource.Attributes {
+		if kv.Key == "service.name" {
+			service = kv.Value.GetStringValue()
+		}
+	}
+	if service != "demo" {
+		t.Errorf("service.name = %q", service)
+	}
+	metrics := make(map[string]*metricpb.Metric)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+	sum := metrics["app_requests_total"].GetSum()
+	if sum == nil || !sum.IsMonotonic || sum.AggregationTemporality != metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE ||
+		sum.DataPoints[0].GetAsDouble() != 3 || sum.DataPoints[0].Attributes[0].Value.GetStringValue() != "GET" {
+		t.Errorf("Unexpected counter %v", metrics["app_requests_total"])
+	}
+	if g := metrics["app_inflight"].GetGauge(); g == nil || g.DataPoints[0].GetAsDouble() != 2 {
+		t.Errorf("Unexpected gauge %v", metrics["app_inflight"])
+	}
+	h := metrics["app_size_bytes"].GetHistogram()
+	if h == nil {
+		t.Fatalf("Unexpected histogram %v", metrics["app_size_bytes"])
+	}
+	dp := h.DataPoints[0]
+	if dp.Count != 2 || dp.GetSum() != 55 || len(dp.ExplicitBounds) != 2 ||
+		len(dp.BucketCounts) != 3 || dp.BucketCounts[0] != 1 || dp.BucketCounts[1] != 1 || dp.BucketCounts[2] != 0 {
+		t.Errorf("Unexpected histogram data point %v", dp)
+	}
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "metrics"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the metrics plugin factory registered as metrics-default, which
+// sets up the sinks of DefaultRegistry.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of metrics. Configure it as:
+//
+//	metrics:
+//	  default:
+//	    namespace: myapp
+//	    const_labels:
+//	      env: prod
+//	    sinks:
+//	      - sink: prometheus
+//	        prometheus:
+//	          addr: :9090
+//	          path: /metrics
+type Factory struct {
+	mu    sync.Mutex
+	close func() error
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Validate checks that the sinks of the plugin config are registered.
+func (f *Factory) Validate(dec plugin.Decoder) error {
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	for _, s := range cfg.Sinks {
+		if GetSink(s.Sink) == nil {
+			return fmt.Errorf("metrics: sink %s no registered", s.Sink)
+		}
+	}
+	return nil
+}
+
+// Setup starts the sinks of DefaultRegistry by the plugin config.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// close the old sinks first, the new ones may listen on the same address
+	if f.close != nil {
+		_ = f.close()
+		f.close = nil
+	}
+	closeSinks, err := Setup(DefaultRegistry, cfg)
+	if err != nil {
+		return err
+	}
+	f.close = closeSinks
+	return nil
+}
+
+// Close closes the sinks.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.close == nil {
+		return nil
+	}
+	err := f.close()
+	f.close = nil
+	return err
+}
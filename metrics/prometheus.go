@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contentType is the content type of the Prometheus text format 0.0.4.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler returns the http handler serving the metrics of r in the Prometheus text
+// format, to be mounted on an existing server:
+//
+//	mux.Handle("/metrics", metrics.Handler(metrics.DefaultRegistry))
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_ = WritePrometheus(w, r.Gather())
+	})
+}
+
+// WritePrometheus writes the families in the Prometheus text format.
+func WritePrometheus(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		if f.Help != "" {
+			bw.WriteString("# HELP " + f.Name + " " + escapeHelp(f.Help) + "\n")
+		}
+		bw.WriteString("# TYPE " + f.Name + " " + string(f.Type) + "\n")
+		for _, s := range f.Series {
+			if f.Type != TypeHistogram {
+				writeSample(bw, f.Name, s.Labels, nil, s.Value)
+				continue
+			}
+			for _, b := range s.Buckets {
+				le := &Label{Name: "le", Value: formatFloat(b.UpperBound)}
+				writeSample(bw, f.Name+"_bucket", s.Labels, le, float64(b.Count))
+			}
+			writeSample(bw, f.Name+"_sum", s.Labels, nil, s.Sum)
+			writeSample(bw, f.Name+"_count", s.Labels, nil, float64(s.Count))
+		}
+	}
+	return bw.Flush()
+}
+
+func writeSample(w *bufio.Writer, name string, labels []Label, extra *Label, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extra != nil {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l.Name + `="` + escapeLabelValue(l.Value) + `"`)
+		}
+		if extra != nil {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extra.Name + `="` + extra.Value + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// PrometheusSink serves the metrics of a registry over http for Prometheus to scrape.
+type PrometheusSink struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewPrometheusSink listens on cfg.Addr and serves the metrics of r at cfg.Path.
+func NewPrometheusSink(r *Registry, cfg PrometheusConfig) (*PrometheusSink, error) {
+	cfg.setDefaults()
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, Handler(r))
+	s := &PrometheusSink{
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second},
+		listener: ln,
+	}
+	go func() {
+		_ = s.server.Serve(ln)
+	}()
+	return s, nil
+}
+
+func newPrometheusSink(r *Registry, cfg *SinkConfig) (Sink, error) {
+	return NewPrometheusSink(r, cfg.Prometheus)
+}
+
+// Addr returns the listen address, useful with the port 0.
+func (s *PrometheusSink) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close shuts down the http server.
+func (s *PrometheusSink) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
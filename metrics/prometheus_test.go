@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+// TestWritePrometheus tests the Prometheus text format.
+func TestWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests_total", "The requests.\nBy method.", "method").With(`GE"T`).Add(3)
+	r.Histogram("size_bytes", "", []float64{10}).Observe(5)
+
+	rec := httptest.NewRecorder()
+	Handler(r).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != contentType {
+		t.Errorf("Unexpected content type %s", ct)
+	}
+	want := `# HELP requests_total The requests.\nBy method.
+# TYPE requests_total counter
+requests_total{method="GE\"T"} 3
+# TYPE size_bytes histogram
+size_bytes_bucket{le="10"} 1
+size_bytes_bucket{le="+Inf"} 1
+size_bytes_sum 5
+size_bytes_count 1
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestPluginSetup tests setting up the prometheus sink by the plugin config.
+func TestPluginSetup(t *testing.T) {
+	var cfg plugin.Config
+	err := yaml.Unmarshal([]byte(`
+metrics:
+  default:
+    namespace: app
+    sinks:
+      - sink: prometheus
+        prometheus:
+          addr: 127.0.0.1:0
+          path: /m
+`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closePlugins, err := cfg.SetupClosables()
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	defer closePlugins()
+	defer DefaultRegistry.SetNamespace("")
+
+	NewCounter("plugin_test_total", "").Inc()
+	DefaultFactory.mu.Lock()
+	if DefaultFactory.close == nil {
+		t.Fatal("Expected the sinks started")
+	}
+	DefaultFactory.mu.Unlock()
+
+	s, err := NewPrometheusSink(DefaultRegistry, PrometheusConfig{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rsp, err := http.Get("http://" + s.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	body, _ := io.ReadAll(rsp.Body)
+	if !strings.Contains(string(body), "app_plugin_test_total 1\n") {
+		t.Errorf("Unexpected body %s", body)
+	}
+
+	var bad plugin.Config
+	_ = yaml.Unmarshal([]byte("metrics:\n  default:\n    sinks:\n      - sink: missing\n"), &bad)
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected validate error for the unregistered sink")
+	}
+}
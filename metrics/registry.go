@@ -0,0 +1,346 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Type is the type of a metric family.
+type Type string
+
+const (
+	TypeCounter   Type = "counter"
+	TypeGauge     Type = "gauge"
+	TypeHistogram Type = "histogram"
+)
+
+// Label is a label name and value pair.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Bucket is a cumulative histogram bucket.
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// Series is a snapshot of the metric of a label value set. Value is set for the
+// counters and gauges, Buckets, Count and Sum for the histograms.
+type Series struct {
+	Labels  []Label
+	Value   float64
+	Buckets []Bucket
+	Count   uint64
+	Sum     float64
+}
+
+// Family is a snapshot of the metrics with the same name.
+type Family struct {
+	Name   string
+	Help   string
+	Type   Type
+	Series []Series
+}
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// Registry holds the metrics, which are read by the sinks through Gather.
+type Registry struct {
+	mu          sync.RWMutex
+	families    map[string]*family
+	namespace   string
+	constLabels []Label
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// SetNamespace sets the prefix of the exported metric names, e.g. myapp makes
+// http_requests_total exported as myapp_http_requests_total.
+func (r *Registry) SetNamespace(namespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.namespace = namespace
+}
+
+// SetConstLabels sets the labels added to every exported series, like env or region.
+func (r *Registry) SetConstLabels(labels map[string]string) {
+	constLabels := make([]Label, 0, len(labels))
+	for k, v := range labels {
+		constLabels = append(constLabels, Label{Name: k, Value: v})
+	}
+	sort.Slice(constLabels, func(i, j int) bool { return constLabels[i].Name < constLabels[j].Name })
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.constLabels = constLabels
+}
+
+// Counter creates a counter, or returns the existing one of the name.
+// It panics if the name is invalid or already used by another type.
+func (r *Registry) Counter(name, help string, labelNames ...string) Counter {
+	return counter{r.family(name, help, TypeCounter, nil, labelNames), nil}
+}
+
+// Gauge creates a gauge, or returns the existing one of the name.
+func (r *Registry) Gauge(name, help string, labelNames ...string) Gauge {
+	return gauge{r.family(name, help, TypeGauge, nil, labelNames), nil}
+}
+
+// Histogram creates a histogram, or returns the existing one of the name.
+// nil buckets as DefBuckets.
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	return histogram{r.family(name, help, TypeHistogram, buckets, labelNames), nil}
+}
+
+// Timer creates a timer observing seconds with DefBuckets, or returns the existing one
+// of the name. The name should end with _seconds by the Prometheus convention.
+func (r *Registry) Timer(name, help string, labelNames ...string) Timer {
+	return timer{histogram{r.family(name, help, TypeHistogram, DefBuckets, labelNames), nil}}
+}
+
+func (r *Registry) family(name, help string, typ Type, buckets []float64, labelNames []string) *family {
+	if !namePattern.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	for _, l := range labelNames {
+		if !namePattern.MatchString(l) || strings.Contains(l, ":") || l == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q of %s", l, name))
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		if f.typ != typ || len(f.labelNames) != len(labelNames) {
+			panic(fmt.Sprintf("metrics: %s registered as %s with labels %v", name, f.typ, f.labelNames))
+		}
+		return f
+	}
+	if buckets != nil {
+		buckets = append([]float64(nil), buckets...)
+		sort.Float64s(buckets)
+	}
+	f := &family{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		buckets:    buckets,
+		labelNames: labelNames,
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// Gather returns the snapshot of all the metrics sorted by name, with the namespace
+// and the const labels applied.
+func (r *Registry) Gather() []Family {
+	r.mu.RLock()
+	namespace, constLabels := r.namespace, r.constLabels
+	fs := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		fs = append(fs, f)
+	}
+	r.mu.RUnlock()
+
+	out := make([]Family, 0, len(fs))
+	for _, f := range fs {
+		name := f.name
+		if namespace != "" {
+			name = namespace + "_" + name
+		}
+		out = append(out, Family{Name: name, Help: f.help, Type: f.typ, Series: f.gather(constLabels)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+type family struct {
+	name       string
+	help       string
+	typ        Type
+	buckets    []float64
+	labelNames []string
+
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+
+	value atomic.Uint64 // float64 bits of the counter or gauge
+
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative, the last one is +Inf
+	count  uint64
+	sum    float64
+}
+
+func (s *series) add(delta float64) {
+	for {
+		old := s.value.Load()
+		if s.value.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// get returns the series of the label values, missing values are empty.
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) > len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", f.name, len(f.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+	values := make([]string, len(f.labelNames))
+	copy(values, labelValues)
+	s = &series{labelValues: values}
+	if f.typ == TypeHistogram {
+		s.counts = make([]uint64, len(f.buckets)+1)
+	}
+	f.series[key] = s
+	return s
+}
+
+func (f *family) gather(constLabels []Label) []Series {
+	f.mu.RLock()
+	ss := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		ss = append(ss, s)
+	}
+	f.mu.RUnlock()
+	sort.Slice(ss, func(i, j int) bool {
+		return strings.Join(ss[i].labelValues, "\xff") < strings.Join(ss[j].labelValues, "\xff")
+	})
+
+	out := make([]Series, 0, len(ss))
+	for _, s := range ss {
+		labels := make([]Label, 0, len(constLabels)+len(f.labelNames))
+		labels = append(labels, constLabels...)
+		for i, name := range f.labelNames {
+			labels = append(labels, Label{Name: name, Value: s.labelValues[i]})
+		}
+		if f.typ != TypeHistogram {
+			out = append(out, Series{Labels: labels, Value: math.Float64frombits(s.value.Load())})
+			continue
+		}
+		s.mu.Lock()
+		buckets := make([]Bucket, 0, len(f.buckets)+1)
+		var cum uint64
+		for i, c := range s.counts {
+			cum += c
+			upper := math.Inf(1)
+			if i < len(f.buckets) {
+				upper = f.buckets[i]
+			}
+			buckets = append(buckets, Bucket{UpperBound: upper, Count: cum})
+		}
+		out = append(out, Series{Labels: labels, Buckets: buckets, Count: s.count, Sum: s.sum})
+		s.mu.Unlock()
+	}
+	return out
+}
+
+type counter struct {
+	f      *family
+	values []string
+}
+
+func (c counter) With(labelValues ...string) Counter {
+	return counter{c.f, append(append([]string(nil), c.values...), labelValues...)}
+}
+
+func (c counter) Inc() {
+	c.Add(1)
+}
+
+func (c counter) Add(delta float64) {
+	if delta < 0 {
+		panic(fmt.Sprintf("metrics: counter %s decreased", c.f.name))
+	}
+	c.f.get(c.values).add(delta)
+}
+
+type gauge struct {
+	f      *family
+	values []string
+}
+
+func (g gauge) With(labelValues ...string) Gauge {
+	return gauge{g.f, append(append([]string(nil), g.values...), labelValues...)}
+}
+
+func (g gauge) Set(v float64) {
+	g.f.get(g.values).value.Store(math.Float64bits(v))
+}
+
+func (g gauge) Add(delta float64) {
+	g.f.get(g.values).add(delta)
+}
+
+func (g gauge) Inc() {
+	g.Add(1)
+}
+
+func (g gauge) Dec() {
+	g.Add(-1)
+}
+
+type histogram struct {
+	f      *family
+	values []string
+}
+
+func (h histogram) With(labelValues ...string) Histogram {
+	return histogram{h.f, append(append([]string(nil), h.values...), labelValues...)}
+}
+
+func (h histogram) Observe(v float64) {
+	s := h.f.get(h.values)
+	i := sort.SearchFloat64s(h.f.buckets, v) // the first bucket with upper bound >= v
+	s.mu.Lock()
+	s.counts[i]++
+	s.count++
+	s.sum += v
+	s.mu.Unlock()
+}
+
+type timer struct {
+	h histogram
+}
+
+func (t timer) With(labelValues ...string) Timer {
+	return timer{t.h.With(labelValues...).(histogram)}
+}
+
+func (t timer) Observe(d time.Duration) {
+	t.h.Observe(d.Seconds())
+}
+
+func (t timer) ObserveSince(start time.Time) {
+	t.Observe(time.Since(start))
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func findFamily(t *testing.T, fs []Family, name string) Family {
+	t.Helper()
+	for _, f := range fs {
+		if f.Name == name {
+			return f
+		}
+	}
+	t.Fatalf("Family %s not found in %+v", name, fs)
+	return Family{}
+}
+
+// TestCounterGauge tests the counters and gauges with labels.
+func TestCounterGauge(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("requests_total", "The requests.", "method", "code")
+	c.With("GET", "200").Inc()
+	c.With("GET").With("200").Add(2)
+	c.With("POST", "500").Inc()
+	if r.Counter("requests_total", "", "method", "code") == nil {
+		t.Fatal("Expected the existing counter")
+	}
+
+	g := r.Gauge("queue_depth", "The queue depth.")
+	g.Set(10)
+	g.Inc()
+	g.Dec()
+	g.Add(-2.5)
+
+	fs := r.Gather()
+	if len(fs) != 2 || fs[0].Name != "queue_depth" {
+		t.Fatalf("Unexpected families %+v", fs)
+	}
+	if v := fs[0].Series[0].Value; v != 7.5 {
+		t.Errorf("Expected gauge 7.5, got %v", v)
+	}
+	req := findFamily(t, fs, "requests_total")
+	if req.Type != TypeCounter || len(req.Series) != 2 {
+		t.Fatalf("Unexpected counter %+v", req)
+	}
+	s := req.Series[0]
+	if s.Value != 3 || s.Labels[0] != (Label{"method", "GET"}) || s.Labels[1] != (Label{"code", "200"}) {
+		t.Errorf("Unexpected series %+v", s)
+	}
+}
+
+// TestHistogramTimer tests the buckets of histograms and timers.
+func TestHistogramTimer(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("size_bytes", "", []float64{100, 10})
+	for _, v := range []float64{5, 10, 50, 500} {
+		h.Observe(v)
+	}
+	tm := r.Timer("latency_seconds", "", "route")
+	tm.With("/a").Observe(20 * time.Millisecond)
+	tm.With("/a").ObserveSince(time.Now())
+
+	fs := r.Gather()
+	size := findFamily(t, fs, "size_bytes").Series[0]
+	want := []Bucket{{10, 2}, {100, 3}, {math.Inf(1), 4}}
+	if len(size.Buckets) != len(want) || size.Count != 4 || size.Sum != 565 {
+		t.Fatalf("Unexpected histogram %+v", size)
+	}
+	for i, b := range want {
+		if size.Buckets[i] != b {
+			t.Errorf("Bucket %d = %+v, want %+v", i, size.Buckets[i], b)
+		}
+	}
+	latency := findFamily(t, fs, "latency_seconds")
+	if latency.Type != TypeHistogram || latency.Series[0].Count != 2 || latency.Series[0].Labels[0].Value != "/a" {
+		t.Errorf("Unexpected timer %+v", latency)
+	}
+}
+
+// TestNamespaceConstLabels tests the namespace and const labels applied on Gather.
+func TestNamespaceConstLabels(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("jobs_total", "", "queue").With("default").Inc()
+	r.SetNamespace("app")
+	r.SetConstLabels(map[string]string{"env": "prod"})
+	f := r.Gather()[0]
+	if f.Name != "app_jobs_total" {
+		t.Errorf("Unexpected name %s", f.Name)
+	}
+	if ls := f.Series[0].Labels; len(ls) != 2 || ls[0] != (Label{"env", "prod"}) || ls[1] != (Label{"queue", "default"}) {
+		t.Errorf("Unexpected labels %+v", ls)
+	}
+}
+
+// TestRegistryPanics tests the invalid registrations and usages.
+func TestRegistryPanics(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("ok_total", "", "a")
+	for name, fn := range map[string]func(){
+		"invalid name":  func() { r.Counter("bad-name", "") },
+		"invalid label": func() { r.Gauge("g", "", "le") },
+		"type conflict": func() { r.Gauge("ok_total", "", "a") },
+		"label values":  func() { r.Counter("ok_total", "", "a").With("1", "2").Inc() },
+		"negative add":  func() { r.Counter("ok_total", "", "a").Add(-1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for %s", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
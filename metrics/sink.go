@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Sink exports the metrics of a registry, e.g. serving them over http or pushing
+// them periodically. Close stops exporting.
+type Sink interface {
+	Close() error
+}
+
+// SinkFactory creates a sink of the registry by the config.
+type SinkFactory interface {
+	Setup(r *Registry, cfg *SinkConfig) (Sink, error)
+}
+
+// SinkFactoryFunc is an adapter to allow the use of ordinary functions as SinkFactory.
+type SinkFactoryFunc func(r *Registry, cfg *SinkConfig) (Sink, error)
+
+// Setup calls fn(r, cfg).
+func (fn SinkFactoryFunc) Setup(r *Registry, cfg *SinkConfig) (Sink, error) {
+	return fn(r, cfg)
+}
+
+var (
+	sinkMu sync.RWMutex
+	sinks  = make(map[string]SinkFactory)
+)
+
+func init() {
+	RegisterSink(SinkPrometheus, SinkFactoryFunc(newPrometheusSink))
+	RegisterSink(SinkOTLP, SinkFactoryFunc(newOTLPSink))
+}
+
+// RegisterSink registers a sink factory, e.g. a statsd or OTLP exporter.
+func RegisterSink(name string, factory SinkFactory) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sinks[name] = factory
+}
+
+// GetSink gets a registered sink factory.
+func GetSink(name string) SinkFactory {
+	sinkMu.RLock()
+	f := sinks[name]
+	sinkMu.RUnlock()
+	return f
+}
+
+// Setup applies the namespace and the const labels of cfg to r and starts the sinks.
+// The returned function closes the sinks.
+func Setup(r *Registry, cfg Config) (close func() error, err error) {
+	started := make([]Sink, 0, len(cfg.Sinks))
+	closeAll := func() error {
+		var errs []error
+		for _, s := range started {
+			errs = append(errs, s.Close())
+		}
+		return errors.Join(errs...)
+	}
+	for i := range cfg.Sinks {
+		c := &cfg.Sinks[i]
+		factory := GetSink(c.Sink)
+		if factory == nil {
+			_ = closeAll()
+			return nil, fmt.Errorf("metrics: sink %s no registered", c.Sink)
+		}
+		s, err := factory.Setup(r, c)
+		if err != nil {
+			_ = closeAll()
+			return nil, fmt.Errorf("metrics: sink %s setup fail: %w", c.Sink, err)
+		}
+		started = append(started, s)
+	}
+	r.SetNamespace(cfg.Namespace)
+	r.SetConstLabels(cfg.ConstLabels)
+	return closeAll, nil
+}
@@ -0,0 +1,142 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const (
+	// CommitAfterHandle commits the offset after the handler returns (at least once).
+	CommitAfterHandle = "after"
+	// CommitBeforeHandle commits the offset before the handler runs (at most once).
+	CommitBeforeHandle = "before"
+
+	OffsetEarliest = "earliest"
+	OffsetLatest   = "latest"
+)
+
+// Config is the configuration of a kafka client.
+type Config struct {
+	Brokers  []string       `yaml:"brokers" mapstructure:"brokers"`
+	Producer ProducerConfig `yaml:"producer" mapstructure:"producer"`
+	Consumer ConsumerConfig `yaml:"consumer" mapstructure:"consumer"`
+}
+
+// ProducerConfig is the configuration of the producer.
+type ProducerConfig struct {
+	// Topic is the default topic, messages can override it.
+	Topic string `yaml:"topic" mapstructure:"topic"`
+	// BatchSize is the max number of messages per batch, default as 100.
+	BatchSize int `yaml:"batch_size" mapstructure:"batch_size"`
+	// BatchBytes is the max bytes per batch, default as 1MB.
+	BatchBytes int64 `yaml:"batch_bytes" mapstructure:"batch_bytes"`
+	// BatchTimeout is the max time to wait before a batch is sent, default as 10ms.
+	BatchTimeout time.Duration `yaml:"batch_timeout" mapstructure:"batch_timeout"`
+	// Compression is one of gzip, snappy, lz4, zstd, empty means none.
+	Compression string `yaml:"compression" mapstructure:"compression"`
+	// MaxAttempts is the max number of attempts to deliver a batch, default as 3.
+	MaxAttempts int `yaml:"max_attempts" mapstructure:"max_attempts"`
+	// RequiredAcks is one of none, one, all, default as all.
+	RequiredAcks string `yaml:"required_acks" mapstructure:"required_acks"`
+	// Async makes Send return immediately, results are reported via the delivery callback.
+	Async bool `yaml:"async" mapstructure:"async"`
+	// WriteTimeout is the timeout of write requests, default as 10s.
+	WriteTimeout time.Duration `yaml:"write_timeout" mapstructure:"write_timeout"`
+}
+
+// ConsumerConfig is the configuration of the consumer group.
+type ConsumerConfig struct {
+	GroupID string   `yaml:"group_id" mapstructure:"group_id"`
+	Topics  []string `yaml:"topics" mapstructure:"topics"`
+	// StartOffset is earliest or latest, used when the group has no committed offset.
+	StartOffset string `yaml:"start_offset" mapstructure:"start_offset"`
+	// Commit is the commit strategy, after (default) or before.
+	Commit string `yaml:"commit" mapstructure:"commit"`
+	// CommitInterval flushes commits periodically instead of synchronously when > 0.
+	CommitInterval time.Duration `yaml:"commit_interval" mapstructure:"commit_interval"`
+	// PartitionQueueSize is the buffered messages per partition handler, default as 64.
+	PartitionQueueSize int `yaml:"partition_queue_size" mapstructure:"partition_queue_size"`
+	// HandlerRetries is the retries of a failed handler before the message is skipped.
+	HandlerRetries int `yaml:"handler_retries" mapstructure:"handler_retries"`
+	// RetryBackoff is the wait between handler retries, default as 100ms.
+	RetryBackoff time.Duration `yaml:"retry_backoff" mapstructure:"retry_backoff"`
+	MinBytes     int           `yaml:"min_bytes" mapstructure:"min_bytes"`
+	MaxBytes     int           `yaml:"max_bytes" mapstructure:"max_bytes"`
+	MaxWait      time.Duration `yaml:"max_wait" mapstructure:"max_wait"`
+	// SessionTimeout and RebalanceTimeout tune the group membership.
+	SessionTimeout   time.Duration `yaml:"session_timeout" mapstructure:"session_timeout"`
+	RebalanceTimeout time.Duration `yaml:"rebalance_timeout" mapstructure:"rebalance_timeout"`
+}
+
+func (c *ProducerConfig) setDefaults() {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.BatchBytes <= 0 {
+		c.BatchBytes = 1 << 20
+	}
+	if c.BatchTimeout <= 0 {
+		c.BatchTimeout = 10 * time.Millisecond
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 10 * time.Second
+	}
+}
+
+func (c *ConsumerConfig) setDefaults() {
+	if c.Commit == "" {
+		c.Commit = CommitAfterHandle
+	}
+	if c.PartitionQueueSize <= 0 {
+		c.PartitionQueueSize = 64
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 100 * time.Millisecond
+	}
+}
+
+func parseCompression(s string) (kafkago.Compression, error) {
+	switch s {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafkago.Gzip, nil
+	case "snappy":
+		return kafkago.Snappy, nil
+	case "lz4":
+		return kafkago.Lz4, nil
+	case "zstd":
+		return kafkago.Zstd, nil
+	default:
+		return 0, fmt.Errorf("kafka: compression %s not supported", s)
+	}
+}
+
+func parseRequiredAcks(s string) (kafkago.RequiredAcks, error) {
+	switch s {
+	case "", "all":
+		return kafkago.RequireAll, nil
+	case "one":
+		return kafkago.RequireOne, nil
+	case "none":
+		return kafkago.RequireNone, nil
+	default:
+		return 0, fmt.Errorf("kafka: required_acks %s not supported", s)
+	}
+}
+
+func parseStartOffset(s string) (int64, error) {
+	switch s {
+	case "", OffsetLatest:
+		return kafkago.LastOffset, nil
+	case OffsetEarliest:
+		return kafkago.FirstOffset, nil
+	default:
+		return 0, fmt.Errorf("kafka: start_offset %s not supported", s)
+	}
+}
@@ -0,0 +1,223 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// Handler handles a consumed message. The ctx carries the propagated request context.
+type Handler func(ctx context.Context, msg Message) error
+
+// ConsumerOption is the option of the consumer.
+type ConsumerOption func(*Consumer)
+
+// WithConsumerLogger sets the logger, default as the global logger.
+func WithConsumerLogger(l log.Logger) ConsumerOption {
+	return func(c *Consumer) {
+		c.logger = l
+	}
+}
+
+// reader is the part of kafkago.Reader used by the consumer.
+type reader interface {
+	FetchMessage(ctx context.Context) (Message, error)
+	CommitMessages(ctx context.Context, msgs ...Message) error
+	Stats() kafkago.ReaderStats
+	Close() error
+}
+
+// Consumer runs a consumer group, messages of each partition are handled
+// in order by a dedicated goroutine.
+type Consumer struct {
+	cfg     ConsumerConfig
+	reader  reader
+	handler Handler
+	logger  log.Logger
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+	workers map[string]chan Message // topic/partition => queue
+	wg      sync.WaitGroup
+}
+
+// NewConsumer creates a consumer group runner.
+func NewConsumer(brokers []string, cfg ConsumerConfig, handler Handler, opts ...ConsumerOption) (*Consumer, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("kafka: brokers empty")
+	}
+	if cfg.GroupID == "" || len(cfg.Topics) == 0 {
+		return nil, errors.New("kafka: consumer group_id and topics required")
+	}
+	cfg.setDefaults()
+	if cfg.Commit != CommitAfterHandle && cfg.Commit != CommitBeforeHandle {
+		return nil, fmt.Errorf("kafka: commit strategy %s not supported", cfg.Commit)
+	}
+	startOffset, err := parseStartOffset(cfg.StartOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConsumer(cfg, nil, handler, opts...)
+	c.reader = kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:          brokers,
+		GroupID:          cfg.GroupID,
+		GroupTopics:      cfg.Topics,
+		StartOffset:      startOffset,
+		CommitInterval:   cfg.CommitInterval,
+		MinBytes:         cfg.MinBytes,
+		MaxBytes:         cfg.MaxBytes,
+		MaxWait:          cfg.MaxWait,
+		SessionTimeout:   cfg.SessionTimeout,
+		RebalanceTimeout: cfg.RebalanceTimeout,
+		ErrorLogger:      kafkago.LoggerFunc(c.logger.Errorf),
+	})
+	return c, nil
+}
+
+func newConsumer(cfg ConsumerConfig, r reader, handler Handler, opts ...ConsumerOption) *Consumer {
+	c := &Consumer{
+		cfg:     cfg,
+		reader:  r,
+		handler: handler,
+		logger:  log.GetDefaultLogger(),
+		workers: make(map[string]chan Message),
+		done:    make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Run fetches and dispatches messages until ctx is done or Close is called.
+// On return the in-flight messages have been handled and the reader is closed.
+func (c *Consumer) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+	defer close(c.done)
+	defer cancel()
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				break
+			}
+			c.logger.Errorf("kafka: fetch message error: %v", err)
+			time.Sleep(c.cfg.RetryBackoff)
+			continue
+		}
+		if !c.dispatch(ctx, msg) {
+			break
+		}
+	}
+
+	// 停止拉取后等待各分区处理完已分发的消息，再关闭 reader
+	c.mu.Lock()
+	for key, ch := range c.workers {
+		close(ch)
+		delete(c.workers, key)
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+	return c.reader.Close()
+}
+
+// Close stops the consumer and waits for Run to return.
+func (c *Consumer) Close() error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.mu.Unlock()
+	if cancel == nil {
+		return c.reader.Close()
+	}
+	cancel()
+	<-c.done
+	return nil
+}
+
+// Stats returns the statistics of the consumer since the last call.
+func (c *Consumer) Stats() kafkago.ReaderStats {
+	return c.reader.Stats()
+}
+
+func (c *Consumer) dispatch(ctx context.Context, msg Message) bool {
+	key := fmt.Sprintf("%s/%d", msg.Topic, msg.Partition)
+	c.mu.Lock()
+	ch, ok := c.workers[key]
+	if !ok {
+		ch = make(chan Message, c.cfg.PartitionQueueSize)
+		c.workers[key] = ch
+		c.wg.Add(1)
+		go c.work(ch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case ch <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (c *Consumer) work(ch chan Message) {
+	defer c.wg.Done()
+	for msg := range ch {
+		c.process(msg)
+	}
+}
+
+func (c *Consumer) process(msg Message) {
+	// 处理过程不受 Run 的 ctx 取消影响，保证优雅退出时已分发的消息能处理完
+	ctx := extractHeaders(context.Background(), msg.Headers)
+	if c.cfg.Commit == CommitBeforeHandle {
+		c.commit(ctx, msg)
+	}
+	if err := c.handle(ctx, msg); err != nil {
+		c.logger.Errorf("kafka: handle message %s/%d@%d error, skipped: %v",
+			msg.Topic, msg.Partition, msg.Offset, err)
+	}
+	if c.cfg.Commit == CommitAfterHandle {
+		c.commit(ctx, msg)
+	}
+}
+
+func (c *Consumer) commit(ctx context.Context, msg Message) {
+	if err := c.reader.CommitMessages(ctx, msg); err != nil {
+		c.logger.Errorf("kafka: commit %s/%d@%d error: %v", msg.Topic, msg.Partition, msg.Offset, err)
+	}
+}
+
+func (c *Consumer) handle(ctx context.Context, msg Message) error {
+	for attempt := 0; ; attempt++ {
+		err := c.safeHandle(ctx, msg)
+		if err == nil || attempt >= c.cfg.HandlerRetries {
+			return err
+		}
+		c.logger.Warnf("kafka: handle message %s/%d@%d error, retry %d: %v",
+			msg.Topic, msg.Partition, msg.Offset, attempt+1, err)
+		time.Sleep(c.cfg.RetryBackoff)
+	}
+}
+
+func (c *Consumer) safeHandle(ctx context.Context, msg Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("kafka: handler panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return c.handler(ctx, msg)
+}
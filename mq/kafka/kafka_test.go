@@ -0,0 +1,209 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	"github.com/baisiyi/go-kits/contextkit"
+)
+
+// mockReader feeds the preset messages and records commits.
+type mockReader struct {
+	mu        sync.Mutex
+	msgs      chan Message
+	committed []Message
+	closed    bool
+}
+
+func newMockReader(msgs ...Message) *mockReader {
+	r := &mockReader{msgs: make(chan Message, len(msgs))}
+	for _, m := range msgs {
+		r.msgs <- m
+	}
+	return r
+}
+
+func (r *mockReader) FetchMessage(ctx context.Context) (Message, error) {
+	select {
+	case m := <-r.msgs:
+		return m, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+func (r *mockReader) CommitMessages(_ context.Context, msgs ...Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *mockReader) Stats() kafkago.ReaderStats { return kafkago.ReaderStats{} }
+
+func (r *mockReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+// TestConsumerPartitionOrder tests that messages of one partition are handled in order
+// and all of them are committed before Run returns.
+func TestConsumerPartitionOrder(t *testing.T) {
+	var msgs []Message
+	for i := 0; i < 10; i++ {
+		msgs = append(msgs, Message{Topic: "t", Partition: i % 2, Offset: int64(i)})
+	}
+	r := newMockReader(msgs...)
+
+	var (
+		mu   sync.Mutex
+		seen = map[int][]int64{}
+	)
+	c := newConsumer(ConsumerConfig{PartitionQueueSize: 4}, r, func(ctx context.Context, m Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[m.Partition] = append(seen[m.Partition], m.Offset)
+		return nil
+	})
+	c.cfg.setDefaults()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			r.mu.Lock()
+			n := len(r.committed)
+			r.mu.Unlock()
+			if n == len(msgs) {
+				cancel()
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if !r.closed {
+		t.Error("Expected reader to be closed")
+	}
+	for p, offsets := range seen {
+		for i := 1; i < len(offsets); i++ {
+			if offsets[i] < offsets[i-1] {
+				t.Errorf("Partition %d handled out of order: %v", p, offsets)
+			}
+		}
+	}
+}
+
+// TestConsumerRetryAndPanic tests handler retries and panic recovery.
+func TestConsumerRetryAndPanic(t *testing.T) {
+	r := newMockReader()
+	calls := 0
+	c := newConsumer(ConsumerConfig{HandlerRetries: 2, RetryBackoff: time.Millisecond}, r,
+		func(ctx context.Context, m Message) error {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			if calls == 2 {
+				return errors.New("fail")
+			}
+			return nil
+		})
+	c.cfg.setDefaults()
+
+	c.process(Message{Topic: "t"})
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	if len(r.committed) != 1 {
+		t.Errorf("Expected 1 commit, got %d", len(r.committed))
+	}
+}
+
+// TestConsumerStopsOnEOF tests that Run returns when the reader is closed.
+func TestConsumerStopsOnEOF(t *testing.T) {
+	c := newConsumer(ConsumerConfig{}, eofReader{}, func(context.Context, Message) error { return nil })
+	c.cfg.setDefaults()
+	done := make(chan error)
+	go func() { done <- c.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return on EOF")
+	}
+}
+
+type eofReader struct{}
+
+func (eofReader) FetchMessage(context.Context) (Message, error)    { return Message{}, io.EOF }
+func (eofReader) CommitMessages(context.Context, ...Message) error { return nil }
+func (eofReader) Stats() kafkago.ReaderStats                       { return kafkago.ReaderStats{} }
+func (eofReader) Close() error                                     { return nil }
+
+// TestHeaderPropagation tests propagating the request context through headers.
+func TestHeaderPropagation(t *testing.T) {
+	ctx := contextkit.WithRequestID(context.Background(), "req")
+	headers := injectHeaders(ctx, nil)
+	if len(headers) != 1 {
+		t.Fatalf("Expected 1 header, got %d", len(headers))
+	}
+	if got := contextkit.RequestID(extractHeaders(context.Background(), headers)); got != "req" {
+		t.Errorf("RequestID = %q, want req", got)
+	}
+
+	// the spare capacity of the headers of the caller is not written
+	own := make([]Header, 1, 4)
+	own[0] = Header{Key: "k", Value: []byte("v")}
+	headers = injectHeaders(ctx, own)
+	if len(headers) != 2 || headers[0].Key != "k" {
+		t.Fatalf("Unexpected headers %v", headers)
+	}
+	if spare := own[:2]; spare[1].Key != "" {
+		t.Errorf("Expected the headers of the caller not modified, got %v", spare)
+	}
+}
+
+// TestNewConsumerValidate tests the config validation of NewConsumer.
+func TestNewConsumerValidate(t *testing.T) {
+	h := func(context.Context, Message) error { return nil }
+	if _, err := NewConsumer(nil, ConsumerConfig{GroupID: "g", Topics: []string{"t"}}, h); err == nil {
+		t.Error("Expected error for empty brokers")
+	}
+	if _, err := NewConsumer([]string{"b"}, ConsumerConfig{Topics: []string{"t"}}, h); err == nil {
+		t.Error("Expected error for empty group id")
+	}
+	if _, err := NewConsumer([]string{"b"}, ConsumerConfig{GroupID: "g", Topics: []string{"t"}, Commit: "x"}, h); err == nil {
+		t.Error("Expected error for unknown commit strategy")
+	}
+}
+
+// TestNewProducerValidate tests the config validation of NewProducer.
+func TestNewProducerValidate(t *testing.T) {
+	if _, err := NewProducer(nil, ProducerConfig{}); err == nil {
+		t.Error("Expected error for empty brokers")
+	}
+	if _, err := NewProducer([]string{"b"}, ProducerConfig{Compression: "x"}); err == nil {
+		t.Error("Expected error for unknown compression")
+	}
+	p, err := NewProducer([]string{"b"}, ProducerConfig{Compression: "zstd", RequiredAcks: "one"})
+	if err != nil {
+		t.Fatalf("NewProducer failed: %v", err)
+	}
+	if p.writer.Compression != kafkago.Zstd || p.writer.RequiredAcks != kafkago.RequireOne {
+		t.Errorf("Unexpected writer config: %v %v", p.writer.Compression, p.writer.RequiredAcks)
+	}
+	_ = p.Close()
+}
@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "mq"
+	pluginName = "kafka"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the kafka plugin factory registered as mq-kafka.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of kafka. The config is a map of client name => Config:
+//
+//	mq:
+//	  kafka:
+//	    orders:
+//	      brokers: [127.0.0.1:9092]
+//	      producer:
+//	        topic: orders
+//	      consumer:
+//	        group_id: order-svc
+//	        topics: [orders]
+type Factory struct {
+	mu        sync.RWMutex
+	configs   map[string]Config
+	producers map[string]*Producer
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates the producers of all the clients with a producer topic configured.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfgs map[string]Config
+	if err := dec.Decode(&cfgs); err != nil {
+		return err
+	}
+	producers := make(map[string]*Producer)
+	for client, cfg := range cfgs {
+		if cfg.Producer.Topic == "" {
+			continue
+		}
+		p, err := NewProducer(cfg.Brokers, cfg.Producer)
+		if err != nil {
+			for _, created := range producers {
+				_ = created.Close()
+			}
+			return fmt.Errorf("kafka client %s: %w", client, err)
+		}
+		producers[client] = p
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.configs = cfgs
+	f.producers = producers
+	return nil
+}
+
+// Close flushes and closes all the producers.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var errs []error
+	for client, p := range f.producers {
+		if err := p.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("kafka client %s: %w", client, err))
+		}
+	}
+	f.producers = nil
+	return errors.Join(errs...)
+}
+
+// GetProducer returns the producer of the configured client, nil if not found.
+func GetProducer(client string) *Producer {
+	DefaultFactory.mu.RLock()
+	defer DefaultFactory.mu.RUnlock()
+	return DefaultFactory.producers[client]
+}
+
+// NewClientConsumer creates a consumer by the config of the configured client.
+func NewClientConsumer(client string, handler Handler, opts ...ConsumerOption) (*Consumer, error) {
+	DefaultFactory.mu.RLock()
+	cfg, ok := DefaultFactory.configs[client]
+	DefaultFactory.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("kafka client %s not configured", client)
+	}
+	return NewConsumer(cfg.Brokers, cfg.Consumer, handler, opts...)
+}
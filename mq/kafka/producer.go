@@ -0,0 +1,141 @@
+/*
+kafka 生产者与消费组封装，基于 segmentio/kafka-go
+*/
+
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	"github.com/baisiyi/go-kits/contextkit"
+	"github.com/baisiyi/go-kits/log"
+)
+
+// Message is the kafka message.
+type Message = kafkago.Message
+
+// Header is the kafka message header.
+type Header = kafkago.Header
+
+// DeliveryCallback is called with the result of every delivered batch.
+type DeliveryCallback func(msgs []Message, err error)
+
+// ProducerOption is the option of the producer.
+type ProducerOption func(*Producer)
+
+// WithDeliveryCallback sets the callback invoked after each batch is delivered or failed.
+func WithDeliveryCallback(cb DeliveryCallback) ProducerOption {
+	return func(p *Producer) {
+		p.callback = cb
+	}
+}
+
+// WithProducerLogger sets the logger, default as the global logger.
+func WithProducerLogger(l log.Logger) ProducerOption {
+	return func(p *Producer) {
+		p.logger = l
+	}
+}
+
+// Producer sends messages with batching, compression and retries.
+type Producer struct {
+	writer   *kafkago.Writer
+	callback DeliveryCallback
+	logger   log.Logger
+}
+
+// NewProducer creates a producer.
+func NewProducer(brokers []string, cfg ProducerConfig, opts ...ProducerOption) (*Producer, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("kafka: brokers empty")
+	}
+	cfg.setDefaults()
+	compression, err := parseCompression(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+	acks, err := parseRequiredAcks(cfg.RequiredAcks)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Producer{logger: log.GetDefaultLogger()}
+	for _, o := range opts {
+		o(p)
+	}
+	p.writer = &kafkago.Writer{
+		Addr:         kafkago.TCP(brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafkago.Hash{},
+		MaxAttempts:  cfg.MaxAttempts,
+		BatchSize:    cfg.BatchSize,
+		BatchBytes:   cfg.BatchBytes,
+		BatchTimeout: cfg.BatchTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		RequiredAcks: acks,
+		Async:        cfg.Async,
+		Compression:  compression,
+		Completion:   p.complete,
+		ErrorLogger:  kafkago.LoggerFunc(p.logger.Errorf),
+	}
+	return p, nil
+}
+
+func (p *Producer) complete(msgs []Message, err error) {
+	if err != nil {
+		p.logger.Errorf("kafka: deliver %d messages error: %v", len(msgs), err)
+	}
+	if p.callback != nil {
+		p.callback(msgs, err)
+	}
+}
+
+// Send sends the messages, the request context (request id, trace id ...) is
+// propagated in the message headers. The messages and the headers of the caller
+// are not modified.
+func (p *Producer) Send(ctx context.Context, msgs ...Message) error {
+	sent := make([]Message, len(msgs))
+	for i, msg := range msgs {
+		msg.Headers = injectHeaders(ctx, msg.Headers)
+		sent[i] = msg
+	}
+	return p.writer.WriteMessages(ctx, sent...)
+}
+
+// Stats returns the statistics of the producer since the last call.
+func (p *Producer) Stats() kafkago.WriterStats {
+	return p.writer.Stats()
+}
+
+// Close flushes the pending messages and closes the producer.
+func (p *Producer) Close() error {
+	return p.writer.Close()
+}
+
+// injectHeaders returns a copy of the message headers with the context values appended,
+// headers is not modified even if it has spare capacity.
+func injectHeaders(ctx context.Context, headers []Header) []Header {
+	md := make(map[string][]string)
+	contextkit.InjectMetadata(ctx, md)
+	if len(md) == 0 {
+		return headers
+	}
+	out := make([]Header, len(headers), len(headers)+len(md))
+	copy(out, headers)
+	for k, v := range md {
+		out = append(out, Header{Key: k, Value: []byte(v[0])})
+	}
+	return out
+}
+
+// extractHeaders returns ctx carrying the context values in the message headers.
+func extractHeaders(ctx context.Context, headers []Header) context.Context {
+	md := make(map[string][]string, len(headers))
+	for _, h := range headers {
+		md[h.Key] = []string{string(h.Value)}
+	}
+	return contextkit.ExtractMetadata(ctx, md)
+}
@@ -0,0 +1,151 @@
+/*
+nats NATS 客户端封装，支持 core 订阅和 JetStream 持久化消费
+*/
+
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/baisiyi/go-kits/contextkit"
+	"github.com/baisiyi/go-kits/log"
+)
+
+// Client owns a nats connection and its jetstream context.
+type Client struct {
+	cfg    Config
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	codec  Codec
+	logger log.Logger
+}
+
+// NewClient connects to the server and creates the configured streams.
+func NewClient(cfg Config, logger log.Logger) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("nats: url empty")
+	}
+	cfg.setDefaults()
+	if logger == nil {
+		logger = log.GetDefaultLogger()
+	}
+	codec := GetCodec(cfg.Codec)
+	if codec == nil {
+		return nil, fmt.Errorf("nats: codec %s not registered", cfg.Codec)
+	}
+
+	opts := []nats.Option{
+		nats.Name(cfg.Name),
+		nats.Timeout(cfg.Timeout),
+		nats.ReconnectWait(cfg.ReconnectWait),
+		nats.MaxReconnects(*cfg.MaxReconnects),
+		nats.DrainTimeout(cfg.DrainTimeout),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Warnf("nats: disconnected: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			logger.Infof("nats: reconnected to %s", nc.ConnectedUrl())
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, sub *nats.Subscription, err error) {
+			if sub != nil {
+				logger.Errorf("nats: subscription %s error: %v", sub.Subject, err)
+				return
+			}
+			logger.Errorf("nats: async error: %v", err)
+		}),
+	}
+	if cfg.User != "" {
+		opts = append(opts, nats.UserInfo(cfg.User, cfg.Password))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, nats.Token(cfg.Token))
+	}
+
+	nc, err := nats.Connect(cfg.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("nats: connect error: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("nats: jetstream error: %w", err)
+	}
+	c := &Client{cfg: cfg, nc: nc, js: js, codec: codec, logger: logger}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, s := range cfg.Streams {
+		sc, err := s.toJetStream()
+		if err == nil {
+			_, err = js.CreateOrUpdateStream(ctx, sc)
+		}
+		if err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("nats: create stream %s error: %w", s.Name, err)
+		}
+	}
+	return c, nil
+}
+
+// Conn returns the underlying connection.
+func (c *Client) Conn() *nats.Conn {
+	return c.nc
+}
+
+// JetStream returns the underlying jetstream context.
+func (c *Client) JetStream() jetstream.JetStream {
+	return c.js
+}
+
+// Stats returns the statistics of the connection.
+func (c *Client) Stats() nats.Statistics {
+	return c.nc.Stats()
+}
+
+// Close drains the subscriptions and closes the connection.
+func (c *Client) Close() error {
+	if c.nc.IsClosed() {
+		return nil
+	}
+	closed := make(chan struct{})
+	c.nc.SetClosedHandler(func(*nats.Conn) { close(closed) })
+	if err := c.nc.Drain(); err != nil {
+		c.nc.Close()
+		return err
+	}
+	select {
+	case <-closed:
+	case <-time.After(c.cfg.DrainTimeout + time.Second):
+		c.nc.Close()
+	}
+	return nil
+}
+
+// newMsg builds a message carrying the request context in the headers.
+func newMsg(ctx context.Context, subject string, data []byte) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	md := make(map[string][]string)
+	contextkit.InjectMetadata(ctx, md)
+	for k, v := range md {
+		msg.Header.Set(k, v[0])
+	}
+	return msg
+}
+
+// msgContext returns a context carrying the request context in the headers.
+func msgContext(h nats.Header) context.Context {
+	md := make(map[string][]string, len(h))
+	for k, v := range h {
+		md[k] = v
+	}
+	return contextkit.ExtractMetadata(context.Background(), md)
+}
@@ -0,0 +1,39 @@
+package nats
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// CodecJSON is the name of the json codec.
+const CodecJSON = "json"
+
+// Codec marshals and unmarshals the typed messages.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+var (
+	codecMu sync.RWMutex
+	codecs  = map[string]Codec{CodecJSON: jsonCodec{}}
+)
+
+// RegisterCodec registers a codec, e.g. protobuf or msgpack, selectable by Config.Codec.
+func RegisterCodec(name string, c Codec) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	codecs[name] = c
+}
+
+// GetCodec gets a registered codec.
+func GetCodec(name string) Codec {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	return codecs[name]
+}
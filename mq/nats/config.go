@@ -0,0 +1,101 @@
+package nats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Config is the configuration of a nats client.
+type Config struct {
+	// URL is the server url, multiple servers are separated by comma.
+	URL      string `yaml:"url" mapstructure:"url"`
+	Name     string `yaml:"name" mapstructure:"name"`
+	User     string `yaml:"user" mapstructure:"user"`
+	Password string `yaml:"password" mapstructure:"password"`
+	Token    string `yaml:"token" mapstructure:"token"`
+	// Codec is the codec of the typed helpers, default as json.
+	Codec string `yaml:"codec" mapstructure:"codec"`
+	// Timeout is the dial timeout, default as 2s.
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// ReconnectWait is the wait between reconnect attempts, default as 2s.
+	ReconnectWait time.Duration `yaml:"reconnect_wait" mapstructure:"reconnect_wait"`
+	// MaxReconnects is the max reconnect attempts, default as -1 which means forever.
+	MaxReconnects *int `yaml:"max_reconnects" mapstructure:"max_reconnects"`
+	// DrainTimeout is the max wait of draining on Close, default as 30s.
+	DrainTimeout time.Duration `yaml:"drain_timeout" mapstructure:"drain_timeout"`
+	// Streams are the jetstream streams created or updated on setup.
+	Streams []StreamConfig `yaml:"streams" mapstructure:"streams"`
+	// Consumers are the durable jetstream consumers by name.
+	Consumers map[string]ConsumerConfig `yaml:"consumers" mapstructure:"consumers"`
+}
+
+// StreamConfig declares a jetstream stream.
+type StreamConfig struct {
+	Name     string        `yaml:"name" mapstructure:"name"`
+	Subjects []string      `yaml:"subjects" mapstructure:"subjects"`
+	MaxAge   time.Duration `yaml:"max_age" mapstructure:"max_age"`
+	// Storage is file or memory, default as file.
+	Storage  string `yaml:"storage" mapstructure:"storage"`
+	Replicas int    `yaml:"replicas" mapstructure:"replicas"`
+}
+
+// ConsumerConfig declares a durable jetstream consumer.
+type ConsumerConfig struct {
+	Stream        string        `yaml:"stream" mapstructure:"stream"`
+	Durable       string        `yaml:"durable" mapstructure:"durable"`
+	FilterSubject string        `yaml:"filter_subject" mapstructure:"filter_subject"`
+	AckWait       time.Duration `yaml:"ack_wait" mapstructure:"ack_wait"`
+	// MaxDeliver is the max deliveries of a message before it is dropped.
+	MaxDeliver    int `yaml:"max_deliver" mapstructure:"max_deliver"`
+	MaxAckPending int `yaml:"max_ack_pending" mapstructure:"max_ack_pending"`
+}
+
+func (c *Config) setDefaults() {
+	if c.Codec == "" {
+		c.Codec = CodecJSON
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 2 * time.Second
+	}
+	if c.ReconnectWait <= 0 {
+		c.ReconnectWait = 2 * time.Second
+	}
+	if c.MaxReconnects == nil {
+		forever := -1
+		c.MaxReconnects = &forever
+	}
+	if c.DrainTimeout <= 0 {
+		c.DrainTimeout = 30 * time.Second
+	}
+}
+
+func (s *StreamConfig) toJetStream() (jetstream.StreamConfig, error) {
+	cfg := jetstream.StreamConfig{
+		Name:     s.Name,
+		Subjects: s.Subjects,
+		MaxAge:   s.MaxAge,
+		Replicas: s.Replicas,
+	}
+	switch s.Storage {
+	case "", "file":
+		cfg.Storage = jetstream.FileStorage
+	case "memory":
+		cfg.Storage = jetstream.MemoryStorage
+	default:
+		return cfg, fmt.Errorf("nats: stream %s storage %s not supported", s.Name, s.Storage)
+	}
+	return cfg, nil
+}
+
+func (c *ConsumerConfig) toJetStream() jetstream.ConsumerConfig {
+	return jetstream.ConsumerConfig{
+		Durable:       c.Durable,
+		FilterSubject: c.FilterSubject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       c.AckWait,
+		MaxDeliver:    c.MaxDeliver,
+		MaxAckPending: c.MaxAckPending,
+	}
+}
@@ -0,0 +1,108 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/baisiyi/go-kits/contextkit"
+	"github.com/baisiyi/go-kits/log"
+)
+
+// mockMsg is a mock jetstream message recording the ack calls.
+type mockMsg struct {
+	data    []byte
+	headers nats.Header
+	acked   bool
+	naked   bool
+	termed  bool
+}
+
+func (m *mockMsg) Data() []byte         { return m.data }
+func (m *mockMsg) Headers() nats.Header { return m.headers }
+func (m *mockMsg) Subject() string      { return "subject" }
+func (m *mockMsg) Ack() error           { m.acked = true; return nil }
+func (m *mockMsg) Nak() error           { m.naked = true; return nil }
+func (m *mockMsg) Term() error          { m.termed = true; return nil }
+
+type order struct {
+	ID int `json:"id"`
+}
+
+func newTestClient() *Client {
+	return &Client{codec: GetCodec(CodecJSON), logger: log.GetDefaultLogger()}
+}
+
+// TestHandleJSMsg tests the ack, nak and term decisions.
+func TestHandleJSMsg(t *testing.T) {
+	c := newTestClient()
+	ctx := contextkit.WithRequestID(context.Background(), "req")
+	msg := newMsg(ctx, "subject", []byte(`{"id":1}`))
+
+	var (
+		got    order
+		gotReq string
+	)
+	ok := &mockMsg{data: msg.Data, headers: msg.Header}
+	handleJSMsg(c, ok, func(ctx context.Context, v order) error {
+		got = v
+		gotReq = contextkit.RequestID(ctx)
+		return nil
+	})
+	if !ok.acked || got.ID != 1 || gotReq != "req" {
+		t.Errorf("Expected acked message with id 1 and request id, got %+v %v %q", ok, got, gotReq)
+	}
+
+	failed := &mockMsg{data: []byte(`{"id":2}`)}
+	handleJSMsg(c, failed, func(ctx context.Context, v order) error { return errors.New("fail") })
+	if !failed.naked || failed.acked {
+		t.Errorf("Expected naked message, got %+v", failed)
+	}
+
+	panicked := &mockMsg{data: []byte(`{"id":3}`)}
+	handleJSMsg(c, panicked, func(ctx context.Context, v order) error { panic("boom") })
+	if !panicked.naked {
+		t.Errorf("Expected naked message on panic, got %+v", panicked)
+	}
+
+	bad := &mockMsg{data: []byte(`not json`)}
+	handleJSMsg(c, bad, func(ctx context.Context, v order) error { return nil })
+	if !bad.termed {
+		t.Errorf("Expected terminated message, got %+v", bad)
+	}
+}
+
+// TestStreamConfig tests the conversion of the stream config.
+func TestStreamConfig(t *testing.T) {
+	sc, err := (&StreamConfig{Name: "s", Storage: "memory"}).toJetStream()
+	if err != nil {
+		t.Fatalf("toJetStream failed: %v", err)
+	}
+	if sc.Storage != jetstream.MemoryStorage {
+		t.Errorf("Storage = %v, want memory", sc.Storage)
+	}
+	if _, err := (&StreamConfig{Name: "s", Storage: "disk"}).toJetStream(); err == nil {
+		t.Error("Expected error for unknown storage")
+	}
+}
+
+// TestRegisterCodec tests registering a custom codec.
+func TestRegisterCodec(t *testing.T) {
+	RegisterCodec("custom", jsonCodec{})
+	if GetCodec("custom") == nil {
+		t.Error("Expected registered codec")
+	}
+	if _, err := NewClient(Config{URL: "nats://127.0.0.1:1", Codec: "unknown"}, nil); err == nil {
+		t.Error("Expected error for unregistered codec")
+	}
+}
+
+// TestNewClientValidate tests the config validation of NewClient.
+func TestNewClientValidate(t *testing.T) {
+	if _, err := NewClient(Config{}, nil); err == nil {
+		t.Error("Expected error for empty url")
+	}
+}
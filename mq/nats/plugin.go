@@ -0,0 +1,77 @@
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "mq"
+	pluginName = "nats"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the nats plugin factory registered as mq-nats.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of nats. The config is a map of client name => Config.
+type Factory struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup connects all the configured clients.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfgs map[string]Config
+	if err := dec.Decode(&cfgs); err != nil {
+		return err
+	}
+	clients := make(map[string]*Client, len(cfgs))
+	for client, cfg := range cfgs {
+		c, err := NewClient(cfg, nil)
+		if err != nil {
+			for _, created := range clients {
+				_ = created.Close()
+			}
+			return fmt.Errorf("nats client %s: %w", client, err)
+		}
+		clients[client] = c
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clients = clients
+	return nil
+}
+
+// Close drains and closes all the clients.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var errs []error
+	for client, c := range f.clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("nats client %s: %w", client, err))
+		}
+	}
+	f.clients = nil
+	return errors.Join(errs...)
+}
+
+// GetClient returns the configured client, nil if not found.
+func GetClient(client string) *Client {
+	DefaultFactory.mu.RLock()
+	defer DefaultFactory.mu.RUnlock()
+	return DefaultFactory.clients[client]
+}
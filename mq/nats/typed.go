@@ -0,0 +1,106 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Handler handles a decoded message. The ctx carries the propagated request context.
+type Handler[T any] func(ctx context.Context, v T) error
+
+// Publish encodes v with the client codec and publishes it on the core subject.
+func Publish[T any](ctx context.Context, c *Client, subject string, v T) error {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("nats: marshal error: %w", err)
+	}
+	return c.nc.PublishMsg(newMsg(ctx, subject, data))
+}
+
+// Subscribe subscribes the core subject, queue is the optional queue group.
+// Decode errors and handler errors are logged since core nats has no redelivery.
+func Subscribe[T any](c *Client, subject, queue string, handler Handler[T]) (*nats.Subscription, error) {
+	cb := func(msg *nats.Msg) {
+		var v T
+		if err := c.codec.Unmarshal(msg.Data, &v); err != nil {
+			c.logger.Errorf("nats: decode message of %s error: %v", msg.Subject, err)
+			return
+		}
+		if err := safeHandle(msgContext(msg.Header), handler, v); err != nil {
+			c.logger.Errorf("nats: handle message of %s error: %v", msg.Subject, err)
+		}
+	}
+	if queue != "" {
+		return c.nc.QueueSubscribe(subject, queue, cb)
+	}
+	return c.nc.Subscribe(subject, cb)
+}
+
+// JSPublish encodes v and publishes it to jetstream, returning after the stream acks.
+func JSPublish[T any](ctx context.Context, c *Client, subject string, v T) (*jetstream.PubAck, error) {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("nats: marshal error: %w", err)
+	}
+	return c.js.PublishMsg(ctx, newMsg(ctx, subject, data))
+}
+
+// Consume starts the durable consumer of the given name in Config.Consumers.
+// A message is acked when handler returns nil, naked for redelivery on error
+// and terminated when it cannot be decoded. Stop it with Drain on the returned context.
+func Consume[T any](ctx context.Context, c *Client, name string, handler Handler[T]) (jetstream.ConsumeContext, error) {
+	cfg, ok := c.cfg.Consumers[name]
+	if !ok {
+		return nil, fmt.Errorf("nats: consumer %s not configured", name)
+	}
+	if cfg.Durable == "" {
+		cfg.Durable = name
+	}
+	consumer, err := c.js.CreateOrUpdateConsumer(ctx, cfg.Stream, cfg.toJetStream())
+	if err != nil {
+		return nil, fmt.Errorf("nats: create consumer %s error: %w", name, err)
+	}
+	return consumer.Consume(func(msg jetstream.Msg) {
+		handleJSMsg(c, msg, handler)
+	})
+}
+
+// jsMsg is the part of jetstream.Msg used by handleJSMsg.
+type jsMsg interface {
+	Data() []byte
+	Headers() nats.Header
+	Subject() string
+	Ack() error
+	Nak() error
+	Term() error
+}
+
+func handleJSMsg[T any](c *Client, msg jsMsg, handler Handler[T]) {
+	var v T
+	if err := c.codec.Unmarshal(msg.Data(), &v); err != nil {
+		c.logger.Errorf("nats: decode message of %s error, terminated: %v", msg.Subject(), err)
+		_ = msg.Term()
+		return
+	}
+	if err := safeHandle(msgContext(msg.Headers()), handler, v); err != nil {
+		c.logger.Errorf("nats: handle message of %s error, redelivering: %v", msg.Subject(), err)
+		_ = msg.Nak()
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		c.logger.Errorf("nats: ack message of %s error: %v", msg.Subject(), err)
+	}
+}
+
+func safeHandle[T any](ctx context.Context, handler Handler[T], v T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("nats: handler panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return handler(ctx, v)
+}
@@ -0,0 +1,200 @@
+/*
+rabbit RabbitMQ 封装，负责连接恢复、拓扑声明、确认发布和消费者并发处理
+*/
+
+package rabbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// ErrClosed is returned when the client has been closed.
+var ErrClosed = errors.New("rabbit: client closed")
+
+// Client owns the amqp connection and recovers it when lost.
+type Client struct {
+	cfg    Config
+	logger log.Logger
+
+	mu      sync.RWMutex
+	conn    *amqp.Connection
+	ready   chan struct{} // closed when conn is usable
+	closed  bool
+	closing chan struct{}
+	done    chan struct{}
+}
+
+// NewClient dials the broker, declares the topology and starts the recovery loop.
+func NewClient(cfg Config, logger log.Logger) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("rabbit: url empty")
+	}
+	cfg.setDefaults()
+	if logger == nil {
+		logger = log.GetDefaultLogger()
+	}
+	c := &Client{
+		cfg:     cfg,
+		logger:  logger,
+		ready:   make(chan struct{}),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	c.setConn(conn)
+	go c.watch(conn)
+	return c, nil
+}
+
+func (c *Client) connect() (*amqp.Connection, error) {
+	conn, err := amqp.Dial(c.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("rabbit: dial error: %w", err)
+	}
+	if err := c.declare(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// declare declares the exchanges, queues and bindings of the config.
+func (c *Client) declare(conn *amqp.Connection) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("rabbit: open channel error: %w", err)
+	}
+	defer ch.Close()
+	for _, e := range c.cfg.Exchanges {
+		kind := e.Kind
+		if kind == "" {
+			kind = amqp.ExchangeDirect
+		}
+		if err := ch.ExchangeDeclare(e.Name, kind, e.Durable, e.AutoDelete, e.Internal, false, amqp.Table(e.Args)); err != nil {
+			return fmt.Errorf("rabbit: declare exchange %s error: %w", e.Name, err)
+		}
+	}
+	for _, q := range c.cfg.Queues {
+		if _, err := ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, false, q.args()); err != nil {
+			return fmt.Errorf("rabbit: declare queue %s error: %w", q.Name, err)
+		}
+	}
+	for _, b := range c.cfg.Bindings {
+		if err := ch.QueueBind(b.Queue, b.RoutingKey, b.Exchange, false, nil); err != nil {
+			return fmt.Errorf("rabbit: bind queue %s to %s error: %w", b.Queue, b.Exchange, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) setConn(conn *amqp.Connection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn = conn
+	close(c.ready)
+}
+
+// watch reconnects when the connection is lost until the client is closed.
+func (c *Client) watch(conn *amqp.Connection) {
+	defer close(c.done)
+	for {
+		select {
+		case <-c.closing:
+			return
+		case err, ok := <-conn.NotifyClose(make(chan *amqp.Error, 1)):
+			if !ok && c.isClosed() {
+				return
+			}
+			c.logger.Warnf("rabbit: connection lost: %v, reconnecting", err)
+		}
+
+		c.mu.Lock()
+		c.ready = make(chan struct{})
+		c.mu.Unlock()
+
+		for {
+			newConn, err := c.connect()
+			if err == nil {
+				conn = newConn
+				c.setConn(conn)
+				c.logger.Infof("rabbit: reconnected")
+				break
+			}
+			c.logger.Errorf("rabbit: reconnect error: %v", err)
+			select {
+			case <-c.closing:
+				return
+			case <-time.After(c.cfg.ReconnectDelay):
+			}
+		}
+	}
+}
+
+// Channel opens a channel, waiting for the connection to be recovered if necessary.
+func (c *Client) Channel(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		c.mu.RLock()
+		closed, ready, conn := c.closed, c.ready, c.conn
+		c.mu.RUnlock()
+		if closed {
+			return nil, ErrClosed
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.closing:
+			return nil, ErrClosed
+		}
+		ch, err := conn.Channel()
+		if err == nil {
+			return ch, nil
+		}
+		if !errors.Is(err, amqp.ErrClosed) {
+			return nil, err
+		}
+		// 连接已断开，等待 watch 重连
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.cfg.ReconnectDelay / 4):
+		}
+	}
+}
+
+func (c *Client) isClosed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.closed
+}
+
+// Close closes the connection and stops the recovery loop.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.closing)
+	conn := c.conn
+	c.mu.Unlock()
+
+	var err error
+	if conn != nil && !conn.IsClosed() {
+		err = conn.Close()
+	}
+	<-c.done
+	return err
+}
@@ -0,0 +1,159 @@
+package rabbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/baisiyi/go-kits/contextkit"
+	"github.com/baisiyi/go-kits/log"
+)
+
+// Delivery is the consumed message.
+type Delivery = amqp.Delivery
+
+// Handler handles a delivery. The ctx carries the propagated request context.
+// Returning nil acks the message, otherwise it is retried and finally
+// dead-lettered (or requeued if configured).
+type Handler func(ctx context.Context, d Delivery) error
+
+// Consumer consumes a queue with a pool of workers.
+type Consumer struct {
+	client  *Client
+	cfg     ConsumerConfig
+	handler Handler
+	logger  log.Logger
+	tag     string
+}
+
+// NewConsumer creates a consumer of the client.
+func (c *Client) NewConsumer(cfg ConsumerConfig, handler Handler) (*Consumer, error) {
+	if cfg.Queue == "" {
+		return nil, errors.New("rabbit: consumer queue empty")
+	}
+	cfg.setDefaults()
+	return &Consumer{
+		client:  c,
+		cfg:     cfg,
+		handler: handler,
+		logger:  c.logger,
+		tag:     "go-kits-" + contextkit.NewULID(),
+	}, nil
+}
+
+// NewNamedConsumer creates a consumer by the consumer config of the given name.
+func (c *Client) NewNamedConsumer(name string, handler Handler) (*Consumer, error) {
+	cfg, ok := c.cfg.Consumers[name]
+	if !ok {
+		return nil, fmt.Errorf("rabbit: consumer %s not configured", name)
+	}
+	return c.NewConsumer(cfg, handler)
+}
+
+// Run consumes until ctx is done, re-subscribing when the channel is lost.
+// On return the in-flight deliveries have been handled (drained).
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		err := c.consume(ctx)
+		if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+			return nil
+		}
+		c.logger.Warnf("rabbit: consumer %s interrupted: %v, resubscribing", c.cfg.Queue, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.client.cfg.ReconnectDelay):
+		}
+	}
+}
+
+func (c *Consumer) consume(ctx context.Context) error {
+	ch, err := c.client.Channel(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	if err := ch.Qos(c.cfg.Prefetch, 0, false); err != nil {
+		return fmt.Errorf("rabbit: qos error: %w", err)
+	}
+	deliveries, err := ch.Consume(c.cfg.Queue, c.tag, false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("rabbit: consume %s error: %w", c.cfg.Queue, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range deliveries {
+				c.process(d)
+			}
+		}()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return errors.New("deliveries closed")
+	case <-ctx.Done():
+		// 取消订阅后 broker 不再投递，deliveries 被关闭，等待 worker 处理完已收到的消息
+		if err := ch.Cancel(c.tag, false); err != nil {
+			c.logger.Errorf("rabbit: cancel consumer %s error: %v", c.cfg.Queue, err)
+		}
+		<-stopped
+		return ctx.Err()
+	}
+}
+
+func (c *Consumer) process(d Delivery) {
+	ctx := extractHeaders(context.Background(), d.Headers)
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = c.safeHandle(ctx, d); err == nil || attempt >= c.cfg.Retries {
+			break
+		}
+		c.logger.Warnf("rabbit: handle message of %s error, retry %d: %v", c.cfg.Queue, attempt+1, err)
+		time.Sleep(c.cfg.RetryBackoff)
+	}
+	if err == nil {
+		if ackErr := d.Ack(false); ackErr != nil {
+			c.logger.Errorf("rabbit: ack message of %s error: %v", c.cfg.Queue, ackErr)
+		}
+		return
+	}
+	c.logger.Errorf("rabbit: handle message of %s error, requeue=%v: %v", c.cfg.Queue, c.cfg.Requeue, err)
+	// requeue=false 时消息进入队列配置的死信交换机
+	if nackErr := d.Nack(false, c.cfg.Requeue); nackErr != nil {
+		c.logger.Errorf("rabbit: nack message of %s error: %v", c.cfg.Queue, nackErr)
+	}
+}
+
+func (c *Consumer) safeHandle(ctx context.Context, d Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rabbit: handler panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return c.handler(ctx, d)
+}
+
+// extractHeaders returns ctx carrying the context values in the message headers.
+func extractHeaders(ctx context.Context, headers amqp.Table) context.Context {
+	md := make(map[string][]string, len(headers))
+	for k, v := range headers {
+		if s, ok := v.(string); ok {
+			md[k] = []string{s}
+		}
+	}
+	return contextkit.ExtractMetadata(ctx, md)
+}
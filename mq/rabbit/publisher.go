@@ -0,0 +1,114 @@
+package rabbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/baisiyi/go-kits/contextkit"
+)
+
+// Publishing is the message to publish.
+type Publishing = amqp.Publishing
+
+// ErrNacked is returned when the broker refuses a confirmed message.
+var ErrNacked = errors.New("rabbit: message nacked by broker")
+
+// Publisher publishes messages over a dedicated channel, reopened on failure.
+type Publisher struct {
+	client *Client
+	cfg    PublisherConfig
+
+	mu sync.Mutex
+	ch *amqp.Channel
+}
+
+// NewPublisher creates a publisher of the client.
+func (c *Client) NewPublisher() *Publisher {
+	return &Publisher{client: c, cfg: c.cfg.Publisher}
+}
+
+// Publish publishes the message. The request context is propagated in the
+// message headers; with confirm enabled it returns after the broker acks.
+func (p *Publisher) Publish(ctx context.Context, exchange, key string, msg Publishing) error {
+	md := make(map[string][]string)
+	contextkit.InjectMetadata(ctx, md)
+	if len(md) > 0 {
+		headers := amqp.Table{}
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+		for k, v := range md {
+			headers[k] = v[0]
+		}
+		msg.Headers = headers
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ch, err := p.channel(ctx)
+	if err != nil {
+		return err
+	}
+	if !p.cfg.Confirm {
+		if err := ch.PublishWithContext(ctx, exchange, key, false, false, msg); err != nil {
+			p.reset()
+			return fmt.Errorf("rabbit: publish error: %w", err)
+		}
+		return nil
+	}
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		p.reset()
+		return fmt.Errorf("rabbit: publish error: %w", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, p.cfg.ConfirmTimeout)
+	defer cancel()
+	acked, err := confirm.WaitContext(waitCtx)
+	if err != nil {
+		p.reset()
+		return fmt.Errorf("rabbit: wait confirm error: %w", err)
+	}
+	if !acked {
+		return ErrNacked
+	}
+	return nil
+}
+
+// channel returns the publishing channel, opening a new one if necessary.
+func (p *Publisher) channel(ctx context.Context) (*amqp.Channel, error) {
+	if p.ch != nil && !p.ch.IsClosed() {
+		return p.ch, nil
+	}
+	ch, err := p.client.Channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.cfg.Confirm {
+		if err := ch.Confirm(false); err != nil {
+			_ = ch.Close()
+			return nil, fmt.Errorf("rabbit: enable confirm error: %w", err)
+		}
+	}
+	p.ch = ch
+	return ch, nil
+}
+
+func (p *Publisher) reset() {
+	if p.ch != nil {
+		_ = p.ch.Close()
+		p.ch = nil
+	}
+}
+
+// Close closes the publishing channel.
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reset()
+	return nil
+}
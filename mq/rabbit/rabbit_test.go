@@ -0,0 +1,138 @@
+package rabbit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/baisiyi/go-kits/contextkit"
+	"github.com/baisiyi/go-kits/log"
+)
+
+// mockAcknowledger records the ack/nack calls of a delivery.
+type mockAcknowledger struct {
+	acked   bool
+	nacked  bool
+	requeue bool
+}
+
+func (m *mockAcknowledger) Ack(tag uint64, multiple bool) error {
+	m.acked = true
+	return nil
+}
+
+func (m *mockAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	m.nacked = true
+	m.requeue = requeue
+	return nil
+}
+
+func (m *mockAcknowledger) Reject(tag uint64, requeue bool) error {
+	return m.Nack(tag, false, requeue)
+}
+
+func newTestConsumer(t *testing.T, cfg ConsumerConfig, h Handler) *Consumer {
+	c := &Client{logger: log.GetDefaultLogger()}
+	consumer, err := c.NewConsumer(cfg, h)
+	if err != nil {
+		t.Fatalf("NewConsumer failed: %v", err)
+	}
+	return consumer
+}
+
+// TestQueueArgs tests the translation of the dead letter and limit settings.
+func TestQueueArgs(t *testing.T) {
+	q := QueueConfig{
+		DeadLetterExchange:   "dlx",
+		DeadLetterRoutingKey: "dead",
+		MessageTTL:           time.Minute,
+		MaxLength:            100,
+		Args:                 map[string]any{"x-queue-type": "quorum"},
+	}
+	args := q.args()
+	want := amqp.Table{
+		"x-dead-letter-exchange":    "dlx",
+		"x-dead-letter-routing-key": "dead",
+		"x-message-ttl":             int64(60000),
+		"x-max-length":              int64(100),
+		"x-queue-type":              "quorum",
+	}
+	for k, v := range want {
+		if args[k] != v {
+			t.Errorf("args[%s] = %v, want %v", k, args[k], v)
+		}
+	}
+}
+
+// TestConsumerAck tests that a successful delivery is acked.
+func TestConsumerAck(t *testing.T) {
+	var gotReqID string
+	c := newTestConsumer(t, ConsumerConfig{Queue: "q"}, func(ctx context.Context, d Delivery) error {
+		gotReqID = contextkit.RequestID(ctx)
+		return nil
+	})
+	ack := &mockAcknowledger{}
+	c.process(Delivery{Acknowledger: ack, Headers: amqp.Table{"x-request-id": "req"}})
+
+	if !ack.acked || ack.nacked {
+		t.Errorf("Expected ack only, got %+v", ack)
+	}
+	if gotReqID != "req" {
+		t.Errorf("RequestID = %q, want req", gotReqID)
+	}
+}
+
+// TestConsumerDeadLetter tests that a failed delivery is retried and then dead-lettered.
+func TestConsumerDeadLetter(t *testing.T) {
+	calls := 0
+	c := newTestConsumer(t, ConsumerConfig{Queue: "q", Retries: 2, RetryBackoff: time.Millisecond},
+		func(ctx context.Context, d Delivery) error {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			return errors.New("fail")
+		})
+	ack := &mockAcknowledger{}
+	c.process(Delivery{Acknowledger: ack})
+
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	if !ack.nacked || ack.requeue {
+		t.Errorf("Expected nack without requeue, got %+v", ack)
+	}
+}
+
+// TestConsumerRequeue tests the requeue policy.
+func TestConsumerRequeue(t *testing.T) {
+	c := newTestConsumer(t, ConsumerConfig{Queue: "q", Requeue: true}, func(ctx context.Context, d Delivery) error {
+		return errors.New("fail")
+	})
+	ack := &mockAcknowledger{}
+	c.process(Delivery{Acknowledger: ack})
+	if !ack.nacked || !ack.requeue {
+		t.Errorf("Expected nack with requeue, got %+v", ack)
+	}
+}
+
+// TestConsumerDefaults tests the defaults of the consumer config.
+func TestConsumerDefaults(t *testing.T) {
+	c := newTestConsumer(t, ConsumerConfig{Queue: "q", Workers: 4}, nil)
+	if c.cfg.Prefetch != 8 {
+		t.Errorf("Prefetch = %d, want 8", c.cfg.Prefetch)
+	}
+	if _, err := (&Client{}).NewConsumer(ConsumerConfig{}, nil); err == nil {
+		t.Error("Expected error for empty queue")
+	}
+}
+
+// TestNewClientValidate tests the config validation of NewClient.
+func TestNewClientValidate(t *testing.T) {
+	if _, err := NewClient(Config{}, nil); err == nil {
+		t.Error("Expected error for empty url")
+	}
+}
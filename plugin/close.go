@@ -0,0 +1,212 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CloseTimeout is the close deadline of each plugin in CloseWithTimeout.
+var CloseTimeout = 10 * time.Second
+
+// CloseTimeoutConfigurer is the interface used to override CloseTimeout of a plugin,
+// e.g. for the plugins flushing buffered data on close.
+type CloseTimeoutConfigurer interface {
+	// CloseTimeout returns the close deadline of the plugin, CloseTimeout is used if not positive.
+	CloseTimeout() time.Duration
+}
+
+// Closables is the plugins set up by Config.Setup and kept up to date by Reload.
+type Closables struct {
+	mu sync.Mutex
+	// cfg is the enabled config the plugins are set up or last reloaded with.
+	cfg Config
+	// plugins are in the setup order, the ones set up by Reload last.
+	plugins []pluginInfo
+	// duration is the time taken by Config.Setup.
+	duration time.Duration
+}
+
+// Setup loads plugins like SetupClosables, the returned Closables closes them one by
+// one by Close or in parallel by CloseWithTimeout.
+func (c Config) Setup(opts ...SetupOption) (*Closables, error) {
+	start := time.Now()
+	r := newSetupOptions(opts).registry
+	if err := c.validate(r); err != nil {
+		return nil, err
+	}
+	c, _ = c.Enabled()
+	plugins, status, err := c.loadPlugins(r)
+	if err != nil {
+		return nil, err
+	}
+	pluginInfos, err := c.setupPlugins(plugins, status)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.onFinish(pluginInfos); err != nil {
+		return nil, err
+	}
+	return &Closables{cfg: c, plugins: pluginInfos, duration: time.Since(start)}, nil
+}
+
+// Close closes the plugins in reverse dependency order and stops at the first error,
+// a plugin is closed after all the plugins depending on it, including the flexible
+// dependencies. The plugins not depending on each other are closed in reverse setup order.
+func (cs *Closables) Close() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for _, p := range cs.closeOrder() {
+		if err := p.close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeOrder returns the plugins in reverse dependency order. The order is computed
+// from the dependency graph instead of the setup order, which depends on how the
+// plugins were set up, e.g. in parallel.
+func (cs *Closables) closeOrder() []*pluginInfo {
+	var (
+		byKey = make(map[string]*pluginInfo, len(cs.plugins))
+		nodes = make([]string, 0, len(cs.plugins))
+		deps  = make(map[string][]string, len(cs.plugins))
+	)
+	for i := range cs.plugins {
+		byKey[cs.plugins[i].key()] = &cs.plugins[i]
+		nodes = append(nodes, cs.plugins[i].key())
+	}
+	for i := range cs.plugins {
+		deps[cs.plugins[i].key()] = cs.plugins[i].dependencies(byKey)
+	}
+	order, _ := topoSort(nodes, deps)
+	closed := make(map[string]bool, len(order))
+	ps := make([]*pluginInfo, 0, len(cs.plugins))
+	for i := len(order) - 1; i >= 0; i-- {
+		ps = append(ps, byKey[order[i]])
+		closed[order[i]] = true
+	}
+	// the plugins in a cycle can not be set up, close them in reverse setup order anyway.
+	for i := len(cs.plugins) - 1; i >= 0; i-- {
+		if !closed[cs.plugins[i].key()] {
+			ps = append(ps, &cs.plugins[i])
+		}
+	}
+	return ps
+}
+
+// CloseWithTimeout closes the plugins in parallel, a plugin is closed after all the
+// plugins depending on it are closed. Each plugin is given CloseTimeout, a plugin not
+// closed in time is treated as closed so it does not block the others, and the plugins
+// not closed yet when ctx is done are skipped. The errors of all plugins are joined.
+func (cs *Closables) CloseWithTimeout(ctx context.Context) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	type closeResult struct {
+		key string
+		err error
+	}
+	var (
+		byKey      = make(map[string]*pluginInfo, len(cs.plugins))
+		deps       = make(map[string][]string, len(cs.plugins))
+		dependents = make(map[string]int, len(cs.plugins))
+		done       = make(chan closeResult, len(cs.plugins))
+		running    int
+		errs       []error
+	)
+	for i := range cs.plugins {
+		byKey[cs.plugins[i].key()] = &cs.plugins[i]
+	}
+	for i := range cs.plugins {
+		p := &cs.plugins[i]
+		for _, dep := range p.dependencies(byKey) {
+			deps[p.key()] = append(deps[p.key()], dep)
+			dependents[dep]++
+		}
+	}
+
+	start := func(p *pluginInfo) {
+		running++
+		go func() {
+			done <- closeResult{key: p.key(), err: p.closeWithin(ctx)}
+		}()
+	}
+	// start from the last set up plugins, the order only matters for the logs.
+	for i := len(cs.plugins) - 1; i >= 0; i-- {
+		if dependents[cs.plugins[i].key()] == 0 {
+			start(&cs.plugins[i])
+		}
+	}
+	for running > 0 {
+		r := <-done
+		running--
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+		for _, dep := range deps[r.key] {
+			if dependents[dep]--; dependents[dep] == 0 {
+				start(byKey[dep])
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dependencies returns the keys of the plugins in set that p depends on.
+func (p *pluginInfo) dependencies(set map[string]*pluginInfo) []string {
+	var keys []string
+	if d, ok := p.factory.(Depender); ok {
+		for _, dep := range d.DependsOn() {
+			if _, ok := set[dep]; ok && dep != p.key() {
+				keys = append(keys, dep)
+			}
+		}
+	}
+	if fd, ok := p.factory.(FlexDepender); ok {
+		for _, dep := range fd.FlexDependsOn() {
+			if _, ok := set[dep]; ok && dep != p.key() {
+				keys = append(keys, dep)
+			}
+		}
+	}
+	return keys
+}
+
+// closeTimeout returns the close deadline of the plugin.
+func (p *pluginInfo) closeTimeout() time.Duration {
+	if tc, ok := p.factory.(CloseTimeoutConfigurer); ok {
+		if d := tc.CloseTimeout(); d > 0 {
+			return d
+		}
+	}
+	return CloseTimeout
+}
+
+// closeWithin closes the plugin with its close deadline, the plugin is not closed
+// if ctx is already done.
+func (p *pluginInfo) closeWithin(ctx context.Context) error {
+	if _, ok := p.asCloser(); !ok {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("close plugin %s skipped: %w", p.key(), err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.closeTimeout())
+	defer cancel()
+	ch := make(chan error, 1)
+	go func() {
+		ch <- p.close()
+	}()
+	select {
+	case err := <-ch:
+		if err != nil {
+			return fmt.Errorf("close plugin %s error: %w", p.key(), err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("close plugin %s timeout: %w", p.key(), ctx.Err())
+	}
+}
@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// closeFuncFactory is a mock factory calling closeFunc on Close.
+type closeFuncFactory struct {
+	mockDependerFactory
+	closeFunc    func() error
+	closeTimeout time.Duration
+}
+
+func (m *closeFuncFactory) Close() error {
+	return m.closeFunc()
+}
+
+func (m *closeFuncFactory) CloseTimeout() time.Duration {
+	return m.closeTimeout
+}
+
+// TestCloseWithTimeout tests closing in parallel in reverse dependency order.
+func TestCloseWithTimeout(t *testing.T) {
+	Reset()
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	closer := func(key string, d time.Duration, err error) func() error {
+		return func() error {
+			time.Sleep(d)
+			mu.Lock()
+			closed = append(closed, key)
+			mu.Unlock()
+			return err
+		}
+	}
+	newFactory := func(typ string, deps []string, close func() error) *closeFuncFactory {
+		return &closeFuncFactory{
+			mockDependerFactory: mockDependerFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: typ}, dependsOn: deps},
+			closeFunc:           close,
+		}
+	}
+	Register("main", newFactory("server", []string{"cache-a", "cache-b"}, closer("server-main", 0, nil)))
+	Register("a", newFactory("cache", nil, closer("cache-a", 50*time.Millisecond, nil)))
+	Register("b", newFactory("cache", nil, closer("cache-b", 50*time.Millisecond, errors.New("flush failed"))))
+	hang := newFactory("cache", nil, func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	hang.closeTimeout = 20 * time.Millisecond
+	Register("hang", hang)
+
+	cfg := Config{
+		"server": {"main": yaml.Node{}},
+		"cache":  {"a": yaml.Node{}, "b": yaml.Node{}, "hang": yaml.Node{}},
+	}
+	cs, err := cfg.Setup()
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	start := time.Now()
+	err = cs.CloseWithTimeout(context.Background())
+	if d := time.Since(start); d >= 100*time.Millisecond {
+		t.Errorf("Expected parallel close, took %v", d)
+	}
+	if err == nil || !strings.Contains(err.Error(), "close plugin cache-b error: flush failed") ||
+		!strings.Contains(err.Error(), "close plugin cache-hang timeout") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	mu.Lock()
+	if len(closed) != 3 || closed[0] != "server-main" {
+		t.Errorf("Expected server-main closed first, got %v", closed)
+	}
+	mu.Unlock()
+
+	// the plugins not closed when ctx is done are skipped.
+	cs, err = Config{"server": {"main": yaml.Node{}}, "cache": {"a": yaml.Node{}, "b": yaml.Node{}}}.Setup()
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = cs.CloseWithTimeout(ctx)
+	if err == nil || strings.Count(err.Error(), "skipped") != 3 {
+		t.Errorf("Expected all skipped, got %v", err)
+	}
+}
+
+// flexCloseFuncFactory is a mock flexible depender calling closeFunc on Close.
+type flexCloseFuncFactory struct {
+	mockFlexDependerFactory
+	closeFunc func() error
+}
+
+func (m *flexCloseFuncFactory) Close() error {
+	return m.closeFunc()
+}
+
+// TestCloseDependencyOrder tests that Close never closes a plugin before its dependents,
+// whatever the setup order and whether the plugins in between are closers.
+func TestCloseDependencyOrder(t *testing.T) {
+	Reset()
+	var closed []string
+	closer := func(key string) func() error {
+		return func() error {
+			closed = append(closed, key)
+			return nil
+		}
+	}
+	newFactory := func(typ string, deps []string, close func() error) *closeFuncFactory {
+		return &closeFuncFactory{
+			mockDependerFactory: mockDependerFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: typ}, dependsOn: deps},
+			closeFunc:           close,
+		}
+	}
+	// server ~> dao (not a closer) -> database, server ~> cache, ~> for the flexible dependencies.
+	server := &flexCloseFuncFactory{
+		mockFlexDependerFactory: mockFlexDependerFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "server"}, flexDependsOn: []string{"cache-default", "dao-default", "missing-default"}},
+		closeFunc:               closer("server-default"),
+	}
+	dao := &mockDependerFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "dao"}, dependsOn: []string{"database-default"}}
+	database := newFactory("database", nil, closer("database-default"))
+	cache := newFactory("cache", nil, closer("cache-default"))
+
+	// the setup order is out of the dependency order on purpose.
+	cs := &Closables{plugins: []pluginInfo{
+		{factory: server, typ: "server", name: "default"},
+		{factory: database, typ: "database", name: "default"},
+		{factory: dao, typ: "dao", name: "default"},
+		{factory: cache, typ: "cache", name: "default"},
+	}}
+	if err := cs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	index := make(map[string]int, len(closed))
+	for i, key := range closed {
+		index[key] = i
+	}
+	if len(closed) != 3 || index["server-default"] > index["database-default"] || index["server-default"] > index["cache-default"] {
+		t.Errorf("closed = %v", closed)
+	}
+
+	// the same through Setup with the flexible dependency configured.
+	Register("default", server)
+	Register("default", dao)
+	Register("default", database)
+	Register("default", cache)
+	var cfg Config
+	if err := yaml.Unmarshal([]byte("server:\n  default: {}\ndao:\n  default: {}\ndatabase:\n  default: {}\ncache:\n  default: {}\n"), &cfg); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	closeFn, err := cfg.SetupClosables()
+	if err != nil {
+		t.Fatalf("SetupClosables failed: %v", err)
+	}
+	closed = nil
+	if err := closeFn(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if len(closed) != 3 || closed[0] != "server-default" {
+		t.Errorf("closed = %v", closed)
+	}
+}
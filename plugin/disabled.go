@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DisabledKey is the reserved key of a plugin config to skip the plugin, e.g. in the
+// config overlay of an environment. The key is removed before the config is passed to
+// the plugin, only a scalar value is taken as the flag so a nested config named
+// disabled, e.g. a client of the plugin, is kept as is.
+const DisabledKey = "disabled"
+
+// split returns the configs of the enabled and the disabled plugins with DisabledKey
+// removed, neither c nor its nodes are modified.
+func (c Config) split() (enabled, disabled Config, err error) {
+	enabled, disabled = make(Config, len(c)), make(Config)
+	for typ, factories := range c {
+		for name, cfg := range factories {
+			node, off, err := stripDisabled(&cfg)
+			if err != nil {
+				return nil, nil, fmt.Errorf("plugin %s-%s: %w", typ, name, err)
+			}
+			target := enabled
+			if off {
+				target = disabled
+			}
+			if target[typ] == nil {
+				target[typ] = make(map[string]yaml.Node)
+			}
+			target[typ][name] = node
+		}
+	}
+	return enabled, disabled, nil
+}
+
+// Enabled returns the config of the plugins not disabled by DisabledKey, with the key
+// removed.
+func (c Config) Enabled() (Config, error) {
+	enabled, _, err := c.split()
+	return enabled, err
+}
+
+func stripDisabled(cfg *yaml.Node) (yaml.Node, bool, error) {
+	n := resolveNode(cfg)
+	if n.Kind != yaml.MappingNode {
+		return *cfg, false, nil
+	}
+	i := mappingIndex(n, DisabledKey)
+	if i < 0 || n.Content[i+1].Kind != yaml.ScalarNode {
+		return *cfg, false, nil
+	}
+	var off bool
+	if err := n.Content[i+1].Decode(&off); err != nil {
+		return yaml.Node{}, false, fmt.Errorf("%s must be a bool: %w", DisabledKey, err)
+	}
+	stripped := *n
+	stripped.Content = append(append([]*yaml.Node{}, n.Content[:i]...), n.Content[i+2:]...)
+	return stripped, off, nil
+}
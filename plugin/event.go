@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"sync"
+	"time"
+)
+
+// EventListener is notified of the lifecycle events of each plugin, e.g. to record
+// the setup latency or emit metrics.
+type EventListener interface {
+	// OnSetupStart is called before the plugin is set up.
+	OnSetupStart(typ, name string)
+	// OnSetupDone is called after the plugin is set up successfully.
+	OnSetupDone(typ, name string, duration time.Duration)
+	// OnSetupError is called if the plugin setup fails or times out.
+	OnSetupError(typ, name string, err error)
+	// OnClose is called after the plugin is closed, err is the result of Close.
+	OnClose(typ, name string, err error)
+}
+
+// NopEventListener implements EventListener with no-op methods, embed it to
+// implement only the events needed.
+type NopEventListener struct{}
+
+// OnSetupStart implements EventListener.
+func (NopEventListener) OnSetupStart(typ, name string) {}
+
+// OnSetupDone implements EventListener.
+func (NopEventListener) OnSetupDone(typ, name string, duration time.Duration) {}
+
+// OnSetupError implements EventListener.
+func (NopEventListener) OnSetupError(typ, name string, err error) {}
+
+// OnClose implements EventListener.
+func (NopEventListener) OnClose(typ, name string, err error) {}
+
+var (
+	listenerMu sync.RWMutex
+	listeners  []EventListener
+)
+
+// AddEventListener adds the listener of the plugin lifecycle events.
+func AddEventListener(l EventListener) {
+	listenerMu.Lock()
+	defer listenerMu.Unlock()
+	listeners = append(listeners, l)
+}
+
+// RemoveEventListener removes the listener added by AddEventListener.
+func RemoveEventListener(l EventListener) {
+	listenerMu.Lock()
+	defer listenerMu.Unlock()
+	for i := range listeners {
+		if listeners[i] == l {
+			listeners = append(listeners[:i], listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+func notify(fn func(l EventListener)) {
+	listenerMu.RLock()
+	defer listenerMu.RUnlock()
+	for _, l := range listeners {
+		fn(l)
+	}
+}
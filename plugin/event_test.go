@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type recordListener struct {
+	NopEventListener
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordListener) add(e string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recordListener) OnSetupStart(typ, name string) { r.add("start " + typ + "-" + name) }
+
+func (r *recordListener) OnSetupDone(typ, name string, d time.Duration) {
+	r.add("done " + typ + "-" + name)
+}
+
+func (r *recordListener) OnSetupError(typ, name string, err error) { r.add("error " + typ + "-" + name) }
+
+func (r *recordListener) OnClose(typ, name string, err error) { r.add("close " + typ + "-" + name) }
+
+// TestEventListener tests the lifecycle events of the plugins.
+func TestEventListener(t *testing.T) {
+	Reset()
+	l := &recordListener{}
+	AddEventListener(l)
+	defer RemoveEventListener(l)
+
+	Register("default", &mockCloserFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "log"}})
+	closeFn, err := Config{"log": {"default": yaml.Node{}}}.SetupClosables()
+	if err != nil {
+		t.Fatalf("SetupClosables failed: %v", err)
+	}
+	_ = closeFn()
+
+	Register("default", &mockFactoryWithConfig{typ: "cache", setupFunc: func(string, Decoder) error {
+		return errors.New("boom")
+	}})
+	if _, err := (Config{"cache": {"default": yaml.Node{}}}).SetupClosables(); err == nil {
+		t.Fatal("Expected setup error")
+	}
+
+	want := []string{"start log-default", "done log-default", "close log-default", "start cache-default", "error cache-default"}
+	if len(l.events) != len(want) {
+		t.Fatalf("events = %v, want %v", l.events, want)
+	}
+	for i := range want {
+		if l.events[i] != want[i] {
+			t.Errorf("events[%d] = %s, want %s", i, l.events[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph is the resolved dependency graph of the configured plugins, the nodes are
+// the plugin keys in the form of type-name.
+type Graph struct {
+	Nodes []string `json:"nodes"`
+	Edges []Edge   `json:"edges"`
+	// Order is the setup order, only the nodes out of cycles are included.
+	Order []string `json:"order"`
+	// Cycle is a dependency cycle in the form of a -> b -> a, empty if there is none.
+	Cycle []string `json:"cycle,omitempty"`
+}
+
+// Edge means From depends on To, Flexible is true for the FlexDepender dependencies.
+type Edge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Flexible bool   `json:"flexible,omitempty"`
+}
+
+// DependencyGraph resolves the dependency graph of the plugins. It returns an error
+// if a plugin is not registered or a strong dependency is not configured, a cycle
+// is reported in Graph.Cycle. The disabled plugins are not included.
+func (c Config) DependencyGraph(opts ...SetupOption) (*Graph, error) {
+	r := newSetupOptions(opts).registry
+	c, err := c.Enabled()
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{}
+	deps := make(map[string][]string)
+	for typ, factories := range c {
+		for name := range factories {
+			p := pluginInfo{factory: r.Get(typ, name), typ: typ, name: name}
+			if p.factory == nil {
+				return nil, fmt.Errorf("plugin %s:%s no registered or imported, do not configure", typ, name)
+			}
+			g.Nodes = append(g.Nodes, p.key())
+		}
+	}
+	sort.Strings(g.Nodes)
+
+	exists := make(map[string]bool, len(g.Nodes))
+	for _, n := range g.Nodes {
+		exists[n] = true
+	}
+	for typ, factories := range c {
+		for name := range factories {
+			p := pluginInfo{factory: r.Get(typ, name), typ: typ, name: name}
+			if d, ok := p.factory.(Depender); ok {
+				for _, dep := range d.DependsOn() {
+					if !exists[dep] {
+						return nil, fmt.Errorf("depends plugin %s not exists", dep)
+					}
+					g.Edges = append(g.Edges, Edge{From: p.key(), To: dep})
+					deps[p.key()] = append(deps[p.key()], dep)
+				}
+			}
+			if fd, ok := p.factory.(FlexDepender); ok {
+				for _, dep := range fd.FlexDependsOn() {
+					if exists[dep] {
+						g.Edges = append(g.Edges, Edge{From: p.key(), To: dep, Flexible: true})
+						deps[p.key()] = append(deps[p.key()], dep)
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+
+	g.Order, g.Cycle = topoSort(g.Nodes, deps)
+	return g, nil
+}
+
+// topoSort returns the nodes in dependency order and a cycle of the remaining nodes.
+// deps maps a node to the nodes it depends on.
+func topoSort(nodes []string, deps map[string][]string) (order, cycle []string) {
+	done := make(map[string]bool, len(nodes))
+	for len(order) < len(nodes) {
+		var ready []string
+		for _, n := range nodes {
+			if done[n] {
+				continue
+			}
+			ok := true
+			for _, d := range deps[n] {
+				if !done[d] {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				ready = append(ready, n)
+			}
+		}
+		if len(ready) == 0 {
+			return order, findCycle(nodes, deps, done)
+		}
+		for _, n := range ready {
+			done[n] = true
+		}
+		order = append(order, ready...)
+	}
+	return order, nil
+}
+
+// findCycle walks the dependencies from the first node not done until a node repeats.
+// Every node not done has a dependency not done, so the walk always ends in a cycle.
+func findCycle(nodes []string, deps map[string][]string, done map[string]bool) []string {
+	var start string
+	for _, n := range nodes {
+		if !done[n] {
+			start = n
+			break
+		}
+	}
+	if start == "" {
+		return nil
+	}
+	var (
+		path []string
+		seen = make(map[string]int)
+	)
+	for n := start; ; {
+		if i, ok := seen[n]; ok {
+			return append(path[i:], n)
+		}
+		seen[n] = len(path)
+		path = append(path, n)
+		next := ""
+		for _, d := range deps[n] {
+			if !done[d] {
+				next = d
+				break
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		n = next
+	}
+}
+
+// DOT returns the graph in Graphviz DOT format, the flexible dependencies are dashed
+// and the nodes in the cycle are red.
+func (g *Graph) DOT() string {
+	inCycle := make(map[string]bool, len(g.Cycle))
+	for _, n := range g.Cycle {
+		inCycle[n] = true
+	}
+	var b strings.Builder
+	b.WriteString("digraph plugins {\n")
+	for _, n := range g.Nodes {
+		if inCycle[n] {
+			fmt.Fprintf(&b, "\t%q [color=red];\n", n)
+		} else {
+			fmt.Fprintf(&b, "\t%q;\n", n)
+		}
+	}
+	for _, e := range g.Edges {
+		if e.Flexible {
+			fmt.Fprintf(&b, "\t%q -> %q [style=dashed];\n", e.From, e.To)
+		} else {
+			fmt.Fprintf(&b, "\t%q -> %q;\n", e.From, e.To)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// JSON returns the graph in JSON format.
+func (g *Graph) JSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestDependencyGraph tests the nodes, edges and setup order of the graph.
+func TestDependencyGraph(t *testing.T) {
+	Reset()
+	Register("default", &mockFactoryWithConfig{typ: "log"})
+	Register("default", &mockDependerFactory{
+		mockFactoryWithConfig: mockFactoryWithConfig{typ: "database"},
+		dependsOn:             []string{"log-default"},
+	})
+	Register("default", &mockFlexDependerFactory{
+		mockFactoryWithConfig: mockFactoryWithConfig{typ: "cache"},
+		flexDependsOn:         []string{"database-default", "metrics-default"},
+	})
+	config := Config{
+		"log":      {"default": yaml.Node{}},
+		"database": {"default": yaml.Node{}},
+		"cache":    {"default": yaml.Node{}},
+	}
+
+	g, err := config.DependencyGraph()
+	if err != nil {
+		t.Fatalf("DependencyGraph failed: %v", err)
+	}
+	if got := strings.Join(g.Order, ","); got != "log-default,database-default,cache-default" {
+		t.Errorf("Order = %s", got)
+	}
+	if len(g.Edges) != 2 || !g.Edges[0].Flexible || g.Edges[1].To != "log-default" || len(g.Cycle) != 0 {
+		t.Errorf("Edges = %+v, Cycle = %v", g.Edges, g.Cycle)
+	}
+	if dot := g.DOT(); !strings.Contains(dot, `"cache-default" -> "database-default" [style=dashed];`) {
+		t.Errorf("DOT = %s", dot)
+	}
+	data, err := g.JSON()
+	var decoded Graph
+	if err != nil || json.Unmarshal(data, &decoded) != nil || len(decoded.Nodes) != 3 {
+		t.Errorf("JSON = %s, %v", data, err)
+	}
+
+	config["queue"] = map[string]yaml.Node{"default": {}}
+	if _, err := config.DependencyGraph(); err == nil {
+		t.Error("Expected error for unregistered plugin")
+	}
+}
+
+// TestDependencyGraphCycle tests that the cycle is reported with its nodes.
+func TestDependencyGraphCycle(t *testing.T) {
+	Reset()
+	for name, dep := range map[string]string{"A": "log-B", "B": "log-C", "C": "log-A"} {
+		Register(name, &mockDependerFactory{
+			mockFactoryWithConfig: mockFactoryWithConfig{typ: "log"},
+			dependsOn:             []string{dep},
+		})
+	}
+	Register("D", &mockFactoryWithConfig{typ: "log"})
+	config := Config{"log": {"A": {}, "B": {}, "C": {}, "D": {}}}
+
+	g, err := config.DependencyGraph()
+	if err != nil {
+		t.Fatalf("DependencyGraph failed: %v", err)
+	}
+	if got := strings.Join(g.Cycle, " -> "); got != "log-A -> log-B -> log-C -> log-A" {
+		t.Errorf("Cycle = %s", got)
+	}
+	if len(g.Order) != 1 || g.Order[0] != "log-D" {
+		t.Errorf("Order = %v", g.Order)
+	}
+	if !strings.Contains(g.DOT(), `"log-A" [color=red];`) {
+		t.Error("Expected cycle nodes highlighted")
+	}
+
+	_, err = config.SetupClosables()
+	if err == nil || !strings.Contains(err.Error(), "log-A -> log-B -> log-C -> log-A") {
+		t.Errorf("Expected cycle in setup error, got %v", err)
+	}
+}
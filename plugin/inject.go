@@ -0,0 +1,117 @@
+package plugin
+
+import "fmt"
+
+// Injector is the interface used to receive the instances registered by the other
+// plugins instead of the package globals, e.g. the logger created by the log plugin.
+// Inject is called before Setup of the plugin, after all its dependencies are set up,
+// so the plugin should depend on the plugins registering the instances it resolves.
+type Injector interface {
+	// Inject is called with resolve returning the instance registered with key in the
+	// registry the plugin is set up with, nil if not registered. An error fails the
+	// setup of the plugin.
+	Inject(resolve func(key string) any) error
+}
+
+// RegisterInstance registers v as the instance of key in the registry. A key registered
+// again is replaced. Factories should use RegisterInstanceOf in Setup instead, so the
+// instance is registered in the registry the plugin is set up with.
+func (r *Registry) RegisterInstance(key string, v any) {
+	r.registerInstance("", key, v)
+}
+
+func (r *Registry) registerInstance(owner, key string, v any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instances[key] = v
+	if owner == "" {
+		delete(r.owners, key)
+		return
+	}
+	r.owners[key] = owner
+}
+
+// deregisterInstances removes the instances registered by the plugin of owner.
+func (r *Registry) deregisterInstances(owner string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, o := range r.owners {
+		if o == owner {
+			delete(r.instances, key)
+			delete(r.owners, key)
+		}
+	}
+}
+
+// Instance returns the instance registered with key in the registry, nil if not registered.
+func (r *Registry) Instance(key string) any {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.instances[key]
+}
+
+// RegisterInstance registers v as the instance of key in DefaultRegistry. The instance
+// is not removed when the plugin registering it is closed, use RegisterInstanceOf in
+// Setup instead.
+func RegisterInstance(key string, v any) {
+	DefaultRegistry.RegisterInstance(key, v)
+}
+
+// Instance returns the instance registered with key in DefaultRegistry, nil if not registered.
+func Instance(key string) any {
+	return DefaultRegistry.Instance(key)
+}
+
+// RegisterInstanceOf registers v as the instance of key for the plugin given dec in
+// Setup or Reload, e.g.
+//
+//	func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+//		logger := newLogger(cfg)
+//		plugin.RegisterInstanceOf(dec, "log-"+name, logger)
+//		return nil
+//	}
+//
+// The instance is registered in the registry the plugin is set up with, and removed
+// when the plugin is closed or reloaded, so Reload should register its instances again.
+// For the other decoders, v is registered in DefaultRegistry like RegisterInstance.
+func RegisterInstanceOf(dec Decoder, key string, v any) {
+	if d, ok := dec.(*YamlNodeDecoder); ok && d.registry != nil {
+		d.registry.registerInstance(d.owner, key, v)
+		return
+	}
+	RegisterInstance(key, v)
+}
+
+// RegistryOf returns the registry the plugin given dec in Setup or Reload is set up
+// with, DefaultRegistry for the other decoders.
+func RegistryOf(dec Decoder) *Registry {
+	if d, ok := dec.(*YamlNodeDecoder); ok && d.registry != nil {
+		return d.registry
+	}
+	return DefaultRegistry
+}
+
+// registryOrDefault returns the registry the plugin is set up with.
+func (p *pluginInfo) registryOrDefault() *Registry {
+	if p.registry == nil {
+		return DefaultRegistry
+	}
+	return p.registry
+}
+
+// decoder returns the decoder of the config of the plugin passed to Setup and Reload.
+func (p *pluginInfo) decoder() *YamlNodeDecoder {
+	return &YamlNodeDecoder{Node: &p.cfg, registry: p.registryOrDefault(), owner: p.key()}
+}
+
+// inject calls Inject of the plugin implementing Injector.
+func (p *pluginInfo) inject() error {
+	injector, ok := p.factory.(Injector)
+	if !ok {
+		return nil
+	}
+	if err := injector.Inject(p.registryOrDefault().Instance); err != nil {
+		return fmt.Errorf("inject: %w", err)
+	}
+	return nil
+}
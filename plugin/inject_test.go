@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mockInjectorFactory is a mock factory that implements Injector and Depender interfaces.
+type mockInjectorFactory struct {
+	mockDependerFactory
+	keys      []string
+	injected  map[string]any
+	injectErr error
+}
+
+func (m *mockInjectorFactory) Inject(resolve func(key string) any) error {
+	m.injected = make(map[string]any)
+	for _, key := range m.keys {
+		m.injected[key] = resolve(key)
+	}
+	return m.injectErr
+}
+
+// TestInject tests that the instances registered by the dependencies are injected
+// before Setup of the dependents.
+func TestInject(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		t.Run(fmt.Sprintf("concurrency=%d", concurrency), func(t *testing.T) {
+			Reset()
+			old := SetupConcurrency
+			SetupConcurrency = concurrency
+			defer func() { SetupConcurrency = old }()
+
+			Register("default", &mockFactoryWithConfig{
+				typ: "log",
+				setupFunc: func(name string, dec Decoder) error {
+					RegisterInstance("log-"+name, "logger-"+name)
+					return nil
+				},
+			})
+			app := &mockInjectorFactory{
+				mockDependerFactory: mockDependerFactory{
+					mockFactoryWithConfig: mockFactoryWithConfig{typ: "svc"},
+					dependsOn:             []string{"log-default"},
+				},
+				keys: []string{"log-default", "missing"},
+			}
+			var setupSaw map[string]any
+			app.setupFunc = func(string, Decoder) error {
+				setupSaw = app.injected
+				return nil
+			}
+			Register("app", app)
+
+			config := Config{"log": {"default": yaml.Node{}}, "svc": {"app": yaml.Node{}}}
+			closeFunc, err := config.SetupClosables()
+			if err != nil {
+				t.Fatalf("SetupClosables failed: %v", err)
+			}
+			defer closeFunc()
+			if setupSaw["log-default"] != "logger-default" || setupSaw["missing"] != nil {
+				t.Errorf("Expected the logger injected before Setup, got %v", setupSaw)
+			}
+			if v := Instance("log-default"); v != "logger-default" {
+				t.Errorf("Instance = %v", v)
+			}
+		})
+	}
+}
+
+// TestInjectError tests that an error of Inject fails the setup of the plugin.
+func TestInjectError(t *testing.T) {
+	Reset()
+	setup := false
+	Register("app", &mockInjectorFactory{
+		mockDependerFactory: mockDependerFactory{mockFactoryWithConfig: mockFactoryWithConfig{
+			typ:       "svc",
+			setupFunc: func(string, Decoder) error { setup = true; return nil },
+		}},
+		injectErr: errors.New("logger required"),
+	})
+	_, err := Config{"svc": {"app": yaml.Node{}}}.SetupClosables()
+	if err == nil || !strings.Contains(err.Error(), "inject: logger required") {
+		t.Fatalf("Expected the inject error, got %v", err)
+	}
+	if setup {
+		t.Error("Expected Setup not called")
+	}
+}
+
+// TestInjectRegistry tests resolving the instances of a Registry only, not the ones
+// of DefaultRegistry, and Reset removing them.
+func TestInjectRegistry(t *testing.T) {
+	Reset()
+	r := NewRegistry()
+	r.RegisterInstance("a", 1)
+	r.RegisterInstance("b", 2)
+	RegisterInstance("b", 20)
+	RegisterInstance("c", 30)
+	app := &mockInjectorFactory{
+		mockDependerFactory: mockDependerFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "svc"}},
+		keys:                []string{"a", "b", "c"},
+	}
+	r.Register("app", app)
+	if _, err := r.Setup(Config{"svc": {"app": yaml.Node{}}}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if app.injected["a"] != 1 || app.injected["b"] != 2 || app.injected["c"] != nil {
+		t.Errorf("Unexpected instances %v", app.injected)
+	}
+
+	r.Reset()
+	if v := r.Instance("a"); v != nil {
+		t.Errorf("Expected the instances removed by Reset, got %v", v)
+	}
+}
+
+// TestRegisterInstanceOf tests that the instances registered by RegisterInstanceOf are
+// registered in the registry of the plugin, and removed when the plugin is reloaded
+// or closed.
+func TestRegisterInstanceOf(t *testing.T) {
+	Reset()
+	r := NewRegistry()
+	provider := &mockReloaderFactory{
+		mockFactoryWithConfig: mockFactoryWithConfig{typ: "log", setupFunc: func(name string, dec Decoder) error {
+			RegisterInstanceOf(dec, "log-"+name, "v1")
+			RegisterInstanceOf(dec, "log-old", "old")
+			return nil
+		}},
+		reloaded: make(map[string]string),
+	}
+	r.Register("default", provider)
+	oldCfg := mustConfig(t, "log:\n  default: {addr: a}\n")
+	cs, err := r.Setup(oldCfg)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if r.Instance("log-default") != "v1" || Instance("log-default") != nil {
+		t.Fatalf("Expected the instance registered in r only, got %v %v", r.Instance("log-default"), Instance("log-default"))
+	}
+
+	// Reload registers the instances again
+	provider.setupFunc = nil
+	if _, err := oldCfg.Reload(mustConfig(t, "log:\n  default: {addr: b}\n"), WithRegistry(r)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if r.Instance("log-old") != nil {
+		t.Error("Expected the instances of the reloaded plugin removed")
+	}
+
+	r.RegisterInstance("other", 1)
+	RegisterInstanceOf(&YamlNodeDecoder{}, "global", 2)
+	if err := cs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if r.Instance("log-default") != nil || r.Instance("other") != 1 || Instance("global") != 2 {
+		t.Error("Expected the instances of the closed plugin removed only")
+	}
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import "gopkg.in/yaml.v3"
+
+// MergeOverlay returns the effective config of c overlaid by overlay, e.g. the config
+// of an environment over the base config. The plugin configs are deep merged: the
+// mappings are merged by key recursively, and the other values of overlay, including
+// sequences, replace the ones of c. Neither c nor overlay is modified.
+func (c Config) MergeOverlay(overlay Config) Config {
+	merged := make(Config, len(c))
+	for typ, factories := range c {
+		merged[typ] = make(map[string]yaml.Node, len(factories))
+		for name, cfg := range factories {
+			merged[typ][name] = *copyNode(&cfg)
+		}
+	}
+	for typ, factories := range overlay {
+		if merged[typ] == nil {
+			merged[typ] = make(map[string]yaml.Node, len(factories))
+		}
+		for name, cfg := range factories {
+			base, ok := merged[typ][name]
+			if ok && emptyNode(&cfg) {
+				continue // the plugin is listed without config
+			}
+			if !ok {
+				merged[typ][name] = *copyNode(&cfg)
+				continue
+			}
+			merged[typ][name] = *mergeNode(&base, &cfg)
+		}
+	}
+	return merged
+}
+
+// mergeNode returns overlay merged into base, base is modified.
+func mergeNode(base, overlay *yaml.Node) *yaml.Node {
+	overlay = resolveNode(overlay)
+	base = resolveNode(base)
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return copyNode(overlay)
+	}
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		if j := mappingIndex(base, key.Value); j >= 0 {
+			base.Content[j+1] = mergeNode(base.Content[j+1], value)
+			continue
+		}
+		base.Content = append(base.Content, copyNode(key), copyNode(value))
+	}
+	return base
+}
+
+func emptyNode(n *yaml.Node) bool {
+	n = resolveNode(n)
+	return n.Kind == 0 || n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
+// resolveNode returns the content of a document or the target of an alias.
+func resolveNode(n *yaml.Node) *yaml.Node {
+	for {
+		switch {
+		case n.Kind == yaml.DocumentNode && len(n.Content) == 1:
+			n = n.Content[0]
+		case n.Kind == yaml.AliasNode && n.Alias != nil:
+			n = n.Alias
+		default:
+			return n
+		}
+	}
+}
+
+func mappingIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyNode deep copies the node with the aliases resolved, so that the merged config
+// shares nothing with its sources.
+func copyNode(n *yaml.Node) *yaml.Node {
+	n = resolveNode(n)
+	c := *n
+	if len(n.Content) > 0 {
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = copyNode(child)
+		}
+	}
+	return &c
+}
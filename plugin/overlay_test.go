@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestMergeOverlay tests that the overlay is deep merged into the base config.
+func TestMergeOverlay(t *testing.T) {
+	base := mustConfig(t, `
+redis:
+  default:
+    cache:
+      addr: 127.0.0.1:6379
+      pool_size: 10
+      tags: [a, b]
+log:
+  default:
+    - writer: console
+`)
+	overlay := mustConfig(t, `
+redis:
+  default:
+    cache:
+      addr: redis.prod:6379
+      tags: [c]
+    session:
+      addr: redis.prod:6380
+metrics:
+  default:
+    namespace: prod
+`)
+	merged := base.MergeOverlay(overlay)
+
+	var redisCfg map[string]struct {
+		Addr     string   `yaml:"addr"`
+		PoolSize int      `yaml:"pool_size"`
+		Tags     []string `yaml:"tags"`
+	}
+	node := merged["redis"]["default"]
+	if err := node.Decode(&redisCfg); err != nil {
+		t.Fatal(err)
+	}
+	cache := redisCfg["cache"]
+	if cache.Addr != "redis.prod:6379" || cache.PoolSize != 10 || len(cache.Tags) != 1 || cache.Tags[0] != "c" {
+		t.Errorf("cache = %+v", cache)
+	}
+	if redisCfg["session"].Addr != "redis.prod:6380" {
+		t.Errorf("session = %+v", redisCfg["session"])
+	}
+	if _, ok := merged["log"]["default"]; !ok {
+		t.Error("log-default missing")
+	}
+	if _, ok := merged["metrics"]["default"]; !ok {
+		t.Error("metrics-default missing")
+	}
+
+	// the sources are not modified
+	var orig map[string]map[string]any
+	node = base["redis"]["default"]
+	if err := node.Decode(&orig); err != nil {
+		t.Fatal(err)
+	}
+	if orig["cache"]["addr"] != "127.0.0.1:6379" || orig["session"] != nil {
+		t.Errorf("base modified: %v", orig)
+	}
+}
+
+// TestMergeOverlayEmpty tests that a plugin listed without config keeps the base config.
+func TestMergeOverlayEmpty(t *testing.T) {
+	base := mustConfig(t, "redis:\n  default:\n    addr: a\n")
+	for _, overlay := range []Config{{"redis": {"default": yaml.Node{}}}, mustConfig(t, "redis:\n  default:\n")} {
+		merged := base.MergeOverlay(overlay)
+		var cfg struct {
+			Addr string `yaml:"addr"`
+		}
+		node := merged["redis"]["default"]
+		if err := node.Decode(&cfg); err != nil || cfg.Addr != "a" {
+			t.Errorf("addr = %q, err = %v", cfg.Addr, err)
+		}
+	}
+}
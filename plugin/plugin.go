@@ -1,6 +1,28 @@
 package plugin
 
-var plugins = make(map[string]map[string]Factory) // type => name => factory
+import "sync"
+
+// DefaultRegistry is the registry used by the package-level functions, e.g. Register
+// and Get, and by Config.Setup if no registry is given by WithRegistry.
+var DefaultRegistry = NewRegistry()
+
+// Registry is a namespace of the registered factories. Use a Registry of its own for
+// each app instance to embed several independent apps, or tests, in one process.
+type Registry struct {
+	mu        sync.RWMutex
+	plugins   map[string]map[string]Factory // type => name => factory
+	instances map[string]any                // key => instance registered by RegisterInstance
+	owners    map[string]string             // key => plugin registering the instance by RegisterInstanceOf
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		plugins:   make(map[string]map[string]Factory),
+		instances: make(map[string]any),
+		owners:    make(map[string]string),
+	}
+}
 
 // Factory is the interface for plugin factory abstraction.
 // Custom Plugins need to implement this interface to be registered as a plugin with certain type.
@@ -17,15 +39,136 @@ type Decoder interface {
 	Decode(v any) error // the input param is the custom configuration of the plugin
 }
 
-func Register(name string, f Factory) {
-	factories, ok := plugins[f.Type()]
+// Register registers the factory of the plugin with name in the registry.
+func (r *Registry) Register(name string, f Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	factories, ok := r.plugins[f.Type()]
 	if !ok {
 		factories = make(map[string]Factory)
-		plugins[f.Type()] = factories
+		r.plugins[f.Type()] = factories
 	}
 	factories[name] = f
 }
 
+// Get returns the factory of the type and name, nil if not registered.
+func (r *Registry) Get(typ string, name string) Factory {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.plugins[typ][name]
+}
+
+// Deregister removes the factory of the type and name. It does nothing if not registered.
+func (r *Registry) Deregister(typ string, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.plugins[typ], name)
+	if len(r.plugins[typ]) == 0 {
+		delete(r.plugins, typ)
+	}
+}
+
+// Reset removes all the registered factories and instances of the registry.
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.plugins = make(map[string]map[string]Factory)
+	r.instances = make(map[string]any)
+	r.owners = make(map[string]string)
+}
+
+// Snapshot returns a copy of the registered factories, which are brought back by Restore.
+func (r *Registry) Snapshot() RegistrySnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return RegistrySnapshot{plugins: copyPlugins(r.plugins)}
+}
+
+// Restore replaces the registered factories with the snapshot.
+func (r *Registry) Restore(s RegistrySnapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.plugins = copyPlugins(s.plugins)
+}
+
+// Setup sets up the plugins configured in c with the factories of the registry, the
+// same as c.Setup(WithRegistry(r)).
+func (r *Registry) Setup(c Config) (*Closables, error) {
+	return c.Setup(WithRegistry(r))
+}
+
+// SetupClosables sets up the plugins configured in c with the factories of the
+// registry, the same as c.SetupClosables(WithRegistry(r)).
+func (r *Registry) SetupClosables(c Config) (close func() error, err error) {
+	return c.SetupClosables(WithRegistry(r))
+}
+
+// Register registers the factory in DefaultRegistry.
+func Register(name string, f Factory) {
+	DefaultRegistry.Register(name, f)
+}
+
+// Get returns the factory registered in DefaultRegistry.
 func Get(typ string, name string) Factory {
-	return plugins[typ][name]
+	return DefaultRegistry.Get(typ, name)
+}
+
+// Deregister removes the factory of the type and name, e.g. registered by a test or
+// replaced by an embedding framework. It does nothing if not registered.
+func Deregister(typ string, name string) {
+	DefaultRegistry.Deregister(typ, name)
+}
+
+// Reset removes all the registered factories, including the ones registered by init
+// of the imported packages, and the instances. Use Snapshot and Restore to bring the
+// factories back.
+func Reset() {
+	DefaultRegistry.Reset()
+}
+
+// RegistrySnapshot is a copy of the registered factories taken by Snapshot.
+type RegistrySnapshot struct {
+	plugins map[string]map[string]Factory
+}
+
+// Snapshot returns a copy of the registered factories, which are brought back by
+// Restore, e.g. in a test:
+//
+//	defer plugin.Restore(plugin.Snapshot())
+//	plugin.Register("default", &fakeFactory{})
+func Snapshot() RegistrySnapshot {
+	return DefaultRegistry.Snapshot()
+}
+
+// Restore replaces the registered factories with the snapshot.
+func Restore(s RegistrySnapshot) {
+	DefaultRegistry.Restore(s)
+}
+
+func copyPlugins(src map[string]map[string]Factory) map[string]map[string]Factory {
+	dst := make(map[string]map[string]Factory, len(src))
+	for typ, factories := range src {
+		dst[typ] = make(map[string]Factory, len(factories))
+		for name, f := range factories {
+			dst[typ][name] = f
+		}
+	}
+	return dst
+}
+
+// RegisterTyped registers f like Register and returns it with its concrete type, e.g.
+//
+//	var DefaultFactory = plugin.RegisterTyped("default", &Factory{})
+func RegisterTyped[T Factory](name string, f T) T {
+	Register(name, f)
+	return f
+}
+
+// GetTyped returns the factory of the type and name as T, false if not registered
+// or not a T, so the callers need no unchecked type assertion:
+//
+//	f, ok := plugin.GetTyped[*redis.Factory]("redis", "default")
+func GetTyped[T Factory](typ string, name string) (T, bool) {
+	f, ok := Get(typ, name).(T)
+	return f, ok
 }
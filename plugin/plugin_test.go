@@ -21,8 +21,8 @@ func (m *mockFactory) Setup(name string, dec Decoder) error {
 
 // TestRegisterAndGet tests that a registered factory can be retrieved.
 func TestRegisterAndGet(t *testing.T) {
-	// Clear the global plugins map before test.
-	plugins = make(map[string]map[string]Factory)
+	// Clear the registered factories before test.
+	Reset()
 
 	factory := &mockFactory{typ: "test"}
 	Register("test_plugin", factory)
@@ -38,8 +38,8 @@ func TestRegisterAndGet(t *testing.T) {
 
 // TestGetNotFound tests that Get returns nil for unregistered plugins.
 func TestGetNotFound(t *testing.T) {
-	// Clear the global plugins map before test.
-	plugins = make(map[string]map[string]Factory)
+	// Clear the registered factories before test.
+	Reset()
 
 	retrieved := Get("nonexistent", "nonexistent")
 	if retrieved != nil {
@@ -49,8 +49,8 @@ func TestGetNotFound(t *testing.T) {
 
 // TestMultiplePluginsSameType tests that multiple plugins of the same type are stored separately.
 func TestMultiplePluginsSameType(t *testing.T) {
-	// Clear the global plugins map before test.
-	plugins = make(map[string]map[string]Factory)
+	// Clear the registered factories before test.
+	Reset()
 
 	factory1 := &mockFactory{typ: "log"}
 	factory2 := &mockFactory{typ: "log"}
@@ -71,8 +71,8 @@ func TestMultiplePluginsSameType(t *testing.T) {
 
 // TestDifferentTypes tests that different types are isolated from each other.
 func TestDifferentTypes(t *testing.T) {
-	// Clear the global plugins map before test.
-	plugins = make(map[string]map[string]Factory)
+	// Clear the registered factories before test.
+	Reset()
 
 	logFactory := &mockFactory{typ: "log"}
 	configFactory := &mockFactory{typ: "config"}
@@ -101,8 +101,8 @@ func TestDifferentTypes(t *testing.T) {
 
 // TestRegisterOverwrite tests that duplicate registration overwrites the previous one.
 func TestRegisterOverwrite(t *testing.T) {
-	// Clear the global plugins map before test.
-	plugins = make(map[string]map[string]Factory)
+	// Clear the registered factories before test.
+	Reset()
 
 	factory1 := &mockFactory{typ: "test"}
 	factory2 := &mockFactory{typ: "test"}
@@ -115,3 +115,123 @@ func TestRegisterOverwrite(t *testing.T) {
 		t.Error("Expected second factory after overwrite, got first factory")
 	}
 }
+
+// TestTyped tests registering and getting the factories with the concrete type.
+func TestTyped(t *testing.T) {
+	Reset()
+
+	factory := RegisterTyped("typed", &mockFactory{typ: "test"})
+	got, ok := GetTyped[*mockFactory]("test", "typed")
+	if !ok || got != factory {
+		t.Errorf("GetTyped = %v, %v", got, ok)
+	}
+	if _, ok := GetTyped[*mockCloserFactory]("test", "typed"); ok {
+		t.Error("Expected false for the other type")
+	}
+	if got, ok := GetTyped[*mockFactory]("test", "missing"); ok || got != nil {
+		t.Errorf("Expected nil and false for the missing factory, got %v, %v", got, ok)
+	}
+}
+
+// TestSnapshotRestore tests that Deregister and Reset are undone by Restore, and the
+// snapshot is not changed by the later registrations.
+func TestSnapshotRestore(t *testing.T) {
+	Reset()
+	a, b := &mockFactory{typ: "test"}, &mockFactory{typ: "test"}
+	Register("a", a)
+	Register("b", b)
+
+	snapshot := Snapshot()
+	Deregister("test", "a")
+	Deregister("test", "missing")
+	if Get("test", "a") != nil || Get("test", "b") != b {
+		t.Error("Expected only a deregistered")
+	}
+	Reset()
+	Register("c", &mockFactory{typ: "test"})
+
+	Restore(snapshot)
+	if Get("test", "a") != a || Get("test", "b") != b || Get("test", "c") != nil {
+		t.Error("Expected the snapshot restored")
+	}
+	Register("d", &mockFactory{typ: "test"})
+	Restore(snapshot)
+	if Get("test", "d") != nil {
+		t.Error("Expected the snapshot not changed by Register")
+	}
+}
+
+// TestRegistry tests that the plugins are set up, validated and reloaded with the
+// factories of their own registry, isolated from DefaultRegistry and each other.
+func TestRegistry(t *testing.T) {
+	Reset()
+	newApp := func() (*Registry, *mockReloaderFactory, *int) {
+		setups := new(int)
+		f := &mockReloaderFactory{
+			mockFactoryWithConfig: mockFactoryWithConfig{typ: "log", setupFunc: func(string, Decoder) error {
+				*setups++
+				return nil
+			}},
+			reloaded: make(map[string]string),
+		}
+		r := NewRegistry()
+		r.Register("default", f)
+		return r, f, setups
+	}
+	a, fa, aSetups := newApp()
+	b, fb, bSetups := newApp()
+	cfg := mustConfig(t, "log:\n  default:\n    addr: a\n")
+
+	if Get("log", "default") != nil {
+		t.Fatal("Expected the registries isolated from DefaultRegistry")
+	}
+	if _, err := cfg.SetupClosables(); err == nil {
+		t.Error("Expected error for the plugin not registered in DefaultRegistry")
+	}
+	if err := cfg.Validate(WithRegistry(a)); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+	if err := cfg.Verify(WithRegistry(a)); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+	if g, err := cfg.DependencyGraph(WithRegistry(a)); err != nil || len(g.Nodes) != 1 {
+		t.Errorf("DependencyGraph = %v, %v", g, err)
+	}
+
+	cs, err := a.Setup(cfg)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if *aSetups != 1 || *bSetups != 0 {
+		t.Errorf("Expected only a set up, got %d and %d", *aSetups, *bSetups)
+	}
+	closeB, err := b.SetupClosables(cfg)
+	if err != nil {
+		t.Fatalf("SetupClosables failed: %v", err)
+	}
+	if *bSetups != 1 {
+		t.Errorf("Expected b set up, got %d", *bSetups)
+	}
+
+	if _, err := cfg.Reload(mustConfig(t, "log:\n  default:\n    addr: b\n"), WithRegistry(b)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(fa.reloaded) != 0 || fb.reloaded["default"] != "b" {
+		t.Errorf("Expected only b reloaded, got %v and %v", fa.reloaded, fb.reloaded)
+	}
+	_ = cs.Close()
+	_ = closeB()
+	if fa.closed != 1 || fb.closed != 1 {
+		t.Errorf("Expected each closed once, got %d and %d", fa.closed, fb.closed)
+	}
+
+	snapshot := a.Snapshot()
+	a.Reset()
+	if a.Get("log", "default") != nil || b.Get("log", "default") == nil {
+		t.Error("Expected only a reset")
+	}
+	a.Restore(snapshot)
+	if a.Get("log", "default") != fa {
+		t.Error("Expected the snapshot restored")
+	}
+}
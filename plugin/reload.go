@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Reloader is the interface of the plugins able to apply a changed configuration without restart.
+type Reloader interface {
+	Reload(name string, dec Decoder) error
+}
+
+// Reload applies newCfg to the plugins set up by c: the changed plugins are reloaded,
+// the new ones are set up and the removed ones are closed. The plugins disabled by
+// DisabledKey are taken as not configured, so disabling a plugin closes it and enabling
+// one sets it up.
+//
+// Before changing anything, it checks that every changed plugin implements Reloader and
+// every new plugin is registered, and validates the configs of the changed and the new
+// plugins. If a changed plugin fails to reload, or a new plugin fails to set up or to
+// finish, the new plugins already set up are closed and the reloaded plugins are
+// reloaded with their configs in c again.
+//
+// The returned function closes the plugins set up by this reload in reverse dependency
+// order like Closables.Close. The caller should use newCfg as the current config afterwards.
+// The removed plugins are closed here, so the close function of the plugins set up from c
+// would close them again; use Closables.Reload to reload the plugins set up by Setup.
+func (c Config) Reload(newCfg Config, opts ...SetupOption) (close func() error, err error) {
+	if c, err = c.Enabled(); err != nil {
+		return nil, err
+	}
+	cs := &Closables{cfg: c}
+	applied, err := cs.reload(newCfg, newSetupOptions(opts).registry)
+	if !applied {
+		return nil, err
+	}
+	return cs.Close, err
+}
+
+// Reload applies newCfg to the plugins like Config.Reload, from the config they were set
+// up or last reloaded with. The removed plugins are closed and dropped from cs and the
+// new ones are added, so Close and CloseWithTimeout close only the plugins in effect.
+// If closing some removed plugins fails, newCfg is still applied and the error is returned.
+func (cs *Closables) Reload(newCfg Config, opts ...SetupOption) error {
+	_, err := cs.reload(newCfg, newSetupOptions(opts).registry)
+	return err
+}
+
+// reload applies newCfg to the plugins of cs. applied reports whether newCfg is in
+// effect, err is the error of closing the removed plugins then.
+func (cs *Closables) reload(newCfg Config, r *Registry) (applied bool, err error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	c := cs.cfg
+	if newCfg, err = newCfg.Enabled(); err != nil {
+		return false, err
+	}
+	var (
+		added   = make(Config)
+		changed []pluginInfo
+		removed []pluginInfo
+	)
+	for typ, factories := range newCfg {
+		for name, cfg := range factories {
+			old, ok := c[typ][name]
+			if !ok {
+				if added[typ] == nil {
+					added[typ] = make(map[string]yaml.Node)
+				}
+				added[typ][name] = cfg
+				continue
+			}
+			if nodeEqual(&old, &cfg) {
+				continue
+			}
+			p := pluginInfo{factory: r.Get(typ, name), typ: typ, name: name, cfg: cfg, registry: r}
+			if _, ok := p.factory.(Reloader); !ok {
+				return false, fmt.Errorf("plugin %s changed but not reloadable", p.key())
+			}
+			changed = append(changed, p)
+		}
+	}
+	for typ, factories := range c {
+		for name := range factories {
+			if _, ok := newCfg[typ][name]; !ok {
+				removed = append(removed, pluginInfo{factory: r.Get(typ, name), typ: typ, name: name, registry: r})
+			}
+		}
+	}
+
+	if err := validatePlugins(append(added.infos(r), changed...)); err != nil {
+		return false, err
+	}
+
+	plugins, status, err := added.loadPlugins(r)
+	if err != nil {
+		return false, err
+	}
+	for typ, factories := range newCfg {
+		for name := range factories {
+			if _, ok := added[typ][name]; !ok {
+				status[typ+"-"+name] = true
+			}
+		}
+	}
+
+	sortPlugins(changed)
+	for i := range changed {
+		if err := changed[i].reload(); err != nil {
+			return false, c.rollback(changed[:i+1], err)
+		}
+	}
+	pluginInfos, err := added.setupPlugins(plugins, status)
+	if err == nil {
+		err = added.onFinish(pluginInfos)
+	}
+	if err != nil {
+		if cerr := closeAdded(pluginInfos); cerr != nil {
+			err = errors.Join(err, fmt.Errorf("rollback: %w", cerr))
+		}
+		return false, c.rollback(changed, err)
+	}
+
+	sortPlugins(removed)
+	var errs []error
+	gone := make(map[string]bool, len(removed))
+	for i := range removed {
+		gone[removed[i].key()] = true
+		if err := removed[i].close(); err != nil {
+			errs = append(errs, fmt.Errorf("close plugin %s error: %v", removed[i].key(), err))
+		}
+	}
+	live := make([]pluginInfo, 0, len(cs.plugins)+len(pluginInfos))
+	for _, p := range cs.plugins {
+		if gone[p.key()] {
+			continue
+		}
+		if cfg, ok := newCfg[p.typ][p.name]; ok {
+			p.cfg = cfg
+		}
+		live = append(live, p)
+	}
+	cs.plugins = append(live, pluginInfos...)
+	cs.cfg = newCfg
+	return true, errors.Join(errs...)
+}
+
+// closeAdded closes the plugins set up by a failed reload in reverse dependency order,
+// the errors of all plugins are joined.
+func closeAdded(ps []pluginInfo) error {
+	var errs []error
+	for _, p := range (&Closables{plugins: ps}).closeOrder() {
+		if err := p.close(); err != nil {
+			errs = append(errs, fmt.Errorf("close plugin %s error: %v", p.key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rollback reloads the plugins with their configs in c, the failed one included since
+// it may be partially reloaded.
+func (c Config) rollback(ps []pluginInfo, cause error) error {
+	errs := []error{cause}
+	for _, p := range ps {
+		p.cfg = c[p.typ][p.name]
+		if err := p.reload(); err != nil {
+			errs = append(errs, fmt.Errorf("rollback: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *pluginInfo) reload() error {
+	reloader := p.factory.(Reloader)
+	return p.run("reload", func() error {
+		// the instances are registered again by Reload
+		p.registryOrDefault().deregisterInstances(p.key())
+		return reloader.Reload(p.name, p.decoder())
+	})
+}
+
+func nodeEqual(a, b *yaml.Node) bool {
+	if a.Kind == 0 || b.Kind == 0 {
+		return a.Kind == b.Kind
+	}
+	ab, err := yaml.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bb, err := yaml.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ab, bb)
+}
+
+func sortPlugins(ps []pluginInfo) {
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].key() < ps[j].key()
+	})
+}
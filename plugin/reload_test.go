@@ -0,0 +1,202 @@
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mockReloaderFactory is a mock factory that implements Reloader and Closer interfaces.
+type mockReloaderFactory struct {
+	mockFactoryWithConfig
+	reloaded map[string]string
+	closed   int
+	failAddr string // Reload fails with the addr
+}
+
+func (m *mockReloaderFactory) Reload(name string, dec Decoder) error {
+	var cfg struct {
+		Addr string `yaml:"addr"`
+	}
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	if m.failAddr != "" && cfg.Addr == m.failAddr {
+		return errors.New("reload failed")
+	}
+	m.reloaded[name] = cfg.Addr
+	return nil
+}
+
+func (m *mockReloaderFactory) Close() error {
+	m.closed++
+	return nil
+}
+
+func mustConfig(t *testing.T, s string) Config {
+	t.Helper()
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(s), &cfg); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	return cfg
+}
+
+// TestReload tests that Reload reloads the changed plugins, sets up the new ones and closes the removed ones.
+func TestReload(t *testing.T) {
+	Reset()
+	var setups []string
+	newReloader := func(typ string) *mockReloaderFactory {
+		return &mockReloaderFactory{
+			mockFactoryWithConfig: mockFactoryWithConfig{typ: typ, setupFunc: func(name string, dec Decoder) error {
+				setups = append(setups, typ+"-"+name)
+				return nil
+			}},
+			reloaded: make(map[string]string),
+		}
+	}
+	redis, db, cache := newReloader("redis"), newReloader("database"), newReloader("cache")
+	Register("default", redis)
+	Register("default", db)
+	Register("default", cache)
+
+	oldCfg := mustConfig(t, `
+redis:
+  default: {addr: "a:6379"}
+database:
+  default: {addr: "db"}
+`)
+	if _, err := oldCfg.SetupClosables(); err != nil {
+		t.Fatalf("SetupClosables failed: %v", err)
+	}
+	setups = nil
+
+	newCfg := mustConfig(t, `
+redis:
+  default: {addr: "b:6379"}
+cache:
+  default: {addr: "mem"}
+`)
+	closeFn, err := oldCfg.Reload(newCfg)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if redis.reloaded["default"] != "b:6379" {
+		t.Errorf("Expected redis reloaded, got %v", redis.reloaded)
+	}
+	if len(setups) != 1 || setups[0] != "cache-default" {
+		t.Errorf("Expected cache set up, got %v", setups)
+	}
+	if db.closed != 1 {
+		t.Errorf("Expected database closed once, got %d", db.closed)
+	}
+	if err := closeFn(); err != nil || cache.closed != 1 {
+		t.Errorf("Expected cache closed by the returned func, got %v %d", err, cache.closed)
+	}
+
+	redis.reloaded = make(map[string]string)
+	if _, err := newCfg.Reload(newCfg); err != nil || len(redis.reloaded) != 0 {
+		t.Errorf("Expected unchanged config not reloaded, got %v %v", err, redis.reloaded)
+	}
+}
+
+// TestClosablesReload tests that Closables.Reload drops the removed plugins and adds the new
+// ones, so Close closes only the plugins in effect.
+func TestClosablesReload(t *testing.T) {
+	Reset()
+	newReloader := func(typ string) *mockReloaderFactory {
+		return &mockReloaderFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: typ}, reloaded: make(map[string]string)}
+	}
+	redis, db, cache := newReloader("redis"), newReloader("database"), newReloader("cache")
+	Register("default", redis)
+	Register("default", db)
+	Register("default", cache)
+
+	cs, err := mustConfig(t, "redis:\n  default: {addr: \"a:6379\"}\ndatabase:\n  default: {addr: db}\n").Setup()
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := cs.Reload(mustConfig(t, "redis:\n  default: {addr: \"b:6379\"}\ncache:\n  default: {addr: mem}\n")); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if db.closed != 1 {
+		t.Errorf("Expected database closed by Reload, got %d", db.closed)
+	}
+	if err := cs.Reload(mustConfig(t, "redis:\n  default: {addr: \"b:6379\"}\n")); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if err := cs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if db.closed != 1 || cache.closed != 1 || redis.closed != 1 {
+		t.Errorf("Expected each plugin closed once, got database %d cache %d redis %d", db.closed, cache.closed, redis.closed)
+	}
+}
+
+// TestReloadNotReloadable tests that Reload fails without changes if a changed plugin is not a Reloader.
+func TestReloadNotReloadable(t *testing.T) {
+	Reset()
+	Register("default", &mockFactoryWithConfig{typ: "log"})
+	added := &mockCloserFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "cache"}}
+	Register("default", added)
+
+	oldCfg := mustConfig(t, "log:\n  default: {level: info}\n")
+	newCfg := mustConfig(t, "log:\n  default: {level: debug}\ncache:\n  default: {}\n")
+	if _, err := oldCfg.Reload(newCfg); err == nil {
+		t.Fatal("Expected error for not reloadable plugin")
+	}
+	if _, err := oldCfg.Reload(mustConfig(t, "log:\n  default: {level: info}\nmissing:\n  default: {}\n")); err == nil {
+		t.Error("Expected error for unregistered plugin")
+	}
+}
+
+// TestReloadRollback tests that Reload reloads the old configs if a changed plugin fails to reload.
+func TestReloadRollback(t *testing.T) {
+	Reset()
+	db := &mockReloaderFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "database"}, reloaded: make(map[string]string)}
+	redis := &mockReloaderFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "redis"}, reloaded: make(map[string]string), failAddr: "bad"}
+	Register("default", db)
+	Register("default", redis)
+
+	oldCfg := mustConfig(t, "database:\n  default: {addr: db1}\nredis:\n  default: {addr: \"a:6379\"}\n")
+	newCfg := mustConfig(t, "database:\n  default: {addr: db2}\nredis:\n  default: {addr: bad}\n")
+	if _, err := oldCfg.Reload(newCfg); err == nil {
+		t.Fatal("Expected reload error")
+	}
+	if db.reloaded["default"] != "db1" {
+		t.Errorf("Expected database rolled back to db1, got %v", db.reloaded)
+	}
+	if redis.reloaded["default"] != "a:6379" {
+		t.Errorf("Expected redis rolled back to a:6379, got %v", redis.reloaded)
+	}
+}
+
+// TestReloadCloseAdded tests that the new plugins set up are closed and the changed
+// ones rolled back if a new plugin fails to set up.
+func TestReloadCloseAdded(t *testing.T) {
+	Reset()
+	db := &mockReloaderFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "database"}, reloaded: make(map[string]string)}
+	cache := &mockReloaderFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "cache"}, reloaded: make(map[string]string)}
+	mq := &mockDependerFactory{
+		mockFactoryWithConfig: mockFactoryWithConfig{typ: "mq", setupFunc: func(name string, dec Decoder) error {
+			return errors.New("setup failed")
+		}},
+		dependsOn: []string{"cache-default"},
+	}
+	Register("default", db)
+	Register("default", cache)
+	Register("default", mq)
+
+	oldCfg := mustConfig(t, "database:\n  default: {addr: db1}\n")
+	newCfg := mustConfig(t, "database:\n  default: {addr: db2}\ncache:\n  default: {addr: mem}\nmq:\n  default: {}\n")
+	if _, err := oldCfg.Reload(newCfg); err == nil {
+		t.Fatal("Expected reload error")
+	}
+	if cache.closed != 1 {
+		t.Errorf("Expected the new cache closed once, got %d", cache.closed)
+	}
+	if db.reloaded["default"] != "db1" {
+		t.Errorf("Expected database rolled back to db1, got %v", db.reloaded)
+	}
+}
@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// SetupReport is the report of the plugins set up by Config.Setup, used to find out
+// which plugins slow down the startup.
+type SetupReport struct {
+	// Plugins are in the setup order, i.e. the order the dependencies are resolved.
+	Plugins []PluginReport `json:"plugins"`
+	// Duration is the time taken by the whole setup, including validating the configs
+	// and notifying OnFinish.
+	Duration time.Duration `json:"duration"`
+}
+
+// PluginReport is the setup report of a plugin.
+type PluginReport struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Order is the 1-based position of the plugin in the setup order.
+	Order int `json:"order"`
+	// Duration is the time taken by Setup of the plugin.
+	Duration       time.Duration `json:"duration"`
+	Closer         bool          `json:"closer"`
+	FinishNotifier bool          `json:"finish_notifier"`
+}
+
+// Key returns the key of the plugin used by Depender, i.e. "type-name".
+func (r PluginReport) Key() string {
+	return r.Type + "-" + r.Name
+}
+
+// Report returns the setup report of the plugins.
+func (cs *Closables) Report() *SetupReport {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	r := &SetupReport{
+		Plugins:  make([]PluginReport, 0, len(cs.plugins)),
+		Duration: cs.duration,
+	}
+	for i := range cs.plugins {
+		p := &cs.plugins[i]
+		_, closer := p.asCloser()
+		_, notifier := p.factory.(FinishNotifier)
+		r.Plugins = append(r.Plugins, PluginReport{
+			Type:           p.typ,
+			Name:           p.name,
+			Order:          i + 1,
+			Duration:       p.duration,
+			Closer:         closer,
+			FinishNotifier: notifier,
+		})
+	}
+	return r
+}
+
+// Slowest returns at most n plugins which take the longest time to set up, the slowest first.
+func (r *SetupReport) Slowest(n int) []PluginReport {
+	ps := append([]PluginReport(nil), r.Plugins...)
+	sort.SliceStable(ps, func(i, j int) bool { return ps[i].Duration > ps[j].Duration })
+	if n >= 0 && n < len(ps) {
+		ps = ps[:n]
+	}
+	return ps
+}
+
+// WriteTo writes the report as a table to w, one plugin per line in the setup order.
+func (r *SetupReport) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ORDER\tPLUGIN\tDURATION\tCLOSER\tFINISH_NOTIFIER\n")
+	for _, p := range r.Plugins {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", p.Order, p.Key(), p.Duration.Round(time.Microsecond),
+			yesNo(p.Closer), yesNo(p.FinishNotifier))
+	}
+	_ = tw.Flush()
+	fmt.Fprintf(&b, "%d plugins set up in %s\n", len(r.Plugins), r.Duration.Round(time.Microsecond))
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// String returns the report as a table.
+func (r *SetupReport) String() string {
+	var b strings.Builder
+	_, _ = r.WriteTo(&b)
+	return b.String()
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+// WithReportWriter writes the startup summary table to w after all plugins are set up
+// by SetupClosablesWithReport.
+func WithReportWriter(w io.Writer) SetupOption {
+	return func(o *setupOptions) {
+		o.reportWriter = w
+	}
+}
+
+// WithReportLogf logs the startup summary table by logf after all plugins are set up
+// by SetupClosablesWithReport, e.g. log.Infof, so the plugin package does not depend
+// on any logger.
+func WithReportLogf(logf func(format string, args ...any)) SetupOption {
+	return func(o *setupOptions) {
+		o.reportLogf = logf
+	}
+}
+
+// SetupClosablesWithReport loads plugins like SetupClosables and returns the setup
+// report too, which lists each plugin with its setup duration and order, helping
+// diagnose slow startups. The report is not returned if the setup fails.
+func (c Config) SetupClosablesWithReport(opts ...SetupOption) (close func() error, report *SetupReport, err error) {
+	o := newSetupOptions(opts)
+	cs, err := c.Setup(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	report = cs.Report()
+	if o.reportWriter != nil {
+		_, _ = report.WriteTo(o.reportWriter)
+	}
+	if o.reportLogf != nil {
+		o.reportLogf("plugin setup report:\n%s", report)
+	}
+	return cs.Close, report, nil
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestSetupClosablesWithReport tests that the report lists the plugins in the setup
+// order with their durations and the interfaces implemented.
+func TestSetupClosablesWithReport(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		t.Run(fmt.Sprintf("concurrency=%d", concurrency), func(t *testing.T) {
+			Reset()
+			old := SetupConcurrency
+			SetupConcurrency = concurrency
+			defer func() { SetupConcurrency = old }()
+
+			Register("slow", &mockCloserFactory{mockFactoryWithConfig: mockFactoryWithConfig{
+				typ: "db",
+				setupFunc: func(string, Decoder) error {
+					time.Sleep(20 * time.Millisecond)
+					return nil
+				},
+			}})
+			Register("app", &mockDependerFactory{
+				mockFactoryWithConfig: mockFactoryWithConfig{typ: "svc"},
+				dependsOn:             []string{"db-slow"},
+			})
+			Register("done", &mockFinishNotifierFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "log"}})
+
+			config := Config{
+				"db":  {"slow": yaml.Node{}},
+				"svc": {"app": yaml.Node{}},
+				"log": {"done": yaml.Node{}},
+			}
+			var (
+				w      strings.Builder
+				logged string
+			)
+			closeFunc, report, err := config.SetupClosablesWithReport(
+				WithReportWriter(&w),
+				WithReportLogf(func(format string, args ...any) { logged = fmt.Sprintf(format, args...) }),
+			)
+			if err != nil {
+				t.Fatalf("SetupClosablesWithReport failed: %v", err)
+			}
+			defer closeFunc()
+
+			if len(report.Plugins) != 3 {
+				t.Fatalf("Expected 3 plugins, got %+v", report.Plugins)
+			}
+			pos := make(map[string]PluginReport)
+			for i, p := range report.Plugins {
+				if p.Order != i+1 {
+					t.Errorf("Expected order %d, got %d", i+1, p.Order)
+				}
+				pos[p.Key()] = p
+			}
+			slow, app, done := pos["db-slow"], pos["svc-app"], pos["log-done"]
+			if slow.Order > app.Order {
+				t.Errorf("Expected db-slow set up before svc-app, got %+v", report.Plugins)
+			}
+			if slow.Duration < 20*time.Millisecond {
+				t.Errorf("Expected db-slow duration >= 20ms, got %v", slow.Duration)
+			}
+			if report.Duration < slow.Duration {
+				t.Errorf("Expected total duration >= %v, got %v", slow.Duration, report.Duration)
+			}
+			if !slow.Closer || slow.FinishNotifier || app.Closer || !done.FinishNotifier {
+				t.Errorf("Unexpected interfaces in %+v", report.Plugins)
+			}
+			if got := report.Slowest(1); len(got) != 1 || got[0].Key() != "db-slow" {
+				t.Errorf("Slowest(1) = %+v", got)
+			}
+
+			table := report.String()
+			for _, want := range []string{"ORDER", "db-slow", "svc-app", "log-done", "3 plugins set up in"} {
+				if !strings.Contains(table, want) {
+					t.Errorf("Expected %q in the table:\n%s", want, table)
+				}
+			}
+			if w.String() != table {
+				t.Errorf("Expected the table written, got:\n%s", w.String())
+			}
+			if !strings.Contains(logged, table) {
+				t.Errorf("Expected the table logged, got:\n%s", logged)
+			}
+		})
+	}
+}
+
+// TestSetupClosablesWithReportError tests that no report is returned if the setup fails.
+func TestSetupClosablesWithReportError(t *testing.T) {
+	Reset()
+	Register("bad", &mockFactoryWithConfig{
+		typ:       "log",
+		setupFunc: func(string, Decoder) error { return errors.New("boom") },
+	})
+
+	var w strings.Builder
+	closeFunc, report, err := Config{"log": {"bad": yaml.Node{}}}.SetupClosablesWithReport(WithReportWriter(&w))
+	if err == nil || closeFunc != nil || report != nil {
+		t.Fatalf("Expected error only, got %v, %v", report, err)
+	}
+	if w.Len() != 0 {
+		t.Errorf("Expected nothing written, got %q", w.String())
+	}
+}
@@ -1,11 +1,17 @@
 package plugin
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/retry"
 )
 
 var (
@@ -14,53 +20,135 @@ var (
 
 	// MaxPluginSize is the max number of plugins.
 	MaxPluginSize = 1000
+
+	// SetupConcurrency is the max number of plugins set up at the same time, the
+	// plugins whose dependencies are all set up are set up in parallel if it is
+	// greater than 1. Default as 1, all plugins are set up one by one.
+	SetupConcurrency = 1
+
+	// SetupAttempts is the max number of attempts to set up each plugin including the
+	// first one, e.g. for the plugins connecting to services which may start later.
+	// Default as 1, a failed setup is not retried. A timed out setup is never retried
+	// since it may be still running.
+	SetupAttempts = 1
+
+	// SetupRetryDelay is the wait before the first setup retry, doubled every retry
+	// and capped by 10 times of it.
+	SetupRetryDelay = time.Second
 )
 
+// errTimeout is the error of a timed out setup.
+var errTimeout = errors.New("timeout")
+
+// TimeoutConfigurer is the interface used to override SetupTimeout of a plugin,
+// e.g. for the plugins connecting to remote services slowly.
+type TimeoutConfigurer interface {
+	// SetupTimeout returns the setup timeout of the plugin, SetupTimeout is used if not positive.
+	SetupTimeout() time.Duration
+}
+
+// SetupRetryConfigurer is the interface used to override SetupAttempts of a plugin,
+// e.g. to retry only the plugins connecting to remote services.
+type SetupRetryConfigurer interface {
+	// SetupAttempts returns the max setup attempts of the plugin, SetupAttempts is used if not positive.
+	SetupAttempts() int
+}
+
 // Config is the configuration of all plugins. plugin type => { plugin name => plugin config }
 type Config map[string]map[string]yaml.Node
 
+// SetupOption is the option of setting up, validating and reloading the plugins.
+type SetupOption func(*setupOptions)
+
+type setupOptions struct {
+	registry     *Registry
+	reportWriter io.Writer
+	reportLogf   func(format string, args ...any)
+}
+
+func newSetupOptions(opts []SetupOption) *setupOptions {
+	o := &setupOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.registry == nil {
+		o.registry = DefaultRegistry
+	}
+	return o
+}
+
+// WithRegistry looks up the factories of the plugins in r instead of DefaultRegistry.
+func WithRegistry(r *Registry) SetupOption {
+	return func(o *setupOptions) {
+		o.registry = r
+	}
+}
+
 // SetupClosables loads plugins and returns a function to close them in reverse order.
-func (c Config) SetupClosables() (close func() error, err error) {
-	plugins, status, err := c.loadPlugins()
+// The configs of all plugins are validated before any plugin is set up. Use Setup to
+// close the plugins in parallel with deadlines.
+func (c Config) SetupClosables(opts ...SetupOption) (close func() error, err error) {
+	cs, err := c.Setup(opts...)
 	if err != nil {
 		return nil, err
 	}
+	return cs.Close, nil
+}
+
+// Validate checks that all plugins are registered and validates the configs of the
+// plugins implementing Validator, the errors of all plugins are joined into one error.
+// The disabled plugins are validated too, so they can be enabled safely.
+func (c Config) Validate(opts ...SetupOption) error {
+	return c.validate(newSetupOptions(opts).registry)
+}
 
-	pluginInfos, closes, err := c.setupPlugins(plugins, status)
+func (c Config) validate(r *Registry) error {
+	enabled, disabled, err := c.split()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return validatePlugins(append(enabled.infos(r), disabled.infos(r)...))
+}
 
-	if err := c.onFinish(pluginInfos); err != nil {
-		return nil, err
+// infos returns the information of all plugins configured.
+func (c Config) infos(r *Registry) []pluginInfo {
+	var ps []pluginInfo
+	for typ, factories := range c {
+		for name, cfg := range factories {
+			ps = append(ps, pluginInfo{factory: r.Get(typ, name), typ: typ, name: name, cfg: cfg, registry: r})
+		}
 	}
+	return ps
+}
 
-	return func() error {
-		for i := len(closes) - 1; i >= 0; i-- {
-			if err := closes[i](); err != nil {
-				return err
-			}
+func validatePlugins(ps []pluginInfo) error {
+	sortPlugins(ps)
+	var errs []error
+	for i := range ps {
+		if err := ps[i].validate(); err != nil {
+			errs = append(errs, err)
 		}
-		return nil
-	}, nil
+	}
+	return errors.Join(errs...)
 }
 
-func (c Config) loadPlugins() (chan pluginInfo, map[string]bool, error) {
+func (c Config) loadPlugins(r *Registry) (chan pluginInfo, map[string]bool, error) {
 	var (
 		plugins = make(chan pluginInfo, MaxPluginSize)
 		status  = make(map[string]bool)
 	)
 	for typ, factories := range c {
 		for name, cfg := range factories {
-			factory := Get(typ, name)
+			factory := r.Get(typ, name)
 			if factory == nil {
 				return nil, nil, fmt.Errorf("plugin %s:%s no registered or imported, do not configure", typ, name)
 			}
 			p := pluginInfo{
-				factory: factory,
-				typ:     typ,
-				name:    name,
-				cfg:     cfg,
+				factory:  factory,
+				typ:      typ,
+				name:     name,
+				cfg:      cfg,
+				registry: r,
 			}
 			select {
 			case plugins <- p:
@@ -73,36 +161,134 @@ func (c Config) loadPlugins() (chan pluginInfo, map[string]bool, error) {
 	return plugins, status, nil
 }
 
-func (c Config) setupPlugins(plugins chan pluginInfo, status map[string]bool) ([]pluginInfo, []func() error, error) {
+// setupPlugins sets up the plugins in dependency order and returns them in the order
+// they are set up. On error, the plugins set up before the error are returned with it.
+func (c Config) setupPlugins(plugins chan pluginInfo, status map[string]bool) ([]pluginInfo, error) {
+	if SetupConcurrency > 1 {
+		return c.setupPluginsParallel(plugins, status, SetupConcurrency)
+	}
 	var (
 		result []pluginInfo
-		closes []func() error
 		num    = len(plugins)
 	)
 	for num > 0 {
 		for i := 0; i < num; i++ {
 			p := <-plugins
 			if deps, err := p.hasDependence(status); err != nil {
-				return nil, nil, err
+				return result, err
 			} else if deps {
 				plugins <- p
 				continue
 			}
 			if err := p.setup(); err != nil {
-				return nil, nil, err
-			}
-			if closer, ok := p.asCloser(); ok {
-				closes = append(closes, closer.Close)
+				return result, err
 			}
 			status[p.key()] = true
 			result = append(result, p)
 		}
 		if len(plugins) == num {
-			return nil, nil, fmt.Errorf("cycle depends, not plugin is setup")
+			return result, cycleError(plugins, status)
 		}
 		num = len(plugins)
 	}
-	return result, closes, nil
+	return result, nil
+}
+
+// setupPluginsParallel sets up the plugins whose dependencies are all set up with at
+// most workers goroutines, the plugins are returned in the order they are set up.
+func (c Config) setupPluginsParallel(plugins chan pluginInfo, status map[string]bool, workers int) ([]pluginInfo, error) {
+	type setupResult struct {
+		p   pluginInfo
+		err error
+	}
+	var (
+		result   []pluginInfo
+		pending  []pluginInfo
+		running  int
+		done     = make(chan setupResult, len(plugins))
+		setupErr error
+	)
+	for len(plugins) > 0 {
+		pending = append(pending, <-plugins)
+	}
+	for len(pending) > 0 || running > 0 {
+		// dispatch the ready plugins, stop dispatching once an error occurs.
+		waiting := pending[:0]
+		for _, p := range pending {
+			if setupErr != nil || running >= workers {
+				waiting = append(waiting, p)
+				continue
+			}
+			deps, err := p.hasDependence(status)
+			if err != nil {
+				setupErr = err
+				waiting = append(waiting, p)
+				continue
+			}
+			if deps {
+				waiting = append(waiting, p)
+				continue
+			}
+			running++
+			go func(p pluginInfo) {
+				err := p.setup()
+				done <- setupResult{p: p, err: err}
+			}(p)
+		}
+		pending = waiting
+
+		if running == 0 {
+			if setupErr != nil {
+				return result, setupErr
+			}
+			left := make(chan pluginInfo, len(pending))
+			for _, p := range pending {
+				left <- p
+			}
+			return result, cycleError(left, status)
+		}
+
+		r := <-done
+		running--
+		if r.err != nil {
+			if setupErr == nil {
+				setupErr = r.err
+			}
+			continue
+		}
+		status[r.p.key()] = true
+		result = append(result, r.p)
+	}
+	if setupErr != nil {
+		return result, setupErr
+	}
+	return result, nil
+}
+
+// cycleError reports a cycle of the plugins left in the channel, which all wait for
+// dependencies not set up.
+func cycleError(plugins chan pluginInfo, status map[string]bool) error {
+	var (
+		nodes []string
+		deps  = make(map[string][]string)
+	)
+	for len(plugins) > 0 {
+		p := <-plugins
+		nodes = append(nodes, p.key())
+		if d, ok := p.factory.(Depender); ok {
+			deps[p.key()] = append(deps[p.key()], d.DependsOn()...)
+		}
+		if fd, ok := p.factory.(FlexDepender); ok {
+			for _, dep := range fd.FlexDependsOn() {
+				if _, ok := status[dep]; ok {
+					deps[p.key()] = append(deps[p.key()], dep)
+				}
+			}
+		}
+	}
+	sort.Strings(nodes)
+	cycle := findCycle(nodes, deps, status)
+	return fmt.Errorf("cycle depends, not plugin is setup: %s", strings.Join(cycle, " -> "))
 }
 
 func (c Config) onFinish(plugins []pluginInfo) error {
@@ -120,6 +306,10 @@ type pluginInfo struct {
 	typ     string
 	name    string
 	cfg     yaml.Node
+	// duration is the time taken by Setup of the plugin.
+	duration time.Duration
+	// registry is the registry the plugin is set up with, DefaultRegistry if nil.
+	registry *Registry
 }
 
 // hasDependence decides if any other plugins that this plugin depends on haven't been initialized.
@@ -161,7 +351,7 @@ func (p *pluginInfo) checkDependence(status map[string]bool, dependences []strin
 			if flexible {
 				continue
 			}
-			return false, fmt.Errorf("depends plugin %s not exists", name)
+			return false, fmt.Errorf("depends plugin %s not exists or disabled", name)
 		}
 		if !setup {
 			return true, nil
@@ -171,21 +361,72 @@ func (p *pluginInfo) checkDependence(status map[string]bool, dependences []strin
 }
 
 func (p *pluginInfo) setup() error {
+	notify(func(l EventListener) { l.OnSetupStart(p.typ, p.name) })
+	start := time.Now()
+	err := retry.Do(context.Background(), func(context.Context) error {
+		return p.run("setup", func() error {
+			if err := p.inject(); err != nil {
+				return err
+			}
+			return p.factory.Setup(p.name, p.decoder())
+		})
+	},
+		retry.Attempts(p.attempts()),
+		retry.ExponentialBackoff(SetupRetryDelay, 10*SetupRetryDelay),
+		retry.Jitter(),
+		retry.RetryIf(func(err error) bool { return !errors.Is(err, errTimeout) }),
+		retry.OnRetry(func(int, error, time.Duration) {
+			// the instances are registered again by Setup
+			p.registryOrDefault().deregisterInstances(p.key())
+		}),
+	)
+	if err != nil {
+		notify(func(l EventListener) { l.OnSetupError(p.typ, p.name, err) })
+		return err
+	}
+	d := time.Since(start)
+	p.duration = d
+	notify(func(l EventListener) { l.OnSetupDone(p.typ, p.name, d) })
+	return nil
+}
+
+// timeout returns the setup timeout of the plugin.
+func (p *pluginInfo) timeout() time.Duration {
+	if tc, ok := p.factory.(TimeoutConfigurer); ok {
+		if d := tc.SetupTimeout(); d > 0 {
+			return d
+		}
+	}
+	return SetupTimeout
+}
+
+// attempts returns the max setup attempts of the plugin.
+func (p *pluginInfo) attempts() int {
+	if rc, ok := p.factory.(SetupRetryConfigurer); ok {
+		if n := rc.SetupAttempts(); n > 0 {
+			return n
+		}
+	}
+	return max(SetupAttempts, 1)
+}
+
+// run calls fn with the setup timeout of the plugin.
+func (p *pluginInfo) run(action string, fn func() error) error {
 	var (
 		ch  = make(chan struct{})
 		err error
 	)
 	go func() {
-		err = p.factory.Setup(p.name, &YamlNodeDecoder{Node: &p.cfg})
+		err = fn()
 		close(ch)
 	}()
 	select {
 	case <-ch:
-	case <-time.After(SetupTimeout):
-		return fmt.Errorf("setup plugin %s timeout", p.key())
+	case <-time.After(p.timeout()):
+		return fmt.Errorf("%s plugin %s %w", action, p.key(), errTimeout)
 	}
 	if err != nil {
-		return fmt.Errorf("setup plugin %s error: %v", p.key(), err)
+		return fmt.Errorf("%s plugin %s error: %v", action, p.key(), err)
 	}
 	return nil
 }
@@ -202,11 +443,43 @@ func (p *pluginInfo) onFinish() error {
 	return f.OnFinish(p.name)
 }
 
+// Validator is the interface used to validate the configuration of a plugin before
+// any plugin is set up, so a misconfigured plugin does not leave the others half set up.
+type Validator interface {
+	Validate(dec Decoder) error
+}
+
+func (p *pluginInfo) validate() error {
+	if p.factory == nil {
+		return fmt.Errorf("plugin %s:%s no registered or imported, do not configure", p.typ, p.name)
+	}
+	v, ok := p.factory.(Validator)
+	if !ok {
+		return nil
+	}
+	if err := v.Validate(&YamlNodeDecoder{Node: &p.cfg}); err != nil {
+		return fmt.Errorf("validate plugin %s error: %w", p.key(), err)
+	}
+	return nil
+}
+
 // FinishNotifier is the interface used to notify that all plugins' loading has been done.
 type FinishNotifier interface {
 	OnFinish(name string) error
 }
 
+// close closes the plugin and notifies the listeners.
+func (p *pluginInfo) close() error {
+	closer, ok := p.asCloser()
+	if !ok {
+		return nil
+	}
+	err := closer.Close()
+	p.registryOrDefault().deregisterInstances(p.key())
+	notify(func(l EventListener) { l.OnClose(p.typ, p.name, err) })
+	return err
+}
+
 func (p *pluginInfo) asCloser() (Closer, bool) {
 	closer, ok := p.factory.(Closer)
 	return closer, ok
@@ -220,6 +493,9 @@ type Closer interface {
 // YamlNodeDecoder is a decoder for a yaml.Node of the yaml config file.
 type YamlNodeDecoder struct {
 	Node *yaml.Node
+
+	registry *Registry // the registry of the plugin decoding the config, see RegisterInstanceOf
+	owner    string    // the key of the plugin
 }
 
 // Decode decodes a yaml.Node of the yaml config file.
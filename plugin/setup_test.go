@@ -2,8 +2,11 @@ package plugin
 
 import (
 	"errors"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -69,7 +72,7 @@ func (m *mockFlexDependerFactory) FlexDependsOn() []string {
 
 // TestSetupClosablesBasic tests basic plugin setup.
 func TestSetupClosablesBasic(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	factory := &mockFactoryWithConfig{typ: "log"}
 	Register("default", factory)
@@ -96,7 +99,7 @@ func TestSetupClosablesBasic(t *testing.T) {
 
 // TestSetupClosablesNotRegistered tests error when plugin is not registered.
 func TestSetupClosablesNotRegistered(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	config := Config{
 		"log": {
@@ -112,7 +115,7 @@ func TestSetupClosablesNotRegistered(t *testing.T) {
 
 // TestSetupClosablesWithConfig tests that config is properly decoded.
 func TestSetupClosablesWithConfig(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	var receivedConfig map[string]any
 	factory := &mockFactoryWithConfig{
@@ -148,7 +151,7 @@ output: console`
 
 // TestSetupClosablesWithCloser tests that Closer interface is properly handled.
 func TestSetupClosablesWithCloser(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	factory := &mockCloserFactory{
 		mockFactoryWithConfig: mockFactoryWithConfig{typ: "log"},
@@ -183,7 +186,7 @@ func TestSetupClosablesWithCloser(t *testing.T) {
 
 // TestSetupClosablesWithFinishNotifier tests that FinishNotifier is properly called.
 func TestSetupClosablesWithFinishNotifier(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	factory := &mockFinishNotifierFactory{
 		mockFactoryWithConfig: mockFactoryWithConfig{typ: "log"},
@@ -208,7 +211,7 @@ func TestSetupClosablesWithFinishNotifier(t *testing.T) {
 
 // TestSetupClosablesWithDepender tests strong dependency between plugins.
 func TestSetupClosablesWithDepender(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	var callOrder []string
 	mu := sync.Mutex{}
@@ -263,7 +266,7 @@ func TestSetupClosablesWithDepender(t *testing.T) {
 
 // TestSetupClosablesWithFlexDepender tests weak dependency between plugins.
 func TestSetupClosablesWithFlexDepender(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	var callOrder []string
 	mu := sync.Mutex{}
@@ -313,7 +316,7 @@ func TestSetupClosablesWithFlexDepender(t *testing.T) {
 
 // TestSetupClosablesCycleDependence tests cycle dependency detection.
 func TestSetupClosablesCycleDependence(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	factoryA := &mockDependerFactory{
 		mockFactoryWithConfig: mockFactoryWithConfig{typ: "log"},
@@ -342,7 +345,7 @@ func TestSetupClosablesCycleDependence(t *testing.T) {
 
 // TestSetupClosablesSelfDependence tests self-dependency detection.
 func TestSetupClosablesSelfDependence(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	factory := &mockDependerFactory{
 		mockFactoryWithConfig: mockFactoryWithConfig{typ: "log"},
@@ -423,7 +426,7 @@ func TestPluginInfoKey(t *testing.T) {
 
 // TestMultiplePlugins tests setup of multiple plugins.
 func TestMultiplePlugins(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	setupCalls := &sync.Map{}
 
@@ -465,7 +468,7 @@ func TestMultiplePlugins(t *testing.T) {
 
 // TestSetupErrorPropagation tests that setup errors are propagated.
 func TestSetupErrorPropagation(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	factory := &mockFactoryWithConfig{
 		typ: "log",
@@ -489,7 +492,7 @@ func TestSetupErrorPropagation(t *testing.T) {
 
 // TestEmptyConfig tests setup with empty config.
 func TestEmptyConfig(t *testing.T) {
-	plugins = make(map[string]map[string]Factory)
+	Reset()
 
 	config := Config{}
 
@@ -505,3 +508,338 @@ func TestEmptyConfig(t *testing.T) {
 		t.Fatalf("Close failed: %v", err)
 	}
 }
+
+// mockTimeoutFactory is a mock factory that implements TimeoutConfigurer interface.
+type mockTimeoutFactory struct {
+	mockFactoryWithConfig
+	timeout time.Duration
+}
+
+func (m *mockTimeoutFactory) SetupTimeout() time.Duration {
+	return m.timeout
+}
+
+// TestSetupTimeoutOverride tests the per-plugin setup timeout.
+func TestSetupTimeoutOverride(t *testing.T) {
+	Reset()
+	oldTimeout := SetupTimeout
+	SetupTimeout = 20 * time.Millisecond
+	defer func() { SetupTimeout = oldTimeout }()
+
+	slow := func(string, Decoder) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+	Register("slow", &mockFactoryWithConfig{typ: "log", setupFunc: slow})
+	Register("patient", &mockTimeoutFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "log", setupFunc: slow}, timeout: time.Second})
+
+	_, err := Config{"log": {"slow": yaml.Node{}}}.SetupClosables()
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	if _, err := (Config{"log": {"patient": yaml.Node{}}}).SetupClosables(); err != nil {
+		t.Errorf("SetupClosables failed: %v", err)
+	}
+}
+
+// mockRetryFactory is a mock factory that implements SetupRetryConfigurer interface.
+type mockRetryFactory struct {
+	mockFactoryWithConfig
+	attempts int
+}
+
+func (m *mockRetryFactory) SetupAttempts() int {
+	return m.attempts
+}
+
+// TestSetupRetry tests retrying the failed setups, but not the timed out ones.
+func TestSetupRetry(t *testing.T) {
+	Reset()
+	oldAttempts, oldDelay, oldTimeout := SetupAttempts, SetupRetryDelay, SetupTimeout
+	SetupAttempts, SetupRetryDelay, SetupTimeout = 3, time.Millisecond, 20*time.Millisecond
+	defer func() { SetupAttempts, SetupRetryDelay, SetupTimeout = oldAttempts, oldDelay, oldTimeout }()
+
+	var calls atomic.Int32
+	failing := func(times int) func(string, Decoder) error {
+		return func(string, Decoder) error {
+			if calls.Add(1) <= int32(times) {
+				return errors.New("connection refused")
+			}
+			return nil
+		}
+	}
+	Register("default", &mockFactoryWithConfig{typ: "db", setupFunc: failing(2)})
+	if _, err := (Config{"db": {"default": yaml.Node{}}}).SetupClosables(); err != nil || calls.Load() != 3 {
+		t.Errorf("Expected success after 2 retries, got %v and %d calls", err, calls.Load())
+	}
+
+	calls.Store(0)
+	Register("default", &mockFactoryWithConfig{typ: "db", setupFunc: failing(3)})
+	if _, err := (Config{"db": {"default": yaml.Node{}}}).SetupClosables(); err == nil || calls.Load() != 3 {
+		t.Errorf("Expected error after the attempts exhausted, got %v and %d calls", err, calls.Load())
+	}
+
+	calls.Store(0)
+	Register("default", &mockRetryFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "db", setupFunc: failing(3)}, attempts: 4})
+	if _, err := (Config{"db": {"default": yaml.Node{}}}).SetupClosables(); err != nil || calls.Load() != 4 {
+		t.Errorf("Expected the attempts of the plugin, got %v and %d calls", err, calls.Load())
+	}
+
+	calls.Store(0)
+	Register("default", &mockFactoryWithConfig{typ: "db", setupFunc: func(string, Decoder) error {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}})
+	if _, err := (Config{"db": {"default": yaml.Node{}}}).SetupClosables(); err == nil || calls.Load() != 1 {
+		t.Errorf("Expected timeout without retry, got %v and %d calls", err, calls.Load())
+	}
+}
+
+// TestSetupParallel tests setting up independent plugins in parallel while keeping the dependencies in order.
+func TestSetupParallel(t *testing.T) {
+	Reset()
+	oldConcurrency := SetupConcurrency
+	SetupConcurrency = 4
+	defer func() { SetupConcurrency = oldConcurrency }()
+
+	var (
+		mu   sync.Mutex
+		done = make(map[string]bool)
+	)
+	setup := func(name string, _ Decoder) error {
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		done[name] = true
+		mu.Unlock()
+		return nil
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		Register(name, &mockCloserFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "cache", setupFunc: setup}})
+	}
+	Register("main", &mockDependerFactory{
+		mockFactoryWithConfig: mockFactoryWithConfig{typ: "server", setupFunc: func(string, Decoder) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(done) != 4 {
+				return errors.New("dependencies not set up")
+			}
+			return nil
+		}},
+		dependsOn: []string{"cache-a", "cache-b", "cache-c", "cache-d"},
+	})
+
+	config := Config{
+		"cache":  {"a": yaml.Node{}, "b": yaml.Node{}, "c": yaml.Node{}, "d": yaml.Node{}},
+		"server": {"main": yaml.Node{}},
+	}
+	start := time.Now()
+	closeFunc, err := config.SetupClosables()
+	if err != nil {
+		t.Fatalf("SetupClosables failed: %v", err)
+	}
+	if d := time.Since(start); d >= 150*time.Millisecond {
+		t.Errorf("Expected parallel setup, took %v", d)
+	}
+	if err := closeFunc(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	// the errors and the cycles are reported as in sequential setup.
+	Register("broken", &mockFactoryWithConfig{typ: "cache", setupFunc: func(string, Decoder) error {
+		return errors.New("setup failed")
+	}})
+	if _, err := (Config{"cache": {"a": yaml.Node{}, "broken": yaml.Node{}}}).SetupClosables(); err == nil {
+		t.Error("Expected setup error")
+	}
+	Register("x", &mockDependerFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "cycle"}, dependsOn: []string{"cycle-y"}})
+	Register("y", &mockDependerFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "cycle"}, dependsOn: []string{"cycle-x"}})
+	_, err = Config{"cycle": {"x": yaml.Node{}, "y": yaml.Node{}}}.SetupClosables()
+	if err == nil || !strings.Contains(err.Error(), "cycle depends") {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+}
+
+// mockValidatorFactory is a mock factory that implements Validator interface.
+type mockValidatorFactory struct {
+	mockFactoryWithConfig
+	validateFunc func(dec Decoder) error
+}
+
+func (m *mockValidatorFactory) Validate(dec Decoder) error {
+	return m.validateFunc(dec)
+}
+
+// TestSetupClosablesValidate tests that all configs are validated before any plugin is set up.
+func TestSetupClosablesValidate(t *testing.T) {
+	Reset()
+
+	var setups int
+	requireHost := func(dec Decoder) error {
+		var cfg struct {
+			Host string `yaml:"host"`
+		}
+		if err := dec.Decode(&cfg); err != nil {
+			return err
+		}
+		if cfg.Host == "" {
+			return errors.New("host required")
+		}
+		return nil
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		Register(name, &mockValidatorFactory{
+			mockFactoryWithConfig: mockFactoryWithConfig{typ: "db", setupFunc: func(string, Decoder) error {
+				setups++
+				return nil
+			}},
+			validateFunc: requireHost,
+		})
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(`
+db:
+  a: {host: localhost}
+  b: {port: 3306}
+  c: {}
+`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	_, err := cfg.SetupClosables()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if setups != 0 {
+		t.Errorf("Expected no plugin set up, got %d", setups)
+	}
+	for _, want := range []string{"validate plugin db-b error: host required", "validate plugin db-c error: host required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "db-a") {
+		t.Errorf("error %q should not report the valid plugin", err)
+	}
+
+	// the new plugins of reload are validated too.
+	current := Config{"db": {"a": cfg["db"]["a"]}}
+	if _, err := current.Reload(cfg); err == nil || setups != 0 {
+		t.Errorf("Expected reload validation error without setup, got %v and %d setups", err, setups)
+	}
+}
+
+// TestSetupDisabled tests that the disabled plugins are validated but not set up, and
+// toggled by reload.
+func TestSetupDisabled(t *testing.T) {
+	Reset()
+
+	var (
+		setups  []string
+		configs = make(map[string]map[string]any)
+		closes  []string
+	)
+	newFactory := func(name string) *mockValidatorFactory {
+		return &mockValidatorFactory{
+			mockFactoryWithConfig: mockFactoryWithConfig{typ: "db", setupFunc: func(n string, dec Decoder) error {
+				var cfg map[string]any
+				if err := dec.Decode(&cfg); err != nil {
+					return err
+				}
+				setups = append(setups, n)
+				configs[n] = cfg
+				return nil
+			}},
+			validateFunc: func(dec Decoder) error {
+				var cfg map[string]any
+				if err := dec.Decode(&cfg); err != nil {
+					return err
+				}
+				if cfg["host"] == nil {
+					return errors.New("host required")
+				}
+				return nil
+			},
+		}
+	}
+	for _, name := range []string{"a", "b"} {
+		Register(name, &closeRecorder{mockValidatorFactory: newFactory(name), closes: &closes, name: name})
+	}
+	Register("c", &mockFlexDependerFactory{
+		mockFactoryWithConfig: mockFactoryWithConfig{typ: "server"},
+		flexDependsOn:         []string{"db-b"},
+	})
+
+	parse := func(s string) Config {
+		var cfg Config
+		if err := yaml.Unmarshal([]byte(s), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	// the disabled config is validated.
+	if _, err := parse(`
+db:
+  a: {host: a}
+  b: {disabled: true}
+`).Setup(); err == nil || !strings.Contains(err.Error(), "db-b") {
+		t.Errorf("Expected validation error of the disabled plugin, got %v", err)
+	}
+	if _, err := parse(`
+db:
+  a: {host: a, disabled: maybe}
+`).Setup(); err == nil {
+		t.Error("Expected error for the disabled value not bool")
+	}
+
+	cfg := parse(`
+db:
+  a: {host: a, disabled: false, clients: {disabled: {x: 1}}}
+  b: {host: b, disabled: true}
+server:
+  c: {}
+`)
+	if _, err := cfg.Setup(); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if len(setups) != 1 || setups[0] != "a" {
+		t.Fatalf("Expected only db-a set up, got %v", setups)
+	}
+	if _, ok := configs["a"]["disabled"]; ok {
+		t.Errorf("Expected the disabled key removed, got %v", configs["a"])
+	}
+	if clients, _ := configs["a"]["clients"].(map[string]any); clients["disabled"] == nil {
+		t.Errorf("Expected the nested config named disabled kept, got %v", configs["a"])
+	}
+	if g, err := cfg.DependencyGraph(); err != nil || len(g.Nodes) != 2 {
+		t.Errorf("DependencyGraph = %+v, %v", g, err)
+	}
+
+	// enabling b sets it up, disabling a closes it.
+	newCfg := parse(`
+db:
+  a: {host: a, disabled: true, clients: {disabled: {x: 1}}}
+  b: {host: b}
+server:
+  c: {}
+`)
+	if _, err := cfg.Reload(newCfg); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(setups) != 2 || setups[1] != "b" || len(closes) != 1 || closes[0] != "a" {
+		t.Errorf("Expected b set up and a closed, got setups %v, closes %v", setups, closes)
+	}
+}
+
+// closeRecorder records the closes of a validator factory.
+type closeRecorder struct {
+	*mockValidatorFactory
+	closes *[]string
+	name   string
+}
+
+func (r *closeRecorder) Close() error {
+	*r.closes = append(*r.closes, r.name)
+	return nil
+}
@@ -0,0 +1,155 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// The config formats of NewConfigFromBytes.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+// ConfigSource is the config block of a plugin in any format. The blocks are decoded
+// by the yaml tags of v whatever the format, so the same config struct of a plugin
+// serves all the formats.
+type ConfigSource interface {
+	// UnmarshalTo decodes the config block into v.
+	UnmarshalTo(v any) error
+}
+
+// YAMLSource is the config block of a yaml.Node.
+type YAMLSource struct {
+	Node *yaml.Node
+}
+
+// UnmarshalTo implements ConfigSource.
+func (s YAMLSource) UnmarshalTo(v any) error {
+	return (&YamlNodeDecoder{Node: s.Node}).Decode(v)
+}
+
+// JSONSource is the config block of a JSON value.
+type JSONSource []byte
+
+// UnmarshalTo implements ConfigSource.
+func (s JSONSource) UnmarshalTo(v any) error {
+	dec := json.NewDecoder(bytes.NewReader(s))
+	dec.UseNumber() // keep the integers exact
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("decode json config error: %w", err)
+	}
+	return unmarshalValue(jsonNumbers(value), v)
+}
+
+// jsonNumbers replaces the json.Number in value by int64 or float64, which are
+// encoded as yaml numbers rather than strings.
+func jsonNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = jsonNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = jsonNumbers(e)
+		}
+	}
+	return value
+}
+
+// TOMLSource is the config block of a TOML document, whose top level keys are the
+// keys of the block.
+type TOMLSource []byte
+
+// UnmarshalTo implements ConfigSource.
+func (s TOMLSource) UnmarshalTo(v any) error {
+	var value map[string]any
+	if err := toml.Unmarshal(s, &value); err != nil {
+		return fmt.Errorf("decode toml config error: %w", err)
+	}
+	return unmarshalValue(value, v)
+}
+
+// unmarshalValue decodes the value parsed from any format into v through a yaml.Node.
+func unmarshalValue(value, v any) error {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Errorf("encode config error: %w", err)
+	}
+	return node.Decode(v)
+}
+
+// NewConfigFromSources creates the config of the plugin blocks in any format.
+// plugin type => { plugin name => config block }
+func NewConfigFromSources(sources map[string]map[string]ConfigSource) (Config, error) {
+	cfg := make(Config, len(sources))
+	for typ, factories := range sources {
+		cfg[typ] = make(map[string]yaml.Node, len(factories))
+		for name, src := range factories {
+			var node yaml.Node
+			if err := src.UnmarshalTo(&node); err != nil {
+				return nil, fmt.Errorf("plugin %s-%s config: %w", typ, name, err)
+			}
+			cfg[typ][name] = node
+		}
+	}
+	return cfg, nil
+}
+
+// NewConfigFromBytes creates the config of the plugin configs in yaml, json or toml,
+// whose top level is the plugin types like Config.
+func NewConfigFromBytes(format string, data []byte) (Config, error) {
+	switch format {
+	case FormatYAML:
+		var cfg Config
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("decode yaml config error: %w", err)
+		}
+		return cfg, nil
+	case FormatJSON:
+		var blocks map[string]map[string]json.RawMessage
+		if err := json.Unmarshal(data, &blocks); err != nil {
+			return nil, fmt.Errorf("decode json config error: %w", err)
+		}
+		sources := make(map[string]map[string]ConfigSource, len(blocks))
+		for typ, factories := range blocks {
+			sources[typ] = make(map[string]ConfigSource, len(factories))
+			for name, raw := range factories {
+				sources[typ][name] = JSONSource(raw)
+			}
+		}
+		return NewConfigFromSources(sources)
+	case FormatTOML:
+		var blocks map[string]map[string]any
+		if err := toml.Unmarshal(data, &blocks); err != nil {
+			return nil, fmt.Errorf("decode toml config error: %w", err)
+		}
+		cfg := make(Config, len(blocks))
+		for typ, factories := range blocks {
+			cfg[typ] = make(map[string]yaml.Node, len(factories))
+			for name, value := range factories {
+				var node yaml.Node
+				if err := unmarshalValue(value, &node); err != nil {
+					return nil, fmt.Errorf("plugin %s-%s config: %w", typ, name, err)
+				}
+				cfg[typ][name] = node
+			}
+		}
+		return cfg, nil
+	default:
+		return nil, fmt.Errorf("config format %s not supported", format)
+	}
+}
@@ -0,0 +1,88 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+type sourceConfig struct {
+	Addr    string        `yaml:"addr"`
+	MaxConn int64         `yaml:"max_conn"`
+	Timeout time.Duration `yaml:"timeout"`
+	Tags    []string      `yaml:"tags"`
+}
+
+// TestNewConfigFromBytes tests that the same config in all the formats is decoded
+// by the yaml tags.
+func TestNewConfigFromBytes(t *testing.T) {
+	want := sourceConfig{Addr: "127.0.0.1:6379", MaxConn: 9007199254740993, Timeout: 3 * time.Second, Tags: []string{"a", "b"}}
+	contents := map[string]string{
+		FormatYAML: `
+redis:
+  default:
+    addr: 127.0.0.1:6379
+    max_conn: 9007199254740993
+    timeout: 3s
+    tags: [a, b]
+`,
+		FormatJSON: `{"redis": {"default": {"addr": "127.0.0.1:6379", "max_conn": 9007199254740993, "timeout": "3s", "tags": ["a", "b"]}}}`,
+		FormatTOML: `
+[redis.default]
+addr = "127.0.0.1:6379"
+max_conn = 9007199254740993
+timeout = "3s"
+tags = ["a", "b"]
+`,
+	}
+	for format, content := range contents {
+		cfg, err := NewConfigFromBytes(format, []byte(content))
+		if err != nil {
+			t.Fatalf("%s: NewConfigFromBytes failed: %v", format, err)
+		}
+		node := cfg["redis"]["default"]
+		var got sourceConfig
+		if err := (&YamlNodeDecoder{Node: &node}).Decode(&got); err != nil {
+			t.Fatalf("%s: Decode failed: %v", format, err)
+		}
+		if got.Addr != want.Addr || got.MaxConn != want.MaxConn || got.Timeout != want.Timeout || len(got.Tags) != 2 {
+			t.Errorf("%s: got %+v", format, got)
+		}
+	}
+	if _, err := NewConfigFromBytes("ini", nil); err == nil {
+		t.Error("Expected error for the unknown format")
+	}
+	if _, err := NewConfigFromBytes(FormatJSON, []byte(`{"redis": 1}`)); err == nil {
+		t.Error("Expected error for the invalid json")
+	}
+}
+
+// TestConfigSource tests the config blocks of the sources and setting them up.
+func TestConfigSource(t *testing.T) {
+	Reset()
+	var got sourceConfig
+	Register("default", &mockFactoryWithConfig{typ: "cache", setupFunc: func(_ string, dec Decoder) error {
+		return dec.Decode(&got)
+	}})
+	cfg, err := NewConfigFromSources(map[string]map[string]ConfigSource{
+		"cache": {"default": TOMLSource("addr = \"local\"\ntimeout = \"1s\"")},
+	})
+	if err != nil {
+		t.Fatalf("NewConfigFromSources failed: %v", err)
+	}
+	if _, err := cfg.Setup(); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if got.Addr != "local" || got.Timeout != time.Second {
+		t.Errorf("got %+v", got)
+	}
+
+	var fromJSON sourceConfig
+	if err := JSONSource(`{"max_conn": 10}`).UnmarshalTo(&fromJSON); err != nil || fromJSON.MaxConn != 10 {
+		t.Errorf("JSONSource: %+v, %v", fromJSON, err)
+	}
+	if _, err := NewConfigFromSources(map[string]map[string]ConfigSource{
+		"cache": {"default": JSONSource(`{`)},
+	}); err == nil {
+		t.Error("Expected error for the invalid block")
+	}
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Verify checks the config as Setup does without setting up any plugin, e.g. for a
+// --check-config flag or in CI before deployment. It reports the plugins not
+// registered, the configs rejected by Validator, the strong dependencies not
+// configured or disabled, the self dependencies and the dependency cycles, the
+// errors of all plugins are joined into one error.
+func (c Config) Verify(opts ...SetupOption) error {
+	r := newSetupOptions(opts).registry
+	enabled, disabled, err := c.split()
+	if err != nil {
+		return err
+	}
+	ps := enabled.infos(r)
+	errs := []error{validatePlugins(append(ps, disabled.infos(r)...))}
+	if len(ps) > MaxPluginSize {
+		errs = append(errs, fmt.Errorf("plugin number exceed max limit:%d", MaxPluginSize))
+	}
+	errs = append(errs, verifyDependencies(ps)...)
+	return errors.Join(errs...)
+}
+
+// verifyDependencies checks the dependencies of the enabled plugins sorted by key.
+func verifyDependencies(ps []pluginInfo) []error {
+	var (
+		errs  []error
+		nodes []string
+		deps  = make(map[string][]string)
+		// exists is true for the registered plugins and false for the others configured,
+		// which are reported by validatePlugins and left out of the graph.
+		exists = make(map[string]bool, len(ps))
+	)
+	for i := range ps {
+		exists[ps[i].key()] = ps[i].factory != nil
+		if ps[i].factory != nil {
+			nodes = append(nodes, ps[i].key())
+		}
+	}
+	for i := range ps {
+		p := &ps[i]
+		if p.factory == nil {
+			continue
+		}
+		if d, ok := p.factory.(Depender); ok {
+			for _, dep := range d.DependsOn() {
+				switch {
+				case dep == p.key():
+					errs = append(errs, fmt.Errorf("plugin %s not allowed to depend on itself", p.key()))
+				case !exists[dep]:
+					if _, configured := exists[dep]; !configured {
+						errs = append(errs, fmt.Errorf("plugin %s depends plugin %s not exists or disabled", p.key(), dep))
+					}
+				default:
+					deps[p.key()] = append(deps[p.key()], dep)
+				}
+			}
+		}
+		if fd, ok := p.factory.(FlexDepender); ok {
+			for _, dep := range fd.FlexDependsOn() {
+				switch {
+				case dep == p.key():
+					errs = append(errs, fmt.Errorf("plugin %s not allowed to depend on itself", p.key()))
+				case exists[dep]:
+					deps[p.key()] = append(deps[p.key()], dep)
+				}
+			}
+		}
+	}
+	sort.Strings(nodes)
+	if _, cycle := topoSort(nodes, deps); len(cycle) > 0 {
+		errs = append(errs, fmt.Errorf("cycle depends: %s", strings.Join(cycle, " -> ")))
+	}
+	return errs
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestVerify tests that all the errors of the config are reported without any plugin
+// set up.
+func TestVerify(t *testing.T) {
+	Reset()
+
+	var setups int
+	setup := func(string, Decoder) error {
+		setups++
+		return nil
+	}
+	Register("default", &mockValidatorFactory{
+		mockFactoryWithConfig: mockFactoryWithConfig{typ: "database", setupFunc: setup},
+		validateFunc: func(dec Decoder) error {
+			var cfg struct {
+				Host string `yaml:"host"`
+			}
+			if err := dec.Decode(&cfg); err != nil {
+				return err
+			}
+			if cfg.Host == "" {
+				return errors.New("host required")
+			}
+			return nil
+		},
+	})
+	Register("default", &mockDependerFactory{
+		mockFactoryWithConfig: mockFactoryWithConfig{typ: "cache", setupFunc: setup},
+		dependsOn:             []string{"redis-default", "database-default"},
+	})
+	for name, dep := range map[string]string{"a": "queue-b", "b": "queue-a"} {
+		Register(name, &mockDependerFactory{
+			mockFactoryWithConfig: mockFactoryWithConfig{typ: "queue", setupFunc: setup},
+			dependsOn:             []string{dep},
+		})
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(`
+database:
+  default: {port: 3306}
+cache:
+  default: {}
+queue:
+  a: {}
+  b: {}
+metrics:
+  default: {}
+`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	err := cfg.Verify()
+	if err == nil {
+		t.Fatal("Expected verify error")
+	}
+	for _, want := range []string{
+		"validate plugin database-default error: host required",
+		"plugin metrics:default no registered",
+		"plugin cache-default depends plugin redis-default not exists or disabled",
+		"cycle depends: queue-a -> queue-b -> queue-a",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+	if setups != 0 {
+		t.Errorf("Expected no plugin set up, got %d", setups)
+	}
+
+	var valid Config
+	if err := yaml.Unmarshal([]byte(`
+database:
+  default: {host: localhost}
+`), &valid); err != nil {
+		t.Fatal(err)
+	}
+	if err := valid.Verify(); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// WatchOption is the option of Watch.
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	debounce time.Duration
+	parse    func(content []byte) (Config, error)
+	onReload func(cfg Config, err error)
+	registry *Registry
+	plugins  *Closables
+}
+
+// WithDebounce sets the quiet period after the last change of the file before it is
+// reloaded, since editors and deployments write a file in several steps. Default as 500ms.
+func WithDebounce(d time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.debounce = d
+	}
+}
+
+// WithParser sets the parser of the file content, e.g. for the plugin configs under
+// a key of the service config. Default parses the content as a yaml Config.
+func WithParser(parse func(content []byte) (Config, error)) WatchOption {
+	return func(o *watchOptions) {
+		o.parse = parse
+	}
+}
+
+// WithReloadHook sets the function called after every reload with the new config,
+// or the error if the file failed to read, parse or reload, e.g. to log the reloads.
+func WithReloadHook(fn func(cfg Config, err error)) WatchOption {
+	return func(o *watchOptions) {
+		o.onReload = fn
+	}
+}
+
+// WithWatchRegistry reloads the plugins with the factories of r, which the plugins
+// should be set up from too. Default as DefaultRegistry.
+func WithWatchRegistry(r *Registry) WatchOption {
+	return func(o *watchOptions) {
+		o.registry = r
+	}
+}
+
+// WithWatchClosables reloads the plugins of cs, which are set up from the watched file,
+// by Closables.Reload, so the plugins removed by a reload are dropped from cs and the
+// new ones are added to it. cs closes the plugins in effect then, Watcher.Close does not.
+func WithWatchClosables(cs *Closables) WatchOption {
+	return func(o *watchOptions) {
+		o.plugins = cs
+	}
+}
+
+// Watcher reloads the plugins when their config file changes, see Watch.
+type Watcher struct {
+	path string
+	opts watchOptions
+	fsw  *fsnotify.Watcher
+
+	mu      sync.Mutex
+	cfg     Config
+	content []byte
+	plugins *Closables // the plugins in effect set up by the reloads, or the ones of WithWatchClosables
+
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// Watch watches the plugin config file at path, which the plugins are assumed to be
+// set up from. When the file changes, it is parsed and applied like Config.Reload: the
+// changed plugins are reloaded, the new ones are set up and the removed ones are
+// closed. A failed reload leaves the current config in effect. The directory of the
+// file is watched, so the files replaced by rename (editors, kubernetes ConfigMaps)
+// are followed too.
+func Watch(path string, opts ...WatchOption) (*Watcher, error) {
+	o := watchOptions{debounce: 500 * time.Millisecond, parse: parseConfig}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.registry == nil {
+		o.registry = DefaultRegistry
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := o.parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse plugin config %s error: %w", path, err)
+	}
+	plugins := o.plugins
+	if plugins == nil {
+		enabled, err := cfg.Enabled()
+		if err != nil {
+			return nil, fmt.Errorf("parse plugin config %s error: %w", path, err)
+		}
+		plugins = &Closables{cfg: enabled}
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fsw.Add(filepath.Dir(path)); err != nil {
+		_ = fsw.Close()
+		return nil, err
+	}
+	w := &Watcher{
+		path:    path,
+		opts:    o,
+		fsw:     fsw,
+		cfg:     cfg,
+		content: content,
+		plugins: plugins,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Config returns the config in effect.
+func (w *Watcher) Config() Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cfg
+}
+
+// Close stops watching and closes the plugins set up by the reloads and still in effect
+// in reverse dependency order. The plugins set up before Watch are closed by their own
+// close function, and none is closed with WithWatchClosables.
+func (w *Watcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	<-w.stopped
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	errs := []error{w.fsw.Close()}
+	if w.plugins != nil && w.opts.plugins == nil {
+		errs = append(errs, w.plugins.Close())
+	}
+	w.plugins = nil
+	return errors.Join(errs...)
+}
+
+func (w *Watcher) run() {
+	defer close(w.stopped)
+	timer := time.NewTimer(w.opts.debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if w.relevant(ev) {
+				timer.Reset(w.opts.debounce)
+			}
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.notify(nil, fmt.Errorf("watch plugin config %s error: %w", w.path, err))
+		case <-timer.C:
+			w.reload()
+		}
+	}
+}
+
+// relevant reports whether the event may change the file: the events of the file,
+// and the ..data symlink swaps of the kubernetes ConfigMaps.
+func (w *Watcher) relevant(ev fsnotify.Event) bool {
+	if ev.Has(fsnotify.Chmod) && !ev.Has(fsnotify.Write) {
+		return false
+	}
+	name := filepath.Clean(ev.Name)
+	return name == w.path || strings.HasPrefix(filepath.Base(name), "..")
+}
+
+// reload applies the file if its content changed.
+func (w *Watcher) reload() {
+	content, err := os.ReadFile(w.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) { // removed in the middle of a replacement
+			w.notify(nil, err)
+		}
+		return
+	}
+	w.mu.Lock()
+	if bytes.Equal(content, w.content) {
+		w.mu.Unlock()
+		return
+	}
+	cfg, err := w.opts.parse(content)
+	if err != nil {
+		w.mu.Unlock()
+		w.notify(nil, fmt.Errorf("parse plugin config %s error: %w", w.path, err))
+		return
+	}
+	applied, err := w.plugins.reload(cfg, w.opts.registry)
+	if applied {
+		// err is the error of closing the removed plugins
+		w.cfg, w.content = cfg, content
+	}
+	w.mu.Unlock()
+	if err != nil {
+		w.notify(nil, fmt.Errorf("reload plugin config %s error: %w", w.path, err))
+		return
+	}
+	w.notify(cfg, nil)
+}
+
+func (w *Watcher) notify(cfg Config, err error) {
+	if w.opts.onReload != nil {
+		w.opts.onReload(cfg, err)
+	}
+}
+
+func parseConfig(content []byte) (Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
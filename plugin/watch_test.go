@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type watchResult struct {
+	cfg Config
+	err error
+}
+
+func waitReload(t *testing.T, ch <-chan watchResult) watchResult {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for reload")
+		return watchResult{}
+	}
+}
+
+// TestWatch tests that Watch reloads the plugins when the file changes and keeps the config on failure.
+func TestWatch(t *testing.T) {
+	Reset()
+	redis := &mockReloaderFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "redis"}, reloaded: make(map[string]string), failAddr: "bad"}
+	cache := &mockReloaderFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "cache"}, reloaded: make(map[string]string)}
+	Register("default", redis)
+	Register("default", cache)
+
+	path := filepath.Join(t.TempDir(), "plugins.yaml")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	write("redis:\n  default: {addr: \"a:6379\"}\n")
+
+	results := make(chan watchResult, 10)
+	w, err := Watch(path, WithDebounce(50*time.Millisecond), WithReloadHook(func(cfg Config, err error) {
+		results <- watchResult{cfg, err}
+	}))
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	write("redis:\n  default: {addr: \"b:6379\"}\ncache:\n  default: {addr: mem}\n")
+	if r := waitReload(t, results); r.err != nil || r.cfg["cache"] == nil {
+		t.Fatalf("Expected reloaded, got %v", r.err)
+	}
+	if redis.reloaded["default"] != "b:6379" {
+		t.Errorf("Expected redis reloaded, got %v", redis.reloaded)
+	}
+
+	write("redis:\n  default: {addr: bad}\ncache:\n  default: {addr: mem}\n")
+	if r := waitReload(t, results); r.err == nil {
+		t.Fatal("Expected reload error")
+	}
+	if redis.reloaded["default"] != "b:6379" {
+		t.Errorf("Expected redis rolled back, got %v", redis.reloaded)
+	}
+	if _, ok := w.Config()["cache"]; !ok {
+		t.Errorf("Expected config kept after failure, got %v", w.Config())
+	}
+
+	write("redis: [")
+	if r := waitReload(t, results); r.err == nil {
+		t.Error("Expected parse error")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if cache.closed != 1 {
+		t.Errorf("Expected cache closed by Close, got %d", cache.closed)
+	}
+}
+
+// TestWatchClosesLive tests that a plugin added by a reload and removed by a later one is
+// closed only once, and the plugins of WithWatchClosables are kept up to date.
+func TestWatchClosesLive(t *testing.T) {
+	Reset()
+	redis := &mockReloaderFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "redis"}, reloaded: make(map[string]string)}
+	cache := &mockReloaderFactory{mockFactoryWithConfig: mockFactoryWithConfig{typ: "cache"}, reloaded: make(map[string]string)}
+	Register("default", redis)
+	Register("default", cache)
+
+	path := filepath.Join(t.TempDir(), "plugins.yaml")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	for _, withClosables := range []bool{false, true} {
+		redis.closed, cache.closed = 0, 0
+		write("redis:\n  default: {addr: \"a:6379\"}\n")
+		cs, err := mustConfig(t, "redis:\n  default: {addr: \"a:6379\"}\n").Setup()
+		if err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		results := make(chan watchResult, 10)
+		opts := []WatchOption{WithDebounce(50 * time.Millisecond), WithReloadHook(func(cfg Config, err error) {
+			results <- watchResult{cfg, err}
+		})}
+		if withClosables {
+			opts = append(opts, WithWatchClosables(cs))
+		}
+		w, err := Watch(path, opts...)
+		if err != nil {
+			t.Fatalf("Watch failed: %v", err)
+		}
+
+		write("redis:\n  default: {addr: \"a:6379\"}\ncache:\n  default: {addr: mem}\n")
+		if r := waitReload(t, results); r.err != nil {
+			t.Fatalf("Expected reloaded, got %v", r.err)
+		}
+		write("redis:\n  default: {addr: \"b:6379\"}\n")
+		if r := waitReload(t, results); r.err != nil {
+			t.Fatalf("Expected reloaded, got %v", r.err)
+		}
+		if cache.closed != 1 {
+			t.Errorf("Expected cache closed by the reload, got %d", cache.closed)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if err := cs.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if cache.closed != 1 || redis.closed != 1 {
+			t.Errorf("withClosables %v: expected each plugin closed once, got cache %d redis %d", withClosables, cache.closed, redis.closed)
+		}
+	}
+}
+
+// TestWatchMissingFile tests that Watch fails if the file does not exist.
+func TestWatchMissingFile(t *testing.T) {
+	if _, err := Watch(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -0,0 +1,101 @@
+package pool
+
+import (
+	"bytes"
+	"math/bits"
+)
+
+// BytePool is a pool of []byte in size classes of powers of two between
+// minSize and maxSize. Requests larger than maxSize are allocated directly.
+type BytePool struct {
+	minShift int
+	maxSize  int
+	classes  []*Pool[*[]byte]
+}
+
+// NewBytePool creates a byte pool, sizes are rounded up to powers of two.
+func NewBytePool(minSize, maxSize int) *BytePool {
+	minShift := bits.Len(uint(max(minSize, 1) - 1))
+	maxShift := bits.Len(uint(max(maxSize, minSize) - 1))
+	bp := &BytePool{minShift: minShift, maxSize: 1 << maxShift}
+	for shift := minShift; shift <= maxShift; shift++ {
+		size := 1 << shift
+		bp.classes = append(bp.classes, New(func() *[]byte {
+			b := make([]byte, size)
+			return &b
+		}))
+	}
+	return bp
+}
+
+// Get returns a slice of length n, its capacity may be larger.
+func (bp *BytePool) Get(n int) []byte {
+	if n > bp.maxSize {
+		return make([]byte, n)
+	}
+	b := *bp.classes[bp.class(n)].Get()
+	return b[:n]
+}
+
+// Put puts the slice back, the slices not from the pool are dropped.
+func (bp *BytePool) Put(b []byte) {
+	c := cap(b)
+	if c > bp.maxSize || c < 1<<bp.minShift || c&(c-1) != 0 {
+		return
+	}
+	b = b[:c]
+	bp.classes[bp.class(c)].Put(&b)
+}
+
+// Stats returns the statistics of each size class, from the smallest.
+func (bp *BytePool) Stats() []Stats {
+	stats := make([]Stats, len(bp.classes))
+	for i, c := range bp.classes {
+		stats[i] = c.Stats()
+	}
+	return stats
+}
+
+func (bp *BytePool) class(n int) int {
+	shift := bits.Len(uint(max(n, 1) - 1))
+	return max(shift-bp.minShift, 0)
+}
+
+// DefaultBytePool holds slices from 512B to 1MB.
+var DefaultBytePool = NewBytePool(512, 1<<20)
+
+// GetBytes gets a slice of length n from DefaultBytePool.
+func GetBytes(n int) []byte {
+	return DefaultBytePool.Get(n)
+}
+
+// PutBytes puts the slice back to DefaultBytePool.
+func PutBytes(b []byte) {
+	DefaultBytePool.Put(b)
+}
+
+// maxBufferSize is the max capacity of the buffers kept by the buffer pool,
+// larger ones are dropped to avoid holding memory after a burst.
+const maxBufferSize = 64 << 10
+
+var bufferPool = New(func() *bytes.Buffer {
+	return new(bytes.Buffer)
+}, WithReset(func(b *bytes.Buffer) { b.Reset() }))
+
+// GetBuffer gets an empty buffer.
+func GetBuffer() *bytes.Buffer {
+	return bufferPool.Get()
+}
+
+// PutBuffer puts the buffer back, buffers over 64KB are dropped.
+func PutBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxBufferSize {
+		return
+	}
+	bufferPool.Put(b)
+}
+
+// BufferStats returns the statistics of the buffer pool.
+func BufferStats() Stats {
+	return bufferPool.Stats()
+}
@@ -0,0 +1,126 @@
+/*
+pool 对象池工具：带统计和泄漏检测的泛型 sync.Pool 封装、分级 []byte 池和 bytes.Buffer 池
+*/
+
+package pool
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats is the statistics of a pool.
+type Stats struct {
+	Gets int64
+	Puts int64
+	// News is the number of objects created because the pool was empty.
+	News int64
+}
+
+// InUse returns the number of objects got but not put back.
+func (s Stats) InUse() int64 {
+	return s.Gets - s.Puts
+}
+
+// Option is the option of Pool.
+type Option[T any] func(*Pool[T])
+
+// WithReset sets the function resetting an object before it is put back.
+func WithReset[T any](reset func(T)) Option[T] {
+	return func(p *Pool[T]) {
+		p.reset = reset
+	}
+}
+
+// WithDebug records the stack of each Get so that the objects never put back
+// can be reported by Leaks. It is costly and meant for tests and debugging,
+// only the comparable objects (e.g. pointers) are tracked.
+func WithDebug[T any]() Option[T] {
+	return func(p *Pool[T]) {
+		p.debug = true
+		p.outstanding = make(map[any][]byte)
+	}
+}
+
+// Pool is a typed sync.Pool with get/put metrics.
+type Pool[T any] struct {
+	pool  sync.Pool
+	reset func(T)
+
+	gets atomic.Int64
+	puts atomic.Int64
+	news atomic.Int64
+
+	debug       bool
+	mu          sync.Mutex
+	outstanding map[any][]byte // object => stack of Get
+}
+
+// New creates a pool with newFn creating the objects.
+func New[T any](newFn func() T, opts ...Option[T]) *Pool[T] {
+	p := &Pool[T]{}
+	p.pool.New = func() any {
+		p.news.Add(1)
+		return newFn()
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// Get gets an object from the pool.
+func (p *Pool[T]) Get() T {
+	p.gets.Add(1)
+	v := p.pool.Get().(T)
+	if p.debug {
+		if key, ok := trackable(v); ok {
+			buf := make([]byte, 4096)
+			buf = buf[:runtime.Stack(buf, false)]
+			p.mu.Lock()
+			p.outstanding[key] = buf
+			p.mu.Unlock()
+		}
+	}
+	return v
+}
+
+// Put resets the object and puts it back to the pool.
+func (p *Pool[T]) Put(v T) {
+	p.puts.Add(1)
+	if p.debug {
+		if key, ok := trackable(v); ok {
+			p.mu.Lock()
+			delete(p.outstanding, key)
+			p.mu.Unlock()
+		}
+	}
+	if p.reset != nil {
+		p.reset(v)
+	}
+	p.pool.Put(v)
+}
+
+// Stats returns the statistics of the pool.
+func (p *Pool[T]) Stats() Stats {
+	return Stats{Gets: p.gets.Load(), Puts: p.puts.Load(), News: p.news.Load()}
+}
+
+// Leaks returns the stacks of the Get calls whose objects are not put back yet,
+// always empty without WithDebug.
+func (p *Pool[T]) Leaks() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	leaks := make([]string, 0, len(p.outstanding))
+	for _, stack := range p.outstanding {
+		leaks = append(leaks, string(stack))
+	}
+	return leaks
+}
+
+func trackable(v any) (any, bool) {
+	t := reflect.TypeOf(v)
+	return v, t != nil && t.Comparable()
+}
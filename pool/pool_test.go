@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"strings"
+	"testing"
+)
+
+type object struct {
+	n int
+}
+
+// TestPool tests the stats and the reset of the pool.
+func TestPool(t *testing.T) {
+	p := New(func() *object { return &object{} }, WithReset(func(o *object) { o.n = 0 }))
+	o := p.Get()
+	o.n = 5
+	p.Put(o)
+	_ = p.Get()
+
+	st := p.Stats()
+	if st.Gets != 2 || st.Puts != 1 || st.InUse() != 1 || st.News < 1 {
+		t.Errorf("Stats = %+v", st)
+	}
+	if o.n != 0 {
+		t.Errorf("Expected object reset, got %d", o.n)
+	}
+}
+
+// TestLeaks tests the leak detection in debug mode.
+func TestLeaks(t *testing.T) {
+	p := New(func() *object { return &object{} }, WithDebug[*object]())
+	a := p.Get()
+	_ = p.Get()
+	p.Put(a)
+
+	leaks := p.Leaks()
+	if len(leaks) != 1 || !strings.Contains(leaks[0], "TestLeaks") {
+		t.Errorf("Expected one leak from TestLeaks, got %v", leaks)
+	}
+}
+
+// TestBytePool tests the size classes of the byte pool.
+func TestBytePool(t *testing.T) {
+	bp := NewBytePool(64, 1024)
+	b := bp.Get(100)
+	if len(b) != 100 || cap(b) != 128 {
+		t.Errorf("Get(100) len %d cap %d, want 100 and 128", len(b), cap(b))
+	}
+	bp.Put(b)
+	if big := bp.Get(4096); len(big) != 4096 {
+		t.Errorf("Get(4096) len %d", len(big))
+	}
+	bp.Put(make([]byte, 100)) // 非池内容量，直接丢弃
+	if small := bp.Get(1); cap(small) != 64 {
+		t.Errorf("Get(1) cap %d, want 64", cap(small))
+	}
+
+	st := bp.Stats()
+	if len(st) != 5 || st[1].Puts != 1 {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+// TestBuffer tests the buffer pool.
+func TestBuffer(t *testing.T) {
+	b := GetBuffer()
+	b.WriteString("hello")
+	PutBuffer(b)
+	if b := GetBuffer(); b.Len() != 0 {
+		t.Errorf("Expected empty buffer, got %q", b.String())
+	}
+}
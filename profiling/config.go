@@ -0,0 +1,82 @@
+package profiling
+
+import "time"
+
+const (
+	// SinkFile 周期性地将 pprof 文件落盘
+	SinkFile = "file"
+	// SinkPyroscope 将 pprof 推送到 Pyroscope 兼容的 /ingest 接口
+	SinkPyroscope = "pyroscope"
+
+	ProfileCPU       = "cpu"
+	ProfileHeap      = "heap"
+	ProfileAllocs    = "allocs"
+	ProfileGoroutine = "goroutine"
+	ProfileMutex     = "mutex"
+	ProfileBlock     = "block"
+)
+
+// Config is the configuration of the profiling plugin.
+type Config struct {
+	// Sink is where the profiles go, such as file or pyroscope.
+	Sink string `yaml:"sink" mapstructure:"sink"`
+	// Profiles lists the profile types to collect, default as cpu and heap.
+	Profiles []string `yaml:"profiles" mapstructure:"profiles"`
+	// Interval is the collection period, default as 60s.
+	Interval time.Duration `yaml:"interval" mapstructure:"interval"`
+	// CPUDuration is how long the cpu profile samples in each period, default as 10s.
+	CPUDuration time.Duration `yaml:"cpu_duration" mapstructure:"cpu_duration"`
+	// MutexFraction is the rate passed to runtime.SetMutexProfileFraction when mutex is enabled.
+	MutexFraction int `yaml:"mutex_fraction" mapstructure:"mutex_fraction"`
+	// BlockRate is the rate passed to runtime.SetBlockProfileRate when block is enabled.
+	BlockRate int `yaml:"block_rate" mapstructure:"block_rate"`
+
+	File      FileConfig      `yaml:"file" mapstructure:"file"`
+	Pyroscope PyroscopeConfig `yaml:"pyroscope" mapstructure:"pyroscope"`
+}
+
+// FileConfig is the config of the file sink.
+type FileConfig struct {
+	// Dir is the directory of the pprof files, default as ./pprof.
+	Dir string `yaml:"dir" mapstructure:"dir"`
+	// MaxFiles is the max number of files kept per profile type, 0 means unlimited.
+	MaxFiles int `yaml:"max_files" mapstructure:"max_files"`
+}
+
+// PyroscopeConfig is the config of the pyroscope sink.
+type PyroscopeConfig struct {
+	// ServerAddress is the address of pyroscope server, like http://pyroscope:4040.
+	ServerAddress string `yaml:"server_address" mapstructure:"server_address"`
+	// AppName is the application name shown in pyroscope.
+	AppName string `yaml:"app_name" mapstructure:"app_name"`
+	// Tags are the static labels attached to every profile.
+	Tags map[string]string `yaml:"tags" mapstructure:"tags"`
+	// AuthToken is sent as a bearer token if not empty.
+	AuthToken string `yaml:"auth_token" mapstructure:"auth_token"`
+	// Timeout is the timeout of each upload request, default as 10s.
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+func (c *Config) setDefaults() {
+	if len(c.Profiles) == 0 {
+		c.Profiles = []string{ProfileCPU, ProfileHeap}
+	}
+	if c.Interval <= 0 {
+		c.Interval = 60 * time.Second
+	}
+	if c.CPUDuration <= 0 {
+		c.CPUDuration = 10 * time.Second
+	}
+	if c.CPUDuration > c.Interval {
+		c.CPUDuration = c.Interval
+	}
+	if c.Sink == "" {
+		c.Sink = SinkFile
+	}
+	if c.File.Dir == "" {
+		c.File.Dir = "./pprof"
+	}
+	if c.Pyroscope.Timeout <= 0 {
+		c.Pyroscope.Timeout = 10 * time.Second
+	}
+}
@@ -0,0 +1,75 @@
+package profiling
+
+import (
+	"sync"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "profiling"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the profiling plugin factory registered as profiling-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of profiling. Configure it as:
+//
+//	profiling:
+//	  default:
+//	    sink: pyroscope
+//	    interval: 60s
+//	    pyroscope:
+//	      server_address: http://pyroscope:4040
+//	      app_name: my-service
+type Factory struct {
+	mu        sync.Mutex
+	profilers map[string]*Profiler
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup starts a profiler by the plugin config.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	p, err := NewProfiler(cfg, nil)
+	if err != nil {
+		return err
+	}
+	if err := p.Start(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.profilers == nil {
+		f.profilers = make(map[string]*Profiler)
+	}
+	if old, ok := f.profilers[name]; ok {
+		_ = old.Stop()
+	}
+	f.profilers[name] = p
+	return nil
+}
+
+// Close stops all the profilers started by the factory.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for name, p := range f.profilers {
+		_ = p.Stop()
+		delete(f.profilers, name)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+profiling 持续性能剖析，周期性采集 pprof 并输出到磁盘或 Pyroscope
+*/
+
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// Profile is a single collected pprof profile.
+type Profile struct {
+	Type  string
+	Start time.Time
+	End   time.Time
+	Data  []byte
+}
+
+// Sink is where the collected profiles are exported to.
+// Custom sinks (e.g. uploading to S3) only need to implement this interface.
+type Sink interface {
+	Export(ctx context.Context, p *Profile) error
+}
+
+// Profiler collects profiles periodically and exports them to the sink.
+type Profiler struct {
+	cfg  Config
+	sink Sink
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+	running bool
+}
+
+// NewProfiler creates a Profiler. If sink is nil, it is built from cfg.Sink.
+func NewProfiler(cfg Config, sink Sink) (*Profiler, error) {
+	cfg.setDefaults()
+	for _, typ := range cfg.Profiles {
+		if typ != ProfileCPU && pprof.Lookup(typ) == nil {
+			return nil, fmt.Errorf("profiling: unknown profile type %s", typ)
+		}
+	}
+	if sink == nil {
+		s, err := newSink(&cfg)
+		if err != nil {
+			return nil, err
+		}
+		sink = s
+	}
+	return &Profiler{cfg: cfg, sink: sink}, nil
+}
+
+func newSink(cfg *Config) (Sink, error) {
+	switch cfg.Sink {
+	case SinkFile:
+		return NewFileSink(cfg.File), nil
+	case SinkPyroscope:
+		return NewPyroscopeSink(cfg.Pyroscope)
+	default:
+		return nil, fmt.Errorf("profiling: sink %s not supported", cfg.Sink)
+	}
+}
+
+// Start starts the background collection loop.
+func (p *Profiler) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return nil
+	}
+	if p.enabled(ProfileMutex) {
+		runtime.SetMutexProfileFraction(p.cfg.MutexFraction)
+	}
+	if p.enabled(ProfileBlock) {
+		runtime.SetBlockProfileRate(p.cfg.BlockRate)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	p.running = true
+	go p.loop(ctx)
+	return nil
+}
+
+// Stop stops the collection loop and waits for the in-flight round to finish.
+func (p *Profiler) Stop() error {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return nil
+	}
+	p.running = false
+	p.cancel()
+	done := p.done
+	p.mu.Unlock()
+	<-done
+	return nil
+}
+
+func (p *Profiler) enabled(typ string) bool {
+	for _, t := range p.cfg.Profiles {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Profiler) loop(ctx context.Context) {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		p.collect(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect runs one round of collection for all the configured profile types.
+func (p *Profiler) collect(ctx context.Context) {
+	for _, typ := range p.cfg.Profiles {
+		var (
+			prof *Profile
+			err  error
+		)
+		if typ == ProfileCPU {
+			prof, err = collectCPU(ctx, p.cfg.CPUDuration)
+		} else {
+			prof, err = collectLookup(typ)
+		}
+		if err != nil {
+			log.Errorf("profiling: collect %s profile error: %v", typ, err)
+			continue
+		}
+		// 退出时仍然导出最后一轮数据，避免丢失
+		if err := p.sink.Export(context.WithoutCancel(ctx), prof); err != nil {
+			log.Errorf("profiling: export %s profile error: %v", typ, err)
+		}
+	}
+}
+
+func collectCPU(ctx context.Context, d time.Duration) (*Profile, error) {
+	var buf bytes.Buffer
+	start := time.Now()
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(d)
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	timer.Stop()
+	pprof.StopCPUProfile()
+	return &Profile{Type: ProfileCPU, Start: start, End: time.Now(), Data: buf.Bytes()}, nil
+}
+
+func collectLookup(typ string) (*Profile, error) {
+	var buf bytes.Buffer
+	now := time.Now()
+	if err := pprof.Lookup(typ).WriteTo(&buf, 0); err != nil {
+		return nil, err
+	}
+	return &Profile{Type: typ, Start: now, End: now, Data: buf.Bytes()}, nil
+}
@@ -0,0 +1,166 @@
+package profiling
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+// mockSink records exported profiles.
+type mockSink struct {
+	mu       sync.Mutex
+	profiles []*Profile
+}
+
+func (m *mockSink) Export(_ context.Context, p *Profile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.profiles = append(m.profiles, p)
+	return nil
+}
+
+func (m *mockSink) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.profiles)
+}
+
+// TestNewProfilerUnknownType tests that unknown profile types are rejected.
+func TestNewProfilerUnknownType(t *testing.T) {
+	_, err := NewProfiler(Config{Profiles: []string{"unknown"}}, &mockSink{})
+	if err == nil {
+		t.Fatal("Expected error for unknown profile type")
+	}
+}
+
+// TestProfilerStartStop tests that profiles are collected and exported.
+func TestProfilerStartStop(t *testing.T) {
+	sink := &mockSink{}
+	p, err := NewProfiler(Config{
+		Profiles: []string{ProfileHeap, ProfileGoroutine},
+		Interval: 10 * time.Millisecond,
+	}, sink)
+	if err != nil {
+		t.Fatalf("NewProfiler failed: %v", err)
+	}
+	if err := p.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	if sink.count() < 2 {
+		t.Fatalf("Expected at least 2 profiles, got %d", sink.count())
+	}
+	for _, prof := range sink.profiles {
+		if len(prof.Data) == 0 {
+			t.Errorf("Profile %s has empty data", prof.Type)
+		}
+	}
+}
+
+// TestFileSinkMaxFiles tests that old files are removed when MaxFiles is exceeded.
+func TestFileSinkMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewFileSink(FileConfig{Dir: dir, MaxFiles: 2})
+
+	start := time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		p := &Profile{Type: ProfileHeap, Start: start.Add(time.Duration(i) * time.Second), Data: []byte("data")}
+		if err := sink.Export(context.Background(), p); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(entries))
+	}
+	if entries[0].Name() != "heap-20240115103001.pprof" {
+		t.Errorf("Unexpected oldest file kept: %s", entries[0].Name())
+	}
+}
+
+// TestPyroscopeSinkExport tests the request sent to the pyroscope ingest API.
+func TestPyroscopeSinkExport(t *testing.T) {
+	var (
+		gotName string
+		gotAuth string
+		gotData string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotName = r.URL.Query().Get("name")
+		gotAuth = r.Header.Get("Authorization")
+		f, _, err := r.FormFile("profile")
+		if err == nil {
+			buf := make([]byte, 16)
+			n, _ := f.Read(buf)
+			gotData = string(buf[:n])
+		}
+	}))
+	defer srv.Close()
+
+	sink, err := NewPyroscopeSink(PyroscopeConfig{
+		ServerAddress: srv.URL,
+		AppName:       "svc",
+		Tags:          map[string]string{"region": "sh", "env": "prod"},
+		AuthToken:     "token",
+	})
+	if err != nil {
+		t.Fatalf("NewPyroscopeSink failed: %v", err)
+	}
+	now := time.Now()
+	if err := sink.Export(context.Background(), &Profile{Type: ProfileCPU, Start: now, End: now, Data: []byte("pprof")}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if gotName != "svc.cpu{env=prod,region=sh}" {
+		t.Errorf("name = %q, want svc.cpu{env=prod,region=sh}", gotName)
+	}
+	if gotAuth != "Bearer token" {
+		t.Errorf("Authorization = %q, want Bearer token", gotAuth)
+	}
+	if gotData != "pprof" {
+		t.Errorf("profile = %q, want pprof", gotData)
+	}
+}
+
+// TestFactorySetup tests setting up the profiler through the plugin factory.
+func TestFactorySetup(t *testing.T) {
+	var node yaml.Node
+	content := `
+profiles: [heap]
+interval: 1h
+file:
+  dir: ` + t.TempDir()
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		t.Fatalf("Failed to unmarshal yaml: %v", err)
+	}
+
+	f := &Factory{}
+	if err := f.Setup("default", &plugin.YamlNodeDecoder{Node: &node}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if len(f.profilers) != 1 {
+		t.Fatalf("Expected 1 profiler, got %d", len(f.profilers))
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(f.profilers) != 0 {
+		t.Errorf("Expected profilers to be cleared after Close")
+	}
+}
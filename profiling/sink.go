@@ -0,0 +1,140 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FileSink writes each profile to a pprof file under Dir.
+type FileSink struct {
+	cfg FileConfig
+
+	mu    sync.Mutex
+	files map[string][]string // profile type => written files, oldest first
+}
+
+// NewFileSink creates a FileSink.
+func NewFileSink(cfg FileConfig) *FileSink {
+	if cfg.Dir == "" {
+		cfg.Dir = "./pprof"
+	}
+	return &FileSink{cfg: cfg, files: make(map[string][]string)}
+}
+
+// Export writes the profile to <dir>/<type>-<yyyyMMddHHmmss>.pprof.
+func (s *FileSink) Export(_ context.Context, p *Profile) error {
+	if err := os.MkdirAll(s.cfg.Dir, 0755); err != nil {
+		return err
+	}
+	name := filepath.Join(s.cfg.Dir, fmt.Sprintf("%s-%s.pprof", p.Type, p.Start.Format("20060102150405")))
+	if err := os.WriteFile(name, p.Data, 0644); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := append(s.files[p.Type], name)
+	if s.cfg.MaxFiles > 0 {
+		for len(files) > s.cfg.MaxFiles {
+			_ = os.Remove(files[0])
+			files = files[1:]
+		}
+	}
+	s.files[p.Type] = files
+	return nil
+}
+
+// PyroscopeSink pushes profiles to the ingest API of a pyroscope server.
+type PyroscopeSink struct {
+	cfg    PyroscopeConfig
+	client *http.Client
+}
+
+// NewPyroscopeSink creates a PyroscopeSink.
+func NewPyroscopeSink(cfg PyroscopeConfig) (*PyroscopeSink, error) {
+	if cfg.ServerAddress == "" {
+		return nil, errors.New("profiling: pyroscope server_address empty")
+	}
+	if cfg.AppName == "" {
+		return nil, errors.New("profiling: pyroscope app_name empty")
+	}
+	return &PyroscopeSink{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Export uploads the profile as multipart form to /ingest.
+func (s *PyroscopeSink) Export(ctx context.Context, p *Profile) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, err := w.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(p.Data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	q.Set("name", s.appName(p.Type))
+	q.Set("from", strconv.FormatInt(p.Start.Unix(), 10))
+	q.Set("until", strconv.FormatInt(p.End.Unix(), 10))
+	q.Set("spyName", "gospy")
+	if p.Type == ProfileCPU {
+		q.Set("sampleRate", "100")
+	}
+	u := strings.TrimRight(s.cfg.ServerAddress, "/") + "/ingest?" + q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if s.cfg.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.AuthToken)
+	}
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 512))
+		return fmt.Errorf("profiling: pyroscope ingest status %d: %s", rsp.StatusCode, msg)
+	}
+	return nil
+}
+
+// appName builds the pyroscope application name like app.cpu{env=prod}.
+func (s *PyroscopeSink) appName(typ string) string {
+	name := s.cfg.AppName + "." + typ
+	if len(s.cfg.Tags) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(s.cfg.Tags))
+	for k := range s.cfg.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]string, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, k+"="+s.cfg.Tags[k])
+	}
+	return name + "{" + strings.Join(tags, ",") + "}"
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// KeyedLimiter limits the rate of events per key, implemented by Keyed in process
+// and RedisLimiter across processes.
+type KeyedLimiter interface {
+	// Allow reports whether an event of key may happen now.
+	Allow(key string) bool
+	// Wait blocks until an event of key may happen, see Limiter.Wait.
+	Wait(ctx context.Context, key string) error
+	// Reserve reserves an event of key, see Limiter.Reserve.
+	Reserve(key string) *Reservation
+}
+
+// Keyed keeps a limiter per key, e.g. per client ip or user. The number of
+// keys is bounded: the least recently used key is evicted when MaxKeys is
+// reached, and the keys idle for longer than IdleTTL are dropped.
+type Keyed struct {
+	newLimiter func() Limiter
+	maxKeys    int
+	idleTTL    time.Duration
+	now        func() time.Time
+
+	mu    sync.Mutex
+	lru   *list.List // front is the most recently used
+	items map[string]*list.Element
+}
+
+type keyedItem struct {
+	key      string
+	limiter  Limiter
+	lastSeen time.Time
+}
+
+// KeyedOption is the option of Keyed.
+type KeyedOption func(*Keyed)
+
+// WithMaxKeys sets the max number of keys kept, default as 10000.
+func WithMaxKeys(n int) KeyedOption {
+	return func(k *Keyed) {
+		k.maxKeys = n
+	}
+}
+
+// WithIdleTTL sets how long an unused key is kept, default as 10m.
+func WithIdleTTL(d time.Duration) KeyedOption {
+	return func(k *Keyed) {
+		k.idleTTL = d
+	}
+}
+
+// NewKeyed creates a keyed limiter with newLimiter creating the limiter of a new key.
+func NewKeyed(newLimiter func() Limiter, opts ...KeyedOption) *Keyed {
+	k := &Keyed{
+		newLimiter: newLimiter,
+		maxKeys:    10000,
+		idleTTL:    10 * time.Minute,
+		now:        time.Now,
+		lru:        list.New(),
+		items:      make(map[string]*list.Element),
+	}
+	for _, o := range opts {
+		o(k)
+	}
+	return k
+}
+
+// NewKeyedTokenBucket creates a keyed limiter of token buckets.
+func NewKeyedTokenBucket(rate float64, burst int, opts ...KeyedOption) *Keyed {
+	return NewKeyed(func() Limiter { return NewTokenBucket(rate, burst) }, opts...)
+}
+
+// NewKeyedSlidingWindow creates a keyed limiter of sliding windows.
+func NewKeyedSlidingWindow(limit int, window time.Duration, opts ...KeyedOption) *Keyed {
+	return NewKeyed(func() Limiter { return NewSlidingWindow(limit, window) }, opts...)
+}
+
+// Allow reports whether an event of key may happen now.
+func (k *Keyed) Allow(key string) bool {
+	return k.get(key).Allow()
+}
+
+// Wait blocks until an event of key may happen, see Limiter.Wait.
+func (k *Keyed) Wait(ctx context.Context, key string) error {
+	return k.get(key).Wait(ctx)
+}
+
+// Reserve reserves an event of key, see Limiter.Reserve.
+func (k *Keyed) Reserve(key string) *Reservation {
+	return k.get(key).Reserve()
+}
+
+// Len returns the number of keys kept.
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.lru.Len()
+}
+
+func (k *Keyed) get(key string) Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := k.now()
+	if e, ok := k.items[key]; ok {
+		it := e.Value.(*keyedItem)
+		it.lastSeen = now
+		k.lru.MoveToFront(e)
+		return it.limiter
+	}
+
+	// 新 key 加入前先清理尾部过期的 key，再按容量淘汰最久未使用的
+	for e := k.lru.Back(); e != nil; e = k.lru.Back() {
+		it := e.Value.(*keyedItem)
+		if now.Sub(it.lastSeen) < k.idleTTL && k.lru.Len() < k.maxKeys {
+			break
+		}
+		k.lru.Remove(e)
+		delete(k.items, it.key)
+	}
+	it := &keyedItem{key: key, limiter: k.newLimiter(), lastSeen: now}
+	k.items[key] = k.lru.PushFront(it)
+	return it.limiter
+}
@@ -0,0 +1,260 @@
+/*
+ratelimit 限流，提供令牌桶和滑动窗口两种算法的进程内和基于 Redis 的分布式实现，按 key 限流并通过 LRU 淘汰空闲 key
+*/
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrLimited is returned by Wait when the event can not happen before the deadline of the context.
+var ErrLimited = errors.New("ratelimit: rate limit exceeded")
+
+// infDuration is the max wait of Reserve, which never fails for waiting too long.
+const infDuration = time.Duration(math.MaxInt64)
+
+// Limiter limits the rate of events.
+type Limiter interface {
+	// Allow reports whether an event may happen now.
+	Allow() bool
+	// Wait blocks until an event may happen, returns ErrLimited at once if it can
+	// not happen before the deadline of ctx, or the error of ctx if done while waiting.
+	Wait(ctx context.Context) error
+	// Reserve reserves an event, which may happen after the delay of the reservation.
+	Reserve() *Reservation
+}
+
+// Reservation is an event reserved by Reserve.
+type Reservation struct {
+	ok     bool
+	delay  time.Duration
+	cancel func()
+}
+
+// OK reports whether the event is reserved, false if it can never happen, e.g. more than the burst.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long to wait before the event happens, 0 if not OK.
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel gives the reserved event back if it has not happened yet, so that the
+// later events may happen earlier.
+func (r *Reservation) Cancel() {
+	if r.ok && r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+// wait blocks until the reserved event happens, the reservation is canceled if ctx is done first.
+func (r *Reservation) wait(ctx context.Context) error {
+	if r.delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(r.delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// waitReserve implements Limiter.Wait by reserve, with the max wait up to the deadline of ctx.
+func waitReserve(ctx context.Context, reserve func(maxWait time.Duration) *Reservation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	maxWait := infDuration
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = time.Until(deadline)
+	}
+	r := reserve(maxWait)
+	if !r.OK() {
+		return ErrLimited
+	}
+	return r.wait(ctx)
+}
+
+// TokenBucket is a token bucket limiter: tokens refill at rate per second up to burst.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64 // negative when the tokens are reserved in advance
+	last   time.Time
+}
+
+// NewTokenBucket creates a full token bucket.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		now:    time.Now,
+		tokens: float64(burst),
+	}
+}
+
+// Allow implements Limiter.
+func (b *TokenBucket) Allow() bool {
+	return b.AllowN(1)
+}
+
+// AllowN reports whether n events may happen now, taking n tokens if so.
+func (b *TokenBucket) AllowN(n int) bool {
+	return b.reserveN(n, 0).ok
+}
+
+// Wait implements Limiter.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return waitReserve(ctx, func(maxWait time.Duration) *Reservation { return b.reserveN(1, maxWait) })
+}
+
+// Reserve implements Limiter.
+func (b *TokenBucket) Reserve() *Reservation {
+	return b.ReserveN(1)
+}
+
+// ReserveN reserves n events, which is not OK if n is more than the burst.
+func (b *TokenBucket) ReserveN(n int) *Reservation {
+	return b.reserveN(n, infDuration)
+}
+
+// reserveN takes n tokens in advance if they are refilled within maxWait.
+func (b *TokenBucket) reserveN(n int, maxWait time.Duration) *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+	if float64(n) > b.burst {
+		return &Reservation{}
+	}
+	tokens := b.tokens - float64(n)
+	var delay time.Duration
+	if tokens < 0 {
+		if b.rate <= 0 {
+			return &Reservation{}
+		}
+		delay = time.Duration(-tokens / b.rate * float64(time.Second))
+	}
+	if delay > maxWait {
+		return &Reservation{}
+	}
+	b.tokens = tokens
+	at := now.Add(delay)
+	return &Reservation{ok: true, delay: delay, cancel: func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.now().Before(at) {
+			b.tokens = min(b.burst, b.tokens+float64(n))
+		}
+	}}
+}
+
+// SlidingWindow allows limit events in any window, estimated by weighting the
+// count of the previous fixed window with its overlap with the sliding one.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	base   int64 // index of the fixed window counts[0], the previous one of now
+	counts []int // counts of the fixed windows from base, including the reserved ones
+}
+
+// NewSlidingWindow creates a sliding window limiter.
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{limit: limit, window: window, now: time.Now}
+}
+
+// Allow implements Limiter.
+func (w *SlidingWindow) Allow() bool {
+	return w.reserve(0).ok
+}
+
+// Wait implements Limiter.
+func (w *SlidingWindow) Wait(ctx context.Context) error {
+	return waitReserve(ctx, w.reserve)
+}
+
+// Reserve implements Limiter.
+func (w *SlidingWindow) Reserve() *Reservation {
+	return w.reserve(infDuration)
+}
+
+// reserve counts the event in the first fixed window where the estimate drops
+// below the limit, if that is within maxWait.
+func (w *SlidingWindow) reserve(maxWait time.Duration) *Reservation {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.limit <= 0 {
+		return &Reservation{}
+	}
+	now := w.now()
+	curr := now.UnixNano() / int64(w.window)
+	if shift := int(curr - 1 - w.base); shift > 0 {
+		w.counts = w.counts[min(shift, len(w.counts)):]
+		w.base = curr - 1
+	}
+
+	for i := curr; ; i++ {
+		start := time.Unix(0, i*int64(w.window))
+		if start.Sub(now) > maxWait {
+			return &Reservation{}
+		}
+		c := w.count(i)
+		if c >= w.limit {
+			continue
+		}
+		// 上个窗口的权重降到 (limit-c)/prev 以下时估算值小于 limit
+		at := start
+		if prev := w.count(i - 1); prev > 0 {
+			at = start.Add(time.Duration(float64(w.window)*(1-float64(w.limit-c)/float64(prev))) + 1)
+		}
+		var delay time.Duration
+		if at.After(now) {
+			delay = at.Sub(now)
+		}
+		if delay > maxWait {
+			return &Reservation{}
+		}
+		for int(i-w.base) >= len(w.counts) {
+			w.counts = append(w.counts, 0)
+		}
+		w.counts[i-w.base]++
+		return &Reservation{ok: true, delay: delay, cancel: func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if w.now().Before(at) && i >= w.base && int(i-w.base) < len(w.counts) {
+				w.counts[i-w.base]--
+			}
+		}}
+	}
+}
+
+// count returns the count of the fixed window i.
+func (w *SlidingWindow) count(i int64) int {
+	if i < w.base || int(i-w.base) >= len(w.counts) {
+		return 0
+	}
+	return w.counts[i-w.base]
+}
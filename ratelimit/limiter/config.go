@@ -0,0 +1,103 @@
+/*
+limiter 限流插件，按配置创建进程内或基于 Redis 的限流器，供 HTTP/gRPC 中间件使用
+*/
+
+package limiter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/baisiyi/go-kits/log"
+	"github.com/baisiyi/go-kits/ratelimit"
+	"github.com/baisiyi/go-kits/redis"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+)
+
+// Config is the configuration of a limiter.
+type Config struct {
+	// Algorithm is token_bucket or sliding_window, default as token_bucket.
+	Algorithm string `yaml:"algorithm" mapstructure:"algorithm"`
+	// Rate is the tokens refilled per second of token_bucket.
+	Rate float64 `yaml:"rate" mapstructure:"rate"`
+	// Burst is the bucket size of token_bucket, default as the rate rounded up.
+	Burst int `yaml:"burst" mapstructure:"burst"`
+	// Limit is the max events in a window of sliding_window.
+	Limit int `yaml:"limit" mapstructure:"limit"`
+	// Window is the window of sliding_window, default as 1s.
+	Window time.Duration `yaml:"window" mapstructure:"window"`
+	// MaxKeys is the max number of keys kept by the local limiter, default as 10000.
+	MaxKeys int `yaml:"max_keys" mapstructure:"max_keys"`
+	// IdleTTL is how long an unused key is kept by the local limiter, default as 10m.
+	IdleTTL time.Duration `yaml:"idle_ttl" mapstructure:"idle_ttl"`
+	// RedisClient is the client name of the redis plugin, the limiter is shared by
+	// all the processes through redis if set, otherwise local to the process.
+	RedisClient string `yaml:"redis_client" mapstructure:"redis_client"`
+	// KeyPrefix is the redis key prefix, default as ratelimit:<limiter name>:.
+	KeyPrefix string `yaml:"key_prefix" mapstructure:"key_prefix"`
+}
+
+func (c *Config) setDefaults(name string) {
+	if c.Algorithm == "" {
+		c.Algorithm = AlgorithmTokenBucket
+	}
+	if c.Burst <= 0 {
+		c.Burst = int(c.Rate)
+		if float64(c.Burst) < c.Rate {
+			c.Burst++
+		}
+	}
+	if c.Window <= 0 {
+		c.Window = time.Second
+	}
+	if c.MaxKeys <= 0 {
+		c.MaxKeys = 10000
+	}
+	if c.IdleTTL <= 0 {
+		c.IdleTTL = 10 * time.Minute
+	}
+	if c.KeyPrefix == "" {
+		c.KeyPrefix = "ratelimit:" + name + ":"
+	}
+}
+
+// New creates the limiter named name by cfg, the redis client is taken from the redis plugin.
+func New(name string, cfg Config) (ratelimit.KeyedLimiter, error) {
+	cfg.setDefaults(name)
+	switch cfg.Algorithm {
+	case AlgorithmTokenBucket:
+		if cfg.Rate <= 0 {
+			return nil, fmt.Errorf("ratelimit: limiter %s rate must be positive", name)
+		}
+	case AlgorithmSlidingWindow:
+		if cfg.Limit <= 0 {
+			return nil, fmt.Errorf("ratelimit: limiter %s limit must be positive", name)
+		}
+	default:
+		return nil, fmt.Errorf("ratelimit: limiter %s unknown algorithm %s", name, cfg.Algorithm)
+	}
+
+	if cfg.RedisClient == "" {
+		opts := []ratelimit.KeyedOption{ratelimit.WithMaxKeys(cfg.MaxKeys), ratelimit.WithIdleTTL(cfg.IdleTTL)}
+		if cfg.Algorithm == AlgorithmSlidingWindow {
+			return ratelimit.NewKeyedSlidingWindow(cfg.Limit, cfg.Window, opts...), nil
+		}
+		return ratelimit.NewKeyedTokenBucket(cfg.Rate, cfg.Burst, opts...), nil
+	}
+
+	client := redis.GetClient(cfg.RedisClient)
+	if client == nil {
+		return nil, fmt.Errorf("ratelimit: limiter %s redis client %s not found", name, cfg.RedisClient)
+	}
+	onError := ratelimit.WithErrorHandler(func(key string, err error) {
+		log.Warnf("ratelimit: limiter %s key %s redis error, allowed: %v", name, key, err)
+	})
+	if cfg.Algorithm == AlgorithmSlidingWindow {
+		return ratelimit.NewRedisSlidingWindow(client, cfg.KeyPrefix, cfg.Limit, cfg.Window, onError)
+	}
+	return ratelimit.NewRedisTokenBucket(client, cfg.KeyPrefix, cfg.Rate, cfg.Burst, onError)
+}
@@ -0,0 +1,73 @@
+package limiter
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/plugin"
+	"github.com/baisiyi/go-kits/ratelimit"
+	"github.com/baisiyi/go-kits/redis"
+)
+
+// TestFactorySetup tests setting up the local and the redis limiters through the plugin factory.
+func TestFactorySetup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisNode := yaml.Node{}
+	if err := yaml.Unmarshal([]byte("cache:\n  addr: "+mr.Addr()), &redisNode); err != nil {
+		t.Fatalf("Failed to unmarshal yaml: %v", err)
+	}
+	if err := redis.DefaultFactory.Setup("default", &plugin.YamlNodeDecoder{Node: &redisNode}); err != nil {
+		t.Fatalf("redis Setup failed: %v", err)
+	}
+	defer redis.DefaultFactory.Close()
+
+	var node yaml.Node
+	content := `
+api:
+  rate: 1
+login:
+  algorithm: sliding_window
+  limit: 1
+  window: 1m
+  redis_client: cache
+`
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		t.Fatalf("Failed to unmarshal yaml: %v", err)
+	}
+	if err := DefaultFactory.Setup("default", &plugin.YamlNodeDecoder{Node: &node}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if _, ok := Get("api").(*ratelimit.Keyed); !ok {
+		t.Errorf("Expected a local limiter, got %T", Get("api"))
+	}
+	if _, ok := Get("login").(*ratelimit.RedisLimiter); !ok {
+		t.Errorf("Expected a redis limiter, got %T", Get("login"))
+	}
+	for _, name := range []string{"api", "login"} {
+		if l := Get(name); !l.Allow("u1") || l.Allow("u1") {
+			t.Errorf("Expected limiter %s allowed once", name)
+		}
+	}
+	if keys := mr.Keys(); len(keys) != 1 || keys[0] != "ratelimit:login:u1" {
+		t.Errorf("redis keys = %v", keys)
+	}
+	if Get("missing") != nil {
+		t.Error("Expected nil for the missing limiter")
+	}
+}
+
+// TestNewInvalid tests the invalid configs.
+func TestNewInvalid(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{Algorithm: AlgorithmSlidingWindow},
+		{Algorithm: "leaky", Rate: 1},
+		{Rate: 1, RedisClient: "missing"},
+	} {
+		if _, err := New("test", cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package limiter
+
+import (
+	"sync"
+
+	"github.com/baisiyi/go-kits/plugin"
+	"github.com/baisiyi/go-kits/ratelimit"
+)
+
+const (
+	pluginType = "ratelimit"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the ratelimit plugin factory registered as ratelimit-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of ratelimit. The config is a map of limiter name => Config:
+//
+//	ratelimit:
+//	  default:
+//	    api:
+//	      rate: 100
+//	      burst: 200
+//	    login:
+//	      algorithm: sliding_window
+//	      limit: 5
+//	      window: 1m
+//	      redis_client: default
+type Factory struct {
+	mu       sync.RWMutex
+	limiters map[string]ratelimit.KeyedLimiter
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates all the configured limiters.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfgs map[string]Config
+	if err := dec.Decode(&cfgs); err != nil {
+		return err
+	}
+	limiters := make(map[string]ratelimit.KeyedLimiter, len(cfgs))
+	for limiter, cfg := range cfgs {
+		l, err := New(limiter, cfg)
+		if err != nil {
+			return err
+		}
+		limiters[limiter] = l
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.limiters = limiters
+	return nil
+}
+
+// FlexDependsOn sets up the redis plugin first if configured, used by the redis limiters.
+func (f *Factory) FlexDependsOn() []string {
+	return []string{"redis-default"}
+}
+
+// Get returns the configured limiter, nil if not found.
+func Get(name string) ratelimit.KeyedLimiter {
+	DefaultFactory.mu.RLock()
+	defer DefaultFactory.mu.RUnlock()
+	return DefaultFactory.limiters[name]
+}
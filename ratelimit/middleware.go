@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// KeyFunc returns the rate limit key of a http request, empty means not limited.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP keys the requests by the ip of RemoteAddr. Behind proxies, use
+// KeyByForwardedIP with the addresses of the proxies instead.
+func KeyByIP(r *http.Request) string {
+	return remoteIP(r.RemoteAddr)
+}
+
+// KeyByForwardedIP keys the requests by the client ip in X-Forwarded-For, read only if
+// the request comes from a trusted proxy: the last address of X-Forwarded-For not of a
+// trusted proxy, so the addresses forged by the clients are skipped. trustedProxies are
+// IPs or CIDRs, e.g. "10.0.0.0/8". The requests not from a trusted proxy are keyed by
+// RemoteAddr like KeyByIP.
+func KeyByForwardedIP(trustedProxies ...string) (KeyFunc, error) {
+	prefixes := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, s := range trustedProxies {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(s)
+			if aerr != nil {
+				return nil, fmt.Errorf("ratelimit: invalid trusted proxy %s", s)
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	trusted := func(ip string) bool {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, p := range prefixes {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(r *http.Request) string {
+		ip := remoteIP(r.RemoteAddr)
+		if !trusted(ip) {
+			return ip
+		}
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if !trusted(hop) {
+				return hop
+			}
+			ip = hop
+		}
+		// all the hops are trusted proxies
+		return ip
+	}, nil
+}
+
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// KeyByHeader keys the requests by the header value, e.g. an api key.
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// HTTPMiddleware rejects the requests over the limit with 429 Too Many Requests.
+func HTTPMiddleware(l KeyedLimiter, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if k := key(r); k != "" && !l.Allow(k) {
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GRPCKeyFunc returns the rate limit key of a grpc call, empty means not limited.
+type GRPCKeyFunc func(ctx context.Context, fullMethod string) string
+
+// GRPCKeyByPeer keys the calls by the peer ip.
+func GRPCKeyByPeer(ctx context.Context, _ string) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return remoteIP(p.Addr.String())
+}
+
+// GRPCKeyByMetadata keys the calls by the incoming metadata value.
+func GRPCKeyByMetadata(name string) GRPCKeyFunc {
+	return func(ctx context.Context, _ string) string {
+		if vs := metadata.ValueFromIncomingContext(ctx, name); len(vs) > 0 {
+			return vs[0]
+		}
+		return ""
+	}
+}
+
+// UnaryServerInterceptor rejects the calls over the limit with ResourceExhausted.
+func UnaryServerInterceptor(l KeyedLimiter, key GRPCKeyFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if k := key(ctx, info.FullMethod); k != "" && !l.Allow(k) {
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects the streams over the limit with ResourceExhausted.
+func StreamServerInterceptor(l KeyedLimiter, key GRPCKeyFunc) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if k := key(ss.Context(), info.FullMethod); k != "" && !l.Allow(k) {
+			return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(srv, ss)
+	}
+}
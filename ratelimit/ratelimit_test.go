@@ -0,0 +1,266 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TestTokenBucket tests the burst and the refill.
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := NewTokenBucket(10, 2)
+	b.now = func() time.Time { return now }
+	if !b.Allow() || !b.Allow() || b.Allow() {
+		t.Fatal("Expected burst of 2")
+	}
+	now = now.Add(100 * time.Millisecond)
+	if !b.Allow() || b.Allow() {
+		t.Error("Expected one token refilled after 100ms")
+	}
+}
+
+// TestSlidingWindow tests the weighted previous window.
+func TestSlidingWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	w := NewSlidingWindow(4, time.Second)
+	w.now = func() time.Time { return now }
+	for i := 0; i < 4; i++ {
+		if !w.Allow() {
+			t.Fatalf("event %d rejected", i)
+		}
+	}
+	if w.Allow() {
+		t.Error("Expected limit reached")
+	}
+	// 进入下一个窗口 1/4 处，上个窗口权重 0.75，估算 3 个，还能再放 1 个
+	now = now.Add(1250 * time.Millisecond)
+	if !w.Allow() || w.Allow() {
+		t.Error("Expected exactly one event allowed")
+	}
+	now = now.Add(3 * time.Second)
+	if !w.Allow() {
+		t.Error("Expected window reset")
+	}
+}
+
+// TestTokenBucketReserve tests the tokens reserved in advance and given back by Cancel.
+func TestTokenBucketReserve(t *testing.T) {
+	now := time.Now()
+	b := NewTokenBucket(10, 1)
+	b.now = func() time.Time { return now }
+	if r := b.Reserve(); !r.OK() || r.Delay() != 0 {
+		t.Fatalf("Expected the first event at once, delay = %s", r.Delay())
+	}
+	r := b.Reserve()
+	if !r.OK() || r.Delay() != 100*time.Millisecond {
+		t.Fatalf("Expected the second event after 100ms, delay = %s", r.Delay())
+	}
+	if d := b.Reserve().Delay(); d != 200*time.Millisecond {
+		t.Errorf("Expected the third event after 200ms, delay = %s", d)
+	}
+	r.Cancel()
+	if d := b.Reserve().Delay(); d != 200*time.Millisecond {
+		t.Errorf("Expected the canceled token reused, delay = %s", d)
+	}
+	if b.ReserveN(2).OK() {
+		t.Error("Expected not OK over the burst")
+	}
+}
+
+// TestSlidingWindowReserve tests the events reserved in the next window.
+func TestSlidingWindowReserve(t *testing.T) {
+	now := time.Unix(1000, 0)
+	w := NewSlidingWindow(2, time.Second)
+	w.now = func() time.Time { return now }
+	w.Allow()
+	w.Allow()
+	// 下个窗口开始时放行第一个，上个窗口的权重降到 1/2 以下时放行第二个
+	if d := w.Reserve().Delay(); d != time.Second+1 {
+		t.Errorf("delay = %s", d)
+	}
+	if d := w.Reserve().Delay(); d != 1500*time.Millisecond+1 {
+		t.Errorf("delay = %s", d)
+	}
+	now = now.Add(1600 * time.Millisecond)
+	if w.Allow() {
+		t.Error("Expected the next window taken by the reservations")
+	}
+}
+
+// TestWait tests the wait, the deadline and the canceled context.
+func TestWait(t *testing.T) {
+	b := NewTokenBucket(20, 1)
+	ctx := context.Background()
+	start := time.Now()
+	if err := b.Wait(ctx); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if err := b.Wait(ctx); err != nil || time.Since(start) < 40*time.Millisecond {
+		t.Fatalf("Expected waited about 50ms, err = %v, elapsed = %s", err, time.Since(start))
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(short); !errors.Is(err, ErrLimited) {
+		t.Errorf("Expected ErrLimited before the deadline, got %v", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := b.Wait(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// TestRedisLimiter tests the redis limiters by the redis server time.
+func TestRedisLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	now := time.Unix(1000, 0)
+	mr.SetTime(now)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	bucket, err := NewRedisTokenBucket(client, "rl:tb:", 10, 2)
+	if err != nil {
+		t.Fatalf("NewRedisTokenBucket failed: %v", err)
+	}
+	if !bucket.Allow("a") || !bucket.Allow("a") || bucket.Allow("a") {
+		t.Fatal("Expected burst of 2")
+	}
+	if !bucket.Allow("b") {
+		t.Error("Expected the keys limited separately")
+	}
+	if d := bucket.Reserve("a").Delay(); d != 100*time.Millisecond {
+		t.Errorf("delay = %s", d)
+	}
+	mr.SetTime(now.Add(300 * time.Millisecond))
+	if !bucket.Key("a").Allow() || !bucket.Allow("a") || bucket.Allow("a") {
+		t.Error("Expected the reserved token taken from the refilled ones")
+	}
+
+	window, err := NewRedisSlidingWindow(client, "rl:sw:", 4, time.Second)
+	if err != nil {
+		t.Fatalf("NewRedisSlidingWindow failed: %v", err)
+	}
+	mr.SetTime(now)
+	for i := 0; i < 4; i++ {
+		if !window.Allow("a") {
+			t.Fatalf("event %d rejected", i)
+		}
+	}
+	if window.Allow("a") {
+		t.Error("Expected limit reached")
+	}
+	mr.SetTime(now.Add(1250 * time.Millisecond))
+	if !window.Allow("a") || window.Allow("a") {
+		t.Error("Expected exactly one event allowed")
+	}
+
+	var errs int
+	failing, _ := NewRedisTokenBucket(client, "rl:tb:", 1, 1, WithErrorHandler(func(string, error) { errs++ }))
+	mr.Close()
+	if !failing.Allow("a") || errs != 1 {
+		t.Errorf("Expected allowed on redis error, errors = %d", errs)
+	}
+}
+
+// TestKeyedEviction tests the LRU and idle eviction of the keys.
+func TestKeyedEviction(t *testing.T) {
+	now := time.Now()
+	k := NewKeyedTokenBucket(1, 1, WithMaxKeys(2), WithIdleTTL(time.Minute))
+	k.now = func() time.Time { return now }
+
+	k.Allow("a")
+	k.Allow("b")
+	k.Allow("a")
+	k.Allow("c") // 淘汰最久未使用的 b
+	if _, ok := k.items["b"]; ok || k.Len() != 2 {
+		t.Errorf("Expected b evicted, keys = %d", k.Len())
+	}
+	if k.Allow("a") {
+		t.Error("Expected a still limited")
+	}
+
+	now = now.Add(2 * time.Minute)
+	k.Allow("d")
+	if k.Len() != 1 {
+		t.Errorf("Expected idle keys dropped, keys = %d", k.Len())
+	}
+}
+
+// TestHTTPMiddleware tests the 429 response over the limit.
+func TestHTTPMiddleware(t *testing.T) {
+	h := HTTPMiddleware(NewKeyedTokenBucket(1, 1), KeyByIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	var got []int
+	for _, ip := range []string{"1.1.1.1", "1.1.1.1", "2.2.2.2"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		// X-Forwarded-For is forged by the client without a trusted proxy
+		req.Header.Set("X-Forwarded-For", "3.3.3.3")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		got = append(got, rec.Code)
+	}
+	if got[0] != http.StatusOK || got[1] != http.StatusTooManyRequests || got[2] != http.StatusOK {
+		t.Errorf("codes = %v", got)
+	}
+}
+
+// TestKeyByForwardedIP tests that X-Forwarded-For is read only from the trusted proxies.
+func TestKeyByForwardedIP(t *testing.T) {
+	if _, err := KeyByForwardedIP("bad"); err == nil {
+		t.Error("Expected error for the invalid proxy")
+	}
+	key, err := KeyByForwardedIP("10.0.0.0/8", "192.168.1.1")
+	if err != nil {
+		t.Fatalf("KeyByForwardedIP failed: %v", err)
+	}
+	tests := []struct {
+		remote string
+		xff    string
+		want   string
+	}{
+		{"1.1.1.1:80", "3.3.3.3", "1.1.1.1"},
+		{"10.0.0.2:80", "", "10.0.0.2"},
+		{"10.0.0.2:80", "3.3.3.3", "3.3.3.3"},
+		{"10.0.0.2:80", "6.6.6.6, 3.3.3.3, 192.168.1.1", "3.3.3.3"},
+		{"10.0.0.2:80", "192.168.1.1, 10.0.0.3", "192.168.1.1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			req.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if got := key(req); got != tt.want {
+			t.Errorf("key(%s, %s) = %s, want %s", tt.remote, tt.xff, got, tt.want)
+		}
+	}
+}
+
+// TestUnaryServerInterceptor tests the ResourceExhausted status over the limit.
+func TestUnaryServerInterceptor(t *testing.T) {
+	i := UnaryServerInterceptor(NewKeyedSlidingWindow(1, time.Minute), GRPCKeyByMetadata("x-api-key"))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "k1"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	_, first := i(ctx, nil, info, handler)
+	_, second := i(ctx, nil, info, handler)
+	if status.Code(first) != codes.OK || status.Code(second) != codes.ResourceExhausted {
+		t.Errorf("codes = %v, %v", status.Code(first), status.Code(second))
+	}
+}
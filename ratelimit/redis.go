@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills and takes the tokens of the bucket KEYS[1] by the redis
+// server time, returns the delay in microseconds, or -1 if not within the max wait.
+// ARGV: rate per second, burst, n, max wait in microseconds (-1 as infinite).
+var tokenBucketScript = goredis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local rate, burst, n, max_wait = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+if n > burst then
+	return -1
+end
+local s = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens, ts = tonumber(s[1]) or burst, tonumber(s[2]) or now
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate / 1000000)
+	ts = now
+end
+tokens = tokens - n
+local delay = 0
+if tokens < 0 then
+	delay = math.ceil(-tokens * 1000000 / rate)
+end
+if max_wait >= 0 and delay > max_wait then
+	return -1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) * 1000 / rate) + 1000)
+return delay`)
+
+// slidingWindowScript counts the event in the first fixed window of the hash KEYS[1]
+// where the estimate drops below the limit, returns the delay in microseconds, or -1
+// if not within the max wait. ARGV: limit, window in microseconds, max wait in
+// microseconds (-1 as infinite).
+var slidingWindowScript = goredis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local limit, window, max_wait = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local curr = math.floor(now / window)
+for _, f in ipairs(redis.call('HKEYS', KEYS[1])) do
+	if tonumber(f) < curr - 1 then
+		redis.call('HDEL', KEYS[1], f)
+	end
+end
+local i = curr
+while true do
+	local start = i * window
+	if max_wait >= 0 and start - now > max_wait then
+		return -1
+	end
+	local c = tonumber(redis.call('HGET', KEYS[1], i)) or 0
+	if c < limit then
+		local at = start
+		local prev = tonumber(redis.call('HGET', KEYS[1], i - 1)) or 0
+		if prev > 0 then
+			at = start + math.floor(window * (1 - (limit - c) / prev)) + 1
+		end
+		local delay = math.max(at - now, 0)
+		if max_wait >= 0 and delay > max_wait then
+			return -1
+		end
+		redis.call('HINCRBY', KEYS[1], i, 1)
+		redis.call('PEXPIRE', KEYS[1], math.ceil(((i + 2) * window - now) / 1000))
+		return delay
+	end
+	i = i + 1
+end`)
+
+// RedisLimiter limits the rate of events per key across processes, the state of
+// each key is kept in the redis hash prefix+key and expires when idle. The time is
+// taken from the redis server, so the clocks of the processes do not matter.
+//
+// The redis errors are passed to the error handler and the events are allowed,
+// so that a redis outage does not take the service down. The reservations can
+// not be canceled.
+type RedisLimiter struct {
+	client  goredis.Scripter
+	prefix  string
+	onError func(key string, err error)
+
+	script *goredis.Script
+	args   []any // the script args before the max wait
+}
+
+// RedisOption is the option of RedisLimiter.
+type RedisOption func(*RedisLimiter)
+
+// WithErrorHandler sets the handler of the redis errors, which are ignored by default.
+func WithErrorHandler(f func(key string, err error)) RedisOption {
+	return func(l *RedisLimiter) {
+		l.onError = f
+	}
+}
+
+// NewRedisTokenBucket creates a redis limiter of token buckets.
+func NewRedisTokenBucket(client goredis.Scripter, prefix string, rate float64, burst int, opts ...RedisOption) (*RedisLimiter, error) {
+	if rate <= 0 || burst <= 0 {
+		return nil, errors.New("ratelimit: rate and burst must be positive")
+	}
+	return newRedisLimiter(client, prefix, tokenBucketScript, []any{rate, burst, 1}, opts), nil
+}
+
+// NewRedisSlidingWindow creates a redis limiter of sliding windows.
+func NewRedisSlidingWindow(client goredis.Scripter, prefix string, limit int, window time.Duration, opts ...RedisOption) (*RedisLimiter, error) {
+	if limit <= 0 || window < time.Millisecond {
+		return nil, errors.New("ratelimit: limit must be positive and window at least 1ms")
+	}
+	return newRedisLimiter(client, prefix, slidingWindowScript, []any{limit, window.Microseconds()}, opts), nil
+}
+
+func newRedisLimiter(client goredis.Scripter, prefix string, script *goredis.Script, args []any, opts []RedisOption) *RedisLimiter {
+	l := &RedisLimiter{
+		client:  client,
+		prefix:  prefix,
+		onError: func(string, error) {},
+		script:  script,
+		args:    args,
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	return l
+}
+
+// Allow reports whether an event of key may happen now.
+func (l *RedisLimiter) Allow(key string) bool {
+	return l.reserve(context.Background(), key, 0).ok
+}
+
+// Wait blocks until an event of key may happen, see Limiter.Wait.
+func (l *RedisLimiter) Wait(ctx context.Context, key string) error {
+	return waitReserve(ctx, func(maxWait time.Duration) *Reservation { return l.reserve(ctx, key, maxWait) })
+}
+
+// Reserve reserves an event of key, see Limiter.Reserve.
+func (l *RedisLimiter) Reserve(key string) *Reservation {
+	return l.reserve(context.Background(), key, infDuration)
+}
+
+// Key returns the Limiter of key.
+func (l *RedisLimiter) Key(key string) Limiter {
+	return &redisKeyLimiter{l: l, key: key}
+}
+
+func (l *RedisLimiter) reserve(ctx context.Context, key string, maxWait time.Duration) *Reservation {
+	wait := int64(-1)
+	if maxWait != infDuration {
+		wait = maxWait.Microseconds()
+	}
+	delay, err := l.script.Run(ctx, l.client, []string{l.prefix + key}, append(l.args[:len(l.args):len(l.args)], wait)...).Int64()
+	if err != nil {
+		l.onError(key, err)
+		return &Reservation{ok: true}
+	}
+	if delay < 0 {
+		return &Reservation{}
+	}
+	return &Reservation{ok: true, delay: time.Duration(delay) * time.Microsecond}
+}
+
+// redisKeyLimiter is the Limiter of a key of RedisLimiter.
+type redisKeyLimiter struct {
+	l   *RedisLimiter
+	key string
+}
+
+// Allow implements Limiter.
+func (k *redisKeyLimiter) Allow() bool {
+	return k.l.Allow(k.key)
+}
+
+// Wait implements Limiter.
+func (k *redisKeyLimiter) Wait(ctx context.Context) error {
+	return k.l.Wait(ctx, k.key)
+}
+
+// Reserve implements Limiter.
+func (k *redisKeyLimiter) Reserve() *Reservation {
+	return k.l.Reserve(k.key)
+}
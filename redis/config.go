@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"crypto/tls"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Config is the configuration of a redis client.
+type Config struct {
+	// Addr is the host:port of the redis server, default as 127.0.0.1:6379.
+	Addr     string `yaml:"addr" mapstructure:"addr"`
+	Username string `yaml:"username" mapstructure:"username"`
+	Password string `yaml:"password" mapstructure:"password"`
+	DB       int    `yaml:"db" mapstructure:"db"`
+
+	// PoolSize is the max number of connections, default as 10 per CPU by go-redis.
+	PoolSize     int `yaml:"pool_size" mapstructure:"pool_size"`
+	MinIdleConns int `yaml:"min_idle_conns" mapstructure:"min_idle_conns"`
+	MaxIdleConns int `yaml:"max_idle_conns" mapstructure:"max_idle_conns"`
+	// ConnMaxIdleTime closes the connections idle longer than it, default as 30m by go-redis.
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" mapstructure:"conn_max_idle_time"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" mapstructure:"conn_max_lifetime"`
+
+	// DialTimeout is the timeout of establishing connections, default as 5s.
+	DialTimeout time.Duration `yaml:"dial_timeout" mapstructure:"dial_timeout"`
+	// ReadTimeout and WriteTimeout are the socket timeouts, default as 3s.
+	ReadTimeout  time.Duration `yaml:"read_timeout" mapstructure:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout" mapstructure:"write_timeout"`
+	// PoolTimeout is the wait for a free connection when all are busy, default as ReadTimeout + 1s.
+	PoolTimeout time.Duration `yaml:"pool_timeout" mapstructure:"pool_timeout"`
+	// MaxRetries is the retries of a failed command, default as 3 by go-redis, -1 disables retries.
+	MaxRetries int `yaml:"max_retries" mapstructure:"max_retries"`
+
+	// SlowThreshold logs the commands slower than it as warnings, 0 disables.
+	SlowThreshold time.Duration `yaml:"slow_threshold" mapstructure:"slow_threshold"`
+	// TLS enables tls with the system root CAs.
+	TLS bool `yaml:"tls" mapstructure:"tls"`
+}
+
+func (c *Config) setDefaults() {
+	if c.Addr == "" {
+		c.Addr = "127.0.0.1:6379"
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = 5 * time.Second
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = 3 * time.Second
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = c.ReadTimeout
+	}
+}
+
+func (c *Config) options() *goredis.Options {
+	opts := &goredis.Options{
+		Addr:            c.Addr,
+		Username:        c.Username,
+		Password:        c.Password,
+		DB:              c.DB,
+		PoolSize:        c.PoolSize,
+		MinIdleConns:    c.MinIdleConns,
+		MaxIdleConns:    c.MaxIdleConns,
+		ConnMaxIdleTime: c.ConnMaxIdleTime,
+		ConnMaxLifetime: c.ConnMaxLifetime,
+		DialTimeout:     c.DialTimeout,
+		ReadTimeout:     c.ReadTimeout,
+		WriteTimeout:    c.WriteTimeout,
+		PoolTimeout:     c.PoolTimeout,
+		MaxRetries:      c.MaxRetries,
+	}
+	if c.TLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return opts
+}
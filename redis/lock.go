@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// unlockScript deletes the lock only if it is still held by the token, so a lock
+// expired and taken by another holder is not released.
+var unlockScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// TryLock takes the lock of key for ttl without waiting, e.g. as the distributed lock
+// of the scheduler jobs. ok is false without error if the lock is held by another holder.
+// unlock releases the lock if it has not expired and been taken by another holder.
+func (c *Client) TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, false, fmt.Errorf("redis: lock token error: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	ok, err = c.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	unlock = func() {
+		// ctx may be done when the holder finishes, e.g. a job timed out
+		if err := unlockScript.Run(context.WithoutCancel(ctx), c, []string{key}, token).Err(); err != nil {
+			c.logger.Errorf("redis: unlock %s error: %v", key, err)
+		}
+	}
+	return unlock, true, nil
+}
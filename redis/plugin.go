@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "redis"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the redis plugin factory registered as redis-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of redis. The config is a map of client name => Config:
+//
+//	redis:
+//	  default:
+//	    cache:
+//	      addr: 127.0.0.1:6379
+//	      pool_size: 20
+//	      slow_threshold: 50ms
+type Factory struct {
+	// Options are applied to all the clients on Setup.
+	Options []Option
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates and pings the clients of the plugin config.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfgs map[string]Config
+	if err := dec.Decode(&cfgs); err != nil {
+		return err
+	}
+	clients := make(map[string]*Client, len(cfgs))
+	for client, cfg := range cfgs {
+		c, err := New(cfg, f.Options...)
+		if err != nil {
+			for _, created := range clients {
+				_ = created.Close()
+			}
+			return fmt.Errorf("redis client %s: %w", client, err)
+		}
+		clients[client] = c
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clients = clients
+	return nil
+}
+
+// Close closes all the clients.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var errs []error
+	for client, c := range f.clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis client %s: %w", client, err))
+		}
+	}
+	f.clients = nil
+	return errors.Join(errs...)
+}
+
+// GetClient returns the configured client, nil if not found.
+func GetClient(name string) *Client {
+	DefaultFactory.mu.RLock()
+	defer DefaultFactory.mu.RUnlock()
+	return DefaultFactory.clients[name]
+}
@@ -0,0 +1,172 @@
+/*
+redis 基于 go-redis 的 Redis 客户端封装，支持慢命令日志、健康检查和插件化配置
+*/
+
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+// Nil is the error returned when the key does not exist.
+const Nil = goredis.Nil
+
+// maxCmdLen is the max length of a command printed in the logs.
+const maxCmdLen = 256
+
+// Option is the option of Client.
+type Option func(*Client)
+
+// WithLogger sets the logger of the slow and failed commands, default as the default logger.
+func WithLogger(l log.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// Client wraps goredis.Client, all the redis commands are available on it.
+type Client struct {
+	*goredis.Client
+	cfg    Config
+	logger log.Logger
+}
+
+// New creates a Client and pings the server to fail fast.
+func New(cfg Config, opts ...Option) (*Client, error) {
+	c := NewLazy(cfg, opts...)
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.DialTimeout)
+	defer cancel()
+	if err := c.Health(ctx); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("redis: ping %s error: %w", c.cfg.Addr, err)
+	}
+	return c, nil
+}
+
+// NewLazy creates a Client without connecting, the connections are made on first use.
+func NewLazy(cfg Config, opts ...Option) *Client {
+	cfg.setDefaults()
+	c := &Client{cfg: cfg}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.logger == nil {
+		c.logger = log.GetDefaultLogger()
+	}
+	c.Client = goredis.NewClient(cfg.options())
+	c.AddHook(&logHook{logger: c.logger, slowThreshold: cfg.SlowThreshold})
+	return c
+}
+
+// Config returns the config of the client with the defaults applied.
+func (c *Client) Config() Config {
+	return c.cfg
+}
+
+// Health pings the server.
+func (c *Client) Health(ctx context.Context) error {
+	return c.Ping(ctx).Err()
+}
+
+// HealthReport is the ping latency and the pool stats of the client.
+type HealthReport struct {
+	Latency    time.Duration
+	TotalConns uint32
+	IdleConns  uint32
+	// Timeouts is the number of times waiting for a free connection timed out.
+	Timeouts uint32
+}
+
+// MarshalJSON formats the latency like 1.5ms for the health endpoints.
+func (r HealthReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Latency    string `json:"latency"`
+		TotalConns uint32 `json:"total_conns"`
+		IdleConns  uint32 `json:"idle_conns"`
+		Timeouts   uint32 `json:"timeouts"`
+	}{r.Latency.String(), r.TotalConns, r.IdleConns, r.Timeouts})
+}
+
+// Check implements health.Checker, the details are HealthReport.
+func (c *Client) Check(ctx context.Context) (any, error) {
+	start := time.Now()
+	err := c.Health(ctx)
+	s := c.PoolStats()
+	return HealthReport{
+		Latency:    time.Since(start),
+		TotalConns: s.TotalConns,
+		IdleConns:  s.IdleConns,
+		Timeouts:   s.Timeouts,
+	}, err
+}
+
+// logHook logs the slow and failed commands.
+type logHook struct {
+	logger        log.Logger
+	slowThreshold time.Duration
+}
+
+// DialHook implements goredis.Hook.
+func (h *logHook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook implements goredis.Hook.
+func (h *logHook) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.log(ctx, time.Since(start), cmdString(cmd), err)
+		return err
+	}
+}
+
+// ProcessPipelineHook implements goredis.Hook.
+func (h *logHook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name())
+		}
+		h.log(ctx, time.Since(start), "pipeline["+strings.Join(names, " ")+"]", err)
+		return err
+	}
+}
+
+func (h *logHook) log(ctx context.Context, elapsed time.Duration, cmd string, err error) {
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		log.WithContextFields(h.logger, ctx).Errorf("[REDIS_ERROR] Elapsed: %v | Cmd: %s | Error: %v", elapsed, cmd, err)
+		return
+	}
+	if h.slowThreshold > 0 && elapsed > h.slowThreshold {
+		log.WithContextFields(h.logger, ctx).Warnf("[REDIS_SLOW] Elapsed: %v > %v | Cmd: %s", elapsed, h.slowThreshold, cmd)
+	}
+}
+
+func cmdString(cmd goredis.Cmder) string {
+	var b strings.Builder
+	for i, arg := range cmd.Args() {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprint(&b, arg)
+		if b.Len() > maxCmdLen {
+			return b.String()[:maxCmdLen] + "..."
+		}
+	}
+	return b.String()
+}
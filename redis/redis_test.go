@@ -0,0 +1,152 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/log"
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+// captureLogger records the formatted warn and error logs.
+type captureLogger struct {
+	log.Logger
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *captureLogger) Warnf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, fmt.Sprintf(format, args...))
+}
+
+func (l *captureLogger) Errorf(format string, args ...interface{}) {
+	l.Warnf(format, args...)
+}
+
+func (l *captureLogger) With(fields ...log.Field) log.Logger { return l }
+
+// TestClient tests the commands, the health check and the command logging.
+func TestClient(t *testing.T) {
+	mr := miniredis.RunT(t)
+	logger := &captureLogger{}
+	c, err := New(Config{Addr: mr.Addr(), SlowThreshold: time.Nanosecond}, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Health(ctx); err != nil {
+		t.Errorf("Health failed: %v", err)
+	}
+	if details, err := c.Check(ctx); err != nil || details.(HealthReport).TotalConns == 0 {
+		t.Errorf("Check = %+v, %v", details, err)
+	}
+	if err := c.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if v, _ := c.Get(ctx, "k").Result(); v != "v" {
+		t.Errorf("Get = %q, want v", v)
+	}
+	if err := c.Get(ctx, "missing").Err(); !errors.Is(err, Nil) {
+		t.Errorf("Expected Nil, got %v", err)
+	}
+	if len(logger.logs) == 0 || !strings.Contains(logger.logs[len(logger.logs)-1], "[REDIS_SLOW]") {
+		t.Errorf("Expected slow command logs, got %v", logger.logs)
+	}
+
+	logger.logs = nil
+	mr.SetError("boom")
+	_ = c.Get(ctx, "k").Err()
+	if len(logger.logs) != 1 || !strings.Contains(logger.logs[0], "[REDIS_ERROR] ") || !strings.Contains(logger.logs[0], "get k") {
+		t.Errorf("Expected error log, got %v", logger.logs)
+	}
+}
+
+// TestNewPingError tests that New fails fast when the server is unreachable.
+func TestNewPingError(t *testing.T) {
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	mr.Close()
+	if _, err := New(Config{Addr: addr, DialTimeout: 100 * time.Millisecond, MaxRetries: -1}); err == nil {
+		t.Error("Expected ping error")
+	}
+	if cfg := NewLazy(Config{}).Config(); cfg.Addr != "127.0.0.1:6379" || cfg.DialTimeout != 5*time.Second {
+		t.Errorf("Unexpected defaults %+v", cfg)
+	}
+}
+
+// TestFactory tests setting up the clients by the plugin config.
+func TestFactory(t *testing.T) {
+	mr := miniredis.RunT(t)
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("cache:\n  addr: "+mr.Addr()+"\n"), &node); err != nil {
+		t.Fatal(err)
+	}
+	f := &Factory{}
+	if err := f.Setup(pluginName, &plugin.YamlNodeDecoder{Node: &node}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	DefaultFactory, f = f, DefaultFactory
+	defer func() { DefaultFactory = f }()
+
+	c := GetClient("cache")
+	if c == nil || c.Health(context.Background()) != nil {
+		t.Fatal("Expected healthy cache client")
+	}
+	if err := DefaultFactory.Close(); err != nil || GetClient("cache") != nil {
+		t.Errorf("Close = %v", err)
+	}
+}
+
+// TestTryLock tests that the lock is taken once and released only by its holder.
+func TestTryLock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, err := New(Config{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	unlock, ok, err := c.TryLock(ctx, "lock:job", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("TryLock = %v, %v", ok, err)
+	}
+	if ttl := mr.TTL("lock:job"); ttl != time.Minute {
+		t.Errorf("Expected ttl 1m, got %v", ttl)
+	}
+	if _, ok, err := c.TryLock(ctx, "lock:job", time.Minute); err != nil || ok {
+		t.Errorf("Expected the held lock not taken, got %v, %v", ok, err)
+	}
+	unlock()
+	if mr.Exists("lock:job") {
+		t.Error("Expected the lock released")
+	}
+
+	// the expired lock taken by another holder is not released by the previous one
+	unlock, _, _ = c.TryLock(ctx, "lock:job", time.Second)
+	mr.FastForward(2 * time.Second)
+	if _, ok, _ := c.TryLock(ctx, "lock:job", time.Minute); !ok {
+		t.Fatal("Expected the expired lock taken")
+	}
+	unlock()
+	if !mr.Exists("lock:job") {
+		t.Error("Expected the lock of the other holder kept")
+	}
+
+	mr.SetError("boom")
+	if _, ok, err := c.TryLock(ctx, "lock:other", time.Minute); err == nil || ok {
+		t.Errorf("Expected the redis error, got %v, %v", ok, err)
+	}
+}
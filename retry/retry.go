@@ -0,0 +1,196 @@
+/*
+retry 通用重试工具，支持退避策略、抖动、可重试判断和每次重试的回调
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/baisiyi/go-kits/clock"
+)
+
+// Backoff returns the delay before the given retry, attempt starts from 1.
+type Backoff func(attempt int) time.Duration
+
+type options struct {
+	attempts int
+	backoff  Backoff
+	jitter   bool
+	retryIf  func(error) bool
+	onRetry  []func(attempt int, err error, delay time.Duration)
+	clock    clock.Clock
+}
+
+// Option is the option of Do.
+type Option func(*options)
+
+// Attempts sets the max number of attempts including the first one, default as 3.
+// n <= 0 retries until ctx is done.
+func Attempts(n int) Option {
+	return func(o *options) {
+		o.attempts = n
+	}
+}
+
+// ConstantBackoff waits d before every retry.
+func ConstantBackoff(d time.Duration) Option {
+	return func(o *options) {
+		o.backoff = func(int) time.Duration { return d }
+	}
+}
+
+// ExponentialBackoff waits base before the first retry, doubled every retry and capped by max.
+// It is the default with base 100ms and max 10s.
+func ExponentialBackoff(base, max time.Duration) Option {
+	return func(o *options) {
+		o.backoff = exponential(base, max)
+	}
+}
+
+// WithBackoff sets a custom backoff.
+func WithBackoff(b Backoff) Option {
+	return func(o *options) {
+		o.backoff = b
+	}
+}
+
+// Jitter randomizes each delay in [delay/2, delay] to avoid retry storms.
+func Jitter() Option {
+	return func(o *options) {
+		o.jitter = true
+	}
+}
+
+// RetryIf sets the predicate of the retryable errors, by default all errors
+// except the ones wrapped by Unrecoverable and context errors are retried.
+func RetryIf(pred func(error) bool) Option {
+	return func(o *options) {
+		o.retryIf = pred
+	}
+}
+
+// OnRetry adds the hook called before each retry with the failed attempt number,
+// its error and the delay before the next attempt.
+func OnRetry(fn func(attempt int, err error, delay time.Duration)) Option {
+	return func(o *options) {
+		o.onRetry = append(o.onRetry, fn)
+	}
+}
+
+// WithClock sets the clock of the backoff waits, default as clock.Real.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+type unrecoverable struct {
+	err error
+}
+
+func (u unrecoverable) Error() string { return u.err.Error() }
+func (u unrecoverable) Unwrap() error { return u.err }
+
+// Unrecoverable wraps err so that Do returns it without retrying, also if wrapped again.
+func Unrecoverable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return unrecoverable{err: err}
+}
+
+// IsUnrecoverable reports whether err is wrapped by Unrecoverable.
+func IsUnrecoverable(err error) bool {
+	var u unrecoverable
+	return errors.As(err, &u)
+}
+
+// Do calls fn until it succeeds, the attempts are exhausted, the error is not
+// retryable or ctx is done. It returns the last error of fn, joined with
+// ctx.Err() if canceled while waiting.
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	o := &options{
+		attempts: 3,
+		backoff:  exponential(100*time.Millisecond, 10*time.Second),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	clk := clock.OrReal(o.clock)
+
+	var timer clock.Timer
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var u unrecoverable
+		if errors.As(err, &u) {
+			// the error wrapping Unrecoverable, e.g. by fmt.Errorf with %w, keeps its context
+			if _, ok := err.(unrecoverable); ok {
+				return u.err
+			}
+			return err
+		}
+		if !o.retryable(err) || (o.attempts > 0 && attempt >= o.attempts) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return errors.Join(ctx.Err(), err)
+		}
+
+		delay := o.backoff(attempt)
+		if o.jitter && delay > 0 {
+			delay = delay/2 + rand.N(delay/2+1)
+		}
+		for _, hook := range o.onRetry {
+			hook(attempt, err, delay)
+		}
+		if timer == nil {
+			timer = clk.NewTimer(delay)
+			defer timer.Stop()
+		} else {
+			timer.Reset(delay)
+		}
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), err)
+		}
+	}
+}
+
+// DoValue is Do for the functions returning a value.
+func DoValue[T any](ctx context.Context, fn func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	var v T
+	err := Do(ctx, func(ctx context.Context) error {
+		var err error
+		v, err = fn(ctx)
+		return err
+	}, opts...)
+	return v, err
+}
+
+func (o *options) retryable(err error) bool {
+	if o.retryIf != nil {
+		return o.retryIf(err)
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func exponential(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if max > 0 && d > max {
+			d = max
+		}
+		return d
+	}
+}
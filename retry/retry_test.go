@@ -0,0 +1,142 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/baisiyi/go-kits/clock"
+)
+
+var errTemp = errors.New("temporary")
+
+// TestDo tests the retries until success and the exhausted attempts.
+func TestDo(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTemp
+		}
+		return nil
+	}, Attempts(5), ConstantBackoff(time.Millisecond))
+	if err != nil || calls != 3 {
+		t.Errorf("Do = %v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	err = Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errTemp
+	}, Attempts(2), ConstantBackoff(time.Millisecond))
+	if !errors.Is(err, errTemp) || calls != 2 {
+		t.Errorf("Do = %v after %d calls, want errTemp after 2", err, calls)
+	}
+}
+
+// TestDoStop tests the unrecoverable errors and the RetryIf predicate.
+func TestDoStop(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return Unrecoverable(errTemp)
+	})
+	if err != errTemp || calls != 1 {
+		t.Errorf("Do = %v after %d calls, want unwrapped errTemp after 1", err, calls)
+	}
+
+	calls = 0
+	err = Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return fmt.Errorf("fetch: %w", Unrecoverable(errTemp))
+	})
+	if !errors.Is(err, errTemp) || err.Error() != "fetch: "+errTemp.Error() || calls != 1 {
+		t.Errorf("Do = %v after %d calls, want the wrapped errTemp after 1", err, calls)
+	}
+
+	calls = 0
+	errFatal := errors.New("fatal")
+	_ = Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errFatal
+	}, RetryIf(func(err error) bool { return !errors.Is(err, errFatal) }))
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+// TestDoContext tests that Do returns when ctx is done while waiting.
+func TestDoContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := Do(ctx, func(ctx context.Context) error {
+		return errTemp
+	}, Attempts(0), ConstantBackoff(time.Hour))
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errTemp) {
+		t.Errorf("Expected deadline and last error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected Do to return on ctx done")
+	}
+}
+
+// TestBackoff tests the exponential backoff, jitter and the retry hook.
+func TestBackoff(t *testing.T) {
+	b := exponential(10*time.Millisecond, 50*time.Millisecond)
+	want := []time.Duration{10, 20, 40, 50, 50}
+	for i, w := range want {
+		if got := b(i + 1); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+	}
+
+	var delays []time.Duration
+	_ = Do(context.Background(), func(ctx context.Context) error {
+		return errTemp
+	}, Attempts(3), ExponentialBackoff(2*time.Millisecond, time.Second), Jitter(),
+		OnRetry(func(attempt int, err error, delay time.Duration) {
+			delays = append(delays, delay)
+		}))
+	if len(delays) != 2 {
+		t.Fatalf("hook called %d times, want 2", len(delays))
+	}
+	if delays[0] < time.Millisecond || delays[0] > 2*time.Millisecond {
+		t.Errorf("jittered delay %v out of range", delays[0])
+	}
+}
+
+// TestDoValue tests the generic variant.
+func TestDoValue(t *testing.T) {
+	v, err := DoValue(context.Background(), func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	if err != nil || v != 42 {
+		t.Errorf("DoValue = %d, %v", v, err)
+	}
+}
+
+// TestDoFakeClock tests the backoff waits driven by a fake clock.
+func TestDoFakeClock(t *testing.T) {
+	clk := clock.NewFake(time.Time{})
+	done := make(chan error)
+	go func() {
+		done <- Do(context.Background(), func(ctx context.Context) error {
+			return errTemp
+		}, Attempts(3), ConstantBackoff(time.Hour), WithClock(clk))
+	}()
+	for i := 0; i < 2; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(time.Hour)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, errTemp) {
+			t.Errorf("Do = %v, want errTemp", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Do not returned after advancing the clock")
+	}
+}
@@ -0,0 +1,44 @@
+package scheduler
+
+import "time"
+
+// Config is the configuration of the scheduler plugin.
+type Config struct {
+	// Timezone is the location the cron expressions are evaluated in, default as Local.
+	Timezone string `yaml:"timezone" mapstructure:"timezone"`
+	// Jobs is the schedule of the jobs, keyed by the job name passed to Add.
+	Jobs map[string]JobConfig `yaml:"jobs" mapstructure:"jobs"`
+	// RedisClient is the client name of the redis plugin used as the distributed lock
+	// of the jobs with Lock set, unless WithLocker is set in Factory.Options.
+	RedisClient string `yaml:"redis_client" mapstructure:"redis_client"`
+	// Metrics records the duration and the failures of the jobs to metrics.DefaultRegistry.
+	Metrics bool `yaml:"metrics" mapstructure:"metrics"`
+}
+
+// JobConfig is the schedule of a job. One of Cron and Every must be set.
+type JobConfig struct {
+	// Cron is the cron expression, seconds field optional, e.g. "0 */5 * * * *" or "@hourly".
+	Cron string `yaml:"cron" mapstructure:"cron"`
+	// Every is the fixed interval between two runs.
+	Every time.Duration `yaml:"every" mapstructure:"every"`
+	// Timeout cancels the job context after the duration, 0 means no timeout.
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// AllowOverlap runs the job even if the previous run has not finished.
+	AllowOverlap bool `yaml:"allow_overlap" mapstructure:"allow_overlap"`
+	// Lock takes the distributed lock before each run so only one replica runs it.
+	Lock bool `yaml:"lock" mapstructure:"lock"`
+	// LockTTL is the ttl of the distributed lock, default as Timeout or 1m.
+	LockTTL time.Duration `yaml:"lock_ttl" mapstructure:"lock_ttl"`
+	// Disabled skips the job.
+	Disabled bool `yaml:"disabled" mapstructure:"disabled"`
+}
+
+func (c *JobConfig) lockTTL() time.Duration {
+	if c.LockTTL > 0 {
+		return c.LockTTL
+	}
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return time.Minute
+}
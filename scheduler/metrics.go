@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/baisiyi/go-kits/metrics"
+)
+
+// MetricsObserver records the duration scheduler_job_duration_seconds and the runs
+// scheduler_job_runs_total of the jobs, labelled by job and status (ok/error).
+type MetricsObserver struct {
+	runs     metrics.Counter
+	duration metrics.Timer
+}
+
+// NewMetricsObserver creates the observer recording the metrics into reg, set by WithObserver.
+func NewMetricsObserver(reg *metrics.Registry) *MetricsObserver {
+	return &MetricsObserver{
+		runs:     reg.Counter("scheduler_job_runs_total", "Total number of scheduled job runs.", "job", "status"),
+		duration: reg.Timer("scheduler_job_duration_seconds", "Duration of scheduled job runs in seconds.", "job", "status"),
+	}
+}
+
+// ObserveJob implements Observer.
+func (o *MetricsObserver) ObserveJob(name string, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	o.runs.With(name, status).Inc()
+	o.duration.With(name, status).Observe(duration)
+}
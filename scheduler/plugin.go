@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/baisiyi/go-kits/metrics"
+	"github.com/baisiyi/go-kits/plugin"
+	"github.com/baisiyi/go-kits/redis"
+)
+
+const (
+	pluginType = "scheduler"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+var (
+	mu               sync.RWMutex
+	defaultScheduler *Scheduler
+
+	jobsMu sync.RWMutex
+	jobs   = make(map[string]Job)
+)
+
+// RegisterJob registers the job by name, usually in init. The scheduler plugin adds
+// the registered jobs configured in Config.Jobs on Setup, so the jobs are declared
+// in yaml and need not be added after the plugins are set up.
+func RegisterJob(name string, job Job) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	jobs[name] = job
+}
+
+// registeredJob returns the job registered by RegisterJob.
+func registeredJob(name string) (Job, bool) {
+	jobsMu.RLock()
+	defer jobsMu.RUnlock()
+	job, ok := jobs[name]
+	return job, ok
+}
+
+// Default returns the scheduler created by the scheduler plugin, nil if not set up.
+func Default() *Scheduler {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultScheduler
+}
+
+// SetDefault sets the default scheduler.
+func SetDefault(s *Scheduler) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultScheduler = s
+}
+
+// DefaultFactory is the scheduler plugin factory registered as scheduler-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of scheduler. The scheduler set up is started
+// and the jobs added to it by Add run by their configured schedule.
+type Factory struct {
+	// Options are applied to the scheduler on Setup, e.g. WithLocker and WithObserver.
+	Options []Option
+	// StopTimeout is how long Close waits for the running jobs, default as 30s.
+	StopTimeout time.Duration
+
+	scheduler *Scheduler
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates and starts the scheduler by the plugin config, the configured jobs
+// registered by RegisterJob are added, the others can be added by Add later.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	var opts []Option
+	if cfg.RedisClient != "" {
+		client := redis.GetClient(cfg.RedisClient)
+		if client == nil {
+			return fmt.Errorf("scheduler: redis client %s not found", cfg.RedisClient)
+		}
+		opts = append(opts, WithLocker(client))
+	}
+	if cfg.Metrics {
+		opts = append(opts, WithObserver(NewMetricsObserver(metrics.DefaultRegistry)))
+	}
+	s, err := New(cfg, append(opts, f.Options...)...)
+	if err != nil {
+		return err
+	}
+	for jobName := range cfg.Jobs {
+		job, ok := registeredJob(jobName)
+		if !ok {
+			continue
+		}
+		if err := s.Add(jobName, job); err != nil {
+			return err
+		}
+	}
+	s.Start()
+	f.scheduler = s
+	SetDefault(s)
+	return nil
+}
+
+// FlexDependsOn sets up the redis plugin first if configured, used by the distributed lock.
+func (f *Factory) FlexDependsOn() []string {
+	return []string{"redis-default"}
+}
+
+// Close stops the scheduler and waits for the running jobs.
+func (f *Factory) Close() error {
+	if f.scheduler == nil {
+		return nil
+	}
+	s := f.scheduler
+	f.scheduler = nil
+	if Default() == s {
+		SetDefault(nil)
+	}
+	timeout := f.StopTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.Stop(ctx)
+}
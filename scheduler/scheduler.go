@@ -0,0 +1,374 @@
+/*
+scheduler 定时任务调度，支持 cron 表达式和固定间隔，带超时、防重入、分布式锁和执行指标
+*/
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/baisiyi/go-kits/clock"
+	"github.com/baisiyi/go-kits/log"
+)
+
+var (
+	// ErrDuplicateJob is returned when a job of the same name is added twice.
+	ErrDuplicateJob = errors.New("scheduler: duplicate job")
+	// ErrNoSchedule is returned when a job has neither cron nor every.
+	ErrNoSchedule = errors.New("scheduler: job has no schedule")
+	// ErrJobPanic is wrapped by the error of a run recovered from panic.
+	ErrJobPanic = errors.New("scheduler: job panic")
+)
+
+// parser accepts the standard 5 fields, an optional leading seconds field and descriptors.
+var parser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour |
+	cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Job is the function run by the scheduler. ctx is canceled on timeout or Stop.
+type Job func(ctx context.Context) error
+
+// Locker is the distributed lock used by the jobs with Lock set.
+// TryLock returns ok false without error if the lock is held by another replica.
+// *redis.Client of the redis package implements it.
+type Locker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// Observer is notified after each run, e.g. to record duration and failure metrics.
+type Observer interface {
+	ObserveJob(name string, duration time.Duration, err error)
+}
+
+// ObserverFunc is the function adapter of Observer.
+type ObserverFunc func(name string, duration time.Duration, err error)
+
+// ObserveJob implements Observer.
+func (f ObserverFunc) ObserveJob(name string, duration time.Duration, err error) {
+	f(name, duration, err)
+}
+
+// Stats is the statistics of a job.
+type Stats struct {
+	Runs         int64
+	Failures     int64
+	Panics       int64 // the failures recovered from panic
+	Skipped      int64 // skipped by overlap prevention or the distributed lock
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastError    error
+}
+
+// Option is the option of Scheduler.
+type Option func(*Scheduler)
+
+// WithLocker sets the distributed lock of the jobs with Lock set.
+func WithLocker(l Locker) Option {
+	return func(s *Scheduler) {
+		s.locker = l
+	}
+}
+
+// WithObserver adds an observer of the job runs.
+func WithObserver(o Observer) Option {
+	return func(s *Scheduler) {
+		s.observers = append(s.observers, o)
+	}
+}
+
+// WithLocation sets the location the cron expressions are evaluated in.
+func WithLocation(loc *time.Location) Option {
+	return func(s *Scheduler) {
+		s.loc = loc
+	}
+}
+
+// WithLogger sets the logger, default as the default logger.
+func WithLogger(l log.Logger) Option {
+	return func(s *Scheduler) {
+		s.logger = l
+	}
+}
+
+// WithClock sets the clock timing the runs, default as clock.Real.
+// The cron triggering itself always follows the real time.
+func WithClock(c clock.Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = c
+	}
+}
+
+// WithLockPrefix sets the prefix of the lock keys, default as "scheduler:".
+func WithLockPrefix(prefix string) Option {
+	return func(s *Scheduler) {
+		s.lockPrefix = prefix
+	}
+}
+
+// Scheduler runs the named jobs by their schedule.
+type Scheduler struct {
+	cfg        Config
+	loc        *time.Location
+	locker     Locker
+	observers  []Observer
+	logger     log.Logger
+	lockPrefix string
+	clock      clock.Clock
+
+	cron   *cron.Cron
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu   sync.RWMutex
+	jobs map[string]*entry
+}
+
+type entry struct {
+	name    string
+	cfg     JobConfig
+	job     Job
+	id      cron.EntryID
+	running atomic.Bool
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// New creates a Scheduler, cfg provides the schedules of the jobs added by Add.
+func New(cfg Config, opts ...Option) (*Scheduler, error) {
+	s := &Scheduler{
+		cfg:        cfg,
+		loc:        time.Local,
+		lockPrefix: "scheduler:",
+		jobs:       make(map[string]*entry),
+	}
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("scheduler: load timezone error: %w", err)
+		}
+		s.loc = loc
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if s.logger == nil {
+		s.logger = log.GetDefaultLogger()
+	}
+	s.clock = clock.OrReal(s.clock)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cron = cron.New(cron.WithLocation(s.loc), cron.WithParser(parser))
+	return s, nil
+}
+
+// Add adds the job with the schedule configured in Config.Jobs[name].
+func (s *Scheduler) Add(name string, job Job) error {
+	cfg, ok := s.cfg.Jobs[name]
+	if !ok {
+		return fmt.Errorf("scheduler: job %s not configured", name)
+	}
+	return s.AddJob(name, cfg, job)
+}
+
+// Cron adds the job run by the cron expression.
+func (s *Scheduler) Cron(name, expr string, job Job) error {
+	return s.AddJob(name, JobConfig{Cron: expr}, job)
+}
+
+// Every adds the job run every d.
+func (s *Scheduler) Every(name string, d time.Duration, job Job) error {
+	return s.AddJob(name, JobConfig{Every: d}, job)
+}
+
+// AddJob adds the job with the given schedule. A disabled job is ignored.
+func (s *Scheduler) AddJob(name string, cfg JobConfig, job Job) error {
+	if cfg.Disabled {
+		return nil
+	}
+	if cfg.Lock && s.locker == nil {
+		return fmt.Errorf("scheduler: job %s needs a lock but no locker set", name)
+	}
+	var (
+		sched cron.Schedule
+		err   error
+	)
+	switch {
+	case cfg.Cron != "":
+		sched, err = parser.Parse(cfg.Cron)
+		if err != nil {
+			return fmt.Errorf("scheduler: job %s cron error: %w", name, err)
+		}
+	case cfg.Every > 0:
+		sched = every(cfg.Every)
+	default:
+		return fmt.Errorf("%w: %s", ErrNoSchedule, name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, name)
+	}
+	e := &entry{name: name, cfg: cfg, job: job}
+	e.id = s.cron.Schedule(sched, cron.FuncJob(func() { s.run(e) }))
+	s.jobs[name] = e
+	return nil
+}
+
+// Remove removes the job, the running one is not interrupted.
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.jobs[name]; ok {
+		s.cron.Remove(e.id)
+		delete(s.jobs, name)
+	}
+}
+
+// Start starts the scheduler in its own goroutine.
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops scheduling new runs, cancels the running jobs and waits for them
+// to return until ctx is done.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	done := s.cron.Stop()
+	s.cancel()
+	select {
+	case <-done.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Trigger runs the job once right now, following its timeout, overlap and lock settings.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.RLock()
+	e, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("scheduler: job %s not found", name)
+	}
+	return s.run(e)
+}
+
+// Stats returns the statistics of the job.
+func (s *Scheduler) Stats(name string) (Stats, bool) {
+	s.mu.RLock()
+	e, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return Stats{}, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats, true
+}
+
+// Next returns the next run time of the job.
+func (s *Scheduler) Next(name string) (time.Time, bool) {
+	s.mu.RLock()
+	e, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return time.Time{}, false
+	}
+	return s.cron.Entry(e.id).Next, true
+}
+
+func (s *Scheduler) run(e *entry) error {
+	if !e.cfg.AllowOverlap {
+		if !e.running.CompareAndSwap(false, true) {
+			s.skip(e, "previous run not finished")
+			return nil
+		}
+		defer e.running.Store(false)
+	}
+
+	ctx := s.ctx
+	if e.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.Timeout)
+		defer cancel()
+	}
+
+	if e.cfg.Lock {
+		unlock, ok, err := s.locker.TryLock(ctx, s.lockPrefix+e.name, e.cfg.lockTTL())
+		if err != nil {
+			s.logger.Errorf("scheduler: job %s lock error: %v", e.name, err)
+			s.finish(e, s.clock.Now(), 0, err)
+			return err
+		}
+		if !ok {
+			s.skip(e, "lock held by another replica")
+			return nil
+		}
+		defer unlock()
+	}
+
+	start := s.clock.Now()
+	stack, err := safeRun(ctx, e.job)
+	d := s.clock.Since(start)
+	switch {
+	case stack != nil:
+		s.logger.Error("scheduler: job panic", log.String("job", e.name),
+			log.Duration("duration", d), log.Any("error", err), log.ByteString("stack", stack))
+		e.mu.Lock()
+		e.stats.Panics++
+		e.mu.Unlock()
+	case err != nil:
+		s.logger.Error("scheduler: job failed", log.String("job", e.name),
+			log.Duration("duration", d), log.Any("error", err))
+	}
+	s.finish(e, start, d, err)
+	return err
+}
+
+func (s *Scheduler) skip(e *entry, reason string) {
+	s.logger.Debugf("scheduler: job %s skipped: %s", e.name, reason)
+	e.mu.Lock()
+	e.stats.Skipped++
+	e.mu.Unlock()
+}
+
+func (s *Scheduler) finish(e *entry, start time.Time, d time.Duration, err error) {
+	e.mu.Lock()
+	e.stats.Runs++
+	if err != nil {
+		e.stats.Failures++
+	}
+	e.stats.LastRun = start
+	e.stats.LastDuration = d
+	e.stats.LastError = err
+	e.mu.Unlock()
+	for _, o := range s.observers {
+		o.ObserveJob(e.name, d, err)
+	}
+}
+
+// safeRun runs the job and recovers the panic, stack is the stack of the panic if any.
+func safeRun(ctx context.Context, job Job) (stack []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack, err = debug.Stack(), fmt.Errorf("%w: %v", ErrJobPanic, r)
+		}
+	}()
+	return nil, job(ctx)
+}
+
+// every is a fixed interval schedule not rounded to seconds like cron.Every.
+type every time.Duration
+
+// Next implements cron.Schedule.
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
@@ -0,0 +1,312 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/clock"
+	"github.com/baisiyi/go-kits/log"
+	"github.com/baisiyi/go-kits/metrics"
+	"github.com/baisiyi/go-kits/plugin"
+	"github.com/baisiyi/go-kits/redis"
+)
+
+// captureLogger records the messages and fields of the error logs.
+type captureLogger struct {
+	log.Logger
+	mu     sync.Mutex
+	msgs   []string
+	fields [][]log.Field
+}
+
+func (l *captureLogger) Error(msg string, fields ...log.Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, msg)
+	l.fields = append(l.fields, fields)
+}
+
+// TestEvery tests that an interval job runs and records stats.
+func TestEvery(t *testing.T) {
+	s, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	var runs atomic.Int32
+	if err := s.Every("tick", 10*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Every failed: %v", err)
+	}
+	s.Start()
+	time.Sleep(55 * time.Millisecond)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if runs.Load() < 2 {
+		t.Errorf("Expected at least 2 runs, got %d", runs.Load())
+	}
+	st, ok := s.Stats("tick")
+	if !ok || st.Runs != int64(runs.Load()) {
+		t.Errorf("Stats = %+v, want %d runs", st, runs.Load())
+	}
+}
+
+// TestAddJobValidate tests the job validation.
+func TestAddJobValidate(t *testing.T) {
+	s, _ := New(Config{Jobs: map[string]JobConfig{"cfg": {Cron: "*/5 * * * *"}}})
+	noop := func(context.Context) error { return nil }
+
+	if err := s.Add("cfg", noop); err != nil {
+		t.Errorf("Add failed: %v", err)
+	}
+	if _, ok := s.Next("cfg"); !ok {
+		t.Error("Expected next run of the added job")
+	}
+	if err := s.Add("cfg", noop); !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("Expected ErrDuplicateJob, got %v", err)
+	}
+	if err := s.Add("missing", noop); err == nil {
+		t.Error("Expected error for unconfigured job")
+	}
+	if err := s.AddJob("none", JobConfig{}, noop); !errors.Is(err, ErrNoSchedule) {
+		t.Errorf("Expected ErrNoSchedule, got %v", err)
+	}
+	if err := s.Cron("bad", "not a cron", noop); err == nil {
+		t.Error("Expected error for invalid cron")
+	}
+	if err := s.AddJob("lock", JobConfig{Every: time.Second, Lock: true}, noop); err == nil {
+		t.Error("Expected error for lock without locker")
+	}
+	if err := s.Cron("seconds", "*/10 * * * * *", noop); err != nil {
+		t.Errorf("Expected seconds field accepted, got %v", err)
+	}
+}
+
+// TestTriggerTimeoutAndPanic tests the timeout and panic recovery of a run.
+func TestTriggerTimeoutAndPanic(t *testing.T) {
+	var observed []error
+	logger := &captureLogger{}
+	s, _ := New(Config{}, WithLogger(logger), WithObserver(ObserverFunc(func(name string, d time.Duration, err error) {
+		observed = append(observed, err)
+	})))
+	_ = s.AddJob("slow", JobConfig{Every: time.Hour, Timeout: 10 * time.Millisecond}, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	_ = s.Every("panic", time.Hour, func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	if err := s.Trigger("slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if err := s.Trigger("panic"); !errors.Is(err, ErrJobPanic) {
+		t.Errorf("Expected ErrJobPanic, got %v", err)
+	}
+	st, _ := s.Stats("panic")
+	if st.Failures != 1 || st.Panics != 1 || len(observed) != 2 {
+		t.Errorf("Expected failure recorded, got %+v and %d observed", st, len(observed))
+	}
+	if len(logger.msgs) != 2 || logger.msgs[0] != "scheduler: job failed" || logger.msgs[1] != "scheduler: job panic" {
+		t.Fatalf("Unexpected error logs %v", logger.msgs)
+	}
+	var stack bool
+	for _, f := range logger.fields[1] {
+		stack = stack || f.Key == "stack" && len(f.Interface.([]byte)) > 0
+	}
+	if !stack {
+		t.Errorf("Expected the stack logged, got %+v", logger.fields[1])
+	}
+}
+
+// TestPluginRegisteredJobs tests that the plugin adds the configured jobs registered by RegisterJob.
+func TestPluginRegisteredJobs(t *testing.T) {
+	runs := make(chan string, 10)
+	RegisterJob("plugin_tick", func(ctx context.Context) error {
+		runs <- "tick"
+		return nil
+	})
+	RegisterJob("plugin_unconfigured", func(ctx context.Context) error { return nil })
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(`
+jobs:
+  plugin_tick:
+    every: 10ms
+  plugin_later:
+    cron: "@hourly"
+`), &node); err != nil {
+		t.Fatal(err)
+	}
+	f := &Factory{}
+	if err := f.Setup(pluginName, &plugin.YamlNodeDecoder{Node: &node}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	defer f.Close()
+
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the registered job run")
+	}
+	s := Default()
+	if _, ok := s.Next("plugin_unconfigured"); ok {
+		t.Error("Expected the unconfigured job not added")
+	}
+	if err := s.Add("plugin_later", func(context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected the configured job not registered added later, got %v", err)
+	}
+}
+
+// TestOverlap tests that a running job is not run again.
+func TestOverlap(t *testing.T) {
+	s, _ := New(Config{})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	_ = s.Every("long", time.Hour, func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	go func() { _ = s.Trigger("long") }()
+	<-started
+	if err := s.Trigger("long"); err != nil {
+		t.Errorf("Expected skipped run without error, got %v", err)
+	}
+	close(release)
+	st, _ := s.Stats("long")
+	if st.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", st.Skipped)
+	}
+}
+
+type memLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (l *memLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[key] {
+		return nil, false, nil
+	}
+	l.held[key] = true
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, key)
+	}, true, nil
+}
+
+// TestLock tests that a job is skipped if the lock is held by another replica.
+func TestLock(t *testing.T) {
+	locker := &memLocker{held: map[string]bool{"scheduler:report": true}}
+	s, _ := New(Config{}, WithLocker(locker))
+	var runs int
+	_ = s.AddJob("report", JobConfig{Every: time.Hour, Lock: true}, func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+
+	_ = s.Trigger("report")
+	if runs != 0 {
+		t.Errorf("Expected job skipped while locked, got %d runs", runs)
+	}
+	delete(locker.held, "scheduler:report")
+	_ = s.Trigger("report")
+	if runs != 1 || locker.held["scheduler:report"] {
+		t.Errorf("Expected job run and lock released, got %d runs", runs)
+	}
+}
+
+// TestPluginRedisLockAndMetrics tests the distributed lock of the redis plugin client
+// and the job metrics set up by the plugin config.
+func TestPluginRedisLockAndMetrics(t *testing.T) {
+	mr := miniredis.RunT(t)
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("locks:\n  addr: "+mr.Addr()+"\n"), &node); err != nil {
+		t.Fatal(err)
+	}
+	if err := redis.DefaultFactory.Setup("default", &plugin.YamlNodeDecoder{Node: &node}); err != nil {
+		t.Fatalf("redis Setup failed: %v", err)
+	}
+	defer redis.DefaultFactory.Close()
+
+	if err := yaml.Unmarshal([]byte(`
+redis_client: locks
+metrics: true
+jobs:
+  plugin_locked:
+    cron: "@hourly"
+    lock: true
+`), &node); err != nil {
+		t.Fatal(err)
+	}
+	f := &Factory{}
+	if err := f.Setup(pluginName, &plugin.YamlNodeDecoder{Node: &node}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	defer f.Close()
+
+	s := Default()
+	if err := s.Add("plugin_locked", func(context.Context) error { return errors.New("boom") }); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	_ = mr.Set("scheduler:plugin_locked", "other")
+	_ = s.Trigger("plugin_locked")
+	if st, _ := s.Stats("plugin_locked"); st.Runs != 0 || st.Skipped != 1 {
+		t.Errorf("Expected the job skipped while locked in redis, got %+v", st)
+	}
+	mr.Del("scheduler:plugin_locked")
+	_ = s.Trigger("plugin_locked")
+	if st, _ := s.Stats("plugin_locked"); st.Runs != 1 || mr.Exists("scheduler:plugin_locked") {
+		t.Errorf("Expected the job run and the lock released, got %+v", st)
+	}
+
+	var runs float64
+	for _, fam := range metrics.DefaultRegistry.Gather() {
+		if fam.Name != "scheduler_job_runs_total" {
+			continue
+		}
+		for _, series := range fam.Series {
+			if series.Labels[0].Value == "plugin_locked" && series.Labels[1].Value == "error" {
+				runs = series.Value
+			}
+		}
+	}
+	if runs != 1 {
+		t.Errorf("Expected 1 failed run recorded, got %v", runs)
+	}
+
+	if err := yaml.Unmarshal([]byte("redis_client: missing\n"), &node); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Factory{}).Setup(pluginName, &plugin.YamlNodeDecoder{Node: &node}); err == nil {
+		t.Error("Expected error for the missing redis client")
+	}
+}
+
+// TestFakeClock tests that the run duration is measured by the clock.
+func TestFakeClock(t *testing.T) {
+	clk := clock.NewFake(time.Time{})
+	s, _ := New(Config{}, WithClock(clk))
+	_ = s.Every("report", time.Hour, func(ctx context.Context) error {
+		clk.Advance(3 * time.Second)
+		return nil
+	})
+	_ = s.Trigger("report")
+	st, _ := s.Stats("report")
+	if st.LastDuration != 3*time.Second || !st.LastRun.Equal(clk.Now().Add(-3*time.Second)) {
+		t.Errorf("Stats = %+v", st)
+	}
+}
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "tracing"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the tracing plugin factory registered as tracing-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of tracing, setting the global TracerProvider:
+//
+//	tracing:
+//	  default:
+//	    service_name: order-svc
+//	    endpoint: otel-collector:4318
+//	    insecure: true
+//	    sampler: parentbased_traceidratio
+//	    ratio: 0.1
+type Factory struct {
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates the TracerProvider by the plugin config and sets it global.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	tp, err := NewProvider(cfg)
+	if err != nil {
+		return err
+	}
+	SetGlobal(tp)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.provider = tp
+	return nil
+}
+
+// Close flushes the spans and shuts down the TracerProvider.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.provider == nil {
+		return nil
+	}
+	err := f.provider.Shutdown(context.Background())
+	f.provider = nil
+	return err
+}
+
+// Provider returns the TracerProvider set up by the plugin, nil if not set up.
+func Provider() *sdktrace.TracerProvider {
+	DefaultFactory.mu.Lock()
+	defer DefaultFactory.mu.Unlock()
+	return DefaultFactory.provider
+}
@@ -0,0 +1,221 @@
+/*
+tracing 基于 OpenTelemetry 的链路追踪，以插件方式配置全局 TracerProvider，供 database、httpclient 等组件共用
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scope is the instrumentation scope of the spans started by this package.
+const scope = "github.com/baisiyi/go-kits/tracing"
+
+// The exporters.
+const (
+	// ExporterOTLP exports the spans by OTLP over HTTP.
+	ExporterOTLP = "otlp"
+	// ExporterNone exports nothing, the spans are still created and propagated, e.g.
+	// for the trace ids in the logs.
+	ExporterNone = "none"
+)
+
+// The samplers, named like the OTEL_TRACES_SAMPLER values.
+const (
+	SamplerAlwaysOn            = "always_on"
+	SamplerAlwaysOff           = "always_off"
+	SamplerTraceIDRatio        = "traceidratio"
+	SamplerParentBasedAlwaysOn = "parentbased_always_on"
+	SamplerParentBasedRatio    = "parentbased_traceidratio"
+)
+
+// Config is the configuration of the tracing plugin.
+type Config struct {
+	// ServiceName is the service.name resource attribute.
+	ServiceName string `yaml:"service_name" mapstructure:"service_name"`
+	// ResourceAttributes are the other resource attributes, like deployment.environment.
+	ResourceAttributes map[string]string `yaml:"resource_attributes" mapstructure:"resource_attributes"`
+
+	// Exporter is otlp or none, default as otlp.
+	Exporter string `yaml:"exporter" mapstructure:"exporter"`
+	// Endpoint is the host:port of the OTLP collector, default as the OTEL_EXPORTER_OTLP_* envs or localhost:4318.
+	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"`
+	// URLPath is the path of the traces, default as /v1/traces.
+	URLPath string `yaml:"url_path" mapstructure:"url_path"`
+	// Insecure uses http instead of https.
+	Insecure bool `yaml:"insecure" mapstructure:"insecure"`
+	// Headers are sent with every export, e.g. the auth token.
+	Headers map[string]string `yaml:"headers" mapstructure:"headers"`
+	// Timeout is the timeout of an export, default as 10s.
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+
+	// Sampler is always_on, always_off, traceidratio, parentbased_always_on or
+	// parentbased_traceidratio, default as parentbased_always_on.
+	Sampler string `yaml:"sampler" mapstructure:"sampler"`
+	// Ratio is the sampled ratio of the ratio samplers in [0, 1].
+	Ratio float64 `yaml:"ratio" mapstructure:"ratio"`
+}
+
+func (c *Config) setDefaults() {
+	if c.Exporter == "" {
+		c.Exporter = ExporterOTLP
+	}
+	if c.Sampler == "" {
+		c.Sampler = SamplerParentBasedAlwaysOn
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// NewProvider creates a TracerProvider by cfg, exporting the spans in batches.
+// The caller should Shutdown it to flush the spans on exit.
+func NewProvider(cfg Config) (*sdktrace.TracerProvider, error) {
+	cfg.setDefaults()
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	res, err := newResource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithSampler(sampler), sdktrace.WithResource(res)}
+	switch cfg.Exporter {
+	case ExporterNone:
+	case ExporterOTLP:
+		exporter, err := newOTLPExporter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter, sdktrace.WithExportTimeout(cfg.Timeout)))
+	default:
+		return nil, fmt.Errorf("tracing: exporter %s not supported", cfg.Exporter)
+	}
+	return sdktrace.NewTracerProvider(opts...), nil
+}
+
+func newOTLPExporter(cfg Config) (sdktrace.SpanExporter, error) {
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.URLPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(cfg.URLPath))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	opts = append(opts, otlptracehttp.WithTimeout(cfg.Timeout))
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: create otlp exporter error: %w", err)
+	}
+	return exporter, nil
+}
+
+func newSampler(cfg Config) (sdktrace.Sampler, error) {
+	if cfg.Ratio < 0 || cfg.Ratio > 1 {
+		return nil, fmt.Errorf("tracing: ratio %v out of range [0, 1]", cfg.Ratio)
+	}
+	switch cfg.Sampler {
+	case SamplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case SamplerAlwaysOff:
+		return sdktrace.NeverSample(), nil
+	case SamplerTraceIDRatio:
+		return sdktrace.TraceIDRatioBased(cfg.Ratio), nil
+	case SamplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case SamplerParentBasedRatio:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Ratio)), nil
+	default:
+		return nil, fmt.Errorf("tracing: sampler %s not supported", cfg.Sampler)
+	}
+}
+
+func newResource(cfg Config) (*resource.Resource, error) {
+	attrs := make([]attribute.KeyValue, 0, len(cfg.ResourceAttributes)+1)
+	for k, v := range cfg.ResourceAttributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	if cfg.ServiceName != "" {
+		attrs = append(attrs, attribute.String("service.name", cfg.ServiceName))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attrs...))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: resource error: %w", err)
+	}
+	return res, nil
+}
+
+// SetGlobal sets tp as the global TracerProvider and the W3C trace context and
+// baggage as the global propagator, used by the database and httpclient kits.
+func SetGlobal(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Tracer returns the tracer of the instrumentation scope from the global TracerProvider.
+func Tracer(name string) trace.Tracer {
+	return otel.GetTracerProvider().Tracer(name)
+}
+
+// Start starts a span by the global TracerProvider, the caller must End it.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer(scope).Start(ctx, name, opts...)
+}
+
+// End records err on span if not nil and ends it, e.g. defer func() { tracing.End(span, err) }().
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject writes the span context of ctx into carrier by the global propagator, like
+// the traceparent header.
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// Extract returns a copy of ctx carrying the remote span context read from carrier.
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// InjectHTTPHeader writes the span context of ctx into the http header.
+func InjectHTTPHeader(ctx context.Context, h http.Header) {
+	Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// ExtractHTTPHeader returns a copy of ctx carrying the remote span context of the http header.
+func ExtractHTTPHeader(ctx context.Context, h http.Header) context.Context {
+	return Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// TraceID returns the trace id of the span in ctx, "" if there is none.
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+// setRecorder sets a global TracerProvider recording the ended spans.
+func setRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	prev := otel.GetTracerProvider()
+	rec := tracetest.NewSpanRecorder()
+	SetGlobal(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+// TestNewProvider tests the samplers, the resource and the invalid configs.
+func TestNewProvider(t *testing.T) {
+	tp, err := NewProvider(Config{
+		Exporter:           ExporterNone,
+		ServiceName:        "order-svc",
+		ResourceAttributes: map[string]string{"deployment.environment": "test"},
+		Sampler:            SamplerAlwaysOff,
+	})
+	if err != nil {
+		t.Fatalf("NewProvider failed: %v", err)
+	}
+	defer tp.Shutdown(context.Background())
+	if _, span := tp.Tracer("test").Start(context.Background(), "op"); span.SpanContext().IsSampled() {
+		t.Error("Expected the span not sampled by always_off")
+	}
+
+	for _, cfg := range []Config{
+		{Exporter: "zipkin"},
+		{Exporter: ExporterNone, Sampler: "sometimes"},
+		{Exporter: ExporterNone, Sampler: SamplerTraceIDRatio, Ratio: 1.5},
+	} {
+		if _, err := NewProvider(cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
+
+// TestHTTPPropagation tests the traceparent injected by Transport and extracted by Middleware.
+func TestHTTPPropagation(t *testing.T) {
+	rec := setRecorder(t)
+
+	var serverTrace, traceparent string
+	srv := httptest.NewServer(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		serverTrace = TraceID(r.Context())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))
+	defer srv.Close()
+
+	ctx, span := Start(context.Background(), "caller")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/orders", nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	resp.Body.Close()
+	End(span, errors.New("failed"))
+
+	if traceparent == "" || serverTrace != TraceID(ctx) {
+		t.Errorf("Expected the trace %s propagated, got traceparent %q and trace %q", TraceID(ctx), traceparent, serverTrace)
+	}
+	kinds := make(map[trace.SpanKind]codes.Code)
+	for _, s := range rec.Ended() {
+		if s.SpanContext().TraceID().String() != TraceID(ctx) {
+			t.Errorf("span %s not in the trace", s.Name())
+		}
+		kinds[s.SpanKind()] = s.Status().Code
+	}
+	if len(kinds) != 3 {
+		t.Fatalf("Expected internal, client and server spans, got %v", kinds)
+	}
+	for kind, code := range kinds {
+		if code != codes.Error {
+			t.Errorf("Expected the %s span failed, got %v", kind, code)
+		}
+	}
+}
+
+// TestFactory tests setting up the global TracerProvider by the plugin.
+func TestFactory(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("exporter: none\nservice_name: test"), &node); err != nil {
+		t.Fatal(err)
+	}
+	if err := DefaultFactory.Setup(pluginName, &plugin.YamlNodeDecoder{Node: &node}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if Provider() == nil || otel.GetTracerProvider() != Provider() {
+		t.Error("Expected the provider set global")
+	}
+	if _, span := Start(context.Background(), "op"); !span.SpanContext().IsSampled() {
+		t.Error("Expected the span sampled by the default sampler")
+	}
+	if err := DefaultFactory.Close(); err != nil || Provider() != nil {
+		t.Errorf("Close = %v, provider %v", err, Provider())
+	}
+}
@@ -0,0 +1,25 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxBodySize is the max size of the request body decoded by DecodeJSON.
+const maxBodySize = 4 << 20
+
+// DecodeJSON decodes the json body of r into dst and validates it with the
+// default validator, for request binding in http handlers.
+func DecodeJSON(r *http.Request, dst any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	if err := dec.Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("validate: request body empty")
+		}
+		return fmt.Errorf("validate: decode request body error: %w", err)
+	}
+	return Struct(dst)
+}
@@ -0,0 +1,232 @@
+/*
+validate 基于 go-playground/validator 的校验封装，统一自定义规则注册和错误信息翻译
+*/
+
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+const (
+	LocaleEN = "en"
+	LocaleZH = "zh"
+)
+
+// FieldLevel and StructLevel are re-exported for the custom rules.
+type (
+	FieldLevel  = validator.FieldLevel
+	StructLevel = validator.StructLevel
+)
+
+// FieldError is a failed rule of a field.
+type FieldError struct {
+	// Field is the field name, taken from the json or yaml tag if present.
+	Field string
+	// Namespace is the full path of the field, like User.Address.City.
+	Namespace string
+	Tag       string
+	Param     string
+	// Message is the translated message.
+	Message string
+}
+
+// Errors is the error returned when the validation fails.
+type Errors []FieldError
+
+// Error implements error.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Option is the option of Validator.
+type Option func(*Validator)
+
+// WithLocale sets the locale of the messages, en or zh, default as en.
+func WithLocale(locale string) Option {
+	return func(v *Validator) {
+		v.locale = locale
+	}
+}
+
+// WithTagName sets the struct tag of the rules, default as validate.
+func WithTagName(name string) Option {
+	return func(v *Validator) {
+		v.tagName = name
+	}
+}
+
+// Validator validates structs and values.
+type Validator struct {
+	validate *validator.Validate
+	trans    map[string]ut.Translator
+	locale   string
+	tagName  string
+}
+
+// New creates a Validator with the builtin translations of en and zh.
+func New(opts ...Option) *Validator {
+	v := &Validator{locale: LocaleEN, tagName: "validate"}
+	for _, o := range opts {
+		o(v)
+	}
+	v.validate = validator.New(validator.WithRequiredStructEnabled())
+	v.validate.SetTagName(v.tagName)
+	v.validate.RegisterTagNameFunc(fieldName)
+
+	uni := ut.New(en.New(), en.New(), zh.New())
+	enTrans, _ := uni.GetTranslator(LocaleEN)
+	zhTrans, _ := uni.GetTranslator(LocaleZH)
+	_ = en_translations.RegisterDefaultTranslations(v.validate, enTrans)
+	_ = zh_translations.RegisterDefaultTranslations(v.validate, zhTrans)
+	v.trans = map[string]ut.Translator{LocaleEN: enTrans, LocaleZH: zhTrans}
+	return v
+}
+
+// fieldName names the fields by the json or yaml tag so the messages match the payload.
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "yaml", "mapstructure"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// Struct validates the exported fields of s, returning Errors on failure.
+func (v *Validator) Struct(s any) error {
+	return v.StructCtx(context.Background(), s)
+}
+
+// StructCtx validates s with ctx passed to the ctx aware rules.
+func (v *Validator) StructCtx(ctx context.Context, s any) error {
+	return v.convert(v.validate.StructCtx(ctx, s))
+}
+
+// Var validates a single value with the rules tag, e.g. Var(email, "required,email").
+func (v *Validator) Var(field any, tag string) error {
+	return v.convert(v.validate.Var(field, tag))
+}
+
+// RegisterRule registers a custom field rule. messages are the messages by
+// locale, where {0} is the field name and {1} the rule param.
+func (v *Validator) RegisterRule(tag string, fn func(fl FieldLevel) bool, messages map[string]string) error {
+	if err := v.validate.RegisterValidation(tag, fn); err != nil {
+		return fmt.Errorf("validate: register rule %s error: %w", tag, err)
+	}
+	for locale, msg := range messages {
+		trans, ok := v.trans[locale]
+		if !ok {
+			return fmt.Errorf("validate: unknown locale %s", locale)
+		}
+		err := v.validate.RegisterTranslation(tag, trans,
+			func(t ut.Translator) error { return t.Add(tag, msg, true) },
+			func(t ut.Translator, fe validator.FieldError) string {
+				s, _ := t.T(fe.Tag(), fe.Field(), fe.Param())
+				return s
+			})
+		if err != nil {
+			return fmt.Errorf("validate: register message of %s error: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// RegisterStructRule registers a struct level rule of the types, for the rules
+// across fields. Report the errors with sl.ReportError.
+func (v *Validator) RegisterStructRule(fn func(sl StructLevel), types ...any) {
+	v.validate.RegisterStructValidation(fn, types...)
+}
+
+// Translate translates the errors of err into the locale, returning err as is
+// if it is not a validation error.
+func (v *Validator) Translate(err error, locale string) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	return v.translate(verrs, locale)
+}
+
+func (v *Validator) convert(err error) error {
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		// 如传入 nil 或非 struct 的 InvalidValidationError
+		return fmt.Errorf("validate: %w", err)
+	}
+	return v.translate(verrs, v.locale)
+}
+
+func (v *Validator) translate(verrs validator.ValidationErrors, locale string) Errors {
+	trans, ok := v.trans[locale]
+	if !ok {
+		trans = v.trans[LocaleEN]
+	}
+	errs := make(Errors, len(verrs))
+	for i, fe := range verrs {
+		errs[i] = FieldError{
+			Field:     fe.Field(),
+			Namespace: fe.Namespace(),
+			Tag:       fe.Tag(),
+			Param:     fe.Param(),
+			Message:   fe.Translate(trans),
+		}
+	}
+	return errs
+}
+
+var defaultValidator = New()
+
+// Default returns the process wide validator used by the package functions, e.g.
+// Struct and DecodeJSON, and by config.Config.Decode and DecodeKey to validate the
+// decoded structs.
+func Default() *Validator {
+	return defaultValidator
+}
+
+// SetDefault replaces the default validator, e.g. to use another locale.
+func SetDefault(v *Validator) {
+	defaultValidator = v
+}
+
+// Struct validates s with the default validator.
+func Struct(s any) error {
+	return defaultValidator.Struct(s)
+}
+
+// Var validates a single value with the default validator.
+func Var(field any, tag string) error {
+	return defaultValidator.Var(field, tag)
+}
+
+// RegisterRule registers a custom field rule to the default validator.
+func RegisterRule(tag string, fn func(fl FieldLevel) bool, messages map[string]string) error {
+	return defaultValidator.RegisterRule(tag, fn, messages)
+}
+
+// RegisterStructRule registers a struct level rule to the default validator.
+func RegisterStructRule(fn func(sl StructLevel), types ...any) {
+	defaultValidator.RegisterStructRule(fn, types...)
+}
@@ -0,0 +1,103 @@
+package validate
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type signup struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8"`
+	Confirm  string `json:"confirm"`
+}
+
+type invited struct {
+	Code string `json:"code" validate:"invite"`
+}
+
+// TestStruct tests the field names and the translated messages.
+func TestStruct(t *testing.T) {
+	err := New().Struct(&signup{Email: "bad", Password: "12345678"})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("Expected one field error, got %v", err)
+	}
+	if errs[0].Field != "email" || errs[0].Tag != "email" {
+		t.Errorf("FieldError = %+v", errs[0])
+	}
+	if !strings.Contains(errs[0].Message, "email") {
+		t.Errorf("Message = %q", errs[0].Message)
+	}
+
+	zhErr := New(WithLocale(LocaleZH)).Struct(&signup{})
+	if !strings.Contains(zhErr.Error(), "必填") {
+		t.Errorf("Expected zh message, got %q", zhErr.Error())
+	}
+}
+
+// TestRegisterRule tests the custom rules with messages.
+func TestRegisterRule(t *testing.T) {
+	v := New()
+	err := v.RegisterRule("invite", func(fl FieldLevel) bool {
+		return strings.HasPrefix(fl.Field().String(), "INV-")
+	}, map[string]string{LocaleEN: "{0} must be an invite code"})
+	if err != nil {
+		t.Fatalf("RegisterRule failed: %v", err)
+	}
+	err = v.Struct(&invited{Code: "ABC"})
+	var errs Errors
+	if !errors.As(err, &errs) || errs[0].Message != "code must be an invite code" {
+		t.Errorf("Expected invite code message, got %v", err)
+	}
+	if err := v.Struct(&invited{Code: "INV-1"}); err != nil {
+		t.Errorf("Struct failed: %v", err)
+	}
+}
+
+// TestRegisterStructRule tests the rules across fields.
+func TestRegisterStructRule(t *testing.T) {
+	v := New()
+	v.RegisterStructRule(func(sl StructLevel) {
+		s := sl.Current().Interface().(signup)
+		if s.Confirm != s.Password {
+			sl.ReportError(s.Confirm, "confirm", "Confirm", "eqpassword", "")
+		}
+	}, signup{})
+
+	err := v.Struct(&signup{Email: "a@b.c", Password: "12345678", Confirm: "x"})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Tag != "eqpassword" {
+		t.Errorf("Expected struct rule error, got %v", err)
+	}
+}
+
+// TestVarAndInvalid tests Var and the non struct input.
+func TestVarAndInvalid(t *testing.T) {
+	if err := Var("a@b.c", "email"); err != nil {
+		t.Errorf("Var failed: %v", err)
+	}
+	if err := Var("x", "email"); err == nil {
+		t.Error("Expected error for invalid email")
+	}
+	if err := Struct(nil); err == nil {
+		t.Error("Expected error for nil")
+	}
+}
+
+// TestDecodeJSON tests the request binding.
+func TestDecodeJSON(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"email":"a@b.c","password":"short"}`))
+	var s signup
+	err := DecodeJSON(req, &s)
+	var errs Errors
+	if !errors.As(err, &errs) || errs[0].Field != "password" {
+		t.Errorf("Expected password error, got %v", err)
+	}
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader(``))
+	if err := DecodeJSON(req, &s); err == nil {
+		t.Error("Expected error for empty body")
+	}
+}
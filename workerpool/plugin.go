@@ -0,0 +1,81 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/baisiyi/go-kits/plugin"
+)
+
+const (
+	pluginType = "workerpool"
+	pluginName = "default"
+)
+
+func init() {
+	plugin.Register(pluginName, DefaultFactory)
+}
+
+// DefaultFactory is the workerpool plugin factory registered as workerpool-default.
+var DefaultFactory = &Factory{}
+
+// Factory is the plugin factory of workerpool. The config is a map of pool name => Config.
+type Factory struct {
+	// CloseTimeout is how long Close waits for the queued tasks, default as 30s.
+	CloseTimeout time.Duration
+
+	mu    sync.RWMutex
+	pools map[string]*Pool
+}
+
+// Type returns the plugin type.
+func (f *Factory) Type() string {
+	return pluginType
+}
+
+// Setup creates all the configured pools.
+func (f *Factory) Setup(name string, dec plugin.Decoder) error {
+	var cfgs map[string]Config
+	if err := dec.Decode(&cfgs); err != nil {
+		return err
+	}
+	pools := make(map[string]*Pool, len(cfgs))
+	for pool, cfg := range cfgs {
+		pools[pool] = New(cfg)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pools = pools
+	return nil
+}
+
+// Close drains all the pools.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	timeout := f.CloseTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for pool, p := range f.pools {
+		if err := p.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("workerpool %s: %w", pool, err))
+		}
+	}
+	f.pools = nil
+	return errors.Join(errs...)
+}
+
+// GetPool returns the configured pool, nil if not found.
+func GetPool(pool string) *Pool {
+	DefaultFactory.mu.RLock()
+	defer DefaultFactory.mu.RUnlock()
+	return DefaultFactory.pools[pool]
+}
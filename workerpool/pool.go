@@ -0,0 +1,345 @@
+/*
+workerpool 有界并发的任务池，支持优先级队列、单任务超时重试、优雅关闭和队列指标
+*/
+
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/baisiyi/go-kits/log"
+)
+
+var (
+	// ErrClosed is returned when submitting to a closed pool.
+	ErrClosed = errors.New("workerpool: pool closed")
+	// ErrQueueFull is returned when the queue reaches Config.QueueSize.
+	ErrQueueFull = errors.New("workerpool: queue full")
+)
+
+// Config is the configuration of a pool.
+type Config struct {
+	// Workers is the max number of tasks running concurrently, default as 10.
+	Workers int `yaml:"workers" mapstructure:"workers"`
+	// QueueSize is the max number of queued tasks, 0 means unbounded.
+	QueueSize int `yaml:"queue_size" mapstructure:"queue_size"`
+	// Timeout is the default timeout of each attempt, 0 means no timeout.
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// Retries is the default number of retries after the first failed attempt.
+	Retries int `yaml:"retries" mapstructure:"retries"`
+	// RetryBackoff is the wait before each retry, doubled every time, default as 100ms.
+	RetryBackoff time.Duration `yaml:"retry_backoff" mapstructure:"retry_backoff"`
+}
+
+func (c *Config) setDefaults() {
+	if c.Workers <= 0 {
+		c.Workers = 10
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 100 * time.Millisecond
+	}
+}
+
+// Task is the function run by the pool.
+type Task func(ctx context.Context) error
+
+// Observer is notified of the pool activity, e.g. to export queue depth and latency metrics.
+type Observer interface {
+	// ObserveTask is called after a task finishes, wait is the time spent in the queue.
+	ObserveTask(wait, duration time.Duration, err error)
+	// ObserveQueue is called when the queue depth changes.
+	ObserveQueue(depth int)
+}
+
+// Stats is the statistics of a pool.
+type Stats struct {
+	Queued    int
+	Running   int
+	Completed int64
+	Failed    int64
+	Rejected  int64
+}
+
+// SubmitOption is the option of a submitted task.
+type SubmitOption func(*item)
+
+// WithPriority sets the priority of the task, higher runs first, default as 0.
+func WithPriority(p int) SubmitOption {
+	return func(it *item) {
+		it.priority = p
+	}
+}
+
+// WithTimeout overrides the timeout of each attempt of the task.
+func WithTimeout(d time.Duration) SubmitOption {
+	return func(it *item) {
+		it.timeout = d
+	}
+}
+
+// WithRetries overrides the number of retries of the task.
+func WithRetries(n int) SubmitOption {
+	return func(it *item) {
+		it.retries = n
+	}
+}
+
+// WithDone sets the callback called with the final error of the task.
+func WithDone(fn func(error)) SubmitOption {
+	return func(it *item) {
+		it.done = fn
+	}
+}
+
+// Option is the option of Pool.
+type Option func(*Pool)
+
+// WithObserver sets the observer of the pool.
+func WithObserver(o Observer) Option {
+	return func(p *Pool) {
+		p.observer = o
+	}
+}
+
+// WithLogger sets the logger, default as the default logger.
+func WithLogger(l log.Logger) Option {
+	return func(p *Pool) {
+		p.logger = l
+	}
+}
+
+// Pool runs the submitted tasks by priority with bounded concurrency.
+type Pool struct {
+	cfg      Config
+	observer Observer
+	logger   log.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   queue
+	seq     uint64
+	closed  bool
+	running int
+	stats   Stats
+}
+
+// New creates a Pool and starts its workers.
+func New(cfg Config, opts ...Option) *Pool {
+	cfg.setDefaults()
+	p := &Pool{cfg: cfg}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.logger == nil {
+		p.logger = log.GetDefaultLogger()
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+// Submit queues the task. It fails with ErrClosed after Close and ErrQueueFull
+// when the queue is full.
+func (p *Pool) Submit(task Task, opts ...SubmitOption) error {
+	it := &item{
+		task:    task,
+		timeout: p.cfg.Timeout,
+		retries: p.cfg.Retries,
+		queued:  time.Now(),
+	}
+	for _, o := range opts {
+		o(it)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.stats.Rejected++
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	if p.cfg.QueueSize > 0 && p.queue.Len() >= p.cfg.QueueSize {
+		p.stats.Rejected++
+		p.mu.Unlock()
+		return ErrQueueFull
+	}
+	p.seq++
+	it.seq = p.seq
+	heap.Push(&p.queue, it)
+	depth := p.queue.Len()
+	p.mu.Unlock()
+
+	p.cond.Signal()
+	p.observeQueue(depth)
+	return nil
+}
+
+// Stats returns the statistics of the pool.
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.stats
+	st.Queued = p.queue.Len()
+	st.Running = p.running
+	return st
+}
+
+// Close rejects new tasks and waits for the queued and running ones to finish.
+// When ctx is done the running tasks are canceled, the queued ones dropped, and ctx.Err returned.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.cond.Broadcast()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	dropped := p.queue.Len()
+	p.queue = nil
+	p.mu.Unlock()
+	if dropped > 0 {
+		p.logger.Warnf("workerpool: %d queued tasks dropped on close", dropped)
+	}
+	p.cancel()
+	<-done
+	return ctx.Err()
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		for p.queue.Len() == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if p.queue.Len() == 0 {
+			p.mu.Unlock()
+			return
+		}
+		it := heap.Pop(&p.queue).(*item)
+		depth := p.queue.Len()
+		p.running++
+		p.mu.Unlock()
+		p.observeQueue(depth)
+
+		start := time.Now()
+		err := p.run(it)
+		d := time.Since(start)
+
+		p.mu.Lock()
+		p.running--
+		if err != nil {
+			p.stats.Failed++
+		} else {
+			p.stats.Completed++
+		}
+		p.mu.Unlock()
+
+		if it.done != nil {
+			it.done(err)
+		}
+		if p.observer != nil {
+			p.observer.ObserveTask(start.Sub(it.queued), d, err)
+		}
+	}
+}
+
+func (p *Pool) run(it *item) error {
+	backoff := p.cfg.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= it.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-p.ctx.Done():
+				return err
+			}
+			backoff *= 2
+		}
+		if err = p.attempt(it); err == nil {
+			return nil
+		}
+		p.logger.Warnf("workerpool: task attempt %d failed: %v", attempt+1, err)
+	}
+	return err
+}
+
+func (p *Pool) attempt(it *item) (err error) {
+	ctx := p.ctx
+	if it.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, it.timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("workerpool: task panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return it.task(ctx)
+}
+
+func (p *Pool) observeQueue(depth int) {
+	if p.observer != nil {
+		p.observer.ObserveQueue(depth)
+	}
+}
+
+type item struct {
+	task     Task
+	priority int
+	timeout  time.Duration
+	retries  int
+	done     func(error)
+	queued   time.Time
+	seq      uint64
+}
+
+// queue is a max heap by priority, FIFO within the same priority.
+type queue []*item
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x any) { *q = append(*q, x.(*item)) }
+
+func (q *queue) Pop() any {
+	old := *q
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return it
+}
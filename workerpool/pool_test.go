@@ -0,0 +1,146 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestPriority tests that the higher priority tasks run first.
+func TestPriority(t *testing.T) {
+	p := New(Config{Workers: 1})
+	block := make(chan struct{})
+	_ = p.Submit(func(ctx context.Context) error {
+		<-block
+		return nil
+	})
+
+	var (
+		mu    sync.Mutex
+		order []int
+	)
+	for _, pri := range []int{1, 5, 1, 9} {
+		pri := pri
+		_ = p.Submit(func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, pri)
+			mu.Unlock()
+			return nil
+		}, WithPriority(pri))
+	}
+	close(block)
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	want := []int{9, 5, 1, 1}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+// TestRetryAndTimeout tests the retries and the per attempt timeout.
+func TestRetryAndTimeout(t *testing.T) {
+	p := New(Config{Workers: 2, RetryBackoff: time.Millisecond})
+	var attempts atomic.Int32
+	errc := make(chan error, 2)
+	_ = p.Submit(func(ctx context.Context) error {
+		if attempts.Add(1) < 3 {
+			return errors.New("fail")
+		}
+		return nil
+	}, WithRetries(2), WithDone(func(err error) { errc <- err }))
+	_ = p.Submit(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(10*time.Millisecond), WithDone(func(err error) { errc <- err }))
+
+	var results []error
+	for i := 0; i < 2; i++ {
+		results = append(results, <-errc)
+	}
+	_ = p.Close(context.Background())
+
+	if attempts.Load() != 3 {
+		t.Errorf("attempts = %d, want 3", attempts.Load())
+	}
+	var ok, timedOut int
+	for _, err := range results {
+		switch {
+		case err == nil:
+			ok++
+		case errors.Is(err, context.DeadlineExceeded):
+			timedOut++
+		}
+	}
+	if ok != 1 || timedOut != 1 {
+		t.Errorf("results = %v, want one success and one timeout", results)
+	}
+	if st := p.Stats(); st.Completed != 1 || st.Failed != 1 {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+// TestClose tests that Close drains the queue and rejects new tasks.
+func TestClose(t *testing.T) {
+	p := New(Config{Workers: 1, QueueSize: 2})
+	block := make(chan struct{})
+	started := make(chan struct{})
+	var ran atomic.Int32
+	_ = p.Submit(func(ctx context.Context) error {
+		close(started)
+		<-block
+		ran.Add(1)
+		return nil
+	})
+	<-started
+	task := func(ctx context.Context) error { ran.Add(1); return nil }
+	_ = p.Submit(task)
+	_ = p.Submit(task)
+	if err := p.Submit(task); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	closed := make(chan error)
+	go func() { closed <- p.Close(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	if err := p.Submit(task); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	close(block)
+	if err := <-closed; err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if ran.Load() != 3 {
+		t.Errorf("ran = %d, want 3", ran.Load())
+	}
+	if st := p.Stats(); st.Rejected != 2 {
+		t.Errorf("Rejected = %d, want 2", st.Rejected)
+	}
+}
+
+// TestCloseTimeout tests that the running tasks are canceled when Close times out.
+func TestCloseTimeout(t *testing.T) {
+	p := New(Config{Workers: 1})
+	started := make(chan struct{})
+	_ = p.Submit(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	_ = p.Submit(func(ctx context.Context) error { return nil })
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if st := p.Stats(); st.Completed != 0 || st.Queued != 0 {
+		t.Errorf("Expected queued task dropped, got %+v", st)
+	}
+}